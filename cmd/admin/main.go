@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"os"
@@ -187,14 +188,24 @@ func main() {
 }
 
 func listUsers(db database.Database, subscriptionService *services.SubscriptionService) {
-	// For SQLite implementation, we can access the underlying DB
-	if sqliteDB, ok := db.(*database.DB); ok {
+	// For the SQL implementations, we can access the underlying DB
+	var sqlDB *sql.DB
+	isAdminColumn := "COALESCE(is_admin, 0)"
+	switch d := db.(type) {
+	case *database.DB:
+		sqlDB = d.DB
+	case *database.PostgresDB:
+		sqlDB = d.DB
+		isAdminColumn = "COALESCE(is_admin, FALSE)"
+	}
+
+	if sqlDB != nil {
 		// Simple query to list all users
 		query := `SELECT id, email, name, subscription_status, 
-				  COALESCE(is_admin, 0), COALESCE(free_months_remaining, 0),
+				  ` + isAdminColumn + `, COALESCE(free_months_remaining, 0),
 				  created_at FROM users ORDER BY id`
 
-		rows, err := sqliteDB.Query(query)
+		rows, err := sqlDB.Query(query)
 		if err != nil {
 			log.Fatal("Failed to query users:", err)
 		}
@@ -722,6 +733,15 @@ func hasAdminUsers(subscriptionService *services.SubscriptionService) bool {
 		}
 
 		return count > 0
+	} else if pgDB, ok := db.(*database.PostgresDB); ok {
+		var exists bool
+		err := pgDB.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE is_admin)`).Scan(&exists)
+		if err != nil {
+			fmt.Printf("ERROR: Failed to check admin users: %v\n", err)
+			return false
+		}
+
+		return exists
 	} else if datastoreDB, ok := db.(*database.DatastoreDB); ok {
 		ctx := context.Background()
 
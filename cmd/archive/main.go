@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func usage() {
+	fmt.Println("Usage: go run ./cmd/archive [-db path] <command> [file]")
+	fmt.Println("Commands:")
+	fmt.Println("  export <file>  - Write every user, feed, article, subscription, article status,")
+	fmt.Println("                   session and audit log to an archive ('-' for stdout)")
+	fmt.Println("  import <file>  - Restore an archive into an empty database and verify the counts")
+	fmt.Println("                   ('-' for stdin)")
+	fmt.Println("  counts         - Show how many rows of each kind the database holds")
+	fmt.Println("")
+	fmt.Println("The database is chosen as at startup: DATABASE_URL for PostgreSQL,")
+	fmt.Println("GOOGLE_CLOUD_PROJECT for Datastore, otherwise the SQLite file at -db.")
+	fmt.Println("To move a deployment, export with the old backend's settings and import")
+	fmt.Println("with the new one's.")
+}
+
+func main() {
+	dbPath := flag.String("db", database.SQLitePath, "path to the SQLite database")
+	flag.Usage = usage
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" || (command != "counts" && flag.NArg() != 2) {
+		usage()
+		os.Exit(1)
+	}
+
+	db, err := openDatabase(*dbPath)
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	switch command {
+	case "export":
+		out := io.Writer(os.Stdout)
+		if path := flag.Arg(1); path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				log.Fatal("Failed to create archive:", err)
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+		counts, err := database.ExportArchive(db, out)
+		if err != nil {
+			log.Fatal("Export failed:", err)
+		}
+		log.Printf("Exported %s", counts)
+
+	case "import":
+		in := io.Reader(os.Stdin)
+		if path := flag.Arg(1); path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				log.Fatal("Failed to open archive:", err)
+			}
+			defer func() { _ = f.Close() }()
+			in = f
+		}
+		result, err := database.ImportArchive(db, in)
+		if err != nil {
+			log.Fatal("Import failed:", err)
+		}
+		log.Printf("Restored %s", result.Restored)
+		if result.Skipped != (database.ArchiveCounts{}) {
+			log.Printf("Skipped %s (missing parents or duplicate article URLs)", result.Skipped)
+		}
+		if err := database.VerifyArchiveImport(db, result); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("Verified: the database holds exactly what was restored")
+
+	case "counts":
+		counts, err := database.CountArchiveRecords(db)
+		if err != nil {
+			log.Fatal("Failed to count rows:", err)
+		}
+		fmt.Println(counts)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		usage()
+		os.Exit(1)
+	}
+}
+
+// openDatabase picks the backend the way database.InitDB does, but honours -db
+// for SQLite.
+func openDatabase(sqlitePath string) (database.Database, error) {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return database.NewPostgresDB(databaseURL)
+	}
+	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); projectID != "" {
+		return database.NewDatastoreDB(projectID)
+	}
+
+	db, err := database.OpenSQLite(sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.CreateTables(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if _, err := db.Migrate(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func usage() {
+	fmt.Println("Usage: go run ./cmd/backfill [-dry-run] <command> [name]")
+	fmt.Println("Commands:")
+	fmt.Println("  list           - List registered backfills")
+	fmt.Println("  status <name>  - Show a backfill's progress")
+	fmt.Println("  run <name>     - Run a backfill here, batch by batch, until it finishes")
+	fmt.Println("  start <name>   - Start a backfill on App Engine via Cloud Tasks")
+	fmt.Println("  reset <name>   - Forget a backfill's progress so it starts over")
+	fmt.Println("")
+	fmt.Println("-dry-run counts what a backfill would change without writing anything.")
+	fmt.Println("Dry runs keep their own progress, separate from real runs.")
+	fmt.Println("Requires GOOGLE_CLOUD_PROJECT (and DATASTORE_EMULATOR_HOST to target the emulator).")
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report changes without writing them")
+	flag.Usage = usage
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "list" {
+		for _, b := range services.Backfills() {
+			fmt.Printf("%-24s %-12s %s\n", b.Name, b.Kind, b.Description)
+		}
+		return
+	}
+	if flag.NArg() != 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	name := flag.Arg(1)
+	backfill, ok := services.LookupBackfill(name)
+	if !ok {
+		fmt.Printf("Unknown backfill: %s (see 'list')\n", name)
+		os.Exit(1)
+	}
+
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		log.Fatal("GOOGLE_CLOUD_PROJECT must be set")
+	}
+
+	switch command {
+	case "start":
+		// The task runs on App Engine, so only a Cloud Tasks client is needed here
+		queue, err := services.NewCloudTasksQueue(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to create Cloud Tasks client: %v", err)
+		}
+		defer func() { _ = queue.Close() }()
+		uri := services.BackfillTaskURI(name, *dryRun)
+		if err := queue.Enqueue(context.Background(), uri); err != nil {
+			log.Fatalf("Failed to enqueue %s: %v", uri, err)
+		}
+		fmt.Printf("Enqueued %s; check progress with 'status %s'\n", uri, name)
+		return
+	}
+
+	db, err := database.NewDatastoreDB(projectID)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	switch command {
+	case "status":
+		progress, err := db.GetBackfillProgress(name, *dryRun)
+		if err != nil {
+			log.Fatalf("Failed to load progress: %v", err)
+		}
+		if progress == nil {
+			fmt.Printf("%s has not run\n", name)
+			return
+		}
+		printProgress(progress)
+
+	case "run":
+		for {
+			progress, err := db.RunBackfillBatch(backfill, *dryRun)
+			if err != nil {
+				log.Fatalf("Batch failed; rerun to retry from the last checkpoint: %v", err)
+			}
+			printProgress(progress)
+			if progress.Done {
+				return
+			}
+		}
+
+	case "reset":
+		if err := db.ResetBackfill(name, *dryRun); err != nil {
+			log.Fatalf("Failed to reset progress: %v", err)
+		}
+		fmt.Printf("Reset %s\n", name)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		usage()
+		os.Exit(1)
+	}
+}
+
+func printProgress(p *database.BackfillProgress) {
+	state := "in progress"
+	if p.Done {
+		state = "done"
+	}
+	verb := "changed"
+	if p.DryRun {
+		verb = "would change"
+	}
+	fmt.Printf("%s: %s, %d batches, %d scanned, %d %s\n", p.Name, state, p.Batches, p.Scanned, p.Changed, verb)
+	if p.LastError != "" {
+		fmt.Printf("  last error: %s\n", p.LastError)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func usage() {
+	fmt.Println("Usage: go run ./cmd/migrate [-db path] <command> [args]")
+	fmt.Println("Commands:")
+	fmt.Println("  status        - List migrations and whether each has been applied")
+	fmt.Println("  up            - Create missing tables and apply pending migrations")
+	fmt.Println("  down [steps]  - Roll back the most recent migrations (default 1)")
+	fmt.Println("")
+	fmt.Println("Migrations apply to the SQLite backend only. PostgreSQL creates its")
+	fmt.Println("schema idempotently at startup and Datastore has no schema.")
+}
+
+func main() {
+	dbPath := flag.String("db", database.SQLitePath, "path to the SQLite database")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(1)
+	}
+
+	db, err := database.OpenSQLite(*dbPath)
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	switch command := flag.Arg(0); command {
+	case "status":
+		showStatus(db)
+
+	case "up":
+		if err := db.CreateTables(); err != nil {
+			log.Fatal("Failed to create tables:", err)
+		}
+		count, err := db.Migrate()
+		if err != nil {
+			log.Fatalf("Applied %d migration(s) before failing: %v", count, err)
+		}
+		fmt.Printf("Applied %d migration(s)\n", count)
+
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps, err = strconv.Atoi(flag.Arg(1))
+			if err != nil || steps < 1 {
+				fmt.Println("Usage: go run ./cmd/migrate down [steps]")
+				os.Exit(1)
+			}
+		}
+		count, err := db.Rollback(steps)
+		if err != nil {
+			log.Fatalf("Rolled back %d migration(s) before failing: %v", count, err)
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", count)
+
+	default:
+		fmt.Printf("Unknown command: %s\n", command)
+		usage()
+		os.Exit(1)
+	}
+}
+
+func showStatus(db *database.DB) {
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		log.Fatal("Failed to read migration status:", err)
+	}
+
+	fmt.Printf("%-8s %-20s %s\n", "VERSION", "APPLIED", "DESCRIPTION")
+	pending := 0
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		fmt.Printf("%-8d %-20s %s\n", s.Version, applied, s.Description)
+	}
+	fmt.Printf("\n%d of %d migration(s) pending\n", pending, len(statuses))
+}
@@ -2,18 +2,19 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"log"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/jeffreyp/goread2/internal/config"
 	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/mail"
 	"github.com/jeffreyp/goread2/internal/secrets"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 // redactEmail redacts an email address for logging, keeping only first char and domain
@@ -36,28 +37,54 @@ func redactEmail(email string) string {
 	return string(email[0]) + "***" + email[atIndex:]
 }
 
+// Sign-in errors. ErrEmailNotVerified and ErrEmailRequired are shown to the
+// user, so their messages say what to do next.
+var (
+	ErrUnknownProvider  = errors.New("unknown sign-in provider")
+	ErrEmailNotVerified = errors.New("this email address isn't verified with that provider; sign in with the method you used before")
+	ErrEmailRequired    = errors.New("the provider didn't share an email address; make one visible to this app and try again")
+)
+
 type AuthService struct {
-	db     database.Database
-	config *oauth2.Config
+	db database.Database
+
+	// providers holds the enabled OAuth providers by ID, and order is the
+	// order they appear on the login page.
+	providers map[string]Provider
+	order     []string
+
+	// redirectURL is the production OAuth redirect URL (GOOGLE_REDIRECT_URL).
+	// Every provider calls back to the same /auth/callback path, so one URL
+	// is registered with each of them.
+	redirectURL string
 
 	// redirectURLsByHost maps a request's Host header to the OAuth redirect
-	// URL registered for that host in the Google OAuth client. Only hosts
-	// derived from GOOGLE_REDIRECT_URL (production) and STAGING_REDIRECT_URL
+	// URL registered for that host with the providers. Only hosts derived
+	// from GOOGLE_REDIRECT_URL (production) and STAGING_REDIRECT_URL
 	// (staging) are ever present, so an unrecognized or spoofed Host header
-	// simply falls through to the production default in config.RedirectURL —
+	// simply falls through to the production default in redirectURL —
 	// it can never select an arbitrary attacker-supplied URL.
 	redirectURLsByHost map[string]string
-}
 
-type GoogleUserInfo struct {
-	ID      string `json:"id"`
-	Email   string `json:"email"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
+	// configErrors records providers that are only partly configured, for
+	// ValidateConfig to report. googleFallback is set when Google was
+	// enabled only because nothing else was configured.
+	configErrors   []error
+	googleFallback bool
+
+	// mailer and baseURL are set by EnableEmailSignIn.
+	mailer  mail.Mailer
+	baseURL string
 }
 
 func NewAuthService(db database.Database) *AuthService {
 	ctx := context.Background()
+	a := &AuthService{
+		db:          db,
+		providers:   make(map[string]Provider),
+		redirectURL: os.Getenv("GOOGLE_REDIRECT_URL"),
+	}
+	a.redirectURLsByHost = buildRedirectURLsByHost(a.redirectURL, os.Getenv("STAGING_REDIRECT_URL"))
 
 	// Get OAuth credentials from secrets or environment
 	clientID, clientSecret, err := secrets.GetOAuthCredentials(ctx)
@@ -67,27 +94,56 @@ func NewAuthService(db database.Database) *AuthService {
 		clientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
 	}
 
-	config := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+	githubID, githubSecret := os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET")
+	if githubID != "" || githubSecret != "" {
+		a.addProvider(ProviderGitHub, newGitHubProvider(githubID, githubSecret),
+			requireEnv("GITHUB_CLIENT_ID", githubID), requireEnv("GITHUB_CLIENT_SECRET", githubSecret))
+	}
+
+	issuer, oidcID, oidcSecret := os.Getenv("OIDC_ISSUER_URL"), os.Getenv("OIDC_CLIENT_ID"), os.Getenv("OIDC_CLIENT_SECRET")
+	if issuer != "" || oidcID != "" || oidcSecret != "" {
+		a.addProvider(ProviderOIDC, newOIDCProvider(issuer, oidcID, oidcSecret, os.Getenv("OIDC_DISPLAY_NAME")),
+			requireEnv("OIDC_ISSUER_URL", issuer), requireEnv("OIDC_CLIENT_ID", oidcID), requireEnv("OIDC_CLIENT_SECRET", oidcSecret))
+	}
+
+	// Google stays the default: it's enabled whenever it has credentials,
+	// and also when nothing else is, so an unconfigured deployment still
+	// reports the Google settings it's missing.
+	if clientID != "" || clientSecret != "" || len(a.providers) == 0 {
+		a.googleFallback = clientID == "" && clientSecret == ""
+		a.addProvider(ProviderGoogle, newGoogleProvider(clientID, clientSecret, a.redirectURL),
+			requireEnv("GOOGLE_CLIENT_ID", clientID), requireEnv("GOOGLE_CLIENT_SECRET", clientSecret))
+	}
+	return a
+}
+
+func requireEnv(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s environment variable is required", name)
 	}
+	return nil
+}
 
-	return &AuthService{
-		db:                 db,
-		config:             config,
-		redirectURLsByHost: buildRedirectURLsByHost(config.RedirectURL, os.Getenv("STAGING_REDIRECT_URL")),
+// addProvider enables a provider, recording any missing settings.
+func (a *AuthService) addProvider(id string, p Provider, checks ...error) {
+	a.providers[id] = p
+	for _, err := range checks {
+		if err != nil {
+			a.configErrors = append(a.configErrors, err)
+		}
+	}
+	// Keep the login page order stable regardless of registration order
+	a.order = a.order[:0]
+	for _, known := range []string{ProviderGoogle, ProviderGitHub, ProviderOIDC} {
+		if _, ok := a.providers[known]; ok {
+			a.order = append(a.order, known)
+		}
 	}
 }
 
 // buildRedirectURLsByHost derives a Host -> redirect URL allowlist from the
 // production and staging redirect URLs. Both must be exact, pre-registered
-// redirect URIs on the Google OAuth client (Google rejects anything else).
+// redirect URIs with every enabled provider (they reject anything else).
 func buildRedirectURLsByHost(redirectURLs ...string) map[string]string {
 	byHost := make(map[string]string, len(redirectURLs))
 	for _, redirectURL := range redirectURLs {
@@ -104,85 +160,208 @@ func buildRedirectURLsByHost(redirectURLs ...string) map[string]string {
 	return byHost
 }
 
-// configForHost returns the OAuth config to use for a request, with
-// RedirectURL selected by matching host against the allowlist. Unrecognized
-// hosts (including local dev) fall back to the production default.
-func (a *AuthService) configForHost(host string) oauth2.Config {
-	cfg := *a.config
+// redirectURLForHost returns the OAuth redirect URL to use for a request,
+// selected by matching host against the allowlist. Unrecognized hosts
+// (including local dev) fall back to the production default.
+func (a *AuthService) redirectURLForHost(host string) string {
 	if redirectURL, ok := a.redirectURLsByHost[host]; ok {
-		cfg.RedirectURL = redirectURL
+		return redirectURL
 	}
-	return cfg
+	return a.redirectURL
 }
 
-// GetAuthURL builds the Google OAuth consent URL. host is the incoming
-// request's Host header, used to pick the matching registered redirect URI
-// (production vs. staging) — see configForHost.
-func (a *AuthService) GetAuthURL(state, host string) string {
-	cfg := a.configForHost(host)
-	return cfg.AuthCodeURL(state, oauth2.AccessTypeOffline)
+// Providers lists the enabled sign-in methods in login page order.
+func (a *AuthService) Providers() []ProviderInfo {
+	infos := make([]ProviderInfo, 0, len(a.order)+1)
+	for _, id := range a.order {
+		infos = append(infos, ProviderInfo{ID: id, Label: a.providers[id].Label()})
+	}
+	if a.EmailSignInEnabled() {
+		infos = append(infos, ProviderInfo{ID: ProviderEmail, Label: "Email"})
+	}
+	return infos
 }
 
-// HandleCallback exchanges the OAuth code for a user. host must match the
-// Host used when GetAuthURL generated the original auth request, since
-// Google's token exchange requires the redirect_uri to match exactly.
-func (a *AuthService) HandleCallback(code, host string) (*database.User, error) {
-	ctx := context.Background()
-	cfg := a.configForHost(host)
+// DefaultProvider is the provider /auth/login uses when none is named:
+// Google if enabled, for clients that predate the provider parameter.
+func (a *AuthService) DefaultProvider() string {
+	if len(a.order) == 0 {
+		return ProviderGoogle
+	}
+	return a.order[0]
+}
 
-	// Exchange code for token
-	token, err := cfg.Exchange(ctx, code)
-	if err != nil {
-		return nil, fmt.Errorf("failed to exchange code for token (code length: %d): %w", len(code), err)
+// HasProvider reports whether an OAuth provider is enabled.
+func (a *AuthService) HasProvider(provider string) bool {
+	_, ok := a.providers[provider]
+	return ok
+}
+
+// GetAuthURL builds the consent URL for provider. host is the incoming
+// request's Host header, used to pick the matching registered redirect URI
+// (production vs. staging) — see redirectURLForHost.
+func (a *AuthService) GetAuthURL(provider, state, host string) (string, error) {
+	p, ok := a.providers[provider]
+	if !ok {
+		return "", ErrUnknownProvider
 	}
+	return p.AuthCodeURL(context.Background(), state, a.redirectURLForHost(host))
+}
 
-	// Get user info from Google
-	client := cfg.Client(ctx, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+// HandleCallback exchanges the OAuth code from provider for a user. host
+// must match the Host used when GetAuthURL generated the original auth
+// request, since the token exchange requires the redirect_uri to match
+// exactly.
+func (a *AuthService) HandleCallback(provider, code, state, host string) (*database.User, error) {
+	p, ok := a.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
 	}
-	if resp.StatusCode != 200 {
-		_ = resp.Body.Close()
-		return nil, fmt.Errorf("failed to get user info: unexpected status %d", resp.StatusCode)
+	identity, err := p.Exchange(context.Background(), code, state, a.redirectURLForHost(host))
+	if err != nil {
+		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
+	return a.resolveUser(identity)
+}
 
-	var googleUser GoogleUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		return nil, fmt.Errorf("failed to decode user info: %w", err)
+// legacyGoogleID is the users.google_id value for an identity. The column
+// predates other providers and is unique, so Google keeps its bare subject
+// and other providers' subjects are prefixed with the provider ID.
+func legacyGoogleID(identity *Identity) string {
+	if identity.Provider == ProviderGoogle {
+		return identity.Subject
 	}
+	return identity.Provider + ":" + identity.Subject
+}
 
-	// Check if user exists in our database
-	user, err := a.db.GetUserByGoogleID(googleUser.ID)
+// resolveUser finds or creates the account for a provider identity.
+//
+// An identity already linked signs in to its account. Otherwise an account
+// with the same email address is linked, but only when the provider has
+// verified the address: linking on an unverified one would let anyone who
+// types a victim's address into a provider take over their account. New
+// accounts need a verified address for the same reason, since a later
+// sign-in would link to it. Addresses are matched in normalized form, so
+// case differences between providers don't split an account.
+func (a *AuthService) resolveUser(identity *Identity) (*database.User, error) {
+	identity.Email = database.NormalizeEmail(identity.Email)
+
+	link, err := a.db.GetUserIdentity(identity.Provider, identity.Subject)
 	if err != nil {
-		// User doesn't exist, create new user
-		user = &database.User{
-			GoogleID:  googleUser.ID,
-			Email:     googleUser.Email,
-			Name:      googleUser.Name,
-			Avatar:    googleUser.Picture,
-			CreatedAt: time.Now(),
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+	if link != nil {
+		user, err := a.db.GetUserByID(link.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user for identity: %w", err)
 		}
+		return user, nil
+	}
 
-		if err := a.db.CreateUser(user); err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
+	// Accounts created before identities were linked are found by their
+	// google_id, and linked now so the next sign-in takes the path above
+	user, err := a.db.GetUserByGoogleID(legacyGoogleID(identity))
+	if err != nil || user == nil {
+		if identity.Email == "" {
+			return nil, ErrEmailRequired
+		}
+		existing, err := a.db.GetUserByEmail(identity.Email)
+		if err == nil && existing != nil {
+			if !identity.EmailVerified {
+				return nil, ErrEmailNotVerified
+			}
+			user = existing
+		} else {
+			if !identity.EmailVerified {
+				return nil, ErrEmailNotVerified
+			}
+			user = &database.User{
+				GoogleID:  legacyGoogleID(identity),
+				Email:     identity.Email,
+				Name:      identity.Name,
+				Avatar:    identity.Avatar,
+				CreatedAt: time.Now(),
+			}
+			if err := a.db.CreateUser(user); err != nil {
+				return nil, fmt.Errorf("failed to create user: %w", err)
+			}
 		}
 	}
 
+	if err := a.db.CreateUserIdentity(&database.UserIdentity{
+		UserID:    user.ID,
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
 	return user, nil
 }
 
-func (a *AuthService) ValidateConfig() error {
-	if a.config.ClientID == "" {
-		return fmt.Errorf("GOOGLE_CLIENT_ID environment variable is required")
+// EnableEmailSignIn turns on passwordless sign-in, sending links through
+// mailer. baseURL is the public origin the links point at.
+func (a *AuthService) EnableEmailSignIn(mailer mail.Mailer, baseURL string) {
+	a.mailer = mailer
+	a.baseURL = strings.TrimSuffix(baseURL, "/")
+
+	// Email links are enough on their own, so the unconfigured Google
+	// fallback no longer needs to stand in
+	if a.googleFallback {
+		delete(a.providers, ProviderGoogle)
+		a.order, a.configErrors, a.googleFallback = nil, nil, false
 	}
-	if a.config.ClientSecret == "" {
-		return fmt.Errorf("GOOGLE_CLIENT_SECRET environment variable is required")
+}
+
+// EmailSignInEnabled reports whether sign-in links can be sent.
+func (a *AuthService) EmailSignInEnabled() bool {
+	return a.mailer != nil
+}
+
+// SendSignInLink emails a one-time sign-in link carrying token.
+func (a *AuthService) SendSignInLink(ctx context.Context, email, token string) error {
+	if a.mailer == nil {
+		return ErrUnknownProvider
 	}
-	if a.config.RedirectURL == "" {
+	link := a.baseURL + "/auth/email/verify?token=" + url.QueryEscape(token)
+	text := "Use this link to sign in to GoRead2:\n\n" + link +
+		"\n\nThe link works once and expires in 15 minutes. If you didn't ask to sign in, you can ignore this email.\n"
+	html := `<p>Use this link to sign in to GoRead2:</p><p><a href="` + template.HTMLEscapeString(link) + `">Sign in</a></p>` +
+		`<p>The link works once and expires in 15 minutes. If you didn't ask to sign in, you can ignore this email.</p>`
+	return a.mailer.Send(ctx, mail.Message{To: email, Subject: "Your GoRead2 sign-in link", Text: text, HTML: html})
+}
+
+// SignInWithEmail resolves the account for an address whose sign-in link
+// was just redeemed. Following the link proves the address, so it counts
+// as verified.
+func (a *AuthService) SignInWithEmail(email string) (*database.User, error) {
+	email = database.NormalizeEmail(email)
+	name := email
+	if at := strings.Index(email, "@"); at > 0 {
+		name = email[:at]
+	}
+	return a.resolveUser(&Identity{
+		Provider:      ProviderEmail,
+		Subject:       email,
+		Email:         email,
+		EmailVerified: true,
+		Name:          name,
+	})
+}
+
+// ValidateConfig reports missing settings for the enabled sign-in methods.
+// The redirect URL is only needed when an OAuth provider is enabled.
+func (a *AuthService) ValidateConfig() error {
+	if len(a.configErrors) > 0 {
+		return a.configErrors[0]
+	}
+	if len(a.providers) > 0 && a.redirectURL == "" {
 		return fmt.Errorf("GOOGLE_REDIRECT_URL environment variable is required")
 	}
+	if len(a.providers) == 0 && !a.EmailSignInEnabled() {
+		return errors.New("no sign-in method is configured")
+	}
 	return nil
 }
 
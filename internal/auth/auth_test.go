@@ -118,17 +118,18 @@ func TestNewAuthService(t *testing.T) {
 		return
 	}
 
-	if authService.config == nil {
-		t.Error("AuthService OAuth config not set")
+	google, ok := authService.providers[ProviderGoogle].(*googleProvider)
+	if !ok {
+		t.Error("AuthService Google provider not set")
 		return
 	}
 
-	if authService.config.ClientID != "test_client_id_123" {
-		t.Errorf("ClientID = %s, want test_client_id_123", authService.config.ClientID)
+	if google.config.ClientID != "test_client_id_123" {
+		t.Errorf("ClientID = %s, want test_client_id_123", google.config.ClientID)
 	}
 
-	if authService.config.ClientSecret != "test_client_secret_456" {
-		t.Errorf("ClientSecret = %s, want test_client_secret_456", authService.config.ClientSecret)
+	if google.config.ClientSecret != "test_client_secret_456" {
+		t.Errorf("ClientSecret = %s, want test_client_secret_456", google.config.ClientSecret)
 	}
 }
 
@@ -147,7 +148,10 @@ func TestGetAuthURL(t *testing.T) {
 	authService := NewAuthService(db)
 
 	state := "test_state_123"
-	authURL := authService.GetAuthURL(state, "localhost:8080")
+	authURL, err := authService.GetAuthURL(ProviderGoogle, state, "localhost:8080")
+	if err != nil {
+		t.Fatalf("GetAuthURL: %v", err)
+	}
 
 	if authURL == "" {
 		t.Error("GetAuthURL returned empty string")
@@ -192,7 +196,7 @@ func TestGetAuthURL_HostAwareRedirect(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			authURL := authService.GetAuthURL("test_state", tt.host)
+			authURL, _ := authService.GetAuthURL(ProviderGoogle, "test_state", tt.host)
 			wantParam := "redirect_uri=" + url.QueryEscape(tt.wantRedirect)
 			if !contains(authURL, wantParam) {
 				t.Errorf("GetAuthURL(host=%s) = %s, want redirect_uri %s", tt.host, authURL, tt.wantRedirect)
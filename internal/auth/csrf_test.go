@@ -232,6 +232,38 @@ func TestCSRFMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("POST with an access token skips the CSRF check", func(t *testing.T) {
+		session, _ := sessionManager.CreateSession(&database.User{ID: 1})
+		tokens, err := sessionManager.IssueTokens(session)
+		if err != nil {
+			t.Fatalf("Failed to issue tokens: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/test", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+		middleware.CSRFMiddleware(csrfManager)(c)
+
+		if c.IsAborted() {
+			t.Errorf("Expected request not to be aborted, got %d", w.Code)
+		}
+	})
+
+	t.Run("POST with an invalid access token returns unauthorized", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/test", nil)
+		c.Request.Header.Set("Authorization", "Bearer not-a-token")
+
+		middleware.CSRFMiddleware(csrfManager)(c)
+
+		if w.Code != 401 {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+	})
+
 	t.Run("POST without session returns unauthorized", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
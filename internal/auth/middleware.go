@@ -34,8 +34,9 @@ func requestTraceID(c *gin.Context) string {
 type contextKey string
 
 const (
-	UserContextKey    contextKey = "user"
-	sessionContextKey contextKey = "session"
+	UserContextKey         contextKey = "user"
+	sessionContextKey      contextKey = "session"
+	accessClaimsContextKey contextKey = "access_claims"
 )
 
 type Middleware struct {
@@ -67,9 +68,84 @@ func (m *Middleware) getOrLoadSession(c *gin.Context) (*Session, bool) {
 	return session, exists
 }
 
-// RequireAuth is a middleware that requires authentication
+// recordActivity extends the session's expiry for active users and notes
+// when and from where it was last used, for the account page's device list.
+func (m *Middleware) recordActivity(c *gin.Context, session *Session) {
+	_ = m.sessionManager.RefreshSession(session.ID)
+	if err := m.sessionManager.TouchSession(session, GetSecureClientIP(c)); err != nil {
+		log.Printf("Failed to record session activity: %v", err)
+	}
+}
+
+// CurrentSessionHandle returns the SessionHandle of the session that
+// authenticated the request, whether by cookie or by access token, once an
+// auth middleware has run.
+func CurrentSessionHandle(c *gin.Context) (string, bool) {
+	if claims, ok := CurrentAccessClaims(c); ok {
+		return claims.Session, true
+	}
+	if v, ok := c.Get(string(sessionContextKey)); ok {
+		if s, ok := v.(*Session); ok && s != nil {
+			return SessionHandle(s.ID), true
+		}
+	}
+	return "", false
+}
+
+// CurrentAccessClaims returns the claims of the access token that
+// authenticated the request, if it was authenticated by one.
+func CurrentAccessClaims(c *gin.Context) (*AccessClaims, bool) {
+	if v, ok := c.Get(string(accessClaimsContextKey)); ok {
+		claims, ok := v.(*AccessClaims)
+		return claims, ok
+	}
+	return nil, false
+}
+
+// authenticateAccessToken handles a request carrying a bearer access
+// token, which takes the place of the session cookie for mobile clients.
+// It reports whether the request had one, and whether it was valid; a
+// valid token's user and claims are added to the context.
+func (m *Middleware) authenticateAccessToken(c *gin.Context) (present, valid bool) {
+	token, ok := accessTokenFromRequest(c.Request)
+	if !ok {
+		return false, false
+	}
+	// Already checked by an earlier middleware in this request
+	if _, ok := c.Get(string(accessClaimsContextKey)); ok {
+		return true, true
+	}
+	user, claims, err := m.sessionManager.AccessTokenUser(token)
+	if err != nil {
+		return true, false
+	}
+	c.Set(string(accessClaimsContextKey), claims)
+	c.Set(string(UserContextKey), user)
+	return true, true
+}
+
+// rejectAccessToken answers a request whose access token didn't verify.
+// The WWW-Authenticate header tells the client to refresh it rather than
+// sign in again.
+func rejectAccessToken(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Your access token is invalid or has expired."})
+	c.Abort()
+}
+
+// RequireAuth is a middleware that requires authentication, by session
+// cookie or by access token
 func (m *Middleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if present, valid := m.authenticateAccessToken(c); present {
+			if !valid {
+				rejectAccessToken(c)
+				return
+			}
+			c.Next()
+			return
+		}
+
 		session, exists := m.getOrLoadSession(c)
 		if !exists {
 			traceID := requestTraceID(c)
@@ -80,8 +156,7 @@ func (m *Middleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
-		// Refresh session to extend expiry for active users
-		_ = m.sessionManager.RefreshSession(session.ID)
+		m.recordActivity(c, session)
 
 		// Add user to context
 		c.Set(string(UserContextKey), session.User)
@@ -100,8 +175,7 @@ func (m *Middleware) RequireAuthPage() gin.HandlerFunc {
 			return
 		}
 
-		// Refresh session to extend expiry for active users
-		_ = m.sessionManager.RefreshSession(session.ID)
+		m.recordActivity(c, session)
 
 		// Add user to context
 		c.Set(string(UserContextKey), session.User)
@@ -112,10 +186,15 @@ func (m *Middleware) RequireAuthPage() gin.HandlerFunc {
 // OptionalAuth is a middleware that adds user to context if authenticated
 func (m *Middleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// An invalid access token leaves the request anonymous, so the
+		// client sees a 401 and refreshes it
+		if present, _ := m.authenticateAccessToken(c); present {
+			c.Next()
+			return
+		}
 		session, exists := m.getOrLoadSession(c)
 		if exists {
-			// Refresh session to extend expiry for active users
-			_ = m.sessionManager.RefreshSession(session.ID)
+			m.recordActivity(c, session)
 			c.Set(string(UserContextKey), session.User)
 		}
 		c.Next()
@@ -144,8 +223,7 @@ func (m *Middleware) RequireAdmin() gin.HandlerFunc {
 			return
 		}
 
-		// Refresh session to extend expiry for active users
-		_ = m.sessionManager.RefreshSession(session.ID)
+		m.recordActivity(c, session)
 
 		// Add user to context
 		c.Set(string(UserContextKey), session.User)
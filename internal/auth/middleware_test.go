@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -161,6 +162,50 @@ func TestRequireAuth(t *testing.T) {
 			t.Errorf("Wrong user in context: got %d, want %d", retrievedUser.ID, user.ID)
 		}
 	})
+
+	t.Run("valid access token adds user to context", func(t *testing.T) {
+		session, _ := sessionManager.CreateSession(&database.User{ID: 2})
+		tokens, err := sessionManager.IssueTokens(session)
+		if err != nil {
+			t.Fatalf("Failed to issue tokens: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/feeds", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+		middleware.RequireAuth()(c)
+
+		if c.IsAborted() {
+			t.Fatalf("Expected request not to be aborted, got %d", w.Code)
+		}
+		if user, _ := GetUserFromContext(c); user == nil || user.ID != 2 {
+			t.Errorf("Wrong user in context: %+v", user)
+		}
+		if handle, _ := CurrentSessionHandle(c); handle != SessionHandle(session.ID) {
+			t.Error("Expected the token's session to be current")
+		}
+	})
+
+	t.Run("invalid access token is rejected even with a session cookie", func(t *testing.T) {
+		session, _ := sessionManager.CreateSession(&database.User{ID: 1})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/feeds", nil)
+		c.Request.Header.Set("Authorization", "Bearer not-a-token")
+		c.Request.AddCookie(&http.Cookie{Name: "session_id_local", Value: session.ID})
+
+		middleware.RequireAuth()(c)
+
+		if w.Code != 401 {
+			t.Errorf("Expected status 401, got %d", w.Code)
+		}
+		if !strings.Contains(w.Header().Get("WWW-Authenticate"), "invalid_token") {
+			t.Errorf("Expected an invalid_token challenge, got %q", w.Header().Get("WWW-Authenticate"))
+		}
+	})
 }
 
 func TestRequireAuthPage(t *testing.T) {
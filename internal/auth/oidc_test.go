@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// fakeIssuer is an OpenID provider serving discovery, keys and a token
+// endpoint that returns whatever ID token the test sets.
+type fakeIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	idToken string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	f := &fakeIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.server.URL,
+			"authorization_endpoint": f.server.URL + "/authorize",
+			"token_endpoint":         f.server.URL + "/token",
+			"jwks_uri":               f.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		enc := base64.RawURLEncoding
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA", "kid": "key-1", "use": "sig",
+				"n": enc.EncodeToString(key.N.Bytes()),
+				"e": enc.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access", "token_type": "Bearer", "id_token": f.idToken,
+		})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// sign builds an RS256 ID token over claims, signed with the issuer's key
+// unless signer says otherwise.
+func (f *fakeIssuer) sign(t *testing.T, claims map[string]interface{}, signer *rsa.PrivateKey) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "key-1", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, signer, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15: %v", err)
+	}
+	return signed + "." + enc.EncodeToString(sig)
+}
+
+func (f *fakeIssuer) claims(state string) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            f.server.URL,
+		"sub":            "user-1",
+		"aud":            []string{"client-1"},
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          oidcNonce(state),
+		"email":          "ann@example.com",
+		"email_verified": "true",
+		"name":           "Ann",
+	}
+}
+
+func TestOIDCProviderAuthCodeURL(t *testing.T) {
+	f := newFakeIssuer(t)
+	p := newOIDCProvider(f.server.URL, "client-1", "secret", "")
+
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", "https://reader.example.com/auth/callback")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("bad auth URL %q: %v", authURL, err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("scope") != "openid email profile" || q.Get("nonce") != oidcNonce("state-1") {
+		t.Errorf("unexpected auth URL %s", authURL)
+	}
+	if p.Label() != "Single sign-on" {
+		t.Errorf("expected the default label, got %q", p.Label())
+	}
+}
+
+func TestOIDCProviderExchange(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(claims map[string]interface{})
+		signer  func(f *fakeIssuer) *rsa.PrivateKey
+		wantErr bool
+	}{
+		{name: "valid token"},
+		{name: "wrong audience", modify: func(c map[string]interface{}) { c["aud"] = "client-2" }, wantErr: true},
+		{name: "wrong issuer", modify: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "expired", modify: func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "nonce from another sign-in", modify: func(c map[string]interface{}) { c["nonce"] = oidcNonce("other") }, wantErr: true},
+		{name: "missing subject", modify: func(c map[string]interface{}) { delete(c, "sub") }, wantErr: true},
+		{name: "signed by another key", signer: func(*fakeIssuer) *rsa.PrivateKey { return otherKey }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			claims := f.claims("state-1")
+			if tt.modify != nil {
+				tt.modify(claims)
+			}
+			signer := f.key
+			if tt.signer != nil {
+				signer = tt.signer(f)
+			}
+			f.idToken = f.sign(t, claims, signer)
+
+			p := newOIDCProvider(f.server.URL, "client-1", "secret", "Acme SSO")
+			identity, err := p.Exchange(context.Background(), "code", "state-1", "https://reader.example.com/auth/callback")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected ErrInvalidIDToken, got %v (identity %+v)", err, identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Provider != ProviderOIDC || identity.Subject != "user-1" || identity.Email != "ann@example.com" ||
+				!identity.EmailVerified || identity.Name != "Ann" {
+				t.Errorf("unexpected identity %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderRejectsSymmetricAlgorithms(t *testing.T) {
+	// "none" and the HMAC family would let anyone who knows the client
+	// secret, or no one at all, mint tokens
+	f := newFakeIssuer(t)
+	enc := base64.RawURLEncoding
+	payload, _ := json.Marshal(f.claims("state-1"))
+	for _, alg := range []string{"none", "HS256"} {
+		t.Run(alg, func(t *testing.T) {
+			header, _ := json.Marshal(map[string]string{"alg": alg, "kid": "key-1", "typ": "JWT"})
+			signed := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+			var sig []byte
+			if alg == "HS256" {
+				mac := hmac.New(sha256.New, []byte("secret"))
+				mac.Write([]byte(signed))
+				sig = mac.Sum(nil)
+			}
+			f.idToken = signed + "." + enc.EncodeToString(sig)
+
+			p := newOIDCProvider(f.server.URL, "client-1", "secret", "")
+			if _, err := p.Exchange(context.Background(), "code", "state-1", "https://reader.example.com/auth/callback"); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+	"golang.org/x/oauth2/google"
+)
+
+// Sign-in provider IDs, as used in /auth/login?provider= and stored on
+// linked identities.
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+	ProviderOIDC   = "oidc"
+	ProviderEmail  = "email"
+)
+
+// Identity is the account a provider vouched for at the end of a sign-in.
+// Subject is the provider's stable ID for the account; emails and names can
+// change.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Avatar        string
+}
+
+// Provider is an OAuth 2.0 sign-in method. Every provider redirects back to
+// /auth/callback, so the redirect URL is shared and chosen per request by
+// AuthService. state is the one-time OAuth state for the sign-in.
+type Provider interface {
+	// Label is the name shown on the sign-in button.
+	Label() string
+	AuthCodeURL(ctx context.Context, state, redirectURL string) (string, error)
+	Exchange(ctx context.Context, code, state, redirectURL string) (*Identity, error)
+}
+
+// ProviderInfo describes an enabled sign-in method for the login page.
+type ProviderInfo struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// withRedirect returns a copy of cfg that redirects to redirectURL.
+func withRedirect(cfg *oauth2.Config, redirectURL string) *oauth2.Config {
+	c := *cfg
+	c.RedirectURL = redirectURL
+	return &c
+}
+
+// getJSON fetches url with client and decodes the JSON response into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type GoogleUserInfo struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+// googleProvider signs in with Google accounts. It was the only provider
+// before linked identities, so its users' google_id column holds the
+// subject directly.
+type googleProvider struct {
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+func newGoogleProvider(clientID, clientSecret, redirectURL string) *googleProvider {
+	return &googleProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Scopes: []string{
+				"https://www.googleapis.com/auth/userinfo.email",
+				"https://www.googleapis.com/auth/userinfo.profile",
+			},
+			Endpoint: google.Endpoint,
+		},
+		userInfoURL: "https://www.googleapis.com/oauth2/v2/userinfo",
+	}
+}
+
+func (p *googleProvider) Label() string { return "Google" }
+
+func (p *googleProvider) AuthCodeURL(_ context.Context, state, redirectURL string) (string, error) {
+	return withRedirect(p.config, redirectURL).AuthCodeURL(state, oauth2.AccessTypeOffline), nil
+}
+
+func (p *googleProvider) Exchange(ctx context.Context, code, _, redirectURL string) (*Identity, error) {
+	cfg := withRedirect(p.config, redirectURL)
+	token, err := cfg.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token (code length: %d): %w", len(code), err)
+	}
+
+	var googleUser GoogleUserInfo
+	if err := getJSON(ctx, cfg.Client(ctx, token), p.userInfoURL, &googleUser); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if googleUser.ID == "" {
+		return nil, fmt.Errorf("failed to get user info: no account ID")
+	}
+	return &Identity{
+		Provider:      ProviderGoogle,
+		Subject:       googleUser.ID,
+		Email:         googleUser.Email,
+		EmailVerified: googleUser.VerifiedEmail,
+		Name:          googleUser.Name,
+		Avatar:        googleUser.Picture,
+	}, nil
+}
+
+// githubProvider signs in with GitHub accounts. GitHub isn't an OpenID
+// provider, so the profile and verified addresses come from its REST API.
+type githubProvider struct {
+	config *oauth2.Config
+	apiURL string
+}
+
+func newGitHubProvider(clientID, clientSecret string) *githubProvider {
+	return &githubProvider{
+		config: &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint:     github.Endpoint,
+		},
+		apiURL: "https://api.github.com",
+	}
+}
+
+func (p *githubProvider) Label() string { return "GitHub" }
+
+func (p *githubProvider) AuthCodeURL(_ context.Context, state, redirectURL string) (string, error) {
+	return withRedirect(p.config, redirectURL).AuthCodeURL(state), nil
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, _, redirectURL string) (*Identity, error) {
+	cfg := withRedirect(p.config, redirectURL)
+	token, err := cfg.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token (code length: %d): %w", len(code), err)
+	}
+	client := cfg.Client(ctx, token)
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user", &user); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("failed to get user info: no account ID")
+	}
+
+	// The profile email is whatever the user chose to make public; the
+	// emails endpoint says which addresses GitHub has verified
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user/emails", &emails); err != nil {
+		return nil, fmt.Errorf("failed to get email addresses: %w", err)
+	}
+
+	identity := &Identity{
+		Provider: ProviderGitHub,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+		Avatar:   user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	// Prefer a verified address, and the primary one among equals
+	best := -1
+	for _, e := range emails {
+		rank := 0
+		if e.Verified {
+			rank += 2
+		}
+		if e.Primary {
+			rank++
+		}
+		if rank > best {
+			best = rank
+			identity.Email, identity.EmailVerified = e.Email, e.Verified
+		}
+	}
+	return identity, nil
+}
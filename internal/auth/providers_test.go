@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/mail"
+	"golang.org/x/oauth2"
+)
+
+// newIdentityTestDB returns an in-memory SQLite database, since linking
+// identities depends on the real lookups rather than mock behavior.
+func newIdentityTestDB(t *testing.T) *database.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db := &database.DB{DB: conn}
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// recordingMailer keeps sent messages for inspection.
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestGitHubProviderExchange(t *testing.T) {
+	tests := []struct {
+		name         string
+		emails       string
+		wantEmail    string
+		wantVerified bool
+	}{
+		{
+			name:         "verified primary wins",
+			emails:       `[{"email":"old@example.com","primary":false,"verified":true},{"email":"me@example.com","primary":true,"verified":true}]`,
+			wantEmail:    "me@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "verified beats unverified primary",
+			emails:       `[{"email":"new@example.com","primary":true,"verified":false},{"email":"me@example.com","primary":false,"verified":true}]`,
+			wantEmail:    "me@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "only unverified",
+			emails:       `[{"email":"new@example.com","primary":true,"verified":false}]`,
+			wantEmail:    "new@example.com",
+			wantVerified: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"access_token":"gh-token","token_type":"bearer"}`))
+			})
+			mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer gh-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte(`{"id":4242,"login":"octo","name":"","avatar_url":"https://avatars.example.com/octo"}`))
+			})
+			mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.emails))
+			})
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			p := newGitHubProvider("gh-id", "gh-secret")
+			p.config.Endpoint = oauth2.Endpoint{TokenURL: server.URL + "/login/oauth/access_token"}
+			p.apiURL = server.URL
+
+			identity, err := p.Exchange(context.Background(), "code", "state", "http://localhost/auth/callback")
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Provider != ProviderGitHub || identity.Subject != "4242" {
+				t.Errorf("unexpected identity %+v", identity)
+			}
+			if identity.Name != "octo" {
+				t.Errorf("expected the login used when the name is empty, got %q", identity.Name)
+			}
+			if identity.Email != tt.wantEmail || identity.EmailVerified != tt.wantVerified {
+				t.Errorf("got email %q verified=%v, want %q verified=%v",
+					identity.Email, identity.EmailVerified, tt.wantEmail, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestProvidersFromEnvironment(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "")
+	t.Setenv("GOOGLE_REDIRECT_URL", "https://reader.example.com/auth/callback")
+	t.Setenv("GITHUB_CLIENT_ID", "gh-id")
+	t.Setenv("GITHUB_CLIENT_SECRET", "gh-secret")
+
+	a := NewAuthService(newMockDBForAuth())
+	if a.HasProvider(ProviderGoogle) {
+		t.Error("expected Google disabled when only GitHub is configured")
+	}
+	if a.DefaultProvider() != ProviderGitHub {
+		t.Errorf("expected GitHub as the default, got %q", a.DefaultProvider())
+	}
+	if err := a.ValidateConfig(); err != nil {
+		t.Errorf("unexpected config error: %v", err)
+	}
+	if _, err := a.GetAuthURL(ProviderOIDC, "state", "reader.example.com"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("expected ErrUnknownProvider for a disabled provider, got %v", err)
+	}
+
+	a.EnableEmailSignIn(&recordingMailer{}, "https://reader.example.com")
+	infos := a.Providers()
+	if len(infos) != 2 || infos[0].ID != ProviderGitHub || infos[1].ID != ProviderEmail {
+		t.Errorf("expected GitHub then email, got %+v", infos)
+	}
+
+	t.Setenv("GITHUB_CLIENT_SECRET", "")
+	if err := NewAuthService(newMockDBForAuth()).ValidateConfig(); err == nil {
+		t.Error("expected an error for GitHub without a client secret")
+	}
+}
+
+func TestEmailOnlySignIn(t *testing.T) {
+	t.Setenv("GOOGLE_CLIENT_ID", "")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "")
+	t.Setenv("GOOGLE_REDIRECT_URL", "")
+
+	a := NewAuthService(newMockDBForAuth())
+	if err := a.ValidateConfig(); err == nil {
+		t.Fatal("expected an error with no sign-in method configured")
+	}
+	a.EnableEmailSignIn(&recordingMailer{}, "https://reader.example.com")
+	if err := a.ValidateConfig(); err != nil {
+		t.Errorf("expected email links alone to be enough, got %v", err)
+	}
+	if infos := a.Providers(); len(infos) != 1 || infos[0].ID != ProviderEmail {
+		t.Errorf("expected only email sign-in, got %+v", infos)
+	}
+}
+
+func TestResolveUserLinking(t *testing.T) {
+	db := newIdentityTestDB(t)
+	a := &AuthService{db: db, providers: map[string]Provider{}}
+
+	existing := &database.User{GoogleID: "google-123", Email: "ann@example.com", Name: "Ann", CreatedAt: time.Now()}
+	if err := db.CreateUser(existing); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// A Google account from before identities were linked is found by its
+	// google_id, and linked on the way
+	user, err := a.resolveUser(&Identity{Provider: ProviderGoogle, Subject: "google-123", Email: "ann@example.com", EmailVerified: true})
+	if err != nil || user.ID != existing.ID {
+		t.Fatalf("expected the legacy Google account, got %+v, %v", user, err)
+	}
+	if link, _ := db.GetUserIdentity(ProviderGoogle, "google-123"); link == nil || link.UserID != existing.ID {
+		t.Errorf("expected the Google identity linked, got %+v", link)
+	}
+
+	// An unverified address never links to someone else's account
+	_, err = a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "99", Email: "ann@example.com", EmailVerified: false})
+	if !errors.Is(err, ErrEmailNotVerified) {
+		t.Errorf("expected ErrEmailNotVerified, got %v", err)
+	}
+	if link, _ := db.GetUserIdentity(ProviderGitHub, "99"); link != nil {
+		t.Error("expected no link for an unverified address")
+	}
+
+	// A verified address links to the existing account
+	user, err = a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "77", Email: "ann@example.com", EmailVerified: true})
+	if err != nil || user.ID != existing.ID {
+		t.Fatalf("expected the GitHub identity linked to Ann, got %+v, %v", user, err)
+	}
+	// and later sign-ins find it by the link even if the address changes
+	user, err = a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "77", Email: "ann@new.example.com"})
+	if err != nil || user.ID != existing.ID {
+		t.Errorf("expected the linked account, got %+v, %v", user, err)
+	}
+
+	// Email links resolve through the same rules
+	user, err = a.SignInWithEmail(" Ann@Example.com ")
+	if err != nil || user.ID != existing.ID {
+		t.Errorf("expected the email link to sign in to Ann, got %+v, %v", user, err)
+	}
+
+	// A new verified address creates an account, keyed in google_id by
+	// provider so it can't collide with a Google subject
+	user, err = a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "55", Email: "bob@example.com", EmailVerified: true, Name: "Bob"})
+	if err != nil || user.ID == existing.ID || user.GoogleID != "github:55" {
+		t.Errorf("expected a new account for Bob, got %+v, %v", user, err)
+	}
+	identities, err := db.GetUserIdentities(user.ID)
+	if err != nil || len(identities) != 1 || identities[0].Provider != ProviderGitHub {
+		t.Errorf("expected Bob's GitHub identity, got %+v, %v", identities, err)
+	}
+
+	if _, err := a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "33"}); !errors.Is(err, ErrEmailRequired) {
+		t.Errorf("expected ErrEmailRequired without an address, got %v", err)
+	}
+}
+
+func TestResolveUserLinkingMixedCaseEmail(t *testing.T) {
+	db := newIdentityTestDB(t)
+	a := &AuthService{db: db, providers: map[string]Provider{}}
+
+	// A Google sign-in with a mixed-case address stores it normalized
+	user, err := a.resolveUser(&Identity{Provider: ProviderGoogle, Subject: "google-456", Email: "Carol@Example.com", EmailVerified: true})
+	if err != nil {
+		t.Fatalf("resolveUser: %v", err)
+	}
+	if user.Email != "carol@example.com" {
+		t.Errorf("expected the address stored lowercase, got %q", user.Email)
+	}
+	linked, err := a.SignInWithEmail("carol@example.com")
+	if err != nil || linked.ID != user.ID {
+		t.Errorf("expected the email link to sign in to Carol's account, got %+v, %v", linked, err)
+	}
+
+	// Accounts stored in mixed case before addresses were normalized are
+	// still found whatever case the provider reports
+	if _, err := db.Exec(`INSERT INTO users (google_id, email, name, avatar, created_at) VALUES (?, ?, ?, ?, ?)`,
+		"google-123", "Alice@Example.com", "Alice", "", time.Now()); err != nil {
+		t.Fatalf("insert legacy user: %v", err)
+	}
+	legacy, err := db.GetUserByGoogleID("google-123")
+	if err != nil {
+		t.Fatalf("GetUserByGoogleID: %v", err)
+	}
+	user, err = a.SignInWithEmail("alice@example.com")
+	if err != nil || user.ID != legacy.ID {
+		t.Errorf("expected the email link to sign in to Alice's account, got %+v, %v", user, err)
+	}
+	user, err = a.resolveUser(&Identity{Provider: ProviderGitHub, Subject: "77", Email: "ALICE@example.com", EmailVerified: true})
+	if err != nil || user.ID != legacy.ID {
+		t.Errorf("expected the GitHub identity linked to Alice, got %+v, %v", user, err)
+	}
+}
+
+func TestSendSignInLink(t *testing.T) {
+	mailer := &recordingMailer{}
+	a := &AuthService{providers: map[string]Provider{}}
+	a.EnableEmailSignIn(mailer, "https://reader.example.com/")
+
+	if err := a.SendSignInLink(context.Background(), "ann@example.com", "tok+en"); err != nil {
+		t.Fatalf("SendSignInLink: %v", err)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "ann@example.com" {
+		t.Fatalf("expected one message to Ann, got %+v", mailer.sent)
+	}
+	if want := "https://reader.example.com/auth/email/verify?token=tok%2Ben"; !contains(mailer.sent[0].Text, want) {
+		t.Errorf("expected the link %s in the message, got %q", want, mailer.sent[0].Text)
+	}
+}
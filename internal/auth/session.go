@@ -2,14 +2,21 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"log"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/cache"
 	"github.com/jeffreyp/goread2/internal/database"
 )
 
@@ -22,22 +29,38 @@ type CachedSession struct {
 
 type SessionManager struct {
 	db          database.Database
-	cache       map[string]*CachedSession // sessionID -> cached session
+	cache       map[string]*CachedSession // sessionCacheKey(sessionID) -> cached session
 	cacheMu     sync.RWMutex
 	cacheTTL    time.Duration // How long to cache sessions (default: 10 minutes)
 	cacheHits   int64
 	cacheMisses int64
+	cacheTier   *cache.Tier                // shared with other instances; nil when running alone
 	oauthStates map[string]oauthStateEntry // OAuth state -> entry (for one-time use)
 	stateMu     sync.RWMutex
 	authCodes   map[string]authCodeEntry // one-time code -> session handoff (mobile auth)
 	codeMu      sync.Mutex
+	accessKey   []byte             // signs access tokens; see UseAccessTokenKey
+	users       map[int]cachedUser // users behind access tokens
+	usersMu     sync.Mutex
+	userTier    *cache.Tier // shared users behind access tokens; nil when running alone
 }
 
-// oauthStateEntry tracks a pending OAuth state and which kind of client
-// started the flow, so the callback knows where to send the user afterwards.
+// sessionLifetime is how long a session lasts without being used. Each
+// authenticated request, and each token refresh, starts it again.
+const sessionLifetime = 7 * 24 * time.Hour
+
+// OAuthState is what a pending sign-in remembers between /auth/login and
+// the callback: the provider it went to, and whether a native app client
+// started it, so the callback knows where to send the user afterwards.
+type OAuthState struct {
+	Provider string
+	Mobile   bool
+}
+
+// oauthStateEntry tracks a pending OAuth state until it expires.
 type oauthStateEntry struct {
+	OAuthState
 	ExpiresAt time.Time
-	Mobile    bool
 }
 
 // authCodeEntry holds a freshly created session waiting to be claimed by a
@@ -49,11 +72,49 @@ type authCodeEntry struct {
 }
 
 type Session struct {
-	ID        string
-	UserID    int
-	User      *database.User
-	CreatedAt time.Time
-	ExpiresAt time.Time
+	// ID is the credential itself, so it's left out of the copy in the
+	// shared cache; GetSession fills it in from the ID it looked up.
+	ID         string `json:"-"`
+	UserID     int
+	User       *database.User
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	UserAgent  string
+	ClientType string
+	IPAddress  string
+	LastSeenAt time.Time
+}
+
+// Client types recorded on sessions.
+const (
+	ClientTypeWeb = "web"
+	ClientTypeIOS = "ios"
+)
+
+// maxUserAgentLength caps the user agent stored with a session; anything
+// longer is padding nobody needs to read on the sessions page.
+const maxUserAgentLength = 255
+
+// Device describes the client a session is created for, as shown on the
+// account page's list of signed-in devices.
+type Device struct {
+	UserAgent  string
+	ClientType string
+	IPAddress  string
+}
+
+// DeviceFromRequest describes the client making c's request. mobile marks
+// sign-ins started by the iOS app.
+func DeviceFromRequest(c *gin.Context, mobile bool) Device {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	clientType := ClientTypeWeb
+	if mobile {
+		clientType = ClientTypeIOS
+	}
+	return Device{UserAgent: userAgent, ClientType: clientType, IPAddress: GetSecureClientIP(c)}
 }
 
 func NewSessionManager(db database.Database) *SessionManager {
@@ -66,12 +127,19 @@ func NewSessionManager(db database.Database) *SessionManager {
 		}
 	}
 
+	accessKey := make([]byte, 32)
+	if _, err := rand.Read(accessKey); err != nil {
+		log.Fatalf("Failed to generate access token key: %v", err)
+	}
+
 	sm := &SessionManager{
 		db:          db,
 		cache:       make(map[string]*CachedSession),
 		cacheTTL:    cacheTTL,
 		oauthStates: make(map[string]oauthStateEntry),
 		authCodes:   make(map[string]authCodeEntry),
+		accessKey:   accessKey,
+		users:       make(map[int]cachedUser),
 	}
 
 	// Note: Cleanup is now handled by cron jobs instead of background goroutines
@@ -81,26 +149,59 @@ func NewSessionManager(db database.Database) *SessionManager {
 	return sm
 }
 
+// UseCacheBackend shares cached sessions with other instances through b, so a
+// logout on one instance takes effect on all of them. Call it before the
+// manager is used.
+func (sm *SessionManager) UseCacheBackend(b *cache.Backend) {
+	sm.cacheTier = b.Tier("session", func(key string) {
+		sm.cacheMu.Lock()
+		delete(sm.cache, key)
+		sm.cacheMu.Unlock()
+	})
+	sm.userTier = b.Tier("access-user", func(key string) {
+		if userID, err := strconv.Atoi(key); err == nil {
+			sm.usersMu.Lock()
+			delete(sm.users, userID)
+			sm.usersMu.Unlock()
+		}
+	})
+}
+
+// CreateSession starts a session for user without any device details.
 func (sm *SessionManager) CreateSession(user *database.User) (*Session, error) {
+	return sm.CreateDeviceSession(user, Device{})
+}
+
+// CreateDeviceSession starts a session for user on device.
+func (sm *SessionManager) CreateDeviceSession(user *database.User, device Device) (*Session, error) {
 	sessionID, err := generateSessionID()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	session := &Session{
-		ID:        sessionID,
-		UserID:    user.ID,
-		User:      user,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // 7 days
+		ID:         sessionID,
+		UserID:     user.ID,
+		User:       user,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(sessionLifetime),
+		UserAgent:  device.UserAgent,
+		ClientType: device.ClientType,
+		IPAddress:  device.IPAddress,
+		LastSeenAt: now,
 	}
 
 	// Save to database
 	dbSession := &database.Session{
-		ID:        session.ID,
-		UserID:    session.UserID,
-		CreatedAt: session.CreatedAt,
-		ExpiresAt: session.ExpiresAt,
+		ID:         session.ID,
+		UserID:     session.UserID,
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		UserAgent:  session.UserAgent,
+		ClientType: session.ClientType,
+		IPAddress:  session.IPAddress,
+		LastSeenAt: session.LastSeenAt,
 	}
 
 	if err := sm.db.CreateSession(dbSession); err != nil {
@@ -111,9 +212,11 @@ func (sm *SessionManager) CreateSession(user *database.User) (*Session, error) {
 }
 
 func (sm *SessionManager) GetSession(sessionID string) (*Session, bool) {
+	key := sessionCacheKey(sessionID)
+
 	// Check cache first (read lock)
 	sm.cacheMu.RLock()
-	if cached, exists := sm.cache[sessionID]; exists {
+	if cached, exists := sm.cache[key]; exists {
 		// Check if cache entry is still valid
 		if time.Now().Before(cached.CacheExpires) {
 			sm.cacheMu.RUnlock()
@@ -123,6 +226,15 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, bool) {
 	}
 	sm.cacheMu.RUnlock()
 
+	// Then the shared cache, which another instance may have filled
+	var shared Session
+	if cacheExpires, ok := sm.cacheTier.Load(key, &shared); ok && time.Now().Before(shared.ExpiresAt) {
+		shared.ID = sessionID
+		sm.storeCachedSession(key, &shared, cacheExpires)
+		atomic.AddInt64(&sm.cacheHits, 1)
+		return &shared, true
+	}
+
 	atomic.AddInt64(&sm.cacheMisses, 1)
 	dbSession, err := sm.db.GetSession(sessionID)
 	if err != nil || dbSession == nil {
@@ -142,28 +254,38 @@ func (sm *SessionManager) GetSession(sessionID string) (*Session, bool) {
 	}
 
 	session := &Session{
-		ID:        dbSession.ID,
-		UserID:    dbSession.UserID,
-		User:      user,
-		CreatedAt: dbSession.CreatedAt,
-		ExpiresAt: dbSession.ExpiresAt,
-	}
+		ID:         dbSession.ID,
+		UserID:     dbSession.UserID,
+		User:       user,
+		CreatedAt:  dbSession.CreatedAt,
+		ExpiresAt:  dbSession.ExpiresAt,
+		UserAgent:  dbSession.UserAgent,
+		ClientType: dbSession.ClientType,
+		IPAddress:  dbSession.IPAddress,
+		LastSeenAt: dbSession.LastSeenAt,
+	}
+
+	// Store in cache for future requests
+	cacheExpires := time.Now().Add(sm.cacheTTL)
+	sm.storeCachedSession(key, session, cacheExpires)
+	sm.cacheTier.Save(key, session, cacheExpires)
+
+	return session, true
+}
 
-	// Store in cache for future requests (write lock)
+func (sm *SessionManager) storeCachedSession(key string, session *Session, cacheExpires time.Time) {
 	sm.cacheMu.Lock()
-	sm.cache[sessionID] = &CachedSession{
+	sm.cache[key] = &CachedSession{
 		Session:      session,
 		CachedAt:     time.Now(),
-		CacheExpires: time.Now().Add(sm.cacheTTL),
+		CacheExpires: cacheExpires,
 	}
 	sm.cacheMu.Unlock()
-
-	return session, true
 }
 
 func (sm *SessionManager) RefreshSession(sessionID string) error {
 	// Calculate new expiry time (extend by 7 days from now)
-	newExpiry := time.Now().Add(7 * 24 * time.Hour)
+	newExpiry := time.Now().Add(sessionLifetime)
 
 	// Update in database
 	if err := sm.db.UpdateSessionExpiry(sessionID, newExpiry); err != nil {
@@ -171,12 +293,46 @@ func (sm *SessionManager) RefreshSession(sessionID string) error {
 	}
 
 	// Update cache if exists (write lock)
+	key := sessionCacheKey(sessionID)
 	sm.cacheMu.Lock()
-	if cached, exists := sm.cache[sessionID]; exists {
+	if cached, exists := sm.cache[key]; exists {
 		cached.Session.ExpiresAt = newExpiry
 	}
 	sm.cacheMu.Unlock()
 
+	// Other instances re-read the new expiry from the database
+	sm.cacheTier.Delete(key)
+
+	return nil
+}
+
+// sessionActivityInterval is how stale a session's last-seen time may get
+// before a request records it again, so an active user costs one write
+// every few minutes rather than one per request.
+const sessionActivityInterval = 5 * time.Minute
+
+// TouchSession records that session was just used from ipAddress. It only
+// writes when the last recorded use is older than sessionActivityInterval
+// or the address has changed.
+func (sm *SessionManager) TouchSession(session *Session, ipAddress string) error {
+	now := time.Now()
+	sm.cacheMu.RLock()
+	current := now.Sub(session.LastSeenAt) < sessionActivityInterval && session.IPAddress == ipAddress
+	sm.cacheMu.RUnlock()
+	if current {
+		return nil
+	}
+
+	if err := sm.db.UpdateSessionActivity(session.ID, now, ipAddress); err != nil {
+		return err
+	}
+
+	// session is usually the cached copy, so the next request sees the
+	// update without another write
+	sm.cacheMu.Lock()
+	session.LastSeenAt = now
+	session.IPAddress = ipAddress
+	sm.cacheMu.Unlock()
 	return nil
 }
 
@@ -185,11 +341,95 @@ func (sm *SessionManager) DeleteSession(sessionID string) {
 	if err := sm.db.DeleteSession(sessionID); err != nil {
 		log.Printf("Error deleting session %s: %v", sessionID, err)
 	}
+	sm.evictSession(sessionID)
+}
 
-	// Invalidate cache entry (write lock)
+// evictSession drops a session from this instance's cache and tells the
+// other instances to do the same, so a deleted session stops working
+// everywhere straight away rather than when the cached copy expires.
+func (sm *SessionManager) evictSession(sessionID string) {
+	key := sessionCacheKey(sessionID)
 	sm.cacheMu.Lock()
-	delete(sm.cache, sessionID)
+	delete(sm.cache, key)
 	sm.cacheMu.Unlock()
+	sm.cacheTier.Delete(key)
+}
+
+// SessionHandle is the ID a session is listed and revoked by. Session IDs
+// are bearer credentials, so the account page never sees them; the handle
+// is derived from one but can't be turned back into it.
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte("session-handle:" + sessionID))
+	return hex.EncodeToString(sum[:16])
+}
+
+// UserSessions returns userID's unexpired sessions, most recently used
+// first.
+func (sm *SessionManager) UserSessions(userID int) ([]database.Session, error) {
+	all, err := sm.db.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sessions := make([]database.Session, 0, len(all))
+	for _, s := range all {
+		if now.Before(s.ExpiresAt) {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// RevokeUserSession signs out the session of userID's with the given
+// handle. It reports false if userID has no such session, so one user can't
+// revoke, or probe for, another user's sessions.
+func (sm *SessionManager) RevokeUserSession(userID int, handle string) (bool, error) {
+	sessions, err := sm.db.GetUserSessions(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sessions {
+		if SessionHandle(s.ID) != handle {
+			continue
+		}
+		if err := sm.db.DeleteSession(s.ID); err != nil {
+			return false, err
+		}
+		sm.evictSession(s.ID)
+		return true, nil
+	}
+	return false, nil
+}
+
+// RevokeOtherSessions signs out every session of userID's except the one
+// with keepHandle, returning how many were signed out.
+func (sm *SessionManager) RevokeOtherSessions(userID int, keepHandle string) (int, error) {
+	sessions, err := sm.db.GetUserSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+	revoked := 0
+	for _, s := range sessions {
+		if SessionHandle(s.ID) == keepHandle {
+			continue
+		}
+		if err := sm.db.DeleteSession(s.ID); err != nil {
+			return revoked, err
+		}
+		sm.evictSession(s.ID)
+		revoked++
+	}
+	return revoked, nil
+}
+
+// sessionCacheKey hashes a session ID for use as a cache key, so the IDs
+// themselves (which are bearer credentials) never reach a shared cache.
+func sessionCacheKey(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
 }
 
 // getCookieName returns an environment-specific cookie name to prevent
@@ -301,6 +541,10 @@ func (sm *SessionManager) InvalidateCache() {
 	sm.cacheMu.Lock()
 	sm.cache = make(map[string]*CachedSession)
 	sm.cacheMu.Unlock()
+
+	sm.usersMu.Lock()
+	sm.users = make(map[int]cachedUser)
+	sm.usersMu.Unlock()
 }
 
 func generateSessionID() (string, error) {
@@ -312,38 +556,39 @@ func generateSessionID() (string, error) {
 }
 
 // StoreOAuthState stores an OAuth state for one-time use with a 10-minute TTL.
-// mobile marks states started by a native app client (/auth/login?client=ios),
-// so the callback redirects to the app's custom URL scheme instead of /.
-func (sm *SessionManager) StoreOAuthState(state string, mobile bool) {
+// pending.Mobile marks states started by a native app client
+// (/auth/login?client=ios), so the callback redirects to the app's custom
+// URL scheme instead of /.
+func (sm *SessionManager) StoreOAuthState(state string, pending OAuthState) {
 	sm.stateMu.Lock()
 	defer sm.stateMu.Unlock()
 	sm.oauthStates[state] = oauthStateEntry{
-		ExpiresAt: time.Now().Add(10 * time.Minute),
-		Mobile:    mobile,
+		OAuthState: pending,
+		ExpiresAt:  time.Now().Add(10 * time.Minute),
 	}
 }
 
 // ValidateAndConsumeOAuthState checks if a state exists and hasn't been used.
-// Returns valid=true and the state's mobile flag if so, marking it as used
+// Returns what was stored for it and valid=true if so, marking it as used
 // (deletes it). Returns valid=false if the state doesn't exist or has expired.
-func (sm *SessionManager) ValidateAndConsumeOAuthState(state string) (valid, mobile bool) {
+func (sm *SessionManager) ValidateAndConsumeOAuthState(state string) (pending OAuthState, valid bool) {
 	sm.stateMu.Lock()
 	defer sm.stateMu.Unlock()
 
 	entry, exists := sm.oauthStates[state]
 	if !exists {
-		return false, false
+		return OAuthState{}, false
 	}
 
 	// Check if expired
 	if time.Now().After(entry.ExpiresAt) {
 		delete(sm.oauthStates, state)
-		return false, false
+		return OAuthState{}, false
 	}
 
 	// Valid state - consume it (delete so it can't be reused)
 	delete(sm.oauthStates, state)
-	return true, entry.Mobile
+	return entry.OAuthState, true
 }
 
 // CleanupExpiredOAuthStates removes expired OAuth states from memory
@@ -418,9 +663,56 @@ func (sm *SessionManager) CleanupExpiredAuthCodes() {
 	}
 }
 
-// SessionCookieName exposes the environment-specific session cookie name so
-// API clients that set the cookie themselves (the iOS app, after POST
-// /auth/token) use the right name against local and production backends.
-func (sm *SessionManager) SessionCookieName() string {
-	return sm.getCookieName()
+// loginTokenTTL bounds how long an emailed sign-in link stays usable.
+const loginTokenTTL = 15 * time.Minute
+
+// hashToken is the form sign-in link and refresh tokens are stored in, so
+// a leaked database row can't be turned back into a working token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateLoginToken issues a single-use token for an emailed sign-in link.
+// Tokens live in the database rather than in memory like auth codes, since
+// the link is usually opened long enough later to reach another instance.
+// mobile records that a native app asked for the link.
+func (sm *SessionManager) CreateLoginToken(email string, mobile bool) (string, error) {
+	token, err := generateSessionID()
+	if err != nil {
+		return "", err
+	}
+	err = sm.db.CreateLoginToken(&database.LoginToken{
+		TokenHash: hashToken(token),
+		Email:     email,
+		Mobile:    mobile,
+		ExpiresAt: time.Now().Add(loginTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeLoginToken redeems a sign-in link token, returning the address it
+// was sent to. Like auth codes, a token is consumed whether or not it has
+// expired.
+func (sm *SessionManager) ConsumeLoginToken(token string) (email string, mobile bool, ok bool) {
+	if token == "" {
+		return "", false, false
+	}
+	lt, err := sm.db.ConsumeLoginToken(hashToken(token))
+	if err != nil {
+		log.Printf("Failed to consume login token: %v", err)
+		return "", false, false
+	}
+	if lt == nil || time.Now().After(lt.ExpiresAt) {
+		return "", false, false
+	}
+	return lt.Email, lt.Mobile, true
+}
+
+// CleanupExpiredLoginTokens deletes sign-in link tokens nobody redeemed.
+func (sm *SessionManager) CleanupExpiredLoginTokens() error {
+	return sm.db.DeleteExpiredLoginTokens()
 }
@@ -3,30 +3,128 @@ package auth
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/cache"
 	"github.com/jeffreyp/goread2/internal/database"
 )
 
 // mockDB implements database.Database interface for testing
 type mockDB struct {
-	mu       sync.RWMutex
-	sessions map[string]*database.Session
-	users    map[int]*database.User
+	mu            sync.RWMutex
+	sessions      map[string]*database.Session
+	users         map[int]*database.User
+	refreshTokens map[string]database.RefreshToken
 }
 
 func newMockDB() *mockDB {
 	return &mockDB{
-		sessions: make(map[string]*database.Session),
-		users:    make(map[int]*database.User),
+		sessions:      make(map[string]*database.Session),
+		users:         make(map[int]*database.User),
+		refreshTokens: make(map[string]database.RefreshToken),
 	}
 }
 
-func (m *mockDB) Close() error                                     { return nil }
-func (m *mockDB) CreateUser(*database.User) error                  { return nil }
-func (m *mockDB) GetUserByGoogleID(string) (*database.User, error) { return nil, nil }
+func (m *mockDB) Close() error                                                   { return nil }
+func (m *mockDB) GetArticlesByIDs(int, []int) (map[int]database.Article, error)  { return nil, nil }
+func (m *mockDB) CreateUserIdentity(*database.UserIdentity) error                { return nil }
+func (m *mockDB) GetUserIdentity(string, string) (*database.UserIdentity, error) { return nil, nil }
+func (m *mockDB) GetUserIdentities(int) ([]database.UserIdentity, error)         { return nil, nil }
+func (m *mockDB) CreateLoginToken(*database.LoginToken) error                    { return nil }
+func (m *mockDB) ConsumeLoginToken(string) (*database.LoginToken, error)         { return nil, nil }
+func (m *mockDB) DeleteExpiredLoginTokens() error                                { return nil }
+func (m *mockDB) ForEachUserIdentity(func(database.UserIdentity) error) error    { return nil }
+func (m *mockDB) DeleteUser(userID int) error                                    { return nil }
+func (m *mockDB) GetUserArticleHistory(userID int) ([]database.Article, error)   { return nil, nil }
+func (m *mockDB) ForEachUser(fn func(database.User) error) error                 { return nil }
+func (m *mockDB) ForEachArticle(fn func(database.Article) error) error           { return nil }
+func (m *mockDB) ForEachUserArticle(fn func(database.UserArticle) error) error   { return nil }
+func (m *mockDB) ForEachSession(fn func(database.Session) error) error           { return nil }
+func (m *mockDB) GetAllFeedSubscriptions() ([]database.FeedSubscription, error)  { return nil, nil }
+func (m *mockDB) GetFeedCategories() (map[int]string, error)                     { return nil, nil }
+func (m *mockDB) SetFeedCategory(feedID int, category string) error              { return nil }
+func (m *mockDB) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDB) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDB) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDB) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDB) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDB) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDB) DeleteInboundAddress(userID, addressID int) error      { return nil }
+func (m *mockDB) CreateWebhook(*database.Webhook) error                 { return nil }
+func (m *mockDB) GetWebhook(int, int) (*database.Webhook, error)        { return nil, nil }
+func (m *mockDB) GetUserWebhooks(int) ([]database.Webhook, error)       { return []database.Webhook{}, nil }
+func (m *mockDB) UpdateWebhook(*database.Webhook) error                 { return nil }
+func (m *mockDB) DeleteWebhook(int, int) error                          { return nil }
+func (m *mockDB) GetFeedWebhooks(int) ([]database.Webhook, error)       { return []database.Webhook{}, nil }
+func (m *mockDB) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDB) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDB) DeleteWebhookDeliveriesBefore(time.Time) (int, error)              { return 0, nil }
+func (m *mockDB) UpdateUserDigestPreferences(int, database.DigestPreferences) error { return nil }
+func (m *mockDB) GetDigestUsers() ([]database.User, error)                          { return []database.User{}, nil }
+func (m *mockDB) SetUserDigestSentAt(int, time.Time) error                          { return nil }
+func (m *mockDB) GetUserStarredCountsByFeed(int) (map[int]int, error)               { return map[int]int{}, nil }
+func (m *mockDB) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDB) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDB) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDB) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDB) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDB) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDB) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDB) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDB) CreateUndoOperation(*database.UndoOperation) error                 { return nil }
+func (m *mockDB) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) { return nil, nil }
+func (m *mockDB) DeleteExpiredUndoOperations(time.Time) (int, error)                { return 0, nil }
+func (m *mockDB) SetUserArticleProgress(int, int, int) error                        { return nil }
+func (m *mockDB) CreateTag(*database.Tag) error                                     { return nil }
+func (m *mockDB) GetTag(int, int) (*database.Tag, error)                            { return nil, nil }
+func (m *mockDB) GetTagByName(int, string) (*database.Tag, error)                   { return nil, nil }
+func (m *mockDB) GetUserTags(int) ([]database.Tag, error)                           { return nil, nil }
+func (m *mockDB) RenameTag(int, int, string) error                                  { return nil }
+func (m *mockDB) DeleteTag(int, int) error                                          { return nil }
+func (m *mockDB) TagArticle(int, int, int) error                                    { return nil }
+func (m *mockDB) UntagArticle(int, int, int) error                                  { return nil }
+func (m *mockDB) GetArticleTags(int, []int) (map[int][]string, error)               { return nil, nil }
+func (m *mockDB) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDB) CreateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDB) GetAnnotation(int, int) (*database.Annotation, error)          { return nil, nil }
+func (m *mockDB) GetArticleAnnotations(int, int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDB) GetUserAnnotations(int) ([]database.Annotation, error)         { return nil, nil }
+func (m *mockDB) UpdateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDB) DeleteAnnotation(int, int) error                               { return nil }
+func (m *mockDB) CreateUser(*database.User) error                               { return nil }
+func (m *mockDB) GetUserByGoogleID(string) (*database.User, error)              { return nil, nil }
 func (m *mockDB) GetUserByID(userID int) (*database.User, error) {
 	if user, exists := m.users[userID]; exists {
 		return user, nil
@@ -107,6 +205,61 @@ func (m *mockDB) UpdateSessionExpiry(sessionID string, newExpiry time.Time) erro
 	return nil
 }
 
+func (m *mockDB) UpdateSessionActivity(sessionID string, lastSeenAt time.Time, ipAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, exists := m.sessions[sessionID]; exists {
+		s.LastSeenAt = lastSeenAt
+		s.IPAddress = ipAddress
+	}
+	return nil
+}
+
+func (m *mockDB) GetUserSessions(userID int) ([]database.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var sessions []database.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			sessions = append(sessions, *s)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockDB) CreateRefreshToken(token *database.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshTokens[token.TokenHash] = *token
+	return nil
+}
+
+func (m *mockDB) UseRefreshToken(tokenHash string, usedAt time.Time) (*database.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, exists := m.refreshTokens[tokenHash]
+	if !exists {
+		return nil, nil
+	}
+	if token.UsedAt.IsZero() {
+		used := token
+		used.UsedAt = usedAt
+		m.refreshTokens[tokenHash] = used
+	}
+	return &token, nil
+}
+
+func (m *mockDB) DeleteExpiredRefreshTokens() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, token := range m.refreshTokens {
+		if time.Now().After(token.ExpiresAt) {
+			delete(m.refreshTokens, hash)
+		}
+	}
+	return nil
+}
+
 func (m *mockDB) DeleteSession(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -281,6 +434,83 @@ func TestDeleteSession(t *testing.T) {
 	sm.DeleteSession("nonexistent")
 }
 
+func TestDeleteSession_SharedAcrossInstances(t *testing.T) {
+	db := newMockDB()
+	store := cache.NewMemoryStore()
+	invalidator := cache.NewMemoryInvalidator()
+
+	// Two instances sharing the database and cache backend
+	a := NewSessionManager(db)
+	b := NewSessionManager(db)
+	for _, sm := range []*SessionManager{a, b} {
+		backend := cache.NewBackend(store, invalidator)
+		if err := backend.Start(t.Context()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		sm.UseCacheBackend(backend)
+	}
+
+	session, err := a.CreateSession(&database.User{ID: 1, Email: "test@example.com"})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	// Warm both local caches
+	if _, ok := a.GetSession(session.ID); !ok {
+		t.Fatal("session should exist on instance a")
+	}
+	if _, ok := b.GetSession(session.ID); !ok {
+		t.Fatal("session should exist on instance b")
+	}
+
+	// Logging out on a must take effect on b without waiting for its cache TTL
+	a.DeleteSession(session.ID)
+	if _, ok := b.GetSession(session.ID); ok {
+		t.Error("session should be gone on instance b after deletion on a")
+	}
+}
+
+func TestGetSession_SharedCacheOmitsSessionID(t *testing.T) {
+	db := newMockDB()
+	store := cache.NewMemoryStore()
+
+	a := NewSessionManager(db)
+	b := NewSessionManager(db)
+	for _, sm := range []*SessionManager{a, b} {
+		backend := cache.NewBackend(store, cache.NewMemoryInvalidator())
+		if err := backend.Start(t.Context()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		sm.UseCacheBackend(backend)
+	}
+
+	session, err := a.CreateSession(&database.User{ID: 1, Email: "test@example.com"})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, ok := a.GetSession(session.ID); !ok {
+		t.Fatal("session should exist on instance a")
+	}
+
+	data, ok, _ := store.Get("goread2:session:" + sessionCacheKey(session.ID))
+	if !ok {
+		t.Fatal("expected the session in the shared cache")
+	}
+	if strings.Contains(string(data), session.ID) {
+		t.Error("the shared cache must not hold the session ID")
+	}
+
+	// Instance b loads it from the shared cache, not the database
+	delete(db.sessions, session.ID)
+	got, ok := b.GetSession(session.ID)
+	if !ok {
+		t.Fatal("session should load from the shared cache on instance b")
+	}
+	if got.ID != session.ID || got.UserID != 1 {
+		t.Errorf("unexpected session from the shared cache: %+v", got)
+	}
+}
+
 func TestGenerateSessionID(t *testing.T) {
 	id1, err := generateSessionID()
 	if err != nil {
@@ -828,3 +1058,205 @@ func TestInvalidateCache(t *testing.T) {
 		t.Error("Sessions should still be retrievable from database after cache invalidation")
 	}
 }
+
+// TestLoginTokenLifecycle covers emailed sign-in link tokens: stored only
+// as a hash, redeemable once, and dead after they expire.
+func TestLoginTokenLifecycle(t *testing.T) {
+	db := newIdentityTestDB(t)
+	sm := NewSessionManager(db)
+
+	token, err := sm.CreateLoginToken("ann@example.com", true)
+	if err != nil {
+		t.Fatalf("CreateLoginToken: %v", err)
+	}
+	if lt, _ := db.ConsumeLoginToken(token); lt != nil {
+		t.Fatal("expected the raw token not to be stored")
+	}
+
+	email, mobile, ok := sm.ConsumeLoginToken(token)
+	if !ok || email != "ann@example.com" || !mobile {
+		t.Fatalf("expected the token redeemed for Ann on mobile, got %q %v %v", email, mobile, ok)
+	}
+	if _, _, ok := sm.ConsumeLoginToken(token); ok {
+		t.Error("expected a token to redeem only once")
+	}
+	if _, _, ok := sm.ConsumeLoginToken(""); ok {
+		t.Error("expected an empty token to be rejected")
+	}
+
+	expired := "expired-token"
+	if err := db.CreateLoginToken(&database.LoginToken{
+		TokenHash: hashToken(expired),
+		Email:     "ann@example.com",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatalf("CreateLoginToken: %v", err)
+	}
+	if _, _, ok := sm.ConsumeLoginToken(expired); ok {
+		t.Error("expected an expired token to be rejected")
+	}
+	if err := sm.CleanupExpiredLoginTokens(); err != nil {
+		t.Errorf("CleanupExpiredLoginTokens: %v", err)
+	}
+}
+
+func TestCreateDeviceSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := newMockDB()
+	sm := NewSessionManager(db)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/auth/callback", nil)
+	c.Request.RemoteAddr = "198.51.100.7:4321"
+	c.Request.Header.Set("User-Agent", strings.Repeat("a", 300))
+
+	session, err := sm.CreateDeviceSession(&database.User{ID: 1}, DeviceFromRequest(c, true))
+	if err != nil {
+		t.Fatalf("CreateDeviceSession failed: %v", err)
+	}
+	stored := db.sessions[session.ID]
+	if stored.ClientType != ClientTypeIOS || stored.IPAddress != "198.51.100.7" || len(stored.UserAgent) != maxUserAgentLength {
+		t.Errorf("unexpected device details %+v", stored)
+	}
+	if stored.LastSeenAt.IsZero() {
+		t.Error("expected a new session to be last seen now")
+	}
+}
+
+func TestTouchSession(t *testing.T) {
+	db := newMockDB()
+	sm := NewSessionManager(db)
+
+	session, err := sm.CreateDeviceSession(&database.User{ID: 1}, Device{ClientType: ClientTypeWeb, IPAddress: "198.51.100.7"})
+	if err != nil {
+		t.Fatalf("CreateDeviceSession failed: %v", err)
+	}
+	cached, _ := sm.GetSession(session.ID)
+
+	// A request moments later from the same address doesn't write
+	db.sessions[session.ID].LastSeenAt = time.Time{}
+	if err := sm.TouchSession(cached, "198.51.100.7"); err != nil {
+		t.Fatalf("TouchSession failed: %v", err)
+	}
+	if !db.sessions[session.ID].LastSeenAt.IsZero() {
+		t.Error("expected no write while the last-seen time is current")
+	}
+
+	// A new address is recorded straight away, on the cached copy too
+	if err := sm.TouchSession(cached, "203.0.113.9"); err != nil {
+		t.Fatalf("TouchSession failed: %v", err)
+	}
+	if got := db.sessions[session.ID]; got.IPAddress != "203.0.113.9" || got.LastSeenAt.IsZero() {
+		t.Errorf("expected the new address stored, got %+v", got)
+	}
+	if again, _ := sm.GetSession(session.ID); again.IPAddress != "203.0.113.9" {
+		t.Errorf("expected the cached session updated, got %q", again.IPAddress)
+	}
+
+	// So is a stale last-seen time
+	cached.LastSeenAt = time.Now().Add(-sessionActivityInterval - time.Minute)
+	db.sessions[session.ID].LastSeenAt = time.Time{}
+	if err := sm.TouchSession(cached, "203.0.113.9"); err != nil {
+		t.Fatalf("TouchSession failed: %v", err)
+	}
+	if db.sessions[session.ID].LastSeenAt.IsZero() {
+		t.Error("expected a stale last-seen time to be refreshed")
+	}
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	db := newMockDB()
+	store := cache.NewMemoryStore()
+	invalidator := cache.NewMemoryInvalidator()
+
+	// Two instances sharing the database and cache backend
+	a := NewSessionManager(db)
+	b := NewSessionManager(db)
+	for _, sm := range []*SessionManager{a, b} {
+		backend := cache.NewBackend(store, invalidator)
+		if err := backend.Start(t.Context()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		sm.UseCacheBackend(backend)
+	}
+
+	laptop, _ := a.CreateSession(&database.User{ID: 1})
+	phone, _ := a.CreateSession(&database.User{ID: 1})
+	other, _ := a.CreateSession(&database.User{ID: 2})
+	for _, s := range []*Session{laptop, phone, other} {
+		if _, ok := b.GetSession(s.ID); !ok {
+			t.Fatalf("session %s should exist on instance b", s.ID)
+		}
+	}
+
+	// Another user's handle is treated as unknown
+	if revoked, err := a.RevokeUserSession(1, SessionHandle(other.ID)); err != nil || revoked {
+		t.Errorf("expected another user's session to be refused, got %v, %v", revoked, err)
+	}
+	if _, ok := b.GetSession(other.ID); !ok {
+		t.Error("another user's session should be untouched")
+	}
+
+	// Revoking on a signs the phone out on b without waiting for its cache
+	if revoked, err := a.RevokeUserSession(1, SessionHandle(phone.ID)); err != nil || !revoked {
+		t.Fatalf("RevokeUserSession: got %v, %v", revoked, err)
+	}
+	if _, ok := b.GetSession(phone.ID); ok {
+		t.Error("revoked session should be gone on instance b")
+	}
+	if _, ok := b.GetSession(laptop.ID); !ok {
+		t.Error("the other session should still work")
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	db := newMockDB()
+	sm := NewSessionManager(db)
+
+	current, _ := sm.CreateSession(&database.User{ID: 1})
+	var others []*Session
+	for i := 0; i < 2; i++ {
+		s, _ := sm.CreateSession(&database.User{ID: 1})
+		sm.GetSession(s.ID) // cache it
+		others = append(others, s)
+	}
+	unrelated, _ := sm.CreateSession(&database.User{ID: 2})
+
+	revoked, err := sm.RevokeOtherSessions(1, SessionHandle(current.ID))
+	if err != nil || revoked != 2 {
+		t.Fatalf("RevokeOtherSessions: got %d, %v", revoked, err)
+	}
+	for _, s := range others {
+		if _, ok := sm.GetSession(s.ID); ok {
+			t.Error("expected the other sessions signed out")
+		}
+	}
+	if _, ok := sm.GetSession(current.ID); !ok {
+		t.Error("expected the current session kept")
+	}
+	if _, ok := sm.GetSession(unrelated.ID); !ok {
+		t.Error("expected another user's session kept")
+	}
+}
+
+func TestUserSessions(t *testing.T) {
+	db := newMockDB()
+	sm := NewSessionManager(db)
+
+	older, _ := sm.CreateSession(&database.User{ID: 1})
+	newer, _ := sm.CreateSession(&database.User{ID: 1})
+	expired, _ := sm.CreateSession(&database.User{ID: 1})
+	db.sessions[older.ID].LastSeenAt = time.Now().Add(-time.Hour)
+	db.sessions[expired.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	sessions, err := sm.UserSessions(1)
+	if err != nil {
+		t.Fatalf("UserSessions failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != newer.ID || sessions[1].ID != older.ID {
+		t.Errorf("expected the two live sessions, most recent first, got %+v", sessions)
+	}
+	if SessionHandle(newer.ID) == SessionHandle(older.ID) || strings.Contains(SessionHandle(newer.ID), newer.ID) {
+		t.Error("expected distinct handles that don't reveal the session ID")
+	}
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// accessTokenTTL is how long an access token works. Access tokens are
+// checked by signature alone, so this is also how long one keeps working
+// after its session is signed out.
+const accessTokenTTL = 15 * time.Minute
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means a refresh token was presented after it
+	// had already been exchanged, so more than one party holds it. The
+	// session it belonged to has been signed out.
+	ErrRefreshTokenReused = errors.New("refresh token already used")
+)
+
+// AccessClaims is what an access token says about its bearer.
+type AccessClaims struct {
+	UserID    int    `json:"uid"`
+	Session   string `json:"sid"` // SessionHandle of the session it was issued for
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenPair is what a mobile client holds instead of a session ID: a
+// short-lived access token sent with each request, and a single-use
+// refresh token that gets the next pair.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// cachedUser is a user behind an access token, cached like the users in
+// cached sessions.
+type cachedUser struct {
+	user    *database.User
+	expires time.Time
+}
+
+// UseAccessTokenKey sets the key access tokens are signed with. Every
+// instance must use the same key; without one, each process signs with a
+// random key of its own, which only suits development and tests.
+func (sm *SessionManager) UseAccessTokenKey(key []byte) {
+	sm.accessKey = key
+}
+
+// IssueTokens starts a token pair for session. The refresh token lasts as
+// long as the session.
+func (sm *SessionManager) IssueTokens(session *Session) (*TokenPair, error) {
+	return sm.issueTokens(session.UserID, session.ID, session.ExpiresAt)
+}
+
+func (sm *SessionManager) issueTokens(userID int, sessionID string, refreshExpiresAt time.Time) (*TokenPair, error) {
+	refreshToken, err := generateSessionID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = sm.db.CreateRefreshToken(&database.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		SessionID: sessionID,
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: refreshExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := AccessClaims{
+		UserID:    userID,
+		Session:   SessionHandle(sessionID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenTTL).Unix(),
+	}
+	accessToken, err := sm.signAccessToken(claims)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Unix(claims.ExpiresAt, 0),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// RefreshTokens exchanges a refresh token for a new pair, extending the
+// session the way a request with a session cookie does. Each refresh token
+// works once. Presenting one a second time means it was copied, and since
+// there's no telling which holder is legitimate, the whole session is
+// signed out and both have to sign in again.
+func (sm *SessionManager) RefreshTokens(refreshToken, ipAddress string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := time.Now()
+	rt, err := sm.db.UseRefreshToken(hashToken(refreshToken), now)
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil || now.After(rt.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if !rt.UsedAt.IsZero() {
+		log.Printf("SECURITY: refresh token reused for a session of user %d (first used %s); signing the session out",
+			rt.UserID, rt.UsedAt.Format(time.RFC3339))
+		sm.DeleteSession(rt.SessionID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	// The session is gone if it was signed out or expired
+	session, ok := sm.GetSession(rt.SessionID)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err := sm.RefreshSession(session.ID); err != nil {
+		return nil, err
+	}
+	if err := sm.TouchSession(session, ipAddress); err != nil {
+		log.Printf("Failed to record session activity: %v", err)
+	}
+	return sm.issueTokens(session.UserID, session.ID, now.Add(sessionLifetime))
+}
+
+// signAccessToken encodes claims as base64url JSON followed by its
+// HMAC-SHA256 signature.
+func (sm *SessionManager) signAccessToken(claims AccessClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sm.accessTokenMAC(encoded)), nil
+}
+
+func (sm *SessionManager) accessTokenMAC(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, sm.accessKey)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
+
+// VerifyAccessToken checks an access token's signature and expiry. It
+// doesn't consult the database, so a token for a signed-out session keeps
+// working until it expires.
+func (sm *SessionManager) VerifyAccessToken(token string) (*AccessClaims, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidAccessToken
+	}
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, sm.accessTokenMAC(encoded)) {
+		return nil, ErrInvalidAccessToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	var claims AccessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID <= 0 || claims.Session == "" {
+		return nil, ErrInvalidAccessToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidAccessToken
+	}
+	return &claims, nil
+}
+
+// AccessTokenUser verifies an access token and returns the user it was
+// issued to. Users are cached like the users in cached sessions, locally
+// and in the shared tier, so a valid token usually costs no database read.
+func (sm *SessionManager) AccessTokenUser(token string) (*database.User, *AccessClaims, error) {
+	claims, err := sm.VerifyAccessToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	sm.usersMu.Lock()
+	cached, ok := sm.users[claims.UserID]
+	sm.usersMu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.user, claims, nil
+	}
+
+	key := strconv.Itoa(claims.UserID)
+	var shared database.User
+	if cacheExpires, ok := sm.userTier.Load(key, &shared); ok {
+		sm.storeCachedUser(&shared, cacheExpires)
+		return &shared, claims, nil
+	}
+
+	user, err := sm.db.GetUserByID(claims.UserID)
+	if err != nil || user == nil {
+		// Usually the account was deleted after the token was issued. A
+		// failed lookup is refused too; the client's refresh then reports
+		// what went wrong.
+		return nil, nil, ErrInvalidAccessToken
+	}
+	cacheExpires := now.Add(sm.cacheTTL)
+	sm.storeCachedUser(user, cacheExpires)
+	sm.userTier.Save(key, user, cacheExpires)
+	return user, claims, nil
+}
+
+func (sm *SessionManager) storeCachedUser(user *database.User, cacheExpires time.Time) {
+	sm.usersMu.Lock()
+	sm.users[user.ID] = cachedUser{user: user, expires: cacheExpires}
+	sm.usersMu.Unlock()
+}
+
+// ForgetUser drops a user cached for access tokens, here and, through the
+// shared cache tier, on every other instance, so the next request with one
+// of their tokens reads the user again. Access tokens for a deleted account
+// stop working everywhere once it's forgotten.
+func (sm *SessionManager) ForgetUser(userID int) {
+	sm.usersMu.Lock()
+	delete(sm.users, userID)
+	sm.usersMu.Unlock()
+	sm.userTier.Delete(strconv.Itoa(userID))
+}
+
+// CleanupExpiredRefreshTokens deletes refresh tokens past their expiry.
+func (sm *SessionManager) CleanupExpiredRefreshTokens() error {
+	return sm.db.DeleteExpiredRefreshTokens()
+}
+
+// accessTokenFromRequest returns the bearer token in r's Authorization
+// header, if it has one.
+func accessTokenFromRequest(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
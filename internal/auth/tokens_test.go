@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/cache"
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestAccessTokens(t *testing.T) {
+	sm := NewSessionManager(newMockDB())
+	session, _ := sm.CreateSession(&database.User{ID: 7})
+
+	tokens, err := sm.IssueTokens(session)
+	if err != nil {
+		t.Fatalf("IssueTokens: %v", err)
+	}
+	if strings.Contains(tokens.AccessToken, session.ID) || tokens.RefreshToken == session.ID {
+		t.Error("tokens must not contain the session ID")
+	}
+
+	claims, err := sm.VerifyAccessToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken: %v", err)
+	}
+	if claims.UserID != 7 || claims.Session != SessionHandle(session.ID) {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if ttl := time.Until(tokens.AccessExpiresAt); ttl <= 0 || ttl > accessTokenTTL {
+		t.Errorf("expected the access token to expire within %v, got %v", accessTokenTTL, ttl)
+	}
+
+	payload, sig, _ := strings.Cut(tokens.AccessToken, ".")
+	forged, _ := sm.signAccessToken(AccessClaims{UserID: 1, Session: claims.Session, ExpiresAt: claims.ExpiresAt})
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	expired, _ := sm.signAccessToken(AccessClaims{UserID: 7, Session: claims.Session, ExpiresAt: time.Now().Add(-time.Second).Unix()})
+
+	other := NewSessionManager(newMockDB())
+	for name, token := range map[string]string{
+		"empty":             "",
+		"no signature":      payload,
+		"swapped payload":   forgedPayload + "." + sig,
+		"garbled signature": payload + ".!!!",
+		"expired":           expired,
+	} {
+		if _, err := sm.VerifyAccessToken(token); !errors.Is(err, ErrInvalidAccessToken) {
+			t.Errorf("%s: expected ErrInvalidAccessToken, got %v", name, err)
+		}
+	}
+	if _, err := other.VerifyAccessToken(tokens.AccessToken); err == nil {
+		t.Error("expected a token signed with another key to be refused")
+	}
+
+	other.UseAccessTokenKey(sm.accessKey)
+	if _, err := other.VerifyAccessToken(tokens.AccessToken); err != nil {
+		t.Errorf("expected instances sharing a key to accept each other's tokens: %v", err)
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	t.Run("rotates", func(t *testing.T) {
+		db := newMockDB()
+		sm := NewSessionManager(db)
+		session, _ := sm.CreateSession(&database.User{ID: 1})
+		first, _ := sm.IssueTokens(session)
+
+		second, err := sm.RefreshTokens(first.RefreshToken, "203.0.113.9")
+		if err != nil {
+			t.Fatalf("RefreshTokens: %v", err)
+		}
+		if second.RefreshToken == first.RefreshToken {
+			t.Error("expected a new refresh token")
+		}
+		if db.sessions[session.ID].IPAddress != "203.0.113.9" {
+			t.Error("expected the refresh recorded as session activity")
+		}
+		if _, err := sm.RefreshTokens(second.RefreshToken, "203.0.113.9"); err != nil {
+			t.Errorf("expected the new refresh token to work: %v", err)
+		}
+	})
+
+	t.Run("reuse signs the session out", func(t *testing.T) {
+		sm := NewSessionManager(newMockDB())
+		session, _ := sm.CreateSession(&database.User{ID: 1})
+		first, _ := sm.IssueTokens(session)
+		second, _ := sm.RefreshTokens(first.RefreshToken, "")
+
+		if _, err := sm.RefreshTokens(first.RefreshToken, ""); !errors.Is(err, ErrRefreshTokenReused) {
+			t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+		}
+		if _, ok := sm.GetSession(session.ID); ok {
+			t.Error("expected the session signed out")
+		}
+		if _, err := sm.RefreshTokens(second.RefreshToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("expected the latest refresh token to stop working, got %v", err)
+		}
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		sm := NewSessionManager(newMockDB())
+		session, _ := sm.CreateSession(&database.User{ID: 1})
+		tokens, _ := sm.IssueTokens(session)
+
+		if ok, _ := sm.RevokeUserSession(1, SessionHandle(session.ID)); !ok {
+			t.Fatal("expected the session revoked")
+		}
+		if _, err := sm.RefreshTokens(tokens.RefreshToken, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("expected ErrInvalidRefreshToken, got %v", err)
+		}
+	})
+
+	t.Run("unknown and expired", func(t *testing.T) {
+		db := newMockDB()
+		sm := NewSessionManager(db)
+		session, _ := sm.CreateSession(&database.User{ID: 1})
+		expired, _ := sm.issueTokens(1, session.ID, time.Now().Add(-time.Minute))
+
+		for _, token := range []string{"", "bogus", expired.RefreshToken} {
+			if _, err := sm.RefreshTokens(token, ""); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("%q: expected ErrInvalidRefreshToken, got %v", token, err)
+			}
+		}
+
+		if err := sm.CleanupExpiredRefreshTokens(); err != nil {
+			t.Fatalf("CleanupExpiredRefreshTokens: %v", err)
+		}
+		if _, exists := db.refreshTokens[hashToken(expired.RefreshToken)]; exists {
+			t.Error("expected the expired refresh token deleted")
+		}
+	})
+}
+
+func TestAccessTokenUser(t *testing.T) {
+	db := newMockDB()
+	db.users[3] = &database.User{ID: 3, Name: "Before"}
+	sm := NewSessionManager(db)
+	session, _ := sm.CreateSession(db.users[3])
+	tokens, _ := sm.IssueTokens(session)
+
+	user, claims, err := sm.AccessTokenUser(tokens.AccessToken)
+	if err != nil || user.Name != "Before" || claims.UserID != 3 {
+		t.Fatalf("AccessTokenUser: %+v, %+v, %v", user, claims, err)
+	}
+
+	// Served from the cache until the user is forgotten
+	db.users[3] = &database.User{ID: 3, Name: "After"}
+	if user, _, _ := sm.AccessTokenUser(tokens.AccessToken); user.Name != "Before" {
+		t.Errorf("expected the cached user, got %q", user.Name)
+	}
+	sm.ForgetUser(3)
+	if user, _, _ := sm.AccessTokenUser(tokens.AccessToken); user.Name != "After" {
+		t.Errorf("expected the user read again after ForgetUser, got %q", user.Name)
+	}
+
+	if _, _, err := sm.AccessTokenUser("bogus"); !errors.Is(err, ErrInvalidAccessToken) {
+		t.Errorf("expected ErrInvalidAccessToken, got %v", err)
+	}
+}
+
+func TestForgetUser_SharedAcrossInstances(t *testing.T) {
+	db := newMockDB()
+	db.users[3] = &database.User{ID: 3, Name: "Before"}
+	store := cache.NewMemoryStore()
+	invalidator := cache.NewMemoryInvalidator()
+
+	// Two instances sharing the database, cache backend and signing key
+	a := NewSessionManager(db)
+	b := NewSessionManager(db)
+	b.UseAccessTokenKey(a.accessKey)
+	for _, sm := range []*SessionManager{a, b} {
+		backend := cache.NewBackend(store, invalidator)
+		if err := backend.Start(t.Context()); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		sm.UseCacheBackend(backend)
+	}
+
+	session, _ := a.CreateSession(db.users[3])
+	tokens, _ := a.IssueTokens(session)
+
+	// Warm both local caches
+	for _, sm := range []*SessionManager{a, b} {
+		if _, _, err := sm.AccessTokenUser(tokens.AccessToken); err != nil {
+			t.Fatalf("AccessTokenUser: %v", err)
+		}
+	}
+
+	// A change forgotten on a must reach b without waiting for its cache TTL
+	db.users[3] = &database.User{ID: 3, Name: "After"}
+	a.ForgetUser(3)
+	if user, _, _ := b.AccessTokenUser(tokens.AccessToken); user == nil || user.Name != "After" {
+		t.Errorf("expected instance b to read the user again after ForgetUser on a, got %+v", user)
+	}
+}
+
+func TestAccessTokenFromRequest(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                 "",
+		"Bearer abc.def":   "abc.def",
+		"bearer abc.def":   "abc.def",
+		"Basic dXNlcjpwdw": "",
+		"Bearer ":          "",
+	} {
+		r := httptest.NewRequest("GET", "/api/feeds", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		got, ok := accessTokenFromRequest(r)
+		if got != want || ok != (want != "") {
+			t.Errorf("%q: got %q, %v", header, got, ok)
+		}
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	cm := NewCSRFManager()
+	a := cm.DeriveKey("access-token")
+	if !bytes.Equal(a, cm.DeriveKey("access-token")) {
+		t.Error("expected the same label to derive the same key")
+	}
+	if bytes.Equal(a, cm.DeriveKey("something-else")) {
+		t.Error("expected different labels to derive different keys")
+	}
+}
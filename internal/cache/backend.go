@@ -0,0 +1,296 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Store is a shared key/value store with per-entry expiry. Implementations
+// must be safe for concurrent use. A missing or expired key is reported as
+// (nil, false, nil).
+type Store interface {
+	Get(key string) ([]byte, bool, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+}
+
+// Invalidator carries invalidation messages between instances. Subscribe
+// delivers every published message, including the subscriber's own, until
+// the returned cancel function is called.
+type Invalidator interface {
+	Publish(msg []byte) error
+	Subscribe(fn func(msg []byte)) (cancel func(), err error)
+}
+
+// keyPrefix namespaces every shared cache key.
+const keyPrefix = "goread2:"
+
+// Backend is the shared tier behind the in-memory caches. Each cache keeps its
+// own per-instance copy for speed; the Backend stores entries where other
+// instances can read them and announces changes so those instances drop
+// their now-stale copies.
+type Backend struct {
+	store       Store
+	invalidator Invalidator
+	origin      string // identifies this instance so it can ignore its own messages
+
+	mu       sync.RWMutex
+	handlers map[string]func(key string) // tier name → local invalidation
+}
+
+// invalidation is the message published when a shared entry changes.
+type invalidation struct {
+	Origin string `json:"o"`
+	Tier   string `json:"t"`
+	Key    string `json:"k"`
+}
+
+// NewBackend creates a shared cache backend. The invalidator may be nil when
+// only one instance runs, in which case the store is still shared but no
+// messages are sent.
+func NewBackend(store Store, invalidator Invalidator) *Backend {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return &Backend{
+		store:       store,
+		invalidator: invalidator,
+		origin:      hex.EncodeToString(b),
+		handlers:    make(map[string]func(key string)),
+	}
+}
+
+// Start subscribes to invalidation messages from other instances. The
+// subscription ends when ctx is cancelled.
+func (b *Backend) Start(ctx context.Context) error {
+	if b.invalidator == nil {
+		return nil
+	}
+	cancel, err := b.invalidator.Subscribe(b.receive)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+	return nil
+}
+
+func (b *Backend) receive(msg []byte) {
+	var inv invalidation
+	if err := json.Unmarshal(msg, &inv); err != nil {
+		log.Printf("Cache: ignoring malformed invalidation message: %v", err)
+		return
+	}
+	if inv.Origin == b.origin {
+		return
+	}
+
+	b.mu.RLock()
+	handler := b.handlers[inv.Tier]
+	b.mu.RUnlock()
+	if handler != nil {
+		handler(inv.Key)
+	}
+}
+
+// Tier returns the named section of the backend for one cache. onInvalidate
+// is called with the key whenever another instance changes an entry, and
+// should drop the local copy.
+func (b *Backend) Tier(name string, onInvalidate func(key string)) *Tier {
+	b.mu.Lock()
+	b.handlers[name] = onInvalidate
+	b.mu.Unlock()
+	return &Tier{backend: b, name: name}
+}
+
+// Tier is one cache's view of a Backend. A nil *Tier is valid: loads miss and
+// writes do nothing, so caches behave as purely local ones without a backend.
+type Tier struct {
+	backend *Backend
+	name    string
+}
+
+// sharedEntry wraps a cached value with its expiry so instances that load it
+// keep the original deadline rather than starting a fresh TTL.
+type sharedEntry struct {
+	ExpiresAt time.Time       `json:"expires_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func (t *Tier) storeKey(key string) string {
+	return keyPrefix + t.name + ":" + key
+}
+
+// Load decodes the shared entry for key into v and returns its expiry.
+// Errors are logged and reported as a miss.
+func (t *Tier) Load(key string, v interface{}) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+
+	data, ok, err := t.backend.store.Get(t.storeKey(key))
+	if err != nil {
+		log.Printf("Cache %s: shared load failed: %v", t.name, err)
+		return time.Time{}, false
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	var entry sharedEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Now().After(entry.ExpiresAt) {
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(entry.Value, v); err != nil {
+		log.Printf("Cache %s: failed to decode shared entry: %v", t.name, err)
+		return time.Time{}, false
+	}
+	return entry.ExpiresAt, true
+}
+
+// Save writes v as the shared entry for key until expiresAt and tells other
+// instances to drop their copies.
+func (t *Tier) Save(key string, v interface{}, expiresAt time.Time) {
+	if t == nil {
+		return
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		t.Delete(key)
+		return
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Cache %s: failed to encode shared entry: %v", t.name, err)
+		return
+	}
+	data, err := json.Marshal(sharedEntry{ExpiresAt: expiresAt, Value: value})
+	if err != nil {
+		log.Printf("Cache %s: failed to encode shared entry: %v", t.name, err)
+		return
+	}
+
+	if err := t.backend.store.Set(t.storeKey(key), data, ttl); err != nil {
+		log.Printf("Cache %s: shared save failed: %v", t.name, err)
+		// Fall through: other instances must still drop their copies.
+	}
+	t.announce(key)
+}
+
+// Delete removes the shared entry for key and tells other instances to drop
+// their copies.
+func (t *Tier) Delete(key string) {
+	if t == nil {
+		return
+	}
+	if err := t.backend.store.Delete(t.storeKey(key)); err != nil {
+		log.Printf("Cache %s: shared delete failed: %v", t.name, err)
+	}
+	t.announce(key)
+}
+
+func (t *Tier) announce(key string) {
+	if t.backend.invalidator == nil {
+		return
+	}
+	msg, err := json.Marshal(invalidation{Origin: t.backend.origin, Tier: t.name, Key: key})
+	if err != nil {
+		return
+	}
+	if err := t.backend.invalidator.Publish(msg); err != nil {
+		log.Printf("Cache %s: failed to publish invalidation: %v", t.name, err)
+	}
+}
+
+// MemoryStore is an in-process Store. Sharing one MemoryStore (with a
+// MemoryInvalidator) between several caches simulates a multi-instance
+// deployment in tests and local development.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(e.expiresAt) {
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+	return append([]byte(nil), e.value...), true, nil
+}
+
+func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{value: append([]byte(nil), value...), expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// MemoryInvalidator is an in-process Invalidator that delivers each message
+// synchronously to every subscriber.
+type MemoryInvalidator struct {
+	mu   sync.RWMutex
+	subs map[int]func(msg []byte)
+	next int
+}
+
+func NewMemoryInvalidator() *MemoryInvalidator {
+	return &MemoryInvalidator{subs: make(map[int]func(msg []byte))}
+}
+
+func (m *MemoryInvalidator) Publish(msg []byte) error {
+	m.mu.RLock()
+	subs := make([]func(msg []byte), 0, len(m.subs))
+	for _, fn := range m.subs {
+		subs = append(subs, fn)
+	}
+	m.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(msg)
+	}
+	return nil
+}
+
+func (m *MemoryInvalidator) Subscribe(fn func(msg []byte)) (func(), error) {
+	m.mu.Lock()
+	id := m.next
+	m.next++
+	m.subs[id] = fn
+	m.mu.Unlock()
+
+	return func() {
+		m.mu.Lock()
+		delete(m.subs, id)
+		m.mu.Unlock()
+	}, nil
+}
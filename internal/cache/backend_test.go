@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// newSharedBackends returns n backends sharing one store and invalidation
+// channel, simulating n application instances.
+func newSharedBackends(t *testing.T, n int) []*Backend {
+	t.Helper()
+	store := NewMemoryStore()
+	invalidator := NewMemoryInvalidator()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	backends := make([]*Backend, n)
+	for i := range backends {
+		backends[i] = NewBackend(store, invalidator)
+		if err := backends[i].Start(ctx); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+	}
+	return backends
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	store := NewMemoryStore()
+	if err := store.Set("k", []byte("v"), 20*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	if v, ok, _ := store.Get("k"); !ok || string(v) != "v" {
+		t.Fatalf("expected hit with value v, got %q, %v", v, ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, ok, _ := store.Get("k"); ok {
+		t.Error("expected entry to expire")
+	}
+}
+
+func TestBackend_IgnoresOwnInvalidations(t *testing.T) {
+	backends := newSharedBackends(t, 2)
+
+	var own, other []string
+	tierA := backends[0].Tier("test", func(key string) { own = append(own, key) })
+	backends[1].Tier("test", func(key string) { other = append(other, key) })
+
+	tierA.Delete("k")
+
+	if len(own) != 0 {
+		t.Errorf("expected sender to ignore its own invalidation, got %v", own)
+	}
+	if len(other) != 1 || other[0] != "k" {
+		t.Errorf("expected other instance to receive [k], got %v", other)
+	}
+}
+
+func TestNilTier(t *testing.T) {
+	var tier *Tier
+	var v int
+	if _, ok := tier.Load("k", &v); ok {
+		t.Error("expected nil tier to miss")
+	}
+	// Must not panic
+	tier.Save("k", 1, time.Now().Add(time.Minute))
+	tier.Delete("k")
+}
+
+func TestUnreadCache_SharedAcrossInstances(t *testing.T) {
+	backends := newSharedBackends(t, 2)
+	a := NewUnreadCache(time.Minute)
+	a.UseBackend(backends[0])
+	b := NewUnreadCache(time.Minute)
+	b.UseBackend(backends[1])
+
+	a.Set(1, map[int]int{10: 5})
+
+	// b has no local copy, so it loads from the shared store
+	counts, hit := b.Get(1)
+	if !hit || counts[10] != 5 {
+		t.Fatalf("expected shared hit with 5 unread, got %v, %v", counts, hit)
+	}
+
+	// Marking an article read on a drops b's local copy and publishes the update
+	a.UpdateCount(1, 10, false, true)
+	counts, hit = b.Get(1)
+	if !hit || counts[10] != 4 {
+		t.Errorf("expected 4 unread after update on other instance, got %v, %v", counts, hit)
+	}
+
+	b.Invalidate(1)
+	if _, hit := a.Get(1); hit {
+		t.Error("expected invalidation on b to clear a")
+	}
+}
+
+func TestFeedListCache_SharedAcrossInstances(t *testing.T) {
+	backends := newSharedBackends(t, 2)
+	a := NewFeedListCache(time.Minute)
+	a.UseBackend(backends[0])
+	b := NewFeedListCache(time.Minute)
+	b.UseBackend(backends[1])
+
+	a.Set([]database.Feed{{ID: 1, Title: "One"}})
+	feeds, hit := b.Get()
+	if !hit || len(feeds) != 1 || feeds[0].Title != "One" {
+		t.Fatalf("expected shared hit, got %v, %v", feeds, hit)
+	}
+
+	a.Invalidate()
+	if _, hit := b.Get(); hit {
+		t.Error("expected invalidation on a to clear b")
+	}
+}
@@ -20,8 +20,12 @@ type FeedListCache struct {
 	maxFeeds  int // 0 means unlimited
 	hits      int64
 	misses    int64
+	tier      *Tier // shared with other instances; nil when running alone
 }
 
+// feedListKey is the shared-tier key for the single cached list.
+const feedListKey = "all"
+
 // NewFeedListCache creates a new feed list cache with the specified TTL.
 // Typical TTL is 15-30 minutes to balance freshness with cost savings.
 // Call Start(ctx) to begin the background cleanup goroutine.
@@ -37,28 +41,48 @@ func (fc *FeedListCache) Start(ctx context.Context) {
 	go fc.cleanupIfExpired(ctx)
 }
 
+// UseBackend shares the cached list with other instances through b, so a
+// subscription change on one instance is seen by the others' refresh runs.
+// Call it before the cache is used.
+func (fc *FeedListCache) UseBackend(b *Backend) {
+	fc.tier = b.Tier("feedlist", func(string) { fc.dropLocal() })
+}
+
 // Get retrieves the cached feed list if it exists and is not expired.
 // Returns the feeds and true if cache hit, nil and false if cache miss.
 func (fc *FeedListCache) Get() ([]database.Feed, bool) {
+	if feeds, ok := fc.getLocal(); ok {
+		atomic.AddInt64(&fc.hits, 1)
+		return feeds, true
+	}
+
+	var feeds []database.Feed
+	if expiresAt, ok := fc.tier.Load(feedListKey, &feeds); ok && feeds != nil {
+		fc.setLocal(feeds, expiresAt)
+		atomic.AddInt64(&fc.hits, 1)
+		return feeds, true
+	}
+
+	atomic.AddInt64(&fc.misses, 1)
+	return nil, false
+}
+
+func (fc *FeedListCache) getLocal() ([]database.Feed, bool) {
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
 
 	if fc.feeds == nil {
-		atomic.AddInt64(&fc.misses, 1)
 		return nil, false
 	}
 
 	// Check if cache has expired
 	if time.Now().After(fc.refreshAt) {
-		atomic.AddInt64(&fc.misses, 1)
 		return nil, false
 	}
 
 	// Return a copy to prevent external modification
 	result := make([]database.Feed, len(fc.feeds))
 	copy(result, fc.feeds)
-
-	atomic.AddInt64(&fc.hits, 1)
 	return result, true
 }
 
@@ -74,11 +98,18 @@ func (fc *FeedListCache) SetMaxFeeds(n int) {
 // Set stores the feed list in the cache with the configured TTL. If the list
 // exceeds the configured maxFeeds limit it is not cached.
 func (fc *FeedListCache) Set(feeds []database.Feed) {
+	expiresAt := time.Now().Add(fc.ttl)
+	if fc.setLocal(feeds, expiresAt) {
+		fc.tier.Save(feedListKey, feeds, expiresAt)
+	}
+}
+
+func (fc *FeedListCache) setLocal(feeds []database.Feed, expiresAt time.Time) bool {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 
 	if fc.maxFeeds > 0 && len(feeds) > fc.maxFeeds {
-		return
+		return false
 	}
 
 	// Store a copy to prevent external modification
@@ -86,12 +117,18 @@ func (fc *FeedListCache) Set(feeds []database.Feed) {
 	copy(cached, feeds)
 
 	fc.feeds = cached
-	fc.refreshAt = time.Now().Add(fc.ttl)
+	fc.refreshAt = expiresAt
+	return true
 }
 
 // Invalidate clears the cached feed list, forcing a fresh fetch on next request.
 // Use this when users subscribe/unsubscribe from feeds to ensure immediate updates.
 func (fc *FeedListCache) Invalidate() {
+	fc.dropLocal()
+	fc.tier.Delete(feedListKey)
+}
+
+func (fc *FeedListCache) dropLocal() {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 
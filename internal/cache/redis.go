@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisPoolSize is the number of connections kept for reuse.
+	redisPoolSize = 8
+
+	// redisTimeout bounds each command. Cache operations are best-effort, so a
+	// slow server should fall back to the database rather than stall requests.
+	redisTimeout = time.Second
+
+	// redisInvalidationChannel is the pub/sub channel for invalidation messages.
+	redisInvalidationChannel = "goread2:cache-invalidations"
+)
+
+// RedisStore is a Store and Invalidator for servers speaking the Redis
+// protocol, such as Redis, Valkey or Cloud Memorystore.
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a store for the server at addr (host:port). Pass an
+// empty password if the server doesn't require AUTH. Connections are opened
+// lazily.
+func NewRedisStore(addr, password string) *RedisStore {
+	return &RedisStore{
+		client: redis.NewClient(&redis.Options{
+			Addr:         addr,
+			Password:     password,
+			PoolSize:     redisPoolSize,
+			DialTimeout:  redisTimeout,
+			ReadTimeout:  redisTimeout,
+			WriteTimeout: redisTimeout,
+		}),
+	}
+}
+
+func (s *RedisStore) Get(key string) ([]byte, bool, error) {
+	value, err := s.client.Get(context.Background(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	// A zero TTL would store the entry forever
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	return s.client.Set(context.Background(), key, value, ttl).Err()
+}
+
+func (s *RedisStore) Delete(key string) error {
+	return s.client.Del(context.Background(), key).Err()
+}
+
+func (s *RedisStore) Publish(msg []byte) error {
+	return s.client.Publish(context.Background(), redisInvalidationChannel, msg).Err()
+}
+
+// Subscribe listens for invalidation messages. The client reconnects if the
+// subscription drops; messages published while disconnected are lost, and
+// the affected entries are corrected when their TTL expires. The
+// subscription is confirmed before returning so configuration errors
+// surface at startup.
+func (s *RedisStore) Subscribe(fn func(msg []byte)) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	pubsub := s.client.Subscribe(ctx, redisInvalidationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to invalidations at %s: %w", s.client.Options().Addr, err)
+	}
+
+	messages := pubsub.Channel()
+	go func() {
+		for msg := range messages {
+			fn([]byte(msg.Payload))
+		}
+	}()
+	return func() { _ = pubsub.Close() }, nil
+}
+
+// Close releases the store's connections.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
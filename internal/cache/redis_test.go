@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisStore_GetSetDelete(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	store := NewRedisStore(server.Addr(), "secret")
+	defer func() { _ = store.Close() }()
+
+	if _, ok, err := store.Get("missing"); err != nil || ok {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+
+	value := "line one\r\nline two" // must survive binary-safe framing
+	if err := store.Set("k", []byte(value), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got, ok, err := store.Get("k")
+	if err != nil || !ok || string(got) != value {
+		t.Fatalf("expected %q, got %q ok=%v err=%v", value, got, ok, err)
+	}
+
+	if err := store.Delete("k"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := store.Get("k"); ok {
+		t.Error("expected miss after delete")
+	}
+}
+
+func TestRedisStore_SetExpires(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(server.Addr(), "")
+	defer func() { _ = store.Close() }()
+
+	if err := store.Set("k", []byte("v"), 0); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if server.TTL("k") <= 0 {
+		t.Error("expected a zero TTL to still expire the entry")
+	}
+	server.FastForward(time.Second)
+	if _, ok, _ := store.Get("k"); ok {
+		t.Error("expected miss after expiry")
+	}
+}
+
+func TestRedisStore_AuthFailure(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	store := NewRedisStore(server.Addr(), "wrong")
+	defer func() { _ = store.Close() }()
+
+	if _, _, err := store.Get("k"); err == nil {
+		t.Error("expected error with wrong password")
+	}
+}
+
+func TestRedisStore_PublishSubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(server.Addr(), "")
+	defer func() { _ = store.Close() }()
+
+	received := make(chan string, 1)
+	cancel, err := store.Subscribe(func(msg []byte) { received <- string(msg) })
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	if err := store.Publish([]byte("hello")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	select {
+	case msg := <-received:
+		if msg != "hello" {
+			t.Errorf("expected hello, got %q", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
+
+func TestRedisStore_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	store := NewRedisStore(addr, "")
+	defer func() { _ = store.Close() }()
+	if _, _, err := store.Get("k"); err == nil {
+		t.Error("expected error for unreachable server")
+	}
+	if _, err := store.Subscribe(func([]byte) {}); err == nil {
+		t.Error("expected Subscribe to fail for unreachable server")
+	}
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,6 +19,7 @@ type UnreadCache struct {
 	maxUsers  int // 0 means unlimited
 	hits      int64
 	misses    int64
+	tier      *Tier // shared with other instances; nil when running alone
 }
 
 // NewUnreadCache creates a new unread count cache with the specified TTL.
@@ -36,21 +38,48 @@ func (uc *UnreadCache) Start(ctx context.Context) {
 	go uc.cleanupExpiredEntries(ctx)
 }
 
+// UseBackend shares cached counts with other instances through b, so a
+// mark-read handled by one instance isn't masked by stale counts on another.
+// Call it before the cache is used.
+func (uc *UnreadCache) UseBackend(b *Backend) {
+	uc.tier = b.Tier("unread", func(key string) {
+		if userID, err := strconv.Atoi(key); err == nil {
+			uc.dropLocal(userID)
+		}
+	})
+}
+
 // Get retrieves cached unread counts for a user if they exist and are not expired.
 // Returns the counts and true if cache hit, nil and false if cache miss.
 func (uc *UnreadCache) Get(userID int) (map[int]int, bool) {
+	if counts, ok := uc.getLocal(userID); ok {
+		atomic.AddInt64(&uc.hits, 1)
+		return counts, true
+	}
+
+	// Another instance may have cached the counts already
+	var counts map[int]int
+	if expiresAt, ok := uc.tier.Load(userKey(userID), &counts); ok && counts != nil {
+		uc.setLocal(userID, counts, expiresAt)
+		atomic.AddInt64(&uc.hits, 1)
+		return counts, true
+	}
+
+	atomic.AddInt64(&uc.misses, 1)
+	return nil, false
+}
+
+func (uc *UnreadCache) getLocal(userID int) (map[int]int, bool) {
 	uc.mu.RLock()
 	defer uc.mu.RUnlock()
 
 	counts, exists := uc.counts[userID]
 	if !exists {
-		atomic.AddInt64(&uc.misses, 1)
 		return nil, false
 	}
 
 	// Check if cache has expired
 	if time.Now().After(uc.refreshAt[userID]) {
-		atomic.AddInt64(&uc.misses, 1)
 		return nil, false
 	}
 
@@ -59,8 +88,6 @@ func (uc *UnreadCache) Get(userID int) (map[int]int, bool) {
 	for feedID, count := range counts {
 		result[feedID] = count
 	}
-
-	atomic.AddInt64(&uc.hits, 1)
 	return result, true
 }
 
@@ -75,6 +102,12 @@ func (uc *UnreadCache) SetMaxUsers(n int) {
 
 // Set stores unread counts for a user in the cache with the configured TTL.
 func (uc *UnreadCache) Set(userID int, counts map[int]int) {
+	expiresAt := time.Now().Add(uc.ttl)
+	uc.setLocal(userID, counts, expiresAt)
+	uc.tier.Save(userKey(userID), counts, expiresAt)
+}
+
+func (uc *UnreadCache) setLocal(userID int, counts map[int]int, expiresAt time.Time) {
 	uc.mu.Lock()
 	defer uc.mu.Unlock()
 
@@ -101,7 +134,7 @@ func (uc *UnreadCache) Set(userID int, counts map[int]int) {
 	}
 
 	uc.counts[userID] = cached
-	uc.refreshAt[userID] = time.Now().Add(uc.ttl)
+	uc.refreshAt[userID] = expiresAt
 }
 
 // UpdateCount incrementally updates the cached count when an article's read status changes.
@@ -113,36 +146,73 @@ func (uc *UnreadCache) Set(userID int, counts map[int]int) {
 //   - wasRead: Previous read status of the article
 //   - nowRead: New read status of the article
 func (uc *UnreadCache) UpdateCount(userID, feedID int, wasRead, nowRead bool) {
-	uc.mu.Lock()
-	defer uc.mu.Unlock()
+	// Update count based on state transition
+	if !wasRead && nowRead {
+		// Article marked as read - decrement unread count
+		uc.adjust(userID, map[int]int{feedID: -1})
+	} else if wasRead && !nowRead {
+		// Article marked as unread - increment unread count
+		uc.adjust(userID, map[int]int{feedID: 1})
+	}
+	// If wasRead == nowRead, no change needed
+}
 
-	counts, exists := uc.counts[userID]
-	if !exists {
-		return // No cache to update
+// DecrementCounts subtracts the number of articles marked read in each feed from
+// the cached counts, so bulk operations that know their per-feed totals can
+// keep the cache warm instead of invalidating it.
+func (uc *UnreadCache) DecrementCounts(userID int, readByFeed map[int]int) {
+	deltas := make(map[int]int, len(readByFeed))
+	for feedID, n := range readByFeed {
+		deltas[feedID] = -n
 	}
+	uc.adjust(userID, deltas)
+}
 
-	// Check if cache is still valid - don't update expired cache
-	if time.Now().After(uc.refreshAt[userID]) {
+// AdjustCounts adds signed per-feed deltas to the cached counts, for bulk
+// operations that mark some articles read and others unread.
+func (uc *UnreadCache) AdjustCounts(userID int, deltas map[int]int) {
+	uc.adjust(userID, deltas)
+}
+
+// adjust applies per-feed deltas to the cached counts, never going below zero,
+// and publishes the result to other instances.
+func (uc *UnreadCache) adjust(userID int, deltas map[int]int) {
+	uc.mu.Lock()
+	counts, exists := uc.counts[userID]
+	// Don't update an expired cache
+	if !exists || time.Now().After(uc.refreshAt[userID]) {
+		uc.mu.Unlock()
+		// Nothing to update here, but another instance's copy is now stale
+		uc.tier.Delete(userKey(userID))
 		return
 	}
 
-	// Update count based on state transition
-	if !wasRead && nowRead {
-		// Article marked as read - decrement unread count
-		counts[feedID]--
+	for feedID, delta := range deltas {
+		counts[feedID] += delta
 		if counts[feedID] < 0 {
 			counts[feedID] = 0 // Safety check
 		}
-	} else if wasRead && !nowRead {
-		// Article marked as unread - increment unread count
-		counts[feedID]++
 	}
-	// If wasRead == nowRead, no change needed
+	updated := make(map[int]int, len(counts))
+	for feedID, count := range counts {
+		updated[feedID] = count
+	}
+	expiresAt := uc.refreshAt[userID]
+	uc.mu.Unlock()
+
+	// Keep the original expiry so counts are still re-read from the database
+	// periodically, however often they are adjusted.
+	uc.tier.Save(userKey(userID), updated, expiresAt)
 }
 
 // Invalidate removes cached counts for a user, forcing a fresh fetch on next request.
 // Use this for complex operations where incremental updates are difficult (e.g., batch operations).
 func (uc *UnreadCache) Invalidate(userID int) {
+	uc.dropLocal(userID)
+	uc.tier.Delete(userKey(userID))
+}
+
+func (uc *UnreadCache) dropLocal(userID int) {
 	uc.mu.Lock()
 	defer uc.mu.Unlock()
 
@@ -202,6 +272,10 @@ func (uc *UnreadCache) GetStats() CacheStats {
 	}
 }
 
+func userKey(userID int) string {
+	return strconv.Itoa(userID)
+}
+
 // cleanupExpiredEntries removes expired cache entries to prevent memory leak.
 // Runs every 5 minutes to clean up entries that have passed their expiry time.
 func (uc *UnreadCache) cleanupExpiredEntries(ctx context.Context) {
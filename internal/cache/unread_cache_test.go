@@ -179,6 +179,46 @@ func TestUnreadCache_UpdateCountNegativeSafety(t *testing.T) {
 	}
 }
 
+func TestUnreadCache_DecrementCounts(t *testing.T) {
+	cache := NewUnreadCache(60 * time.Second)
+	userID := 1
+
+	cache.Set(userID, map[int]int{10: 5, 20: 2, 30: 7})
+
+	// Feed 20 is over-decremented and must floor at zero
+	cache.DecrementCounts(userID, map[int]int{10: 3, 20: 4})
+
+	retrieved, hit := cache.Get(userID)
+	if !hit {
+		t.Fatal("DecrementCounts should keep the cache entry")
+	}
+	expected := map[int]int{10: 2, 20: 0, 30: 7}
+	for feedID, want := range expected {
+		if retrieved[feedID] != want {
+			t.Errorf("feed %d: expected %d, got %d", feedID, want, retrieved[feedID])
+		}
+	}
+
+	// No cache entry - should not create one
+	cache.DecrementCounts(999, map[int]int{10: 1})
+	if _, hit := cache.Get(999); hit {
+		t.Error("DecrementCounts should not create cache for non-existent user")
+	}
+}
+
+func TestUnreadCache_AdjustCounts(t *testing.T) {
+	cache := NewUnreadCache(60 * time.Second)
+	userID := 1
+
+	cache.Set(userID, map[int]int{10: 5, 20: 2})
+	cache.AdjustCounts(userID, map[int]int{10: -2, 20: 3})
+
+	retrieved, _ := cache.Get(userID)
+	if retrieved[10] != 3 || retrieved[20] != 5 {
+		t.Errorf("expected counts {10:3 20:5}, got %v", retrieved)
+	}
+}
+
 func TestUnreadCache_Invalidate(t *testing.T) {
 	cache := NewUnreadCache(60 * time.Second)
 	userID := 1
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"regexp"
 	"strconv"
@@ -22,6 +23,7 @@ type Config struct {
 
 	// Database
 	DatabasePath string
+	DatabaseURL  string // PostgreSQL connection URL; takes precedence over Datastore and SQLite
 
 	// OAuth
 	GoogleClientID     string
@@ -39,6 +41,27 @@ type Config struct {
 	// Server
 	Port string
 
+	// Shared cache (optional; enables cross-instance caching)
+	CacheRedisAddr     string // host:port of a Redis-protocol server
+	CacheRedisPassword string
+
+	// Email (optional; enables digests). SMTP takes precedence over MailDevDir.
+	SMTPAddr     string // host:port of an SMTP server
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string // sender address, e.g. "GoRead2 <digest@example.com>"
+	MailDevDir   string // write messages as .eml files here instead of sending
+
+	// EmailSignInEnabled turns on passwordless sign-in links; needs email
+	EmailSignInEnabled bool
+
+	// Inbound email (optional; enables newsletter addresses)
+	InboundEmailDomain string // domain whose mail is posted to /inbound/email
+	InboundEmailSecret string // HTTP Basic password required on /inbound/email
+
+	// Feed directory
+	DirectoryMinSubscribers int // subscribers a feed needs before it is listed or recommended
+
 	// Feed Rate Limiting
 	RateLimitRequestsPerMinute int           // Requests per minute per domain
 	RateLimitBurstSize         int           // Burst allowance per domain
@@ -73,6 +96,7 @@ func Load() *Config {
 
 		// Database
 		DatabasePath: getEnvOrDefault("DATABASE_PATH", "./goread2.db"),
+		DatabaseURL:  os.Getenv("DATABASE_URL"),
 
 		// OAuth
 		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
@@ -90,6 +114,26 @@ func Load() *Config {
 		// Server
 		Port: getEnvOrDefault("PORT", "8080"),
 
+		// Shared cache
+		CacheRedisAddr:     os.Getenv("CACHE_REDIS_ADDR"),
+		CacheRedisPassword: os.Getenv("CACHE_REDIS_PASSWORD"),
+
+		// Email
+		SMTPAddr:     os.Getenv("SMTP_ADDR"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		MailFrom:     os.Getenv("MAIL_FROM"),
+		MailDevDir:   os.Getenv("MAIL_DEV_DIR"),
+
+		EmailSignInEnabled: parseBool(os.Getenv("EMAIL_SIGN_IN_ENABLED"), false),
+
+		// Inbound email
+		InboundEmailDomain: strings.ToLower(strings.TrimSpace(os.Getenv("INBOUND_EMAIL_DOMAIN"))),
+		InboundEmailSecret: os.Getenv("INBOUND_EMAIL_SECRET"),
+
+		// Feed directory
+		DirectoryMinSubscribers: parseInt(os.Getenv("DIRECTORY_MIN_SUBSCRIBERS"), 3),
+
 		// Feed Rate Limiting
 		RateLimitRequestsPerMinute: parseInt(os.Getenv("RATE_LIMIT_REQUESTS_PER_MINUTE"), 120),
 		RateLimitBurstSize:         parseInt(os.Getenv("RATE_LIMIT_BURST_SIZE"), 30),
@@ -106,6 +150,17 @@ func Load() *Config {
 	return globalConfig
 }
 
+// BaseURL returns the app's scheme and host, taken from GOOGLE_REDIRECT_URL
+// rather than request headers so it can't be spoofed. It is empty when the
+// redirect URL isn't set.
+func (c *Config) BaseURL() string {
+	u, err := url.Parse(c.GoogleRedirectURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
 // validateConfig checks that configuration values are within acceptable ranges.
 func validateConfig(cfg *Config) error {
 	if cfg.RateLimitRequestsPerMinute <= 0 {
@@ -129,6 +184,24 @@ func validateConfig(cfg *Config) error {
 	if cfg.SchedulerCleanupInterval <= 0 {
 		return fmt.Errorf("SCHEDULER_CLEANUP_INTERVAL must be positive, got %v", cfg.SchedulerCleanupInterval)
 	}
+	if cfg.SMTPAddr != "" && cfg.MailFrom == "" {
+		return fmt.Errorf("MAIL_FROM is required when SMTP_ADDR is set")
+	}
+	if cfg.EmailSignInEnabled && cfg.SMTPAddr == "" && cfg.MailDevDir == "" {
+		return fmt.Errorf("SMTP_ADDR or MAIL_DEV_DIR is required when EMAIL_SIGN_IN_ENABLED is set")
+	}
+	if cfg.InboundEmailDomain != "" && cfg.InboundEmailSecret == "" {
+		return fmt.Errorf("INBOUND_EMAIL_SECRET is required when INBOUND_EMAIL_DOMAIN is set")
+	}
+	if cfg.DatabaseURL != "" && !strings.HasPrefix(cfg.DatabaseURL, "postgres://") && !strings.HasPrefix(cfg.DatabaseURL, "postgresql://") {
+		return fmt.Errorf("DATABASE_URL must be a postgres:// or postgresql:// URL")
+	}
+	if cfg.DirectoryMinSubscribers <= 0 {
+		return fmt.Errorf("DIRECTORY_MIN_SUBSCRIBERS must be positive, got %d", cfg.DirectoryMinSubscribers)
+	}
+	if cfg.DirectoryMinSubscribers < 3 {
+		log.Printf("WARNING: DIRECTORY_MIN_SUBSCRIBERS=%d lets the feed directory reveal what individual users read", cfg.DirectoryMinSubscribers)
+	}
 	if cfg.SchedulerMinInterval > cfg.SchedulerUpdateWindow {
 		return fmt.Errorf("SCHEDULER_MIN_INTERVAL (%v) must be less than SCHEDULER_UPDATE_WINDOW (%v)",
 			cfg.SchedulerMinInterval, cfg.SchedulerUpdateWindow)
@@ -508,6 +508,23 @@ func TestParseIntMalformed(t *testing.T) {
 	}
 }
 
+func TestConfigBaseURL(t *testing.T) {
+	tests := []struct {
+		redirect string
+		want     string
+	}{
+		{"https://reader.example.com/auth/callback", "https://reader.example.com"},
+		{"http://localhost:8080/auth/callback", "http://localhost:8080"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		cfg := &Config{GoogleRedirectURL: tt.redirect}
+		if got := cfg.BaseURL(); got != tt.want {
+			t.Errorf("BaseURL() with redirect %q = %q, want %q", tt.redirect, got, tt.want)
+		}
+	}
+}
+
 func clearConfigEnvVars() {
 	envVars := []string{
 		"SUBSCRIPTION_ENABLED",
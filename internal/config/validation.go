@@ -169,12 +169,23 @@ func WarnAboutUnhandledEnvVars() {
 		"CSRF_SECRET":                    true,
 		"ENVIRONMENT":                    true,
 		"DATABASE_PATH":                  true,
+		"DATABASE_URL":                   true,
 		"RATE_LIMIT_REQUESTS_PER_MINUTE": true,
 		"RATE_LIMIT_BURST_SIZE":          true,
 		"SCHEDULER_UPDATE_WINDOW":        true,
 		"SCHEDULER_MIN_INTERVAL":         true,
 		"SCHEDULER_MAX_CONCURRENT":       true,
 		"SCHEDULER_CLEANUP_INTERVAL":     true,
+		"CACHE_REDIS_ADDR":               true,
+		"CACHE_REDIS_PASSWORD":           true,
+		"SMTP_ADDR":                      true,
+		"SMTP_USERNAME":                  true,
+		"SMTP_PASSWORD":                  true,
+		"MAIL_FROM":                      true,
+		"MAIL_DEV_DIR":                   true,
+		"INBOUND_EMAIL_DOMAIN":           true,
+		"INBOUND_EMAIL_SECRET":           true,
+		"DIRECTORY_MIN_SUBSCRIBERS":      true,
 	}
 
 	// Check all environment variables
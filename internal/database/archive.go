@@ -0,0 +1,508 @@
+package database
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat and ArchiveVersion identify the archives written by
+// ExportArchive. Bump the version when a record's shape changes in a way
+// an older ImportArchive would misread, or a new record type is added.
+// Version 2 added user_identity records.
+const (
+	ArchiveFormat  = "goread2-archive"
+	ArchiveVersion = 2
+)
+
+// ErrArchiveTargetNotEmpty is returned when importing into a database that already has users or feeds.
+var ErrArchiveTargetNotEmpty = errors.New("target database is not empty")
+
+// errStopWalk ends a ForEach walk early without it being reported as a failure.
+var errStopWalk = errors.New("stop")
+
+const auditLogPageSize = 500
+
+// An archive is a gzip-compressed stream of JSON values: an archiveHeader,
+// then one archiveRecord per row, parents before children (users, feeds,
+// articles, user_feeds, user_articles, sessions, user_identities,
+// audit_logs), then an "end"
+// record with the counts, so a truncated file is detected on import. Rows
+// keep their source IDs; ImportArchive maps them to the IDs the target
+// assigns.
+type archiveHeader struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type archiveRecord struct {
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Counts *ArchiveCounts  `json:"counts,omitempty"`
+}
+
+type archiveUserFeed struct {
+	UserID int `json:"user_id"`
+	FeedID int `json:"feed_id"`
+}
+
+// ArchiveCounts is how many rows of each kind an archive or database holds.
+type ArchiveCounts struct {
+	Users        int `json:"users"`
+	Feeds        int `json:"feeds"`
+	Articles     int `json:"articles"`
+	UserFeeds    int `json:"user_feeds"`
+	UserArticles int `json:"user_articles"`
+	Sessions     int `json:"sessions"`
+	Identities   int `json:"identities"`
+	AuditLogs    int `json:"audit_logs"`
+}
+
+func (c ArchiveCounts) String() string {
+	return fmt.Sprintf("%d users, %d feeds, %d articles, %d subscriptions, %d article statuses, %d sessions, %d identities, %d audit logs",
+		c.Users, c.Feeds, c.Articles, c.UserFeeds, c.UserArticles, c.Sessions, c.Identities, c.AuditLogs)
+}
+
+// ArchiveImportResult reports what an import read, wrote and left out.
+// Skipped rows refer to a parent the archive doesn't contain, or are
+// articles whose URL another article in the archive already has (the SQL
+// backends keep article URLs unique); those duplicates are merged into the
+// first article and their read state is dropped.
+type ArchiveImportResult struct {
+	Archive  ArchiveCounts
+	Restored ArchiveCounts
+	Skipped  ArchiveCounts
+}
+
+type archiveWriter struct {
+	enc    *json.Encoder
+	counts ArchiveCounts
+}
+
+func (w *archiveWriter) write(recordType string, v interface{}, count *int) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := w.enc.Encode(archiveRecord{Type: recordType, Data: data}); err != nil {
+		return err
+	}
+	*count++
+	return nil
+}
+
+// ExportArchive streams every user, feed, article, subscription, article
+// status, session, linked identity and audit log in db to w.
+func ExportArchive(db Database, w io.Writer) (*ArchiveCounts, error) {
+	gz := gzip.NewWriter(w)
+	aw := &archiveWriter{enc: json.NewEncoder(gz)}
+	c := &aw.counts
+
+	if err := aw.enc.Encode(archiveHeader{Format: ArchiveFormat, Version: ArchiveVersion, CreatedAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+
+	if err := db.ForEachUser(func(u User) error { return aw.write("user", u, &c.Users) }); err != nil {
+		return nil, fmt.Errorf("exporting users: %w", err)
+	}
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("exporting feeds: %w", err)
+	}
+	for _, f := range feeds {
+		if err := aw.write("feed", f, &c.Feeds); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.ForEachArticle(func(a Article) error { return aw.write("article", a, &c.Articles) }); err != nil {
+		return nil, fmt.Errorf("exporting articles: %w", err)
+	}
+	subscriptions, err := db.GetAllFeedSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("exporting subscriptions: %w", err)
+	}
+	for _, s := range subscriptions {
+		if err := aw.write("user_feed", archiveUserFeed{UserID: s.UserID, FeedID: s.FeedID}, &c.UserFeeds); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.ForEachUserArticle(func(ua UserArticle) error { return aw.write("user_article", ua, &c.UserArticles) }); err != nil {
+		return nil, fmt.Errorf("exporting article statuses: %w", err)
+	}
+	if err := db.ForEachSession(func(s Session) error { return aw.write("session", s, &c.Sessions) }); err != nil {
+		return nil, fmt.Errorf("exporting sessions: %w", err)
+	}
+	if err := db.ForEachUserIdentity(func(i UserIdentity) error { return aw.write("user_identity", i, &c.Identities) }); err != nil {
+		return nil, fmt.Errorf("exporting identities: %w", err)
+	}
+	if err := forEachAuditLog(db, func(l AuditLog) error { return aw.write("audit_log", l, &c.AuditLogs) }); err != nil {
+		return nil, fmt.Errorf("exporting audit logs: %w", err)
+	}
+
+	if err := aw.enc.Encode(archiveRecord{Type: "end", Counts: c}); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func forEachAuditLog(db Database, fn func(AuditLog) error) error {
+	for offset := 0; ; offset += auditLogPageSize {
+		logs, err := db.GetAuditLogs(auditLogPageSize, offset, nil)
+		if err != nil {
+			return err
+		}
+		for _, l := range logs {
+			if err := fn(l); err != nil {
+				return err
+			}
+		}
+		if len(logs) < auditLogPageSize {
+			return nil
+		}
+	}
+}
+
+// archiveImporter restores records in archive order, mapping each source ID
+// to the one the target assigned.
+type archiveImporter struct {
+	db           Database
+	result       ArchiveImportResult
+	users        map[int]int
+	feeds        map[int]int
+	articles     map[int]int
+	articleURLs  map[string]int
+	mergedSource map[int]bool
+}
+
+// ImportArchive restores an archive written by ExportArchive into db, which
+// must not have any users or feeds yet. Restoring stops at the first error;
+// db is then partly filled and should be discarded.
+func ImportArchive(db Database, r io.Reader) (*ArchiveImportResult, error) {
+	if empty, err := isArchiveTargetEmpty(db); err != nil {
+		return nil, err
+	} else if !empty {
+		return nil, ErrArchiveTargetNotEmpty
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not an archive: %w", err)
+	}
+	dec := json.NewDecoder(gz)
+
+	var header archiveHeader
+	if err := dec.Decode(&header); err != nil || header.Format != ArchiveFormat {
+		return nil, fmt.Errorf("not an archive: missing %s header", ArchiveFormat)
+	}
+	if header.Version < 1 || header.Version > ArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is not supported (this build reads up to %d)", header.Version, ArchiveVersion)
+	}
+
+	im := &archiveImporter{
+		db:           db,
+		users:        make(map[int]int),
+		feeds:        make(map[int]int),
+		articles:     make(map[int]int),
+		articleURLs:  make(map[string]int),
+		mergedSource: make(map[int]bool),
+	}
+	var read ArchiveCounts
+	for {
+		var record archiveRecord
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return &im.result, fmt.Errorf("archive is truncated after %s", read)
+			}
+			return &im.result, fmt.Errorf("reading archive: %w", err)
+		}
+		if record.Type == "end" {
+			if record.Counts == nil || *record.Counts != read {
+				return &im.result, fmt.Errorf("archive is inconsistent: read %s", read)
+			}
+			im.result.Archive = read
+			return &im.result, nil
+		}
+		if err := im.restore(record, &read); err != nil {
+			return &im.result, err
+		}
+	}
+}
+
+func (im *archiveImporter) restore(record archiveRecord, read *ArchiveCounts) error {
+	restored, skipped := &im.result.Restored, &im.result.Skipped
+
+	switch record.Type {
+	case "user":
+		read.Users++
+		var u User
+		if err := json.Unmarshal(record.Data, &u); err != nil {
+			return err
+		}
+		id, err := im.restoreUser(u)
+		if err != nil {
+			return fmt.Errorf("restoring user %d: %w", u.ID, err)
+		}
+		im.users[u.ID] = id
+		restored.Users++
+
+	case "feed":
+		read.Feeds++
+		var f Feed
+		if err := json.Unmarshal(record.Data, &f); err != nil {
+			return err
+		}
+		sourceID := f.ID
+		f.ID = 0
+		if err := im.db.AddFeed(&f); err != nil {
+			return fmt.Errorf("restoring feed %d: %w", sourceID, err)
+		}
+		im.feeds[sourceID] = f.ID
+		restored.Feeds++
+
+	case "article":
+		read.Articles++
+		var a Article
+		if err := json.Unmarshal(record.Data, &a); err != nil {
+			return err
+		}
+		sourceID := a.ID
+		feedID, ok := im.feeds[a.FeedID]
+		if !ok {
+			skipped.Articles++
+			return nil
+		}
+		if existing, ok := im.articleURLs[a.URL]; ok {
+			im.articles[sourceID] = existing
+			im.mergedSource[sourceID] = true
+			skipped.Articles++
+			return nil
+		}
+		a.ID, a.FeedID = 0, feedID
+		if err := im.db.AddArticle(&a); err != nil {
+			return fmt.Errorf("restoring article %d: %w", sourceID, err)
+		}
+		im.articles[sourceID] = a.ID
+		im.articleURLs[a.URL] = a.ID
+		restored.Articles++
+
+	case "user_feed":
+		read.UserFeeds++
+		var s archiveUserFeed
+		if err := json.Unmarshal(record.Data, &s); err != nil {
+			return err
+		}
+		userID, userOK := im.users[s.UserID]
+		feedID, feedOK := im.feeds[s.FeedID]
+		if !userOK || !feedOK {
+			skipped.UserFeeds++
+			return nil
+		}
+		if err := im.db.SubscribeUserToFeed(userID, feedID); err != nil {
+			return fmt.Errorf("restoring subscription of user %d to feed %d: %w", s.UserID, s.FeedID, err)
+		}
+		restored.UserFeeds++
+
+	case "user_article":
+		read.UserArticles++
+		var ua UserArticle
+		if err := json.Unmarshal(record.Data, &ua); err != nil {
+			return err
+		}
+		userID, userOK := im.users[ua.UserID]
+		articleID, articleOK := im.articles[ua.ArticleID]
+		if !userOK || !articleOK || im.mergedSource[ua.ArticleID] {
+			skipped.UserArticles++
+			return nil
+		}
+		if err := im.db.SetUserArticleStatus(userID, articleID, ua.IsRead, ua.IsStarred); err != nil {
+			return fmt.Errorf("restoring status of article %d for user %d: %w", ua.ArticleID, ua.UserID, err)
+		}
+		if ua.ReadProgress > 0 {
+			if err := im.db.SetUserArticleProgress(userID, articleID, ua.ReadProgress); err != nil {
+				return fmt.Errorf("restoring progress of article %d for user %d: %w", ua.ArticleID, ua.UserID, err)
+			}
+		}
+		restored.UserArticles++
+
+	case "session":
+		read.Sessions++
+		var s Session
+		if err := json.Unmarshal(record.Data, &s); err != nil {
+			return err
+		}
+		userID, ok := im.users[s.UserID]
+		if !ok {
+			skipped.Sessions++
+			return nil
+		}
+		s.UserID = userID
+		if err := im.db.CreateSession(&s); err != nil {
+			return fmt.Errorf("restoring session: %w", err)
+		}
+		restored.Sessions++
+
+	case "user_identity":
+		read.Identities++
+		var identity UserIdentity
+		if err := json.Unmarshal(record.Data, &identity); err != nil {
+			return err
+		}
+		userID, ok := im.users[identity.UserID]
+		if !ok {
+			skipped.Identities++
+			return nil
+		}
+		identity.UserID = userID
+		if err := im.db.CreateUserIdentity(&identity); err != nil {
+			return fmt.Errorf("restoring %s identity: %w", identity.Provider, err)
+		}
+		restored.Identities++
+
+	case "audit_log":
+		read.AuditLogs++
+		var l AuditLog
+		if err := json.Unmarshal(record.Data, &l); err != nil {
+			return err
+		}
+		// The emails identify users who have since been deleted; their old
+		// IDs could belong to someone else in the target
+		l.ID = 0
+		l.AdminUserID = im.users[l.AdminUserID]
+		l.TargetUserID = im.users[l.TargetUserID]
+		if err := im.db.CreateAuditLog(&l); err != nil {
+			return fmt.Errorf("restoring audit log: %w", err)
+		}
+		restored.AuditLogs++
+
+	default:
+		return fmt.Errorf("unknown archive record type %q", record.Type)
+	}
+	return nil
+}
+
+// restoreUser creates u and then applies the fields CreateUser doesn't set.
+func (im *archiveImporter) restoreUser(u User) (int, error) {
+	user := u
+	user.ID = 0
+	if err := im.db.CreateUser(&user); err != nil {
+		return 0, err
+	}
+	if user.MaxArticlesOnFeedAdd != u.MaxArticlesOnFeedAdd {
+		if err := im.db.UpdateUserMaxArticlesOnFeedAdd(user.ID, u.MaxArticlesOnFeedAdd); err != nil {
+			return 0, err
+		}
+	}
+	if u.IsAdmin {
+		if err := im.db.SetUserAdmin(user.ID, true); err != nil {
+			return 0, err
+		}
+	}
+	if u.FreeMonthsRemaining > 0 {
+		if err := im.db.GrantFreeMonths(user.ID, u.FreeMonthsRemaining); err != nil {
+			return 0, err
+		}
+	}
+	if u.DigestFrequency != "" {
+		prefs := DigestPreferences{Frequency: u.DigestFrequency, Timezone: u.DigestTimezone, Hour: u.DigestHour, Weekday: u.DigestWeekday}
+		if err := im.db.UpdateUserDigestPreferences(user.ID, prefs); err != nil {
+			return 0, err
+		}
+	}
+	if !u.DigestLastSentAt.IsZero() {
+		if err := im.db.SetUserDigestSentAt(user.ID, u.DigestLastSentAt); err != nil {
+			return 0, err
+		}
+	}
+	return user.ID, nil
+}
+
+func isArchiveTargetEmpty(db Database) (bool, error) {
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return false, err
+	}
+	if len(feeds) > 0 {
+		return false, nil
+	}
+	hasUsers := false
+	err = db.ForEachUser(func(User) error {
+		hasUsers = true
+		return errStopWalk
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return false, err
+	}
+	return !hasUsers, nil
+}
+
+// CountArchiveRecords counts the rows in db that an archive would hold.
+func CountArchiveRecords(db Database) (*ArchiveCounts, error) {
+	var c ArchiveCounts
+	count := func(n *int) func() error { return func() error { *n++; return nil } }
+
+	if err := db.ForEachUser(func(User) error { return count(&c.Users)() }); err != nil {
+		return nil, err
+	}
+	feeds, err := db.GetFeeds()
+	if err != nil {
+		return nil, err
+	}
+	c.Feeds = len(feeds)
+	if err := db.ForEachArticle(func(Article) error { return count(&c.Articles)() }); err != nil {
+		return nil, err
+	}
+	subscriptions, err := db.GetAllFeedSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	c.UserFeeds = len(subscriptions)
+	if err := db.ForEachUserArticle(func(UserArticle) error { return count(&c.UserArticles)() }); err != nil {
+		return nil, err
+	}
+	if err := db.ForEachSession(func(Session) error { return count(&c.Sessions)() }); err != nil {
+		return nil, err
+	}
+	if err := db.ForEachUserIdentity(func(UserIdentity) error { return count(&c.Identities)() }); err != nil {
+		return nil, err
+	}
+	if err := forEachAuditLog(db, func(AuditLog) error { return count(&c.AuditLogs)() }); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// VerifyArchiveImport checks that db holds exactly the rows an import restored.
+func VerifyArchiveImport(db Database, result *ArchiveImportResult) error {
+	got, err := CountArchiveRecords(db)
+	if err != nil {
+		return fmt.Errorf("counting restored rows: %w", err)
+	}
+	want := result.Restored
+
+	var mismatches []string
+	check := func(kind string, want, got int) {
+		if want != got {
+			mismatches = append(mismatches, fmt.Sprintf("%s: restored %d, found %d", kind, want, got))
+		}
+	}
+	check("users", want.Users, got.Users)
+	check("feeds", want.Feeds, got.Feeds)
+	check("articles", want.Articles, got.Articles)
+	check("subscriptions", want.UserFeeds, got.UserFeeds)
+	check("article statuses", want.UserArticles, got.UserArticles)
+	check("sessions", want.Sessions, got.Sessions)
+	check("identities", want.Identities, got.Identities)
+	check("audit logs", want.AuditLogs, got.AuditLogs)
+	if len(mismatches) > 0 {
+		return fmt.Errorf("verification failed: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupArchiveTestDB(t *testing.T, name string) *DB {
+	t.Helper()
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("CreateTables failed: %v", err)
+	}
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	return db
+}
+
+// seedArchiveSource fills db with one of everything an archive holds. A feed
+// is added and deleted first so the source's feed and article IDs don't line
+// up with the ones a fresh target assigns.
+func seedArchiveSource(t *testing.T, db Database) {
+	t.Helper()
+
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	scratch := &Feed{Title: "Scratch", URL: "https://scratch.example.com/feed", CreatedAt: created, UpdatedAt: created}
+	mustDo(t, db.AddFeed(scratch))
+	mustDo(t, db.DeleteFeed(scratch.ID))
+
+	admin := &User{GoogleID: "g-admin", Email: "admin@example.com", Name: "Admin", CreatedAt: created}
+	reader := &User{GoogleID: "g-reader", Email: "reader@example.com", Name: "Reader", CreatedAt: created, SubscriptionStatus: "active"}
+	mustDo(t, db.CreateUser(admin))
+	mustDo(t, db.CreateUser(reader))
+	mustDo(t, db.SetUserAdmin(admin.ID, true))
+	mustDo(t, db.GrantFreeMonths(reader.ID, 2))
+	mustDo(t, db.UpdateUserMaxArticlesOnFeedAdd(reader.ID, 0))
+	mustDo(t, db.UpdateUserDigestPreferences(reader.ID, DigestPreferences{Frequency: "weekly", Timezone: "Europe/Paris", Hour: 7, Weekday: 1}))
+	mustDo(t, db.SetUserDigestSentAt(reader.ID, created.Add(48*time.Hour)))
+
+	feed := &Feed{Title: "Blog", URL: "https://blog.example.com/feed", CreatedAt: created, UpdatedAt: created}
+	mustDo(t, db.AddFeed(feed))
+	articles := addArticles(t, db, feed, 3)
+
+	mustDo(t, db.SubscribeUserToFeed(admin.ID, feed.ID))
+	mustDo(t, db.SubscribeUserToFeed(reader.ID, feed.ID))
+	mustDo(t, db.SetUserArticleStatus(reader.ID, articles[0], true, true))
+	mustDo(t, db.SetUserArticleProgress(reader.ID, articles[1], 40))
+
+	mustDo(t, db.CreateSession(&Session{ID: "session-token", UserID: reader.ID, CreatedAt: created, ExpiresAt: created.Add(24 * time.Hour)}))
+	mustDo(t, db.CreateUserIdentity(&UserIdentity{UserID: reader.ID, Provider: "github", Subject: "4242", Email: reader.Email, CreatedAt: created}))
+	mustDo(t, db.CreateAuditLog(&AuditLog{
+		Timestamp: created, AdminUserID: admin.ID, AdminEmail: admin.Email, OperationType: "grant_months",
+		TargetUserID: reader.ID, TargetUserEmail: reader.Email, OperationDetails: `{"months":2}`, Result: "success",
+	}))
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exportArchive(t *testing.T, db Database) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := ExportArchive(db, &buf); err != nil {
+		t.Fatalf("ExportArchive failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	source := setupArchiveTestDB(t, "source.db")
+	seedArchiveSource(t, source)
+
+	var buf bytes.Buffer
+	exported, err := ExportArchive(source, &buf)
+	if err != nil {
+		t.Fatalf("ExportArchive failed: %v", err)
+	}
+	want := ArchiveCounts{Users: 2, Feeds: 1, Articles: 3, UserFeeds: 2, UserArticles: 2, Sessions: 1, Identities: 1, AuditLogs: 1}
+	if *exported != want {
+		t.Errorf("Expected export of %s, got %s", want, exported)
+	}
+
+	target := setupArchiveTestDB(t, "target.db")
+	result, err := ImportArchive(target, &buf)
+	if err != nil {
+		t.Fatalf("ImportArchive failed: %v", err)
+	}
+	if result.Archive != want || result.Restored != want || result.Skipped != (ArchiveCounts{}) {
+		t.Errorf("Expected everything restored, got %+v", result)
+	}
+	if err := VerifyArchiveImport(target, result); err != nil {
+		t.Errorf("VerifyArchiveImport failed: %v", err)
+	}
+
+	reader, err := target.GetUserByGoogleID("g-reader")
+	if err != nil {
+		t.Fatalf("Expected the reader restored: %v", err)
+	}
+	if reader.SubscriptionStatus != "active" || reader.FreeMonthsRemaining != 2 || reader.MaxArticlesOnFeedAdd != 0 {
+		t.Errorf("Expected subscription fields restored, got %+v", reader)
+	}
+	if reader.DigestFrequency != "weekly" || reader.DigestTimezone != "Europe/Paris" || reader.DigestHour != 7 || reader.DigestLastSentAt.IsZero() {
+		t.Errorf("Expected digest settings restored, got %+v", reader)
+	}
+	admin, err := target.GetUserByGoogleID("g-admin")
+	if err != nil || !admin.IsAdmin {
+		t.Errorf("Expected the admin restored as an admin, got %+v, %v", admin, err)
+	}
+
+	feeds, err := target.GetUserFeeds(reader.ID)
+	if err != nil || len(feeds) != 1 || feeds[0].URL != "https://blog.example.com/feed" {
+		t.Fatalf("Expected the subscription restored, got %+v, %v", feeds, err)
+	}
+	articles, err := target.GetUserFeedArticles(reader.ID, feeds[0].ID)
+	if err != nil || len(articles) != 3 {
+		t.Fatalf("Expected 3 articles on the restored feed, got %d, %v", len(articles), err)
+	}
+	if !articles[0].IsRead || !articles[0].IsStarred {
+		t.Errorf("Expected the newest article read and starred, got %+v", articles[0])
+	}
+	var progress int
+	mustDo(t, target.QueryRow(`SELECT read_progress FROM user_articles WHERE user_id = ? AND article_id = ?`,
+		reader.ID, articles[1].ID).Scan(&progress))
+	if progress != 40 {
+		t.Errorf("Expected read progress 40, got %d", progress)
+	}
+
+	session, err := target.GetSession("session-token")
+	if err != nil || session.UserID != reader.ID {
+		t.Errorf("Expected the session restored for the reader, got %+v, %v", session, err)
+	}
+	identity, err := target.GetUserIdentity("github", "4242")
+	if err != nil || identity == nil || identity.UserID != reader.ID {
+		t.Errorf("Expected the identity linked to the restored reader, got %+v, %v", identity, err)
+	}
+	logs, err := target.GetAuditLogs(10, 0, nil)
+	if err != nil || len(logs) != 1 || logs[0].AdminUserID != admin.ID || logs[0].TargetUserID != reader.ID {
+		t.Errorf("Expected the audit log restored with remapped users, got %+v, %v", logs, err)
+	}
+}
+
+func TestImportArchiveRefusesNonEmptyTarget(t *testing.T) {
+	source := setupArchiveTestDB(t, "source.db")
+	seedArchiveSource(t, source)
+	archive := exportArchive(t, source)
+
+	if _, err := ImportArchive(source, bytes.NewReader(archive)); !errors.Is(err, ErrArchiveTargetNotEmpty) {
+		t.Errorf("Expected ErrArchiveTargetNotEmpty, got %v", err)
+	}
+}
+
+func TestImportArchiveRejectsTruncatedArchive(t *testing.T) {
+	source := setupArchiveTestDB(t, "source.db")
+	seedArchiveSource(t, source)
+
+	// Drop the trailer, as if the export was cut short
+	lines := strings.Split(strings.TrimSpace(decompressArchive(t, exportArchive(t, source))), "\n")
+	truncated := compressArchive(t, strings.Join(lines[:len(lines)-1], "\n"))
+
+	target := setupArchiveTestDB(t, "target.db")
+	if _, err := ImportArchive(target, bytes.NewReader(truncated)); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("Expected a truncated archive to be rejected, got %v", err)
+	}
+}
+
+func TestImportArchiveRejectsNewerVersion(t *testing.T) {
+	header, _ := json.Marshal(archiveHeader{Format: ArchiveFormat, Version: ArchiveVersion + 1})
+	archive := compressArchive(t, string(header)+"\n"+`{"type":"end","counts":{}}`)
+
+	target := setupArchiveTestDB(t, "target.db")
+	if _, err := ImportArchive(target, bytes.NewReader(archive)); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Expected a newer archive version to be rejected, got %v", err)
+	}
+}
+
+func TestImportArchiveSkipsDanglingReferences(t *testing.T) {
+	header, _ := json.Marshal(archiveHeader{Format: ArchiveFormat, Version: ArchiveVersion})
+	records := []string{
+		string(header),
+		`{"type":"feed","data":{"id":7,"title":"Blog","url":"https://blog.example.com/feed"}}`,
+		`{"type":"article","data":{"id":1,"feed_id":7,"title":"One","url":"https://blog.example.com/1"}}`,
+		`{"type":"article","data":{"id":2,"feed_id":7,"title":"One again","url":"https://blog.example.com/1"}}`,
+		`{"type":"article","data":{"id":3,"feed_id":99,"title":"Orphan","url":"https://blog.example.com/3"}}`,
+		`{"type":"user_feed","data":{"user_id":5,"feed_id":7}}`,
+		`{"type":"end","counts":{"feeds":1,"articles":3,"user_feeds":1}}`,
+	}
+
+	target := setupArchiveTestDB(t, "target.db")
+	result, err := ImportArchive(target, bytes.NewReader(compressArchive(t, strings.Join(records, "\n"))))
+	if err != nil {
+		t.Fatalf("ImportArchive failed: %v", err)
+	}
+	if result.Restored.Articles != 1 || result.Skipped.Articles != 2 || result.Skipped.UserFeeds != 1 {
+		t.Errorf("Expected the duplicate and orphan articles and the subscription skipped, got %+v", result)
+	}
+	if err := VerifyArchiveImport(target, result); err != nil {
+		t.Errorf("VerifyArchiveImport failed: %v", err)
+	}
+}
+
+func TestArchiveRoundTripToDatastore(t *testing.T) {
+	target := setupTestDatastoreDB(t)
+	source := setupArchiveTestDB(t, "source.db")
+	seedArchiveSource(t, source)
+
+	result, err := ImportArchive(target, bytes.NewReader(exportArchive(t, source)))
+	if err != nil {
+		t.Fatalf("ImportArchive failed: %v", err)
+	}
+	if err := VerifyArchiveImport(target, result); err != nil {
+		t.Errorf("VerifyArchiveImport failed: %v", err)
+	}
+}
+
+func decompressArchive(t *testing.T, archive []byte) string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("gzip.NewReader failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(gz); err != nil {
+		t.Fatalf("Failed to decompress archive: %v", err)
+	}
+	return buf.String()
+}
+
+func compressArchive(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to compress archive: %v", err)
+	}
+	mustDo(t, gz.Close())
+	return buf.Bytes()
+}
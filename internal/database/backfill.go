@@ -0,0 +1,265 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
+)
+
+// defaultBackfillBatchSize keeps each batch's transaction well under
+// Datastore's 500 entity write limit.
+const defaultBackfillBatchSize = 100
+
+// Backfill is a named data migration over every entity of one Datastore kind,
+// for changes to stored fields that the SQLite migrations handle with UPDATE
+// statements. Entities are handled as property lists, so properties the
+// current structs don't know about survive the rewrite.
+type Backfill struct {
+	Name        string
+	Description string
+	Kind        string
+	BatchSize   int // Defaults to defaultBackfillBatchSize
+
+	// Apply updates props in place and reports whether it changed anything;
+	// unchanged entities aren't written back. It must be idempotent: a batch
+	// interrupted after its writes is retried from the same checkpoint.
+	Apply func(key *datastore.Key, props *datastore.PropertyList) (bool, error)
+}
+
+// BackfillProgress is the checkpoint stored for a backfill after each batch.
+// Dry runs are checkpointed separately and never write entities.
+type BackfillProgress struct {
+	Name      string    `datastore:"name" json:"name"`
+	DryRun    bool      `datastore:"dry_run,noindex" json:"dry_run"`
+	Cursor    string    `datastore:"cursor,noindex" json:"-"`
+	Batches   int64     `datastore:"batches,noindex" json:"batches"`
+	Scanned   int64     `datastore:"scanned,noindex" json:"scanned"`
+	Changed   int64     `datastore:"changed,noindex" json:"changed"`
+	Done      bool      `datastore:"done,noindex" json:"done"`
+	LastError string    `datastore:"last_error,noindex" json:"last_error,omitempty"`
+	StartedAt time.Time `datastore:"started_at,noindex" json:"started_at"`
+	UpdatedAt time.Time `datastore:"updated_at,noindex" json:"updated_at"`
+}
+
+// backfillStore is the storage a backfill runs against. DatastoreDB is the
+// real one; tests substitute an in-memory fake.
+type backfillStore interface {
+	// scanKeys returns up to limit keys of kind after cursor, in key order,
+	// and the cursor to continue from.
+	scanKeys(ctx context.Context, kind, cursor string, limit int) ([]*datastore.Key, string, error)
+	// updateEntities loads the entities for keys, passes each through apply
+	// and, unless dryRun, writes back the changed ones atomically. Keys whose
+	// entity has since been deleted are skipped. Returns how many changed.
+	updateEntities(ctx context.Context, keys []*datastore.Key, apply func(*datastore.Key, *datastore.PropertyList) (bool, error), dryRun bool) (int, error)
+	getBackfillProgress(ctx context.Context, id string) (*BackfillProgress, error)
+	putBackfillProgress(ctx context.Context, id string, progress *BackfillProgress) error
+}
+
+// backfillProgressID names the checkpoint entity, keeping dry runs apart from real runs.
+func backfillProgressID(name string, dryRun bool) string {
+	if dryRun {
+		return name + ":dry-run"
+	}
+	return name
+}
+
+// runBackfillBatch processes the next batch of b from its checkpoint and
+// saves the new checkpoint. A failed batch records the error and leaves the
+// cursor where it was, so the next run retries the same entities.
+func runBackfillBatch(ctx context.Context, store backfillStore, b Backfill, dryRun bool) (*BackfillProgress, error) {
+	id := backfillProgressID(b.Name, dryRun)
+	progress, err := store.getBackfillProgress(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backfill progress: %w", err)
+	}
+	now := time.Now()
+	if progress == nil {
+		progress = &BackfillProgress{Name: b.Name, DryRun: dryRun, StartedAt: now}
+	}
+	if progress.Done {
+		return progress, nil
+	}
+
+	batchSize := b.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+
+	keys, next, err := store.scanKeys(ctx, b.Kind, progress.Cursor, batchSize)
+	var changed int
+	if err == nil && len(keys) > 0 {
+		changed, err = store.updateEntities(ctx, keys, b.Apply, dryRun)
+	}
+	progress.UpdatedAt = now
+	if err != nil {
+		progress.LastError = err.Error()
+		if saveErr := store.putBackfillProgress(ctx, id, progress); saveErr != nil {
+			log.Printf("Failed to record error for backfill %s: %v", b.Name, saveErr)
+		}
+		return progress, fmt.Errorf("backfill %s failed: %w", b.Name, err)
+	}
+
+	progress.Batches++
+	progress.Scanned += int64(len(keys))
+	progress.Changed += int64(changed)
+	progress.LastError = ""
+	progress.Cursor = next
+	if len(keys) < batchSize {
+		progress.Done = true
+		progress.Cursor = ""
+	}
+	if err := store.putBackfillProgress(ctx, id, progress); err != nil {
+		return progress, fmt.Errorf("failed to save backfill progress: %w", err)
+	}
+	return progress, nil
+}
+
+// RunBackfillBatch runs the next batch of b, picking up from its last
+// checkpoint. Calling it after the backfill is done is a no-op.
+func (db *DatastoreDB) RunBackfillBatch(b Backfill, dryRun bool) (*BackfillProgress, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+	return runBackfillBatch(ctx, db, b, dryRun)
+}
+
+// GetBackfillProgress returns the checkpoint for b, or nil if it has never run.
+func (db *DatastoreDB) GetBackfillProgress(name string, dryRun bool) (*BackfillProgress, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+	return db.getBackfillProgress(ctx, backfillProgressID(name, dryRun))
+}
+
+// ResetBackfill deletes the checkpoint for b so its next run starts from the first entity.
+func (db *DatastoreDB) ResetBackfill(name string, dryRun bool) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+	key := datastore.NameKey("BackfillProgress", backfillProgressID(name, dryRun), nil)
+	return db.client.Delete(ctx, key)
+}
+
+func (db *DatastoreDB) scanKeys(ctx context.Context, kind, cursor string, limit int) ([]*datastore.Key, string, error) {
+	query := datastore.NewQuery(kind).KeysOnly().Limit(limit)
+	if cursor != "" {
+		start, err := datastore.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid backfill cursor: %w", err)
+		}
+		query = query.Start(start)
+	}
+
+	var keys []*datastore.Key
+	it := db.client.Run(ctx, query)
+	for {
+		key, err := it.Next(nil)
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to query %s keys: %w", kind, err)
+		}
+		keys = append(keys, key)
+	}
+
+	next, err := it.Cursor()
+	if err != nil {
+		return nil, "", err
+	}
+	return keys, next.String(), nil
+}
+
+func (db *DatastoreDB) updateEntities(ctx context.Context, keys []*datastore.Key, apply func(*datastore.Key, *datastore.PropertyList) (bool, error), dryRun bool) (int, error) {
+	changed := 0
+	process := func(get func([]*datastore.Key, interface{}) error, put func([]*datastore.Key, []datastore.PropertyList) error) error {
+		changed = 0
+		props := make([]datastore.PropertyList, len(keys))
+		err := get(keys, props)
+		var multiErr datastore.MultiError
+		if err != nil && !errors.As(err, &multiErr) {
+			return err
+		}
+
+		var putKeys []*datastore.Key
+		var putProps []datastore.PropertyList
+		for i, key := range keys {
+			if multiErr != nil && multiErr[i] != nil {
+				if errors.Is(multiErr[i], datastore.ErrNoSuchEntity) {
+					continue
+				}
+				return multiErr[i]
+			}
+			ok, err := apply(key, &props[i])
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if ok {
+				changed++
+				putKeys = append(putKeys, key)
+				putProps = append(putProps, props[i])
+			}
+		}
+		if dryRun || len(putKeys) == 0 {
+			return nil
+		}
+		return put(putKeys, putProps)
+	}
+
+	if dryRun {
+		err := process(func(k []*datastore.Key, dst interface{}) error {
+			return db.client.GetMulti(ctx, k, dst)
+		}, nil)
+		return changed, err
+	}
+
+	// Read and write in one transaction so a concurrent update to an entity
+	// between the two isn't overwritten with stale values
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		return process(tx.GetMulti, func(k []*datastore.Key, src []datastore.PropertyList) error {
+			_, err := tx.PutMulti(k, src)
+			return err
+		})
+	})
+	return changed, err
+}
+
+func (db *DatastoreDB) getBackfillProgress(ctx context.Context, id string) (*BackfillProgress, error) {
+	var progress BackfillProgress
+	err := db.client.Get(ctx, datastore.NameKey("BackfillProgress", id, nil), &progress)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (db *DatastoreDB) putBackfillProgress(ctx context.Context, id string, progress *BackfillProgress) error {
+	_, err := db.client.Put(ctx, datastore.NameKey("BackfillProgress", id, nil), progress)
+	return err
+}
+
+// PropertyValue returns the value of the named property, if props has it.
+func PropertyValue(props datastore.PropertyList, name string) (interface{}, bool) {
+	for _, p := range props {
+		if p.Name == name {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// SetProperty replaces the property with p's name, or appends p if there is none.
+func SetProperty(props *datastore.PropertyList, p datastore.Property) {
+	for i := range *props {
+		if (*props)[i].Name == p.Name {
+			(*props)[i] = p
+			return
+		}
+	}
+	*props = append(*props, p)
+}
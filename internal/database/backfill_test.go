@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+// fakeBackfillStore keeps entities of one kind in key order and uses the
+// index of the next key as its cursor.
+type fakeBackfillStore struct {
+	keys     []*datastore.Key
+	entities map[string]datastore.PropertyList
+	progress map[string]BackfillProgress
+	writes   int
+}
+
+func newFakeBackfillStore(kind string, n int) *fakeBackfillStore {
+	store := &fakeBackfillStore{
+		entities: make(map[string]datastore.PropertyList),
+		progress: make(map[string]BackfillProgress),
+	}
+	for i := 1; i <= n; i++ {
+		key := datastore.IDKey(kind, int64(i), nil)
+		store.keys = append(store.keys, key)
+		store.entities[key.String()] = datastore.PropertyList{{Name: "n", Value: int64(i)}}
+	}
+	return store
+}
+
+func (s *fakeBackfillStore) scanKeys(_ context.Context, _ string, cursor string, limit int) ([]*datastore.Key, string, error) {
+	start := 0
+	if cursor != "" {
+		start, _ = strconv.Atoi(cursor)
+	}
+	end := min(start+limit, len(s.keys))
+	return s.keys[start:end], strconv.Itoa(end), nil
+}
+
+func (s *fakeBackfillStore) updateEntities(_ context.Context, keys []*datastore.Key, apply func(*datastore.Key, *datastore.PropertyList) (bool, error), dryRun bool) (int, error) {
+	updated := make(map[string]datastore.PropertyList)
+	for _, key := range keys {
+		stored, ok := s.entities[key.String()]
+		if !ok {
+			continue
+		}
+		props := append(datastore.PropertyList(nil), stored...)
+		changed, err := apply(key, &props)
+		if err != nil {
+			return 0, err
+		}
+		if changed {
+			updated[key.String()] = props
+		}
+	}
+	if !dryRun {
+		for k, props := range updated {
+			s.entities[k] = props
+			s.writes++
+		}
+	}
+	return len(updated), nil
+}
+
+func (s *fakeBackfillStore) getBackfillProgress(_ context.Context, id string) (*BackfillProgress, error) {
+	p, ok := s.progress[id]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (s *fakeBackfillStore) putBackfillProgress(_ context.Context, id string, progress *BackfillProgress) error {
+	s.progress[id] = *progress
+	return nil
+}
+
+// markEven sets done=true on entities with an even n, once
+func markEven(calls *int) func(*datastore.Key, *datastore.PropertyList) (bool, error) {
+	return func(_ *datastore.Key, props *datastore.PropertyList) (bool, error) {
+		*calls++
+		n, _ := PropertyValue(*props, "n")
+		if n.(int64)%2 != 0 {
+			return false, nil
+		}
+		if _, ok := PropertyValue(*props, "done"); ok {
+			return false, nil
+		}
+		SetProperty(props, datastore.Property{Name: "done", Value: true})
+		return true, nil
+	}
+}
+
+func runToCompletion(t *testing.T, store backfillStore, b Backfill, dryRun bool) *BackfillProgress {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		progress, err := runBackfillBatch(context.Background(), store, b, dryRun)
+		if err != nil {
+			t.Fatalf("runBackfillBatch: %v", err)
+		}
+		if progress.Done {
+			return progress
+		}
+	}
+	t.Fatal("backfill did not finish")
+	return nil
+}
+
+func TestRunBackfillBatches(t *testing.T) {
+	store := newFakeBackfillStore("Article", 7)
+	var calls int
+	b := Backfill{Name: "mark-even", Kind: "Article", BatchSize: 3, Apply: markEven(&calls)}
+
+	progress, err := runBackfillBatch(context.Background(), store, b, false)
+	if err != nil {
+		t.Fatalf("runBackfillBatch: %v", err)
+	}
+	if progress.Done || progress.Scanned != 3 || progress.Changed != 1 || progress.Cursor == "" {
+		t.Fatalf("expected a checkpoint after the first batch, got %+v", progress)
+	}
+
+	progress = runToCompletion(t, store, b, false)
+	if progress.Batches != 3 || progress.Scanned != 7 || progress.Changed != 3 {
+		t.Errorf("expected 3 batches, 7 scanned and 3 changed, got %+v", progress)
+	}
+	if calls != 7 || store.writes != 3 {
+		t.Errorf("expected each entity visited once and 3 written, got %d visits and %d writes", calls, store.writes)
+	}
+	for i, key := range store.keys {
+		_, done := PropertyValue(store.entities[key.String()], "done")
+		if done != ((i+1)%2 == 0) {
+			t.Errorf("entity %d: done=%v", i+1, done)
+		}
+	}
+
+	// A finished backfill stays finished
+	again, err := runBackfillBatch(context.Background(), store, b, false)
+	if err != nil || again.Batches != 3 || calls != 7 {
+		t.Errorf("expected a no-op once done, got %+v, %v", again, err)
+	}
+}
+
+func TestRunBackfillDryRun(t *testing.T) {
+	store := newFakeBackfillStore("Article", 4)
+	var calls int
+	b := Backfill{Name: "mark-even", Kind: "Article", BatchSize: 10, Apply: markEven(&calls)}
+
+	progress := runToCompletion(t, store, b, true)
+	if !progress.DryRun || progress.Changed != 2 {
+		t.Errorf("expected a dry run reporting 2 changes, got %+v", progress)
+	}
+	if store.writes != 0 {
+		t.Errorf("expected a dry run to write nothing, got %d writes", store.writes)
+	}
+	if _, ok := store.progress["mark-even"]; ok {
+		t.Error("expected the dry run to leave the real checkpoint alone")
+	}
+
+	progress = runToCompletion(t, store, b, false)
+	if progress.Changed != 2 || store.writes != 2 {
+		t.Errorf("expected the real run to start from scratch, got %+v with %d writes", progress, store.writes)
+	}
+}
+
+func TestRunBackfillResumesAfterFailure(t *testing.T) {
+	store := newFakeBackfillStore("Article", 6)
+	failOn := int64(5)
+	var calls int
+	apply := markEven(&calls)
+	b := Backfill{Name: "flaky", Kind: "Article", BatchSize: 2, Apply: func(key *datastore.Key, props *datastore.PropertyList) (bool, error) {
+		if key.ID == failOn {
+			return false, errors.New("boom")
+		}
+		return apply(key, props)
+	}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := runBackfillBatch(context.Background(), store, b, false); err != nil {
+			t.Fatalf("batch %d: %v", i+1, err)
+		}
+	}
+	progress, err := runBackfillBatch(context.Background(), store, b, false)
+	if err == nil {
+		t.Fatal("expected the third batch to fail")
+	}
+	if progress.LastError == "" || progress.Scanned != 4 || progress.Cursor != "4" {
+		t.Errorf("expected the error recorded and the checkpoint unmoved, got %+v", progress)
+	}
+	if _, done := PropertyValue(store.entities[store.keys[5].String()], "done"); done {
+		t.Error("expected nothing from the failed batch to be written")
+	}
+
+	failOn = 0
+	progress = runToCompletion(t, store, b, false)
+	if progress.LastError != "" || progress.Scanned != 6 || progress.Changed != 3 {
+		t.Errorf("expected the retry to finish cleanly, got %+v", progress)
+	}
+	if calls != 6 {
+		t.Errorf("expected finished batches not to be repeated, got %d visits", calls)
+	}
+}
+
+func TestRunBackfillSkipsDeletedEntities(t *testing.T) {
+	store := newFakeBackfillStore("Article", 4)
+	delete(store.entities, store.keys[1].String())
+	var calls int
+	b := Backfill{Name: "mark-even", Kind: "Article", BatchSize: 10, Apply: markEven(&calls)}
+
+	progress := runToCompletion(t, store, b, false)
+	if progress.Changed != 1 || calls != 3 {
+		t.Errorf("expected the deleted entity to be skipped, got %+v with %d visits", progress, calls)
+	}
+}
+
+func TestDatastoreRunBackfill(t *testing.T) {
+	db := setupTestDatastoreDB(t)
+	ctx := context.Background()
+
+	var keys []*datastore.Key
+	for i := 1; i <= 5; i++ {
+		key, err := db.client.Put(ctx, datastore.IncompleteKey("Article", nil), &ArticleEntity{
+			FeedID: 1,
+			Title:  fmt.Sprintf("Article %d", i),
+			URL:    fmt.Sprintf("https://example.com/%d", i),
+		})
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	b := Backfill{Name: "retitle", Kind: "Article", BatchSize: 2, Apply: func(_ *datastore.Key, props *datastore.PropertyList) (bool, error) {
+		title, _ := PropertyValue(*props, "title")
+		if s := title.(string); len(s) < 3 || s[:3] != "[x]" {
+			SetProperty(props, datastore.Property{Name: "title", Value: "[x] " + s})
+			return true, nil
+		}
+		return false, nil
+	}}
+
+	dry := runToCompletion(t, db, b, true)
+	if dry.Changed != 5 {
+		t.Errorf("expected a dry run to find 5 changes, got %+v", dry)
+	}
+
+	var progress *BackfillProgress
+	for {
+		var err error
+		progress, err = db.RunBackfillBatch(b, false)
+		if err != nil {
+			t.Fatalf("RunBackfillBatch: %v", err)
+		}
+		if progress.Done {
+			break
+		}
+	}
+	if progress.Scanned != 5 || progress.Changed != 5 {
+		t.Errorf("expected 5 scanned and changed, got %+v", progress)
+	}
+
+	entities := make([]ArticleEntity, len(keys))
+	if err := db.client.GetMulti(ctx, keys, entities); err != nil {
+		t.Fatalf("GetMulti: %v", err)
+	}
+	for _, e := range entities {
+		if e.Title[:3] != "[x]" || e.URL == "" {
+			t.Errorf("expected the title rewritten and other fields kept, got %+v", e)
+		}
+	}
+
+	stored, err := db.GetBackfillProgress("retitle", false)
+	if err != nil || stored == nil || !stored.Done {
+		t.Errorf("expected stored progress to be done, got %+v, %v", stored, err)
+	}
+	if err := db.ResetBackfill("retitle", false); err != nil {
+		t.Fatalf("ResetBackfill: %v", err)
+	}
+	if stored, _ := db.GetBackfillProgress("retitle", false); stored != nil {
+		t.Errorf("expected reset to clear progress, got %+v", stored)
+	}
+}
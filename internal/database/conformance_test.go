@@ -0,0 +1,1031 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// conformanceScenarios are behaviours every Database implementation must
+// share. Each scenario gets a fresh, empty database.
+var conformanceScenarios = []struct {
+	name string
+	run  func(t *testing.T, db Database)
+}{
+	{"feeds", conformFeeds},
+	{"users", conformUsers},
+	{"subscriptions", conformSubscriptions},
+	{"article pagination", conformArticlePagination},
+	{"pagination stability", conformPaginationStability},
+	{"article status", conformArticleStatus},
+	{"mark all read", conformMarkAllRead},
+	{"orphan cleanup", conformOrphanCleanup},
+	{"sessions", conformSessions},
+	{"session activity", conformSessionActivity},
+	{"identities", conformIdentities},
+	{"login tokens", conformLoginTokens},
+	{"refresh tokens", conformRefreshTokens},
+	{"account deletion", conformDeleteUser},
+	{"audit log filters", conformAuditLogFilters},
+	{"tags", conformTags},
+	{"annotations", conformAnnotations},
+	{"articles by ID", conformArticlesByIDs},
+	{"sync versions", conformSyncVersions},
+}
+
+func runConformance(t *testing.T, newDB func(t *testing.T) Database) {
+	for _, scenario := range conformanceScenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			scenario.run(t, newDB(t))
+		})
+	}
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Database { return setupTestDB(t) })
+}
+
+func TestDatastoreConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Database { return setupTestDatastoreDB(t) })
+}
+
+func TestPostgresConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) Database { return setupTestPostgresDB(t) })
+}
+
+// setupTestPostgresDB creates the schema in a throwaway PostgreSQL schema on
+// the server at TEST_DATABASE_URL, which must be a postgres:// URL.
+func setupTestPostgresDB(t *testing.T) *PostgresDB {
+	t.Helper()
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("PostgreSQL not available - set TEST_DATABASE_URL to run postgres tests")
+	}
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatalf("Invalid TEST_DATABASE_URL: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("Failed to open postgres: %v", err)
+	}
+	defer func() { _ = admin.Close() }()
+	schema := fmt.Sprintf("goread_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	db, err := NewPostgresDB(u.String())
+	if err != nil {
+		t.Fatalf("Failed to create postgres DB: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = db.Close()
+		cleanup, err := sql.Open("postgres", databaseURL)
+		if err != nil {
+			t.Logf("Failed to drop test schema: %v", err)
+			return
+		}
+		defer func() { _ = cleanup.Close() }()
+		if _, err := cleanup.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Logf("Failed to drop test schema: %v", err)
+		}
+	})
+
+	return db
+}
+
+func conformFeeds(t *testing.T, db Database) {
+	feed := createTestFeed(t, db)
+	if feed.ID == 0 {
+		t.Fatal("expected AddFeed to assign an ID")
+	}
+
+	got, err := db.GetFeedByURL(feed.URL)
+	if err != nil || got == nil || got.ID != feed.ID || got.Title != feed.Title {
+		t.Fatalf("GetFeedByURL: got %+v, %v", got, err)
+	}
+	if got, err := db.GetFeedByURL("https://example.com/missing.xml"); got != nil || err != nil {
+		t.Errorf("expected nil for an unknown URL, got %+v, %v", got, err)
+	}
+
+	feed.Title = "Renamed Feed"
+	if err := db.UpdateFeed(feed); err != nil {
+		t.Fatalf("UpdateFeed: %v", err)
+	}
+	if err := db.UpdateFeedCacheHeaders(feed.ID, `"v2"`, "Mon, 02 Jan 2006 15:04:05 GMT"); err != nil {
+		t.Fatalf("UpdateFeedCacheHeaders: %v", err)
+	}
+	got, _ = db.GetFeedByURL(feed.URL)
+	if got.Title != "Renamed Feed" || got.ETag != `"v2"` || got.LastModified == "" {
+		t.Errorf("expected the updates to be saved, got %+v", got)
+	}
+
+	if err := db.DeleteFeed(feed.ID); err != nil {
+		t.Fatalf("DeleteFeed: %v", err)
+	}
+	if got, _ := db.GetFeedByURL(feed.URL); got != nil {
+		t.Errorf("expected the feed to be deleted, got %+v", got)
+	}
+}
+
+func conformUsers(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	if user.ID == 0 {
+		t.Fatal("expected CreateUser to assign an ID")
+	}
+
+	for name, lookup := range map[string]func() (*User, error){
+		"id":        func() (*User, error) { return db.GetUserByID(user.ID) },
+		"google id": func() (*User, error) { return db.GetUserByGoogleID(user.GoogleID) },
+		"email":     func() (*User, error) { return db.GetUserByEmail(user.Email) },
+	} {
+		got, err := lookup()
+		if err != nil || got.ID != user.ID || got.Email != user.Email || got.IsAdmin {
+			t.Errorf("lookup by %s: got %+v, %v", name, got, err)
+		}
+	}
+
+	if err := db.SetUserAdmin(user.ID, true); err != nil {
+		t.Fatalf("SetUserAdmin: %v", err)
+	}
+	if err := db.GrantFreeMonths(user.ID, 2); err != nil {
+		t.Fatalf("GrantFreeMonths: %v", err)
+	}
+	got, _ := db.GetUserByID(user.ID)
+	if !got.IsAdmin || got.FreeMonthsRemaining != 2 {
+		t.Errorf("expected an admin with 2 free months, got %+v", got)
+	}
+	if err := db.SetUserAdminAtomic(user.ID, user.ID, false); !errors.Is(err, ErrSelfDemotion) {
+		t.Errorf("expected ErrSelfDemotion, got %v", err)
+	}
+
+	expired := createTestUser(t, db)
+	if err := db.UpdateUserSubscription(expired.ID, "expired", "", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("UpdateUserSubscription: %v", err)
+	}
+	if active, err := db.IsUserSubscriptionActive(expired.ID); err != nil || active {
+		t.Errorf("expected an expired subscription to be inactive, got %v, %v", active, err)
+	}
+	if active, err := db.IsUserSubscriptionActive(user.ID); err != nil || !active {
+		t.Errorf("expected a trial user to be active, got %v, %v", active, err)
+	}
+}
+
+func conformSubscriptions(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+
+	// Subscribing twice is not an error and doesn't duplicate the feed
+	for i := 0; i < 2; i++ {
+		if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+	feeds, err := db.GetUserFeeds(user.ID)
+	if err != nil || len(feeds) != 1 || feeds[0].ID != feed.ID {
+		t.Fatalf("GetUserFeeds: got %+v, %v", feeds, err)
+	}
+	if count, _ := db.GetUserFeedCount(user.ID); count != 1 {
+		t.Errorf("expected 1 feed, got %d", count)
+	}
+	if all, _ := db.GetAllUserFeeds(); len(all) != 1 {
+		t.Errorf("expected 1 subscribed feed overall, got %+v", all)
+	}
+
+	if err := db.UnsubscribeUserFromFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("UnsubscribeUserFromFeed: %v", err)
+	}
+	if feeds, _ := db.GetUserFeeds(user.ID); len(feeds) != 0 {
+		t.Errorf("expected no feeds after unsubscribing, got %+v", feeds)
+	}
+}
+
+func conformArticlePagination(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	other := createTestFeed(t, db)
+	for _, f := range []*Feed{feed, other} {
+		if err := db.SubscribeUserToFeed(user.ID, f.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+
+	// Two articles share a publish time, so the ID has to break the tie
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var want []int
+	for i, offset := range []time.Duration{5, 4, 3, 3, 2, 1} {
+		f := feed
+		if i%2 == 1 {
+			f = other
+		}
+		article := &Article{
+			FeedID:      f.ID,
+			Title:       fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://example.com/paged/%d/%d", base.UnixNano(), i),
+			PublishedAt: base.Add(offset * time.Minute),
+			CreatedAt:   base,
+		}
+		if err := db.AddArticle(article); err != nil {
+			t.Fatalf("AddArticle: %v", err)
+		}
+		want = append(want, article.ID)
+	}
+	// Newest first, and the higher ID first among equal times
+	if want[3] > want[2] {
+		want[2], want[3] = want[3], want[2]
+	}
+
+	ids := collectPages(t, 2, func(cursor string) (*ArticlePaginationResult, error) {
+		return db.GetUserArticlesPaginated(user.ID, 2, cursor, false)
+	})
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("expected %v across pages, got %v", want, ids)
+	}
+
+	ids = collectPages(t, 2, func(cursor string) (*ArticlePaginationResult, error) {
+		return db.GetUserFeedArticlesPaginated(user.ID, feed.ID, 2, cursor, false)
+	})
+	if len(ids) != 3 {
+		t.Errorf("expected the feed's 3 articles, got %v", ids)
+	}
+
+	if err := db.MarkUserArticleRead(user.ID, want[0], true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	ids = collectPages(t, 2, func(cursor string) (*ArticlePaginationResult, error) {
+		return db.GetUserArticlesPaginated(user.ID, 2, cursor, true)
+	})
+	if fmt.Sprint(ids) != fmt.Sprint(want[1:]) {
+		t.Errorf("expected unread articles %v, got %v", want[1:], ids)
+	}
+}
+
+// collectPages follows cursors from the first page to the last and returns
+// the article IDs in the order they were served.
+func collectPages(t *testing.T, limit int, page func(cursor string) (*ArticlePaginationResult, error)) []int {
+	t.Helper()
+
+	var ids []int
+	cursor := ""
+	for i := 0; i < 100; i++ {
+		result, err := page(cursor)
+		if err != nil {
+			t.Fatalf("paginating: %v", err)
+		}
+		if len(result.Articles) > limit {
+			t.Fatalf("expected at most %d articles per page, got %d", limit, len(result.Articles))
+		}
+		for _, a := range result.Articles {
+			ids = append(ids, a.ID)
+		}
+		if result.NextCursor == "" {
+			return ids
+		}
+		cursor = result.NextCursor
+	}
+	t.Fatal("pagination did not terminate")
+	return nil
+}
+
+// addArticles adds n articles to feed, one minute apart, the newest published
+// an hour ago, and returns their IDs newest first.
+func addArticles(t *testing.T, db Database, feed *Feed, n int) []int {
+	t.Helper()
+
+	newest := time.Now().Add(-time.Hour).Truncate(time.Second)
+	ids := make([]int, n)
+	for i := 0; i < n; i++ {
+		article := &Article{
+			FeedID:      feed.ID,
+			Title:       fmt.Sprintf("%s %d", feed.Title, i),
+			URL:         fmt.Sprintf("%s/articles/%d", feed.URL, i),
+			PublishedAt: newest.Add(-time.Duration(i) * time.Minute),
+			CreatedAt:   time.Now(),
+		}
+		if err := db.AddArticle(article); err != nil {
+			t.Fatalf("AddArticle: %v", err)
+		}
+		ids[i] = article.ID
+	}
+	return ids
+}
+
+func conformPaginationStability(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	// Deeper than any per-page read-ahead, so later pages depend on the cursor
+	ids := addArticles(t, db, feed, 11)
+
+	all := collectPages(t, 2, func(cursor string) (*ArticlePaginationResult, error) {
+		return db.GetUserArticlesPaginated(user.ID, 2, cursor, false)
+	})
+	if fmt.Sprint(all) != fmt.Sprint(ids) {
+		t.Fatalf("expected %v across pages, got %v", ids, all)
+	}
+
+	// Articles arriving mid-scroll don't shift the pages that follow
+	first, err := db.GetUserArticlesPaginated(user.ID, 3, "", false)
+	if err != nil {
+		t.Fatalf("GetUserArticlesPaginated: %v", err)
+	}
+	fresh := &Article{FeedID: feed.ID, Title: "Breaking", URL: feed.URL + "/articles/fresh", PublishedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.AddArticle(fresh); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+	second, err := db.GetUserArticlesPaginated(user.ID, 3, first.NextCursor, false)
+	if err != nil {
+		t.Fatalf("GetUserArticlesPaginated: %v", err)
+	}
+	if len(second.Articles) != 3 || second.Articles[0].ID != ids[3] {
+		t.Errorf("expected the second page to start at %d, got %+v", ids[3], second.Articles)
+	}
+
+	// Reading articles between unread-only pages doesn't skip any: the cursor
+	// marks a position, not a count
+	for _, id := range []int{ids[0], ids[1], ids[2], ids[3], ids[4], ids[5], ids[8]} {
+		if err := db.MarkUserArticleRead(user.ID, id, true); err != nil {
+			t.Fatalf("MarkUserArticleRead: %v", err)
+		}
+	}
+	unread := collectPages(t, 2, func(cursor string) (*ArticlePaginationResult, error) {
+		page, err := db.GetUserArticlesPaginated(user.ID, 2, cursor, true)
+		if err == nil && cursor == "" {
+			// Reading the first page's articles moves nothing else
+			for _, a := range page.Articles {
+				if err := db.MarkUserArticleRead(user.ID, a.ID, true); err != nil {
+					t.Fatalf("MarkUserArticleRead: %v", err)
+				}
+			}
+		}
+		return page, err
+	})
+	want := []int{fresh.ID, ids[6], ids[7], ids[9], ids[10]}
+	if fmt.Sprint(unread) != fmt.Sprint(want) {
+		t.Errorf("expected unread %v, got %v", want, unread)
+	}
+
+	// A feed the user doesn't follow pages as empty
+	stranger := createTestFeed(t, db)
+	addArticles(t, db, stranger, 1)
+	page, err := db.GetUserFeedArticlesPaginated(user.ID, stranger.ID, 2, "", false)
+	if err != nil || len(page.Articles) != 0 || page.NextCursor != "" {
+		t.Errorf("expected nothing from an unsubscribed feed, got %+v, %v", page, err)
+	}
+}
+
+func conformArticleStatus(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	first := createTestArticle(t, db, feed.ID)
+	second := createTestArticle(t, db, feed.ID)
+
+	if counts, err := db.GetUserUnreadCounts(user.ID); err != nil || counts[feed.ID] != 2 {
+		t.Fatalf("expected 2 unread, got %v, %v", counts, err)
+	}
+
+	if err := db.MarkUserArticleRead(user.ID, first.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	if err := db.ToggleUserArticleStar(user.ID, second.ID); err != nil {
+		t.Fatalf("ToggleUserArticleStar: %v", err)
+	}
+	status, err := db.GetUserArticleStatus(user.ID, second.ID)
+	if err != nil || status.IsRead || !status.IsStarred {
+		t.Errorf("expected the second article starred and unread, got %+v, %v", status, err)
+	}
+	if counts, _ := db.GetUserUnreadCounts(user.ID); counts[feed.ID] != 1 {
+		t.Errorf("expected 1 unread, got %v", counts)
+	}
+
+	article, err := db.GetArticleByID(user.ID, first.ID)
+	if err != nil || article == nil || !article.IsRead || article.Content != first.Content {
+		t.Errorf("GetArticleByID: got %+v, %v", article, err)
+	}
+	stranger := createTestUser(t, db)
+	if article, err := db.GetArticleByID(stranger.ID, first.ID); article != nil || err != nil {
+		t.Errorf("expected no article for an unsubscribed user, got %+v, %v", article, err)
+	}
+
+	existing, err := db.FilterExistingArticleURLs(feed.ID, []string{first.URL, "https://example.com/new"})
+	if err != nil || len(existing) != 1 || !existing[first.URL] {
+		t.Errorf("FilterExistingArticleURLs: got %v, %v", existing, err)
+	}
+}
+
+func conformMarkAllRead(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	other := createTestUser(t, db)
+	news := createTestFeed(t, db)
+	blog := createTestFeed(t, db)
+	ignored := createTestFeed(t, db)
+	for _, s := range []FeedSubscription{{user.ID, news.ID}, {user.ID, blog.ID}, {other.ID, news.ID}} {
+		if err := db.SubscribeUserToFeed(s.UserID, s.FeedID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+	newsIDs := addArticles(t, db, news, 3)
+	blogIDs := addArticles(t, db, blog, 2)
+	ignoredIDs := addArticles(t, db, ignored, 1)
+
+	if err := db.MarkUserArticleRead(user.ID, newsIDs[0], true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	if err := db.ToggleUserArticleStar(user.ID, newsIDs[1]); err != nil {
+		t.Fatalf("ToggleUserArticleStar: %v", err)
+	}
+
+	// Every article in the user's feeds counts, including ones already read
+	n, err := db.MarkAllUserArticlesRead(user.ID)
+	if err != nil || n != 5 {
+		t.Fatalf("expected 5 articles marked, got %d, %v", n, err)
+	}
+	counts, _ := db.GetUserUnreadCounts(user.ID)
+	if counts[news.ID] != 0 || counts[blog.ID] != 0 {
+		t.Errorf("expected nothing unread, got %v", counts)
+	}
+	statuses, err := db.GetUserArticleStatuses(user.ID, append(append(newsIDs, blogIDs...), ignoredIDs...))
+	if err != nil {
+		t.Fatalf("GetUserArticleStatuses: %v", err)
+	}
+	if len(statuses) != 5 {
+		t.Errorf("expected statuses for the 5 subscribed articles only, got %+v", statuses)
+	}
+	if statuses[newsIDs[1]].IsStarred {
+		t.Error("expected marking all read to clear stars")
+	}
+
+	// Other users are unaffected
+	if counts, _ := db.GetUserUnreadCounts(other.ID); counts[news.ID] != 3 {
+		t.Errorf("expected the other user to keep 3 unread, got %v", counts)
+	}
+	loner := createTestUser(t, db)
+	if n, err := db.MarkAllUserArticlesRead(loner.ID); err != nil || n != 0 {
+		t.Errorf("expected 0 for a user without feeds, got %d, %v", n, err)
+	}
+}
+
+func conformOrphanCleanup(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	other := createTestUser(t, db)
+	kept := createTestFeed(t, db)
+	dropped := createTestFeed(t, db)
+	for _, s := range []FeedSubscription{{user.ID, kept.ID}, {user.ID, dropped.ID}, {other.ID, dropped.ID}} {
+		if err := db.SubscribeUserToFeed(s.UserID, s.FeedID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+	keptIDs := addArticles(t, db, kept, 1)
+	droppedIDs := addArticles(t, db, dropped, 2)
+	for _, s := range []struct{ userID, articleID int }{
+		{user.ID, keptIDs[0]}, {user.ID, droppedIDs[0]}, {user.ID, droppedIDs[1]}, {other.ID, droppedIDs[0]},
+	} {
+		if err := db.MarkUserArticleRead(s.userID, s.articleID, true); err != nil {
+			t.Fatalf("MarkUserArticleRead: %v", err)
+		}
+	}
+	if err := db.UnsubscribeUserFromFeed(user.ID, dropped.ID); err != nil {
+		t.Fatalf("UnsubscribeUserFromFeed: %v", err)
+	}
+
+	// The articles were just created, so an age limit spares them
+	if n, err := db.CleanupOrphanedUserArticles(30); err != nil || n != 0 {
+		t.Errorf("expected nothing older than 30 days, got %d, %v", n, err)
+	}
+	if n, err := db.CleanupOrphanedUserArticles(0); err != nil || n != 2 {
+		t.Fatalf("expected the 2 orphans to be removed, got %d, %v", n, err)
+	}
+
+	statuses, _ := db.GetUserArticleStatuses(user.ID, append(keptIDs, droppedIDs...))
+	if len(statuses) != 1 || !statuses[keptIDs[0]].IsRead {
+		t.Errorf("expected only the subscribed feed's status to remain, got %+v", statuses)
+	}
+	if statuses, _ := db.GetUserArticleStatuses(other.ID, droppedIDs); len(statuses) != 1 {
+		t.Errorf("expected the still-subscribed user's status to remain, got %+v", statuses)
+	}
+	if n, err := db.CleanupOrphanedUserArticles(0); err != nil || n != 0 {
+		t.Errorf("expected a second run to find nothing, got %d, %v", n, err)
+	}
+}
+
+func conformSessions(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	now := time.Now()
+	live := &Session{ID: fmt.Sprintf("live-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	stale := &Session{ID: fmt.Sprintf("stale-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(-time.Hour)}
+	for _, s := range []*Session{live, stale} {
+		if err := db.CreateSession(s); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+
+	got, err := db.GetSession(live.ID)
+	if err != nil || got == nil || got.UserID != user.ID || got.ExpiresAt.Unix() != live.ExpiresAt.Unix() {
+		t.Fatalf("GetSession: got %+v, %v", got, err)
+	}
+	if got, err := db.GetSession("missing"); got != nil || err != nil {
+		t.Errorf("expected nil for an unknown session, got %+v, %v", got, err)
+	}
+
+	if err := db.DeleteExpiredSessions(); err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	if got, _ := db.GetSession(stale.ID); got != nil {
+		t.Errorf("expected the expired session to be deleted, got %+v", got)
+	}
+	if got, _ := db.GetSession(live.ID); got == nil {
+		t.Error("expected the live session to be kept")
+	}
+
+	// Expiry follows UpdateSessionExpiry in both directions
+	renewed := &Session{ID: fmt.Sprintf("renewed-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(-time.Minute)}
+	if err := db.CreateSession(renewed); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if err := db.UpdateSessionExpiry(renewed.ID, now.Add(time.Hour)); err != nil {
+		t.Fatalf("UpdateSessionExpiry: %v", err)
+	}
+	if err := db.UpdateSessionExpiry(live.ID, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("UpdateSessionExpiry: %v", err)
+	}
+	if err := db.DeleteExpiredSessions(); err != nil {
+		t.Fatalf("DeleteExpiredSessions: %v", err)
+	}
+	if got, _ := db.GetSession(renewed.ID); got == nil || got.ExpiresAt.Before(now) {
+		t.Errorf("expected the renewed session to be kept, got %+v", got)
+	}
+	if got, _ := db.GetSession(live.ID); got != nil {
+		t.Errorf("expected the shortened session to be deleted, got %+v", got)
+	}
+
+	if err := db.DeleteSession(renewed.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if got, _ := db.GetSession(renewed.ID); got != nil {
+		t.Errorf("expected the session to be deleted, got %+v", got)
+	}
+}
+
+func conformSessionActivity(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	now := time.Now().Truncate(time.Second)
+	legacy := &Session{ID: fmt.Sprintf("legacy-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	phone := &Session{
+		ID: fmt.Sprintf("phone-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+		UserAgent: "GoRead/2.0 (iPhone)", ClientType: "ios", IPAddress: "198.51.100.7", LastSeenAt: now,
+	}
+	for _, s := range []*Session{legacy, phone} {
+		if err := db.CreateSession(s); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+	}
+
+	got, err := db.GetSession(phone.ID)
+	if err != nil || got == nil || got.UserAgent != phone.UserAgent || got.ClientType != "ios" ||
+		got.IPAddress != phone.IPAddress || got.LastSeenAt.Unix() != now.Unix() {
+		t.Fatalf("GetSession: got %+v, %v", got, err)
+	}
+	// A session stored without activity was last seen when it was created
+	if got, _ := db.GetSession(legacy.ID); got == nil || got.LastSeenAt.Unix() != legacy.CreatedAt.Unix() {
+		t.Errorf("expected last seen to fall back to creation, got %+v", got)
+	}
+
+	later := now.Add(10 * time.Minute)
+	if err := db.UpdateSessionActivity(phone.ID, later, "203.0.113.9"); err != nil {
+		t.Fatalf("UpdateSessionActivity: %v", err)
+	}
+	if err := db.UpdateSessionActivity("missing", later, "203.0.113.9"); err != nil {
+		t.Errorf("expected no error for an unknown session, got %v", err)
+	}
+	sessions, err := db.GetUserSessions(user.ID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("GetUserSessions: got %+v, %v", sessions, err)
+	}
+	for _, s := range sessions {
+		if s.ID != phone.ID {
+			continue
+		}
+		if s.IPAddress != "203.0.113.9" || s.LastSeenAt.Unix() != later.Unix() || s.ExpiresAt.Unix() != phone.ExpiresAt.Unix() {
+			t.Errorf("expected the new activity and the same expiry, got %+v", s)
+		}
+	}
+}
+
+func conformIdentities(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	other := createTestUser(t, db)
+	now := time.Now().Truncate(time.Second)
+
+	github := &UserIdentity{UserID: user.ID, Provider: "github", Subject: "4242", Email: user.Email, CreatedAt: now}
+	oidc := &UserIdentity{UserID: user.ID, Provider: "oidc", Subject: "a:b", Email: user.Email, CreatedAt: now.Add(time.Second)}
+	for _, identity := range []*UserIdentity{github, oidc} {
+		if err := db.CreateUserIdentity(identity); err != nil {
+			t.Fatalf("CreateUserIdentity: %v", err)
+		}
+	}
+	// The same provider account can't be linked twice
+	if err := db.CreateUserIdentity(&UserIdentity{UserID: other.ID, Provider: "github", Subject: "4242", CreatedAt: now}); err == nil {
+		t.Error("expected a second link of the same identity to fail")
+	}
+
+	got, err := db.GetUserIdentity("github", "4242")
+	if err != nil || got == nil || got.UserID != user.ID || got.Email != user.Email {
+		t.Fatalf("GetUserIdentity: got %+v, %v", got, err)
+	}
+	if got, err := db.GetUserIdentity("github", "missing"); got != nil || err != nil {
+		t.Errorf("expected nil for an unlinked identity, got %+v, %v", got, err)
+	}
+
+	identities, err := db.GetUserIdentities(user.ID)
+	if err != nil || len(identities) != 2 || identities[0].Provider != "github" || identities[1].Subject != "a:b" {
+		t.Fatalf("GetUserIdentities: got %+v, %v", identities, err)
+	}
+	if identities, _ := db.GetUserIdentities(other.ID); len(identities) != 0 {
+		t.Errorf("expected no identities for the other user, got %+v", identities)
+	}
+
+	if err := db.DeleteUser(user.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if got, _ := db.GetUserIdentity("github", "4242"); got != nil {
+		t.Errorf("expected the identity to go with the user, got %+v", got)
+	}
+}
+
+func conformLoginTokens(t *testing.T, db Database) {
+	now := time.Now()
+	live := &LoginToken{TokenHash: fmt.Sprintf("live-%d", now.UnixNano()), Email: "a@example.com", Mobile: true, ExpiresAt: now.Add(time.Hour)}
+	stale := &LoginToken{TokenHash: fmt.Sprintf("stale-%d", now.UnixNano()), Email: "b@example.com", ExpiresAt: now.Add(-time.Hour)}
+	for _, token := range []*LoginToken{live, stale} {
+		if err := db.CreateLoginToken(token); err != nil {
+			t.Fatalf("CreateLoginToken: %v", err)
+		}
+	}
+
+	if err := db.DeleteExpiredLoginTokens(); err != nil {
+		t.Fatalf("DeleteExpiredLoginTokens: %v", err)
+	}
+	if got, err := db.ConsumeLoginToken(stale.TokenHash); got != nil || err != nil {
+		t.Errorf("expected the expired token to be deleted, got %+v, %v", got, err)
+	}
+
+	got, err := db.ConsumeLoginToken(live.TokenHash)
+	if err != nil || got == nil || got.Email != live.Email || !got.Mobile || got.ExpiresAt.Unix() != live.ExpiresAt.Unix() {
+		t.Fatalf("ConsumeLoginToken: got %+v, %v", got, err)
+	}
+	if got, err := db.ConsumeLoginToken(live.TokenHash); got != nil || err != nil {
+		t.Errorf("expected a consumed token to be gone, got %+v, %v", got, err)
+	}
+}
+
+func conformRefreshTokens(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	now := time.Now()
+	session := &Session{ID: fmt.Sprintf("phone-%d", now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := db.CreateSession(session); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	live := &RefreshToken{TokenHash: fmt.Sprintf("live-%d", now.UnixNano()), SessionID: session.ID, UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	stale := &RefreshToken{TokenHash: fmt.Sprintf("stale-%d", now.UnixNano()), SessionID: session.ID, UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(-time.Hour)}
+	for _, token := range []*RefreshToken{live, stale} {
+		if err := db.CreateRefreshToken(token); err != nil {
+			t.Fatalf("CreateRefreshToken: %v", err)
+		}
+	}
+
+	if err := db.DeleteExpiredRefreshTokens(); err != nil {
+		t.Fatalf("DeleteExpiredRefreshTokens: %v", err)
+	}
+	if got, err := db.UseRefreshToken(stale.TokenHash, now); got != nil || err != nil {
+		t.Errorf("expected the expired token to be deleted, got %+v, %v", got, err)
+	}
+
+	// The first use comes back unused; every later one shows when it was used
+	got, err := db.UseRefreshToken(live.TokenHash, now)
+	if err != nil || got == nil || got.SessionID != session.ID || got.UserID != user.ID ||
+		got.ExpiresAt.Unix() != live.ExpiresAt.Unix() || !got.UsedAt.IsZero() {
+		t.Fatalf("UseRefreshToken: got %+v, %v", got, err)
+	}
+	again, err := db.UseRefreshToken(live.TokenHash, now.Add(time.Minute))
+	if err != nil || again == nil || again.UsedAt.Unix() != now.Unix() {
+		t.Errorf("expected the reuse to report the first use, got %+v, %v", again, err)
+	}
+	if got, err := db.UseRefreshToken("missing", now); got != nil || err != nil {
+		t.Errorf("expected nil for an unknown token, got %+v, %v", got, err)
+	}
+}
+
+func conformDeleteUser(t *testing.T, db Database) {
+	leaving := createTestUser(t, db)
+	staying := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	ids := addArticles(t, db, feed, 3)
+
+	now := time.Now()
+	for i, user := range []*User{leaving, staying} {
+		if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+		session := &Session{ID: fmt.Sprintf("session-%d-%d", i, now.UnixNano()), UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := db.CreateSession(session); err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		refresh := &RefreshToken{TokenHash: fmt.Sprintf("refresh-%d-%d", i, now.UnixNano()), SessionID: session.ID, UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := db.CreateRefreshToken(refresh); err != nil {
+			t.Fatalf("CreateRefreshToken: %v", err)
+		}
+		if err := db.SetUserArticleStatus(user.ID, ids[0], true, false); err != nil {
+			t.Fatalf("SetUserArticleStatus: %v", err)
+		}
+		if err := db.CreateTag(&Tag{UserID: user.ID, Name: "later", CreatedAt: now}); err != nil {
+			t.Fatalf("CreateTag: %v", err)
+		}
+	}
+	if err := db.SetUserArticleStatus(leaving.ID, ids[2], false, true); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+	// A status row with nothing set isn't history
+	if err := db.SetUserArticleStatus(leaving.ID, ids[1], false, false); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+
+	history, err := db.GetUserArticleHistory(leaving.ID)
+	if err != nil || len(history) != 2 {
+		t.Fatalf("GetUserArticleHistory: got %+v, %v", history, err)
+	}
+	if history[0].ID != ids[0] || !history[0].IsRead || history[1].ID != ids[2] || !history[1].IsStarred {
+		t.Errorf("expected the read article then the starred one, got %+v", history)
+	}
+	if history[0].FeedTitle != feed.Title || history[0].URL == "" {
+		t.Errorf("expected history to carry the feed title and URL, got %+v", history[0])
+	}
+	if sessions, err := db.GetUserSessions(leaving.ID); err != nil || len(sessions) != 1 || sessions[0].UserID != leaving.ID {
+		t.Errorf("GetUserSessions: got %+v, %v", sessions, err)
+	}
+
+	if err := db.DeleteUser(leaving.ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if user, err := db.GetUserByID(leaving.ID); err == nil && user != nil {
+		t.Errorf("expected the user to be gone, got %+v", user)
+	}
+	if sessions, _ := db.GetUserSessions(leaving.ID); len(sessions) != 0 {
+		t.Errorf("expected the user's sessions to be gone, got %+v", sessions)
+	}
+	if token, _ := db.UseRefreshToken(fmt.Sprintf("refresh-0-%d", now.UnixNano()), now); token != nil {
+		t.Errorf("expected the user's refresh tokens to be gone, got %+v", token)
+	}
+	if feeds, _ := db.GetUserFeeds(leaving.ID); len(feeds) != 0 {
+		t.Errorf("expected the user's subscriptions to be gone, got %+v", feeds)
+	}
+	if history, _ := db.GetUserArticleHistory(leaving.ID); len(history) != 0 {
+		t.Errorf("expected the user's article statuses to be gone, got %+v", history)
+	}
+	if tags, _ := db.GetUserTags(leaving.ID); len(tags) != 0 {
+		t.Errorf("expected the user's tags to be gone, got %+v", tags)
+	}
+
+	// Other users and the shared feed are untouched
+	if sessions, _ := db.GetUserSessions(staying.ID); len(sessions) != 1 {
+		t.Errorf("expected the other user's session kept, got %+v", sessions)
+	}
+	if token, _ := db.UseRefreshToken(fmt.Sprintf("refresh-1-%d", now.UnixNano()), now); token == nil {
+		t.Error("expected the other user's refresh token kept")
+	}
+	if history, _ := db.GetUserArticleHistory(staying.ID); len(history) != 1 {
+		t.Errorf("expected the other user's status kept, got %+v", history)
+	}
+	if tags, _ := db.GetUserTags(staying.ID); len(tags) != 1 {
+		t.Errorf("expected the other user's tag kept, got %+v", tags)
+	}
+	if articles, _ := db.GetArticles(feed.ID); len(articles) != 3 {
+		t.Errorf("expected the feed's articles kept, got %d", len(articles))
+	}
+}
+
+func conformAuditLogFilters(t *testing.T, db Database) {
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	entries := []AuditLog{
+		{AdminUserID: 1, AdminEmail: "a@example.com", OperationType: "grant_admin", TargetUserID: 10, TargetUserEmail: "x@example.com", Result: "success"},
+		{AdminUserID: 1, AdminEmail: "a@example.com", OperationType: "grant_months", TargetUserID: 11, TargetUserEmail: "y@example.com", Result: "success"},
+		{AdminUserID: 2, AdminEmail: "b@example.com", OperationType: "grant_admin", TargetUserID: 11, TargetUserEmail: "y@example.com", Result: "failure", ErrorMessage: "denied"},
+		{AdminUserID: 2, AdminEmail: "b@example.com", OperationType: "grant_months", TargetUserID: 10, TargetUserEmail: "x@example.com", Result: "success"},
+	}
+	var ids []int
+	for i := range entries {
+		entry := entries[i]
+		entry.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		entry.OperationDetails = "{}"
+		entry.IPAddress = "127.0.0.1"
+		if err := db.CreateAuditLog(&entry); err != nil {
+			t.Fatalf("CreateAuditLog: %v", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		limit   int
+		offset  int
+		want    []int
+	}{
+		{"no filters, newest first", nil, 10, 0, []int{ids[3], ids[2], ids[1], ids[0]}},
+		{"admin", map[string]interface{}{"admin_user_id": 1}, 10, 0, []int{ids[1], ids[0]}},
+		{"target", map[string]interface{}{"target_user_id": 11}, 10, 0, []int{ids[2], ids[1]}},
+		{"operation", map[string]interface{}{"operation_type": "grant_admin"}, 10, 0, []int{ids[2], ids[0]}},
+		{"combined", map[string]interface{}{"admin_user_id": 2, "operation_type": "grant_months"}, 10, 0, []int{ids[3]}},
+		{"no match", map[string]interface{}{"target_user_id": 99}, 10, 0, nil},
+		{"limit and offset", nil, 2, 1, []int{ids[2], ids[1]}},
+	}
+	for _, tt := range tests {
+		logs, err := db.GetAuditLogs(tt.limit, tt.offset, tt.filters)
+		if err != nil {
+			t.Fatalf("%s: GetAuditLogs: %v", tt.name, err)
+		}
+		var got []int
+		for _, l := range logs {
+			got = append(got, l.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	logs, _ := db.GetAuditLogs(1, 1, map[string]interface{}{"admin_user_id": 2})
+	if len(logs) != 1 || logs[0].Result != "failure" || logs[0].ErrorMessage != "denied" || logs[0].TargetUserEmail != "y@example.com" {
+		t.Errorf("expected the failed entry with its details, got %+v", logs)
+	}
+}
+
+func conformTags(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+
+	tag := &Tag{UserID: user.ID, Name: "later", CreatedAt: time.Now()}
+	if err := db.CreateTag(tag); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := db.CreateTag(&Tag{UserID: user.ID, Name: "later", CreatedAt: time.Now()}); !errors.Is(err, ErrTagExists) {
+		t.Errorf("expected ErrTagExists, got %v", err)
+	}
+
+	if err := db.TagArticle(user.ID, article.ID, tag.ID); err != nil {
+		t.Fatalf("TagArticle: %v", err)
+	}
+	if err := db.TagArticle(user.ID, article.ID, tag.ID); err != nil {
+		t.Errorf("expected tagging twice to be a no-op, got %v", err)
+	}
+	tags, err := db.GetArticleTags(user.ID, []int{article.ID})
+	if err != nil || len(tags[article.ID]) != 1 || tags[article.ID][0] != "later" {
+		t.Errorf("GetArticleTags: got %v, %v", tags, err)
+	}
+	page, err := db.GetTagArticlesPaginated(user.ID, tag.ID, 10, "")
+	if err != nil || len(page.Articles) != 1 || page.Articles[0].ID != article.ID {
+		t.Errorf("GetTagArticlesPaginated: got %+v, %v", page, err)
+	}
+
+	if err := db.DeleteTag(user.ID, tag.ID); err != nil {
+		t.Fatalf("DeleteTag: %v", err)
+	}
+	if tags, _ := db.GetArticleTags(user.ID, []int{article.ID}); len(tags[article.ID]) != 0 {
+		t.Errorf("expected the tagging to go with the tag, got %v", tags)
+	}
+}
+
+func conformAnnotations(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	other := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	first := createTestArticle(t, db, feed.ID)
+	second := createTestArticle(t, db, feed.ID)
+
+	now := time.Now().Truncate(time.Second)
+	later := &Annotation{UserID: user.ID, ArticleID: first.ID, QuotedText: "test", StartOffset: 10, EndOffset: 14, CreatedAt: now, UpdatedAt: now}
+	opening := &Annotation{UserID: user.ID, ArticleID: first.ID, QuotedText: "This", StartOffset: 0, EndOffset: 4, CreatedAt: now, UpdatedAt: now}
+	elsewhere := &Annotation{UserID: user.ID, ArticleID: second.ID, QuotedText: "article", StartOffset: 5, EndOffset: 12, CreatedAt: now, UpdatedAt: now}
+	for _, a := range []*Annotation{later, opening, elsewhere} {
+		if err := db.CreateAnnotation(a); err != nil {
+			t.Fatalf("CreateAnnotation: %v", err)
+		}
+	}
+
+	annotations, err := db.GetArticleAnnotations(user.ID, first.ID)
+	if err != nil || len(annotations) != 2 || annotations[0].ID != opening.ID || annotations[1].ID != later.ID {
+		t.Errorf("GetArticleAnnotations: expected annotations ordered by offset, got %+v, %v", annotations, err)
+	}
+	if all, err := db.GetUserAnnotations(user.ID); err != nil || len(all) != 3 {
+		t.Errorf("GetUserAnnotations: got %+v, %v", all, err)
+	}
+
+	// Annotations are private to their owner
+	if got, err := db.GetAnnotation(other.ID, opening.ID); err != nil || got != nil {
+		t.Errorf("expected another user's read to find nothing, got %+v, %v", got, err)
+	}
+	if err := db.DeleteAnnotation(other.ID, opening.ID); err != nil {
+		t.Fatalf("DeleteAnnotation: %v", err)
+	}
+	if got, _ := db.GetAnnotation(user.ID, opening.ID); got == nil {
+		t.Error("another user's delete should not remove the annotation")
+	}
+
+	opening.Note = "revised"
+	opening.UpdatedAt = now.Add(time.Minute)
+	if err := db.UpdateAnnotation(opening); err != nil {
+		t.Fatalf("UpdateAnnotation: %v", err)
+	}
+	if got, err := db.GetAnnotation(user.ID, opening.ID); err != nil || got == nil || got.Note != "revised" {
+		t.Errorf("GetAnnotation after update: got %+v, %v", got, err)
+	}
+
+	if err := db.DeleteAnnotation(user.ID, opening.ID); err != nil {
+		t.Fatalf("DeleteAnnotation: %v", err)
+	}
+	if got, _ := db.GetAnnotation(user.ID, opening.ID); got != nil {
+		t.Errorf("expected the annotation to be deleted, got %+v", got)
+	}
+}
+
+func conformArticlesByIDs(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	subscribed := createTestFeed(t, db)
+	unsubscribed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, subscribed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	visible := addArticles(t, db, subscribed, 2)
+	hidden := addArticles(t, db, unsubscribed, 1)
+
+	articles, err := db.GetArticlesByIDs(user.ID, append(append(visible, hidden...), visible[0]+hidden[0]+1000))
+	if err != nil {
+		t.Fatalf("GetArticlesByIDs: %v", err)
+	}
+	if len(articles) != len(visible) {
+		t.Fatalf("expected only the subscribed feed's %d articles, got %+v", len(visible), articles)
+	}
+	for _, id := range visible {
+		article, ok := articles[id]
+		if !ok || article.ID != id || article.FeedTitle != subscribed.Title || article.URL == "" {
+			t.Errorf("article %d: got %+v", id, article)
+		}
+	}
+
+	if empty, err := db.GetArticlesByIDs(user.ID, nil); err != nil || len(empty) != 0 {
+		t.Errorf("GetArticlesByIDs with no IDs: got %+v, %v", empty, err)
+	}
+}
+
+func conformSyncVersions(t *testing.T, db Database) {
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	article := createTestArticle(t, db, feed.ID)
+
+	before, err := db.GetUserSyncVersion(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserSyncVersion: %v", err)
+	}
+	if err := db.MarkUserArticleRead(user.ID, article.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	after, _ := db.GetUserSyncVersion(user.ID)
+	if after <= before {
+		t.Fatalf("expected the version to advance past %d, got %d", before, after)
+	}
+
+	// Writing the same state again is not a change
+	if err := db.MarkUserArticleRead(user.ID, article.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	if again, _ := db.GetUserSyncVersion(user.ID); again != after {
+		t.Errorf("expected the version to stay at %d, got %d", after, again)
+	}
+
+	changes, err := db.GetUserArticleChanges(user.ID, before, 10)
+	if err != nil || len(changes) != 1 || changes[0].ArticleID != article.ID || !changes[0].IsRead {
+		t.Errorf("GetUserArticleChanges: got %+v, %v", changes, err)
+	}
+	feedChanges, err := db.GetUserFeedChanges(user.ID, 0)
+	if err != nil || len(feedChanges) != 1 || !feedChanges[0].Subscribed {
+		t.Errorf("GetUserFeedChanges: got %+v, %v", feedChanges, err)
+	}
+}
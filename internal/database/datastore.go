@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/datastore"
@@ -63,6 +64,11 @@ type UserEntity struct {
 	IsAdmin              bool      `datastore:"is_admin"`
 	FreeMonthsRemaining  int       `datastore:"free_months_remaining"`
 	MaxArticlesOnFeedAdd int       `datastore:"max_articles_on_feed_add"`
+	DigestFrequency      string    `datastore:"digest_frequency"`
+	DigestTimezone       string    `datastore:"digest_timezone,noindex"`
+	DigestHour           int       `datastore:"digest_hour,noindex"`
+	DigestWeekday        int       `datastore:"digest_weekday,noindex"`
+	DigestLastSentAt     time.Time `datastore:"digest_last_sent_at,noindex"`
 }
 
 type UserFeedEntity struct {
@@ -71,10 +77,28 @@ type UserFeedEntity struct {
 }
 
 type UserArticleEntity struct {
-	UserID    int64 `datastore:"user_id"`
-	ArticleID int64 `datastore:"article_id"`
-	IsRead    bool  `datastore:"is_read"`
-	IsStarred bool  `datastore:"is_starred"`
+	UserID       int64     `datastore:"user_id"`
+	ArticleID    int64     `datastore:"article_id"`
+	IsRead       bool      `datastore:"is_read"`
+	IsStarred    bool      `datastore:"is_starred"`
+	ReadProgress int64     `datastore:"read_progress,noindex"`
+	Version      int64     `datastore:"version"`
+	UpdatedAt    time.Time `datastore:"updated_at,noindex"`
+}
+
+// UserSyncStateEntity holds a user's change counter, keyed by user ID.
+type UserSyncStateEntity struct {
+	Version int64 `datastore:"version,noindex"`
+}
+
+// UserFeedChangeEntity is the latest subscription change for a user and feed,
+// keyed "userID_feedID" so each feed keeps only its newest change.
+type UserFeedChangeEntity struct {
+	UserID     int64     `datastore:"user_id"`
+	FeedID     int64     `datastore:"feed_id,noindex"`
+	Subscribed bool      `datastore:"subscribed,noindex"`
+	Version    int64     `datastore:"version"`
+	ChangedAt  time.Time `datastore:"changed_at,noindex"`
 }
 
 type AdminTokenEntity struct {
@@ -87,10 +111,41 @@ type AdminTokenEntity struct {
 }
 
 type SessionEntity struct {
-	ID        string    `datastore:"-"` // SessionID is the key
+	ID         string    `datastore:"-"` // SessionID is the key
+	UserID     int64     `datastore:"user_id"`
+	CreatedAt  time.Time `datastore:"created_at"`
+	ExpiresAt  time.Time `datastore:"expires_at"`
+	UserAgent  string    `datastore:"user_agent,noindex"`
+	ClientType string    `datastore:"client_type,noindex"`
+	IPAddress  string    `datastore:"ip_address,noindex"`
+	LastSeenAt time.Time `datastore:"last_seen_at,noindex"`
+}
+
+// UserIdentityEntity links a provider account to a user, keyed by
+// "<provider>:<subject>" so each account can only be linked once.
+type UserIdentityEntity struct {
 	UserID    int64     `datastore:"user_id"`
-	CreatedAt time.Time `datastore:"created_at"`
+	Provider  string    `datastore:"provider,noindex"`
+	Subject   string    `datastore:"subject,noindex"`
+	Email     string    `datastore:"email,noindex"`
+	CreatedAt time.Time `datastore:"created_at,noindex"`
+}
+
+// LoginTokenEntity is a pending email sign-in, keyed by the token hash.
+type LoginTokenEntity struct {
+	Email     string    `datastore:"email,noindex"`
+	Mobile    bool      `datastore:"mobile,noindex"`
+	ExpiresAt time.Time `datastore:"expires_at"`
+}
+
+// RefreshTokenEntity is a mobile client's refresh token, keyed by the
+// token hash.
+type RefreshTokenEntity struct {
+	SessionID string    `datastore:"session_id,noindex"`
+	UserID    int64     `datastore:"user_id"`
+	CreatedAt time.Time `datastore:"created_at,noindex"`
 	ExpiresAt time.Time `datastore:"expires_at"`
+	UsedAt    time.Time `datastore:"used_at,noindex"`
 }
 
 type AuditLogEntity struct {
@@ -107,6 +162,109 @@ type AuditLogEntity struct {
 	ErrorMessage     string    `datastore:"error_message,noindex"`
 }
 
+type AnnotationEntity struct {
+	ID          int64     `datastore:"-"`
+	UserID      int64     `datastore:"user_id"`
+	ArticleID   int64     `datastore:"article_id"`
+	QuotedText  string    `datastore:"quoted_text,noindex"`
+	StartOffset int       `datastore:"start_offset,noindex"`
+	EndOffset   int       `datastore:"end_offset,noindex"`
+	Note        string    `datastore:"note,noindex"`
+	CreatedAt   time.Time `datastore:"created_at"`
+	UpdatedAt   time.Time `datastore:"updated_at,noindex"`
+}
+
+type TagEntity struct {
+	ID        int64     `datastore:"-"`
+	UserID    int64     `datastore:"user_id"`
+	Name      string    `datastore:"name"`
+	CreatedAt time.Time `datastore:"created_at"`
+}
+
+// TagNameEntity records which tag holds a name.
+type TagNameEntity struct {
+	TagID int64 `datastore:"tag_id,noindex"`
+}
+
+// UndoOperationEntity is keyed by the operation ID; Changes holds the packed
+// change set from encodeStatusChanges.
+type UndoOperationEntity struct {
+	UserID    int64     `datastore:"user_id"`
+	Kind      string    `datastore:"kind,noindex"`
+	Changes   string    `datastore:"changes,noindex"`
+	CreatedAt time.Time `datastore:"created_at,noindex"`
+	ExpiresAt time.Time `datastore:"expires_at"`
+}
+
+// ArticleTagEntity records one tag applied to one article. PublishedAt is
+// copied from the article so tag listings can be ordered without loading
+// article bodies.
+type ArticleTagEntity struct {
+	UserID      int64     `datastore:"user_id"`
+	ArticleID   int64     `datastore:"article_id"`
+	TagID       int64     `datastore:"tag_id"`
+	PublishedAt time.Time `datastore:"published_at"`
+	CreatedAt   time.Time `datastore:"created_at"`
+}
+
+type WebhookEntity struct {
+	ID        int64     `datastore:"-"`
+	UserID    int64     `datastore:"user_id"`
+	URL       string    `datastore:"url,noindex"`
+	Secret    string    `datastore:"secret,noindex"`
+	FeedID    int64     `datastore:"feed_id"`
+	Keywords  []string  `datastore:"keywords,noindex"`
+	Enabled   bool      `datastore:"enabled,noindex"`
+	CreatedAt time.Time `datastore:"created_at,noindex"`
+	UpdatedAt time.Time `datastore:"updated_at,noindex"`
+}
+
+type InboundAddressEntity struct {
+	ID        int64     `datastore:"-"`
+	UserID    int64     `datastore:"user_id"`
+	FeedID    int64     `datastore:"feed_id,noindex"`
+	Token     string    `datastore:"token"`
+	CreatedAt time.Time `datastore:"created_at,noindex"`
+}
+
+// FeedCategoryEntity is a feed's directory category, keyed by feed ID.
+type FeedCategoryEntity struct {
+	Category string `datastore:"category,noindex"`
+}
+
+// DirectoryFeedEntity is a directory listing, keyed by feed ID.
+type DirectoryFeedEntity struct {
+	Title       string    `datastore:"title,noindex"`
+	URL         string    `datastore:"url,noindex"`
+	Description string    `datastore:"description,noindex"`
+	Category    string    `datastore:"category,noindex"`
+	Subscribers int64     `datastore:"subscribers,noindex"`
+	UpdatedAt   time.Time `datastore:"updated_at,noindex"`
+}
+
+// FeedRecommendationsEntity holds a feed's recommendations as parallel lists,
+// keyed by feed ID, so a user's recommendations are one batch read.
+type FeedRecommendationsEntity struct {
+	RecommendedFeedIDs []int64   `datastore:"recommended_feed_ids,noindex"`
+	SharedSubscribers  []int64   `datastore:"shared_subscribers,noindex"`
+	Scores             []float64 `datastore:"scores,noindex"`
+}
+
+type WebhookDeliveryEntity struct {
+	ID         int64     `datastore:"-"`
+	WebhookID  int64     `datastore:"webhook_id"`
+	UserID     int64     `datastore:"user_id,noindex"`
+	EventID    string    `datastore:"event_id,noindex"`
+	Event      string    `datastore:"event,noindex"`
+	ArticleID  int64     `datastore:"article_id,noindex"`
+	Attempt    int       `datastore:"attempt,noindex"`
+	StatusCode int       `datastore:"status_code,noindex"`
+	Success    bool      `datastore:"success,noindex"`
+	Error      string    `datastore:"error,noindex"`
+	DurationMS int       `datastore:"duration_ms,noindex"`
+	CreatedAt  time.Time `datastore:"created_at"`
+}
+
 type FeedEntity struct {
 	ID                    int64     `datastore:"-"`
 	Title                 string    `datastore:"title"`
@@ -134,6 +292,8 @@ type ArticleEntity struct {
 	CreatedAt   time.Time `datastore:"created_at"`
 	IsRead      bool      `datastore:"is_read"`
 	IsStarred   bool      `datastore:"is_starred"`
+
+	ReadingTimeMinutes int64 `datastore:"reading_time_minutes,noindex"`
 }
 
 func NewDatastoreDB(projectID string) (*DatastoreDB, error) {
@@ -412,6 +572,8 @@ func (db *DatastoreDB) AddArticle(article *Article) error {
 		CreatedAt:   article.CreatedAt,
 		IsRead:      article.IsRead,
 		IsStarred:   article.IsStarred,
+
+		ReadingTimeMinutes: int64(article.ReadingTimeMinutes),
 	}
 
 	key := datastore.IncompleteKey("Article", nil)
@@ -450,6 +612,8 @@ func (db *DatastoreDB) GetArticles(feedID int) ([]Article, error) {
 			CreatedAt:   entity.CreatedAt,
 			IsRead:      entity.IsRead,
 			IsStarred:   entity.IsStarred,
+
+			ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
 		}
 	}
 
@@ -486,6 +650,8 @@ func (db *DatastoreDB) FindArticleByURL(url string) (*Article, error) {
 		CreatedAt:   entity.CreatedAt,
 		IsRead:      entity.IsRead,
 		IsStarred:   entity.IsStarred,
+
+		ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
 	}
 
 	return &article, nil
@@ -605,6 +771,7 @@ func (db *DatastoreDB) CreateUser(user *User) error {
 	if user.MaxArticlesOnFeedAdd == 0 {
 		user.MaxArticlesOnFeedAdd = 100 // Default to 100 articles
 	}
+	user.Email = NormalizeEmail(user.Email)
 
 	entity := &UserEntity{
 		GoogleID:             user.GoogleID,
@@ -632,24 +799,8 @@ func (db *DatastoreDB) CreateUser(user *User) error {
 	return nil
 }
 
-func (db *DatastoreDB) GetUserByGoogleID(googleID string) (*User, error) {
-	ctx, cancel := newDatastoreContext()
-	defer cancel()
-
-	query := datastore.NewQuery("User").FilterField("google_id", "=", googleID).Limit(1)
-	var entities []UserEntity
-	keys, err := db.client.GetAll(ctx, query, &entities)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query user: %w", err)
-	}
-
-	if len(entities) == 0 {
-		return nil, fmt.Errorf("user not found")
-	}
-
-	entity := entities[0]
-	entity.ID = keys[0].ID
-
+// userFromEntity converts a stored user; entity.ID must already be set.
+func userFromEntity(entity *UserEntity) *User {
 	maxArticles := entity.MaxArticlesOnFeedAdd
 	if maxArticles == 0 {
 		maxArticles = 100 // Default for existing users
@@ -670,7 +821,33 @@ func (db *DatastoreDB) GetUserByGoogleID(googleID string) (*User, error) {
 		IsAdmin:              entity.IsAdmin,
 		FreeMonthsRemaining:  entity.FreeMonthsRemaining,
 		MaxArticlesOnFeedAdd: maxArticles,
-	}, nil
+		DigestFrequency:      entity.DigestFrequency,
+		DigestTimezone:       entity.DigestTimezone,
+		DigestHour:           entity.DigestHour,
+		DigestWeekday:        entity.DigestWeekday,
+		DigestLastSentAt:     entity.DigestLastSentAt,
+	}
+}
+
+func (db *DatastoreDB) GetUserByGoogleID(googleID string) (*User, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	query := datastore.NewQuery("User").FilterField("google_id", "=", googleID).Limit(1)
+	var entities []UserEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	}
+
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	entity := entities[0]
+	entity.ID = keys[0].ID
+
+	return userFromEntity(&entity), nil
 }
 
 func (db *DatastoreDB) GetUserByID(userID int) (*User, error) {
@@ -685,27 +862,7 @@ func (db *DatastoreDB) GetUserByID(userID int) (*User, error) {
 
 	entity.ID = int64(userID)
 
-	maxArticles := entity.MaxArticlesOnFeedAdd
-	if maxArticles == 0 {
-		maxArticles = 100 // Default for existing users
-	}
-
-	return &User{
-		ID:                   int(entity.ID),
-		GoogleID:             entity.GoogleID,
-		Email:                entity.Email,
-		Name:                 entity.Name,
-		Avatar:               entity.Avatar,
-		CreatedAt:            entity.CreatedAt,
-		SubscriptionStatus:   entity.SubscriptionStatus,
-		SubscriptionID:       entity.SubscriptionID,
-		TrialEndsAt:          entity.TrialEndsAt,
-		LastPaymentDate:      entity.LastPaymentDate,
-		NextBillingDate:      entity.NextBillingDate,
-		IsAdmin:              entity.IsAdmin,
-		FreeMonthsRemaining:  entity.FreeMonthsRemaining,
-		MaxArticlesOnFeedAdd: maxArticles,
-	}, nil
+	return userFromEntity(&entity), nil
 }
 
 func (db *DatastoreDB) GetUserFeeds(userID int) ([]Feed, error) {
@@ -819,7 +976,12 @@ func (db *DatastoreDB) SubscribeUserToFeed(userID, feedID int) error {
 
 	// Use a composite key to ensure uniqueness
 	key := datastore.NameKey("UserFeed", fmt.Sprintf("%d_%d", userID, feedID), nil)
-	_, err = db.client.Put(ctx, key, entity)
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		if _, err := tx.Put(key, entity); err != nil {
+			return err
+		}
+		return recordFeedChange(tx, userID, feedID, true)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create subscription: %w", err)
 	}
@@ -835,7 +997,12 @@ func (db *DatastoreDB) UnsubscribeUserFromFeed(userID, feedID int) error {
 	// Note: Orphaned UserArticle entities will be cleaned up by periodic background job
 	// (see CleanupOrphanedUserArticles)
 	key := datastore.NameKey("UserFeed", fmt.Sprintf("%d_%d", userID, feedID), nil)
-	err := db.client.Delete(ctx, key)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		if err := tx.Delete(key); err != nil {
+			return err
+		}
+		return recordFeedChange(tx, userID, feedID, false)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to delete subscription: %w", err)
 	}
@@ -912,7 +1079,17 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 		feedTitleMap[feed.ID] = feed.Title
 	}
 
-	// How many refs to project per feed. Same ceiling as before to preserve pagination depth.
+	var after *sqliteCursor
+	if cursor != "" {
+		after, err = decodeSQLiteCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+	}
+
+	// How many refs to project per feed on each call. Every query starts at
+	// the cursor, so this bounds the work per page rather than how deep the
+	// user can page.
 	articlesPerFeed := limit * 2
 	if articlesPerFeed > maxArticlesPerFeed {
 		articlesPerFeed = maxArticlesPerFeed
@@ -922,7 +1099,12 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 	// These are Datastore "small operations" (~1/6 the cost of full entity reads), so we can
 	// project the same number of refs as before without significantly increasing cost, while
 	// deferring full entity reads until we know exactly which articles we need.
+	type feedRefs struct {
+		refs []articleRef
+		err  error
+	}
 	allRefs := make([]articleRef, 0, len(feedIDs)*articlesPerFeed)
+	var truncated []articleRef // the oldest ref of each feed that had more to give
 
 	batchSize := 5
 	for i := 0; i < len(feedIDs); i += batchSize {
@@ -931,20 +1113,25 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 			end = len(feedIDs)
 		}
 		batch := feedIDs[i:end]
-		results := make(chan []articleRef, len(batch))
+		results := make(chan feedRefs, len(batch))
 
 		for _, fid := range batch {
 			go func(fid int64) {
+				// Ordered like the SQL backends: newest first, higher ID first among ties
 				query := datastore.NewQuery("Article").
 					FilterField("feed_id", "=", fid).
 					Order("-published_at").
+					Order("-__key__").
 					Limit(articlesPerFeed).
 					Project("published_at")
+				if after != nil {
+					query = query.FilterField("published_at", "<=", after.PublishedAt)
+				}
 
 				var projs []articlePublishedAtProjection
 				keys, err := db.client.GetAll(ctx, query, &projs)
 				if err != nil {
-					results <- nil
+					results <- feedRefs{err: err}
 					return
 				}
 				refs := make([]articleRef, len(projs))
@@ -955,61 +1142,78 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 						publishedAt: projs[j].PublishedAt,
 					}
 				}
-				results <- refs
+				results <- feedRefs{refs: refs}
 			}(fid)
 		}
 
 		for range batch {
-			if refs := <-results; refs != nil {
-				allRefs = append(allRefs, refs...)
+			r := <-results
+			if r.err != nil {
+				log.Printf("WARNING: article projection for user %d failed: %v", userID, r.err)
+				continue
+			}
+			if len(r.refs) == articlesPerFeed {
+				truncated = append(truncated, r.refs[len(r.refs)-1])
+			}
+			for _, ref := range r.refs {
+				// Ties with the cursor's publish time that were already returned
+				if after == nil || ref.publishedAt.Before(after.PublishedAt) || ref.key.ID < int64(after.ID) {
+					allRefs = append(allRefs, ref)
+				}
 			}
 		}
 	}
 
-	// Sort refs globally by published_at desc, then by key ID desc for determinism.
-	sort.Slice(allRefs, func(i, j int) bool {
-		if allRefs[i].publishedAt.Equal(allRefs[j].publishedAt) {
-			return allRefs[i].key.ID > allRefs[j].key.ID
-		}
-		return allRefs[i].publishedAt.After(allRefs[j].publishedAt)
-	})
-
-	// Apply cursor to find where the next page starts.
-	startIdx := 0
-	if cursor != "" {
-		cursorData, err := decodeSQLiteCursor(cursor)
-		if err != nil {
-			return nil, fmt.Errorf("invalid cursor: %w", err)
+	// refBefore reports whether a sorts ahead of b: published_at desc, then key ID desc.
+	refBefore := func(a, b articleRef) bool {
+		if a.publishedAt.Equal(b.publishedAt) {
+			return a.key.ID > b.key.ID
 		}
-		for i, ref := range allRefs {
-			if ref.publishedAt.Before(cursorData.PublishedAt) ||
-				(ref.publishedAt.Equal(cursorData.PublishedAt) && ref.key.ID < int64(cursorData.ID)) {
-				startIdx = i
-				break
+		return a.publishedAt.After(b.publishedAt)
+	}
+	sort.Slice(allRefs, func(i, j int) bool { return refBefore(allRefs[i], allRefs[j]) })
+
+	// A feed that filled its projection may have unseen articles just after its
+	// last ref, so the merged order is only complete down to the newest such
+	// ref. Anything later waits for the next page.
+	usable := allRefs
+	more := false
+	if len(truncated) > 0 {
+		horizon := truncated[0]
+		for _, ref := range truncated[1:] {
+			if refBefore(ref, horizon) {
+				horizon = ref
 			}
 		}
-	}
-	remainingRefs := allRefs[startIdx:]
-
-	// For unreadOnly we need extra candidates because some will be filtered out.
-	// For all-articles we need exactly limit candidates.
-	candidateCount := limit
-	if unreadOnly {
-		candidateCount = limit * 2
-		if candidateCount > maxArticlesPerFeed {
-			candidateCount = maxArticlesPerFeed
+		n := sort.Search(len(allRefs), func(i int) bool { return refBefore(horizon, allRefs[i]) })
+		if n == 0 {
+			// Only possible when a whole projection is ties with the cursor;
+			// fall back to the merged refs rather than stalling.
+			n = len(allRefs)
 		}
+		usable = allRefs[:n]
+		more = true
 	}
-	if candidateCount > len(remainingRefs) {
-		candidateCount = len(remainingRefs)
-	}
-	candidates := remainingRefs[:candidateCount]
 
-	// Pass 2a: batch-fetch UserArticle status for candidates only (not all projected refs).
+	// Pass 2a: walk the usable refs in order, batch-fetching UserArticle status
+	// for each chunk, until the page plus one lookahead article is filled.
+	// Unread-only pages may need several chunks when many articles are read.
 	statusMap := make(map[int64]UserArticleEntity)
-	if len(candidates) > 0 {
-		userArticleKeys := make([]*datastore.Key, len(candidates))
-		for i, ref := range candidates {
+	pageRefs := make([]articleRef, 0, limit+1)
+	examined := 0
+	for examined < len(usable) && len(pageRefs) <= limit {
+		chunkSize := limit + 1 - len(pageRefs)
+		if unreadOnly {
+			chunkSize *= 2
+		}
+		chunkEnd := examined + chunkSize
+		if chunkEnd > len(usable) {
+			chunkEnd = len(usable)
+		}
+		chunk := usable[examined:chunkEnd]
+
+		userArticleKeys := make([]*datastore.Key, len(chunk))
+		for i, ref := range chunk {
 			userArticleKeys[i] = datastore.NameKey("UserArticle",
 				fmt.Sprintf("%d_%d", userID, ref.key.ID), nil)
 		}
@@ -1025,34 +1229,35 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 			for _, ua := range userArticles {
 				statusMap[ua.ArticleID] = ua
 			}
+		} else {
+			return nil, fmt.Errorf("failed to fetch article statuses: %w", uaErr)
 		}
-	}
 
-	// Determine the page refs, filtering for unread if requested.
-	pageRefs := make([]articleRef, 0, limit)
-	for _, ref := range candidates {
-		if unreadOnly {
-			ua, exists := statusMap[ref.key.ID]
-			if exists && ua.IsRead {
+		for _, ref := range chunk {
+			examined++
+			if unreadOnly && statusMap[ref.key.ID].IsRead {
 				continue
 			}
-		}
-		pageRefs = append(pageRefs, ref)
-		if len(pageRefs) == limit {
-			break
+			pageRefs = append(pageRefs, ref)
+			if len(pageRefs) > limit {
+				break
+			}
 		}
 	}
 
-	// Set next cursor if there are more results beyond this page.
+	// The lookahead article means there is another page, as with the
+	// over-fetch in the SQL backends. Otherwise, if refs were held back at the
+	// horizon, continue after the last ref looked at, even when this page came
+	// out short because everything up to there was read.
 	var nextCursor string
-	if len(pageRefs) == limit {
-		// Check if any refs remain after this page (in candidates or in remainingRefs).
-		if candidateCount > len(pageRefs) || len(remainingRefs) > candidateCount {
-			last := pageRefs[len(pageRefs)-1]
-			nextCursor = encodeSQLiteCursor(int(last.key.ID), last.publishedAt)
-		}
+	if len(pageRefs) > limit {
+		pageRefs = pageRefs[:limit]
+		last := pageRefs[limit-1]
+		nextCursor = encodeSQLiteCursor(int(last.key.ID), last.publishedAt)
+	} else if more && examined > 0 {
+		last := usable[examined-1]
+		nextCursor = encodeSQLiteCursor(int(last.key.ID), last.publishedAt)
 	}
-
 	if len(pageRefs) == 0 {
 		return &ArticlePaginationResult{Articles: []Article{}, NextCursor: nextCursor}, nil
 	}
@@ -1086,6 +1291,9 @@ func (db *DatastoreDB) getUserArticlesPaginated(userID, feedID int, limit int, c
 			CreatedAt:   entity.CreatedAt,
 			IsRead:      ua.IsRead,
 			IsStarred:   ua.IsStarred,
+
+			ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+			ReadProgress:       int(ua.ReadProgress),
 		})
 	}
 	if !isME && fetchErr != nil {
@@ -1192,9 +1400,11 @@ func (db *DatastoreDB) GetUserFeedArticles(userID, feedID int) ([]Article, error
 		// Get user status from map (defaults to false if not found)
 		isRead := false
 		isStarred := false
+		readProgress := 0
 		if userStatus, exists := statusMap[articleID]; exists {
 			isRead = userStatus.IsRead
 			isStarred = userStatus.IsStarred
+			readProgress = int(userStatus.ReadProgress)
 		}
 
 		articles[i] = Article{
@@ -1209,12 +1419,120 @@ func (db *DatastoreDB) GetUserFeedArticles(userID, feedID int) ([]Article, error
 			CreatedAt:   entity.CreatedAt,
 			IsRead:      isRead,
 			IsStarred:   isStarred,
+
+			ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+			ReadProgress:       readProgress,
 		}
 	}
 
 	return articles, nil
 }
 
+// GetArticlesByIDs loads the articles, the user's subscriptions to their
+// feeds and the feeds themselves with one GetMulti each per chunk, rather
+// than the per-article queries GetArticleByID makes.
+func (db *DatastoreDB) GetArticlesByIDs(userID int, articleIDs []int) (map[int]Article, error) {
+	defer logSlowQuery("GetArticlesByIDs", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	articles := make(map[int]Article)
+	chunkSize := 1000 // GetMulti accepts at most 1000 keys
+	for i := 0; i < len(articleIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+		chunk := articleIDs[i:end]
+
+		keys := make([]*datastore.Key, len(chunk))
+		for j, id := range chunk {
+			keys[j] = datastore.IDKey("Article", int64(id), nil)
+		}
+		entities := make([]ArticleEntity, len(keys))
+		var articleErrs datastore.MultiError
+		if err := db.client.GetMulti(ctx, keys, entities); err != nil && !errors.As(err, &articleErrs) {
+			return nil, fmt.Errorf("failed to get articles: %w", err)
+		}
+
+		var feedIDs []int64
+		seen := make(map[int64]bool)
+		for j, entity := range entities {
+			if articleErrs != nil && articleErrs[j] != nil {
+				if articleErrs[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to get article: %w", articleErrs[j])
+				}
+				continue
+			}
+			if !seen[entity.FeedID] {
+				seen[entity.FeedID] = true
+				feedIDs = append(feedIDs, entity.FeedID)
+			}
+		}
+		if len(feedIDs) == 0 {
+			continue
+		}
+
+		userFeedKeys := make([]*datastore.Key, len(feedIDs))
+		feedKeys := make([]*datastore.Key, len(feedIDs))
+		for j, feedID := range feedIDs {
+			userFeedKeys[j] = datastore.NameKey("UserFeed", fmt.Sprintf("%d_%d", userID, feedID), nil)
+			feedKeys[j] = datastore.IDKey("Feed", feedID, nil)
+		}
+		userFeeds := make([]UserFeedEntity, len(userFeedKeys))
+		var userFeedErrs datastore.MultiError
+		if err := db.client.GetMulti(ctx, userFeedKeys, userFeeds); err != nil && !errors.As(err, &userFeedErrs) {
+			return nil, fmt.Errorf("failed to check user subscriptions: %w", err)
+		}
+		feeds := make([]FeedEntity, len(feedKeys))
+		var feedErrs datastore.MultiError
+		if err := db.client.GetMulti(ctx, feedKeys, feeds); err != nil && !errors.As(err, &feedErrs) {
+			return nil, fmt.Errorf("failed to get feeds: %w", err)
+		}
+
+		feedTitles := make(map[int64]string)
+		for j, feedID := range feedIDs {
+			if userFeedErrs != nil && userFeedErrs[j] != nil {
+				if userFeedErrs[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to check user subscription: %w", userFeedErrs[j])
+				}
+				continue
+			}
+			if feedErrs != nil && feedErrs[j] != nil {
+				if feedErrs[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to get feed: %w", feedErrs[j])
+				}
+				continue
+			}
+			feedTitles[feedID] = feeds[j].Title
+		}
+
+		for j, entity := range entities {
+			if articleErrs != nil && articleErrs[j] != nil {
+				continue
+			}
+			feedTitle, ok := feedTitles[entity.FeedID]
+			if !ok {
+				continue
+			}
+			articles[chunk[j]] = Article{
+				ID:          chunk[j],
+				FeedID:      int(entity.FeedID),
+				FeedTitle:   feedTitle,
+				Title:       entity.Title,
+				URL:         entity.URL,
+				Description: entity.Description,
+				Author:      entity.Author,
+				PublishedAt: entity.PublishedAt,
+				CreatedAt:   entity.CreatedAt,
+
+				ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+			}
+		}
+	}
+	return articles, nil
+}
+
 func (db *DatastoreDB) GetArticleByID(userID, articleID int) (*Article, error) {
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
@@ -1250,10 +1568,11 @@ func (db *DatastoreDB) GetArticleByID(userID, articleID int) (*Article, error) {
 
 	uaKey := datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, articleID), nil)
 	var ua UserArticleEntity
-	isRead, isStarred := false, false
+	isRead, isStarred, readProgress := false, false, 0
 	if err := db.client.Get(ctx, uaKey, &ua); err == nil {
 		isRead = ua.IsRead
 		isStarred = ua.IsStarred
+		readProgress = int(ua.ReadProgress)
 	}
 
 	return &Article{
@@ -1269,9 +1588,100 @@ func (db *DatastoreDB) GetArticleByID(userID, articleID int) (*Article, error) {
 		CreatedAt:   entity.CreatedAt,
 		IsRead:      isRead,
 		IsStarred:   isStarred,
+
+		ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+		ReadProgress:       readProgress,
 	}, nil
 }
 
+func (db *DatastoreDB) GetUserArticleHistory(userID int) ([]Article, error) {
+	defer logSlowQuery("GetUserArticleHistory", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var statuses []UserArticleEntity
+	query := datastore.NewQuery("UserArticle").FilterField("user_id", "=", int64(userID))
+	if _, err := db.client.GetAll(ctx, query, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to query user articles: %w", err)
+	}
+
+	var articleKeys []*datastore.Key
+	var kept []UserArticleEntity
+	for _, ua := range statuses {
+		if ua.IsRead || ua.IsStarred || ua.ReadProgress > 0 {
+			articleKeys = append(articleKeys, datastore.IDKey("Article", ua.ArticleID, nil))
+			kept = append(kept, ua)
+		}
+	}
+
+	var articles []Article
+	feedTitles := make(map[int64]string)
+	chunkSize := 1000 // GetMulti accepts at most 1000 keys
+	for i := 0; i < len(articleKeys); i += chunkSize {
+		end := min(i+chunkSize, len(articleKeys))
+		entities := make([]ArticleEntity, end-i)
+		err := db.client.GetMulti(ctx, articleKeys[i:end], entities)
+		var multiErr datastore.MultiError
+		if err != nil && !errors.As(err, &multiErr) {
+			return nil, fmt.Errorf("failed to get articles: %w", err)
+		}
+		for j, entity := range entities {
+			if multiErr != nil && multiErr[j] != nil {
+				if errors.Is(multiErr[j], datastore.ErrNoSuchEntity) {
+					continue // Status left behind by a deleted article
+				}
+				return nil, fmt.Errorf("failed to get article: %w", multiErr[j])
+			}
+			ua := kept[i+j]
+			feedTitles[entity.FeedID] = ""
+			articles = append(articles, Article{
+				ID:                 int(ua.ArticleID),
+				FeedID:             int(entity.FeedID),
+				Title:              entity.Title,
+				URL:                entity.URL,
+				Description:        entity.Description,
+				Author:             entity.Author,
+				PublishedAt:        entity.PublishedAt,
+				CreatedAt:          entity.CreatedAt,
+				ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+				IsRead:             ua.IsRead,
+				IsStarred:          ua.IsStarred,
+				ReadProgress:       int(ua.ReadProgress),
+			})
+		}
+	}
+
+	feedKeys := make([]*datastore.Key, 0, len(feedTitles))
+	for id := range feedTitles {
+		feedKeys = append(feedKeys, datastore.IDKey("Feed", id, nil))
+	}
+	for i := 0; i < len(feedKeys); i += chunkSize {
+		end := min(i+chunkSize, len(feedKeys))
+		feeds := make([]FeedEntity, end-i)
+		err := db.client.GetMulti(ctx, feedKeys[i:end], feeds)
+		var multiErr datastore.MultiError
+		if err != nil && !errors.As(err, &multiErr) {
+			return nil, fmt.Errorf("failed to get feeds: %w", err)
+		}
+		for j, feed := range feeds {
+			if multiErr == nil || multiErr[j] == nil {
+				feedTitles[feedKeys[i+j].ID] = feed.Title
+			}
+		}
+	}
+	for i := range articles {
+		articles[i].FeedTitle = feedTitles[int64(articles[i].FeedID)]
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].PublishedAt.Equal(articles[j].PublishedAt) {
+			return articles[i].PublishedAt.After(articles[j].PublishedAt)
+		}
+		return articles[i].ID > articles[j].ID
+	})
+	return articles, nil
+}
+
 func (db *DatastoreDB) GetUserArticleStatus(userID, articleID int) (*UserArticle, error) {
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
@@ -1287,10 +1697,11 @@ func (db *DatastoreDB) GetUserArticleStatus(userID, articleID int) (*UserArticle
 	}
 
 	return &UserArticle{
-		UserID:    int(entity.UserID),
-		ArticleID: int(entity.ArticleID),
-		IsRead:    entity.IsRead,
-		IsStarred: entity.IsStarred,
+		UserID:       int(entity.UserID),
+		ArticleID:    int(entity.ArticleID),
+		IsRead:       entity.IsRead,
+		IsStarred:    entity.IsStarred,
+		ReadProgress: int(entity.ReadProgress),
 	}, nil
 }
 
@@ -1298,15 +1709,23 @@ func (db *DatastoreDB) SetUserArticleStatus(userID, articleID int, isRead, isSta
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
 
-	entity := &UserArticleEntity{
-		UserID:    int64(userID),
-		ArticleID: int64(articleID),
-		IsRead:    isRead,
-		IsStarred: isStarred,
-	}
-
 	key := datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, articleID), nil)
-	_, err := db.client.Put(ctx, key, entity)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		entity := &UserArticleEntity{
+			UserID:    int64(userID),
+			ArticleID: int64(articleID),
+			IsRead:    isRead,
+			IsStarred: isStarred,
+		}
+		if err := preserveReadProgress(tx, []*datastore.Key{key}, []*UserArticleEntity{entity}); err != nil {
+			return err
+		}
+		if err := stampSyncVersion(tx, userID, entity); err != nil {
+			return err
+		}
+		_, err := tx.Put(key, entity)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to set user article status: %w", err)
 	}
@@ -1314,22 +1733,129 @@ func (db *DatastoreDB) SetUserArticleStatus(userID, articleID int, isRead, isSta
 	return nil
 }
 
-func (db *DatastoreDB) MarkUserArticleRead(userID, articleID int, isRead bool) error {
-	// Get existing status or create new one
-	existing, err := db.GetUserArticleStatus(userID, articleID)
-	isStarred := false
-	if err == nil && existing != nil {
-		isStarred = existing.IsStarred
-	}
+func (db *DatastoreDB) SetUserArticleProgress(userID, articleID, progress int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
 
-	return db.SetUserArticleStatus(userID, articleID, isRead, isStarred)
+	key := datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, articleID), nil)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity UserArticleEntity
+		if err := tx.Get(key, &entity); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		entity.UserID = int64(userID)
+		entity.ArticleID = int64(articleID)
+		entity.ReadProgress = int64(progress)
+		if err := stampSyncVersion(tx, userID, &entity); err != nil {
+			return err
+		}
+		_, err := tx.Put(key, &entity)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set read progress: %w", err)
+	}
+	return nil
 }
 
-func (db *DatastoreDB) ToggleUserArticleStar(userID, articleID int) error {
-	// Get existing status
-	existing, err := db.GetUserArticleStatus(userID, articleID)
-	isRead := false
-	isStarred := false
+// preserveReadProgress copies read_progress from any existing UserArticle
+// entities into the replacements about to be written, so status writes (which
+// replace whole entities) don't reset a user's place in an article.
+func preserveReadProgress(tx *datastore.Transaction, keys []*datastore.Key, entities []*UserArticleEntity) error {
+	existing := make([]UserArticleEntity, len(keys))
+	err := tx.GetMulti(keys, existing)
+	multiErr, isME := err.(datastore.MultiError)
+	if err != nil && !isME {
+		return fmt.Errorf("failed to read existing article statuses: %w", err)
+	}
+	for i := range entities {
+		if isME && multiErr[i] != nil {
+			if multiErr[i] != datastore.ErrNoSuchEntity {
+				return fmt.Errorf("failed to read existing article status: %w", multiErr[i])
+			}
+			continue
+		}
+		entities[i].ReadProgress = existing[i].ReadProgress
+	}
+	return nil
+}
+
+// reserveSyncVersions advances the user's change counter by n inside tx and
+// returns the first reserved version. Reading the counter in the transaction
+// makes concurrent writers for the same user commit in version order.
+func reserveSyncVersions(tx *datastore.Transaction, userID, n int) (int64, error) {
+	key := datastore.NameKey("UserSyncState", strconv.Itoa(userID), nil)
+	var state UserSyncStateEntity
+	if err := tx.Get(key, &state); err != nil && err != datastore.ErrNoSuchEntity {
+		return 0, fmt.Errorf("failed to read sync version: %w", err)
+	}
+	first := state.Version + 1
+	state.Version += int64(n)
+	if _, err := tx.Put(key, &state); err != nil {
+		return 0, fmt.Errorf("failed to update sync version: %w", err)
+	}
+	return first, nil
+}
+
+// stampSyncVersions gives each entity about to be written the next reserved
+// change version so sync clients pick it up. Every UserArticle write must be
+// stamped. A transaction sees the counter as of its start, so writes spread
+// over several chunks reserve once and pass next through.
+func stampSyncVersions(entities []*UserArticleEntity, next *int64) {
+	now := time.Now().UTC()
+	for _, e := range entities {
+		e.Version = *next
+		e.UpdatedAt = now
+		*next++
+	}
+}
+
+// stampSyncVersion reserves and stamps a version for a single-entity write.
+func stampSyncVersion(tx *datastore.Transaction, userID int, entity *UserArticleEntity) error {
+	next, err := reserveSyncVersions(tx, userID, 1)
+	if err != nil {
+		return err
+	}
+	stampSyncVersions([]*UserArticleEntity{entity}, &next)
+	return nil
+}
+
+// recordFeedChange stamps a subscribe or unsubscribe for sync clients.
+func recordFeedChange(tx *datastore.Transaction, userID, feedID int, subscribed bool) error {
+	version, err := reserveSyncVersions(tx, userID, 1)
+	if err != nil {
+		return err
+	}
+	key := datastore.NameKey("UserFeedChange", fmt.Sprintf("%d_%d", userID, feedID), nil)
+	_, err = tx.Put(key, &UserFeedChangeEntity{
+		UserID:     int64(userID),
+		FeedID:     int64(feedID),
+		Subscribed: subscribed,
+		Version:    version,
+		ChangedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record subscription change: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) MarkUserArticleRead(userID, articleID int, isRead bool) error {
+	// Get existing status or create new one
+	existing, err := db.GetUserArticleStatus(userID, articleID)
+	isStarred := false
+	if err == nil && existing != nil {
+		isStarred = existing.IsStarred
+	}
+
+	return db.SetUserArticleStatus(userID, articleID, isRead, isStarred)
+}
+
+func (db *DatastoreDB) ToggleUserArticleStar(userID, articleID int) error {
+	// Get existing status
+	existing, err := db.GetUserArticleStatus(userID, articleID)
+	isRead := false
+	isStarred := false
 
 	if err == nil && existing != nil {
 		isRead = existing.IsRead
@@ -1352,6 +1878,10 @@ func (db *DatastoreDB) BatchSetUserArticleStatus(userID int, articles []Article,
 	// Wrap all chunk writes in a single transaction so a failure in any chunk
 	// rolls back the entire operation instead of leaving a partial update committed.
 	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		next, err := reserveSyncVersions(tx, userID, len(articles))
+		if err != nil {
+			return err
+		}
 		chunkSize := 500 // Cloud Datastore supports up to 500 entities per batch commit
 		for i := 0; i < len(articles); i += chunkSize {
 			end := i + chunkSize
@@ -1374,6 +1904,10 @@ func (db *DatastoreDB) BatchSetUserArticleStatus(userID int, articles []Article,
 				keys[j] = datastore.NameKey("UserArticle", keyStr, nil)
 			}
 
+			if err := preserveReadProgress(tx, keys, entities); err != nil {
+				return err
+			}
+			stampSyncVersions(entities, &next)
 			if _, err := tx.PutMulti(keys, entities); err != nil {
 				return fmt.Errorf("failed to write article status batch: %w", err)
 			}
@@ -1416,6 +1950,10 @@ func (db *DatastoreDB) MarkAllUserArticlesRead(userID int) (int, error) {
 	}
 
 	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		next, err := reserveSyncVersions(tx, userID, len(articleIDs))
+		if err != nil {
+			return err
+		}
 		chunkSize := 500
 		for i := 0; i < len(articleIDs); i += chunkSize {
 			end := i + chunkSize
@@ -1434,6 +1972,10 @@ func (db *DatastoreDB) MarkAllUserArticlesRead(userID int) (int, error) {
 				}
 				keys[j] = datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, aid), nil)
 			}
+			if err := preserveReadProgress(tx, keys, entities); err != nil {
+				return err
+			}
+			stampSyncVersions(entities, &next)
 			if _, err := tx.PutMulti(keys, entities); err != nil {
 				return fmt.Errorf("failed to write read status batch: %w", err)
 			}
@@ -1446,6 +1988,379 @@ func (db *DatastoreDB) MarkAllUserArticlesRead(userID int) (int, error) {
 	return len(articleIDs), nil
 }
 
+// MarkUserArticlesReadScoped marks the user's unread articles within scope as
+// read, keeping starred flags and reading progress.
+func (db *DatastoreDB) MarkUserArticlesReadScoped(userID int, scope MarkReadScope) (*MarkReadResult, error) {
+	defer logSlowQuery("MarkUserArticlesReadScoped", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	feeds, err := db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user feeds: %w", err)
+	}
+	inScope := make(map[int]bool, len(scope.FeedIDs))
+	for _, id := range scope.FeedIDs {
+		inScope[id] = true
+	}
+
+	// Recent articles count towards unread totals; skip looking them up when
+	// the cutoff excludes the whole unread-count window.
+	windowStart := time.Now().UTC().Add(-unreadCountWindowDays * 24 * time.Hour)
+	checkRecent := scope.PublishedBefore.IsZero() || scope.PublishedBefore.After(windowStart)
+
+	type candidate struct {
+		articleID int64
+		feedID    int
+		recent    bool
+	}
+	var candidates []candidate
+	for _, feed := range feeds {
+		if len(inScope) > 0 && !inScope[feed.ID] {
+			continue
+		}
+
+		q := datastore.NewQuery("Article").FilterField("feed_id", "=", int64(feed.ID))
+		if !scope.PublishedBefore.IsZero() {
+			q = q.FilterField("published_at", "<", scope.PublishedBefore.UTC())
+		}
+		keys, err := db.client.GetAll(ctx, q.KeysOnly(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get article keys for feed %d: %w", feed.ID, err)
+		}
+
+		recent := make(map[int64]bool)
+		if checkRecent && len(keys) > 0 {
+			rq := datastore.NewQuery("Article").
+				FilterField("feed_id", "=", int64(feed.ID)).
+				FilterField("published_at", ">=", windowStart).
+				KeysOnly()
+			recentKeys, err := db.client.GetAll(ctx, rq, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get recent article keys for feed %d: %w", feed.ID, err)
+			}
+			for _, k := range recentKeys {
+				recent[k.ID] = true
+			}
+		}
+
+		for _, k := range keys {
+			candidates = append(candidates, candidate{articleID: k.ID, feedID: feed.ID, recent: recent[k.ID]})
+		}
+	}
+
+	var result *MarkReadResult
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		// Reset on each attempt since transactions may be retried.
+		result = &MarkReadResult{UnreadCleared: make(map[int]int)}
+		if len(candidates) == 0 {
+			return nil
+		}
+		// Reserve enough for every candidate; already-read ones leave gaps,
+		// which sync clients don't mind.
+		next, err := reserveSyncVersions(tx, userID, len(candidates))
+		if err != nil {
+			return err
+		}
+		chunkSize := 500
+		for i := 0; i < len(candidates); i += chunkSize {
+			end := i + chunkSize
+			if end > len(candidates) {
+				end = len(candidates)
+			}
+			chunk := candidates[i:end]
+
+			keys := make([]*datastore.Key, len(chunk))
+			for j, c := range chunk {
+				keys[j] = datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, c.articleID), nil)
+			}
+			existing := make([]UserArticleEntity, len(keys))
+			err := tx.GetMulti(keys, existing)
+			multiErr, isME := err.(datastore.MultiError)
+			if err != nil && !isME {
+				return fmt.Errorf("failed to read existing article statuses: %w", err)
+			}
+
+			var putKeys []*datastore.Key
+			var entities []*UserArticleEntity
+			for j, c := range chunk {
+				if isME && multiErr[j] != nil {
+					if multiErr[j] != datastore.ErrNoSuchEntity {
+						return fmt.Errorf("failed to read existing article status: %w", multiErr[j])
+					}
+					existing[j] = UserArticleEntity{}
+				}
+				if existing[j].IsRead {
+					continue
+				}
+				putKeys = append(putKeys, keys[j])
+				entities = append(entities, &UserArticleEntity{
+					UserID:       int64(userID),
+					ArticleID:    c.articleID,
+					IsRead:       true,
+					IsStarred:    existing[j].IsStarred,
+					ReadProgress: existing[j].ReadProgress,
+				})
+				result.Changes = append(result.Changes, ArticleStatusChange{
+					ArticleID:   int(c.articleID),
+					FeedID:      c.feedID,
+					WasStarred:  existing[j].IsStarred,
+					ReadChanged: true,
+				})
+				if c.recent {
+					result.UnreadCleared[c.feedID]++
+				}
+			}
+
+			if len(putKeys) == 0 {
+				continue
+			}
+			stampSyncVersions(entities, &next)
+			if _, err := tx.PutMulti(putKeys, entities); err != nil {
+				return fmt.Errorf("failed to write read status batch: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark articles read: %w", err)
+	}
+	return result, nil
+}
+
+func (db *DatastoreDB) UpdateUserArticleStatuses(userID int, updates []ArticleStatusUpdate) (*StatusUpdateResult, error) {
+	defer logSlowQuery("UpdateUserArticleStatuses", time.Now())
+	if len(updates) == 0 {
+		return &StatusUpdateResult{Found: make(map[int]int), UnreadDelta: make(map[int]int)}, nil
+	}
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	feeds, err := db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user feeds: %w", err)
+	}
+	subscribed := make(map[int64]bool, len(feeds))
+	for _, feed := range feeds {
+		subscribed[int64(feed.ID)] = true
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, u := range updates {
+		if !seen[u.ArticleID] {
+			seen[u.ArticleID] = true
+			ids = append(ids, u.ArticleID)
+		}
+	}
+
+	// Keep only articles in the user's feeds, noting which count towards
+	// unread totals.
+	type candidate struct {
+		articleID int
+		feedID    int
+		recent    bool
+	}
+	var candidates []candidate
+	windowStart := time.Now().UTC().Add(-unreadCountWindowDays * 24 * time.Hour)
+	for i := 0; i < len(ids); i += 1000 {
+		end := i + 1000
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[i:end]
+		keys := make([]*datastore.Key, len(chunk))
+		for j, id := range chunk {
+			keys[j] = datastore.IDKey("Article", int64(id), nil)
+		}
+		articles := make([]ArticleEntity, len(keys))
+		err := db.client.GetMulti(ctx, keys, articles)
+		multiErr, isME := err.(datastore.MultiError)
+		if err != nil && !isME {
+			return nil, fmt.Errorf("failed to get articles: %w", err)
+		}
+		for j, a := range articles {
+			if isME && multiErr[j] != nil {
+				if multiErr[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to get article: %w", multiErr[j])
+				}
+				continue
+			}
+			if !subscribed[a.FeedID] {
+				continue
+			}
+			candidates = append(candidates, candidate{
+				articleID: chunk[j],
+				feedID:    int(a.FeedID),
+				recent:    !a.PublishedAt.Before(windowStart),
+			})
+		}
+	}
+
+	var result *StatusUpdateResult
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		// Reset on each attempt since transactions may be retried.
+		result = &StatusUpdateResult{Found: make(map[int]int), UnreadDelta: make(map[int]int)}
+		if len(candidates) == 0 {
+			return nil
+		}
+
+		keys := make([]*datastore.Key, len(candidates))
+		entities := make([]*UserArticleEntity, len(candidates))
+		index := make(map[int]int, len(candidates))
+		chunkSize := 500
+		for i := 0; i < len(candidates); i += chunkSize {
+			end := i + chunkSize
+			if end > len(candidates) {
+				end = len(candidates)
+			}
+			existing := make([]UserArticleEntity, end-i)
+			for j, c := range candidates[i:end] {
+				keys[i+j] = datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, c.articleID), nil)
+			}
+			err := tx.GetMulti(keys[i:end], existing)
+			multiErr, isME := err.(datastore.MultiError)
+			if err != nil && !isME {
+				return fmt.Errorf("failed to read existing article statuses: %w", err)
+			}
+			for j, c := range candidates[i:end] {
+				if isME && multiErr[j] != nil {
+					if multiErr[j] != datastore.ErrNoSuchEntity {
+						return fmt.Errorf("failed to read existing article status: %w", multiErr[j])
+					}
+					existing[j] = UserArticleEntity{UserID: int64(userID), ArticleID: int64(c.articleID)}
+				}
+				entities[i+j] = &existing[j]
+				index[c.articleID] = i + j
+				result.Found[c.articleID] = c.feedID
+			}
+		}
+
+		prior := make([]UserArticleEntity, len(entities))
+		for i, e := range entities {
+			prior[i] = *e
+		}
+		for _, u := range updates {
+			i, ok := index[u.ArticleID]
+			if !ok {
+				continue
+			}
+			if u.IsRead != nil {
+				entities[i].IsRead = *u.IsRead
+			}
+			if u.IsStarred != nil {
+				entities[i].IsStarred = *u.IsStarred
+			}
+		}
+
+		var putKeys []*datastore.Key
+		var changed []*UserArticleEntity
+		for i, c := range candidates {
+			e, was := entities[i], prior[i]
+			if e.IsRead == was.IsRead && e.IsStarred == was.IsStarred {
+				continue
+			}
+			putKeys = append(putKeys, keys[i])
+			changed = append(changed, e)
+			result.Changes = append(result.Changes, ArticleStatusChange{
+				ArticleID:      c.articleID,
+				FeedID:         c.feedID,
+				WasRead:        was.IsRead,
+				WasStarred:     was.IsStarred,
+				ReadChanged:    e.IsRead != was.IsRead,
+				StarredChanged: e.IsStarred != was.IsStarred,
+			})
+			if c.recent && e.IsRead != was.IsRead {
+				if e.IsRead {
+					result.UnreadDelta[c.feedID]--
+				} else {
+					result.UnreadDelta[c.feedID]++
+				}
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		next, err := reserveSyncVersions(tx, userID, len(changed))
+		if err != nil {
+			return err
+		}
+		stampSyncVersions(changed, &next)
+		for i := 0; i < len(changed); i += chunkSize {
+			end := i + chunkSize
+			if end > len(changed) {
+				end = len(changed)
+			}
+			if _, err := tx.PutMulti(putKeys[i:end], changed[i:end]); err != nil {
+				return fmt.Errorf("failed to write article status batch: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update article statuses: %w", err)
+	}
+	sort.Slice(result.Changes, func(i, j int) bool { return result.Changes[i].ArticleID < result.Changes[j].ArticleID })
+	return result, nil
+}
+
+// GetUserStarredCountsByFeed returns how many articles the user has starred in
+// each subscribed feed. Feeds without starred articles are omitted.
+func (db *DatastoreDB) GetUserStarredCountsByFeed(userID int) (map[int]int, error) {
+	defer logSlowQuery("GetUserStarredCountsByFeed", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	feeds, err := db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user feeds: %w", err)
+	}
+	subscribed := make(map[int64]bool, len(feeds))
+	for _, feed := range feeds {
+		subscribed[int64(feed.ID)] = true
+	}
+
+	q := datastore.NewQuery("UserArticle").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("is_starred", "=", true)
+	var starred []UserArticleEntity
+	if _, err := db.client.GetAll(ctx, q, &starred); err != nil {
+		return nil, fmt.Errorf("failed to get starred articles: %w", err)
+	}
+
+	counts := make(map[int]int)
+	chunkSize := 1000 // GetMulti accepts at most 1000 keys
+	for i := 0; i < len(starred); i += chunkSize {
+		end := i + chunkSize
+		if end > len(starred) {
+			end = len(starred)
+		}
+		keys := make([]*datastore.Key, end-i)
+		for j, ua := range starred[i:end] {
+			keys[j] = datastore.IDKey("Article", ua.ArticleID, nil)
+		}
+		articles := make([]ArticleEntity, len(keys))
+		err := db.client.GetMulti(ctx, keys, articles)
+		multiErr, isME := err.(datastore.MultiError)
+		if err != nil && !isME {
+			return nil, fmt.Errorf("failed to get starred article feeds: %w", err)
+		}
+		for j, a := range articles {
+			if isME && multiErr[j] != nil {
+				if multiErr[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to get starred article: %w", multiErr[j])
+				}
+				continue
+			}
+			if subscribed[a.FeedID] {
+				counts[int(a.FeedID)]++
+			}
+		}
+	}
+	return counts, nil
+}
+
 func (db *DatastoreDB) GetUserUnreadCounts(userID int) (map[int]int, error) {
 	defer logSlowQuery("GetUserUnreadCounts", time.Now())
 	ctx, cancel := newDatastoreContext()
@@ -2094,11 +3009,14 @@ func (db *DatastoreDB) GrantFreeMonths(userID int, months int) error {
 	return nil
 }
 
+// GetUserByEmail matches the normalized address exactly: Datastore can't
+// compare case-insensitively, so users stored before addresses were
+// normalized need the user-email-lowercase backfill to be found.
 func (db *DatastoreDB) GetUserByEmail(email string) (*User, error) {
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
 
-	query := datastore.NewQuery("User").FilterField("email", "=", email).Limit(1)
+	query := datastore.NewQuery("User").FilterField("email", "=", NormalizeEmail(email)).Limit(1)
 
 	var users []UserEntity
 	keys, err := db.client.GetAll(ctx, query, &users)
@@ -2113,27 +3031,50 @@ func (db *DatastoreDB) GetUserByEmail(email string) (*User, error) {
 	user := users[0]
 	user.ID = keys[0].ID
 
-	maxArticles := user.MaxArticlesOnFeedAdd
-	if maxArticles == 0 {
-		maxArticles = 100 // Default for existing users
+	return userFromEntity(&user), nil
+}
+
+func (db *DatastoreDB) DeleteUser(userID int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	uid := int64(userID)
+	var keys []*datastore.Key
+	for _, kind := range []string{"Session", "RefreshToken", "UserIdentity", "UserFeed", "UserArticle", "ArticleTag", "Tag", "Annotation",
+		"UndoOperation", "InboundAddress", "UserFeedChange"} {
+		kindKeys, err := db.client.GetAll(ctx, datastore.NewQuery(kind).FilterField("user_id", "=", uid).KeysOnly(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to query %s entities: %w", kind, err)
+		}
+		keys = append(keys, kindKeys...)
 	}
 
-	return &User{
-		ID:                   int(user.ID),
-		GoogleID:             user.GoogleID,
-		Email:                user.Email,
-		Name:                 user.Name,
-		Avatar:               user.Avatar,
-		CreatedAt:            user.CreatedAt,
-		SubscriptionStatus:   user.SubscriptionStatus,
-		SubscriptionID:       user.SubscriptionID,
-		TrialEndsAt:          user.TrialEndsAt,
-		LastPaymentDate:      user.LastPaymentDate,
-		NextBillingDate:      user.NextBillingDate,
-		IsAdmin:              user.IsAdmin,
-		FreeMonthsRemaining:  user.FreeMonthsRemaining,
-		MaxArticlesOnFeedAdd: maxArticles,
-	}, nil
+	// Deliveries only index their webhook, so they're found through the webhooks
+	webhookKeys, err := db.client.GetAll(ctx, datastore.NewQuery("Webhook").FilterField("user_id", "=", uid).KeysOnly(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	for _, webhookKey := range webhookKeys {
+		q := datastore.NewQuery("WebhookDelivery").FilterField("webhook_id", "=", webhookKey.ID).KeysOnly()
+		deliveryKeys, err := db.client.GetAll(ctx, q, nil)
+		if err != nil {
+			return fmt.Errorf("failed to query webhook deliveries: %w", err)
+		}
+		keys = append(keys, deliveryKeys...)
+	}
+	keys = append(keys, webhookKeys...)
+	keys = append(keys, datastore.NameKey("UserSyncState", strconv.Itoa(userID), nil))
+
+	// The user key is last, so a failure part-way leaves the account in
+	// place and the delete can be retried.
+	keys = append(keys, datastore.IDKey("User", uid, nil))
+	for i := 0; i < len(keys); i += 500 {
+		end := min(i+500, len(keys))
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+	}
+	return nil
 }
 
 // Session methods for Datastore
@@ -2142,10 +3083,14 @@ func (db *DatastoreDB) CreateSession(session *Session) error {
 	defer cancel()
 
 	entity := &SessionEntity{
-		ID:        session.ID,
-		UserID:    int64(session.UserID),
-		CreatedAt: session.CreatedAt,
-		ExpiresAt: session.ExpiresAt,
+		ID:         session.ID,
+		UserID:     int64(session.UserID),
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		UserAgent:  session.UserAgent,
+		ClientType: session.ClientType,
+		IPAddress:  session.IPAddress,
+		LastSeenAt: session.LastSeenAt,
 	}
 
 	key := datastore.NameKey("Session", session.ID, nil)
@@ -2170,14 +3115,27 @@ func (db *DatastoreDB) GetSession(sessionID string) (*Session, error) {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
-	entity.ID = sessionID
+	session := sessionFromEntity(sessionID, &entity)
+	return &session, nil
+}
 
-	return &Session{
-		ID:        entity.ID,
-		UserID:    int(entity.UserID),
-		CreatedAt: entity.CreatedAt,
-		ExpiresAt: entity.ExpiresAt,
-	}, nil
+// sessionFromEntity converts a stored session. Sessions created before
+// activity was tracked were last seen when they were created.
+func sessionFromEntity(id string, entity *SessionEntity) Session {
+	lastSeen := entity.LastSeenAt
+	if lastSeen.IsZero() {
+		lastSeen = entity.CreatedAt
+	}
+	return Session{
+		ID:         id,
+		UserID:     int(entity.UserID),
+		CreatedAt:  entity.CreatedAt,
+		ExpiresAt:  entity.ExpiresAt,
+		UserAgent:  entity.UserAgent,
+		ClientType: entity.ClientType,
+		IPAddress:  entity.IPAddress,
+		LastSeenAt: lastSeen,
+	}
 }
 
 func (db *DatastoreDB) UpdateSessionExpiry(sessionID string, newExpiry time.Time) error {
@@ -2202,6 +3160,31 @@ func (db *DatastoreDB) UpdateSessionExpiry(sessionID string, newExpiry time.Time
 	return nil
 }
 
+func (db *DatastoreDB) UpdateSessionActivity(sessionID string, lastSeenAt time.Time, ipAddress string) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.NameKey("Session", sessionID, nil)
+	// In a transaction so a concurrent expiry refresh isn't overwritten
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity SessionEntity
+		if err := tx.Get(key, &entity); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		entity.LastSeenAt = lastSeenAt
+		entity.IPAddress = ipAddress
+		_, err := tx.Put(key, &entity)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update session activity: %w", err)
+	}
+	return nil
+}
+
 func (db *DatastoreDB) DeleteSession(sessionID string) error {
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
@@ -2213,16 +3196,36 @@ func (db *DatastoreDB) DeleteSession(sessionID string) error {
 	return nil
 }
 
-func (db *DatastoreDB) DeleteExpiredSessions() error {
+func (db *DatastoreDB) GetUserSessions(userID int) ([]Session, error) {
 	ctx, cancel := newDatastoreContext()
 	defer cancel()
 
-	// Query for expired sessions
-	query := datastore.NewQuery("Session").FilterField("expires_at", "<", time.Now()).KeysOnly()
-
-	keys, err := db.client.GetAll(ctx, query, nil)
+	var entities []SessionEntity
+	query := datastore.NewQuery("Session").FilterField("user_id", "=", int64(userID))
+	keys, err := db.client.GetAll(ctx, query, &entities)
 	if err != nil {
-		return fmt.Errorf("failed to query expired sessions: %w", err)
+		return nil, fmt.Errorf("failed to query user sessions: %w", err)
+	}
+
+	sessions := make([]Session, len(entities))
+	for i := range entities {
+		sessions[i] = sessionFromEntity(keys[i].Name, &entities[i])
+	}
+	// Sorted here rather than in the query, which would need a composite index
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (db *DatastoreDB) DeleteExpiredSessions() error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	// Query for expired sessions
+	query := datastore.NewQuery("Session").FilterField("expires_at", "<", time.Now()).KeysOnly()
+
+	keys, err := db.client.GetAll(ctx, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query expired sessions: %w", err)
 	}
 
 	// Delete expired sessions in batches
@@ -2235,6 +3238,220 @@ func (db *DatastoreDB) DeleteExpiredSessions() error {
 	return nil
 }
 
+// Identity methods for Datastore
+
+func userIdentityKey(provider, subject string) *datastore.Key {
+	return datastore.NameKey("UserIdentity", provider+":"+subject, nil)
+}
+
+func (db *DatastoreDB) CreateUserIdentity(identity *UserIdentity) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := userIdentityKey(identity.Provider, identity.Subject)
+	entity := &UserIdentityEntity{
+		UserID:    int64(identity.UserID),
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var existing UserIdentityEntity
+		if err := tx.Get(key, &existing); err == nil {
+			return fmt.Errorf("%s identity is already linked to user %d", identity.Provider, existing.UserID)
+		} else if err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		_, err := tx.Put(key, entity)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entity UserIdentityEntity
+	err := db.client.Get(ctx, userIdentityKey(provider, subject), &entity)
+	if err == datastore.ErrNoSuchEntity {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	identity := userIdentityFromEntity(&entity)
+	return &identity, nil
+}
+
+func (db *DatastoreDB) GetUserIdentities(userID int) ([]UserIdentity, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []UserIdentityEntity
+	query := datastore.NewQuery("UserIdentity").FilterField("user_id", "=", int64(userID))
+	if _, err := db.client.GetAll(ctx, query, &entities); err != nil {
+		return nil, fmt.Errorf("failed to query identities: %w", err)
+	}
+
+	identities := make([]UserIdentity, len(entities))
+	for i := range entities {
+		identities[i] = userIdentityFromEntity(&entities[i])
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].CreatedAt.Before(identities[j].CreatedAt) })
+	return identities, nil
+}
+
+func userIdentityFromEntity(entity *UserIdentityEntity) UserIdentity {
+	return UserIdentity{
+		UserID:    int(entity.UserID),
+		Provider:  entity.Provider,
+		Subject:   entity.Subject,
+		Email:     entity.Email,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+// Login token methods for Datastore
+
+func (db *DatastoreDB) CreateLoginToken(token *LoginToken) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	entity := &LoginTokenEntity{Email: token.Email, Mobile: token.Mobile, ExpiresAt: token.ExpiresAt}
+	if _, err := db.client.Put(ctx, datastore.NameKey("LoginToken", token.TokenHash, nil), entity); err != nil {
+		return fmt.Errorf("failed to create login token: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) ConsumeLoginToken(tokenHash string) (*LoginToken, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.NameKey("LoginToken", tokenHash, nil)
+	var entity LoginTokenEntity
+	var found bool
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		found = false
+		err := tx.Get(key, &entity)
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		return tx.Delete(key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume login token: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &LoginToken{TokenHash: tokenHash, Email: entity.Email, Mobile: entity.Mobile, ExpiresAt: entity.ExpiresAt}, nil
+}
+
+func (db *DatastoreDB) DeleteExpiredLoginTokens() error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	query := datastore.NewQuery("LoginToken").FilterField("expires_at", "<", time.Now()).KeysOnly()
+	keys, err := db.client.GetAll(ctx, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query expired login tokens: %w", err)
+	}
+	for i := 0; i < len(keys); i += 500 {
+		end := min(i+500, len(keys))
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return fmt.Errorf("failed to delete expired login tokens: %w", err)
+		}
+	}
+	return nil
+}
+
+// Refresh token methods for Datastore
+
+func (db *DatastoreDB) CreateRefreshToken(token *RefreshToken) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	entity := &RefreshTokenEntity{
+		SessionID: token.SessionID,
+		UserID:    int64(token.UserID),
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+	if _, err := db.client.Put(ctx, datastore.NameKey("RefreshToken", token.TokenHash, nil), entity); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.NameKey("RefreshToken", tokenHash, nil)
+	var entity RefreshTokenEntity
+	var found bool
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		found = false
+		err := tx.Get(key, &entity)
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if !entity.UsedAt.IsZero() {
+			return nil
+		}
+		used := entity
+		used.UsedAt = usedAt
+		_, err = tx.Put(key, &used)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &RefreshToken{
+		TokenHash: tokenHash,
+		SessionID: entity.SessionID,
+		UserID:    int(entity.UserID),
+		CreatedAt: entity.CreatedAt,
+		ExpiresAt: entity.ExpiresAt,
+		UsedAt:    entity.UsedAt,
+	}, nil
+}
+
+func (db *DatastoreDB) DeleteExpiredRefreshTokens() error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	query := datastore.NewQuery("RefreshToken").FilterField("expires_at", "<", time.Now()).KeysOnly()
+	keys, err := db.client.GetAll(ctx, query, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query expired refresh tokens: %w", err)
+	}
+	for i := 0; i < len(keys); i += 500 {
+		end := min(i+500, len(keys))
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+		}
+	}
+	return nil
+}
+
 // Audit log methods for Datastore
 func (db *DatastoreDB) CreateAuditLog(log *AuditLog) error {
 	ctx, cancel := newDatastoreContext()
@@ -2309,3 +3526,1722 @@ func (db *DatastoreDB) GetAuditLogs(limit, offset int, filters map[string]interf
 
 	return logs, nil
 }
+
+// Annotation methods for Datastore
+func (db *DatastoreDB) CreateAnnotation(annotation *Annotation) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	entity := &AnnotationEntity{
+		UserID:      int64(annotation.UserID),
+		ArticleID:   int64(annotation.ArticleID),
+		QuotedText:  annotation.QuotedText,
+		StartOffset: annotation.StartOffset,
+		EndOffset:   annotation.EndOffset,
+		Note:        annotation.Note,
+		CreatedAt:   annotation.CreatedAt,
+		UpdatedAt:   annotation.UpdatedAt,
+	}
+
+	key, err := db.client.Put(ctx, datastore.IncompleteKey("Annotation", nil), entity)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation: %w", err)
+	}
+
+	annotation.ID = int(key.ID)
+	return nil
+}
+
+func (db *DatastoreDB) GetAnnotation(userID, annotationID int) (*Annotation, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Annotation", int64(annotationID), nil)
+	var entity AnnotationEntity
+	if err := db.client.Get(ctx, key, &entity); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get annotation: %w", err)
+	}
+
+	// Annotations are private; treat another user's annotation as missing.
+	if entity.UserID != int64(userID) {
+		return nil, nil
+	}
+
+	entity.ID = key.ID
+	annotation := annotationFromEntity(&entity)
+	return &annotation, nil
+}
+
+func (db *DatastoreDB) GetArticleAnnotations(userID, articleID int) ([]Annotation, error) {
+	query := datastore.NewQuery("Annotation").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("article_id", "=", int64(articleID))
+
+	annotations, err := db.queryAnnotations(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article annotations: %w", err)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		if annotations[i].StartOffset != annotations[j].StartOffset {
+			return annotations[i].StartOffset < annotations[j].StartOffset
+		}
+		return annotations[i].ID < annotations[j].ID
+	})
+	return annotations, nil
+}
+
+func (db *DatastoreDB) GetUserAnnotations(userID int) ([]Annotation, error) {
+	defer logSlowQuery("GetUserAnnotations", time.Now())
+	query := datastore.NewQuery("Annotation").FilterField("user_id", "=", int64(userID))
+
+	annotations, err := db.queryAnnotations(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user annotations: %w", err)
+	}
+
+	sort.Slice(annotations, func(i, j int) bool {
+		a, b := annotations[i], annotations[j]
+		if a.ArticleID != b.ArticleID {
+			return a.ArticleID < b.ArticleID
+		}
+		if a.StartOffset != b.StartOffset {
+			return a.StartOffset < b.StartOffset
+		}
+		return a.ID < b.ID
+	})
+	return annotations, nil
+}
+
+func (db *DatastoreDB) queryAnnotations(query *datastore.Query) ([]Annotation, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []AnnotationEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := make([]Annotation, len(entities))
+	for i := range entities {
+		entities[i].ID = keys[i].ID
+		annotations[i] = annotationFromEntity(&entities[i])
+	}
+	return annotations, nil
+}
+
+func (db *DatastoreDB) UpdateAnnotation(annotation *Annotation) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Annotation", int64(annotation.ID), nil)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity AnnotationEntity
+		if err := tx.Get(key, &entity); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if entity.UserID != int64(annotation.UserID) {
+			return nil
+		}
+
+		entity.QuotedText = annotation.QuotedText
+		entity.StartOffset = annotation.StartOffset
+		entity.EndOffset = annotation.EndOffset
+		entity.Note = annotation.Note
+		entity.UpdatedAt = annotation.UpdatedAt
+		_, err := tx.Put(key, &entity)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update annotation: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) DeleteAnnotation(userID, annotationID int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Annotation", int64(annotationID), nil)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity AnnotationEntity
+		if err := tx.Get(key, &entity); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if entity.UserID != int64(userID) {
+			return nil
+		}
+		return tx.Delete(key)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete annotation: %w", err)
+	}
+	return nil
+}
+
+func annotationFromEntity(entity *AnnotationEntity) Annotation {
+	return Annotation{
+		ID:          int(entity.ID),
+		UserID:      int(entity.UserID),
+		ArticleID:   int(entity.ArticleID),
+		QuotedText:  entity.QuotedText,
+		StartOffset: entity.StartOffset,
+		EndOffset:   entity.EndOffset,
+		Note:        entity.Note,
+		CreatedAt:   entity.CreatedAt,
+		UpdatedAt:   entity.UpdatedAt,
+	}
+}
+
+// articleTagKey builds the ArticleTag key; the name encodes user, tag and
+// article so tagging is idempotent and counts can be derived from keys alone.
+func articleTagKey(userID, tagID, articleID int64) *datastore.Key {
+	return datastore.NameKey("ArticleTag", fmt.Sprintf("%d_%d_%d", userID, tagID, articleID), nil)
+}
+
+// tagNameKey builds the TagName key that reserves a tag name for a user.
+// Creating or renaming a tag claims it inside the same transaction as the
+// tag write, since a query for the name can't run in a transaction.
+func tagNameKey(userID int, name string) *datastore.Key {
+	return datastore.NameKey("TagName", fmt.Sprintf("%d_%s", userID, name), nil)
+}
+
+// claimTagName reserves name for tagID in tx, failing with ErrTagExists when
+// another tag holds it.
+func claimTagName(tx *datastore.Transaction, userID int, name string, tagID int64) error {
+	key := tagNameKey(userID, name)
+	var marker TagNameEntity
+	if err := tx.Get(key, &marker); err == nil {
+		if marker.TagID != tagID {
+			return ErrTagExists
+		}
+		return nil
+	} else if err != datastore.ErrNoSuchEntity {
+		return err
+	}
+	_, err := tx.Put(key, &TagNameEntity{TagID: tagID})
+	return err
+}
+
+// Tag methods for Datastore
+func (db *DatastoreDB) CreateTag(tag *Tag) error {
+	// Tags created before names were reserved have no TagName entity
+	existing, err := db.GetTagByName(tag.UserID, tag.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrTagExists
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	keys, err := db.client.AllocateIDs(ctx, []*datastore.Key{datastore.IncompleteKey("Tag", nil)})
+	if err != nil {
+		return fmt.Errorf("failed to allocate tag ID: %w", err)
+	}
+	key := keys[0]
+	entity := &TagEntity{
+		UserID:    int64(tag.UserID),
+		Name:      tag.Name,
+		CreatedAt: tag.CreatedAt,
+	}
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		if err := claimTagName(tx, tag.UserID, tag.Name, key.ID); err != nil {
+			return err
+		}
+		_, err := tx.Put(key, entity)
+		return err
+	})
+	if errors.Is(err, ErrTagExists) {
+		return ErrTagExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	tag.ID = int(key.ID)
+	return nil
+}
+
+func (db *DatastoreDB) GetTag(userID, tagID int) (*Tag, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entity TagEntity
+	if err := db.client.Get(ctx, datastore.IDKey("Tag", int64(tagID), nil), &entity); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	if entity.UserID != int64(userID) {
+		return nil, nil
+	}
+
+	return &Tag{
+		ID:        tagID,
+		UserID:    int(entity.UserID),
+		Name:      entity.Name,
+		CreatedAt: entity.CreatedAt,
+	}, nil
+}
+
+func (db *DatastoreDB) GetTagByName(userID int, name string) (*Tag, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	query := datastore.NewQuery("Tag").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("name", "=", name).
+		Limit(1)
+
+	var entities []TagEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag by name: %w", err)
+	}
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	return &Tag{
+		ID:        int(keys[0].ID),
+		UserID:    int(entities[0].UserID),
+		Name:      entities[0].Name,
+		CreatedAt: entities[0].CreatedAt,
+	}, nil
+}
+
+func (db *DatastoreDB) GetUserTags(userID int) ([]Tag, error) {
+	defer logSlowQuery("GetUserTags", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []TagEntity
+	keys, err := db.client.GetAll(ctx, datastore.NewQuery("Tag").FilterField("user_id", "=", int64(userID)), &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tags: %w", err)
+	}
+
+	// Count taggings from a keys-only query; the tag ID is embedded in each key name.
+	tagKeys, err := db.client.GetAll(ctx, datastore.NewQuery("ArticleTag").
+		FilterField("user_id", "=", int64(userID)).KeysOnly(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tagged articles: %w", err)
+	}
+	counts := make(map[int64]int)
+	for _, k := range tagKeys {
+		var uid, tid, aid int64
+		if _, err := fmt.Sscanf(k.Name, "%d_%d_%d", &uid, &tid, &aid); err == nil {
+			counts[tid]++
+		}
+	}
+
+	tags := make([]Tag, len(entities))
+	for i, entity := range entities {
+		tags[i] = Tag{
+			ID:           int(keys[i].ID),
+			UserID:       int(entity.UserID),
+			Name:         entity.Name,
+			CreatedAt:    entity.CreatedAt,
+			ArticleCount: counts[keys[i].ID],
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (db *DatastoreDB) RenameTag(userID, tagID int, name string) error {
+	// Tags created before names were reserved have no TagName entity
+	existing, err := db.GetTagByName(userID, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != tagID {
+		return ErrTagExists
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Tag", int64(tagID), nil)
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity TagEntity
+		if err := tx.Get(key, &entity); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if entity.UserID != int64(userID) || entity.Name == name {
+			return nil
+		}
+		if err := claimTagName(tx, userID, name, key.ID); err != nil {
+			return err
+		}
+		if err := tx.Delete(tagNameKey(userID, entity.Name)); err != nil {
+			return err
+		}
+		entity.Name = name
+		_, err := tx.Put(key, &entity)
+		return err
+	})
+	if errors.Is(err, ErrTagExists) {
+		return ErrTagExists
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) DeleteTag(userID, tagID int) error {
+	tag, err := db.GetTag(userID, tagID)
+	if err != nil || tag == nil {
+		return err
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	keys, err := db.client.GetAll(ctx, datastore.NewQuery("ArticleTag").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("tag_id", "=", int64(tagID)).
+		KeysOnly(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get tagged articles: %w", err)
+	}
+
+	chunkSize := 500
+	for i := 0; i < len(keys); i += chunkSize {
+		end := i + chunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return fmt.Errorf("failed to remove tag from articles: %w", err)
+		}
+	}
+
+	if err := db.client.DeleteMulti(ctx, []*datastore.Key{
+		datastore.IDKey("Tag", int64(tagID), nil),
+		tagNameKey(userID, tag.Name),
+	}); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) TagArticle(userID, articleID, tagID int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var article ArticleEntity
+	if err := db.client.Get(ctx, datastore.IDKey("Article", int64(articleID), nil), &article); err != nil {
+		return fmt.Errorf("failed to get article for tagging: %w", err)
+	}
+
+	entity := &ArticleTagEntity{
+		UserID:      int64(userID),
+		ArticleID:   int64(articleID),
+		TagID:       int64(tagID),
+		PublishedAt: article.PublishedAt,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := db.client.Put(ctx, articleTagKey(int64(userID), int64(tagID), int64(articleID)), entity); err != nil {
+		return fmt.Errorf("failed to tag article: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) UntagArticle(userID, articleID, tagID int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	if err := db.client.Delete(ctx, articleTagKey(int64(userID), int64(tagID), int64(articleID))); err != nil {
+		return fmt.Errorf("failed to untag article: %w", err)
+	}
+	return nil
+}
+
+// datastoreInFilterLimit is the maximum number of values in a single "in" filter.
+const datastoreInFilterLimit = 30
+
+func (db *DatastoreDB) GetArticleTags(userID int, articleIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	if len(articleIDs) == 0 {
+		return result, nil
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var taggings []ArticleTagEntity
+	for i := 0; i < len(articleIDs); i += datastoreInFilterLimit {
+		end := i + datastoreInFilterLimit
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+		ids := make([]interface{}, 0, end-i)
+		for _, id := range articleIDs[i:end] {
+			ids = append(ids, int64(id))
+		}
+
+		var chunk []ArticleTagEntity
+		query := datastore.NewQuery("ArticleTag").
+			FilterField("user_id", "=", int64(userID)).
+			FilterField("article_id", "in", ids)
+		if _, err := db.client.GetAll(ctx, query, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to get article tags: %w", err)
+		}
+		taggings = append(taggings, chunk...)
+	}
+	if len(taggings) == 0 {
+		return result, nil
+	}
+
+	// Resolve tag names with a single batch lookup.
+	tagIndex := make(map[int64]int)
+	var tagKeys []*datastore.Key
+	for _, t := range taggings {
+		if _, ok := tagIndex[t.TagID]; !ok {
+			tagIndex[t.TagID] = len(tagKeys)
+			tagKeys = append(tagKeys, datastore.IDKey("Tag", t.TagID, nil))
+		}
+	}
+	tags := make([]TagEntity, len(tagKeys))
+	err := db.client.GetMulti(ctx, tagKeys, tags)
+	multiErr, isME := err.(datastore.MultiError)
+	if err != nil && !isME {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	for _, t := range taggings {
+		idx := tagIndex[t.TagID]
+		if isME && multiErr[idx] != nil {
+			continue // Tag deleted while its taggings were being removed
+		}
+		result[int(t.ArticleID)] = append(result[int(t.ArticleID)], tags[idx].Name)
+	}
+	for id := range result {
+		sort.Strings(result[id])
+	}
+	return result, nil
+}
+
+// GetTagArticlesPaginated lists the articles carrying a tag, newest first. All
+// of the tag's ArticleTag entities are loaded and sorted in memory (they are
+// small and a single tag rarely spans more than a few hundred articles), then
+// only the page's articles are fetched.
+func (db *DatastoreDB) GetTagArticlesPaginated(userID, tagID int, limit int, cursor string) (*ArticlePaginationResult, error) {
+	defer logSlowQuery("GetTagArticlesPaginated", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var taggings []ArticleTagEntity
+	query := datastore.NewQuery("ArticleTag").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("tag_id", "=", int64(tagID))
+	if _, err := db.client.GetAll(ctx, query, &taggings); err != nil {
+		return nil, fmt.Errorf("failed to get tagged articles: %w", err)
+	}
+
+	sort.Slice(taggings, func(i, j int) bool {
+		if taggings[i].PublishedAt.Equal(taggings[j].PublishedAt) {
+			return taggings[i].ArticleID > taggings[j].ArticleID
+		}
+		return taggings[i].PublishedAt.After(taggings[j].PublishedAt)
+	})
+
+	if cursor != "" {
+		cursorData, err := decodeSQLiteCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		startIdx := len(taggings)
+		for i, t := range taggings {
+			if t.PublishedAt.Before(cursorData.PublishedAt) ||
+				(t.PublishedAt.Equal(cursorData.PublishedAt) && t.ArticleID < int64(cursorData.ID)) {
+				startIdx = i
+				break
+			}
+		}
+		taggings = taggings[startIdx:]
+	}
+
+	var nextCursor string
+	if len(taggings) > limit {
+		last := taggings[limit-1]
+		nextCursor = encodeSQLiteCursor(int(last.ArticleID), last.PublishedAt)
+		taggings = taggings[:limit]
+	}
+	if len(taggings) == 0 {
+		return &ArticlePaginationResult{Articles: []Article{}, NextCursor: nextCursor}, nil
+	}
+
+	articleKeys := make([]*datastore.Key, len(taggings))
+	statusKeys := make([]*datastore.Key, len(taggings))
+	for i, t := range taggings {
+		articleKeys[i] = datastore.IDKey("Article", t.ArticleID, nil)
+		statusKeys[i] = datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, t.ArticleID), nil)
+	}
+
+	entities := make([]ArticleEntity, len(articleKeys))
+	fetchErr := db.client.GetMulti(ctx, articleKeys, entities)
+	articleErrs, articleME := fetchErr.(datastore.MultiError)
+	if fetchErr != nil && !articleME {
+		return nil, fmt.Errorf("failed to fetch article entities: %w", fetchErr)
+	}
+
+	statuses := make([]UserArticleEntity, len(statusKeys))
+	statusErr := db.client.GetMulti(ctx, statusKeys, statuses)
+	statusErrs, statusME := statusErr.(datastore.MultiError)
+	if statusErr != nil && !statusME {
+		return nil, fmt.Errorf("failed to fetch article statuses: %w", statusErr)
+	}
+
+	feedTitles := make(map[int64]string)
+	articles := make([]Article, 0, len(entities))
+	for i, entity := range entities {
+		if articleME && articleErrs[i] != nil {
+			continue // Article was deleted after being tagged
+		}
+		if _, ok := feedTitles[entity.FeedID]; !ok {
+			if feed, err := db.GetFeedByID(int(entity.FeedID)); err == nil && feed != nil {
+				feedTitles[entity.FeedID] = feed.Title
+			}
+		}
+		var status UserArticleEntity
+		if !statusME || statusErrs[i] == nil {
+			status = statuses[i]
+		}
+		articles = append(articles, Article{
+			ID:          int(taggings[i].ArticleID),
+			FeedID:      int(entity.FeedID),
+			FeedTitle:   feedTitles[entity.FeedID],
+			Title:       entity.Title,
+			URL:         entity.URL,
+			Description: entity.Description,
+			Author:      entity.Author,
+			PublishedAt: entity.PublishedAt,
+			CreatedAt:   entity.CreatedAt,
+			IsRead:      status.IsRead,
+			IsStarred:   status.IsStarred,
+
+			ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+			ReadProgress:       int(status.ReadProgress),
+		})
+	}
+
+	return &ArticlePaginationResult{
+		Articles:   articles,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// Undo methods for Datastore
+func (db *DatastoreDB) GetUserArticleStatuses(userID int, articleIDs []int) (map[int]UserArticle, error) {
+	defer logSlowQuery("GetUserArticleStatuses", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	statuses := make(map[int]UserArticle)
+	chunkSize := 1000 // GetMulti accepts at most 1000 keys
+	for i := 0; i < len(articleIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+		chunk := articleIDs[i:end]
+
+		keys := make([]*datastore.Key, len(chunk))
+		for j, id := range chunk {
+			keys[j] = datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, id), nil)
+		}
+		entities := make([]UserArticleEntity, len(keys))
+		err := db.client.GetMulti(ctx, keys, entities)
+		multiErr, isME := err.(datastore.MultiError)
+		if err != nil && !isME {
+			return nil, fmt.Errorf("failed to get user article statuses: %w", err)
+		}
+		for j, entity := range entities {
+			if isME && multiErr[j] != nil {
+				if multiErr[j] != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to get user article status: %w", multiErr[j])
+				}
+				continue
+			}
+			statuses[chunk[j]] = UserArticle{
+				UserID:       userID,
+				ArticleID:    chunk[j],
+				IsRead:       entity.IsRead,
+				IsStarred:    entity.IsStarred,
+				ReadProgress: int(entity.ReadProgress),
+			}
+		}
+	}
+	return statuses, nil
+}
+
+func (db *DatastoreDB) CreateUndoOperation(op *UndoOperation) error {
+	changes, err := encodeStatusChanges(op.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode undo changes: %w", err)
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	entity := &UndoOperationEntity{
+		UserID:    int64(op.UserID),
+		Kind:      op.Kind,
+		Changes:   changes,
+		CreatedAt: op.CreatedAt,
+		ExpiresAt: op.ExpiresAt,
+	}
+	if _, err := db.client.Put(ctx, datastore.NameKey("UndoOperation", op.ID, nil), entity); err != nil {
+		return fmt.Errorf("failed to create undo operation: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) ConsumeUndoOperation(userID int, operationID string) (*UndoOperation, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.NameKey("UndoOperation", operationID, nil)
+	var entity UndoOperationEntity
+	var found bool
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		found = false
+		err := tx.Get(key, &entity)
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entity.UserID != int64(userID) {
+			return nil
+		}
+		found = true
+		return tx.Delete(key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume undo operation: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	changes, err := decodeStatusChanges(entity.Changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode undo changes: %w", err)
+	}
+	return &UndoOperation{
+		ID:        operationID,
+		UserID:    userID,
+		Kind:      entity.Kind,
+		Changes:   changes,
+		CreatedAt: entity.CreatedAt,
+		ExpiresAt: entity.ExpiresAt,
+	}, nil
+}
+
+func (db *DatastoreDB) DeleteExpiredUndoOperations(before time.Time) (int, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("UndoOperation").FilterField("expires_at", "<", before).KeysOnly()
+	keys, err := db.client.GetAll(ctx, q, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query expired undo operations: %w", err)
+	}
+
+	chunkSize := 500
+	for i := 0; i < len(keys); i += chunkSize {
+		end := i + chunkSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return 0, fmt.Errorf("failed to delete expired undo operations: %w", err)
+		}
+	}
+	return len(keys), nil
+}
+
+// GetUserSyncVersion returns the user's current change version, or 0 if
+// nothing has changed yet.
+func (db *DatastoreDB) GetUserSyncVersion(userID int) (int64, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var state UserSyncStateEntity
+	err := db.client.Get(ctx, datastore.NameKey("UserSyncState", strconv.Itoa(userID), nil), &state)
+	if err == datastore.ErrNoSuchEntity {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sync version: %w", err)
+	}
+	return state.Version, nil
+}
+
+// GetUserArticleChanges returns up to limit of the user's article states
+// changed after sinceVersion, oldest change first.
+func (db *DatastoreDB) GetUserArticleChanges(userID int, sinceVersion int64, limit int) ([]UserArticle, error) {
+	defer logSlowQuery("GetUserArticleChanges", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("UserArticle").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("version", ">", sinceVersion).
+		Order("version").
+		Limit(limit)
+	var entities []UserArticleEntity
+	if _, err := db.client.GetAll(ctx, q, &entities); err != nil {
+		return nil, fmt.Errorf("failed to get article changes: %w", err)
+	}
+
+	changes := make([]UserArticle, len(entities))
+	for i, e := range entities {
+		changes[i] = UserArticle{
+			UserID:       int(e.UserID),
+			ArticleID:    int(e.ArticleID),
+			IsRead:       e.IsRead,
+			IsStarred:    e.IsStarred,
+			ReadProgress: int(e.ReadProgress),
+			Version:      e.Version,
+			UpdatedAt:    e.UpdatedAt,
+		}
+	}
+	return changes, nil
+}
+
+// GetUserFeedChanges returns the user's subscription changes after
+// sinceVersion, oldest first. Only the latest change per feed is kept.
+func (db *DatastoreDB) GetUserFeedChanges(userID int, sinceVersion int64) ([]FeedSubscriptionChange, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("UserFeedChange").
+		FilterField("user_id", "=", int64(userID)).
+		FilterField("version", ">", sinceVersion).
+		Order("version")
+	var entities []UserFeedChangeEntity
+	if _, err := db.client.GetAll(ctx, q, &entities); err != nil {
+		return nil, fmt.Errorf("failed to get subscription changes: %w", err)
+	}
+
+	changes := make([]FeedSubscriptionChange, len(entities))
+	for i, e := range entities {
+		changes[i] = FeedSubscriptionChange{FeedID: int(e.FeedID), Subscribed: e.Subscribed, Version: e.Version}
+	}
+	return changes, nil
+}
+
+// GetNewUserArticles returns up to limit articles from the user's feeds created
+// after the given position, in creation order, with the user's flags.
+func (db *DatastoreDB) GetNewUserArticles(userID int, after ArticlePosition, limit int) ([]Article, error) {
+	defer logSlowQuery("GetNewUserArticles", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	feeds, err := db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user feeds: %w", err)
+	}
+
+	// Each feed contributes at most limit articles, so merging the per-feed
+	// results and trimming gives the overall first limit.
+	var articles []Article
+	for _, feed := range feeds {
+		queries := []*datastore.Query{
+			datastore.NewQuery("Article").
+				FilterField("feed_id", "=", int64(feed.ID)).
+				FilterField("created_at", ">", after.CreatedAt).
+				Order("created_at").
+				Limit(limit),
+		}
+		if !after.CreatedAt.IsZero() {
+			// Articles sharing the position's timestamp are ordered by ID
+			queries = append(queries, datastore.NewQuery("Article").
+				FilterField("feed_id", "=", int64(feed.ID)).
+				FilterField("created_at", "=", after.CreatedAt))
+		}
+
+		for _, q := range queries {
+			var entities []ArticleEntity
+			keys, err := db.client.GetAll(ctx, q, &entities)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get new articles for feed %d: %w", feed.ID, err)
+			}
+			for i, e := range entities {
+				id := int(keys[i].ID)
+				if e.CreatedAt.Equal(after.CreatedAt) && id <= after.ID {
+					continue
+				}
+				articles = append(articles, Article{
+					ID:          id,
+					FeedID:      int(e.FeedID),
+					FeedTitle:   feed.Title,
+					Title:       e.Title,
+					URL:         e.URL,
+					Content:     e.Content,
+					Description: e.Description,
+					Author:      e.Author,
+					PublishedAt: e.PublishedAt,
+					CreatedAt:   e.CreatedAt,
+
+					ReadingTimeMinutes: int(e.ReadingTimeMinutes),
+				})
+			}
+		}
+	}
+
+	sort.Slice(articles, func(i, j int) bool {
+		if !articles[i].CreatedAt.Equal(articles[j].CreatedAt) {
+			return articles[i].CreatedAt.Before(articles[j].CreatedAt)
+		}
+		return articles[i].ID < articles[j].ID
+	})
+	if len(articles) > limit {
+		articles = articles[:limit]
+	}
+	if len(articles) == 0 {
+		return []Article{}, nil
+	}
+
+	ids := make([]int, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+	statuses, err := db.GetUserArticleStatuses(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		if st, ok := statuses[articles[i].ID]; ok {
+			articles[i].IsRead = st.IsRead
+			articles[i].IsStarred = st.IsStarred
+			articles[i].ReadProgress = st.ReadProgress
+		}
+	}
+	return articles, nil
+}
+
+// ApplyUserArticleChange applies a client's change unless the stored state was
+// updated more recently, reporting whether it was applied.
+func (db *DatastoreDB) ApplyUserArticleChange(userID int, change UserArticleChange) (bool, error) {
+	if change.IsRead == nil && change.IsStarred == nil && change.ReadProgress == nil {
+		return false, nil
+	}
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.NameKey("UserArticle", fmt.Sprintf("%d_%d", userID, change.ArticleID), nil)
+	var applied bool
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		applied = false
+		var entity UserArticleEntity
+		if err := tx.Get(key, &entity); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		if entity.UpdatedAt.After(change.At) {
+			return nil
+		}
+
+		entity.UserID = int64(userID)
+		entity.ArticleID = int64(change.ArticleID)
+		if change.IsRead != nil {
+			entity.IsRead = *change.IsRead
+		}
+		if change.IsStarred != nil {
+			entity.IsStarred = *change.IsStarred
+		}
+		if change.ReadProgress != nil {
+			entity.ReadProgress = int64(*change.ReadProgress)
+		}
+		if err := stampSyncVersion(tx, userID, &entity); err != nil {
+			return err
+		}
+		entity.UpdatedAt = change.At.UTC()
+		if _, err := tx.Put(key, &entity); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to apply article change: %w", err)
+	}
+	return applied, nil
+}
+
+// Webhook methods for Datastore
+func (db *DatastoreDB) CreateWebhook(webhook *Webhook) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key, err := db.client.Put(ctx, datastore.IncompleteKey("Webhook", nil), webhookToEntity(webhook))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	webhook.ID = int(key.ID)
+	return nil
+}
+
+func (db *DatastoreDB) GetWebhook(userID, webhookID int) (*Webhook, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Webhook", int64(webhookID), nil)
+	var entity WebhookEntity
+	if err := db.client.Get(ctx, key, &entity); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if entity.UserID != int64(userID) {
+		return nil, nil
+	}
+
+	entity.ID = key.ID
+	webhook := webhookFromEntity(&entity)
+	return &webhook, nil
+}
+
+func (db *DatastoreDB) GetUserWebhooks(userID int) ([]Webhook, error) {
+	webhooks, err := db.queryWebhooks(datastore.NewQuery("Webhook").FilterField("user_id", "=", int64(userID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetFeedWebhooks returns the enabled webhooks that cover the feed and belong
+// to one of its subscribers. Webhooks for the feed itself and those covering
+// all feeds are fetched separately, then checked against the owners'
+// UserFeed subscriptions.
+func (db *DatastoreDB) GetFeedWebhooks(feedID int) ([]Webhook, error) {
+	defer logSlowQuery("GetFeedWebhooks", time.Now())
+
+	var candidates []Webhook
+	for _, fid := range []int64{int64(feedID), 0} {
+		found, err := db.queryWebhooks(datastore.NewQuery("Webhook").FilterField("feed_id", "=", fid))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get feed webhooks: %w", err)
+		}
+		for _, w := range found {
+			if w.Enabled {
+				candidates = append(candidates, w)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return []Webhook{}, nil
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	keys := make([]*datastore.Key, len(candidates))
+	for i, w := range candidates {
+		keys[i] = datastore.NameKey("UserFeed", fmt.Sprintf("%d_%d", w.UserID, feedID), nil)
+	}
+	subscribed := make([]bool, len(candidates))
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		entities := make([]UserFeedEntity, end-start)
+		err := db.client.GetMulti(ctx, keys[start:end], entities)
+		if multiErr, ok := err.(datastore.MultiError); ok {
+			for i, e := range multiErr {
+				if e == nil {
+					subscribed[start+i] = true
+				} else if e != datastore.ErrNoSuchEntity {
+					return nil, fmt.Errorf("failed to check webhook subscriptions: %w", e)
+				}
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to check webhook subscriptions: %w", err)
+		} else {
+			for i := start; i < end; i++ {
+				subscribed[i] = true
+			}
+		}
+	}
+
+	webhooks := []Webhook{}
+	for i, w := range candidates {
+		if subscribed[i] {
+			webhooks = append(webhooks, w)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (db *DatastoreDB) queryWebhooks(query *datastore.Query) ([]Webhook, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []WebhookEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]Webhook, len(entities))
+	for i := range entities {
+		entities[i].ID = keys[i].ID
+		webhooks[i] = webhookFromEntity(&entities[i])
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (db *DatastoreDB) UpdateWebhook(webhook *Webhook) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("Webhook", int64(webhook.ID), nil)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity WebhookEntity
+		if err := tx.Get(key, &entity); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if entity.UserID != int64(webhook.UserID) {
+			return nil
+		}
+
+		updated := webhookToEntity(webhook)
+		updated.CreatedAt = entity.CreatedAt
+		_, err := tx.Put(key, updated)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func (db *DatastoreDB) DeleteWebhook(userID, webhookID int) error {
+	webhook, err := db.GetWebhook(userID, webhookID)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("WebhookDelivery").FilterField("webhook_id", "=", int64(webhookID)).KeysOnly()
+	keys, err := db.client.GetAll(ctx, q, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	keys = append(keys, datastore.IDKey("Webhook", int64(webhookID), nil))
+
+	// The webhook key is last, so a failure part-way leaves it in place and
+	// the delete can be retried.
+	for i := 0; i < len(keys); i += 500 {
+		end := i + 500
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+func (db *DatastoreDB) CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	entity := &WebhookDeliveryEntity{
+		WebhookID:  int64(delivery.WebhookID),
+		UserID:     int64(delivery.UserID),
+		EventID:    delivery.EventID,
+		Event:      delivery.Event,
+		ArticleID:  int64(delivery.ArticleID),
+		Attempt:    delivery.Attempt,
+		StatusCode: delivery.StatusCode,
+		Success:    delivery.Success,
+		Error:      delivery.Error,
+		DurationMS: delivery.DurationMS,
+		CreatedAt:  delivery.CreatedAt,
+	}
+	key, err := db.client.Put(ctx, datastore.IncompleteKey("WebhookDelivery", nil), entity)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	delivery.ID = int(key.ID)
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit of the webhook's most recent
+// delivery attempts, newest first.
+func (db *DatastoreDB) GetWebhookDeliveries(userID, webhookID, limit int) ([]WebhookDelivery, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("WebhookDelivery").
+		FilterField("webhook_id", "=", int64(webhookID)).
+		Order("-created_at").
+		Limit(limit)
+	var entities []WebhookDeliveryEntity
+	keys, err := db.client.GetAll(ctx, q, &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+
+	deliveries := []WebhookDelivery{}
+	for i, e := range entities {
+		if e.UserID != int64(userID) {
+			continue
+		}
+		deliveries = append(deliveries, WebhookDelivery{
+			ID:         int(keys[i].ID),
+			WebhookID:  int(e.WebhookID),
+			UserID:     int(e.UserID),
+			EventID:    e.EventID,
+			Event:      e.Event,
+			ArticleID:  int(e.ArticleID),
+			Attempt:    e.Attempt,
+			StatusCode: e.StatusCode,
+			Success:    e.Success,
+			Error:      e.Error,
+			DurationMS: e.DurationMS,
+			CreatedAt:  e.CreatedAt,
+		})
+	}
+	return deliveries, nil
+}
+
+func (db *DatastoreDB) DeleteWebhookDeliveriesBefore(before time.Time) (int, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	q := datastore.NewQuery("WebhookDelivery").FilterField("created_at", "<", before).KeysOnly()
+	keys, err := db.client.GetAll(ctx, q, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query old webhook deliveries: %w", err)
+	}
+
+	for i := 0; i < len(keys); i += 500 {
+		end := i + 500
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := db.client.DeleteMulti(ctx, keys[i:end]); err != nil {
+			return 0, fmt.Errorf("failed to delete old webhook deliveries: %w", err)
+		}
+	}
+	return len(keys), nil
+}
+
+func webhookToEntity(webhook *Webhook) *WebhookEntity {
+	return &WebhookEntity{
+		UserID:    int64(webhook.UserID),
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		FeedID:    int64(webhook.FeedID),
+		Keywords:  webhook.Keywords,
+		Enabled:   webhook.Enabled,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+func webhookFromEntity(entity *WebhookEntity) Webhook {
+	keywords := entity.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+	return Webhook{
+		ID:        int(entity.ID),
+		UserID:    int(entity.UserID),
+		URL:       entity.URL,
+		Secret:    entity.Secret,
+		FeedID:    int(entity.FeedID),
+		Keywords:  keywords,
+		Enabled:   entity.Enabled,
+		CreatedAt: entity.CreatedAt,
+		UpdatedAt: entity.UpdatedAt,
+	}
+}
+
+// Inbound email methods for Datastore
+func (db *DatastoreDB) CreateInboundAddress(address *InboundAddress, feed *Feed) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	// IDs are allocated first so the address can name its feed inside the
+	// transaction
+	keys, err := db.client.AllocateIDs(ctx, []*datastore.Key{
+		datastore.IncompleteKey("Feed", nil),
+		datastore.IncompleteKey("InboundAddress", nil),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to allocate inbound address IDs: %w", err)
+	}
+	feedKey, addressKey := keys[0], keys[1]
+	feedID := int(feedKey.ID)
+
+	feedEntity := &FeedEntity{
+		Title:                 feed.Title,
+		URL:                   feed.URL,
+		Description:           feed.Description,
+		CreatedAt:             feed.CreatedAt,
+		UpdatedAt:             feed.UpdatedAt,
+		LastFetch:             feed.LastFetch,
+		LastChecked:           feed.LastChecked,
+		LastHadNewContent:     feed.LastHadNewContent,
+		AverageUpdateInterval: feed.AverageUpdateInterval,
+		ETag:                  feed.ETag,
+		LastModified:          feed.LastModified,
+	}
+	addressEntity := &InboundAddressEntity{
+		UserID:    int64(address.UserID),
+		FeedID:    feedKey.ID,
+		Token:     address.Token,
+		CreatedAt: address.CreatedAt,
+	}
+	subscriptionKey := datastore.NameKey("UserFeed", fmt.Sprintf("%d_%d", address.UserID, feedID), nil)
+
+	_, err = db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		if _, err := tx.Put(feedKey, feedEntity); err != nil {
+			return err
+		}
+		if _, err := tx.Put(subscriptionKey, &UserFeedEntity{UserID: int64(address.UserID), FeedID: feedKey.ID}); err != nil {
+			return err
+		}
+		if err := recordFeedChange(tx, address.UserID, feedID, true); err != nil {
+			return err
+		}
+		_, err := tx.Put(addressKey, addressEntity)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create inbound address: %w", err)
+	}
+
+	feed.ID = feedID
+	address.FeedID = feedID
+	address.ID = int(addressKey.ID)
+	return nil
+}
+
+func (db *DatastoreDB) GetInboundAddressByToken(token string) (*InboundAddress, error) {
+	addresses, err := db.queryInboundAddresses(datastore.NewQuery("InboundAddress").FilterField("token", "=", token).Limit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inbound address: %w", err)
+	}
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+	return &addresses[0], nil
+}
+
+func (db *DatastoreDB) GetUserInboundAddresses(userID int) ([]InboundAddress, error) {
+	addresses, err := db.queryInboundAddresses(datastore.NewQuery("InboundAddress").FilterField("user_id", "=", int64(userID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user inbound addresses: %w", err)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].ID < addresses[j].ID })
+	return addresses, nil
+}
+
+func (db *DatastoreDB) queryInboundAddresses(query *datastore.Query) ([]InboundAddress, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []InboundAddressEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]InboundAddress, len(entities))
+	for i, e := range entities {
+		addresses[i] = InboundAddress{
+			ID:        int(keys[i].ID),
+			UserID:    int(e.UserID),
+			FeedID:    int(e.FeedID),
+			Token:     e.Token,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return addresses, nil
+}
+
+func (db *DatastoreDB) DeleteInboundAddress(userID, addressID int) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("InboundAddress", int64(addressID), nil)
+	var entity InboundAddressEntity
+	if err := db.client.Get(ctx, key, &entity); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		return fmt.Errorf("failed to get inbound address: %w", err)
+	}
+	if entity.UserID != int64(userID) {
+		return nil
+	}
+
+	if err := db.client.Delete(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete inbound address: %w", err)
+	}
+	return nil
+}
+
+// Directory methods for Datastore
+func (db *DatastoreDB) GetAllFeedSubscriptions() ([]FeedSubscription, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []UserFeedEntity
+	if _, err := db.client.GetAll(ctx, datastore.NewQuery("UserFeed"), &entities); err != nil {
+		return nil, fmt.Errorf("failed to query user feeds: %w", err)
+	}
+
+	subscriptions := make([]FeedSubscription, len(entities))
+	for i, e := range entities {
+		subscriptions[i] = FeedSubscription{UserID: int(e.UserID), FeedID: int(e.FeedID)}
+	}
+	return subscriptions, nil
+}
+
+func (db *DatastoreDB) GetFeedCategories() (map[int]string, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []FeedCategoryEntity
+	keys, err := db.client.GetAll(ctx, datastore.NewQuery("FeedCategory"), &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed categories: %w", err)
+	}
+
+	categories := make(map[int]string, len(entities))
+	for i, e := range entities {
+		categories[int(keys[i].ID)] = e.Category
+	}
+	return categories, nil
+}
+
+func (db *DatastoreDB) SetFeedCategory(feedID int, category string) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	key := datastore.IDKey("FeedCategory", int64(feedID), nil)
+	if category == "" {
+		if err := db.client.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to clear feed category: %w", err)
+		}
+	} else if _, err := db.client.Put(ctx, key, &FeedCategoryEntity{Category: category}); err != nil {
+		return fmt.Errorf("failed to set feed category: %w", err)
+	}
+
+	// Update the listing too, if the feed is in the directory
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		listingKey := datastore.IDKey("DirectoryFeed", int64(feedID), nil)
+		var listing DirectoryFeedEntity
+		if err := tx.Get(listingKey, &listing); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		listing.Category = category
+		_, err := tx.Put(listingKey, &listing)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update directory listing: %w", err)
+	}
+	return nil
+}
+
+// ReplaceFeedDirectory writes the new directory and recommendations, then
+// deletes entries for feeds that dropped out. Datastore can't do this in one
+// transaction, so readers may briefly see a mix of the old and new job.
+func (db *DatastoreDB) ReplaceFeedDirectory(feeds []DirectoryFeed, recommendations []FeedRecommendation) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	oldListings, err := db.client.GetAll(ctx, datastore.NewQuery("DirectoryFeed").KeysOnly(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get directory listings: %w", err)
+	}
+	oldRecommendations, err := db.client.GetAll(ctx, datastore.NewQuery("FeedRecommendations").KeysOnly(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to get feed recommendations: %w", err)
+	}
+
+	keep := make(map[string]bool)
+	listingKeys := make([]*datastore.Key, len(feeds))
+	listings := make([]*DirectoryFeedEntity, len(feeds))
+	for i, f := range feeds {
+		listingKeys[i] = datastore.IDKey("DirectoryFeed", int64(f.FeedID), nil)
+		listings[i] = &DirectoryFeedEntity{
+			Title:       f.Title,
+			URL:         f.URL,
+			Description: f.Description,
+			Category:    f.Category,
+			Subscribers: int64(f.Subscribers),
+			UpdatedAt:   f.UpdatedAt,
+		}
+		keep[listingKeys[i].String()] = true
+	}
+
+	byFeed := make(map[int]*FeedRecommendationsEntity)
+	var recKeys []*datastore.Key
+	var recEntities []*FeedRecommendationsEntity
+	for _, r := range recommendations {
+		entity, ok := byFeed[r.FeedID]
+		if !ok {
+			entity = &FeedRecommendationsEntity{}
+			byFeed[r.FeedID] = entity
+			key := datastore.IDKey("FeedRecommendations", int64(r.FeedID), nil)
+			recKeys = append(recKeys, key)
+			recEntities = append(recEntities, entity)
+			keep[key.String()] = true
+		}
+		entity.RecommendedFeedIDs = append(entity.RecommendedFeedIDs, int64(r.RecommendedFeedID))
+		entity.SharedSubscribers = append(entity.SharedSubscribers, int64(r.SharedSubscribers))
+		entity.Scores = append(entity.Scores, r.Score)
+	}
+
+	const chunkSize = 500
+	for i := 0; i < len(listingKeys); i += chunkSize {
+		end := min(i+chunkSize, len(listingKeys))
+		if _, err := db.client.PutMulti(ctx, listingKeys[i:end], listings[i:end]); err != nil {
+			return fmt.Errorf("failed to save directory listings: %w", err)
+		}
+	}
+	for i := 0; i < len(recKeys); i += chunkSize {
+		end := min(i+chunkSize, len(recKeys))
+		if _, err := db.client.PutMulti(ctx, recKeys[i:end], recEntities[i:end]); err != nil {
+			return fmt.Errorf("failed to save feed recommendations: %w", err)
+		}
+	}
+
+	var stale []*datastore.Key
+	for _, key := range append(oldListings, oldRecommendations...) {
+		if !keep[key.String()] {
+			stale = append(stale, key)
+		}
+	}
+	for i := 0; i < len(stale); i += chunkSize {
+		end := min(i+chunkSize, len(stale))
+		if err := db.client.DeleteMulti(ctx, stale[i:end]); err != nil {
+			return fmt.Errorf("failed to delete stale directory entries: %w", err)
+		}
+	}
+	return nil
+}
+
+func (db *DatastoreDB) GetDirectoryFeeds() ([]DirectoryFeed, error) {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	var entities []DirectoryFeedEntity
+	keys, err := db.client.GetAll(ctx, datastore.NewQuery("DirectoryFeed"), &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get directory listings: %w", err)
+	}
+
+	feeds := make([]DirectoryFeed, len(entities))
+	for i, e := range entities {
+		feeds[i] = DirectoryFeed{
+			FeedID:      int(keys[i].ID),
+			Title:       e.Title,
+			URL:         e.URL,
+			Description: e.Description,
+			Category:    e.Category,
+			Subscribers: int(e.Subscribers),
+			UpdatedAt:   e.UpdatedAt,
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		if feeds[i].Subscribers != feeds[j].Subscribers {
+			return feeds[i].Subscribers > feeds[j].Subscribers
+		}
+		if feeds[i].Title != feeds[j].Title {
+			return feeds[i].Title < feeds[j].Title
+		}
+		return feeds[i].FeedID < feeds[j].FeedID
+	})
+	return feeds, nil
+}
+
+func (db *DatastoreDB) GetFeedRecommendations(feedIDs []int) ([]FeedRecommendation, error) {
+	recommendations := []FeedRecommendation{}
+	if len(feedIDs) == 0 {
+		return recommendations, nil
+	}
+
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	keys := make([]*datastore.Key, len(feedIDs))
+	for i, id := range feedIDs {
+		keys[i] = datastore.IDKey("FeedRecommendations", int64(id), nil)
+	}
+	entities := make([]FeedRecommendationsEntity, len(keys))
+
+	const chunkSize = 1000
+	for start := 0; start < len(keys); start += chunkSize {
+		end := min(start+chunkSize, len(keys))
+		err := db.client.GetMulti(ctx, keys[start:end], entities[start:end])
+		var multiErr datastore.MultiError
+		if err != nil && !errors.As(err, &multiErr) {
+			return nil, fmt.Errorf("failed to get feed recommendations: %w", err)
+		}
+		for i, e := range multiErr {
+			if e != nil && e != datastore.ErrNoSuchEntity {
+				return nil, fmt.Errorf("failed to get feed recommendations for feed %d: %w", feedIDs[start+i], e)
+			}
+		}
+	}
+
+	for i, e := range entities {
+		for j, recommended := range e.RecommendedFeedIDs {
+			if j >= len(e.SharedSubscribers) || j >= len(e.Scores) {
+				break
+			}
+			recommendations = append(recommendations, FeedRecommendation{
+				FeedID:            feedIDs[i],
+				RecommendedFeedID: int(recommended),
+				SharedSubscribers: int(e.SharedSubscribers[j]),
+				Score:             e.Scores[j],
+			})
+		}
+	}
+	return recommendations, nil
+}
+
+// forEachEntity streams every entity of kind to fn a page at a time. Each
+// page is read under its own datastoreTimeout, and fn runs after the page's
+// query has finished, so slow callers don't eat into the read deadline.
+func forEachEntity[E any](db *DatastoreDB, kind string, fn func(*datastore.Key, *E) error) error {
+	const pageSize = 500
+	var cursor *datastore.Cursor
+
+	for {
+		ctx, cancel := newDatastoreContext()
+		query := datastore.NewQuery(kind).Limit(pageSize)
+		if cursor != nil {
+			query = query.Start(*cursor)
+		}
+
+		var keys []*datastore.Key
+		var entities []*E
+		it := db.client.Run(ctx, query)
+		for {
+			entity := new(E)
+			key, err := it.Next(entity)
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				cancel()
+				return fmt.Errorf("failed to query %s: %w", kind, err)
+			}
+			keys = append(keys, key)
+			entities = append(entities, entity)
+		}
+
+		var next datastore.Cursor
+		var err error
+		if len(keys) == pageSize {
+			next, err = it.Cursor()
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to page %s: %w", kind, err)
+		}
+
+		for i, key := range keys {
+			if err := fn(key, entities[i]); err != nil {
+				return err
+			}
+		}
+		if len(keys) < pageSize {
+			return nil
+		}
+		cursor = &next
+	}
+}
+
+func (db *DatastoreDB) ForEachUser(fn func(User) error) error {
+	return forEachEntity(db, "User", func(key *datastore.Key, entity *UserEntity) error {
+		entity.ID = key.ID
+		return fn(*userFromEntity(entity))
+	})
+}
+
+func (db *DatastoreDB) ForEachArticle(fn func(Article) error) error {
+	return forEachEntity(db, "Article", func(key *datastore.Key, entity *ArticleEntity) error {
+		return fn(Article{
+			ID:                 int(key.ID),
+			FeedID:             int(entity.FeedID),
+			Title:              entity.Title,
+			URL:                entity.URL,
+			Content:            entity.Content,
+			Description:        entity.Description,
+			Author:             entity.Author,
+			PublishedAt:        entity.PublishedAt,
+			CreatedAt:          entity.CreatedAt,
+			ReadingTimeMinutes: int(entity.ReadingTimeMinutes),
+		})
+	})
+}
+
+func (db *DatastoreDB) ForEachUserArticle(fn func(UserArticle) error) error {
+	return forEachEntity(db, "UserArticle", func(_ *datastore.Key, entity *UserArticleEntity) error {
+		return fn(UserArticle{
+			UserID:       int(entity.UserID),
+			ArticleID:    int(entity.ArticleID),
+			IsRead:       entity.IsRead,
+			IsStarred:    entity.IsStarred,
+			ReadProgress: int(entity.ReadProgress),
+		})
+	})
+}
+
+func (db *DatastoreDB) ForEachSession(fn func(Session) error) error {
+	return forEachEntity(db, "Session", func(key *datastore.Key, entity *SessionEntity) error {
+		return fn(sessionFromEntity(key.Name, entity))
+	})
+}
+
+func (db *DatastoreDB) ForEachUserIdentity(fn func(UserIdentity) error) error {
+	return forEachEntity(db, "UserIdentity", func(_ *datastore.Key, entity *UserIdentityEntity) error {
+		return fn(userIdentityFromEntity(entity))
+	})
+}
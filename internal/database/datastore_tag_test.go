@@ -0,0 +1,106 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tag tests
+
+func TestDatastoreCreateTagRejectsDuplicateName(t *testing.T) {
+	db := setupTestDatastoreDB(t)
+
+	user := createDatastoreTestUser(t, db)
+	tag := &Tag{UserID: user.ID, Name: "to-read", CreatedAt: time.Now()}
+	if err := db.CreateTag(tag); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	err := db.CreateTag(&Tag{UserID: user.ID, Name: "to-read", CreatedAt: time.Now()})
+	if !errors.Is(err, ErrTagExists) {
+		t.Errorf("Expected ErrTagExists, got %v", err)
+	}
+
+	other := createDatastoreTestUser(t, db)
+	if err := db.CreateTag(&Tag{UserID: other.ID, Name: "to-read", CreatedAt: time.Now()}); err != nil {
+		t.Errorf("Another user's tag with the same name should be allowed: %v", err)
+	}
+}
+
+func TestDatastoreCreateTagConcurrentlyCreatesOne(t *testing.T) {
+	db := setupTestDatastoreDB(t)
+
+	user := createDatastoreTestUser(t, db)
+
+	const attempts = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- db.CreateTag(&Tag{UserID: user.ID, Name: "later", CreatedAt: time.Now()})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected exactly one tag to be created, got %d", created)
+	}
+
+	tags, err := db.GetUserTags(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserTags failed: %v", err)
+	}
+	if len(tags) != 1 {
+		t.Errorf("Expected 1 stored tag, got %d", len(tags))
+	}
+}
+
+func TestDatastoreRenameTag(t *testing.T) {
+	db := setupTestDatastoreDB(t)
+
+	user := createDatastoreTestUser(t, db)
+	postmortem := &Tag{UserID: user.ID, Name: "postmortem", CreatedAt: time.Now()}
+	share := &Tag{UserID: user.ID, Name: "share", CreatedAt: time.Now()}
+	for _, tag := range []*Tag{postmortem, share} {
+		if err := db.CreateTag(tag); err != nil {
+			t.Fatalf("CreateTag failed: %v", err)
+		}
+	}
+
+	if err := db.RenameTag(user.ID, postmortem.ID, "share"); !errors.Is(err, ErrTagExists) {
+		t.Errorf("Expected ErrTagExists, got %v", err)
+	}
+
+	if err := db.RenameTag(user.ID, postmortem.ID, "incidents"); err != nil {
+		t.Fatalf("RenameTag failed: %v", err)
+	}
+	got, err := db.GetTag(user.ID, postmortem.ID)
+	if err != nil {
+		t.Fatalf("GetTag failed: %v", err)
+	}
+	if got == nil || got.Name != "incidents" {
+		t.Errorf("Expected tag to be renamed to incidents, got %+v", got)
+	}
+
+	// The old name is free again once renamed, and after a delete
+	if err := db.CreateTag(&Tag{UserID: user.ID, Name: "postmortem", CreatedAt: time.Now()}); err != nil {
+		t.Errorf("Expected the old name to be reusable: %v", err)
+	}
+	if err := db.DeleteTag(user.ID, share.ID); err != nil {
+		t.Fatalf("DeleteTag failed: %v", err)
+	}
+	if err := db.CreateTag(&Tag{UserID: user.ID, Name: "share", CreatedAt: time.Now()}); err != nil {
+		t.Errorf("Expected a deleted tag's name to be reusable: %v", err)
+	}
+}
@@ -47,7 +47,7 @@ func cleanupDatastoreEntities(t *testing.T, db *DatastoreDB) {
 	ctx := context.Background()
 	client := db.GetClient()
 
-	kinds := []string{"User", "Feed", "Article", "UserFeed", "UserArticle", "Session", "AuditLog", "AdminToken"}
+	kinds := []string{"User", "Feed", "Article", "UserFeed", "UserArticle", "Session", "AuditLog", "AdminToken", "Tag", "TagName", "Annotation"}
 	for _, kind := range kinds {
 		query := datastore.NewQuery(kind).KeysOnly()
 		keys, err := client.GetAll(ctx, query, nil)
@@ -857,11 +857,11 @@ func TestDatastoreGetUserArticlesPaginated(t *testing.T) {
 		t.Fatalf("SubscribeUserToFeed failed: %v", err)
 	}
 
-	// GetUserArticlesPaginated re-runs a per-feed projection query capped at limit*2 articles
-	// on every call (see articlesPerFeed in datastore.go), regardless of cursor. Keep total
-	// within that window (limit*2) so every article is visible to the projection on each page.
+	// Each page projects at most limit*2 articles per feed (see articlesPerFeed in
+	// datastore.go), starting from the cursor. Use more than that so later pages
+	// depend on the cursor reaching the query.
 	const limit = 2
-	const total = limit * 2
+	const total = limit*3 + 1
 	for i := 0; i < total; i++ {
 		a := &Article{
 			FeedID:      feed.ID,
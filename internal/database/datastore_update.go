@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"cloud.google.com/go/datastore"
 )
@@ -34,3 +35,61 @@ func (db *DatastoreDB) UpdateUserMaxArticlesOnFeedAdd(userID int, maxArticles in
 
 	return nil
 }
+
+func (db *DatastoreDB) UpdateUserDigestPreferences(userID int, prefs DigestPreferences) error {
+	return db.updateUser(userID, func(user *UserEntity) {
+		user.DigestFrequency = prefs.Frequency
+		user.DigestTimezone = prefs.Timezone
+		user.DigestHour = prefs.Hour
+		user.DigestWeekday = prefs.Weekday
+	})
+}
+
+func (db *DatastoreDB) SetUserDigestSentAt(userID int, sentAt time.Time) error {
+	return db.updateUser(userID, func(user *UserEntity) {
+		user.DigestLastSentAt = sentAt
+	})
+}
+
+// updateUser applies update to the stored user in a transaction, so settings
+// saved concurrently by other requests aren't overwritten.
+func (db *DatastoreDB) updateUser(userID int, update func(*UserEntity)) error {
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	userKey := datastore.IDKey("User", int64(userID), nil)
+	_, err := db.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var user UserEntity
+		if err := tx.Get(userKey, &user); err != nil {
+			return fmt.Errorf("failed to get user for update: %w", err)
+		}
+		update(&user)
+		_, err := tx.Put(userKey, &user)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// GetDigestUsers returns the users who have opted in to email digests.
+func (db *DatastoreDB) GetDigestUsers() ([]User, error) {
+	defer logSlowQuery("GetDigestUsers", time.Now())
+	ctx, cancel := newDatastoreContext()
+	defer cancel()
+
+	query := datastore.NewQuery("User").FilterField("digest_frequency", ">", "")
+	var entities []UserEntity
+	keys, err := db.client.GetAll(ctx, query, &entities)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest users: %w", err)
+	}
+
+	users := make([]User, len(entities))
+	for i := range entities {
+		entities[i].ID = keys[i].ID
+		users[i] = *userFromEntity(&entities[i])
+	}
+	return users, nil
+}
@@ -136,6 +136,38 @@ func TestDatastoreBatchSetUserArticleStatus(t *testing.T) {
 	}
 }
 
+func TestDatastoreUpdateUserArticleStatuses(t *testing.T) {
+	db := setupTestDatastoreDB(t)
+
+	user := createDatastoreTestUser(t, db)
+	feed := createDatastoreTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+	article := createDatastoreTestArticle(t, db, feed.ID)
+
+	yes := true
+	result, err := db.UpdateUserArticleStatuses(user.ID, []ArticleStatusUpdate{
+		{ArticleID: article.ID, IsRead: &yes},
+		{ArticleID: article.ID, IsStarred: &yes},
+		{ArticleID: 987654321, IsRead: &yes},
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserArticleStatuses failed: %v", err)
+	}
+	if len(result.Found) != 1 || len(result.Changes) != 1 || result.UnreadDelta[feed.ID] != -1 {
+		t.Errorf("expected one found and changed article, got %+v", result)
+	}
+
+	status, err := db.GetUserArticleStatus(user.ID, article.ID)
+	if err != nil {
+		t.Fatalf("GetUserArticleStatus failed: %v", err)
+	}
+	if !status.IsRead || !status.IsStarred {
+		t.Errorf("Expected read+starred status, got %+v", status)
+	}
+}
+
 func TestDatastoreMarkAllUserArticlesRead(t *testing.T) {
 	db := setupTestDatastoreDB(t)
 
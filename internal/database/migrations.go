@@ -0,0 +1,399 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// ErrIrreversibleMigration is returned when rolling back a migration that has no Down step.
+var ErrIrreversibleMigration = errors.New("migration cannot be rolled back")
+
+// Migration is one step in the SQLite schema's history. Up and Down run in
+// the same transaction that records or removes the version in
+// schema_migrations, so a step either applies completely or not at all.
+// A nil Down marks a step that can't be undone.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+	Down        func(tx *sql.Tx) error
+}
+
+// MigrationStatus describes a migration known to this build and whether the
+// database has it. AppliedAt is nil for pending migrations.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+// sqliteMigrations lists every schema change made after a table was first
+// created, in the order they were made. Append new steps with the next
+// version; never edit or renumber a step once it has shipped.
+//
+// CreateTables builds the latest schema for new databases, so column
+// changes belong in both places. addColumn skips columns that already exist,
+// which makes the early steps no-ops on a database created by CreateTables.
+var sqliteMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "add Google account columns to users",
+		Up: func(tx *sql.Tx) error {
+			// Not reversible: google_id and email are UNIQUE on new databases,
+			// and SQLite can't drop a column with a constraint
+			return addColumns(tx, "users",
+				"google_id TEXT",
+				"email TEXT",
+				"name TEXT",
+				"avatar TEXT",
+			)
+		},
+	},
+	{
+		Version:     2,
+		Description: "add subscription columns to users",
+		Up: func(tx *sql.Tx) error {
+			if err := addColumns(tx, "users",
+				"subscription_status TEXT DEFAULT 'trial'",
+				"subscription_id TEXT",
+				"trial_ends_at DATETIME",
+				"last_payment_date DATETIME",
+				"next_billing_date DATETIME",
+			); err != nil {
+				return err
+			}
+			// Users from before trials existed get the standard 30 days from sign-up
+			_, err := tx.Exec(`
+				UPDATE users
+				SET trial_ends_at = datetime(created_at, '+30 days')
+				WHERE trial_ends_at IS NULL AND subscription_status = 'trial'
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "users", "subscription_status", "subscription_id", "trial_ends_at", "last_payment_date", "next_billing_date")
+		},
+	},
+	{
+		Version:     3,
+		Description: "add admin flag and free months to users",
+		Up: func(tx *sql.Tx) error {
+			return addColumns(tx, "users",
+				"is_admin BOOLEAN DEFAULT 0",
+				"free_months_remaining INTEGER DEFAULT 0",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "users", "is_admin", "free_months_remaining")
+		},
+	},
+	{
+		Version:     4,
+		Description: "add max articles on feed add preference to users",
+		Up: func(tx *sql.Tx) error {
+			return addColumns(tx, "users", "max_articles_on_feed_add INTEGER DEFAULT 100")
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "users", "max_articles_on_feed_add")
+		},
+	},
+	{
+		Version:     5,
+		Description: "add digest preferences to users",
+		Up: func(tx *sql.Tx) error {
+			return addColumns(tx, "users",
+				"digest_frequency TEXT DEFAULT ''",
+				"digest_timezone TEXT DEFAULT ''",
+				"digest_hour INTEGER DEFAULT 0",
+				"digest_weekday INTEGER DEFAULT 0",
+				"digest_last_sent_at DATETIME",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "users", "digest_frequency", "digest_timezone", "digest_hour", "digest_weekday", "digest_last_sent_at")
+		},
+	},
+	{
+		Version:     6,
+		Description: "add update tracking columns to feeds",
+		Up: func(tx *sql.Tx) error {
+			// SQLite doesn't allow CURRENT_TIMESTAMP as the default in ALTER
+			// TABLE, so the columns start NULL and existing feeds are seeded
+			// from their last fetch
+			if err := addColumns(tx, "feeds",
+				"last_checked DATETIME",
+				"last_had_new_content DATETIME",
+				"average_update_interval INTEGER DEFAULT 0",
+			); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`
+				UPDATE feeds
+				SET last_checked = COALESCE(last_checked, last_fetch),
+				    last_had_new_content = COALESCE(last_had_new_content, last_fetch)
+				WHERE last_checked IS NULL OR last_had_new_content IS NULL
+			`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "feeds", "last_checked", "last_had_new_content", "average_update_interval")
+		},
+	},
+	{
+		Version:     7,
+		Description: "add HTTP cache headers to feeds",
+		Up: func(tx *sql.Tx) error {
+			return addColumns(tx, "feeds",
+				"etag TEXT DEFAULT ''",
+				"last_modified TEXT DEFAULT ''",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "feeds", "etag", "last_modified")
+		},
+	},
+	{
+		Version:     8,
+		Description: "add reading time to articles and read progress to user articles",
+		Up: func(tx *sql.Tx) error {
+			// Not reversible: the sync triggers watch read_progress, and
+			// SQLite won't drop a column a trigger refers to
+			if err := addColumns(tx, "articles", "reading_time_minutes INTEGER DEFAULT 0"); err != nil {
+				return err
+			}
+			return addColumns(tx, "user_articles", "read_progress INTEGER DEFAULT 0")
+		},
+	},
+	{
+		Version:     9,
+		Description: "add sync versions to user articles",
+		Up: func(tx *sql.Tx) error {
+			// Not reversible: the sync triggers write both columns
+			if err := addColumns(tx, "user_articles",
+				"version INTEGER NOT NULL DEFAULT 0",
+				"updated_at DATETIME",
+			); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_user_articles_user_version ON user_articles (user_id, version)`)
+			return err
+		},
+	},
+	{
+		Version:     10,
+		Description: "create sessions table",
+		Up: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`
+				CREATE TABLE IF NOT EXISTS sessions (
+					id TEXT PRIMARY KEY,
+					user_id INTEGER NOT NULL,
+					created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+					expires_at DATETIME NOT NULL,
+					FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+				)`)
+			return err
+		},
+		Down: func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DROP TABLE IF EXISTS sessions`)
+			return err
+		},
+	},
+	{
+		Version:     11,
+		Description: "add device details and last activity to sessions",
+		Up: func(tx *sql.Tx) error {
+			return addColumns(tx, "sessions",
+				"user_agent TEXT DEFAULT ''",
+				"client_type TEXT DEFAULT ''",
+				"ip_address TEXT DEFAULT ''",
+				"last_seen_at DATETIME",
+			)
+		},
+		Down: func(tx *sql.Tx) error {
+			return dropColumns(tx, "sessions", "user_agent", "client_type", "ip_address", "last_seen_at")
+		},
+	},
+}
+
+// addColumns adds each column definition to table unless a column with that
+// name is already there.
+func addColumns(tx *sql.Tx, table string, definitions ...string) error {
+	for _, definition := range definitions {
+		var name string
+		if _, err := fmt.Sscan(definition, &name); err != nil {
+			return fmt.Errorf("invalid column definition %q: %w", definition, err)
+		}
+		var exists bool
+		err := tx.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropColumns(tx *sql.Tx, table string, columns ...string) error {
+	for _, column := range columns {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DB) createMigrationsTable() error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at DATETIME NOT NULL
+		)`)
+	return err
+}
+
+// appliedMigrations returns when each recorded version was applied. A
+// database that predates schema_migrations has none.
+func (db *DB) appliedMigrations() (map[int]time.Time, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&exists)
+	if err != nil || !exists {
+		return map[int]time.Time{}, err
+	}
+
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs one direction of m and records the result in a single transaction.
+func (db *DB) applyMigration(m Migration, up bool) error {
+	step := m.Up
+	if !up {
+		step = m.Down
+	}
+	if step == nil {
+		return fmt.Errorf("%w: %d (%s)", ErrIrreversibleMigration, m.Version, m.Description)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := step(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+	}
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+			m.Version, m.Description, time.Now().UTC())
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Migrate applies every pending migration in version order and returns how
+// many it applied. It stops at the first failure; migrations applied before
+// it stay applied.
+func (db *DB) Migrate() (int, error) {
+	if err := db.createMigrationsTable(); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range sqliteMigrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := db.applyMigration(m, true); err != nil {
+			return count, err
+		}
+		log.Printf("Applied migration %d: %s", m.Version, m.Description)
+		count++
+	}
+	return count, nil
+}
+
+// Rollback undoes the most recently applied migrations, newest first, up to
+// steps of them, and returns how many it undid. It refuses to go past a
+// migration without a Down step or a version this build doesn't know.
+func (db *DB) Rollback(steps int) (int, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[int]Migration, len(sqliteMigrations))
+	for _, m := range sqliteMigrations {
+		known[m.Version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	count := 0
+	for _, version := range versions {
+		if count == steps {
+			break
+		}
+		m, ok := known[version]
+		if !ok {
+			return count, fmt.Errorf("database has migration %d, which this build doesn't know about", version)
+		}
+		if err := db.applyMigration(m, false); err != nil {
+			return count, err
+		}
+		log.Printf("Rolled back migration %d: %s", m.Version, m.Description)
+		count++
+	}
+	return count, nil
+}
+
+// MigrationStatus lists every migration known to this build in version order.
+func (db *DB) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(sqliteMigrations))
+	for i, m := range sqliteMigrations {
+		statuses[i] = MigrationStatus{Version: m.Version, Description: m.Description}
+		if appliedAt, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func setupMigrationTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func hasColumn(t *testing.T, db *DB, table, column string) bool {
+	t.Helper()
+
+	var exists bool
+	err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil {
+		t.Fatalf("Failed to inspect %s: %v", table, err)
+	}
+	return exists
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	for i, m := range sqliteMigrations {
+		if m.Version != i+1 {
+			t.Errorf("Migration at index %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Description == "" || m.Up == nil {
+			t.Errorf("Migration %d needs a description and an Up step", m.Version)
+		}
+	}
+}
+
+func TestMigrateNewDatabase(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("CreateTables failed: %v", err)
+	}
+
+	count, err := db.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if count != len(sqliteMigrations) {
+		t.Errorf("Expected %d migrations applied, got %d", len(sqliteMigrations), count)
+	}
+
+	count, err = db.Migrate()
+	if err != nil || count != 0 {
+		t.Errorf("Expected nothing left to apply, got %d, %v", count, err)
+	}
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus failed: %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	db := setupMigrationTestDB(t)
+
+	// The shape of a database from before any of the migrations
+	legacy := []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE feeds (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, url TEXT UNIQUE NOT NULL, last_fetch DATETIME)`,
+		`CREATE TABLE articles (id INTEGER PRIMARY KEY AUTOINCREMENT, feed_id INTEGER NOT NULL)`,
+		`CREATE TABLE user_articles (user_id INTEGER NOT NULL, article_id INTEGER NOT NULL, is_read BOOLEAN DEFAULT 0)`,
+		`INSERT INTO users (created_at) VALUES ('2024-01-01 00:00:00')`,
+		`INSERT INTO feeds (title, url, last_fetch) VALUES ('Old', 'https://example.com/old.xml', '2024-02-01 00:00:00')`,
+	}
+	for _, stmt := range legacy {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to build legacy schema: %v", err)
+		}
+	}
+
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	for table, columns := range map[string][]string{
+		"users":         {"google_id", "subscription_status", "is_admin", "max_articles_on_feed_add", "digest_last_sent_at"},
+		"feeds":         {"last_checked", "average_update_interval", "etag"},
+		"articles":      {"reading_time_minutes"},
+		"user_articles": {"read_progress", "version", "updated_at"},
+	} {
+		for _, column := range columns {
+			if !hasColumn(t, db, table, column) {
+				t.Errorf("Expected %s.%s to be added", table, column)
+			}
+		}
+	}
+
+	// Data steps ran alongside the schema changes
+	var trialEndsAt, lastChecked string
+	if err := db.QueryRow(`SELECT CAST(trial_ends_at AS TEXT) FROM users`).Scan(&trialEndsAt); err != nil {
+		t.Fatalf("Failed to read trial end: %v", err)
+	}
+	if trialEndsAt != "2024-01-31 00:00:00" {
+		t.Errorf("Expected a 30 day trial from sign-up, got %s", trialEndsAt)
+	}
+	if err := db.QueryRow(`SELECT CAST(last_checked AS TEXT) FROM feeds`).Scan(&lastChecked); err != nil {
+		t.Fatalf("Failed to read last checked: %v", err)
+	}
+	if lastChecked != "2024-02-01 00:00:00" {
+		t.Errorf("Expected last_checked seeded from last_fetch, got %s", lastChecked)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("CreateTables failed: %v", err)
+	}
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Session device details and then sessions come off, then the sync
+	// versions step can't be undone
+	count, err := db.Rollback(3)
+	if count != 2 || !errors.Is(err, ErrIrreversibleMigration) {
+		t.Fatalf("Expected two rollbacks then an irreversible migration, got %d, %v", count, err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'sessions'`).Scan(&tables); err != nil {
+		t.Fatalf("Failed to look up sessions: %v", err)
+	}
+	if tables != 0 {
+		t.Error("Expected the sessions table to be dropped")
+	}
+
+	statuses, _ := db.MigrationStatus()
+	last := statuses[len(statuses)-1]
+	if last.AppliedAt != nil {
+		t.Errorf("Expected migration %d to be pending again", last.Version)
+	}
+
+	count, err = db.Migrate()
+	if err != nil || count != 2 {
+		t.Fatalf("Expected the rolled back migrations to reapply, got %d, %v", count, err)
+	}
+}
+
+func TestRollbackDropsColumns(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("CreateTables failed: %v", err)
+	}
+	if err := db.createMigrationsTable(); err != nil {
+		t.Fatalf("createMigrationsTable failed: %v", err)
+	}
+
+	cacheHeaders := sqliteMigrations[6]
+	if err := db.applyMigration(cacheHeaders, true); err != nil {
+		t.Fatalf("applyMigration up failed: %v", err)
+	}
+	if err := db.applyMigration(cacheHeaders, false); err != nil {
+		t.Fatalf("applyMigration down failed: %v", err)
+	}
+	if hasColumn(t, db, "feeds", "etag") || hasColumn(t, db, "feeds", "last_modified") {
+		t.Error("Expected the cache header columns to be dropped")
+	}
+}
+
+func TestFailedMigrationLeavesNoTrace(t *testing.T) {
+	db := setupMigrationTestDB(t)
+	if err := db.createMigrationsTable(); err != nil {
+		t.Fatalf("createMigrationsTable failed: %v", err)
+	}
+
+	broken := Migration{
+		Version:     100,
+		Description: "half finished",
+		Up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec(`CREATE TABLE half_finished (id INTEGER)`); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`ALTER TABLE missing ADD COLUMN nope TEXT`)
+			return err
+		},
+	}
+	if err := db.applyMigration(broken, true); err == nil {
+		t.Fatal("Expected the migration to fail")
+	}
+
+	var tables, versions int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_finished'`).Scan(&tables)
+	_ = db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions)
+	if tables != 0 || versions != 0 {
+		t.Errorf("Expected the failed migration to roll back, found %d tables and %d versions", tables, versions)
+	}
+}
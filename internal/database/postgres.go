@@ -0,0 +1,2328 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostgresDB implements Database on PostgreSQL, for self-hosted deployments
+// that share one relational database between several app instances. It is
+// selected by setting DATABASE_URL.
+type PostgresDB struct {
+	*sql.DB
+}
+
+// pgSchemaLockID is the advisory lock held while creating the schema, so
+// instances starting together don't race each other's DDL.
+const pgSchemaLockID = 0x676f7265616432 // "goread2"
+
+// pgUnreadCutoff is the start of the unread count window, matching
+// unreadCountWindowDays on the other backends.
+var pgUnreadCutoff = fmt.Sprintf("now() - interval '%d days'", unreadCountWindowDays)
+
+// NewPostgresDB connects to the database at databaseURL (a postgres:// URL or
+// a libpq connection string) and creates any missing tables.
+func NewPostgresDB(databaseURL string) (*PostgresDB, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	pg := &PostgresDB{db}
+	if err := pg.CreateTables(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return pg, nil
+}
+
+// CreateTables creates all tables, indexes and triggers that don't exist yet
+// (public for testing).
+func (db *PostgresDB) CreateTables() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, pgSchemaLockID); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+
+	statements := append(append(pgTables(), pgIndexes()...), pgSyncSchema()...)
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create postgres schema: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func pgTables() []string {
+	return []string{
+		`CREATE TABLE IF NOT EXISTS users (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			google_id TEXT UNIQUE NOT NULL,
+			email TEXT UNIQUE NOT NULL,
+			name TEXT NOT NULL,
+			avatar TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT now(),
+			subscription_status TEXT DEFAULT 'trial',
+			subscription_id TEXT,
+			trial_ends_at TIMESTAMPTZ,
+			last_payment_date TIMESTAMPTZ,
+			next_billing_date TIMESTAMPTZ,
+			is_admin BOOLEAN DEFAULT FALSE,
+			free_months_remaining INTEGER DEFAULT 0,
+			max_articles_on_feed_add INTEGER DEFAULT 100,
+			digest_frequency TEXT DEFAULT '',
+			digest_timezone TEXT DEFAULT '',
+			digest_hour INTEGER DEFAULT 0,
+			digest_weekday INTEGER DEFAULT 0,
+			digest_last_sent_at TIMESTAMPTZ
+		)`,
+		`CREATE TABLE IF NOT EXISTS feeds (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			title TEXT NOT NULL,
+			url TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT now(),
+			updated_at TIMESTAMPTZ DEFAULT now(),
+			last_fetch TIMESTAMPTZ DEFAULT now(),
+			last_checked TIMESTAMPTZ DEFAULT now(),
+			last_had_new_content TIMESTAMPTZ DEFAULT now(),
+			average_update_interval INTEGER DEFAULT 0,
+			etag TEXT DEFAULT '',
+			last_modified TEXT DEFAULT ''
+		)`,
+		`CREATE TABLE IF NOT EXISTS articles (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			feed_id BIGINT NOT NULL,
+			title TEXT NOT NULL,
+			url TEXT UNIQUE NOT NULL,
+			content TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			author TEXT NOT NULL DEFAULT '',
+			published_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			reading_time_minutes INTEGER DEFAULT 0,
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_feeds (
+			user_id BIGINT NOT NULL,
+			feed_id BIGINT NOT NULL,
+			PRIMARY KEY (user_id, feed_id),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_articles (
+			user_id BIGINT NOT NULL,
+			article_id BIGINT NOT NULL,
+			is_read BOOLEAN NOT NULL DEFAULT FALSE,
+			is_starred BOOLEAN NOT NULL DEFAULT FALSE,
+			read_progress INTEGER NOT NULL DEFAULT 0,
+			version BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMPTZ,
+			PRIMARY KEY (user_id, article_id),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS admin_tokens (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			token_hash TEXT UNIQUE NOT NULL,
+			description TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			last_used_at TIMESTAMPTZ DEFAULT now(),
+			is_active BOOLEAN DEFAULT TRUE
+		)`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			expires_at TIMESTAMPTZ NOT NULL,
+			user_agent TEXT NOT NULL DEFAULT '',
+			client_type TEXT NOT NULL DEFAULT '',
+			ip_address TEXT NOT NULL DEFAULT '',
+			last_seen_at TIMESTAMPTZ,
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		// Device details were added after the sessions table; these bring
+		// databases created before then up to date
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS client_type TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip_address TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ`,
+		`CREATE TABLE IF NOT EXISTS user_identities (
+			provider TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			user_id BIGINT NOT NULL,
+			email TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT now(),
+			PRIMARY KEY (provider, subject),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS login_tokens (
+			token_hash TEXT PRIMARY KEY,
+			email TEXT NOT NULL,
+			mobile BOOLEAN NOT NULL DEFAULT FALSE,
+			expires_at TIMESTAMPTZ NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			token_hash TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			user_id BIGINT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			expires_at TIMESTAMPTZ NOT NULL,
+			used_at TIMESTAMPTZ,
+			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS audit_logs (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			"timestamp" TIMESTAMPTZ DEFAULT now(),
+			admin_user_id BIGINT,
+			admin_email TEXT NOT NULL,
+			operation_type TEXT NOT NULL,
+			target_user_id BIGINT,
+			target_user_email TEXT,
+			operation_details TEXT,
+			ip_address TEXT,
+			result TEXT NOT NULL,
+			error_message TEXT
+		)`,
+		`CREATE TABLE IF NOT EXISTS annotations (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			article_id BIGINT NOT NULL,
+			quoted_text TEXT NOT NULL,
+			start_offset INTEGER NOT NULL,
+			end_offset INTEGER NOT NULL,
+			note TEXT DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT now(),
+			updated_at TIMESTAMPTZ DEFAULT now(),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS tags (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			name TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			UNIQUE (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS article_tags (
+			user_id BIGINT NOT NULL,
+			article_id BIGINT NOT NULL,
+			tag_id BIGINT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			PRIMARY KEY (tag_id, article_id),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE,
+			FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS undo_operations (
+			id TEXT PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			kind TEXT NOT NULL,
+			changes TEXT NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			expires_at TIMESTAMPTZ NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhooks (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			feed_id BIGINT NOT NULL DEFAULT 0,
+			keywords TEXT NOT NULL DEFAULT '',
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			updated_at TIMESTAMPTZ DEFAULT now(),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			webhook_id BIGINT NOT NULL,
+			user_id BIGINT NOT NULL,
+			event_id TEXT NOT NULL,
+			event TEXT NOT NULL,
+			article_id BIGINT NOT NULL DEFAULT 0,
+			attempt INTEGER NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			success BOOLEAN NOT NULL DEFAULT FALSE,
+			error TEXT NOT NULL DEFAULT '',
+			duration_ms INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS inbound_addresses (
+			id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			feed_id BIGINT NOT NULL,
+			token TEXT UNIQUE NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT now(),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS feed_categories (
+			feed_id BIGINT PRIMARY KEY,
+			category TEXT NOT NULL,
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS directory_feeds (
+			feed_id BIGINT PRIMARY KEY,
+			title TEXT NOT NULL,
+			url TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			category TEXT NOT NULL DEFAULT '',
+			subscribers INTEGER NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL,
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS feed_recommendations (
+			feed_id BIGINT NOT NULL,
+			recommended_feed_id BIGINT NOT NULL,
+			shared_subscribers INTEGER NOT NULL,
+			score DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (feed_id, recommended_feed_id),
+			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+			FOREIGN KEY (recommended_feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+		)`,
+		// No foreign keys, as in SQLite: deleting a user cascades into
+		// user_feeds, whose delete trigger writes here.
+		`CREATE TABLE IF NOT EXISTS user_sync_state (
+			user_id BIGINT PRIMARY KEY,
+			version BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_feed_changes (
+			user_id BIGINT NOT NULL,
+			feed_id BIGINT NOT NULL,
+			subscribed BOOLEAN NOT NULL,
+			version BIGINT NOT NULL,
+			changed_at TIMESTAMPTZ DEFAULT now(),
+			PRIMARY KEY (user_id, feed_id)
+		)`,
+	}
+}
+
+// pgIndexes mirrors CreateIndexes. Foreign key columns aren't indexed
+// automatically in PostgreSQL, so the article and subscription lookups that
+// SQLite gets from its rowid tables are indexed explicitly here.
+func pgIndexes() []string {
+	return []string{
+		`CREATE INDEX IF NOT EXISTS idx_articles_feed_published ON articles (feed_id, published_at DESC, id DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_published_at ON articles (published_at DESC, id DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_created ON articles (created_at, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_articles_article_user_read ON user_articles (article_id, user_id, is_read)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_articles_user_starred ON user_articles (user_id) WHERE is_starred`,
+		`CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))`,
+		`CREATE INDEX IF NOT EXISTS idx_user_articles_user_version ON user_articles (user_id, version)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_feeds_feed_id ON user_feeds (feed_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_feed_changes_user_version ON user_feed_changes (user_id, version)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_tokens_expires ON login_tokens (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens (session_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_logs_timestamp ON audit_logs ("timestamp" DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_logs_admin_user ON audit_logs (admin_user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_logs_target_user ON audit_logs (target_user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_logs_operation ON audit_logs (operation_type)`,
+		`CREATE INDEX IF NOT EXISTS idx_annotations_user_article ON annotations (user_id, article_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_article_tags_user_article ON article_tags (user_id, article_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_undo_operations_expires ON undo_operations (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks (user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created ON webhook_deliveries (created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_inbound_addresses_user ON inbound_addresses (user_id)`,
+	}
+}
+
+// pgSyncSchema creates the change tracking triggers, equivalent to the SQLite
+// ones in createSyncSchema. New user_articles rows are stamped by an AFTER
+// trigger because BEFORE INSERT triggers also fire for rows that end up
+// taking the ON CONFLICT path, which would bump the version for nothing.
+func pgSyncSchema() []string {
+	return []string{
+		`CREATE OR REPLACE FUNCTION next_user_sync_version(uid BIGINT) RETURNS BIGINT AS $$
+			INSERT INTO user_sync_state (user_id, version) VALUES (uid, 1)
+			ON CONFLICT (user_id) DO UPDATE SET version = user_sync_state.version + 1
+			RETURNING version
+		$$ LANGUAGE sql`,
+
+		// updated_at keeps a value the statement set explicitly (the client's
+		// time for synced actions) and otherwise records the current time.
+		`CREATE OR REPLACE FUNCTION user_articles_sync_insert() RETURNS trigger AS $$
+		BEGIN
+			UPDATE user_articles
+			SET version = next_user_sync_version(NEW.user_id),
+			    updated_at = COALESCE(NEW.updated_at, now())
+			WHERE user_id = NEW.user_id AND article_id = NEW.article_id;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION user_articles_sync_update() RETURNS trigger AS $$
+		BEGIN
+			NEW.version := next_user_sync_version(NEW.user_id);
+			IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at THEN
+				NEW.updated_at := now();
+			END IF;
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE FUNCTION user_feeds_sync() RETURNS trigger AS $$
+		DECLARE
+			changed user_feeds%ROWTYPE;
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				changed := OLD;
+			ELSE
+				changed := NEW;
+			END IF;
+			INSERT INTO user_feed_changes (user_id, feed_id, subscribed, version, changed_at)
+			VALUES (changed.user_id, changed.feed_id, TG_OP = 'INSERT', next_user_sync_version(changed.user_id), now())
+			ON CONFLICT (user_id, feed_id) DO UPDATE SET
+				subscribed = EXCLUDED.subscribed, version = EXCLUDED.version, changed_at = EXCLUDED.changed_at;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+
+		`DROP TRIGGER IF EXISTS user_articles_sync_insert ON user_articles`,
+		`CREATE TRIGGER user_articles_sync_insert
+		AFTER INSERT ON user_articles
+		FOR EACH ROW EXECUTE FUNCTION user_articles_sync_insert()`,
+		`DROP TRIGGER IF EXISTS user_articles_sync_update ON user_articles`,
+		`CREATE TRIGGER user_articles_sync_update
+		BEFORE UPDATE OF is_read, is_starred, read_progress ON user_articles
+		FOR EACH ROW
+		WHEN (OLD.is_read IS DISTINCT FROM NEW.is_read
+			OR OLD.is_starred IS DISTINCT FROM NEW.is_starred
+			OR OLD.read_progress IS DISTINCT FROM NEW.read_progress)
+		EXECUTE FUNCTION user_articles_sync_update()`,
+		`DROP TRIGGER IF EXISTS user_feeds_sync ON user_feeds`,
+		`CREATE TRIGGER user_feeds_sync
+		AFTER INSERT OR DELETE ON user_feeds
+		FOR EACH ROW EXECUTE FUNCTION user_feeds_sync()`,
+	}
+}
+
+func (db *PostgresDB) Close() error {
+	return db.DB.Close()
+}
+
+// pgText makes s storable in a TEXT column, which rejects NUL bytes and
+// invalid UTF-8 that SQLite and Datastore accept.
+func pgText(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "�"), "\x00", "")
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint error.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// pgIDs converts IDs for binding as a bigint[] parameter.
+func pgIDs(ids []int) interface{} {
+	ids64 := make([]int64, len(ids))
+	for i, id := range ids {
+		ids64[i] = int64(id)
+	}
+	return pq.Array(ids64)
+}
+
+// pgFeedColumns is the column list scanFeed expects.
+const pgFeedColumns = `f.id, f.title, f.url, f.description, f.created_at, f.updated_at, f.last_fetch,
+	f.last_checked, f.last_had_new_content, f.average_update_interval,
+	COALESCE(f.etag, ''), COALESCE(f.last_modified, '')`
+
+func scanFeed(row interface{ Scan(...interface{}) error }) (*Feed, error) {
+	var feed Feed
+	err := row.Scan(&feed.ID, &feed.Title, &feed.URL, &feed.Description,
+		&feed.CreatedAt, &feed.UpdatedAt, &feed.LastFetch, &feed.LastChecked, &feed.LastHadNewContent, &feed.AverageUpdateInterval,
+		&feed.ETag, &feed.LastModified)
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
+
+func (db *PostgresDB) queryFeeds(query string, args ...interface{}) ([]Feed, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var feeds []Feed
+	for rows.Next() {
+		feed, err := scanFeed(rows)
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, *feed)
+	}
+	return feeds, rows.Err()
+}
+
+// pgUserArticleColumns selects an article with the feed title and the user's
+// flags; the query must join feeds as f and left join user_articles as ua.
+// scanUserArticle reads rows selected with it.
+const pgUserArticleColumns = `a.id, a.feed_id, f.title, a.title, a.url, a.description, a.author,
+	a.published_at, a.created_at, a.reading_time_minutes,
+	COALESCE(ua.is_read, FALSE), COALESCE(ua.is_starred, FALSE), COALESCE(ua.read_progress, 0)`
+
+func scanUserArticle(row interface{ Scan(...interface{}) error }) (*Article, error) {
+	var a Article
+	err := row.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Description, &a.Author,
+		&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes,
+		&a.IsRead, &a.IsStarred, &a.ReadProgress)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *PostgresDB) queryUserArticles(query string, args ...interface{}) ([]Article, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var articles []Article
+	for rows.Next() {
+		article, err := scanUserArticle(rows)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, *article)
+	}
+	return articles, rows.Err()
+}
+
+// paginateArticles trims an over-fetched page to limit and returns the cursor
+// for the next page, in the same format as the SQLite backend.
+func paginateArticles(articles []Article, limit int) *ArticlePaginationResult {
+	var nextCursor string
+	if len(articles) > limit {
+		last := articles[limit-1]
+		nextCursor = encodeSQLiteCursor(last.ID, last.PublishedAt)
+		articles = articles[:limit]
+	}
+	return &ArticlePaginationResult{Articles: articles, NextCursor: nextCursor}
+}
+
+// Feed methods
+
+const pgInsertFeedQuery = `INSERT INTO feeds (title, url, description, created_at, updated_at, last_fetch, last_checked, last_had_new_content, average_update_interval, etag, last_modified)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  RETURNING id`
+
+func (db *PostgresDB) AddFeed(feed *Feed) error {
+	return db.QueryRow(pgInsertFeedQuery, pgText(feed.Title), feed.URL, pgText(feed.Description),
+		feed.CreatedAt, feed.UpdatedAt, feed.LastFetch, feed.LastChecked, feed.LastHadNewContent, feed.AverageUpdateInterval,
+		feed.ETag, feed.LastModified).Scan(&feed.ID)
+}
+
+func (db *PostgresDB) UpdateFeed(feed *Feed) error {
+	_, err := db.Exec(`UPDATE feeds SET title = $1, description = $2, updated_at = $3 WHERE id = $4`,
+		pgText(feed.Title), pgText(feed.Description), time.Now(), feed.ID)
+	return err
+}
+
+func (db *PostgresDB) UpdateFeedTracking(feedID int, lastChecked, lastHadNewContent time.Time, averageUpdateInterval int) error {
+	_, err := db.Exec(`UPDATE feeds SET last_checked = $1, last_had_new_content = $2, average_update_interval = $3 WHERE id = $4`,
+		lastChecked, lastHadNewContent, averageUpdateInterval, feedID)
+	return err
+}
+
+func (db *PostgresDB) UpdateFeedCacheHeaders(feedID int, etag, lastModified string) error {
+	_, err := db.Exec(`UPDATE feeds SET etag = $1, last_modified = $2 WHERE id = $3`, etag, lastModified, feedID)
+	return err
+}
+
+func (db *PostgresDB) UpdateFeedLastFetch(feedID int, lastFetch time.Time) error {
+	_, err := db.Exec(`UPDATE feeds SET last_fetch = $1 WHERE id = $2`, lastFetch, feedID)
+	return err
+}
+
+func (db *PostgresDB) UpdateFeedAfterRefresh(feedID int, lastChecked, lastHadNewContent time.Time, averageUpdateInterval int, lastFetch time.Time, etag, lastModified string) error {
+	_, err := db.Exec(`UPDATE feeds SET last_checked = $1, last_had_new_content = $2, average_update_interval = $3,
+		last_fetch = $4, etag = $5, last_modified = $6 WHERE id = $7`,
+		lastChecked, lastHadNewContent, averageUpdateInterval, lastFetch, etag, lastModified, feedID)
+	return err
+}
+
+func (db *PostgresDB) GetFeeds() ([]Feed, error) {
+	return db.queryFeeds(`SELECT ` + pgFeedColumns + ` FROM feeds f ORDER BY f.title`)
+}
+
+func (db *PostgresDB) GetFeedByURL(url string) (*Feed, error) {
+	feed, err := scanFeed(db.QueryRow(`SELECT `+pgFeedColumns+` FROM feeds f WHERE f.url = $1`, url))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return feed, err
+}
+
+func (db *PostgresDB) GetUserFeeds(userID int) ([]Feed, error) {
+	return db.queryFeeds(`SELECT `+pgFeedColumns+`
+		FROM feeds f
+		JOIN user_feeds uf ON f.id = uf.feed_id
+		WHERE uf.user_id = $1
+		ORDER BY f.title`, userID)
+}
+
+func (db *PostgresDB) GetAllUserFeeds() ([]Feed, error) {
+	return db.queryFeeds(`SELECT ` + pgFeedColumns + `
+		FROM feeds f
+		WHERE EXISTS (SELECT 1 FROM user_feeds uf WHERE uf.feed_id = f.id)
+		ORDER BY f.title`)
+}
+
+func (db *PostgresDB) DeleteFeed(id int) error {
+	_, err := db.Exec(`DELETE FROM feeds WHERE id = $1`, id)
+	return err
+}
+
+func (db *PostgresDB) SubscribeUserToFeed(userID, feedID int) error {
+	_, err := db.Exec(`INSERT INTO user_feeds (user_id, feed_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, feedID)
+	return err
+}
+
+func (db *PostgresDB) UnsubscribeUserFromFeed(userID, feedID int) error {
+	// Orphaned user_articles are removed by CleanupOrphanedUserArticles
+	_, err := db.Exec(`DELETE FROM user_feeds WHERE user_id = $1 AND feed_id = $2`, userID, feedID)
+	return err
+}
+
+// Article methods
+
+func (db *PostgresDB) AddArticle(article *Article) error {
+	// Duplicate URLs are ignored; the no-op update is there so RETURNING
+	// still has a row to scan.
+	query := `INSERT INTO articles
+			  (feed_id, title, url, content, description, author, published_at, created_at, reading_time_minutes)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  ON CONFLICT (url) DO UPDATE SET url = EXCLUDED.url
+			  RETURNING id`
+	return db.QueryRow(query, article.FeedID, pgText(article.Title), article.URL, pgText(article.Content),
+		pgText(article.Description), pgText(article.Author), article.PublishedAt, article.CreatedAt,
+		article.ReadingTimeMinutes).Scan(&article.ID)
+}
+
+func (db *PostgresDB) FilterExistingArticleURLs(feedID int, urls []string) (map[string]bool, error) {
+	if len(urls) == 0 {
+		return map[string]bool{}, nil
+	}
+	rows, err := db.Query(`SELECT url FROM articles WHERE feed_id = $1 AND url = ANY($2)`, feedID, pq.Array(urls))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, err
+		}
+		existing[u] = true
+	}
+	return existing, rows.Err()
+}
+
+func (db *PostgresDB) GetArticles(feedID int) ([]Article, error) {
+	rows, err := db.Query(`SELECT id, feed_id, title, url, content, description, author,
+		published_at, created_at, reading_time_minutes
+		FROM articles WHERE feed_id = $1 ORDER BY published_at DESC`, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var articles []Article
+	for rows.Next() {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+func (db *PostgresDB) FindArticleByURL(url string) (*Article, error) {
+	var a Article
+	err := db.QueryRow(`SELECT id, feed_id, title, url, content, description, author,
+		published_at, created_at, reading_time_minutes
+		FROM articles WHERE url = $1`, url).Scan(&a.ID, &a.FeedID, &a.Title, &a.URL,
+		&a.Content, &a.Description, &a.Author, &a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *PostgresDB) GetUserArticles(userID int) ([]Article, error) {
+	result, err := db.GetUserArticlesPaginated(userID, 50, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return result.Articles, nil
+}
+
+func (db *PostgresDB) GetUserArticlesPaginated(userID int, limit int, cursor string, unreadOnly bool) (*ArticlePaginationResult, error) {
+	return db.getUserArticlesPaginated(userID, 0, limit, cursor, unreadOnly)
+}
+
+func (db *PostgresDB) GetUserFeedArticlesPaginated(userID, feedID int, limit int, cursor string, unreadOnly bool) (*ArticlePaginationResult, error) {
+	return db.getUserArticlesPaginated(userID, feedID, limit, cursor, unreadOnly)
+}
+
+// getUserArticlesPaginated uses the same keyset ordering and cursor format as
+// the SQLite backend: published_at DESC, id DESC.
+func (db *PostgresDB) getUserArticlesPaginated(userID, feedID int, limit int, cursor string, unreadOnly bool) (*ArticlePaginationResult, error) {
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	query := `SELECT ` + pgUserArticleColumns + `
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = $1
+		WHERE uf.user_id = $1`
+	if feedID != 0 {
+		query += ` AND a.feed_id = ` + arg(feedID)
+	}
+	if unreadOnly {
+		query += ` AND NOT COALESCE(ua.is_read, FALSE)`
+	}
+	if cursor != "" {
+		cursorData, err := decodeSQLiteCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		query += ` AND (a.published_at, a.id) < (` + arg(cursorData.PublishedAt) + `, ` + arg(cursorData.ID) + `)`
+	}
+	query += ` ORDER BY a.published_at DESC, a.id DESC LIMIT ` + arg(limit+paginationOverfetch)
+
+	articles, err := db.queryUserArticles(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return paginateArticles(articles, limit), nil
+}
+
+func (db *PostgresDB) GetUserFeedArticles(userID, feedID int) ([]Article, error) {
+	var subscribed bool
+	err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_feeds WHERE user_id = $1 AND feed_id = $2)`,
+		userID, feedID).Scan(&subscribed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user subscription: %w", err)
+	}
+	if !subscribed {
+		return []Article{}, nil
+	}
+
+	return db.queryUserArticles(`SELECT `+pgUserArticleColumns+`
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = $1
+		WHERE a.feed_id = $2
+		ORDER BY a.published_at DESC`, userID, feedID)
+}
+
+func (db *PostgresDB) GetArticleByID(userID, articleID int) (*Article, error) {
+	var a Article
+	err := db.QueryRow(`SELECT a.id, a.feed_id, f.title, a.title, a.url, a.content, a.description, a.author,
+			a.published_at, a.created_at, a.reading_time_minutes,
+			COALESCE(ua.is_read, FALSE), COALESCE(ua.is_starred, FALSE), COALESCE(ua.read_progress, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = $1
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = $1
+		WHERE a.id = $2`, userID, articleID).Scan(
+		&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+		&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes,
+		&a.IsRead, &a.IsStarred, &a.ReadProgress)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *PostgresDB) GetArticlesByIDs(userID int, articleIDs []int) (map[int]Article, error) {
+	articles := make(map[int]Article)
+	if len(articleIDs) == 0 {
+		return articles, nil
+	}
+
+	rows, err := db.Query(`SELECT a.id, a.feed_id, f.title, a.title, a.url, a.description, a.author,
+			a.published_at, a.created_at, a.reading_time_minutes
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = $1
+		WHERE a.id = ANY($2)`, userID, pgIDs(articleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes); err != nil {
+			return nil, err
+		}
+		articles[a.ID] = a
+	}
+	return articles, rows.Err()
+}
+
+func (db *PostgresDB) GetUserArticleHistory(userID int) ([]Article, error) {
+	rows, err := db.Query(`SELECT a.id, a.feed_id, f.title, a.title, a.url, a.description, a.author,
+			a.published_at, a.created_at, a.reading_time_minutes,
+			ua.is_read, ua.is_starred, COALESCE(ua.read_progress, 0)
+		FROM user_articles ua
+		JOIN articles a ON a.id = ua.article_id
+		JOIN feeds f ON f.id = a.feed_id
+		WHERE ua.user_id = $1 AND (ua.is_read OR ua.is_starred OR ua.read_progress > 0)
+		ORDER BY a.published_at DESC, a.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var articles []Article
+	for rows.Next() {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes,
+			&a.IsRead, &a.IsStarred, &a.ReadProgress); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// User methods
+
+func (db *PostgresDB) CreateUser(user *User) error {
+	if user.SubscriptionStatus == "" {
+		user.SubscriptionStatus = "trial"
+	}
+	if user.TrialEndsAt.IsZero() {
+		user.TrialEndsAt = user.CreatedAt.AddDate(0, 0, 30)
+	}
+	if user.MaxArticlesOnFeedAdd == 0 {
+		user.MaxArticlesOnFeedAdd = 100
+	}
+	user.Email = NormalizeEmail(user.Email)
+
+	query := `INSERT INTO users (google_id, email, name, avatar, created_at, subscription_status, subscription_id, trial_ends_at, last_payment_date, next_billing_date, max_articles_on_feed_add)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			  RETURNING id`
+	return db.QueryRow(query, user.GoogleID, user.Email, pgText(user.Name), user.Avatar, user.CreatedAt,
+		user.SubscriptionStatus, user.SubscriptionID, user.TrialEndsAt, user.LastPaymentDate, user.NextBillingDate,
+		user.MaxArticlesOnFeedAdd).Scan(&user.ID)
+}
+
+// pgUserColumns is userColumns with PostgreSQL's boolean literal.
+var pgUserColumns = strings.Replace(userColumns, "COALESCE(is_admin, 0)", "COALESCE(is_admin, FALSE)", 1)
+
+func (db *PostgresDB) GetUserByGoogleID(googleID string) (*User, error) {
+	return scanUser(db.QueryRow(`SELECT `+pgUserColumns+` FROM users WHERE google_id = $1`, googleID))
+}
+
+func (db *PostgresDB) GetUserByID(userID int) (*User, error) {
+	return scanUser(db.QueryRow(`SELECT `+pgUserColumns+` FROM users WHERE id = $1`, userID))
+}
+
+// GetUserByEmail matches case-insensitively, like the SQLite version.
+func (db *PostgresDB) GetUserByEmail(email string) (*User, error) {
+	return scanUser(db.QueryRow(`SELECT `+pgUserColumns+` FROM users WHERE LOWER(email) = $1 ORDER BY id LIMIT 1`, NormalizeEmail(email)))
+}
+
+func (db *PostgresDB) DeleteUser(userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Everything else goes with the user through ON DELETE CASCADE. The
+	// subscriptions are removed first so the change rows their trigger
+	// writes can be cleared with the rest of the sync history.
+	statements := []string{
+		`DELETE FROM user_feeds WHERE user_id = $1`,
+		`DELETE FROM user_feed_changes WHERE user_id = $1`,
+		`DELETE FROM user_sync_state WHERE user_id = $1`,
+		`DELETE FROM users WHERE id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (db *PostgresDB) UpdateUserSubscription(userID int, status, subscriptionID string, lastPaymentDate, nextBillingDate time.Time) error {
+	// Redact subscription ID for security - only log prefix for debugging
+	redactedSubID := "***"
+	if len(subscriptionID) > 8 {
+		redactedSubID = subscriptionID[:8] + "***"
+	}
+	log.Printf("UpdateUserSubscription - Updating user %d: status=%s, subscriptionID=%s, lastPaymentDate=%v, nextBillingDate=%v",
+		userID, status, redactedSubID, lastPaymentDate, nextBillingDate)
+
+	result, err := db.Exec(`UPDATE users SET subscription_status = $1, subscription_id = $2, last_payment_date = $3, next_billing_date = $4
+		WHERE id = $5`, status, subscriptionID, lastPaymentDate, nextBillingDate, userID)
+	if err != nil {
+		log.Printf("ERROR: UpdateUserSubscription - Query failed: %v", err)
+		return err
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		log.Printf("WARNING: UpdateUserSubscription - No rows updated for user ID %d", userID)
+	}
+	return nil
+}
+
+func (db *PostgresDB) IsUserSubscriptionActive(userID int) (bool, error) {
+	var status string
+	var trialEndsAt time.Time
+	var isAdmin bool
+	var freeMonths int
+	err := db.QueryRow(`SELECT COALESCE(subscription_status, 'trial'), trial_ends_at,
+		COALESCE(is_admin, FALSE), COALESCE(free_months_remaining, 0)
+		FROM users WHERE id = $1`, userID).Scan(&status, &trialEndsAt, &isAdmin, &freeMonths)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case isAdmin, status == "active", freeMonths > 0:
+		return true, nil
+	case status == "trial" && time.Now().Before(trialEndsAt):
+		return true, nil
+	}
+	return false, nil
+}
+
+func (db *PostgresDB) GetUserFeedCount(userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM user_feeds WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+func (db *PostgresDB) UpdateUserMaxArticlesOnFeedAdd(userID int, maxArticles int) error {
+	_, err := db.Exec(`UPDATE users SET max_articles_on_feed_add = $1 WHERE id = $2`, maxArticles, userID)
+	return err
+}
+
+func (db *PostgresDB) UpdateUserDigestPreferences(userID int, prefs DigestPreferences) error {
+	_, err := db.Exec(`UPDATE users SET digest_frequency = $1, digest_timezone = $2, digest_hour = $3, digest_weekday = $4
+		WHERE id = $5`, prefs.Frequency, prefs.Timezone, prefs.Hour, prefs.Weekday, userID)
+	return err
+}
+
+func (db *PostgresDB) GetDigestUsers() ([]User, error) {
+	rows, err := db.Query(`SELECT ` + pgUserColumns + ` FROM users
+		WHERE COALESCE(digest_frequency, '') != '' ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (db *PostgresDB) SetUserDigestSentAt(userID int, sentAt time.Time) error {
+	_, err := db.Exec(`UPDATE users SET digest_last_sent_at = $1 WHERE id = $2`, sentAt, userID)
+	return err
+}
+
+// Admin methods
+
+func (db *PostgresDB) SetUserAdmin(userID int, isAdmin bool) error {
+	_, err := db.Exec(`UPDATE users SET is_admin = $1 WHERE id = $2`, isAdmin, userID)
+	return err
+}
+
+func (db *PostgresDB) SetUserAdminAtomic(targetID, callerID int, isAdmin bool) error {
+	if targetID == callerID && !isAdmin {
+		return ErrSelfDemotion
+	}
+	_, err := db.Exec(`UPDATE users SET is_admin = $1 WHERE id = $2`, isAdmin, targetID)
+	return err
+}
+
+func (db *PostgresDB) GrantFreeMonths(userID int, months int) error {
+	_, err := db.Exec(`UPDATE users SET free_months_remaining = COALESCE(free_months_remaining, 0) + $1 WHERE id = $2`,
+		months, userID)
+	return err
+}
+
+// User article status methods
+
+func (db *PostgresDB) GetUserArticleStatus(userID, articleID int) (*UserArticle, error) {
+	var ua UserArticle
+	err := db.QueryRow(`SELECT user_id, article_id, is_read, is_starred, read_progress FROM user_articles
+		WHERE user_id = $1 AND article_id = $2`, userID, articleID).Scan(
+		&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress)
+	if err != nil {
+		return nil, err
+	}
+	return &ua, nil
+}
+
+func (db *PostgresDB) SetUserArticleStatus(userID, articleID int, isRead, isStarred bool) error {
+	_, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET
+		is_read = EXCLUDED.is_read, is_starred = EXCLUDED.is_starred`, userID, articleID, isRead, isStarred)
+	return err
+}
+
+func (db *PostgresDB) SetUserArticleProgress(userID, articleID, progress int) error {
+	_, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, read_progress)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET read_progress = EXCLUDED.read_progress`,
+		userID, articleID, progress)
+	return err
+}
+
+func (db *PostgresDB) MarkUserArticleRead(userID, articleID int, isRead bool) error {
+	_, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, is_read)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = EXCLUDED.is_read`, userID, articleID, isRead)
+	return err
+}
+
+func (db *PostgresDB) ToggleUserArticleStar(userID, articleID int) error {
+	_, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, is_starred)
+		VALUES ($1, $2, TRUE)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_starred = NOT user_articles.is_starred`, userID, articleID)
+	return err
+}
+
+func (db *PostgresDB) BatchSetUserArticleStatus(userID int, articles []Article, isRead, isStarred bool) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	// An upsert may not touch the same row twice
+	seen := make(map[int]bool, len(articles))
+	ids := make([]int, 0, len(articles))
+	for _, a := range articles {
+		if !seen[a.ID] {
+			seen[a.ID] = true
+			ids = append(ids, a.ID)
+		}
+	}
+
+	_, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+		SELECT $1::bigint, id, $2::boolean, $3::boolean FROM unnest($4::bigint[]) AS id
+		ON CONFLICT (user_id, article_id) DO UPDATE SET
+		is_read = EXCLUDED.is_read, is_starred = EXCLUDED.is_starred`, userID, isRead, isStarred, pgIDs(ids))
+	return err
+}
+
+func (db *PostgresDB) MarkAllUserArticlesRead(userID int) (int, error) {
+	result, err := db.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+		SELECT $1::bigint, a.id, TRUE, FALSE
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id
+		WHERE uf.user_id = $1
+		ON CONFLICT (user_id, article_id) DO UPDATE SET
+		is_read = EXCLUDED.is_read, is_starred = EXCLUDED.is_starred`, userID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (db *PostgresDB) MarkUserArticlesReadScoped(userID int, scope MarkReadScope) (*MarkReadResult, error) {
+	args := []interface{}{userID}
+	filter := `
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = $1
+		LEFT JOIN user_articles ua ON ua.article_id = a.id AND ua.user_id = $1
+		WHERE NOT COALESCE(ua.is_read, FALSE)`
+	if len(scope.FeedIDs) > 0 {
+		args = append(args, pgIDs(scope.FeedIDs))
+		filter += fmt.Sprintf(` AND a.feed_id = ANY($%d)`, len(args))
+	}
+	if !scope.PublishedBefore.IsZero() {
+		args = append(args, scope.PublishedBefore)
+		filter += fmt.Sprintf(` AND a.published_at < $%d`, len(args))
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Record what is about to change; the recency flag mirrors the window
+	// used by GetUserUnreadCounts.
+	rows, err := tx.Query(`SELECT a.id, a.feed_id, COALESCE(ua.is_starred, FALSE),
+		COALESCE(a.published_at >= `+pgUnreadCutoff+`, FALSE)`+filter+` ORDER BY a.id FOR UPDATE OF a`, args...)
+	if err != nil {
+		return nil, err
+	}
+	result := &MarkReadResult{UnreadCleared: make(map[int]int)}
+	for rows.Next() {
+		var change ArticleStatusChange
+		var recent bool
+		if err := rows.Scan(&change.ArticleID, &change.FeedID, &change.WasStarred, &recent); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		change.ReadChanged = true
+		result.Changes = append(result.Changes, change)
+		if recent {
+			result.UnreadCleared[change.FeedID]++
+		}
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	_, err = tx.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+		SELECT $1::bigint, a.id, TRUE, COALESCE(ua.is_starred, FALSE)`+filter+`
+		ON CONFLICT (user_id, article_id) DO UPDATE SET is_read = TRUE`, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (db *PostgresDB) UpdateUserArticleStatuses(userID int, updates []ArticleStatusUpdate) (*StatusUpdateResult, error) {
+	result := &StatusUpdateResult{Found: make(map[int]int), UnreadDelta: make(map[int]int)}
+	if len(updates) == 0 {
+		return result, nil
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, u := range updates {
+		if !seen[u.ArticleID] {
+			seen[u.ArticleID] = true
+			ids = append(ids, u.ArticleID)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	type state struct {
+		change        ArticleStatusChange
+		read, starred bool
+		recent        bool
+	}
+	states := make(map[int]*state, len(ids))
+	rows, err := tx.Query(`SELECT a.id, a.feed_id, COALESCE(ua.is_read, FALSE), COALESCE(ua.is_starred, FALSE),
+			COALESCE(a.published_at >= `+pgUnreadCutoff+`, FALSE)
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = $1
+		LEFT JOIN user_articles ua ON ua.article_id = a.id AND ua.user_id = $1
+		WHERE a.id = ANY($2)`, userID, pgIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		st := &state{}
+		c := &st.change
+		if err := rows.Scan(&c.ArticleID, &c.FeedID, &c.WasRead, &c.WasStarred, &st.recent); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		st.read, st.starred = c.WasRead, c.WasStarred
+		states[c.ArticleID] = st
+		result.Found[c.ArticleID] = c.FeedID
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range updates {
+		st, ok := states[u.ArticleID]
+		if !ok {
+			continue
+		}
+		if u.IsRead != nil {
+			st.read = *u.IsRead
+		}
+		if u.IsStarred != nil {
+			st.starred = *u.IsStarred
+		}
+	}
+
+	var changedIDs []int64
+	var changedRead, changedStarred []bool
+	for _, id := range ids {
+		st, ok := states[id]
+		if !ok || (st.read == st.change.WasRead && st.starred == st.change.WasStarred) {
+			continue
+		}
+		changedIDs = append(changedIDs, int64(id))
+		changedRead = append(changedRead, st.read)
+		changedStarred = append(changedStarred, st.starred)
+		st.change.ReadChanged = st.read != st.change.WasRead
+		st.change.StarredChanged = st.starred != st.change.WasStarred
+		result.Changes = append(result.Changes, st.change)
+		if st.recent && st.read != st.change.WasRead {
+			if st.read {
+				result.UnreadDelta[st.change.FeedID]--
+			} else {
+				result.UnreadDelta[st.change.FeedID]++
+			}
+		}
+	}
+	sort.Slice(result.Changes, func(i, j int) bool { return result.Changes[i].ArticleID < result.Changes[j].ArticleID })
+
+	if len(changedIDs) > 0 {
+		_, err := tx.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+			SELECT $1::bigint, u.article_id, u.is_read, u.is_starred
+			FROM unnest($2::bigint[], $3::boolean[], $4::boolean[]) AS u(article_id, is_read, is_starred)
+			ON CONFLICT (user_id, article_id) DO UPDATE SET
+			is_read = EXCLUDED.is_read, is_starred = EXCLUDED.is_starred`,
+			userID, pq.Array(changedIDs), pq.Array(changedRead), pq.Array(changedStarred))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (db *PostgresDB) GetUserUnreadCounts(userID int) (map[int]int, error) {
+	// One row per subscribed feed, so feeds with nothing unread report 0
+	rows, err := db.Query(`SELECT uf.feed_id, COUNT(a.id)
+		FROM user_feeds uf
+		LEFT JOIN articles a ON a.feed_id = uf.feed_id
+			AND a.published_at >= `+pgUnreadCutoff+`
+			AND NOT EXISTS (
+				SELECT 1 FROM user_articles ua
+				WHERE ua.article_id = a.id AND ua.user_id = uf.user_id AND ua.is_read
+			)
+		WHERE uf.user_id = $1
+		GROUP BY uf.feed_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var feedID, count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (db *PostgresDB) GetUserStarredCountsByFeed(userID int) (map[int]int, error) {
+	rows, err := db.Query(`SELECT a.feed_id, COUNT(*)
+		FROM user_articles ua
+		JOIN articles a ON a.id = ua.article_id
+		JOIN user_feeds uf ON uf.feed_id = a.feed_id AND uf.user_id = ua.user_id
+		WHERE ua.user_id = $1 AND ua.is_starred
+		GROUP BY a.feed_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var feedID, count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (db *PostgresDB) GetTotalArticleCount(userID int) (int, error) {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*)
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id
+		WHERE uf.user_id = $1`, userID).Scan(&count)
+	return count, err
+}
+
+// GetAccountStats counts unread articles and active feeds over the same
+// window as GetUserUnreadCounts.
+func (db *PostgresDB) GetAccountStats(userID int) (map[string]interface{}, error) {
+	var totalArticles, totalUnread, activeFeeds int
+	err := db.QueryRow(`SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE unread),
+			COUNT(DISTINCT feed_id) FILTER (WHERE unread)
+		FROM (
+			SELECT a.feed_id,
+				a.published_at >= `+pgUnreadCutoff+` AND NOT EXISTS (
+					SELECT 1 FROM user_articles ua
+					WHERE ua.article_id = a.id AND ua.user_id = $1 AND ua.is_read
+				) AS unread
+			FROM articles a
+			JOIN user_feeds uf ON a.feed_id = uf.feed_id
+			WHERE uf.user_id = $1
+		) user_articles_window`, userID).Scan(&totalArticles, &totalUnread, &activeFeeds)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"total_articles": totalArticles,
+		"total_unread":   totalUnread,
+		"active_feeds":   activeFeeds,
+	}, nil
+}
+
+// CleanupOrphanedUserArticles removes user_articles for feeds the user is no
+// longer subscribed to, limited to articles created more than olderThanDays
+// ago when olderThanDays is nonzero. Returns the number of rows deleted.
+func (db *PostgresDB) CleanupOrphanedUserArticles(olderThanDays int) (int, error) {
+	query := `DELETE FROM user_articles ua
+		USING articles a
+		WHERE ua.article_id = a.id
+		AND NOT EXISTS (
+			SELECT 1 FROM user_feeds uf WHERE uf.user_id = ua.user_id AND uf.feed_id = a.feed_id
+		)`
+	var args []interface{}
+	if olderThanDays != 0 {
+		query += ` AND a.created_at < now() - make_interval(days => $1)`
+		args = append(args, olderThanDays)
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cleanup orphaned user articles: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n), nil
+}
+
+// Session methods
+
+func (db *PostgresDB) CreateSession(session *Session) error {
+	lastSeen := session.LastSeenAt
+	if lastSeen.IsZero() {
+		lastSeen = session.CreatedAt
+	}
+	_, err := db.Exec(`INSERT INTO sessions (id, user_id, created_at, expires_at, user_agent, client_type, ip_address, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		session.ID, session.UserID, session.CreatedAt, session.ExpiresAt,
+		session.UserAgent, session.ClientType, session.IPAddress, lastSeen)
+	return err
+}
+
+func (db *PostgresDB) GetSession(sessionID string) (*Session, error) {
+	s, err := scanSession(db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = $1`, sessionID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (db *PostgresDB) UpdateSessionExpiry(sessionID string, newExpiry time.Time) error {
+	_, err := db.Exec(`UPDATE sessions SET expires_at = $1 WHERE id = $2`, newExpiry, sessionID)
+	return err
+}
+
+func (db *PostgresDB) UpdateSessionActivity(sessionID string, lastSeenAt time.Time, ipAddress string) error {
+	_, err := db.Exec(`UPDATE sessions SET last_seen_at = $1, ip_address = $2 WHERE id = $3`, lastSeenAt, ipAddress, sessionID)
+	return err
+}
+
+func (db *PostgresDB) DeleteSession(sessionID string) error {
+	_, err := db.Exec(`DELETE FROM sessions WHERE id = $1`, sessionID)
+	return err
+}
+
+func (db *PostgresDB) GetUserSessions(userID int) ([]Session, error) {
+	rows, err := db.Query(`SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = $1 ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (db *PostgresDB) DeleteExpiredSessions() error {
+	_, err := db.Exec(`DELETE FROM sessions WHERE expires_at < $1`, time.Now())
+	return err
+}
+
+// Identity methods
+
+func (db *PostgresDB) CreateUserIdentity(identity *UserIdentity) error {
+	_, err := db.Exec(`INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	return err
+}
+
+func (db *PostgresDB) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	err := db.QueryRow(`SELECT user_id, provider, subject, email, created_at FROM user_identities WHERE provider = $1 AND subject = $2`,
+		provider, subject).Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (db *PostgresDB) GetUserIdentities(userID int) ([]UserIdentity, error) {
+	rows, err := db.Query(`SELECT user_id, provider, subject, email, created_at FROM user_identities
+		WHERE user_id = $1 ORDER BY created_at, provider`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	identities := []UserIdentity{}
+	for rows.Next() {
+		var identity UserIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// Login token methods
+
+func (db *PostgresDB) CreateLoginToken(token *LoginToken) error {
+	_, err := db.Exec(`INSERT INTO login_tokens (token_hash, email, mobile, expires_at) VALUES ($1, $2, $3, $4)`,
+		token.TokenHash, token.Email, token.Mobile, token.ExpiresAt)
+	return err
+}
+
+func (db *PostgresDB) ConsumeLoginToken(tokenHash string) (*LoginToken, error) {
+	var token LoginToken
+	err := db.QueryRow(`DELETE FROM login_tokens WHERE token_hash = $1 RETURNING token_hash, email, mobile, expires_at`,
+		tokenHash).Scan(&token.TokenHash, &token.Email, &token.Mobile, &token.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (db *PostgresDB) DeleteExpiredLoginTokens() error {
+	_, err := db.Exec(`DELETE FROM login_tokens WHERE expires_at < $1`, time.Now())
+	return err
+}
+
+func (db *PostgresDB) CreateRefreshToken(token *RefreshToken) error {
+	_, err := db.Exec(`INSERT INTO refresh_tokens (token_hash, session_id, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)`,
+		token.TokenHash, token.SessionID, token.UserID, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+func (db *PostgresDB) UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error) {
+	token, err := scanRefreshToken(db.QueryRow(`UPDATE refresh_tokens SET used_at = $1
+		WHERE token_hash = $2 AND used_at IS NULL RETURNING `+refreshTokenColumns, usedAt, tokenHash))
+	if err == nil {
+		token.UsedAt = time.Time{}
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+	token, err = scanRefreshToken(db.QueryRow(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+func (db *PostgresDB) DeleteExpiredRefreshTokens() error {
+	_, err := db.Exec(`DELETE FROM refresh_tokens WHERE expires_at < $1`, time.Now())
+	return err
+}
+
+// Audit log methods
+
+func (db *PostgresDB) CreateAuditLog(log *AuditLog) error {
+	query := `INSERT INTO audit_logs
+		("timestamp", admin_user_id, admin_email, operation_type, target_user_id, target_user_email,
+		 operation_details, ip_address, result, error_message)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+	return db.QueryRow(query, log.Timestamp, log.AdminUserID, log.AdminEmail, log.OperationType,
+		log.TargetUserID, log.TargetUserEmail, pgText(log.OperationDetails), log.IPAddress, log.Result,
+		pgText(log.ErrorMessage)).Scan(&log.ID)
+}
+
+func (db *PostgresDB) GetAuditLogs(limit, offset int, filters map[string]interface{}) ([]AuditLog, error) {
+	query := `SELECT id, "timestamp", COALESCE(admin_user_id, 0), admin_email, operation_type,
+		COALESCE(target_user_id, 0), COALESCE(target_user_email, ''), COALESCE(operation_details, ''),
+		COALESCE(ip_address, ''), result, COALESCE(error_message, '')
+		FROM audit_logs WHERE TRUE`
+	var args []interface{}
+	for _, column := range []string{"admin_user_id", "target_user_id", "operation_type"} {
+		if value, ok := filters[column]; ok {
+			args = append(args, value)
+			query += fmt.Sprintf(" AND %s = $%d", column, len(args))
+		}
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(` ORDER BY "timestamp" DESC, id DESC LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var logs []AuditLog
+	for rows.Next() {
+		var l AuditLog
+		if err := rows.Scan(&l.ID, &l.Timestamp, &l.AdminUserID, &l.AdminEmail, &l.OperationType,
+			&l.TargetUserID, &l.TargetUserEmail, &l.OperationDetails, &l.IPAddress, &l.Result,
+			&l.ErrorMessage); err != nil {
+			return nil, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, rows.Err()
+}
+
+// Annotation methods
+
+func (db *PostgresDB) CreateAnnotation(annotation *Annotation) error {
+	query := `INSERT INTO annotations
+		(user_id, article_id, quoted_text, start_offset, end_offset, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`
+	return db.QueryRow(query, annotation.UserID, annotation.ArticleID, pgText(annotation.QuotedText),
+		annotation.StartOffset, annotation.EndOffset, pgText(annotation.Note),
+		annotation.CreatedAt, annotation.UpdatedAt).Scan(&annotation.ID)
+}
+
+const pgAnnotationColumns = `id, user_id, article_id, quoted_text, start_offset, end_offset,
+	COALESCE(note, ''), created_at, updated_at`
+
+func (db *PostgresDB) GetAnnotation(userID, annotationID int) (*Annotation, error) {
+	annotations, err := db.queryAnnotations(`SELECT `+pgAnnotationColumns+`
+		FROM annotations WHERE id = $1 AND user_id = $2`, annotationID, userID)
+	if err != nil || len(annotations) == 0 {
+		return nil, err
+	}
+	return &annotations[0], nil
+}
+
+func (db *PostgresDB) GetArticleAnnotations(userID, articleID int) ([]Annotation, error) {
+	return db.queryAnnotations(`SELECT `+pgAnnotationColumns+`
+		FROM annotations WHERE user_id = $1 AND article_id = $2
+		ORDER BY start_offset, id`, userID, articleID)
+}
+
+func (db *PostgresDB) GetUserAnnotations(userID int) ([]Annotation, error) {
+	return db.queryAnnotations(`SELECT `+pgAnnotationColumns+`
+		FROM annotations WHERE user_id = $1
+		ORDER BY article_id, start_offset, id`, userID)
+}
+
+func (db *PostgresDB) queryAnnotations(query string, args ...interface{}) ([]Annotation, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	annotations := []Annotation{}
+	for rows.Next() {
+		var a Annotation
+		if err := rows.Scan(&a.ID, &a.UserID, &a.ArticleID, &a.QuotedText,
+			&a.StartOffset, &a.EndOffset, &a.Note, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, rows.Err()
+}
+
+func (db *PostgresDB) UpdateAnnotation(annotation *Annotation) error {
+	_, err := db.Exec(`UPDATE annotations
+		SET quoted_text = $1, start_offset = $2, end_offset = $3, note = $4, updated_at = $5
+		WHERE id = $6 AND user_id = $7`, pgText(annotation.QuotedText), annotation.StartOffset, annotation.EndOffset,
+		pgText(annotation.Note), annotation.UpdatedAt, annotation.ID, annotation.UserID)
+	return err
+}
+
+func (db *PostgresDB) DeleteAnnotation(userID, annotationID int) error {
+	_, err := db.Exec(`DELETE FROM annotations WHERE id = $1 AND user_id = $2`, annotationID, userID)
+	return err
+}
+
+// Tag methods
+
+func (db *PostgresDB) CreateTag(tag *Tag) error {
+	err := db.QueryRow(`INSERT INTO tags (user_id, name, created_at) VALUES ($1, $2, $3) RETURNING id`,
+		tag.UserID, pgText(tag.Name), tag.CreatedAt).Scan(&tag.ID)
+	if isUniqueViolation(err) {
+		return ErrTagExists
+	}
+	return err
+}
+
+func (db *PostgresDB) GetTag(userID, tagID int) (*Tag, error) {
+	return db.getTag(`SELECT id, user_id, name, created_at FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID)
+}
+
+func (db *PostgresDB) GetTagByName(userID int, name string) (*Tag, error) {
+	return db.getTag(`SELECT id, user_id, name, created_at FROM tags WHERE user_id = $1 AND name = $2`, userID, name)
+}
+
+func (db *PostgresDB) getTag(query string, args ...interface{}) (*Tag, error) {
+	var tag Tag
+	err := db.QueryRow(query, args...).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (db *PostgresDB) GetUserTags(userID int) ([]Tag, error) {
+	rows, err := db.Query(`SELECT t.id, t.user_id, t.name, t.created_at, COUNT(atg.article_id)
+		FROM tags t
+		LEFT JOIN article_tags atg ON atg.tag_id = t.id
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY t.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.ArticleCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (db *PostgresDB) RenameTag(userID, tagID int, name string) error {
+	_, err := db.Exec(`UPDATE tags SET name = $1 WHERE id = $2 AND user_id = $3`, pgText(name), tagID, userID)
+	if isUniqueViolation(err) {
+		return ErrTagExists
+	}
+	return err
+}
+
+func (db *PostgresDB) DeleteTag(userID, tagID int) error {
+	// Foreign keys are always enforced here, so taggings go with the tag
+	_, err := db.Exec(`DELETE FROM tags WHERE id = $1 AND user_id = $2`, tagID, userID)
+	return err
+}
+
+func (db *PostgresDB) TagArticle(userID, articleID, tagID int) error {
+	_, err := db.Exec(`INSERT INTO article_tags (user_id, article_id, tag_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`, userID, articleID, tagID, time.Now())
+	return err
+}
+
+func (db *PostgresDB) UntagArticle(userID, articleID, tagID int) error {
+	_, err := db.Exec(`DELETE FROM article_tags WHERE user_id = $1 AND article_id = $2 AND tag_id = $3`,
+		userID, articleID, tagID)
+	return err
+}
+
+func (db *PostgresDB) GetArticleTags(userID int, articleIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	if len(articleIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.Query(`SELECT atg.article_id, t.name
+		FROM article_tags atg
+		JOIN tags t ON t.id = atg.tag_id
+		WHERE atg.user_id = $1 AND atg.article_id = ANY($2)
+		ORDER BY t.name`, userID, pgIDs(articleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var articleID int
+		var name string
+		if err := rows.Scan(&articleID, &name); err != nil {
+			return nil, err
+		}
+		result[articleID] = append(result[articleID], name)
+	}
+	return result, rows.Err()
+}
+
+func (db *PostgresDB) GetTagArticlesPaginated(userID, tagID int, limit int, cursor string) (*ArticlePaginationResult, error) {
+	args := []interface{}{userID, tagID}
+	query := `SELECT ` + pgUserArticleColumns + `
+		FROM article_tags atg
+		JOIN articles a ON a.id = atg.article_id
+		JOIN feeds f ON a.feed_id = f.id
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = $1
+		WHERE atg.user_id = $1 AND atg.tag_id = $2`
+	if cursor != "" {
+		cursorData, err := decodeSQLiteCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		args = append(args, cursorData.PublishedAt, cursorData.ID)
+		query += ` AND (a.published_at, a.id) < ($3, $4)`
+	}
+	args = append(args, limit+paginationOverfetch)
+	query += fmt.Sprintf(` ORDER BY a.published_at DESC, a.id DESC LIMIT $%d`, len(args))
+
+	articles, err := db.queryUserArticles(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if articles == nil {
+		articles = []Article{}
+	}
+	return paginateArticles(articles, limit), nil
+}
+
+// Undo methods
+
+func (db *PostgresDB) GetUserArticleStatuses(userID int, articleIDs []int) (map[int]UserArticle, error) {
+	statuses := make(map[int]UserArticle)
+	if len(articleIDs) == 0 {
+		return statuses, nil
+	}
+
+	rows, err := db.Query(`SELECT user_id, article_id, is_read, is_starred, read_progress FROM user_articles
+		WHERE user_id = $1 AND article_id = ANY($2)`, userID, pgIDs(articleIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var ua UserArticle
+		if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress); err != nil {
+			return nil, err
+		}
+		statuses[ua.ArticleID] = ua
+	}
+	return statuses, rows.Err()
+}
+
+func (db *PostgresDB) CreateUndoOperation(op *UndoOperation) error {
+	changes, err := encodeStatusChanges(op.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode undo changes: %w", err)
+	}
+	_, err = db.Exec(`INSERT INTO undo_operations (id, user_id, kind, changes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`, op.ID, op.UserID, op.Kind, changes, op.CreatedAt, op.ExpiresAt)
+	return err
+}
+
+func (db *PostgresDB) ConsumeUndoOperation(userID int, operationID string) (*UndoOperation, error) {
+	var op UndoOperation
+	var changes string
+	err := db.QueryRow(`DELETE FROM undo_operations WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, kind, changes, created_at, expires_at`, operationID, userID).Scan(
+		&op.ID, &op.UserID, &op.Kind, &changes, &op.CreatedAt, &op.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	op.Changes, err = decodeStatusChanges(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode undo changes: %w", err)
+	}
+	return &op, nil
+}
+
+func (db *PostgresDB) DeleteExpiredUndoOperations(before time.Time) (int, error) {
+	result, err := db.Exec(`DELETE FROM undo_operations WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Sync methods
+
+func (db *PostgresDB) GetUserSyncVersion(userID int) (int64, error) {
+	var version int64
+	err := db.QueryRow(`SELECT version FROM user_sync_state WHERE user_id = $1`, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (db *PostgresDB) GetUserArticleChanges(userID int, sinceVersion int64, limit int) ([]UserArticle, error) {
+	rows, err := db.Query(`SELECT user_id, article_id, is_read, is_starred, read_progress, version, updated_at
+		FROM user_articles
+		WHERE user_id = $1 AND version > $2
+		ORDER BY version
+		LIMIT $3`, userID, sinceVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []UserArticle{}
+	for rows.Next() {
+		var ua UserArticle
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress, &ua.Version, &updatedAt); err != nil {
+			return nil, err
+		}
+		ua.UpdatedAt = updatedAt.Time
+		changes = append(changes, ua)
+	}
+	return changes, rows.Err()
+}
+
+func (db *PostgresDB) GetUserFeedChanges(userID int, sinceVersion int64) ([]FeedSubscriptionChange, error) {
+	rows, err := db.Query(`SELECT feed_id, subscribed, version FROM user_feed_changes
+		WHERE user_id = $1 AND version > $2
+		ORDER BY version`, userID, sinceVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []FeedSubscriptionChange{}
+	for rows.Next() {
+		var c FeedSubscriptionChange
+		if err := rows.Scan(&c.FeedID, &c.Subscribed, &c.Version); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (db *PostgresDB) GetNewUserArticles(userID int, after ArticlePosition, limit int) ([]Article, error) {
+	rows, err := db.Query(`SELECT a.id, a.feed_id, f.title, a.title, a.url, a.content, a.description, a.author,
+			a.published_at, a.created_at, a.reading_time_minutes,
+			COALESCE(ua.is_read, FALSE), COALESCE(ua.is_starred, FALSE), COALESCE(ua.read_progress, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = $1
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = $1
+		WHERE (a.created_at, a.id) > ($2, $3)
+		ORDER BY a.created_at, a.id
+		LIMIT $4`, userID, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	articles := []Article{}
+	for rows.Next() {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes,
+			&a.IsRead, &a.IsStarred, &a.ReadProgress); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+func (db *PostgresDB) ApplyUserArticleChange(userID int, change UserArticleChange) (bool, error) {
+	// Values for a row that doesn't exist yet
+	isRead, isStarred, progress := false, false, 0
+	var sets []string
+	if change.IsRead != nil {
+		isRead = *change.IsRead
+		sets = append(sets, "is_read = EXCLUDED.is_read")
+	}
+	if change.IsStarred != nil {
+		isStarred = *change.IsStarred
+		sets = append(sets, "is_starred = EXCLUDED.is_starred")
+	}
+	if change.ReadProgress != nil {
+		progress = *change.ReadProgress
+		sets = append(sets, "read_progress = EXCLUDED.read_progress")
+	}
+	if len(sets) == 0 {
+		return false, nil
+	}
+
+	query := `INSERT INTO user_articles (user_id, article_id, is_read, is_starred, read_progress, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, article_id) DO UPDATE SET ` + strings.Join(sets, ", ") + `, updated_at = EXCLUDED.updated_at
+		WHERE user_articles.updated_at IS NULL OR user_articles.updated_at <= EXCLUDED.updated_at`
+	result, err := db.Exec(query, userID, change.ArticleID, isRead, isStarred, progress, change.At)
+	if err != nil {
+		return false, fmt.Errorf("failed to apply article change: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Webhook methods
+
+func (db *PostgresDB) CreateWebhook(webhook *Webhook) error {
+	return db.QueryRow(`INSERT INTO webhooks (user_id, url, secret, feed_id, keywords, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id`, webhook.UserID, webhook.URL, webhook.Secret, webhook.FeedID,
+		pgText(joinKeywords(webhook.Keywords)), webhook.Enabled, webhook.CreatedAt, webhook.UpdatedAt).Scan(&webhook.ID)
+}
+
+func (db *PostgresDB) GetWebhook(userID, webhookID int) (*Webhook, error) {
+	webhook, err := scanWebhook(db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND user_id = $2`,
+		webhookID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return webhook, err
+}
+
+func (db *PostgresDB) GetUserWebhooks(userID int) ([]Webhook, error) {
+	return db.queryWebhooks(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id`, userID)
+}
+
+func (db *PostgresDB) GetFeedWebhooks(feedID int) ([]Webhook, error) {
+	return db.queryWebhooks(`SELECT w.id, w.user_id, w.url, w.secret, w.feed_id, w.keywords, w.enabled, w.created_at, w.updated_at
+		FROM webhooks w
+		JOIN user_feeds uf ON uf.user_id = w.user_id AND uf.feed_id = $1
+		WHERE w.enabled AND (w.feed_id = 0 OR w.feed_id = $1)
+		ORDER BY w.id`, feedID)
+}
+
+func (db *PostgresDB) queryWebhooks(query string, args ...interface{}) ([]Webhook, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *PostgresDB) UpdateWebhook(webhook *Webhook) error {
+	_, err := db.Exec(`UPDATE webhooks SET url = $1, secret = $2, feed_id = $3, keywords = $4, enabled = $5, updated_at = $6
+		WHERE id = $7 AND user_id = $8`, webhook.URL, webhook.Secret, webhook.FeedID, pgText(joinKeywords(webhook.Keywords)),
+		webhook.Enabled, webhook.UpdatedAt, webhook.ID, webhook.UserID)
+	return err
+}
+
+func (db *PostgresDB) DeleteWebhook(userID, webhookID int) error {
+	// Deliveries are removed by their foreign key
+	_, err := db.Exec(`DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, webhookID, userID)
+	return err
+}
+
+func (db *PostgresDB) CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	return db.QueryRow(`INSERT INTO webhook_deliveries
+		(webhook_id, user_id, event_id, event, article_id, attempt, status_code, success, error, duration_ms, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id`, delivery.WebhookID, delivery.UserID, delivery.EventID, delivery.Event,
+		delivery.ArticleID, delivery.Attempt, delivery.StatusCode, delivery.Success, pgText(delivery.Error),
+		delivery.DurationMS, delivery.CreatedAt).Scan(&delivery.ID)
+}
+
+func (db *PostgresDB) GetWebhookDeliveries(userID, webhookID, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.Query(`SELECT id, webhook_id, user_id, event_id, event, article_id, attempt, status_code, success, error, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND user_id = $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`, webhookID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.EventID, &d.Event, &d.ArticleID, &d.Attempt,
+			&d.StatusCode, &d.Success, &d.Error, &d.DurationMS, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (db *PostgresDB) DeleteWebhookDeliveriesBefore(before time.Time) (int, error) {
+	result, err := db.Exec(`DELETE FROM webhook_deliveries WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Inbound email methods
+
+func (db *PostgresDB) CreateInboundAddress(address *InboundAddress, feed *Feed) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var feedID, id int
+	err = tx.QueryRow(pgInsertFeedQuery, pgText(feed.Title), feed.URL, pgText(feed.Description),
+		feed.CreatedAt, feed.UpdatedAt, feed.LastFetch, feed.LastChecked, feed.LastHadNewContent, feed.AverageUpdateInterval,
+		feed.ETag, feed.LastModified).Scan(&feedID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO user_feeds (user_id, feed_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, address.UserID, feedID); err != nil {
+		return err
+	}
+	err = tx.QueryRow(`INSERT INTO inbound_addresses (user_id, feed_id, token, created_at) VALUES ($1, $2, $3, $4)
+		RETURNING id`, address.UserID, feedID, address.Token, address.CreatedAt).Scan(&id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	feed.ID = feedID
+	address.FeedID = feedID
+	address.ID = id
+	return nil
+}
+
+func (db *PostgresDB) GetInboundAddressByToken(token string) (*InboundAddress, error) {
+	var a InboundAddress
+	err := db.QueryRow(`SELECT id, user_id, feed_id, token, created_at FROM inbound_addresses WHERE token = $1`,
+		token).Scan(&a.ID, &a.UserID, &a.FeedID, &a.Token, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *PostgresDB) GetUserInboundAddresses(userID int) ([]InboundAddress, error) {
+	rows, err := db.Query(`SELECT id, user_id, feed_id, token, created_at FROM inbound_addresses
+		WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	addresses := []InboundAddress{}
+	for rows.Next() {
+		var a InboundAddress
+		if err := rows.Scan(&a.ID, &a.UserID, &a.FeedID, &a.Token, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (db *PostgresDB) DeleteInboundAddress(userID, addressID int) error {
+	_, err := db.Exec(`DELETE FROM inbound_addresses WHERE id = $1 AND user_id = $2`, addressID, userID)
+	return err
+}
+
+// Directory methods
+
+func (db *PostgresDB) GetAllFeedSubscriptions() ([]FeedSubscription, error) {
+	rows, err := db.Query(`SELECT user_id, feed_id FROM user_feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var subscriptions []FeedSubscription
+	for rows.Next() {
+		var s FeedSubscription
+		if err := rows.Scan(&s.UserID, &s.FeedID); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (db *PostgresDB) GetFeedCategories() (map[int]string, error) {
+	rows, err := db.Query(`SELECT feed_id, category FROM feed_categories`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	categories := make(map[int]string)
+	for rows.Next() {
+		var feedID int
+		var category string
+		if err := rows.Scan(&feedID, &category); err != nil {
+			return nil, err
+		}
+		categories[feedID] = category
+	}
+	return categories, rows.Err()
+}
+
+func (db *PostgresDB) SetFeedCategory(feedID int, category string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if category == "" {
+		_, err = tx.Exec(`DELETE FROM feed_categories WHERE feed_id = $1`, feedID)
+	} else {
+		_, err = tx.Exec(`INSERT INTO feed_categories (feed_id, category) VALUES ($1, $2)
+			ON CONFLICT (feed_id) DO UPDATE SET category = EXCLUDED.category`, feedID, pgText(category))
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE directory_feeds SET category = $1 WHERE feed_id = $2`, pgText(category), feedID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *PostgresDB) ReplaceFeedDirectory(feeds []DirectoryFeed, recommendations []FeedRecommendation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM directory_feeds`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM feed_recommendations`); err != nil {
+		return err
+	}
+
+	feedStmt, err := tx.Prepare(`INSERT INTO directory_feeds (feed_id, title, url, description, category, subscribers, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = feedStmt.Close() }()
+	for _, f := range feeds {
+		if _, err := feedStmt.Exec(f.FeedID, pgText(f.Title), f.URL, pgText(f.Description), pgText(f.Category), f.Subscribers, f.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	recStmt, err := tx.Prepare(`INSERT INTO feed_recommendations (feed_id, recommended_feed_id, shared_subscribers, score)
+		VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = recStmt.Close() }()
+	for _, r := range recommendations {
+		if _, err := recStmt.Exec(r.FeedID, r.RecommendedFeedID, r.SharedSubscribers, r.Score); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *PostgresDB) GetDirectoryFeeds() ([]DirectoryFeed, error) {
+	rows, err := db.Query(`SELECT feed_id, title, url, description, category, subscribers, updated_at
+		FROM directory_feeds ORDER BY subscribers DESC, title, feed_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	feeds := []DirectoryFeed{}
+	for rows.Next() {
+		var f DirectoryFeed
+		if err := rows.Scan(&f.FeedID, &f.Title, &f.URL, &f.Description, &f.Category, &f.Subscribers, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+func (db *PostgresDB) GetFeedRecommendations(feedIDs []int) ([]FeedRecommendation, error) {
+	recommendations := []FeedRecommendation{}
+	if len(feedIDs) == 0 {
+		return recommendations, nil
+	}
+
+	rows, err := db.Query(`SELECT feed_id, recommended_feed_id, shared_subscribers, score
+		FROM feed_recommendations WHERE feed_id = ANY($1)
+		ORDER BY feed_id, score DESC, recommended_feed_id`, pgIDs(feedIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var r FeedRecommendation
+		if err := rows.Scan(&r.FeedID, &r.RecommendedFeedID, &r.SharedSubscribers, &r.Score); err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, r)
+	}
+	return recommendations, rows.Err()
+}
+
+func (db *PostgresDB) ForEachUser(fn func(User) error) error {
+	return forEachRow(db.DB, `SELECT `+pgUserColumns+` FROM users ORDER BY id`, func(rows *sql.Rows) error {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		return fn(*user)
+	})
+}
+
+func (db *PostgresDB) ForEachArticle(fn func(Article) error) error {
+	return forEachRow(db.DB, `SELECT id, feed_id, title, url, content, description, author,
+		published_at, created_at, reading_time_minutes
+		FROM articles ORDER BY id`, func(rows *sql.Rows) error {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes); err != nil {
+			return err
+		}
+		return fn(a)
+	})
+}
+
+func (db *PostgresDB) ForEachUserArticle(fn func(UserArticle) error) error {
+	return forEachRow(db.DB, `SELECT user_id, article_id, is_read, is_starred, read_progress
+		FROM user_articles ORDER BY user_id, article_id`, func(rows *sql.Rows) error {
+		var ua UserArticle
+		if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress); err != nil {
+			return err
+		}
+		return fn(ua)
+	})
+}
+
+func (db *PostgresDB) ForEachSession(fn func(Session) error) error {
+	return forEachRow(db.DB, `SELECT `+sessionColumns+` FROM sessions ORDER BY created_at, id`, func(rows *sql.Rows) error {
+		session, err := scanSession(rows)
+		if err != nil {
+			return err
+		}
+		return fn(session)
+	})
+}
+
+func (db *PostgresDB) ForEachUserIdentity(fn func(UserIdentity) error) error {
+	return forEachRow(db.DB, `SELECT user_id, provider, subject, email, created_at FROM user_identities ORDER BY created_at, provider, subject`, func(rows *sql.Rows) error {
+		var identity UserIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return err
+		}
+		return fn(identity)
+	})
+}
@@ -2,10 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,6 +16,9 @@ import (
 
 var ErrSelfDemotion = errors.New("cannot remove your own admin privileges")
 
+// ErrTagExists is returned when creating or renaming a tag to a name the user already has.
+var ErrTagExists = errors.New("tag already exists")
+
 // paginationOverfetch is added to the requested limit to detect whether more results exist.
 // If len(results) > requested limit, there is a next page; results are then trimmed to limit.
 const paginationOverfetch = 1
@@ -33,6 +38,14 @@ type Database interface {
 	IsUserSubscriptionActive(userID int) (bool, error)
 	GetUserFeedCount(userID int) (int, error)
 	UpdateUserMaxArticlesOnFeedAdd(userID int, maxArticles int) error
+	UpdateUserDigestPreferences(userID int, prefs DigestPreferences) error
+	GetDigestUsers() ([]User, error)
+	SetUserDigestSentAt(userID int, sentAt time.Time) error
+	// DeleteUser removes the user and everything they own: sessions, linked
+	// identities, subscriptions, article statuses, tags, annotations,
+	// webhooks, inbound addresses and sync history. Feeds, articles and audit
+	// logs stay.
+	DeleteUser(userID int) error
 
 	// Admin methods
 	SetUserAdmin(userID int, isAdmin bool) error
@@ -63,15 +76,25 @@ type Database interface {
 	GetUserFeedArticles(userID, feedID int) ([]Article, error)
 	GetUserFeedArticlesPaginated(userID, feedID int, limit int, cursor string, unreadOnly bool) (*ArticlePaginationResult, error)
 	GetArticleByID(userID, articleID int) (*Article, error)
+	// GetArticlesByIDs returns the articles among articleIDs the user can
+	// see, keyed by ID, with their feed titles and without content or status.
+	GetArticlesByIDs(userID int, articleIDs []int) (map[int]Article, error)
+	// GetUserArticleHistory returns the articles the user has read, starred or
+	// started, newest first, with their status and without content.
+	GetUserArticleHistory(userID int) ([]Article, error)
 
 	// User article status methods
 	GetUserArticleStatus(userID, articleID int) (*UserArticle, error)
 	SetUserArticleStatus(userID, articleID int, isRead, isStarred bool) error
+	SetUserArticleProgress(userID, articleID, progress int) error
 	BatchSetUserArticleStatus(userID int, articles []Article, isRead, isStarred bool) error
 	MarkAllUserArticlesRead(userID int) (int, error)
+	MarkUserArticlesReadScoped(userID int, scope MarkReadScope) (*MarkReadResult, error)
+	UpdateUserArticleStatuses(userID int, updates []ArticleStatusUpdate) (*StatusUpdateResult, error)
 	MarkUserArticleRead(userID, articleID int, isRead bool) error
 	ToggleUserArticleStar(userID, articleID int) error
 	GetUserUnreadCounts(userID int) (map[int]int, error)
+	GetUserStarredCountsByFeed(userID int) (map[int]int, error)
 	GetTotalArticleCount(userID int) (int, error)
 	GetAccountStats(userID int) (map[string]interface{}, error)
 	CleanupOrphanedUserArticles(olderThanDays int) (int, error)
@@ -80,13 +103,109 @@ type Database interface {
 	CreateSession(session *Session) error
 	GetSession(sessionID string) (*Session, error)
 	UpdateSessionExpiry(sessionID string, newExpiry time.Time) error
+	// UpdateSessionActivity records when and from where a session was last
+	// used. It is not an error if the session no longer exists.
+	UpdateSessionActivity(sessionID string, lastSeenAt time.Time, ipAddress string) error
 	DeleteSession(sessionID string) error
+	GetUserSessions(userID int) ([]Session, error)
 	DeleteExpiredSessions() error
 
+	// Identity methods link sign-ins from each provider to a user.
+	// GetUserIdentity returns nil when the identity isn't linked.
+	CreateUserIdentity(identity *UserIdentity) error
+	GetUserIdentity(provider, subject string) (*UserIdentity, error)
+	GetUserIdentities(userID int) ([]UserIdentity, error)
+
+	// Login token methods back email sign-in links. ConsumeLoginToken
+	// deletes the token and returns it, or nil when it doesn't exist, so
+	// each link works once.
+	CreateLoginToken(token *LoginToken) error
+	ConsumeLoginToken(tokenHash string) (*LoginToken, error)
+	DeleteExpiredLoginTokens() error
+
+	// Refresh token methods back mobile sign-ins. UseRefreshToken marks a
+	// token used and returns it as it was before, so UsedAt is zero the
+	// first time it's redeemed and set on any later attempt; it returns
+	// nil when the token doesn't exist.
+	CreateRefreshToken(token *RefreshToken) error
+	UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error)
+	DeleteExpiredRefreshTokens() error
+
 	// Audit log methods
 	CreateAuditLog(log *AuditLog) error
 	GetAuditLogs(limit, offset int, filters map[string]interface{}) ([]AuditLog, error)
 
+	// Annotation methods
+	CreateAnnotation(annotation *Annotation) error
+	GetAnnotation(userID, annotationID int) (*Annotation, error)
+	GetArticleAnnotations(userID, articleID int) ([]Annotation, error)
+	GetUserAnnotations(userID int) ([]Annotation, error)
+	UpdateAnnotation(annotation *Annotation) error
+	DeleteAnnotation(userID, annotationID int) error
+
+	// Tag methods
+	CreateTag(tag *Tag) error
+	GetTag(userID, tagID int) (*Tag, error)
+	GetTagByName(userID int, name string) (*Tag, error)
+	GetUserTags(userID int) ([]Tag, error)
+	RenameTag(userID, tagID int, name string) error
+	DeleteTag(userID, tagID int) error
+	TagArticle(userID, articleID, tagID int) error
+	UntagArticle(userID, articleID, tagID int) error
+	GetArticleTags(userID int, articleIDs []int) (map[int][]string, error)
+	GetTagArticlesPaginated(userID, tagID int, limit int, cursor string) (*ArticlePaginationResult, error)
+
+	// Webhook methods
+	CreateWebhook(webhook *Webhook) error
+	GetWebhook(userID, webhookID int) (*Webhook, error)
+	GetUserWebhooks(userID int) ([]Webhook, error)
+	UpdateWebhook(webhook *Webhook) error
+	DeleteWebhook(userID, webhookID int) error
+	GetFeedWebhooks(feedID int) ([]Webhook, error)
+	CreateWebhookDelivery(delivery *WebhookDelivery) error
+	GetWebhookDeliveries(userID, webhookID, limit int) ([]WebhookDelivery, error)
+	DeleteWebhookDeliveriesBefore(before time.Time) (int, error)
+
+	// Inbound email methods
+	// CreateInboundAddress creates feed, subscribes address.UserID to it and
+	// stores address for it, all or nothing. It sets feed.ID, address.FeedID
+	// and address.ID.
+	CreateInboundAddress(address *InboundAddress, feed *Feed) error
+	GetInboundAddressByToken(token string) (*InboundAddress, error)
+	GetUserInboundAddresses(userID int) ([]InboundAddress, error)
+	DeleteInboundAddress(userID, addressID int) error
+
+	// Directory methods
+	GetAllFeedSubscriptions() ([]FeedSubscription, error)
+	GetFeedCategories() (map[int]string, error)
+	SetFeedCategory(feedID int, category string) error
+	ReplaceFeedDirectory(feeds []DirectoryFeed, recommendations []FeedRecommendation) error
+	GetDirectoryFeeds() ([]DirectoryFeed, error)
+	GetFeedRecommendations(feedIDs []int) ([]FeedRecommendation, error)
+
+	// Undo methods
+	GetUserArticleStatuses(userID int, articleIDs []int) (map[int]UserArticle, error)
+	CreateUndoOperation(op *UndoOperation) error
+	// ConsumeUndoOperation deletes the user's operation and returns it, or
+	// nil if there is none, so each operation is handed out only once.
+	ConsumeUndoOperation(userID int, operationID string) (*UndoOperation, error)
+	DeleteExpiredUndoOperations(before time.Time) (int, error)
+
+	// Sync methods
+	GetUserSyncVersion(userID int) (int64, error)
+	GetUserArticleChanges(userID int, sinceVersion int64, limit int) ([]UserArticle, error)
+	GetUserFeedChanges(userID int, sinceVersion int64) ([]FeedSubscriptionChange, error)
+	GetNewUserArticles(userID int, after ArticlePosition, limit int) ([]Article, error)
+	ApplyUserArticleChange(userID int, change UserArticleChange) (bool, error)
+
+	// Export methods stream every row of one kind, for moving a deployment
+	// between backends. An error from fn stops the walk and is returned.
+	ForEachUser(fn func(User) error) error
+	ForEachArticle(fn func(Article) error) error
+	ForEachUserArticle(fn func(UserArticle) error) error
+	ForEachSession(fn func(Session) error) error
+	ForEachUserIdentity(fn func(UserIdentity) error) error
+
 	UpdateFeedLastFetch(feedID int, lastFetch time.Time) error
 	UpdateFeedAfterRefresh(feedID int, lastChecked, lastHadNewContent time.Time, averageUpdateInterval int, lastFetch time.Time, etag, lastModified string) error
 	Close() error
@@ -111,6 +230,26 @@ type User struct {
 	IsAdmin              bool      `json:"is_admin"`                 // Admin users bypass subscription limits
 	FreeMonthsRemaining  int       `json:"free_months_remaining"`    // Additional free months granted
 	MaxArticlesOnFeedAdd int       `json:"max_articles_on_feed_add"` // Max articles to import when adding a new feed (0 = unlimited)
+	DigestFrequency      string    `json:"digest_frequency"`         // 'daily', 'weekly', or empty when digests are off
+	DigestTimezone       string    `json:"digest_timezone"`          // IANA zone for DigestHour; empty means UTC
+	DigestHour           int       `json:"digest_hour"`              // Local hour (0-23) to send the digest
+	DigestWeekday        int       `json:"digest_weekday"`           // Day for weekly digests (0 = Sunday)
+	DigestLastSentAt     time.Time `json:"digest_last_sent_at"`
+}
+
+// NormalizeEmail is the form email addresses are stored and looked up in.
+// Mail providers treat addresses case-insensitively, so the same person
+// signing in through different providers must land on one account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// DigestPreferences are the user-editable digest settings on User.
+type DigestPreferences struct {
+	Frequency string
+	Timezone  string
+	Hour      int
+	Weekday   int
 }
 
 type Feed struct {
@@ -141,6 +280,10 @@ type Article struct {
 	CreatedAt   time.Time `json:"created_at"`
 	IsRead      bool      `json:"is_read"`
 	IsStarred   bool      `json:"is_starred"`
+	Tags        []string  `json:"tags,omitempty"` // Names of the requesting user's tags on this article
+
+	ReadingTimeMinutes int `json:"reading_time_minutes"` // Estimated at ingest; 0 for articles stored before estimates existed
+	ReadProgress       int `json:"read_progress"`        // Requesting user's scroll position, 0-100
 }
 
 type UserFeed struct {
@@ -149,17 +292,61 @@ type UserFeed struct {
 }
 
 type UserArticle struct {
-	UserID    int  `json:"user_id"`
-	ArticleID int  `json:"article_id"`
-	IsRead    bool `json:"is_read"`
-	IsStarred bool `json:"is_starred"`
+	UserID       int  `json:"user_id"`
+	ArticleID    int  `json:"article_id"`
+	IsRead       bool `json:"is_read"`
+	IsStarred    bool `json:"is_starred"`
+	ReadProgress int  `json:"read_progress"`
+
+	// Populated by GetUserArticleChanges
+	Version   int64     `json:"version"`    // User's change counter at the last change to this row
+	UpdatedAt time.Time `json:"updated_at"` // When the last change was made (client time for synced actions)
 }
 
+// Session is a signed-in device. UserAgent, ClientType and IPAddress
+// describe the device at sign-in; IPAddress and LastSeenAt are updated as
+// it's used, at most every few minutes.
 type Session struct {
-	ID        string    `json:"id"`
+	ID         string    `json:"id"`
+	UserID     int       `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	ClientType string    `json:"client_type"`
+	IPAddress  string    `json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// UserIdentity links an account at a sign-in provider to a user. Subject is
+// the provider's stable ID for the account; Email is what the provider
+// reported when the link was made.
+type UserIdentity struct {
 	UserID    int       `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
-	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginToken is a pending email sign-in. Only the SHA-256 hash of the token
+// sent in the link is stored. Mobile marks links requested by the iOS app.
+type LoginToken struct {
+	TokenHash string
+	Email     string
+	Mobile    bool
+	ExpiresAt time.Time
+}
+
+// RefreshToken lets a mobile client get new access tokens for a session.
+// Only the SHA-256 hash of the token is stored. Each one works once: using
+// it issues its replacement, and UsedAt records when that happened.
+type RefreshToken struct {
+	TokenHash string
+	SessionID string
+	UserID    int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    time.Time
 }
 
 type AuditLog struct {
@@ -176,21 +363,268 @@ type AuditLog struct {
 	ErrorMessage     string    `json:"error_message"`
 }
 
-func InitDB() (Database, error) {
-	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); projectID != "" {
-		return NewDatastoreDB(projectID)
+// Annotation is a user's highlight on an article, optionally with a note.
+// StartOffset and EndOffset are character (rune) offsets into the article's
+// sanitised Content, with EndOffset exclusive.
+type Annotation struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	ArticleID   int       `json:"article_id"`
+	QuotedText  string    `json:"quoted_text"`
+	StartOffset int       `json:"start_offset"`
+	EndOffset   int       `json:"end_offset"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Tag is a user-defined label that can be applied to any number of articles.
+// ArticleCount is populated by GetUserTags.
+type Tag struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	Name         string    `json:"name"`
+	CreatedAt    time.Time `json:"created_at"`
+	ArticleCount int       `json:"article_count"`
+}
+
+// Webhook posts new articles from a user's feeds to an external URL. FeedID 0
+// covers all of the user's feeds; when Keywords is non-empty an article must
+// contain at least one of them.
+type Webhook struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"` // HMAC key for payload signatures
+	FeedID    int       `json:"feed_id"`
+	Keywords  []string  `json:"keywords"`
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook.
+// Retries of the same event share an EventID.
+type WebhookDelivery struct {
+	ID         int       `json:"id"`
+	WebhookID  int       `json:"webhook_id"`
+	UserID     int       `json:"-"`
+	EventID    string    `json:"event_id"`
+	Event      string    `json:"event"`
+	ArticleID  int       `json:"article_id,omitempty"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// InboundAddress routes email sent to <Token>@<inbound domain> into FeedID, a
+// synthetic feed created for the address. Address is filled in by the service
+// from the configured domain and isn't stored.
+type InboundAddress struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	FeedID    int       `json:"feed_id"`
+	Token     string    `json:"-"`
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FeedSubscription is one user's subscription to one feed.
+type FeedSubscription struct {
+	UserID int
+	FeedID int
+}
+
+// DirectoryFeed is a feed listed in the feed directory, as of the last
+// directory job (UpdatedAt). Category is assigned by an admin and may be empty.
+type DirectoryFeed struct {
+	FeedID      int       `json:"feed_id"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Subscribers int       `json:"subscribers"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// FeedRecommendation says that subscribers of FeedID also tend to subscribe
+// to RecommendedFeedID. SharedSubscribers is how many subscribe to both and
+// Score how strongly the two are related, from 0 to 1.
+type FeedRecommendation struct {
+	FeedID            int
+	RecommendedFeedID int
+	SharedSubscribers int
+	Score             float64
+}
+
+// ArticleStatusChange is one article's status before a bulk change, and which
+// of its flags the change set. An article with no user_articles row is
+// recorded as unread and unstarred.
+type ArticleStatusChange struct {
+	ArticleID      int
+	FeedID         int
+	WasRead        bool
+	WasStarred     bool
+	ReadChanged    bool
+	StarredChanged bool
+}
+
+// MarkReadScope limits a bulk mark-as-read. Empty FeedIDs covers all of the
+// user's feeds and a zero PublishedBefore applies no date cutoff. Feeds the
+// user isn't subscribed to are ignored.
+type MarkReadScope struct {
+	FeedIDs         []int
+	PublishedBefore time.Time
+}
+
+// MarkReadResult describes a scoped mark-as-read. Changes holds the prior
+// state of every article that was marked read; UnreadCleared counts, per
+// feed, those inside the window covered by GetUserUnreadCounts.
+type MarkReadResult struct {
+	Changes       []ArticleStatusChange
+	UnreadCleared map[int]int
+}
+
+// ArticleStatusUpdate sets an article's read and/or starred flag. Nil fields
+// keep their current value.
+type ArticleStatusUpdate struct {
+	ArticleID int
+	IsRead    *bool
+	IsStarred *bool
+}
+
+// StatusUpdateResult describes a batch of per-article status updates. Found
+// maps each updated article in the user's feeds to its feed; other articles
+// were ignored. Changes holds the prior state of articles whose flags changed
+// and UnreadDelta the resulting per-feed change in the counts returned by
+// GetUserUnreadCounts.
+type StatusUpdateResult struct {
+	Found       map[int]int
+	Changes     []ArticleStatusChange
+	UnreadDelta map[int]int
+}
+
+// FeedSubscriptionChange is the latest subscribe or unsubscribe of a feed by a
+// user, stamped with the user's change counter.
+type FeedSubscriptionChange struct {
+	FeedID     int
+	Subscribed bool
+	Version    int64
+}
+
+// ArticlePosition is a point in article creation order, with ID breaking ties
+// between articles created at the same instant.
+type ArticlePosition struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// UserArticleChange sets some of a user's flags on an article as of At. Nil
+// fields are left unchanged. The change only applies if the stored state was
+// last updated at or before At, so the latest writer wins.
+type UserArticleChange struct {
+	ArticleID    int
+	IsRead       *bool
+	IsStarred    *bool
+	ReadProgress *int
+	At           time.Time
+}
+
+// UndoOperation is the change set recorded for a bulk status update, kept
+// until ExpiresAt so the update can be reversed.
+type UndoOperation struct {
+	ID        string
+	UserID    int
+	Kind      string
+	Changes   []ArticleStatusChange
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// encodeStatusChanges packs a change set as [articleID, feedID, flags] triples
+// (flags bit 0 = read, bit 1 = starred, bit 2 = read changed, bit 3 = starred
+// changed) to keep large mark-all-read change sets well under the Datastore
+// entity size limit.
+func encodeStatusChanges(changes []ArticleStatusChange) (string, error) {
+	packed := make([][3]int, len(changes))
+	for i, c := range changes {
+		flags := 0
+		if c.WasRead {
+			flags |= 1
+		}
+		if c.WasStarred {
+			flags |= 2
+		}
+		if c.ReadChanged {
+			flags |= 4
+		}
+		if c.StarredChanged {
+			flags |= 8
+		}
+		packed[i] = [3]int{c.ArticleID, c.FeedID, flags}
+	}
+	data, err := json.Marshal(packed)
+	if err != nil {
+		return "", err
 	}
+	return string(data), nil
+}
+
+func decodeStatusChanges(data string) ([]ArticleStatusChange, error) {
+	var packed [][3]int
+	if err := json.Unmarshal([]byte(data), &packed); err != nil {
+		return nil, err
+	}
+	changes := make([]ArticleStatusChange, len(packed))
+	for i, p := range packed {
+		flags := p[2]
+		changes[i] = ArticleStatusChange{
+			ArticleID:      p[0],
+			FeedID:         p[1],
+			WasRead:        flags&1 != 0,
+			WasStarred:     flags&2 != 0,
+			ReadChanged:    flags&4 != 0,
+			StarredChanged: flags&8 != 0,
+		}
+	}
+	return changes, nil
+}
+
+// SQLitePath is where InitDB keeps the SQLite database when no other backend is configured.
+const SQLitePath = "./goread2.db"
 
-	db, err := sql.Open("sqlite3", "./goread2.db?_loc=auto")
+// OpenSQLite opens the SQLite database at path without creating or migrating
+// its schema.
+func OpenSQLite(path string) (*DB, error) {
+	db, err := sql.Open("sqlite3", path+"?_loc=auto")
 	if err != nil {
 		return nil, err
 	}
 
 	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &DB{db}, nil
+}
+
+func InitDB() (Database, error) {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return NewPostgresDB(databaseURL)
+	}
+	if projectID := os.Getenv("GOOGLE_CLOUD_PROJECT"); projectID != "" {
+		return NewDatastoreDB(projectID)
+	}
+
+	dbWrapper, err := OpenSQLite(SQLitePath)
+	if err != nil {
 		return nil, err
 	}
 
-	dbWrapper := &DB{db}
 	if err := dbWrapper.CreateTables(); err != nil {
 		return nil, err
 	}
@@ -219,7 +653,12 @@ func (db *DB) CreateTables() error {
 		next_billing_date DATETIME,
 		is_admin BOOLEAN DEFAULT 0,
 		free_months_remaining INTEGER DEFAULT 0,
-		max_articles_on_feed_add INTEGER DEFAULT 100
+		max_articles_on_feed_add INTEGER DEFAULT 100,
+		digest_frequency TEXT DEFAULT '',
+		digest_timezone TEXT DEFAULT '',
+		digest_hour INTEGER DEFAULT 0,
+		digest_weekday INTEGER DEFAULT 0,
+		digest_last_sent_at DATETIME
 	);`
 
 	feedsTable := `
@@ -249,6 +688,7 @@ func (db *DB) CreateTables() error {
 		author TEXT,
 		published_at DATETIME,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		reading_time_minutes INTEGER DEFAULT 0,
 		FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
 	);`
 
@@ -267,6 +707,9 @@ func (db *DB) CreateTables() error {
 		article_id INTEGER NOT NULL,
 		is_read BOOLEAN DEFAULT FALSE,
 		is_starred BOOLEAN DEFAULT FALSE,
+		read_progress INTEGER DEFAULT 0,
+		version INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME,
 		PRIMARY KEY (user_id, article_id),
 		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
 		FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
@@ -288,9 +731,43 @@ func (db *DB) CreateTables() error {
 		user_id INTEGER NOT NULL,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		expires_at DATETIME NOT NULL,
+		user_agent TEXT DEFAULT '',
+		client_type TEXT DEFAULT '',
+		ip_address TEXT DEFAULT '',
+		last_seen_at DATETIME,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);`
+
+	userIdentitiesTable := `
+	CREATE TABLE IF NOT EXISTS user_identities (
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		email TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (provider, subject),
 		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 	);`
 
+	loginTokensTable := `
+	CREATE TABLE IF NOT EXISTS login_tokens (
+		token_hash TEXT PRIMARY KEY,
+		email TEXT NOT NULL,
+		mobile BOOLEAN NOT NULL DEFAULT 0,
+		expires_at DATETIME NOT NULL
+	);`
+
+	refreshTokensTable := `
+	CREATE TABLE IF NOT EXISTS refresh_tokens (
+		token_hash TEXT PRIMARY KEY,
+		session_id TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		used_at DATETIME,
+		FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+	);`
+
 	auditLogsTable := `
 	CREATE TABLE IF NOT EXISTS audit_logs (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -306,7 +783,128 @@ func (db *DB) CreateTables() error {
 		error_message TEXT
 	);`
 
-	tables := []string{usersTable, feedsTable, articlesTable, userFeedsTable, userArticlesTable, adminTokensTable, sessionsTable, auditLogsTable}
+	annotationsTable := `
+	CREATE TABLE IF NOT EXISTS annotations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		article_id INTEGER NOT NULL,
+		quoted_text TEXT NOT NULL,
+		start_offset INTEGER NOT NULL,
+		end_offset INTEGER NOT NULL,
+		note TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+		FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
+	);`
+
+	tagsTable := `
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, name),
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);`
+
+	articleTagsTable := `
+	CREATE TABLE IF NOT EXISTS article_tags (
+		user_id INTEGER NOT NULL,
+		article_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (tag_id, article_id),
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+		FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE,
+		FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+	);`
+
+	undoOperationsTable := `
+	CREATE TABLE IF NOT EXISTS undo_operations (
+		id TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		changes TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);`
+
+	webhooksTable := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		feed_id INTEGER NOT NULL DEFAULT 0,
+		keywords TEXT NOT NULL DEFAULT '',
+		enabled BOOLEAN NOT NULL DEFAULT 1,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+	);`
+
+	webhookDeliveriesTable := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		event_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		article_id INTEGER NOT NULL DEFAULT 0,
+		attempt INTEGER NOT NULL,
+		status_code INTEGER NOT NULL DEFAULT 0,
+		success BOOLEAN NOT NULL DEFAULT 0,
+		error TEXT DEFAULT '',
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+	);`
+
+	inboundAddressesTable := `
+	CREATE TABLE IF NOT EXISTS inbound_addresses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		feed_id INTEGER NOT NULL,
+		token TEXT UNIQUE NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+		FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+	);`
+
+	feedCategoriesTable := `
+	CREATE TABLE IF NOT EXISTS feed_categories (
+		feed_id INTEGER PRIMARY KEY,
+		category TEXT NOT NULL,
+		FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+	);`
+
+	// Rebuilt from scratch by each directory job
+	directoryFeedsTable := `
+	CREATE TABLE IF NOT EXISTS directory_feeds (
+		feed_id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		url TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		category TEXT NOT NULL DEFAULT '',
+		subscribers INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL,
+		FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+	);`
+
+	feedRecommendationsTable := `
+	CREATE TABLE IF NOT EXISTS feed_recommendations (
+		feed_id INTEGER NOT NULL,
+		recommended_feed_id INTEGER NOT NULL,
+		shared_subscribers INTEGER NOT NULL,
+		score REAL NOT NULL,
+		PRIMARY KEY (feed_id, recommended_feed_id),
+		FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+		FOREIGN KEY (recommended_feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+	);`
+
+	tables := []string{usersTable, feedsTable, articlesTable, userFeedsTable, userArticlesTable, adminTokensTable, sessionsTable, userIdentitiesTable, loginTokensTable, refreshTokensTable, auditLogsTable, annotationsTable, tagsTable, articleTagsTable, undoOperationsTable, webhooksTable, webhookDeliveriesTable, inboundAddressesTable, feedCategoriesTable, directoryFeedsTable, feedRecommendationsTable}
 
 	for _, table := range tables {
 		if _, err := db.Exec(table); err != nil {
@@ -319,6 +917,86 @@ func (db *DB) CreateTables() error {
 		return err
 	}
 
+	return db.createSyncSchema()
+}
+
+// createSyncSchema sets up change tracking for offline sync. Triggers stamp
+// every user_articles write and every subscription change with the user's
+// next change version, so no write path has to remember to do it.
+func (db *DB) createSyncSchema() error {
+	// Databases created before sync existed get user_articles.version, and
+	// its index, from migration 9, which runs after this. Trigger bodies
+	// aren't checked until they fire, so the triggers can be created first.
+	//
+	// No foreign keys: deleting a user cascades into user_feeds, whose delete
+	// trigger writes here.
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS user_sync_state (
+			user_id INTEGER PRIMARY KEY,
+			version INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE TABLE IF NOT EXISTS user_feed_changes (
+			user_id INTEGER NOT NULL,
+			feed_id INTEGER NOT NULL,
+			subscribed BOOLEAN NOT NULL,
+			version INTEGER NOT NULL,
+			changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, feed_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_user_feed_changes_user_version ON user_feed_changes (user_id, version)`,
+		`CREATE INDEX IF NOT EXISTS idx_articles_created ON articles (created_at, id)`,
+
+		// updated_at keeps a value the statement set explicitly (the client's
+		// time for synced actions) and otherwise records the current time.
+		`CREATE TRIGGER IF NOT EXISTS user_articles_sync_insert
+		AFTER INSERT ON user_articles
+		BEGIN
+			INSERT INTO user_sync_state (user_id, version) VALUES (NEW.user_id, 1)
+				ON CONFLICT(user_id) DO UPDATE SET version = version + 1;
+			UPDATE user_articles
+			SET version = (SELECT version FROM user_sync_state WHERE user_id = NEW.user_id),
+			    updated_at = COALESCE(NEW.updated_at, CURRENT_TIMESTAMP)
+			WHERE user_id = NEW.user_id AND article_id = NEW.article_id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS user_articles_sync_update
+		AFTER UPDATE OF is_read, is_starred, read_progress ON user_articles
+		WHEN OLD.is_read IS NOT NEW.is_read
+			OR OLD.is_starred IS NOT NEW.is_starred
+			OR OLD.read_progress IS NOT NEW.read_progress
+		BEGIN
+			INSERT INTO user_sync_state (user_id, version) VALUES (NEW.user_id, 1)
+				ON CONFLICT(user_id) DO UPDATE SET version = version + 1;
+			UPDATE user_articles
+			SET version = (SELECT version FROM user_sync_state WHERE user_id = NEW.user_id),
+			    updated_at = CASE WHEN NEW.updated_at IS OLD.updated_at THEN CURRENT_TIMESTAMP ELSE NEW.updated_at END
+			WHERE user_id = NEW.user_id AND article_id = NEW.article_id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS user_feeds_sync_insert
+		AFTER INSERT ON user_feeds
+		BEGIN
+			INSERT INTO user_sync_state (user_id, version) VALUES (NEW.user_id, 1)
+				ON CONFLICT(user_id) DO UPDATE SET version = version + 1;
+			INSERT INTO user_feed_changes (user_id, feed_id, subscribed, version, changed_at)
+			VALUES (NEW.user_id, NEW.feed_id, 1, (SELECT version FROM user_sync_state WHERE user_id = NEW.user_id), CURRENT_TIMESTAMP)
+				ON CONFLICT(user_id, feed_id) DO UPDATE SET
+					subscribed = excluded.subscribed, version = excluded.version, changed_at = excluded.changed_at;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS user_feeds_sync_delete
+		AFTER DELETE ON user_feeds
+		BEGIN
+			INSERT INTO user_sync_state (user_id, version) VALUES (OLD.user_id, 1)
+				ON CONFLICT(user_id) DO UPDATE SET version = version + 1;
+			INSERT INTO user_feed_changes (user_id, feed_id, subscribed, version, changed_at)
+			VALUES (OLD.user_id, OLD.feed_id, 0, (SELECT version FROM user_sync_state WHERE user_id = OLD.user_id), CURRENT_TIMESTAMP)
+				ON CONFLICT(user_id, feed_id) DO UPDATE SET
+					subscribed = excluded.subscribed, version = excluded.version, changed_at = excluded.changed_at;
+		END`,
+	}
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create sync schema: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -344,6 +1022,11 @@ func (db *DB) CreateIndexes() error {
 		// Users table indexes for authentication
 		`CREATE INDEX IF NOT EXISTS idx_users_google_id ON users (google_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users (email)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))`,
+		`CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities (user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_login_tokens_expires ON login_tokens (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires ON refresh_tokens (expires_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens (user_id)`,
 
 		// Admin tokens table indexes for authentication
 		`CREATE INDEX IF NOT EXISTS idx_admin_tokens_hash ON admin_tokens (token_hash)`,
@@ -354,6 +1037,24 @@ func (db *DB) CreateIndexes() error {
 		`CREATE INDEX IF NOT EXISTS idx_audit_logs_admin_user ON audit_logs (admin_user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_audit_logs_target_user ON audit_logs (target_user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_audit_logs_operation ON audit_logs (operation_type)`,
+
+		// Annotations table indexes for per-article listing and per-user export
+		`CREATE INDEX IF NOT EXISTS idx_annotations_user_article ON annotations (user_id, article_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_annotations_user_created ON annotations (user_id, created_at)`,
+
+		// Article tags index for loading the tags shown on a page of articles
+		`CREATE INDEX IF NOT EXISTS idx_article_tags_user_article ON article_tags (user_id, article_id)`,
+
+		// Undo operations index for purging expired change sets
+		`CREATE INDEX IF NOT EXISTS idx_undo_operations_expires ON undo_operations (expires_at)`,
+
+		// Webhook indexes for per-user listing, matching on refresh and delivery history
+		`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks (user_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created ON webhook_deliveries (created_at)`,
+
+		// Inbound address index for per-user listing (token lookups use the UNIQUE index)
+		`CREATE INDEX IF NOT EXISTS idx_inbound_addresses_user ON inbound_addresses (user_id)`,
 	}
 
 	for _, index := range indexes {
@@ -365,121 +1066,24 @@ func (db *DB) CreateIndexes() error {
 	return nil
 }
 
+// migrateDatabase brings an existing database up to date: it applies pending
+// migrations and creates any indexes added since the database was made.
 func (db *DB) migrateDatabase() error {
-	// Add missing columns to existing users table if they don't exist
-	userColumns := []string{
-		"ALTER TABLE users ADD COLUMN google_id TEXT",
-		"ALTER TABLE users ADD COLUMN email TEXT",
-		"ALTER TABLE users ADD COLUMN name TEXT",
-		"ALTER TABLE users ADD COLUMN avatar TEXT",
-		"ALTER TABLE users ADD COLUMN subscription_status TEXT DEFAULT 'trial'",
-		"ALTER TABLE users ADD COLUMN subscription_id TEXT",
-		"ALTER TABLE users ADD COLUMN trial_ends_at DATETIME",
-		"ALTER TABLE users ADD COLUMN last_payment_date DATETIME",
-		"ALTER TABLE users ADD COLUMN next_billing_date DATETIME",
-		"ALTER TABLE users ADD COLUMN is_admin BOOLEAN DEFAULT 0",
-		"ALTER TABLE users ADD COLUMN free_months_remaining INTEGER DEFAULT 0",
-		"ALTER TABLE users ADD COLUMN max_articles_on_feed_add INTEGER DEFAULT 100",
-	}
-
-	for _, alterQuery := range userColumns {
-		_, err := db.Exec(alterQuery)
-		if err != nil {
-			// Ignore "duplicate column" errors - column already exists
-			if !strings.Contains(err.Error(), "duplicate column name") {
-				return fmt.Errorf("migration failed: %w", err)
-			}
-		}
-	}
-
-	// Add missing columns to existing feeds table for smart update tracking
-	// Note: SQLite doesn't allow CURRENT_TIMESTAMP as default in ALTER TABLE
-	// So we add columns with NULL default, then update existing rows
-	feedColumns := []string{
-		"ALTER TABLE feeds ADD COLUMN last_checked DATETIME",
-		"ALTER TABLE feeds ADD COLUMN last_had_new_content DATETIME",
-		"ALTER TABLE feeds ADD COLUMN average_update_interval INTEGER DEFAULT 0",
-	}
-
-	for _, alterQuery := range feedColumns {
-		_, err := db.Exec(alterQuery)
-		if err != nil {
-			// Ignore "duplicate column" errors - column already exists
-			if !strings.Contains(err.Error(), "duplicate column name") {
-				return fmt.Errorf("migration failed: %w", err)
-			}
-		}
-	}
-
-	// Add ETag and LastModified columns for HTTP conditional requests
-	cacheColumns := []string{
-		"ALTER TABLE feeds ADD COLUMN etag TEXT DEFAULT ''",
-		"ALTER TABLE feeds ADD COLUMN last_modified TEXT DEFAULT ''",
-	}
-
-	for _, alterQuery := range cacheColumns {
-		_, err := db.Exec(alterQuery)
-		if err != nil {
-			if !strings.Contains(err.Error(), "duplicate column name") {
-				return fmt.Errorf("migration failed: %w", err)
-			}
-		}
-	}
-
-	// Update existing feeds to have current timestamp for new tracking fields
-	// This only affects feeds that existed before the migration
-	_, errUpdate := db.Exec(`
-		UPDATE feeds
-		SET last_checked = COALESCE(last_checked, last_fetch),
-		    last_had_new_content = COALESCE(last_had_new_content, last_fetch)
-		WHERE last_checked IS NULL OR last_had_new_content IS NULL
-	`)
-	if errUpdate != nil {
-		log.Printf("Warning: Failed to set initial tracking timestamps: %v", errUpdate)
-	}
-
-	// Set trial_ends_at for existing users who don't have it set
-	updateTrialQuery := `
-		UPDATE users
-		SET trial_ends_at = datetime(created_at, '+30 days')
-		WHERE trial_ends_at IS NULL AND subscription_status = 'trial'
-	`
-	_, err := db.Exec(updateTrialQuery)
-	if err != nil {
-		return fmt.Errorf("failed to set trial end dates: %w", err)
-	}
-
-	// Create sessions table if it doesn't exist
-	sessionsTable := `
-	CREATE TABLE IF NOT EXISTS sessions (
-		id TEXT PRIMARY KEY,
-		user_id INTEGER NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		expires_at DATETIME NOT NULL,
-		FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
-	);`
-	_, err = db.Exec(sessionsTable)
-	if err != nil {
-		return fmt.Errorf("failed to create sessions table: %w", err)
-	}
-
-	// Ensure indexes are created on existing databases
-	if err := db.CreateIndexes(); err != nil {
+	if _, err := db.Migrate(); err != nil {
 		return err
 	}
-
-	return nil
+	return db.CreateIndexes()
 }
 
 func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *DB) AddFeed(feed *Feed) error {
-	query := `INSERT INTO feeds (title, url, description, created_at, updated_at, last_fetch, last_checked, last_had_new_content, average_update_interval, etag, last_modified)
+const insertFeedQuery = `INSERT INTO feeds (title, url, description, created_at, updated_at, last_fetch, last_checked, last_had_new_content, average_update_interval, etag, last_modified)
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	result, err := db.Exec(query, feed.Title, feed.URL, feed.Description,
+func (db *DB) AddFeed(feed *Feed) error {
+	result, err := db.Exec(insertFeedQuery, feed.Title, feed.URL, feed.Description,
 		feed.CreatedAt, feed.UpdatedAt, feed.LastFetch, feed.LastChecked, feed.LastHadNewContent, feed.AverageUpdateInterval,
 		feed.ETag, feed.LastModified)
 	if err != nil {
@@ -566,12 +1170,12 @@ func (db *DB) AddArticle(article *Article) error {
 	// ON CONFLICT DO UPDATE ensures last_insert_rowid() returns the existing row's ID
 	// for duplicate URLs, making the ID assignment atomic (no separate SELECT needed).
 	query := `INSERT INTO articles
-			  (feed_id, title, url, content, description, author, published_at, created_at)
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			  (feed_id, title, url, content, description, author, published_at, created_at, reading_time_minutes)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 			  ON CONFLICT(url) DO UPDATE SET id=id`
 
 	result, err := db.Exec(query, article.FeedID, article.Title, article.URL, article.Content,
-		article.Description, article.Author, article.PublishedAt, article.CreatedAt)
+		article.Description, article.Author, article.PublishedAt, article.CreatedAt, article.ReadingTimeMinutes)
 	if err != nil {
 		return err
 	}
@@ -586,7 +1190,7 @@ func (db *DB) AddArticle(article *Article) error {
 
 func (db *DB) GetArticles(feedID int) ([]Article, error) {
 	query := `SELECT id, feed_id, title, url, content, description, author, 
-			  published_at, created_at 
+			  published_at, created_at, reading_time_minutes 
 			  FROM articles WHERE feed_id = ? ORDER BY published_at DESC`
 
 	rows, err := db.Query(query, feedID)
@@ -600,7 +1204,7 @@ func (db *DB) GetArticles(feedID int) ([]Article, error) {
 		var article Article
 		err := rows.Scan(&article.ID, &article.FeedID, &article.Title, &article.URL,
 			&article.Content, &article.Description, &article.Author,
-			&article.PublishedAt, &article.CreatedAt)
+			&article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes)
 		if err != nil {
 			return nil, err
 		}
@@ -614,12 +1218,13 @@ func (db *DB) GetArticles(feedID int) ([]Article, error) {
 }
 
 func (db *DB) FindArticleByURL(url string) (*Article, error) {
-	query := `SELECT id, feed_id, title, url, content, description, author, published_at, created_at
+	query := `SELECT id, feed_id, title, url, content, description, author, published_at, created_at, reading_time_minutes
 			  FROM articles WHERE url = ? LIMIT 1`
 
 	var article Article
 	err := db.QueryRow(query, url).Scan(&article.ID, &article.FeedID, &article.Title, &article.URL,
-		&article.Content, &article.Description, &article.Author, &article.PublishedAt, &article.CreatedAt)
+		&article.Content, &article.Description, &article.Author, &article.PublishedAt, &article.CreatedAt,
+		&article.ReadingTimeMinutes)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -699,6 +1304,7 @@ func (db *DB) CreateUser(user *User) error {
 	if user.MaxArticlesOnFeedAdd == 0 {
 		user.MaxArticlesOnFeedAdd = 100 // Default to 100 articles
 	}
+	user.Email = NormalizeEmail(user.Email)
 
 	query := `INSERT INTO users (google_id, email, name, avatar, created_at, subscription_status, subscription_id, trial_ends_at, last_payment_date, next_billing_date, max_articles_on_feed_add)
 			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
@@ -717,25 +1323,34 @@ func (db *DB) CreateUser(user *User) error {
 	return nil
 }
 
-func (db *DB) GetUserByGoogleID(googleID string) (*User, error) {
-	query := `SELECT id, google_id, email, name, avatar, created_at,
-			  COALESCE(subscription_status, 'trial') as subscription_status,
-			  COALESCE(subscription_id, '') as subscription_id,
-			  trial_ends_at, last_payment_date, next_billing_date,
-			  COALESCE(is_admin, 0) as is_admin,
-			  COALESCE(free_months_remaining, 0) as free_months_remaining,
-			  COALESCE(max_articles_on_feed_add, 100) as max_articles_on_feed_add
-			  FROM users WHERE google_id = ?`
-
+// userColumns is the column list scanUser expects.
+const userColumns = `id, google_id, email, name, avatar, created_at,
+	COALESCE(subscription_status, 'trial') as subscription_status,
+	COALESCE(subscription_id, '') as subscription_id,
+	trial_ends_at, last_payment_date, next_billing_date,
+	COALESCE(is_admin, 0) as is_admin,
+	COALESCE(free_months_remaining, 0) as free_months_remaining,
+	COALESCE(max_articles_on_feed_add, 100) as max_articles_on_feed_add,
+	COALESCE(digest_frequency, '') as digest_frequency,
+	COALESCE(digest_timezone, '') as digest_timezone,
+	COALESCE(digest_hour, 0) as digest_hour,
+	COALESCE(digest_weekday, 0) as digest_weekday,
+	digest_last_sent_at`
+
+// scanUser reads a row selected with userColumns.
+func scanUser(row interface{ Scan(...interface{}) error }) (*User, error) {
 	var user User
 	var trialEndsAt sql.NullTime
 	var lastPaymentDate sql.NullTime
 	var nextBillingDate sql.NullTime
+	var digestLastSentAt sql.NullTime
 
-	err := db.QueryRow(query, googleID).Scan(&user.ID, &user.GoogleID, &user.Email,
+	err := row.Scan(&user.ID, &user.GoogleID, &user.Email,
 		&user.Name, &user.Avatar, &user.CreatedAt, &user.SubscriptionStatus,
 		&user.SubscriptionID, &trialEndsAt, &lastPaymentDate, &nextBillingDate,
-		&user.IsAdmin, &user.FreeMonthsRemaining, &user.MaxArticlesOnFeedAdd)
+		&user.IsAdmin, &user.FreeMonthsRemaining, &user.MaxArticlesOnFeedAdd,
+		&user.DigestFrequency, &user.DigestTimezone, &user.DigestHour, &user.DigestWeekday,
+		&digestLastSentAt)
 	if err != nil {
 		return nil, err
 	}
@@ -756,49 +1371,19 @@ func (db *DB) GetUserByGoogleID(googleID string) (*User, error) {
 		user.NextBillingDate = nextBillingDate.Time
 	}
 
-	return &user, nil
-}
-
-func (db *DB) GetUserByID(userID int) (*User, error) {
-	query := `SELECT id, google_id, email, name, avatar, created_at,
-			  COALESCE(subscription_status, 'trial') as subscription_status,
-			  COALESCE(subscription_id, '') as subscription_id,
-			  trial_ends_at, last_payment_date, next_billing_date,
-			  COALESCE(is_admin, 0) as is_admin,
-			  COALESCE(free_months_remaining, 0) as free_months_remaining,
-			  COALESCE(max_articles_on_feed_add, 100) as max_articles_on_feed_add
-			  FROM users WHERE id = ?`
-
-	var user User
-	var trialEndsAt sql.NullTime
-	var lastPaymentDate sql.NullTime
-	var nextBillingDate sql.NullTime
-
-	err := db.QueryRow(query, userID).Scan(&user.ID, &user.GoogleID, &user.Email,
-		&user.Name, &user.Avatar, &user.CreatedAt, &user.SubscriptionStatus,
-		&user.SubscriptionID, &trialEndsAt, &lastPaymentDate, &nextBillingDate,
-		&user.IsAdmin, &user.FreeMonthsRemaining, &user.MaxArticlesOnFeedAdd)
-	if err != nil {
-		return nil, err
-	}
-
-	// Handle nullable datetime fields
-	if trialEndsAt.Valid {
-		user.TrialEndsAt = trialEndsAt.Time
-	} else {
-		// Set default trial end date if not set
-		user.TrialEndsAt = user.CreatedAt.AddDate(0, 0, 30)
+	if digestLastSentAt.Valid {
+		user.DigestLastSentAt = digestLastSentAt.Time
 	}
 
-	if lastPaymentDate.Valid {
-		user.LastPaymentDate = lastPaymentDate.Time
-	}
+	return &user, nil
+}
 
-	if nextBillingDate.Valid {
-		user.NextBillingDate = nextBillingDate.Time
-	}
+func (db *DB) GetUserByGoogleID(googleID string) (*User, error) {
+	return scanUser(db.QueryRow(`SELECT `+userColumns+` FROM users WHERE google_id = ?`, googleID))
+}
 
-	return &user, nil
+func (db *DB) GetUserByID(userID int) (*User, error) {
+	return scanUser(db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, userID))
 }
 
 // User feed methods
@@ -902,9 +1487,10 @@ func (db *DB) GetUserFeedArticlesPaginated(userID, feedID int, limit int, cursor
 // feedID of 0 means "all of the user's feeds"; a nonzero feedID restricts to that one feed.
 func (db *DB) getUserArticlesPaginated(userID, feedID int, limit int, cursor string, unreadOnly bool) (*ArticlePaginationResult, error) {
 	baseQuery := `SELECT a.id, a.feed_id, f.title as feed_title, a.title, a.url, a.description, a.author,
-			  a.published_at, a.created_at,
+			  a.published_at, a.created_at, a.reading_time_minutes,
 			  COALESCE(ua.is_read, 0) as is_read,
-			  COALESCE(ua.is_starred, 0) as is_starred
+			  COALESCE(ua.is_starred, 0) as is_starred,
+			  COALESCE(ua.read_progress, 0) as read_progress
 			  FROM articles a
 			  JOIN user_feeds uf ON a.feed_id = uf.feed_id
 			  JOIN feeds f ON a.feed_id = f.id
@@ -951,7 +1537,8 @@ func (db *DB) getUserArticlesPaginated(userID, feedID int, limit int, cursor str
 		var article Article
 		err := rows.Scan(&article.ID, &article.FeedID, &article.FeedTitle, &article.Title, &article.URL,
 			&article.Description, &article.Author,
-			&article.PublishedAt, &article.CreatedAt, &article.IsRead, &article.IsStarred)
+			&article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes,
+			&article.IsRead, &article.IsStarred, &article.ReadProgress)
 		if err != nil {
 			return nil, err
 		}
@@ -1015,9 +1602,10 @@ func (db *DB) GetUserFeedArticles(userID, feedID int) ([]Article, error) {
 	}
 
 	query := `SELECT a.id, a.feed_id, f.title as feed_title, a.title, a.url, a.description, a.author,
-			  a.published_at, a.created_at,
+			  a.published_at, a.created_at, a.reading_time_minutes,
 			  COALESCE(ua.is_read, 0) as is_read,
-			  COALESCE(ua.is_starred, 0) as is_starred
+			  COALESCE(ua.is_starred, 0) as is_starred,
+			  COALESCE(ua.read_progress, 0) as read_progress
 			  FROM articles a
 			  JOIN feeds f ON a.feed_id = f.id
 			  LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = ?
@@ -1035,7 +1623,8 @@ func (db *DB) GetUserFeedArticles(userID, feedID int) ([]Article, error) {
 		var article Article
 		err := rows.Scan(&article.ID, &article.FeedID, &article.FeedTitle, &article.Title, &article.URL,
 			&article.Description, &article.Author,
-			&article.PublishedAt, &article.CreatedAt, &article.IsRead, &article.IsStarred)
+			&article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes,
+			&article.IsRead, &article.IsStarred, &article.ReadProgress)
 		if err != nil {
 			return nil, err
 		}
@@ -1047,9 +1636,10 @@ func (db *DB) GetUserFeedArticles(userID, feedID int) ([]Article, error) {
 
 func (db *DB) GetArticleByID(userID, articleID int) (*Article, error) {
 	query := `SELECT a.id, a.feed_id, f.title as feed_title, a.title, a.url, a.content, a.description, a.author,
-			  a.published_at, a.created_at,
+			  a.published_at, a.created_at, a.reading_time_minutes,
 			  COALESCE(ua.is_read, 0) as is_read,
-			  COALESCE(ua.is_starred, 0) as is_starred
+			  COALESCE(ua.is_starred, 0) as is_starred,
+			  COALESCE(ua.read_progress, 0) as read_progress
 			  FROM articles a
 			  JOIN feeds f ON a.feed_id = f.id
 			  JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = ?
@@ -1060,7 +1650,8 @@ func (db *DB) GetArticleByID(userID, articleID int) (*Article, error) {
 	err := db.QueryRow(query, userID, userID, articleID).Scan(
 		&article.ID, &article.FeedID, &article.FeedTitle, &article.Title, &article.URL,
 		&article.Content, &article.Description, &article.Author,
-		&article.PublishedAt, &article.CreatedAt, &article.IsRead, &article.IsStarred)
+		&article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes,
+		&article.IsRead, &article.IsStarred, &article.ReadProgress)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -1070,27 +1661,114 @@ func (db *DB) GetArticleByID(userID, articleID int) (*Article, error) {
 	return &article, nil
 }
 
+func (db *DB) GetArticlesByIDs(userID int, articleIDs []int) (map[int]Article, error) {
+	articles := make(map[int]Article)
+	const chunkSize = 500
+	for i := 0; i < len(articleIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+		chunk := articleIDs[i:end]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, userID)
+		for j, id := range chunk {
+			placeholders[j] = "?"
+			args = append(args, id)
+		}
+
+		query := `SELECT a.id, a.feed_id, f.title, a.title, a.url, a.description, a.author,
+				  a.published_at, a.created_at, a.reading_time_minutes
+				  FROM articles a
+				  JOIN feeds f ON a.feed_id = f.id
+				  JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = ?
+				  WHERE a.id IN (` + strings.Join(placeholders, ",") + `)`
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var a Article
+			if err := rows.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Description, &a.Author,
+				&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			articles[a.ID] = a
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return articles, nil
+}
+
+func (db *DB) GetUserArticleHistory(userID int) ([]Article, error) {
+	query := `SELECT a.id, a.feed_id, f.title, a.title, a.url, a.description, a.author,
+			  a.published_at, a.created_at, a.reading_time_minutes,
+			  ua.is_read, ua.is_starred, COALESCE(ua.read_progress, 0)
+			  FROM user_articles ua
+			  JOIN articles a ON a.id = ua.article_id
+			  JOIN feeds f ON f.id = a.feed_id
+			  WHERE ua.user_id = ? AND (ua.is_read = 1 OR ua.is_starred = 1 OR ua.read_progress > 0)
+			  ORDER BY a.published_at DESC, a.id DESC`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var articles []Article
+	for rows.Next() {
+		var article Article
+		if err := rows.Scan(&article.ID, &article.FeedID, &article.FeedTitle, &article.Title, &article.URL,
+			&article.Description, &article.Author, &article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes,
+			&article.IsRead, &article.IsStarred, &article.ReadProgress); err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+	return articles, rows.Err()
+}
+
 // User article status methods
 func (db *DB) GetUserArticleStatus(userID, articleID int) (*UserArticle, error) {
-	query := `SELECT user_id, article_id, is_read, is_starred FROM user_articles 
+	query := `SELECT user_id, article_id, is_read, is_starred, read_progress FROM user_articles 
 			  WHERE user_id = ? AND article_id = ?`
 
 	var userArticle UserArticle
 	err := db.QueryRow(query, userID, articleID).Scan(&userArticle.UserID, &userArticle.ArticleID,
-		&userArticle.IsRead, &userArticle.IsStarred)
+		&userArticle.IsRead, &userArticle.IsStarred, &userArticle.ReadProgress)
 	if err != nil {
 		return nil, err
 	}
 	return &userArticle, nil
 }
 
+// SetUserArticleStatus upserts rather than using INSERT OR REPLACE so the
+// row's read_progress survives status changes.
 func (db *DB) SetUserArticleStatus(userID, articleID int, isRead, isStarred bool) error {
-	query := `INSERT OR REPLACE INTO user_articles (user_id, article_id, is_read, is_starred) 
-			  VALUES (?, ?, ?, ?)`
+	query := `INSERT INTO user_articles (user_id, article_id, is_read, is_starred) 
+			  VALUES (?, ?, ?, ?)
+			  ON CONFLICT(user_id, article_id) DO UPDATE SET
+			  is_read = excluded.is_read, is_starred = excluded.is_starred`
 	_, err := db.Exec(query, userID, articleID, isRead, isStarred)
 	return err
 }
 
+func (db *DB) SetUserArticleProgress(userID, articleID, progress int) error {
+	query := `INSERT INTO user_articles (user_id, article_id, is_read, is_starred, read_progress)
+			  VALUES (?, ?, 0, 0, ?)
+			  ON CONFLICT(user_id, article_id) DO UPDATE SET read_progress = excluded.read_progress`
+	_, err := db.Exec(query, userID, articleID, progress)
+	return err
+}
+
 func (db *DB) MarkUserArticleRead(userID, articleID int, isRead bool) error {
 	// First check if record exists
 	var dummy int
@@ -1138,8 +1816,8 @@ func (db *DB) BatchSetUserArticleStatus(userID int, articles []Article, isRead,
 		return nil
 	}
 
-	// Use INSERT OR REPLACE for batch operation
-	query := `INSERT OR REPLACE INTO user_articles (user_id, article_id, is_read, is_starred) VALUES `
+	// Upsert in one statement; read_progress is left untouched on existing rows
+	query := `INSERT INTO user_articles (user_id, article_id, is_read, is_starred) VALUES `
 
 	// Build values string
 	values := make([]string, len(articles))
@@ -1155,6 +1833,8 @@ func (db *DB) BatchSetUserArticleStatus(userID int, articles []Article, isRead,
 	}
 
 	query += strings.Join(values, ", ")
+	query += ` ON CONFLICT(user_id, article_id) DO UPDATE SET
+		is_read = excluded.is_read, is_starred = excluded.is_starred`
 
 	_, err := db.Exec(query, args...)
 	return err
@@ -1162,11 +1842,13 @@ func (db *DB) BatchSetUserArticleStatus(userID int, articles []Article, isRead,
 
 func (db *DB) MarkAllUserArticlesRead(userID int) (int, error) {
 	result, err := db.Exec(`
-		INSERT OR REPLACE INTO user_articles (user_id, article_id, is_read, is_starred)
+		INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
 		SELECT ?, a.id, 1, 0
 		FROM articles a
 		JOIN user_feeds uf ON a.feed_id = uf.feed_id
-		WHERE uf.user_id = ?`, userID, userID)
+		WHERE uf.user_id = ?
+		ON CONFLICT(user_id, article_id) DO UPDATE SET
+		is_read = excluded.is_read, is_starred = excluded.is_starred`, userID, userID)
 	if err != nil {
 		return 0, err
 	}
@@ -1174,10 +1856,236 @@ func (db *DB) MarkAllUserArticlesRead(userID int) (int, error) {
 	return int(n), err
 }
 
-func (db *DB) GetUserUnreadCounts(userID int) (map[int]int, error) {
-	// First get user's feeds
-	userFeeds, err := db.GetUserFeeds(userID)
-	if err != nil {
+// MarkUserArticlesReadScoped marks the user's unread articles within scope as
+// read. Unlike MarkAllUserArticlesRead it leaves starred flags alone.
+func (db *DB) MarkUserArticlesReadScoped(userID int, scope MarkReadScope) (*MarkReadResult, error) {
+	filter := `
+		FROM articles a
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = ?
+		LEFT JOIN user_articles ua ON ua.article_id = a.id AND ua.user_id = ?
+		WHERE COALESCE(ua.is_read, 0) = 0`
+	args := []interface{}{userID, userID}
+	if len(scope.FeedIDs) > 0 {
+		placeholders := make([]string, len(scope.FeedIDs))
+		for i, feedID := range scope.FeedIDs {
+			placeholders[i] = "?"
+			args = append(args, feedID)
+		}
+		filter += ` AND a.feed_id IN (` + strings.Join(placeholders, ",") + `)`
+	}
+	if !scope.PublishedBefore.IsZero() {
+		filter += ` AND a.published_at < ?`
+		args = append(args, scope.PublishedBefore)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Record what is about to change; the recency flag mirrors the window
+	// used by getFeedUnreadCountForUser.
+	rows, err := tx.Query(`SELECT a.id, a.feed_id, COALESCE(ua.is_starred, 0),
+		COALESCE(a.published_at >= datetime('now', '-90 days'), 0)`+filter+` ORDER BY a.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	result := &MarkReadResult{UnreadCleared: make(map[int]int)}
+	for rows.Next() {
+		var change ArticleStatusChange
+		var recent bool
+		if err := rows.Scan(&change.ArticleID, &change.FeedID, &change.WasStarred, &recent); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		change.ReadChanged = true
+		result.Changes = append(result.Changes, change)
+		if recent {
+			result.UnreadCleared[change.FeedID]++
+		}
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_articles (user_id, article_id, is_read, is_starred)
+		SELECT ?, a.id, 1, COALESCE(ua.is_starred, 0)`+filter+`
+		ON CONFLICT(user_id, article_id) DO UPDATE SET is_read = 1`,
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateUserArticleStatuses applies per-article flag updates in one
+// transaction. Updates are applied in order, so repeated articles end with
+// their last update.
+func (db *DB) UpdateUserArticleStatuses(userID int, updates []ArticleStatusUpdate) (*StatusUpdateResult, error) {
+	result := &StatusUpdateResult{Found: make(map[int]int), UnreadDelta: make(map[int]int)}
+	if len(updates) == 0 {
+		return result, nil
+	}
+
+	var ids []int
+	seen := make(map[int]bool)
+	for _, u := range updates {
+		if !seen[u.ArticleID] {
+			seen[u.ArticleID] = true
+			ids = append(ids, u.ArticleID)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	type state struct {
+		change        ArticleStatusChange
+		read, starred bool
+		recent        bool
+	}
+	states := make(map[int]*state, len(ids))
+	const chunkSize = 500
+	for i := 0; i < len(ids); i += chunkSize {
+		end := i + chunkSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		chunk := ids[i:end]
+		placeholders := make([]string, len(chunk))
+		args := []interface{}{userID, userID}
+		for j, id := range chunk {
+			placeholders[j] = "?"
+			args = append(args, id)
+		}
+		rows, err := tx.Query(`SELECT a.id, a.feed_id, COALESCE(ua.is_read, 0), COALESCE(ua.is_starred, 0),
+			COALESCE(a.published_at >= datetime('now', '-90 days'), 0)
+			FROM articles a
+			JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = ?
+			LEFT JOIN user_articles ua ON ua.article_id = a.id AND ua.user_id = ?
+			WHERE a.id IN (`+strings.Join(placeholders, ",")+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			st := &state{}
+			c := &st.change
+			if err := rows.Scan(&c.ArticleID, &c.FeedID, &c.WasRead, &c.WasStarred, &st.recent); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			st.read, st.starred = c.WasRead, c.WasStarred
+			states[c.ArticleID] = st
+			result.Found[c.ArticleID] = c.FeedID
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, u := range updates {
+		st, ok := states[u.ArticleID]
+		if !ok {
+			continue
+		}
+		if u.IsRead != nil {
+			st.read = *u.IsRead
+		}
+		if u.IsStarred != nil {
+			st.starred = *u.IsStarred
+		}
+	}
+
+	var changed []*state
+	for _, id := range ids {
+		st, ok := states[id]
+		if !ok || (st.read == st.change.WasRead && st.starred == st.change.WasStarred) {
+			continue
+		}
+		changed = append(changed, st)
+		st.change.ReadChanged = st.read != st.change.WasRead
+		st.change.StarredChanged = st.starred != st.change.WasStarred
+		result.Changes = append(result.Changes, st.change)
+		if st.recent && st.read != st.change.WasRead {
+			if st.read {
+				result.UnreadDelta[st.change.FeedID]--
+			} else {
+				result.UnreadDelta[st.change.FeedID]++
+			}
+		}
+	}
+	sort.Slice(result.Changes, func(i, j int) bool { return result.Changes[i].ArticleID < result.Changes[j].ArticleID })
+
+	for i := 0; i < len(changed); i += chunkSize {
+		end := i + chunkSize
+		if end > len(changed) {
+			end = len(changed)
+		}
+		values := make([]string, 0, end-i)
+		args := make([]interface{}, 0, (end-i)*4)
+		for _, st := range changed[i:end] {
+			values = append(values, "(?, ?, ?, ?)")
+			args = append(args, userID, st.change.ArticleID, st.read, st.starred)
+		}
+		_, err := tx.Exec(`INSERT INTO user_articles (user_id, article_id, is_read, is_starred) VALUES `+
+			strings.Join(values, ", ")+`
+			ON CONFLICT(user_id, article_id) DO UPDATE SET
+			is_read = excluded.is_read, is_starred = excluded.is_starred`, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetUserStarredCountsByFeed returns how many articles the user has starred in
+// each subscribed feed. Feeds without starred articles are omitted.
+func (db *DB) GetUserStarredCountsByFeed(userID int) (map[int]int, error) {
+	rows, err := db.Query(`SELECT a.feed_id, COUNT(*)
+		FROM user_articles ua
+		JOIN articles a ON a.id = ua.article_id
+		JOIN user_feeds uf ON uf.feed_id = a.feed_id AND uf.user_id = ua.user_id
+		WHERE ua.user_id = ? AND ua.is_starred = 1
+		GROUP BY a.feed_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var feedID, count int
+		if err := rows.Scan(&feedID, &count); err != nil {
+			return nil, err
+		}
+		counts[feedID] = count
+	}
+	return counts, rows.Err()
+}
+
+func (db *DB) GetUserUnreadCounts(userID int) (map[int]int, error) {
+	// First get user's feeds
+	userFeeds, err := db.GetUserFeeds(userID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -1413,6 +2321,37 @@ func (db *DB) UpdateUserMaxArticlesOnFeedAdd(userID int, maxArticles int) error
 	return err
 }
 
+func (db *DB) UpdateUserDigestPreferences(userID int, prefs DigestPreferences) error {
+	_, err := db.Exec(`UPDATE users SET digest_frequency = ?, digest_timezone = ?, digest_hour = ?, digest_weekday = ?
+		WHERE id = ?`, prefs.Frequency, prefs.Timezone, prefs.Hour, prefs.Weekday, userID)
+	return err
+}
+
+// GetDigestUsers returns the users who have opted in to email digests.
+func (db *DB) GetDigestUsers() ([]User, error) {
+	rows, err := db.Query(`SELECT ` + userColumns + ` FROM users
+		WHERE COALESCE(digest_frequency, '') != '' ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (db *DB) SetUserDigestSentAt(userID int, sentAt time.Time) error {
+	_, err := db.Exec(`UPDATE users SET digest_last_sent_at = ? WHERE id = ?`, sentAt, userID)
+	return err
+}
+
 // Admin management methods
 func (db *DB) SetUserAdmin(userID int, isAdmin bool) error {
 	query := `UPDATE users SET is_admin = ? WHERE id = ?`
@@ -1444,68 +2383,81 @@ func (db *DB) GrantFreeMonths(userID int, months int) error {
 	return err
 }
 
+// GetUserByEmail matches case-insensitively, since accounts created before
+// addresses were normalized may be stored in mixed case. The oldest account
+// wins if two such addresses differ only in case.
 func (db *DB) GetUserByEmail(email string) (*User, error) {
-	query := `SELECT id, google_id, email, name, avatar, created_at,
-			  COALESCE(subscription_status, 'trial') as subscription_status,
-			  COALESCE(subscription_id, '') as subscription_id,
-			  trial_ends_at, last_payment_date, next_billing_date,
-			  COALESCE(is_admin, 0) as is_admin,
-			  COALESCE(free_months_remaining, 0) as free_months_remaining,
-			  COALESCE(max_articles_on_feed_add, 100) as max_articles_on_feed_add
-			  FROM users WHERE email = ?`
-
-	var user User
-	var trialEndsAt sql.NullTime
-	var lastPaymentDate sql.NullTime
-	var nextBillingDate sql.NullTime
-
-	err := db.QueryRow(query, email).Scan(
-		&user.ID, &user.GoogleID, &user.Email, &user.Name, &user.Avatar,
-		&user.CreatedAt, &user.SubscriptionStatus, &user.SubscriptionID,
-		&trialEndsAt, &lastPaymentDate, &nextBillingDate, &user.IsAdmin, &user.FreeMonthsRemaining, &user.MaxArticlesOnFeedAdd,
-	)
+	return scanUser(db.QueryRow(`SELECT `+userColumns+` FROM users WHERE LOWER(email) = ? ORDER BY id LIMIT 1`, NormalizeEmail(email)))
+}
 
+func (db *DB) DeleteUser(userID int) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	// Handle nullable datetime fields
-	if trialEndsAt.Valid {
-		user.TrialEndsAt = trialEndsAt.Time
-	} else {
-		// Set default trial end date if not set
-		user.TrialEndsAt = user.CreatedAt.AddDate(0, 0, 30)
+	// Foreign keys aren't enforced, so children go first. user_feeds comes
+	// before the sync tables because its delete trigger records a change.
+	statements := []string{
+		`DELETE FROM refresh_tokens WHERE user_id = ?`,
+		`DELETE FROM sessions WHERE user_id = ?`,
+		`DELETE FROM user_identities WHERE user_id = ?`,
+		`DELETE FROM user_feeds WHERE user_id = ?`,
+		`DELETE FROM user_articles WHERE user_id = ?`,
+		`DELETE FROM article_tags WHERE user_id = ?`,
+		`DELETE FROM tags WHERE user_id = ?`,
+		`DELETE FROM annotations WHERE user_id = ?`,
+		`DELETE FROM undo_operations WHERE user_id = ?`,
+		`DELETE FROM webhook_deliveries WHERE user_id = ?`,
+		`DELETE FROM webhooks WHERE user_id = ?`,
+		`DELETE FROM inbound_addresses WHERE user_id = ?`,
+		`DELETE FROM user_feed_changes WHERE user_id = ?`,
+		`DELETE FROM user_sync_state WHERE user_id = ?`,
+		`DELETE FROM users WHERE id = ?`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, userID); err != nil {
+			return err
+		}
 	}
+	return tx.Commit()
+}
 
-	if lastPaymentDate.Valid {
-		user.LastPaymentDate = lastPaymentDate.Time
-	}
+// Session methods for SQLite
 
-	if nextBillingDate.Valid {
-		user.NextBillingDate = nextBillingDate.Time
-	}
+// sessionColumns lists the session columns in the order scanSession reads
+// them.
+const sessionColumns = `id, user_id, created_at, expires_at, COALESCE(user_agent, ''), COALESCE(client_type, ''),
+	COALESCE(ip_address, ''), last_seen_at`
 
-	return &user, nil
+// scanSession reads a row selected with sessionColumns. Sessions created
+// before activity was tracked were last seen when they were created.
+func scanSession(row interface{ Scan(...interface{}) error }) (Session, error) {
+	var s Session
+	var lastSeen sql.NullTime
+	err := row.Scan(&s.ID, &s.UserID, &s.CreatedAt, &s.ExpiresAt, &s.UserAgent, &s.ClientType, &s.IPAddress, &lastSeen)
+	s.LastSeenAt = s.CreatedAt
+	if lastSeen.Valid {
+		s.LastSeenAt = lastSeen.Time
+	}
+	return s, err
 }
 
-// Session methods for SQLite
 func (db *DB) CreateSession(session *Session) error {
-	query := `INSERT INTO sessions (id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, session.ID, session.UserID, session.CreatedAt, session.ExpiresAt)
+	lastSeen := session.LastSeenAt
+	if lastSeen.IsZero() {
+		lastSeen = session.CreatedAt
+	}
+	query := `INSERT INTO sessions (id, user_id, created_at, expires_at, user_agent, client_type, ip_address, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, session.ID, session.UserID, session.CreatedAt, session.ExpiresAt,
+		session.UserAgent, session.ClientType, session.IPAddress, lastSeen)
 	return err
 }
 
 func (db *DB) GetSession(sessionID string) (*Session, error) {
-	query := `SELECT id, user_id, created_at, expires_at FROM sessions WHERE id = ?`
-
-	var session Session
-	err := db.QueryRow(query, sessionID).Scan(
-		&session.ID,
-		&session.UserID,
-		&session.CreatedAt,
-		&session.ExpiresAt,
-	)
-
+	session, err := scanSession(db.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, sessionID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -1522,18 +2474,151 @@ func (db *DB) UpdateSessionExpiry(sessionID string, newExpiry time.Time) error {
 	return err
 }
 
+func (db *DB) UpdateSessionActivity(sessionID string, lastSeenAt time.Time, ipAddress string) error {
+	_, err := db.Exec(`UPDATE sessions SET last_seen_at = ?, ip_address = ? WHERE id = ?`, lastSeenAt, ipAddress, sessionID)
+	return err
+}
+
 func (db *DB) DeleteSession(sessionID string) error {
 	query := `DELETE FROM sessions WHERE id = ?`
 	_, err := db.Exec(query, sessionID)
 	return err
 }
 
+func (db *DB) GetUserSessions(userID int) ([]Session, error) {
+	rows, err := db.Query(`SELECT `+sessionColumns+` FROM sessions
+		WHERE user_id = ? ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
 func (db *DB) DeleteExpiredSessions() error {
 	query := `DELETE FROM sessions WHERE expires_at < ?`
 	_, err := db.Exec(query, time.Now())
 	return err
 }
 
+// Identity methods for SQLite
+
+func (db *DB) CreateUserIdentity(identity *UserIdentity) error {
+	query := `INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	return err
+}
+
+func (db *DB) GetUserIdentity(provider, subject string) (*UserIdentity, error) {
+	var identity UserIdentity
+	query := `SELECT user_id, provider, subject, email, created_at FROM user_identities WHERE provider = ? AND subject = ?`
+	err := db.QueryRow(query, provider, subject).Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (db *DB) GetUserIdentities(userID int) ([]UserIdentity, error) {
+	rows, err := db.Query(`SELECT user_id, provider, subject, email, created_at FROM user_identities
+		WHERE user_id = ? ORDER BY created_at, provider`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	identities := []UserIdentity{}
+	for rows.Next() {
+		var identity UserIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// Login token methods for SQLite
+
+func (db *DB) CreateLoginToken(token *LoginToken) error {
+	query := `INSERT INTO login_tokens (token_hash, email, mobile, expires_at) VALUES (?, ?, ?, ?)`
+	_, err := db.Exec(query, token.TokenHash, token.Email, token.Mobile, token.ExpiresAt)
+	return err
+}
+
+func (db *DB) ConsumeLoginToken(tokenHash string) (*LoginToken, error) {
+	var token LoginToken
+	query := `DELETE FROM login_tokens WHERE token_hash = ? RETURNING token_hash, email, mobile, expires_at`
+	err := db.QueryRow(query, tokenHash).Scan(&token.TokenHash, &token.Email, &token.Mobile, &token.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (db *DB) DeleteExpiredLoginTokens() error {
+	_, err := db.Exec(`DELETE FROM login_tokens WHERE expires_at < ?`, time.Now())
+	return err
+}
+
+// Refresh token methods for SQLite
+
+const refreshTokenColumns = `token_hash, session_id, user_id, created_at, expires_at, used_at`
+
+func scanRefreshToken(row interface{ Scan(...interface{}) error }) (*RefreshToken, error) {
+	var token RefreshToken
+	var usedAt sql.NullTime
+	if err := row.Scan(&token.TokenHash, &token.SessionID, &token.UserID, &token.CreatedAt, &token.ExpiresAt, &usedAt); err != nil {
+		return nil, err
+	}
+	token.UsedAt = usedAt.Time
+	return &token, nil
+}
+
+func (db *DB) CreateRefreshToken(token *RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (token_hash, session_id, user_id, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := db.Exec(query, token.TokenHash, token.SessionID, token.UserID, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+func (db *DB) UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error) {
+	// Only one caller can win the update; anyone else finds it used
+	token, err := scanRefreshToken(db.QueryRow(`UPDATE refresh_tokens SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL RETURNING `+refreshTokenColumns, usedAt, tokenHash))
+	if err == nil {
+		token.UsedAt = time.Time{}
+		return token, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+	token, err = scanRefreshToken(db.QueryRow(`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = ?`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return token, err
+}
+
+func (db *DB) DeleteExpiredRefreshTokens() error {
+	_, err := db.Exec(`DELETE FROM refresh_tokens WHERE expires_at < ?`, time.Now())
+	return err
+}
+
 // Audit log methods for SQLite
 func (db *DB) CreateAuditLog(log *AuditLog) error {
 	query := `INSERT INTO audit_logs
@@ -1646,3 +2731,999 @@ func (db *DB) GetAuditLogs(limit, offset int, filters map[string]interface{}) ([
 
 	return logs, nil
 }
+
+// Annotation methods for SQLite
+func (db *DB) CreateAnnotation(annotation *Annotation) error {
+	query := `INSERT INTO annotations
+		(user_id, article_id, quoted_text, start_offset, end_offset, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := db.Exec(query,
+		annotation.UserID,
+		annotation.ArticleID,
+		annotation.QuotedText,
+		annotation.StartOffset,
+		annotation.EndOffset,
+		annotation.Note,
+		annotation.CreatedAt,
+		annotation.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	annotation.ID = int(id)
+	return nil
+}
+
+func (db *DB) GetAnnotation(userID, annotationID int) (*Annotation, error) {
+	query := `SELECT id, user_id, article_id, quoted_text, start_offset, end_offset,
+		COALESCE(note, ''), created_at, updated_at
+		FROM annotations WHERE id = ? AND user_id = ?`
+
+	var annotation Annotation
+	err := db.QueryRow(query, annotationID, userID).Scan(
+		&annotation.ID, &annotation.UserID, &annotation.ArticleID, &annotation.QuotedText,
+		&annotation.StartOffset, &annotation.EndOffset, &annotation.Note,
+		&annotation.CreatedAt, &annotation.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+func (db *DB) GetArticleAnnotations(userID, articleID int) ([]Annotation, error) {
+	return db.queryAnnotations(`SELECT id, user_id, article_id, quoted_text, start_offset, end_offset,
+		COALESCE(note, ''), created_at, updated_at
+		FROM annotations WHERE user_id = ? AND article_id = ?
+		ORDER BY start_offset, id`, userID, articleID)
+}
+
+func (db *DB) GetUserAnnotations(userID int) ([]Annotation, error) {
+	return db.queryAnnotations(`SELECT id, user_id, article_id, quoted_text, start_offset, end_offset,
+		COALESCE(note, ''), created_at, updated_at
+		FROM annotations WHERE user_id = ?
+		ORDER BY article_id, start_offset, id`, userID)
+}
+
+func (db *DB) queryAnnotations(query string, args ...interface{}) ([]Annotation, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	annotations := []Annotation{}
+	for rows.Next() {
+		var annotation Annotation
+		if err := rows.Scan(
+			&annotation.ID, &annotation.UserID, &annotation.ArticleID, &annotation.QuotedText,
+			&annotation.StartOffset, &annotation.EndOffset, &annotation.Note,
+			&annotation.CreatedAt, &annotation.UpdatedAt); err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations, rows.Err()
+}
+
+func (db *DB) UpdateAnnotation(annotation *Annotation) error {
+	query := `UPDATE annotations
+		SET quoted_text = ?, start_offset = ?, end_offset = ?, note = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`
+	_, err := db.Exec(query, annotation.QuotedText, annotation.StartOffset, annotation.EndOffset,
+		annotation.Note, annotation.UpdatedAt, annotation.ID, annotation.UserID)
+	return err
+}
+
+func (db *DB) DeleteAnnotation(userID, annotationID int) error {
+	query := `DELETE FROM annotations WHERE id = ? AND user_id = ?`
+	_, err := db.Exec(query, annotationID, userID)
+	return err
+}
+
+// Tag methods for SQLite
+func (db *DB) CreateTag(tag *Tag) error {
+	query := `INSERT INTO tags (user_id, name, created_at) VALUES (?, ?, ?)`
+	result, err := db.Exec(query, tag.UserID, tag.Name, tag.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrTagExists
+		}
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tag.ID = int(id)
+	return nil
+}
+
+func (db *DB) GetTag(userID, tagID int) (*Tag, error) {
+	return db.getTag(`SELECT id, user_id, name, created_at FROM tags WHERE id = ? AND user_id = ?`, tagID, userID)
+}
+
+func (db *DB) GetTagByName(userID int, name string) (*Tag, error) {
+	return db.getTag(`SELECT id, user_id, name, created_at FROM tags WHERE user_id = ? AND name = ?`, userID, name)
+}
+
+func (db *DB) getTag(query string, args ...interface{}) (*Tag, error) {
+	var tag Tag
+	err := db.QueryRow(query, args...).Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (db *DB) GetUserTags(userID int) ([]Tag, error) {
+	query := `SELECT t.id, t.user_id, t.name, t.created_at, COUNT(at.article_id)
+		FROM tags t
+		LEFT JOIN article_tags at ON at.tag_id = t.id
+		WHERE t.user_id = ?
+		GROUP BY t.id
+		ORDER BY t.name`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.ArticleCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (db *DB) RenameTag(userID, tagID int, name string) error {
+	_, err := db.Exec(`UPDATE tags SET name = ? WHERE id = ? AND user_id = ?`, name, tagID, userID)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return ErrTagExists
+	}
+	return err
+}
+
+func (db *DB) DeleteTag(userID, tagID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Delete taggings explicitly rather than relying on ON DELETE CASCADE,
+	// which only applies when foreign key enforcement is enabled.
+	if _, err := tx.Exec(`DELETE FROM article_tags WHERE tag_id = ? AND user_id = ?`, tagID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM tags WHERE id = ? AND user_id = ?`, tagID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) TagArticle(userID, articleID, tagID int) error {
+	query := `INSERT OR IGNORE INTO article_tags (user_id, article_id, tag_id, created_at) VALUES (?, ?, ?, ?)`
+	_, err := db.Exec(query, userID, articleID, tagID, time.Now())
+	return err
+}
+
+func (db *DB) UntagArticle(userID, articleID, tagID int) error {
+	query := `DELETE FROM article_tags WHERE user_id = ? AND article_id = ? AND tag_id = ?`
+	_, err := db.Exec(query, userID, articleID, tagID)
+	return err
+}
+
+// GetArticleTags returns the user's tag names for each of the given articles,
+// sorted by name. Articles without tags are omitted from the map.
+func (db *DB) GetArticleTags(userID int, articleIDs []int) (map[int][]string, error) {
+	result := make(map[int][]string)
+	if len(articleIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(articleIDs))
+	args := make([]interface{}, 0, len(articleIDs)+1)
+	args = append(args, userID)
+	for i, id := range articleIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := `SELECT at.article_id, t.name
+		FROM article_tags at
+		JOIN tags t ON t.id = at.tag_id
+		WHERE at.user_id = ? AND at.article_id IN (` + strings.Join(placeholders, ",") + `)
+		ORDER BY t.name`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var articleID int
+		var name string
+		if err := rows.Scan(&articleID, &name); err != nil {
+			return nil, err
+		}
+		result[articleID] = append(result[articleID], name)
+	}
+	return result, rows.Err()
+}
+
+// GetTagArticlesPaginated lists the articles carrying a tag, newest first, using
+// the same keyset cursor as GetUserArticlesPaginated. Tagged articles remain
+// listed after the user unsubscribes from their feed.
+func (db *DB) GetTagArticlesPaginated(userID, tagID int, limit int, cursor string) (*ArticlePaginationResult, error) {
+	query := `SELECT a.id, a.feed_id, f.title as feed_title, a.title, a.url, a.description, a.author,
+			  a.published_at, a.created_at, a.reading_time_minutes,
+			  COALESCE(ua.is_read, 0) as is_read,
+			  COALESCE(ua.is_starred, 0) as is_starred,
+			  COALESCE(ua.read_progress, 0) as read_progress
+			  FROM article_tags at
+			  JOIN articles a ON a.id = at.article_id
+			  JOIN feeds f ON a.feed_id = f.id
+			  LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = ?
+			  WHERE at.user_id = ? AND at.tag_id = ?`
+	args := []interface{}{userID, userID, tagID}
+
+	if cursor != "" {
+		cursorData, err := decodeSQLiteCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		query += ` AND (a.published_at < ? OR (a.published_at = ? AND a.id < ?))`
+		args = append(args, cursorData.PublishedAt, cursorData.PublishedAt, cursorData.ID)
+	}
+
+	query += ` ORDER BY a.published_at DESC, a.id DESC LIMIT ?`
+	args = append(args, limit+paginationOverfetch)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	articles := []Article{}
+	for rows.Next() {
+		var article Article
+		err := rows.Scan(&article.ID, &article.FeedID, &article.FeedTitle, &article.Title, &article.URL,
+			&article.Description, &article.Author,
+			&article.PublishedAt, &article.CreatedAt, &article.ReadingTimeMinutes,
+			&article.IsRead, &article.IsStarred, &article.ReadProgress)
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+
+	var nextCursor string
+	if len(articles) > limit {
+		lastArticle := articles[limit-1]
+		nextCursor = encodeSQLiteCursor(lastArticle.ID, lastArticle.PublishedAt)
+		articles = articles[:limit]
+	}
+
+	return &ArticlePaginationResult{
+		Articles:   articles,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// Undo methods for SQLite
+
+// GetUserArticleStatuses returns the user's status rows for the given
+// articles. Articles without a row are omitted (they are unread and unstarred).
+func (db *DB) GetUserArticleStatuses(userID int, articleIDs []int) (map[int]UserArticle, error) {
+	statuses := make(map[int]UserArticle)
+	const chunkSize = 500
+	for i := 0; i < len(articleIDs); i += chunkSize {
+		end := i + chunkSize
+		if end > len(articleIDs) {
+			end = len(articleIDs)
+		}
+		chunk := articleIDs[i:end]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)+1)
+		args = append(args, userID)
+		for j, id := range chunk {
+			placeholders[j] = "?"
+			args = append(args, id)
+		}
+
+		query := `SELECT user_id, article_id, is_read, is_starred, read_progress FROM user_articles
+			WHERE user_id = ? AND article_id IN (` + strings.Join(placeholders, ",") + `)`
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var ua UserArticle
+			if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			statuses[ua.ArticleID] = ua
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return statuses, nil
+}
+
+func (db *DB) CreateUndoOperation(op *UndoOperation) error {
+	changes, err := encodeStatusChanges(op.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode undo changes: %w", err)
+	}
+	query := `INSERT INTO undo_operations (id, user_id, kind, changes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	_, err = db.Exec(query, op.ID, op.UserID, op.Kind, changes, op.CreatedAt, op.ExpiresAt)
+	return err
+}
+
+func (db *DB) ConsumeUndoOperation(userID int, operationID string) (*UndoOperation, error) {
+	query := `DELETE FROM undo_operations WHERE id = ? AND user_id = ?
+		RETURNING id, user_id, kind, changes, created_at, expires_at`
+
+	var op UndoOperation
+	var changes string
+	err := db.QueryRow(query, operationID, userID).Scan(&op.ID, &op.UserID, &op.Kind, &changes, &op.CreatedAt, &op.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	op.Changes, err = decodeStatusChanges(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode undo changes: %w", err)
+	}
+	return &op, nil
+}
+
+func (db *DB) DeleteExpiredUndoOperations(before time.Time) (int, error) {
+	result, err := db.Exec(`DELETE FROM undo_operations WHERE expires_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// sqliteTimestampFormat matches CURRENT_TIMESTAMP so the trigger-written and
+// explicitly bound updated_at values compare correctly as text.
+const sqliteTimestampFormat = "2006-01-02 15:04:05"
+
+// GetUserSyncVersion returns the user's current change version, or 0 if
+// nothing has changed yet.
+func (db *DB) GetUserSyncVersion(userID int) (int64, error) {
+	var version int64
+	err := db.QueryRow(`SELECT version FROM user_sync_state WHERE user_id = ?`, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return version, err
+}
+
+// GetUserArticleChanges returns up to limit of the user's article states
+// changed after sinceVersion, oldest change first.
+func (db *DB) GetUserArticleChanges(userID int, sinceVersion int64, limit int) ([]UserArticle, error) {
+	rows, err := db.Query(`SELECT user_id, article_id, is_read, is_starred, read_progress, version, updated_at
+		FROM user_articles
+		WHERE user_id = ? AND version > ?
+		ORDER BY version
+		LIMIT ?`, userID, sinceVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []UserArticle{}
+	for rows.Next() {
+		var ua UserArticle
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress, &ua.Version, &updatedAt); err != nil {
+			return nil, err
+		}
+		ua.UpdatedAt = updatedAt.Time
+		changes = append(changes, ua)
+	}
+	return changes, rows.Err()
+}
+
+// GetUserFeedChanges returns the user's subscription changes after
+// sinceVersion, oldest first. Only the latest change per feed is kept.
+func (db *DB) GetUserFeedChanges(userID int, sinceVersion int64) ([]FeedSubscriptionChange, error) {
+	rows, err := db.Query(`SELECT feed_id, subscribed, version FROM user_feed_changes
+		WHERE user_id = ? AND version > ?
+		ORDER BY version`, userID, sinceVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []FeedSubscriptionChange{}
+	for rows.Next() {
+		var c FeedSubscriptionChange
+		if err := rows.Scan(&c.FeedID, &c.Subscribed, &c.Version); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetNewUserArticles returns up to limit articles from the user's feeds created
+// after the given position, in creation order, with the user's flags.
+func (db *DB) GetNewUserArticles(userID int, after ArticlePosition, limit int) ([]Article, error) {
+	rows, err := db.Query(`SELECT a.id, a.feed_id, f.title, a.title, a.url, a.content, a.description, a.author,
+			a.published_at, a.created_at, a.reading_time_minutes,
+			COALESCE(ua.is_read, 0), COALESCE(ua.is_starred, 0), COALESCE(ua.read_progress, 0)
+		FROM articles a
+		JOIN feeds f ON a.feed_id = f.id
+		JOIN user_feeds uf ON a.feed_id = uf.feed_id AND uf.user_id = ?
+		LEFT JOIN user_articles ua ON a.id = ua.article_id AND ua.user_id = ?
+		WHERE a.created_at > ? OR (a.created_at = ? AND a.id > ?)
+		ORDER BY a.created_at, a.id
+		LIMIT ?`, userID, userID, after.CreatedAt, after.CreatedAt, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	articles := []Article{}
+	for rows.Next() {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.FeedTitle, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes,
+			&a.IsRead, &a.IsStarred, &a.ReadProgress); err != nil {
+			return nil, err
+		}
+		articles = append(articles, a)
+	}
+	return articles, rows.Err()
+}
+
+// ApplyUserArticleChange applies a client's change unless the stored state was
+// updated more recently, reporting whether it was applied.
+func (db *DB) ApplyUserArticleChange(userID int, change UserArticleChange) (bool, error) {
+	// Values for a row that doesn't exist yet
+	isRead, isStarred, progress := false, false, 0
+	var sets []string
+	if change.IsRead != nil {
+		isRead = *change.IsRead
+		sets = append(sets, "is_read = excluded.is_read")
+	}
+	if change.IsStarred != nil {
+		isStarred = *change.IsStarred
+		sets = append(sets, "is_starred = excluded.is_starred")
+	}
+	if change.ReadProgress != nil {
+		progress = *change.ReadProgress
+		sets = append(sets, "read_progress = excluded.read_progress")
+	}
+	if len(sets) == 0 {
+		return false, nil
+	}
+
+	query := `INSERT INTO user_articles (user_id, article_id, is_read, is_starred, read_progress, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, article_id) DO UPDATE SET ` + strings.Join(sets, ", ") + `, updated_at = excluded.updated_at
+		WHERE user_articles.updated_at IS NULL OR user_articles.updated_at <= excluded.updated_at`
+	result, err := db.Exec(query, userID, change.ArticleID, isRead, isStarred, progress,
+		change.At.UTC().Format(sqliteTimestampFormat))
+	if err != nil {
+		return false, fmt.Errorf("failed to apply article change: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Webhook methods for SQLite
+
+// webhookColumns lists the webhooks columns read by scanWebhook.
+const webhookColumns = `id, user_id, url, secret, feed_id, keywords, enabled, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	var w Webhook
+	var keywords string
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &w.FeedID, &keywords, &w.Enabled, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Keywords = splitKeywords(keywords)
+	return &w, nil
+}
+
+// Keywords are stored one per line; validation keeps newlines out of them.
+func joinKeywords(keywords []string) string {
+	return strings.Join(keywords, "\n")
+}
+
+func splitKeywords(keywords string) []string {
+	if keywords == "" {
+		return []string{}
+	}
+	return strings.Split(keywords, "\n")
+}
+
+func (db *DB) CreateWebhook(webhook *Webhook) error {
+	query := `INSERT INTO webhooks (user_id, url, secret, feed_id, keywords, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.Exec(query, webhook.UserID, webhook.URL, webhook.Secret, webhook.FeedID,
+		joinKeywords(webhook.Keywords), webhook.Enabled, webhook.CreatedAt, webhook.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.ID = int(id)
+	return nil
+}
+
+func (db *DB) GetWebhook(userID, webhookID int) (*Webhook, error) {
+	row := db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ? AND user_id = ?`, webhookID, userID)
+	webhook, err := scanWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return webhook, err
+}
+
+func (db *DB) GetUserWebhooks(userID int) ([]Webhook, error) {
+	return db.queryWebhooks(`SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY id`, userID)
+}
+
+// GetFeedWebhooks returns the enabled webhooks that cover the feed and belong
+// to one of its subscribers.
+func (db *DB) GetFeedWebhooks(feedID int) ([]Webhook, error) {
+	query := `SELECT w.id, w.user_id, w.url, w.secret, w.feed_id, w.keywords, w.enabled, w.created_at, w.updated_at
+		FROM webhooks w
+		JOIN user_feeds uf ON uf.user_id = w.user_id AND uf.feed_id = ?
+		WHERE w.enabled = 1 AND (w.feed_id = 0 OR w.feed_id = ?)
+		ORDER BY w.id`
+	return db.queryWebhooks(query, feedID, feedID)
+}
+
+func (db *DB) queryWebhooks(query string, args ...interface{}) ([]Webhook, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (db *DB) UpdateWebhook(webhook *Webhook) error {
+	query := `UPDATE webhooks SET url = ?, secret = ?, feed_id = ?, keywords = ?, enabled = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`
+	_, err := db.Exec(query, webhook.URL, webhook.Secret, webhook.FeedID, joinKeywords(webhook.Keywords),
+		webhook.Enabled, webhook.UpdatedAt, webhook.ID, webhook.UserID)
+	return err
+}
+
+func (db *DB) DeleteWebhook(userID, webhookID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ? AND user_id = ?`, webhookID, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, webhookID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries
+		(webhook_id, user_id, event_id, event, article_id, attempt, status_code, success, error, duration_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := db.Exec(query, delivery.WebhookID, delivery.UserID, delivery.EventID, delivery.Event,
+		delivery.ArticleID, delivery.Attempt, delivery.StatusCode, delivery.Success, delivery.Error,
+		delivery.DurationMS, delivery.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	delivery.ID = int(id)
+	return nil
+}
+
+// GetWebhookDeliveries returns up to limit of the webhook's most recent
+// delivery attempts, newest first.
+func (db *DB) GetWebhookDeliveries(userID, webhookID, limit int) ([]WebhookDelivery, error) {
+	query := `SELECT id, webhook_id, user_id, event_id, event, article_id, attempt, status_code, success, error, duration_ms, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`
+	rows, err := db.Query(query, webhookID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.EventID, &d.Event, &d.ArticleID, &d.Attempt,
+			&d.StatusCode, &d.Success, &d.Error, &d.DurationMS, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (db *DB) DeleteWebhookDeliveriesBefore(before time.Time) (int, error) {
+	result, err := db.Exec(`DELETE FROM webhook_deliveries WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// Inbound email methods for SQLite
+
+func (db *DB) CreateInboundAddress(address *InboundAddress, feed *Feed) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.Exec(insertFeedQuery, feed.Title, feed.URL, feed.Description,
+		feed.CreatedAt, feed.UpdatedAt, feed.LastFetch, feed.LastChecked, feed.LastHadNewContent, feed.AverageUpdateInterval,
+		feed.ETag, feed.LastModified)
+	if err != nil {
+		return err
+	}
+	feedID, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO user_feeds (user_id, feed_id) VALUES (?, ?)`, address.UserID, feedID); err != nil {
+		return err
+	}
+	result, err = tx.Exec(`INSERT INTO inbound_addresses (user_id, feed_id, token, created_at) VALUES (?, ?, ?, ?)`,
+		address.UserID, feedID, address.Token, address.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	feed.ID = int(feedID)
+	address.FeedID = feed.ID
+	address.ID = int(id)
+	return nil
+}
+
+func (db *DB) GetInboundAddressByToken(token string) (*InboundAddress, error) {
+	var a InboundAddress
+	query := `SELECT id, user_id, feed_id, token, created_at FROM inbound_addresses WHERE token = ?`
+	err := db.QueryRow(query, token).Scan(&a.ID, &a.UserID, &a.FeedID, &a.Token, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (db *DB) GetUserInboundAddresses(userID int) ([]InboundAddress, error) {
+	query := `SELECT id, user_id, feed_id, token, created_at FROM inbound_addresses WHERE user_id = ? ORDER BY id`
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	addresses := []InboundAddress{}
+	for rows.Next() {
+		var a InboundAddress
+		if err := rows.Scan(&a.ID, &a.UserID, &a.FeedID, &a.Token, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+func (db *DB) DeleteInboundAddress(userID, addressID int) error {
+	_, err := db.Exec(`DELETE FROM inbound_addresses WHERE id = ? AND user_id = ?`, addressID, userID)
+	return err
+}
+
+// Directory methods for SQLite
+
+func (db *DB) GetAllFeedSubscriptions() ([]FeedSubscription, error) {
+	rows, err := db.Query(`SELECT user_id, feed_id FROM user_feeds`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var subscriptions []FeedSubscription
+	for rows.Next() {
+		var s FeedSubscription
+		if err := rows.Scan(&s.UserID, &s.FeedID); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (db *DB) GetFeedCategories() (map[int]string, error) {
+	rows, err := db.Query(`SELECT feed_id, category FROM feed_categories`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	categories := make(map[int]string)
+	for rows.Next() {
+		var feedID int
+		var category string
+		if err := rows.Scan(&feedID, &category); err != nil {
+			return nil, err
+		}
+		categories[feedID] = category
+	}
+	return categories, rows.Err()
+}
+
+// SetFeedCategory assigns a feed's directory category, or clears it when
+// category is empty. A feed already in the directory is updated immediately.
+func (db *DB) SetFeedCategory(feedID int, category string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if category == "" {
+		_, err = tx.Exec(`DELETE FROM feed_categories WHERE feed_id = ?`, feedID)
+	} else {
+		_, err = tx.Exec(`INSERT INTO feed_categories (feed_id, category) VALUES (?, ?)
+			ON CONFLICT(feed_id) DO UPDATE SET category = excluded.category`, feedID, category)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE directory_feeds SET category = ? WHERE feed_id = ?`, category, feedID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceFeedDirectory swaps in a new directory and recommendation set in one
+// transaction, so readers never see a partial job.
+func (db *DB) ReplaceFeedDirectory(feeds []DirectoryFeed, recommendations []FeedRecommendation) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM directory_feeds`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM feed_recommendations`); err != nil {
+		return err
+	}
+
+	feedStmt, err := tx.Prepare(`INSERT INTO directory_feeds (feed_id, title, url, description, category, subscribers, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = feedStmt.Close() }()
+	for _, f := range feeds {
+		if _, err := feedStmt.Exec(f.FeedID, f.Title, f.URL, f.Description, f.Category, f.Subscribers, f.UpdatedAt); err != nil {
+			return err
+		}
+	}
+
+	recStmt, err := tx.Prepare(`INSERT INTO feed_recommendations (feed_id, recommended_feed_id, shared_subscribers, score)
+		VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = recStmt.Close() }()
+	for _, r := range recommendations {
+		if _, err := recStmt.Exec(r.FeedID, r.RecommendedFeedID, r.SharedSubscribers, r.Score); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db *DB) GetDirectoryFeeds() ([]DirectoryFeed, error) {
+	rows, err := db.Query(`SELECT feed_id, title, url, description, category, subscribers, updated_at
+		FROM directory_feeds ORDER BY subscribers DESC, title, feed_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	feeds := []DirectoryFeed{}
+	for rows.Next() {
+		var f DirectoryFeed
+		if err := rows.Scan(&f.FeedID, &f.Title, &f.URL, &f.Description, &f.Category, &f.Subscribers, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+func (db *DB) GetFeedRecommendations(feedIDs []int) ([]FeedRecommendation, error) {
+	if len(feedIDs) == 0 {
+		return []FeedRecommendation{}, nil
+	}
+
+	recommendations := []FeedRecommendation{}
+	const chunkSize = 500
+	for start := 0; start < len(feedIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(feedIDs) {
+			end = len(feedIDs)
+		}
+		chunk := feedIDs[start:end]
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = "?"
+			args[i] = id
+		}
+
+		rows, err := db.Query(`SELECT feed_id, recommended_feed_id, shared_subscribers, score
+			FROM feed_recommendations WHERE feed_id IN (`+strings.Join(placeholders, ",")+`)
+			ORDER BY feed_id, score DESC, recommended_feed_id`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var r FeedRecommendation
+			if err := rows.Scan(&r.FeedID, &r.RecommendedFeedID, &r.SharedSubscribers, &r.Score); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			recommendations = append(recommendations, r)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return recommendations, nil
+}
+
+// forEachRow hands each row of query to scan without loading the result into memory.
+func forEachRow(db *sql.DB, query string, scan func(*sql.Rows) error) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (db *DB) ForEachUser(fn func(User) error) error {
+	return forEachRow(db.DB, `SELECT `+userColumns+` FROM users ORDER BY id`, func(rows *sql.Rows) error {
+		user, err := scanUser(rows)
+		if err != nil {
+			return err
+		}
+		return fn(*user)
+	})
+}
+
+func (db *DB) ForEachArticle(fn func(Article) error) error {
+	return forEachRow(db.DB, `SELECT id, feed_id, title, url, content, description, author,
+		published_at, created_at, reading_time_minutes
+		FROM articles ORDER BY id`, func(rows *sql.Rows) error {
+		var a Article
+		if err := rows.Scan(&a.ID, &a.FeedID, &a.Title, &a.URL, &a.Content, &a.Description, &a.Author,
+			&a.PublishedAt, &a.CreatedAt, &a.ReadingTimeMinutes); err != nil {
+			return err
+		}
+		return fn(a)
+	})
+}
+
+func (db *DB) ForEachUserArticle(fn func(UserArticle) error) error {
+	return forEachRow(db.DB, `SELECT user_id, article_id, is_read, is_starred, COALESCE(read_progress, 0)
+		FROM user_articles ORDER BY user_id, article_id`, func(rows *sql.Rows) error {
+		var ua UserArticle
+		if err := rows.Scan(&ua.UserID, &ua.ArticleID, &ua.IsRead, &ua.IsStarred, &ua.ReadProgress); err != nil {
+			return err
+		}
+		return fn(ua)
+	})
+}
+
+func (db *DB) ForEachSession(fn func(Session) error) error {
+	return forEachRow(db.DB, `SELECT `+sessionColumns+` FROM sessions ORDER BY created_at, id`, func(rows *sql.Rows) error {
+		session, err := scanSession(rows)
+		if err != nil {
+			return err
+		}
+		return fn(session)
+	})
+}
+
+func (db *DB) ForEachUserIdentity(fn func(UserIdentity) error) error {
+	return forEachRow(db.DB, `SELECT user_id, provider, subject, email, created_at FROM user_identities ORDER BY created_at, provider, subject`, func(rows *sql.Rows) error {
+		var identity UserIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return err
+		}
+		return fn(identity)
+	})
+}
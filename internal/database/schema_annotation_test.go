@@ -0,0 +1,98 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnotationCRUD(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	otherUser := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+
+	now := time.Now()
+	second := &Annotation{UserID: user.ID, ArticleID: article.ID, QuotedText: "test", StartOffset: 10, EndOffset: 14, CreatedAt: now, UpdatedAt: now}
+	first := &Annotation{UserID: user.ID, ArticleID: article.ID, QuotedText: "This", StartOffset: 0, EndOffset: 4, Note: "opening", CreatedAt: now, UpdatedAt: now}
+	for _, a := range []*Annotation{second, first} {
+		if err := db.CreateAnnotation(a); err != nil {
+			t.Fatalf("CreateAnnotation failed: %v", err)
+		}
+		if a.ID == 0 {
+			t.Fatal("expected annotation ID to be set")
+		}
+	}
+
+	t.Run("list is ordered by offset", func(t *testing.T) {
+		annotations, err := db.GetArticleAnnotations(user.ID, article.ID)
+		if err != nil {
+			t.Fatalf("GetArticleAnnotations failed: %v", err)
+		}
+		if len(annotations) != 2 {
+			t.Fatalf("expected 2 annotations, got %d", len(annotations))
+		}
+		if annotations[0].ID != first.ID || annotations[1].ID != second.ID {
+			t.Errorf("expected annotations ordered by start offset, got IDs %d, %d", annotations[0].ID, annotations[1].ID)
+		}
+	})
+
+	t.Run("annotations are private to their owner", func(t *testing.T) {
+		got, err := db.GetAnnotation(otherUser.ID, first.ID)
+		if err != nil {
+			t.Fatalf("GetAnnotation failed: %v", err)
+		}
+		if got != nil {
+			t.Error("expected nil when another user reads the annotation")
+		}
+
+		if err := db.DeleteAnnotation(otherUser.ID, first.ID); err != nil {
+			t.Fatalf("DeleteAnnotation failed: %v", err)
+		}
+		if got, _ := db.GetAnnotation(user.ID, first.ID); got == nil {
+			t.Error("another user's delete should not remove the annotation")
+		}
+	})
+
+	t.Run("update changes note and timestamp", func(t *testing.T) {
+		first.Note = "revised"
+		first.UpdatedAt = now.Add(time.Minute)
+		if err := db.UpdateAnnotation(first); err != nil {
+			t.Fatalf("UpdateAnnotation failed: %v", err)
+		}
+		got, err := db.GetAnnotation(user.ID, first.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetAnnotation failed: %v", err)
+		}
+		if got.Note != "revised" {
+			t.Errorf("expected note %q, got %q", "revised", got.Note)
+		}
+		if !got.UpdatedAt.After(got.CreatedAt) {
+			t.Errorf("expected updated_at after created_at, got %v <= %v", got.UpdatedAt, got.CreatedAt)
+		}
+	})
+
+	t.Run("user export includes all annotations", func(t *testing.T) {
+		annotations, err := db.GetUserAnnotations(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserAnnotations failed: %v", err)
+		}
+		if len(annotations) != 2 {
+			t.Errorf("expected 2 annotations, got %d", len(annotations))
+		}
+	})
+
+	t.Run("deleting the article cascades", func(t *testing.T) {
+		if _, err := db.Exec("DELETE FROM articles WHERE id = ?", article.ID); err != nil {
+			t.Fatalf("failed to delete article: %v", err)
+		}
+		annotations, err := db.GetUserAnnotations(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserAnnotations failed: %v", err)
+		}
+		if len(annotations) != 0 {
+			t.Errorf("expected annotations to be removed with their article, got %d", len(annotations))
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestPreferences(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+
+	users, err := db.GetDigestUsers()
+	if err != nil {
+		t.Fatalf("GetDigestUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Fatalf("expected no digest users before opting in, got %d", len(users))
+	}
+
+	prefs := DigestPreferences{Frequency: "weekly", Timezone: "Europe/Paris", Hour: 7, Weekday: 1}
+	if err := db.UpdateUserDigestPreferences(user.ID, prefs); err != nil {
+		t.Fatalf("UpdateUserDigestPreferences failed: %v", err)
+	}
+	sentAt := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	if err := db.SetUserDigestSentAt(user.ID, sentAt); err != nil {
+		t.Fatalf("SetUserDigestSentAt failed: %v", err)
+	}
+
+	got, err := db.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	if got.DigestFrequency != "weekly" || got.DigestTimezone != "Europe/Paris" || got.DigestHour != 7 || got.DigestWeekday != 1 {
+		t.Errorf("unexpected digest preferences: %+v", got)
+	}
+	if !got.DigestLastSentAt.Equal(sentAt) {
+		t.Errorf("expected last sent %v, got %v", sentAt, got.DigestLastSentAt)
+	}
+
+	users, err = db.GetDigestUsers()
+	if err != nil {
+		t.Fatalf("GetDigestUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != user.ID || users[0].Email != user.Email {
+		t.Fatalf("expected the opted-in user, got %+v", users)
+	}
+
+	if err := db.UpdateUserDigestPreferences(user.ID, DigestPreferences{}); err != nil {
+		t.Fatalf("UpdateUserDigestPreferences failed: %v", err)
+	}
+	users, err = db.GetDigestUsers()
+	if err != nil {
+		t.Fatalf("GetDigestUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("expected no digest users after opting out, got %d", len(users))
+	}
+}
+
+func TestGetUserStarredCountsByFeed(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+	article := createTestArticle(t, db, feed.ID)
+
+	counts, err := db.GetUserStarredCountsByFeed(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserStarredCountsByFeed failed: %v", err)
+	}
+	if len(counts) != 0 {
+		t.Fatalf("expected no starred counts, got %v", counts)
+	}
+
+	if err := db.ToggleUserArticleStar(user.ID, article.ID); err != nil {
+		t.Fatalf("ToggleUserArticleStar failed: %v", err)
+	}
+	counts, err = db.GetUserStarredCountsByFeed(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserStarredCountsByFeed failed: %v", err)
+	}
+	if counts[feed.ID] != 1 {
+		t.Errorf("expected 1 starred article in feed %d, got %v", feed.ID, counts)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedDirectory(t *testing.T) {
+	db := setupTestDB(t)
+
+	alice := createTestUser(t, db)
+	bob := createTestUser(t, db)
+	news := createTestFeed(t, db)
+	blog := createTestFeed(t, db)
+	podcast := createTestFeed(t, db)
+
+	for _, s := range []FeedSubscription{{alice.ID, news.ID}, {alice.ID, blog.ID}, {bob.ID, news.ID}} {
+		if err := db.SubscribeUserToFeed(s.UserID, s.FeedID); err != nil {
+			t.Fatalf("SubscribeUserToFeed failed: %v", err)
+		}
+	}
+	subscriptions, err := db.GetAllFeedSubscriptions()
+	if err != nil {
+		t.Fatalf("GetAllFeedSubscriptions failed: %v", err)
+	}
+	if len(subscriptions) != 3 {
+		t.Errorf("expected 3 subscriptions, got %+v", subscriptions)
+	}
+
+	// A category set before the feed is listed is kept for the job to use
+	if err := db.SetFeedCategory(news.ID, "News"); err != nil {
+		t.Fatalf("SetFeedCategory failed: %v", err)
+	}
+	if categories, err := db.GetFeedCategories(); err != nil || categories[news.ID] != "News" || len(categories) != 1 {
+		t.Fatalf("unexpected categories: %v, %v", categories, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	err = db.ReplaceFeedDirectory(
+		[]DirectoryFeed{
+			{FeedID: blog.ID, Title: "Blog", URL: blog.URL, Subscribers: 3, UpdatedAt: now},
+			{FeedID: news.ID, Title: "News", URL: news.URL, Category: "News", Subscribers: 5, UpdatedAt: now},
+			{FeedID: podcast.ID, Title: "Podcast", URL: podcast.URL, Subscribers: 3, UpdatedAt: now},
+		},
+		[]FeedRecommendation{
+			{FeedID: news.ID, RecommendedFeedID: blog.ID, SharedSubscribers: 3, Score: 0.5},
+			{FeedID: news.ID, RecommendedFeedID: podcast.ID, SharedSubscribers: 3, Score: 0.7},
+			{FeedID: blog.ID, RecommendedFeedID: news.ID, SharedSubscribers: 3, Score: 0.5},
+		},
+	)
+	if err != nil {
+		t.Fatalf("ReplaceFeedDirectory failed: %v", err)
+	}
+
+	feeds, err := db.GetDirectoryFeeds()
+	if err != nil {
+		t.Fatalf("GetDirectoryFeeds failed: %v", err)
+	}
+	if len(feeds) != 3 || feeds[0].FeedID != news.ID || feeds[1].FeedID != blog.ID || feeds[2].FeedID != podcast.ID {
+		t.Fatalf("expected feeds by subscribers then title, got %+v", feeds)
+	}
+	if feeds[0].Category != "News" || feeds[0].Subscribers != 5 || !feeds[0].UpdatedAt.Equal(now) {
+		t.Errorf("unexpected listing: %+v", feeds[0])
+	}
+
+	recs, err := db.GetFeedRecommendations([]int{news.ID})
+	if err != nil {
+		t.Fatalf("GetFeedRecommendations failed: %v", err)
+	}
+	if len(recs) != 2 || recs[0].RecommendedFeedID != podcast.ID || recs[1].RecommendedFeedID != blog.ID {
+		t.Errorf("expected news's recommendations by score, got %+v", recs)
+	}
+	if recs, err := db.GetFeedRecommendations(nil); err != nil || len(recs) != 0 {
+		t.Errorf("expected no recommendations for no feeds, got %+v, %v", recs, err)
+	}
+
+	// Categories change listings straight away
+	if err := db.SetFeedCategory(blog.ID, "Tech"); err != nil {
+		t.Fatalf("SetFeedCategory failed: %v", err)
+	}
+	if err := db.SetFeedCategory(news.ID, ""); err != nil {
+		t.Fatalf("SetFeedCategory failed: %v", err)
+	}
+	feeds, _ = db.GetDirectoryFeeds()
+	if feeds[0].Category != "" || feeds[1].Category != "Tech" {
+		t.Errorf("expected categories to be updated, got %+v", feeds)
+	}
+	if categories, _ := db.GetFeedCategories(); len(categories) != 1 || categories[blog.ID] != "Tech" {
+		t.Errorf("unexpected categories: %v", categories)
+	}
+
+	// The next job replaces everything
+	if err := db.ReplaceFeedDirectory([]DirectoryFeed{{FeedID: blog.ID, Title: "Blog", URL: blog.URL, Subscribers: 4, UpdatedAt: now}}, nil); err != nil {
+		t.Fatalf("ReplaceFeedDirectory failed: %v", err)
+	}
+	if feeds, _ := db.GetDirectoryFeeds(); len(feeds) != 1 || feeds[0].Subscribers != 4 {
+		t.Errorf("expected only the new listing, got %+v", feeds)
+	}
+	if recs, _ := db.GetFeedRecommendations([]int{news.ID, blog.ID}); len(recs) != 0 {
+		t.Errorf("expected old recommendations to be removed, got %+v", recs)
+	}
+}
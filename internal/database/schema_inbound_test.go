@@ -0,0 +1,87 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInboundAddresses(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+
+	now := time.Now()
+	newFeed := func(address string) *Feed {
+		return &Feed{Title: "Newsletters", URL: "mailto:" + address, CreatedAt: now, UpdatedAt: now, LastFetch: now}
+	}
+	feed := newFeed("a1b2c3@in.example.com")
+	otherFeed := newFeed("d4e5f6@in.example.com")
+	first := &InboundAddress{UserID: user.ID, Token: "a1b2c3", CreatedAt: now}
+	second := &InboundAddress{UserID: user.ID, Token: "d4e5f6", CreatedAt: now}
+	if err := db.CreateInboundAddress(first, feed); err != nil {
+		t.Fatalf("CreateInboundAddress failed: %v", err)
+	}
+	if err := db.CreateInboundAddress(second, otherFeed); err != nil {
+		t.Fatalf("CreateInboundAddress failed: %v", err)
+	}
+	if first.ID == 0 || second.ID == 0 || feed.ID == 0 || first.FeedID != feed.ID {
+		t.Fatalf("expected CreateInboundAddress to set the IDs, got %+v and feed %d", first, feed.ID)
+	}
+	if feeds, err := db.GetUserFeeds(user.ID); err != nil || len(feeds) != 2 {
+		t.Errorf("expected the user subscribed to both feeds, got %+v, %v", feeds, err)
+	}
+
+	// A duplicate token fails the whole creation: no feed or subscription
+	// is left behind
+	duplicate := newFeed("duplicate@in.example.com")
+	if err := db.CreateInboundAddress(&InboundAddress{UserID: user.ID, Token: "a1b2c3", CreatedAt: now}, duplicate); err == nil {
+		t.Error("expected a duplicate token to be rejected")
+	}
+	if leftover, err := db.GetFeedByURL(duplicate.URL); err == nil && leftover != nil {
+		t.Errorf("expected no feed left from the failed creation, got %+v", leftover)
+	}
+	if feeds, err := db.GetUserFeeds(user.ID); err != nil || len(feeds) != 2 {
+		t.Errorf("expected no subscription left from the failed creation, got %+v, %v", feeds, err)
+	}
+
+	got, err := db.GetInboundAddressByToken("a1b2c3")
+	if err != nil {
+		t.Fatalf("GetInboundAddressByToken failed: %v", err)
+	}
+	if got == nil || got.ID != first.ID || got.UserID != user.ID || got.FeedID != feed.ID {
+		t.Fatalf("unexpected address: %+v", got)
+	}
+	if missing, err := db.GetInboundAddressByToken("nope"); err != nil || missing != nil {
+		t.Errorf("expected no address for an unknown token, got %+v, %v", missing, err)
+	}
+
+	list, err := db.GetUserInboundAddresses(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserInboundAddresses failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != first.ID || list[1].Token != "d4e5f6" {
+		t.Errorf("unexpected addresses: %+v", list)
+	}
+
+	// Another user's delete must not touch the address.
+	if err := db.DeleteInboundAddress(user.ID+1, first.ID); err != nil {
+		t.Fatalf("DeleteInboundAddress failed: %v", err)
+	}
+	if got, _ := db.GetInboundAddressByToken("a1b2c3"); got == nil {
+		t.Fatal("expected the address to survive another user's delete")
+	}
+
+	if err := db.DeleteInboundAddress(user.ID, first.ID); err != nil {
+		t.Fatalf("DeleteInboundAddress failed: %v", err)
+	}
+	if got, _ := db.GetInboundAddressByToken("a1b2c3"); got != nil {
+		t.Errorf("expected the address to be deleted, got %+v", got)
+	}
+	list, err = db.GetUserInboundAddresses(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserInboundAddresses failed: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != second.ID {
+		t.Errorf("expected only the second address, got %+v", list)
+	}
+}
@@ -0,0 +1,66 @@
+package database
+
+import "testing"
+
+func TestReadingTimeAndProgress(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+
+	article := createTestArticle(t, db, feed.ID)
+	if _, err := db.Exec("UPDATE articles SET reading_time_minutes = 7 WHERE id = ?", article.ID); err != nil {
+		t.Fatalf("failed to set reading time: %v", err)
+	}
+
+	if err := db.SetUserArticleProgress(user.ID, article.ID, 40); err != nil {
+		t.Fatalf("SetUserArticleProgress failed: %v", err)
+	}
+
+	got, err := db.GetArticleByID(user.ID, article.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetArticleByID failed: %v", err)
+	}
+	if got.ReadingTimeMinutes != 7 || got.ReadProgress != 40 {
+		t.Errorf("expected reading time 7 and progress 40, got %d and %d", got.ReadingTimeMinutes, got.ReadProgress)
+	}
+	if got.IsRead {
+		t.Error("saving progress should not mark the article read")
+	}
+
+	t.Run("status changes keep progress", func(t *testing.T) {
+		if err := db.MarkUserArticleRead(user.ID, article.ID, true); err != nil {
+			t.Fatalf("MarkUserArticleRead failed: %v", err)
+		}
+		if err := db.SetUserArticleStatus(user.ID, article.ID, true, true); err != nil {
+			t.Fatalf("SetUserArticleStatus failed: %v", err)
+		}
+		if err := db.BatchSetUserArticleStatus(user.ID, []Article{*article}, false, true); err != nil {
+			t.Fatalf("BatchSetUserArticleStatus failed: %v", err)
+		}
+		if _, err := db.MarkAllUserArticlesRead(user.ID); err != nil {
+			t.Fatalf("MarkAllUserArticlesRead failed: %v", err)
+		}
+
+		status, err := db.GetUserArticleStatus(user.ID, article.ID)
+		if err != nil {
+			t.Fatalf("GetUserArticleStatus failed: %v", err)
+		}
+		if status.ReadProgress != 40 || !status.IsRead {
+			t.Errorf("expected read article with progress 40, got %+v", status)
+		}
+	})
+
+	t.Run("paginated listing includes progress", func(t *testing.T) {
+		result, err := db.GetUserArticlesPaginated(user.ID, 10, "", false)
+		if err != nil {
+			t.Fatalf("GetUserArticlesPaginated failed: %v", err)
+		}
+		if len(result.Articles) != 1 || result.Articles[0].ReadProgress != 40 || result.Articles[0].ReadingTimeMinutes != 7 {
+			t.Errorf("unexpected listing: %+v", result.Articles)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserArticleChangesAreVersioned(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	otherUser := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	a1 := createTestArticle(t, db, feed.ID)
+	a2 := createTestArticle(t, db, feed.ID)
+
+	start, err := db.GetUserSyncVersion(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserSyncVersion failed: %v", err)
+	}
+
+	if err := db.MarkUserArticleRead(user.ID, a1.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+	if err := db.ToggleUserArticleStar(user.ID, a2.ID); err != nil {
+		t.Fatalf("ToggleUserArticleStar failed: %v", err)
+	}
+	if err := db.SetUserArticleProgress(user.ID, a1.ID, 40); err != nil {
+		t.Fatalf("SetUserArticleProgress failed: %v", err)
+	}
+	if err := db.MarkUserArticleRead(otherUser.ID, a1.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+
+	changes, err := db.GetUserArticleChanges(user.ID, start, 10)
+	if err != nil {
+		t.Fatalf("GetUserArticleChanges failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed articles, got %+v", changes)
+	}
+	// a2 was starred after a1 was marked read, but a1's progress change is newer
+	if changes[0].ArticleID != a2.ID || !changes[0].IsStarred {
+		t.Errorf("expected a2 starred first, got %+v", changes[0])
+	}
+	if changes[1].ArticleID != a1.ID || !changes[1].IsRead || changes[1].ReadProgress != 40 {
+		t.Errorf("expected a1 read at 40%% last, got %+v", changes[1])
+	}
+	if changes[0].Version >= changes[1].Version || changes[1].UpdatedAt.IsZero() {
+		t.Errorf("expected increasing versions and an update time, got %+v", changes)
+	}
+
+	current, _ := db.GetUserSyncVersion(user.ID)
+	if current != changes[1].Version {
+		t.Errorf("expected sync version %d, got %d", changes[1].Version, current)
+	}
+
+	// Writing the same state again is not a change
+	if err := db.MarkUserArticleRead(user.ID, a1.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+	if changes, _ := db.GetUserArticleChanges(user.ID, current, 10); len(changes) != 0 {
+		t.Errorf("expected no changes after a no-op write, got %+v", changes)
+	}
+}
+
+func TestUserFeedChanges(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	kept := createTestFeed(t, db)
+	dropped := createTestFeed(t, db)
+	for _, f := range []*Feed{kept, dropped} {
+		if err := db.SubscribeUserToFeed(user.ID, f.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed failed: %v", err)
+		}
+	}
+	afterSubscribe, _ := db.GetUserSyncVersion(user.ID)
+
+	if err := db.UnsubscribeUserFromFeed(user.ID, dropped.ID); err != nil {
+		t.Fatalf("UnsubscribeUserFromFeed failed: %v", err)
+	}
+
+	all, err := db.GetUserFeedChanges(user.ID, 0)
+	if err != nil {
+		t.Fatalf("GetUserFeedChanges failed: %v", err)
+	}
+	if len(all) != 2 || all[0].FeedID != kept.ID || !all[0].Subscribed || all[1].FeedID != dropped.ID || all[1].Subscribed {
+		t.Errorf("expected kept subscribed then dropped unsubscribed, got %+v", all)
+	}
+
+	recent, _ := db.GetUserFeedChanges(user.ID, afterSubscribe)
+	if len(recent) != 1 || recent[0].FeedID != dropped.ID {
+		t.Errorf("expected only the unsubscribe, got %+v", recent)
+	}
+}
+
+func TestGetNewUserArticles(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	other := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+
+	first := createTestArticle(t, db, feed.ID)
+	second := createTestArticle(t, db, feed.ID)
+	third := createTestArticle(t, db, feed.ID)
+	createTestArticle(t, db, other.ID)
+	if err := db.MarkUserArticleRead(user.ID, second.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+
+	page, err := db.GetNewUserArticles(user.ID, ArticlePosition{}, 2)
+	if err != nil {
+		t.Fatalf("GetNewUserArticles failed: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != first.ID || page[1].ID != second.ID {
+		t.Fatalf("expected first two articles, got %+v", page)
+	}
+	if !page[1].IsRead || page[1].FeedTitle == "" {
+		t.Errorf("expected user state and feed title on articles, got %+v", page[1])
+	}
+
+	rest, err := db.GetNewUserArticles(user.ID, ArticlePosition{CreatedAt: page[1].CreatedAt, ID: page[1].ID}, 2)
+	if err != nil {
+		t.Fatalf("GetNewUserArticles failed: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != third.ID {
+		t.Errorf("expected only the third article, got %+v", rest)
+	}
+}
+
+func TestApplyUserArticleChange_LastWriterWins(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+	read, starred := true, true
+	now := time.Now()
+
+	// A new row is created from the change alone
+	applied, err := db.ApplyUserArticleChange(user.ID, UserArticleChange{ArticleID: article.ID, IsRead: &read, At: now.Add(-time.Hour)})
+	if err != nil || !applied {
+		t.Fatalf("expected change to apply, got %v, %v", applied, err)
+	}
+
+	// A change made earlier than the stored one loses
+	applied, err = db.ApplyUserArticleChange(user.ID, UserArticleChange{ArticleID: article.ID, IsStarred: &starred, At: now.Add(-2 * time.Hour)})
+	if err != nil || applied {
+		t.Fatalf("expected stale change to be skipped, got %v, %v", applied, err)
+	}
+
+	// A later change wins and keeps fields it doesn't mention
+	applied, err = db.ApplyUserArticleChange(user.ID, UserArticleChange{ArticleID: article.ID, IsStarred: &starred, At: now.Add(-time.Minute)})
+	if err != nil || !applied {
+		t.Fatalf("expected newer change to apply, got %v, %v", applied, err)
+	}
+	status, err := db.GetUserArticleStatus(user.ID, article.ID)
+	if err != nil {
+		t.Fatalf("GetUserArticleStatus failed: %v", err)
+	}
+	if !status.IsRead || !status.IsStarred {
+		t.Errorf("expected read and starred, got %+v", status)
+	}
+
+	// Synced changes are versioned like any other write and keep the client's time
+	changes, _ := db.GetUserArticleChanges(user.ID, 0, 10)
+	if len(changes) != 1 || changes[0].UpdatedAt.Unix() != now.Add(-time.Minute).Unix() {
+		t.Errorf("expected one change stamped with the client time, got %+v", changes)
+	}
+
+	// A server-side write afterwards is newer than any earlier client change
+	if err := db.MarkUserArticleRead(user.ID, article.ID, false); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+	applied, _ = db.ApplyUserArticleChange(user.ID, UserArticleChange{ArticleID: article.ID, IsRead: &read, At: now.Add(-30 * time.Second)})
+	if applied {
+		t.Error("expected change older than the server write to be skipped")
+	}
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTagCRUD(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	otherUser := createTestUser(t, db)
+
+	share := &Tag{UserID: user.ID, Name: "to-share", CreatedAt: time.Now()}
+	if err := db.CreateTag(share); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if share.ID == 0 {
+		t.Fatal("expected tag ID to be set")
+	}
+
+	t.Run("duplicate name is rejected", func(t *testing.T) {
+		err := db.CreateTag(&Tag{UserID: user.ID, Name: "to-share", CreatedAt: time.Now()})
+		if !errors.Is(err, ErrTagExists) {
+			t.Errorf("expected ErrTagExists, got %v", err)
+		}
+	})
+
+	t.Run("same name is allowed for another user", func(t *testing.T) {
+		if err := db.CreateTag(&Tag{UserID: otherUser.ID, Name: "to-share", CreatedAt: time.Now()}); err != nil {
+			t.Errorf("CreateTag for another user failed: %v", err)
+		}
+	})
+
+	t.Run("tags are private to their owner", func(t *testing.T) {
+		got, err := db.GetTag(otherUser.ID, share.ID)
+		if err != nil {
+			t.Fatalf("GetTag failed: %v", err)
+		}
+		if got != nil {
+			t.Error("expected nil when another user reads the tag")
+		}
+	})
+
+	t.Run("rename to an existing name is rejected", func(t *testing.T) {
+		postmortem := &Tag{UserID: user.ID, Name: "incident-postmortem", CreatedAt: time.Now()}
+		if err := db.CreateTag(postmortem); err != nil {
+			t.Fatalf("CreateTag failed: %v", err)
+		}
+		if err := db.RenameTag(user.ID, postmortem.ID, "to-share"); !errors.Is(err, ErrTagExists) {
+			t.Errorf("expected ErrTagExists, got %v", err)
+		}
+		if err := db.RenameTag(user.ID, postmortem.ID, "postmortem"); err != nil {
+			t.Fatalf("RenameTag failed: %v", err)
+		}
+		got, err := db.GetTagByName(user.ID, "postmortem")
+		if err != nil || got == nil || got.ID != postmortem.ID {
+			t.Errorf("expected renamed tag to be found by name, got %+v (err %v)", got, err)
+		}
+	})
+}
+
+func TestTagArticles(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	tag := &Tag{UserID: user.ID, Name: "reading-list", CreatedAt: time.Now()}
+	if err := db.CreateTag(tag); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+
+	var articles []*Article
+	for i := 0; i < 3; i++ {
+		a := createTestArticle(t, db, feed.ID)
+		articles = append(articles, a)
+		if err := db.TagArticle(user.ID, a.ID, tag.ID); err != nil {
+			t.Fatalf("TagArticle failed: %v", err)
+		}
+	}
+	// Tagging twice is a no-op.
+	if err := db.TagArticle(user.ID, articles[0].ID, tag.ID); err != nil {
+		t.Fatalf("repeat TagArticle failed: %v", err)
+	}
+
+	t.Run("counts", func(t *testing.T) {
+		tags, err := db.GetUserTags(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserTags failed: %v", err)
+		}
+		if len(tags) != 1 || tags[0].ArticleCount != 3 {
+			t.Errorf("expected one tag with 3 articles, got %+v", tags)
+		}
+	})
+
+	t.Run("article tags", func(t *testing.T) {
+		got, err := db.GetArticleTags(user.ID, []int{articles[0].ID, articles[1].ID})
+		if err != nil {
+			t.Fatalf("GetArticleTags failed: %v", err)
+		}
+		if len(got) != 2 || len(got[articles[0].ID]) != 1 || got[articles[0].ID][0] != "reading-list" {
+			t.Errorf("unexpected article tags: %v", got)
+		}
+	})
+
+	t.Run("paginates newest first", func(t *testing.T) {
+		page, err := db.GetTagArticlesPaginated(user.ID, tag.ID, 2, "")
+		if err != nil {
+			t.Fatalf("GetTagArticlesPaginated failed: %v", err)
+		}
+		if len(page.Articles) != 2 || page.NextCursor == "" {
+			t.Fatalf("expected 2 articles and a cursor, got %d (cursor %q)", len(page.Articles), page.NextCursor)
+		}
+
+		rest, err := db.GetTagArticlesPaginated(user.ID, tag.ID, 2, page.NextCursor)
+		if err != nil {
+			t.Fatalf("GetTagArticlesPaginated (page 2) failed: %v", err)
+		}
+		if len(rest.Articles) != 1 || rest.NextCursor != "" {
+			t.Fatalf("expected 1 article and no cursor, got %d (cursor %q)", len(rest.Articles), rest.NextCursor)
+		}
+
+		seen := map[int]bool{}
+		for _, a := range append(page.Articles, rest.Articles...) {
+			seen[a.ID] = true
+		}
+		if len(seen) != 3 {
+			t.Errorf("expected 3 distinct articles across pages, got %d", len(seen))
+		}
+	})
+
+	t.Run("untag and delete", func(t *testing.T) {
+		if err := db.UntagArticle(user.ID, articles[2].ID, tag.ID); err != nil {
+			t.Fatalf("UntagArticle failed: %v", err)
+		}
+		got, _ := db.GetArticleTags(user.ID, []int{articles[2].ID})
+		if len(got) != 0 {
+			t.Errorf("expected article to have no tags, got %v", got)
+		}
+
+		if err := db.DeleteTag(user.ID, tag.ID); err != nil {
+			t.Fatalf("DeleteTag failed: %v", err)
+		}
+		got, _ = db.GetArticleTags(user.ID, []int{articles[0].ID, articles[1].ID})
+		if len(got) != 0 {
+			t.Errorf("expected deleting the tag to untag its articles, got %v", got)
+		}
+	})
+}
@@ -44,7 +44,7 @@ func setupTestDB(t *testing.T) *DB {
 	return dbWrapper
 }
 
-func createTestUser(t *testing.T, db *DB) *User {
+func createTestUser(t *testing.T, db Database) *User {
 	t.Helper()
 
 	user := &User{
@@ -66,7 +66,7 @@ func createTestUser(t *testing.T, db *DB) *User {
 	return user
 }
 
-func createTestFeed(t *testing.T, db *DB) *Feed {
+func createTestFeed(t *testing.T, db Database) *Feed {
 	t.Helper()
 
 	now := time.Now()
@@ -90,7 +90,7 @@ func createTestFeed(t *testing.T, db *DB) *Feed {
 	return feed
 }
 
-func createTestArticle(t *testing.T, db *DB, feedID int) *Article {
+func createTestArticle(t *testing.T, db Database, feedID int) *Article {
 	t.Helper()
 
 	article := &Article{
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStatusChangesRoundTrip(t *testing.T) {
+	changes := []ArticleStatusChange{
+		{ArticleID: 1, FeedID: 10, WasRead: false, WasStarred: false, ReadChanged: true},
+		{ArticleID: 2, FeedID: 10, WasRead: true, WasStarred: false, StarredChanged: true},
+		{ArticleID: 3, FeedID: 11, WasRead: false, WasStarred: true, ReadChanged: true, StarredChanged: true},
+		{ArticleID: 4, FeedID: 11, WasRead: true, WasStarred: true},
+	}
+
+	encoded, err := encodeStatusChanges(changes)
+	if err != nil {
+		t.Fatalf("encodeStatusChanges failed: %v", err)
+	}
+	decoded, err := decodeStatusChanges(encoded)
+	if err != nil {
+		t.Fatalf("decodeStatusChanges failed: %v", err)
+	}
+	if !reflect.DeepEqual(changes, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", decoded, changes)
+	}
+}
+
+func TestUndoOperationStorage(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	otherUser := createTestUser(t, db)
+	now := time.Now()
+
+	op := &UndoOperation{
+		ID:        "op-current",
+		UserID:    user.ID,
+		Kind:      "mark_all_read",
+		Changes:   []ArticleStatusChange{{ArticleID: 5, FeedID: 2, WasStarred: true}},
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	expired := &UndoOperation{
+		ID:        "op-expired",
+		UserID:    user.ID,
+		Kind:      "mark_all_read",
+		CreatedAt: now.Add(-2 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	}
+	for _, o := range []*UndoOperation{op, expired} {
+		if err := db.CreateUndoOperation(o); err != nil {
+			t.Fatalf("CreateUndoOperation failed: %v", err)
+		}
+	}
+
+	if got, err := db.ConsumeUndoOperation(otherUser.ID, op.ID); err != nil || got != nil {
+		t.Errorf("expected another user's claim to return nil, got %+v, %v", got, err)
+	}
+
+	n, err := db.DeleteExpiredUndoOperations(now)
+	if err != nil {
+		t.Fatalf("DeleteExpiredUndoOperations failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 expired operation deleted, got %d", n)
+	}
+
+	got, err := db.ConsumeUndoOperation(user.ID, op.ID)
+	if err != nil || got == nil {
+		t.Fatalf("ConsumeUndoOperation failed: %v", err)
+	}
+	if got.Kind != op.Kind || !reflect.DeepEqual(got.Changes, op.Changes) {
+		t.Errorf("unexpected operation: %+v", got)
+	}
+	if got, _ := db.ConsumeUndoOperation(user.ID, op.ID); got != nil {
+		t.Error("expected an operation to be handed out only once")
+	}
+}
+
+func TestGetUserArticleStatuses(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+	read := createTestArticle(t, db, feed.ID)
+	untouched := createTestArticle(t, db, feed.ID)
+	if err := db.MarkUserArticleRead(user.ID, read.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+
+	statuses, err := db.GetUserArticleStatuses(user.ID, []int{read.ID, untouched.ID})
+	if err != nil {
+		t.Fatalf("GetUserArticleStatuses failed: %v", err)
+	}
+	if len(statuses) != 1 || !statuses[read.ID].IsRead {
+		t.Errorf("expected only the read article to have a status row, got %+v", statuses)
+	}
+}
+
+func TestMarkUserArticlesReadScoped(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feedA := createTestFeed(t, db)
+	feedB := createTestFeed(t, db)
+	unsubscribed := createTestFeed(t, db)
+	for _, f := range []*Feed{feedA, feedB} {
+		if err := db.SubscribeUserToFeed(user.ID, f.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed failed: %v", err)
+		}
+	}
+
+	addArticle := func(feedID int, age time.Duration) *Article {
+		a := createTestArticle(t, db, feedID)
+		if _, err := db.Exec("UPDATE articles SET published_at = ? WHERE id = ?", time.Now().Add(-age), a.ID); err != nil {
+			t.Fatalf("failed to backdate article: %v", err)
+		}
+		return a
+	}
+	day := 24 * time.Hour
+	newA := addArticle(feedA.ID, time.Hour)
+	oldA := addArticle(feedA.ID, 10*day)
+	ancientA := addArticle(feedA.ID, 200*day)
+	starredB := addArticle(feedB.ID, 10*day)
+	readB := addArticle(feedB.ID, 10*day)
+	addArticle(unsubscribed.ID, 10*day)
+
+	if err := db.SetUserArticleStatus(user.ID, starredB.ID, false, true); err != nil {
+		t.Fatalf("SetUserArticleStatus failed: %v", err)
+	}
+	if err := db.MarkUserArticleRead(user.ID, readB.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead failed: %v", err)
+	}
+
+	t.Run("feed scope with cutoff", func(t *testing.T) {
+		result, err := db.MarkUserArticlesReadScoped(user.ID, MarkReadScope{
+			FeedIDs:         []int{feedA.ID, unsubscribed.ID},
+			PublishedBefore: time.Now().Add(-5 * day),
+		})
+		if err != nil {
+			t.Fatalf("MarkUserArticlesReadScoped failed: %v", err)
+		}
+		want := []ArticleStatusChange{
+			{ArticleID: oldA.ID, FeedID: feedA.ID, ReadChanged: true},
+			{ArticleID: ancientA.ID, FeedID: feedA.ID, ReadChanged: true},
+		}
+		if !reflect.DeepEqual(result.Changes, want) {
+			t.Errorf("expected changes %+v, got %+v", want, result.Changes)
+		}
+		// The 200-day-old article is outside the unread-count window.
+		if !reflect.DeepEqual(result.UnreadCleared, map[int]int{feedA.ID: 1}) {
+			t.Errorf("unexpected unread cleared counts: %v", result.UnreadCleared)
+		}
+		if status, _ := db.GetUserArticleStatus(user.ID, newA.ID); status != nil && status.IsRead {
+			t.Error("article newer than the cutoff should stay unread")
+		}
+	})
+
+	t.Run("all feeds keeps stars and skips read articles", func(t *testing.T) {
+		result, err := db.MarkUserArticlesReadScoped(user.ID, MarkReadScope{})
+		if err != nil {
+			t.Fatalf("MarkUserArticlesReadScoped failed: %v", err)
+		}
+		want := []ArticleStatusChange{
+			{ArticleID: newA.ID, FeedID: feedA.ID, ReadChanged: true},
+			{ArticleID: starredB.ID, FeedID: feedB.ID, WasStarred: true, ReadChanged: true},
+		}
+		if !reflect.DeepEqual(result.Changes, want) {
+			t.Errorf("expected changes %+v, got %+v", want, result.Changes)
+		}
+		status, err := db.GetUserArticleStatus(user.ID, starredB.ID)
+		if err != nil || status == nil {
+			t.Fatalf("GetUserArticleStatus failed: %v", err)
+		}
+		if !status.IsRead || !status.IsStarred {
+			t.Errorf("expected starred article to be read and still starred, got %+v", status)
+		}
+		counts, err := db.GetUserUnreadCounts(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserUnreadCounts failed: %v", err)
+		}
+		if counts[feedA.ID] != 0 || counts[feedB.ID] != 0 {
+			t.Errorf("expected no unread articles, got %v", counts)
+		}
+	})
+}
@@ -1067,3 +1067,66 @@ func TestGetAccountStats(t *testing.T) {
 		t.Errorf("Expected active_feeds=0 (no unread), got %v", stats["active_feeds"])
 	}
 }
+
+func TestUpdateUserArticleStatuses(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	other := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+	read := createTestArticle(t, db, feed.ID)
+	starred := createTestArticle(t, db, feed.ID)
+	unchanged := createTestArticle(t, db, feed.ID)
+	foreign := createTestArticle(t, db, other.ID)
+
+	if err := db.SetUserArticleStatus(user.ID, starred.ID, true, false); err != nil {
+		t.Fatalf("SetUserArticleStatus failed: %v", err)
+	}
+	if err := db.SetUserArticleProgress(user.ID, starred.ID, 30); err != nil {
+		t.Fatalf("SetUserArticleProgress failed: %v", err)
+	}
+
+	yes, no := true, false
+	result, err := db.UpdateUserArticleStatuses(user.ID, []ArticleStatusUpdate{
+		{ArticleID: read.ID, IsRead: &yes},
+		{ArticleID: starred.ID, IsStarred: &yes},
+		{ArticleID: starred.ID, IsRead: &no}, // later updates to the same article combine
+		{ArticleID: unchanged.ID, IsRead: &no, IsStarred: &no},
+		{ArticleID: foreign.ID, IsRead: &yes},
+	})
+	if err != nil {
+		t.Fatalf("UpdateUserArticleStatuses failed: %v", err)
+	}
+
+	if len(result.Found) != 3 || result.Found[read.ID] != feed.ID {
+		t.Errorf("expected the three subscribed articles to be found, got %v", result.Found)
+	}
+	if _, ok := result.Found[foreign.ID]; ok {
+		t.Error("article in an unsubscribed feed should not be found")
+	}
+	want := []ArticleStatusChange{
+		{ArticleID: read.ID, FeedID: feed.ID, ReadChanged: true},
+		{ArticleID: starred.ID, FeedID: feed.ID, WasRead: true, ReadChanged: true, StarredChanged: true},
+	}
+	if len(result.Changes) != len(want) || result.Changes[0] != want[0] || result.Changes[1] != want[1] {
+		t.Errorf("expected changes %+v, got %+v", want, result.Changes)
+	}
+	// One article read and one unread cancel out
+	if result.UnreadDelta[feed.ID] != 0 {
+		t.Errorf("expected no net unread change, got %v", result.UnreadDelta)
+	}
+
+	status, err := db.GetUserArticleStatus(user.ID, starred.ID)
+	if err != nil {
+		t.Fatalf("GetUserArticleStatus failed: %v", err)
+	}
+	if status.IsRead || !status.IsStarred || status.ReadProgress != 30 {
+		t.Errorf("expected unread, starred, progress kept, got %+v", status)
+	}
+	if status, _ := db.GetUserArticleStatus(user.ID, foreign.ID); status != nil && status.IsRead {
+		t.Error("article in an unsubscribed feed should not be updated")
+	}
+}
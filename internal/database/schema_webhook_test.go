@@ -0,0 +1,138 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed failed: %v", err)
+	}
+
+	now := time.Now()
+	webhook := &Webhook{
+		UserID: user.ID, URL: "https://hooks.example.com/a", Secret: "secret-a",
+		Keywords: []string{"golang", "release notes"}, Enabled: true, CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.CreateWebhook(webhook); err != nil {
+		t.Fatalf("CreateWebhook failed: %v", err)
+	}
+	if webhook.ID == 0 {
+		t.Fatal("expected CreateWebhook to set the ID")
+	}
+
+	got, err := db.GetWebhook(user.ID, webhook.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook failed: %v", err)
+	}
+	if got == nil || got.URL != webhook.URL || got.Secret != "secret-a" || len(got.Keywords) != 2 || got.Keywords[1] != "release notes" {
+		t.Fatalf("unexpected webhook: %+v", got)
+	}
+	if other, err := db.GetWebhook(user.ID+1, webhook.ID); err != nil || other != nil {
+		t.Errorf("expected another user's lookup to find nothing, got %+v, %v", other, err)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		got.URL = "https://hooks.example.com/b"
+		got.Keywords = nil
+		got.FeedID = feed.ID
+		if err := db.UpdateWebhook(got); err != nil {
+			t.Fatalf("UpdateWebhook failed: %v", err)
+		}
+		updated, err := db.GetWebhook(user.ID, webhook.ID)
+		if err != nil {
+			t.Fatalf("GetWebhook failed: %v", err)
+		}
+		if updated.URL != "https://hooks.example.com/b" || updated.FeedID != feed.ID || len(updated.Keywords) != 0 {
+			t.Errorf("unexpected webhook after update: %+v", updated)
+		}
+	})
+
+	t.Run("feed webhooks", func(t *testing.T) {
+		otherFeed := &Feed{Title: "Other", URL: "https://example.com/other-webhook-feed", CreatedAt: now, UpdatedAt: now, LastFetch: now}
+		if err := db.AddFeed(otherFeed); err != nil {
+			t.Fatalf("AddFeed failed: %v", err)
+		}
+		allFeeds := &Webhook{UserID: user.ID, URL: "https://hooks.example.com/all", Secret: "s", Enabled: true, CreatedAt: now, UpdatedAt: now}
+		disabled := &Webhook{UserID: user.ID, URL: "https://hooks.example.com/off", Secret: "s", CreatedAt: now, UpdatedAt: now}
+		for _, w := range []*Webhook{allFeeds, disabled} {
+			if err := db.CreateWebhook(w); err != nil {
+				t.Fatalf("CreateWebhook failed: %v", err)
+			}
+		}
+
+		webhooks, err := db.GetFeedWebhooks(feed.ID)
+		if err != nil {
+			t.Fatalf("GetFeedWebhooks failed: %v", err)
+		}
+		if len(webhooks) != 2 || webhooks[0].ID != webhook.ID || webhooks[1].ID != allFeeds.ID {
+			t.Errorf("expected the feed's webhook and the all-feeds webhook, got %+v", webhooks)
+		}
+
+		// The user isn't subscribed to otherFeed, so nothing should fire for it.
+		webhooks, err = db.GetFeedWebhooks(otherFeed.ID)
+		if err != nil {
+			t.Fatalf("GetFeedWebhooks failed: %v", err)
+		}
+		if len(webhooks) != 0 {
+			t.Errorf("expected no webhooks for an unsubscribed feed, got %+v", webhooks)
+		}
+
+		list, err := db.GetUserWebhooks(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserWebhooks failed: %v", err)
+		}
+		if len(list) != 3 {
+			t.Errorf("expected 3 webhooks, got %d", len(list))
+		}
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		old := &WebhookDelivery{WebhookID: webhook.ID, UserID: user.ID, EventID: "e1", Event: "article.created",
+			Attempt: 1, StatusCode: 500, Error: "target returned HTTP 500", CreatedAt: now.Add(-40 * 24 * time.Hour)}
+		recent := &WebhookDelivery{WebhookID: webhook.ID, UserID: user.ID, EventID: "e1", Event: "article.created",
+			Attempt: 2, StatusCode: 200, Success: true, DurationMS: 12, CreatedAt: now}
+		for _, d := range []*WebhookDelivery{old, recent} {
+			if err := db.CreateWebhookDelivery(d); err != nil {
+				t.Fatalf("CreateWebhookDelivery failed: %v", err)
+			}
+		}
+
+		deliveries, err := db.GetWebhookDeliveries(user.ID, webhook.ID, 10)
+		if err != nil {
+			t.Fatalf("GetWebhookDeliveries failed: %v", err)
+		}
+		if len(deliveries) != 2 || deliveries[0].ID != recent.ID || !deliveries[0].Success || deliveries[1].Error == "" {
+			t.Fatalf("expected newest delivery first, got %+v", deliveries)
+		}
+
+		n, err := db.DeleteWebhookDeliveriesBefore(now.Add(-30 * 24 * time.Hour))
+		if err != nil {
+			t.Fatalf("DeleteWebhookDeliveriesBefore failed: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("expected 1 old delivery removed, got %d", n)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if err := db.DeleteWebhook(user.ID, webhook.ID); err != nil {
+			t.Fatalf("DeleteWebhook failed: %v", err)
+		}
+		if got, err := db.GetWebhook(user.ID, webhook.ID); err != nil || got != nil {
+			t.Errorf("expected webhook to be deleted, got %+v, %v", got, err)
+		}
+		deliveries, err := db.GetWebhookDeliveries(user.ID, webhook.ID, 10)
+		if err != nil {
+			t.Fatalf("GetWebhookDeliveries failed: %v", err)
+		}
+		if len(deliveries) != 0 {
+			t.Errorf("expected delivery history to be deleted, got %d", len(deliveries))
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Event types pushed to clients
+const (
+	// TypeNewArticles reports that a feed refresh stored new articles.
+	TypeNewArticles = "new_articles"
+	// TypeUnreadCounts reports that a user's unread counts changed for some feeds.
+	TypeUnreadCounts = "unread_counts"
+	// TypeArticleState reports that a user's read or starred flag changed on an article.
+	TypeArticleState = "article_state"
+	// TypeImportProgress reports how far one of the user's reader imports has
+	// got. Its Data is the job as returned by GET /api/imports/:id.
+	TypeImportProgress = "import_progress"
+)
+
+// Event is a change notification for connected clients. Events with a UserID
+// go only to that user; events with UserID 0 are feed-wide and go to everyone
+// subscribed to FeedID, which receivers are expected to filter on.
+type Event struct {
+	Type   string
+	UserID int
+	FeedID int
+	Data   interface{} // JSON payload sent to the client
+}
+
+// NewArticlesData is the payload of TypeNewArticles.
+type NewArticlesData struct {
+	FeedID int `json:"feed_id"`
+	Count  int `json:"count"`
+}
+
+// UnreadCountsData is the payload of TypeUnreadCounts. Clients re-fetch the
+// counts for the listed feeds.
+type UnreadCountsData struct {
+	FeedIDs []int `json:"feed_ids"`
+}
+
+// ArticleStateData is the payload of TypeArticleState.
+type ArticleStateData struct {
+	ArticleID int  `json:"article_id"`
+	FeedID    int  `json:"feed_id,omitempty"`
+	IsRead    bool `json:"is_read"`
+	IsStarred bool `json:"is_starred"`
+}
+
+// Bus delivers events from publishers to subscribed clients. MemoryBus works
+// within one process; deployments with several instances can plug in an
+// implementation backed by a shared broker.
+type Bus interface {
+	// Publish delivers an event without blocking the caller.
+	Publish(e Event)
+	// Subscribe returns a channel of events for the user, plus a function
+	// that ends the subscription and closes the channel.
+	Subscribe(userID int) (<-chan Event, func())
+	// HasSubscribers reports whether the user may have listening clients, so
+	// publishers can skip work building events nobody will receive.
+	// Implementations that can't tell should return true.
+	HasSubscribers(userID int) bool
+}
+
+// subscriberBuffer is how many events a slow client can fall behind by before
+// further events are dropped for it.
+const subscriberBuffer = 32
+
+// MemoryBus is an in-process Bus. It suits single-instance deployments and
+// tests; clients connected to other instances won't see its events.
+type MemoryBus struct {
+	mu      sync.RWMutex
+	subs    map[int]map[*subscriber]struct{} // userID → subscribers
+	dropped int64
+	closed  bool
+}
+
+type subscriber struct {
+	ch     chan Event
+	closed bool
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[int]map[*subscriber]struct{})}
+}
+
+func (b *MemoryBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if e.UserID != 0 {
+		b.deliver(b.subs[e.UserID], e)
+		return
+	}
+	for _, subs := range b.subs {
+		b.deliver(subs, e)
+	}
+}
+
+// deliver sends without blocking; a full buffer means the client isn't
+// keeping up, so the event is dropped rather than stalling the publisher.
+// Callers must hold at least a read lock.
+func (b *MemoryBus) deliver(subs map[*subscriber]struct{}, e Event) {
+	for s := range subs {
+		select {
+		case s.ch <- e:
+		default:
+			if n := atomic.AddInt64(&b.dropped, 1); n%100 == 1 {
+				log.Printf("Event bus: dropped %d events for slow subscribers", n)
+			}
+		}
+	}
+}
+
+func (b *MemoryBus) Subscribe(userID int) (<-chan Event, func()) {
+	s := &subscriber{ch: make(chan Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		s.closed = true
+		close(s.ch)
+		return s.ch, func() {}
+	}
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*subscriber]struct{})
+	}
+	b.subs[userID][s] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if s.closed {
+			return
+		}
+		s.closed = true
+		delete(b.subs[userID], s)
+		if len(b.subs[userID]) == 0 {
+			delete(b.subs, userID)
+		}
+		close(s.ch)
+	}
+	return s.ch, cancel
+}
+
+// Close ends every subscription, and any made afterwards, so clients' event
+// streams finish. It's called on shutdown, where open streams would otherwise
+// hold the server until the shutdown timeout.
+func (b *MemoryBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for userID, subs := range b.subs {
+		for s := range subs {
+			s.closed = true
+			close(s.ch)
+		}
+		delete(b.subs, userID)
+	}
+}
+
+func (b *MemoryBus) HasSubscribers(userID int) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[userID]) > 0
+}
+
+// SubscriberCount returns the number of open subscriptions across all users.
+func (b *MemoryBus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	n := 0
+	for _, subs := range b.subs {
+		n += len(subs)
+	}
+	return n
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"testing"
+)
+
+func receive(t *testing.T, ch <-chan Event) (Event, bool) {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		return e, ok
+	default:
+		return Event{}, false
+	}
+}
+
+func TestMemoryBus_UserEventsAreIsolated(t *testing.T) {
+	bus := NewMemoryBus()
+	alice, cancelAlice := bus.Subscribe(1)
+	defer cancelAlice()
+	bob, cancelBob := bus.Subscribe(2)
+	defer cancelBob()
+
+	bus.Publish(Event{Type: TypeArticleState, UserID: 1, Data: ArticleStateData{ArticleID: 7, IsRead: true}})
+
+	e, ok := receive(t, alice)
+	if !ok {
+		t.Fatal("expected user 1 to receive the event")
+	}
+	if e.Type != TypeArticleState || e.Data.(ArticleStateData).ArticleID != 7 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if _, ok := receive(t, bob); ok {
+		t.Error("user 2 should not receive user 1's event")
+	}
+}
+
+func TestMemoryBus_FeedEventsReachEveryUser(t *testing.T) {
+	bus := NewMemoryBus()
+	first, cancelFirst := bus.Subscribe(1)
+	defer cancelFirst()
+	second, cancelSecond := bus.Subscribe(1) // second device
+	defer cancelSecond()
+	other, cancelOther := bus.Subscribe(2)
+	defer cancelOther()
+
+	bus.Publish(Event{Type: TypeNewArticles, FeedID: 3, Data: NewArticlesData{FeedID: 3, Count: 2}})
+
+	for i, ch := range []<-chan Event{first, second, other} {
+		if _, ok := receive(t, ch); !ok {
+			t.Errorf("subscriber %d did not receive the feed event", i)
+		}
+	}
+}
+
+func TestMemoryBus_Cancel(t *testing.T) {
+	bus := NewMemoryBus()
+	ch, cancel := bus.Subscribe(1)
+	if !bus.HasSubscribers(1) {
+		t.Fatal("expected user 1 to have a subscriber")
+	}
+
+	cancel()
+	cancel() // must be safe to call twice
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+	if bus.HasSubscribers(1) || bus.SubscriberCount() != 0 {
+		t.Error("expected no subscribers after cancel")
+	}
+
+	// Publishing with no subscribers must not panic
+	bus.Publish(Event{Type: TypeUnreadCounts, UserID: 1})
+}
+
+func TestMemoryBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := NewMemoryBus()
+	ch, cancel := bus.Subscribe(1)
+	defer cancel()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		bus.Publish(Event{Type: TypeUnreadCounts, UserID: 1})
+	}
+
+	if len(ch) != subscriberBuffer {
+		t.Errorf("expected %d buffered events, got %d", subscriberBuffer, len(ch))
+	}
+}
+
+func TestMemoryBus_Close(t *testing.T) {
+	bus := NewMemoryBus()
+	ch, cancel := bus.Subscribe(1)
+
+	bus.Close()
+	cancel() // must be safe after Close
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after Close")
+	}
+	if bus.SubscriberCount() != 0 {
+		t.Error("expected no subscribers after Close")
+	}
+
+	late, cancelLate := bus.Subscribe(2)
+	defer cancelLate()
+	if _, ok := <-late; ok {
+		t.Error("expected a subscription made after Close to be closed")
+	}
+	bus.Publish(Event{Type: TypeUnreadCounts})
+}
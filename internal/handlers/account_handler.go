@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type AccountHandler struct {
+	accountService *services.AccountService
+	sessionManager *auth.SessionManager
+}
+
+func NewAccountHandler(accountService *services.AccountService, sessionManager *auth.SessionManager) *AccountHandler {
+	return &AccountHandler{accountService: accountService, sessionManager: sessionManager}
+}
+
+// ExportAccount downloads the user's data as a ZIP: account.json,
+// subscriptions.opml, articles.json, tags.json and annotations.json.
+func (ah *AccountHandler) ExportAccount(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	export, err := ah.accountService.ExportAccount(user)
+	if err != nil {
+		log.Printf("Failed to export account for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare your data export. Please try again."})
+		return
+	}
+
+	filename := fmt.Sprintf("goread2-export-%s.zip", export.ExportedAt.Format("2006-01-02"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Status(http.StatusOK)
+
+	// The status is already sent, so a failure here can only cut the download short
+	if err := export.WriteZip(c.Writer); err != nil {
+		log.Printf("Failed to write account export for user %d: %v", user.ID, err)
+	}
+}
+
+// DeleteAccount cancels the user's subscription, deletes the account and
+// everything in it, and signs the user out on every device.
+func (ah *AccountHandler) DeleteAccount(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	sessionIDs, err := ah.accountService.DeleteAccount(user, auth.GetSecureClientIP(c))
+	if err != nil {
+		log.Printf("Failed to delete account for user %d: %v", user.ID, err)
+		if errors.Is(err, services.ErrSubscriptionNotCancelled) {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Your subscription could not be cancelled, so your account was not deleted. Please try again or contact support."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete your account. Please try again."})
+		return
+	}
+
+	// The rows are gone already; this drops the sessions from the caches too
+	for _, id := range sessionIDs {
+		ah.sessionManager.DeleteSession(id)
+	}
+	ah.sessionManager.ForgetUser(user.ID)
+	ah.sessionManager.ClearSessionCookie(c.Writer)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your account has been deleted"})
+}
+
+// sessionInfo is a signed-in device as the account page lists it.
+type sessionInfo struct {
+	ID         string    `json:"id"`
+	ClientType string    `json:"client_type"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessions lists the devices the user is signed in on, most recently
+// used first. The session making the request is marked current.
+func (ah *AccountHandler) ListSessions(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	sessions, err := ah.sessionManager.UserSessions(user.ID)
+	if err != nil {
+		log.Printf("Failed to list sessions for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load your sessions. Please try again."})
+		return
+	}
+
+	currentHandle, _ := auth.CurrentSessionHandle(c)
+	infos := make([]sessionInfo, len(sessions))
+	for i, s := range sessions {
+		handle := auth.SessionHandle(s.ID)
+		infos[i] = sessionInfo{
+			ID:         handle,
+			ClientType: s.ClientType,
+			UserAgent:  s.UserAgent,
+			IPAddress:  s.IPAddress,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    handle == currentHandle,
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": infos})
+}
+
+// RevokeSession signs out one of the user's devices by the id
+// ListSessions gave it. Revoking the current session signs the user out
+// here too.
+func (ah *AccountHandler) RevokeSession(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	handle := c.Param("id")
+	revoked, err := ah.sessionManager.RevokeUserSession(user.ID, handle)
+	if err != nil {
+		log.Printf("Failed to revoke session for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out that session. Please try again."})
+		return
+	}
+	if !revoked {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if current, ok := auth.CurrentSessionHandle(c); ok && current == handle {
+		ah.sessionManager.ClearSessionCookie(c.Writer)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session signed out"})
+}
+
+// SignOutOtherSessions signs out every device but the one making the
+// request.
+func (ah *AccountHandler) SignOutOtherSessions(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+	current, ok := auth.CurrentSessionHandle(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	revoked, err := ah.sessionManager.RevokeOtherSessions(user.ID, current)
+	if err != nil {
+		log.Printf("Failed to sign out other sessions for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign out your other sessions. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signed out everywhere else", "revoked": revoked})
+}
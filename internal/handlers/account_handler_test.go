@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type failingCanceller struct{}
+
+func (failingCanceller) CancelUserSubscription(*database.User) error {
+	return errors.New("stripe unavailable")
+}
+
+func newAccountHandlerForTest(db database.Database) *AccountHandler {
+	feedService := services.NewFeedService(db, nil)
+	accountService := services.NewAccountService(db, feedService, services.NewAuditService(db))
+	return NewAccountHandler(accountService, auth.NewSessionManager(db))
+}
+
+func TestAccountHandlerRequiresAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := newAccountHandlerForTest(newMockDBFeedHandler())
+
+	for name, fn := range map[string]gin.HandlerFunc{
+		"export":             handler.ExportAccount,
+		"delete":             handler.DeleteAccount,
+		"list sessions":      handler.ListSessions,
+		"revoke session":     handler.RevokeSession,
+		"sign out elsewhere": handler.SignOutOtherSessions,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/account", nil)
+
+		fn(c)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestAccountHandlerExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newMockDBFeedHandler()
+	db.mockUser = &database.User{ID: 1, Email: "test@example.com"}
+	db.mockUserFeeds = []database.Feed{{ID: 1, Title: "Blog", URL: "https://blog.example.com/feed"}}
+	handler := newAccountHandlerForTest(db)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/account/export", nil)
+	c.Set("user", &database.User{ID: 1})
+
+	handler.ExportAccount(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("expected application/zip, got %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment; filename=goread2-export-") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("response is not a ZIP: %v", err)
+	}
+	names := make([]string, len(zr.File))
+	for i, f := range zr.File {
+		names[i] = f.Name
+	}
+	if strings.Join(names, ",") != "account.json,subscriptions.opml,articles.json,tags.json,annotations.json" {
+		t.Errorf("unexpected export contents: %v", names)
+	}
+}
+
+func TestAccountHandlerDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := newAccountHandlerForTest(newMockDBFeedHandler())
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("DELETE", "/api/account", nil)
+	c.Set("user", &database.User{ID: 1, Email: "test@example.com"})
+
+	handler.DeleteAccount(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if cookie := w.Header().Get("Set-Cookie"); !strings.Contains(cookie, "Expires=Thu, 01 Jan 1970") {
+		t.Errorf("expected the session cookie to be cleared, got %q", cookie)
+	}
+}
+
+func TestAccountHandlerDeleteCancelFailure(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := newAccountHandlerForTest(newMockDBFeedHandler())
+	handler.accountService.SetSubscriptionCanceller(failingCanceller{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("DELETE", "/api/account", nil)
+	c.Set("user", &database.User{ID: 1, Email: "test@example.com", SubscriptionID: "sub_123"})
+
+	handler.DeleteAccount(c)
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Set-Cookie") != "" {
+		t.Error("expected the session cookie to be kept when the account isn't deleted")
+	}
+}
+
+func TestAccountHandlerSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Listing and revoking go through real session lookups, so this uses
+	// an in-memory database rather than the feed handler mock
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db := &database.DB{DB: conn}
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	user := &database.User{GoogleID: "ann", Email: "ann@example.com", CreatedAt: time.Now()}
+	bob := &database.User{GoogleID: "bob", Email: "bob@example.com", CreatedAt: time.Now()}
+	for _, u := range []*database.User{user, bob} {
+		if err := db.CreateUser(u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	handler := newAccountHandlerForTest(db)
+	sm := handler.sessionManager
+	current, _ := sm.CreateDeviceSession(user, auth.Device{ClientType: auth.ClientTypeWeb, UserAgent: "Firefox", IPAddress: "198.51.100.7"})
+	phone, _ := sm.CreateDeviceSession(user, auth.Device{ClientType: auth.ClientTypeIOS, IPAddress: "203.0.113.9"})
+	laptop, _ := sm.CreateSession(user)
+	other, _ := sm.CreateSession(bob)
+
+	call := func(fn gin.HandlerFunc, method, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/api/account/sessions", nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Set("user", user)
+		c.Set("session", current)
+		fn(c)
+		return w
+	}
+
+	w := call(handler.ListSessions, "GET", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), current.ID) {
+		t.Error("session IDs must not be listed")
+	}
+	var listed struct {
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("bad JSON: %v", err)
+	}
+	if len(listed.Sessions) != 3 {
+		t.Fatalf("expected Ann's three sessions, got %+v", listed.Sessions)
+	}
+	for _, s := range listed.Sessions {
+		if s.Current != (s.ID == auth.SessionHandle(current.ID)) {
+			t.Errorf("expected only the requesting session marked current, got %+v", s)
+		}
+		if s.ID == auth.SessionHandle(phone.ID) && (s.ClientType != "ios" || s.IPAddress != "203.0.113.9") {
+			t.Errorf("expected the phone's device details, got %+v", s)
+		}
+	}
+
+	if w := call(handler.RevokeSession, "DELETE", auth.SessionHandle(other.ID)); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another user's session, got %d", w.Code)
+	}
+	if w := call(handler.RevokeSession, "DELETE", auth.SessionHandle(phone.ID)); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := sm.GetSession(phone.ID); ok {
+		t.Error("expected the phone signed out")
+	}
+
+	w = call(handler.SignOutOtherSessions, "POST", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":1`) {
+		t.Fatalf("expected the laptop signed out, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := sm.GetSession(laptop.ID); ok {
+		t.Error("expected the laptop signed out")
+	}
+	if _, ok := sm.GetSession(current.ID); !ok {
+		t.Error("expected the current session kept")
+	}
+	if _, ok := sm.GetSession(other.ID); !ok {
+		t.Error("expected another user's session kept")
+	}
+}
@@ -93,9 +93,131 @@ func (m *mockDBAdminHandler) GetAuditLogs(limit, offset int, filters map[string]
 func (m *mockDBAdminHandler) GetAccountStats(int) (map[string]interface{}, error) {
 	return map[string]interface{}{}, nil
 }
-func (m *mockDBAdminHandler) Close() error                                     { return nil }
-func (m *mockDBAdminHandler) CreateUser(*database.User) error                  { return nil }
-func (m *mockDBAdminHandler) GetUserByGoogleID(string) (*database.User, error) { return nil, nil }
+func (m *mockDBAdminHandler) Close() error { return nil }
+func (m *mockDBAdminHandler) GetArticlesByIDs(int, []int) (map[int]database.Article, error) {
+	return nil, nil
+}
+func (m mockDBAdminHandler) CreateRefreshToken(*database.RefreshToken) error { return nil }
+func (m mockDBAdminHandler) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBAdminHandler) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBAdminHandler) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBAdminHandler) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBAdminHandler) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) GetUserIdentities(int) ([]database.UserIdentity, error)      { return nil, nil }
+func (m *mockDBAdminHandler) CreateLoginToken(*database.LoginToken) error                 { return nil }
+func (m *mockDBAdminHandler) ConsumeLoginToken(string) (*database.LoginToken, error)      { return nil, nil }
+func (m *mockDBAdminHandler) DeleteExpiredLoginTokens() error                             { return nil }
+func (m *mockDBAdminHandler) ForEachUserIdentity(func(database.UserIdentity) error) error { return nil }
+func (m *mockDBAdminHandler) DeleteUser(userID int) error                                 { return nil }
+func (m *mockDBAdminHandler) GetUserArticleHistory(userID int) ([]database.Article, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) GetUserSessions(userID int) ([]database.Session, error) { return nil, nil }
+func (m *mockDBAdminHandler) ForEachUser(fn func(database.User) error) error         { return nil }
+func (m *mockDBAdminHandler) ForEachArticle(fn func(database.Article) error) error   { return nil }
+func (m *mockDBAdminHandler) ForEachUserArticle(fn func(database.UserArticle) error) error {
+	return nil
+}
+func (m *mockDBAdminHandler) ForEachSession(fn func(database.Session) error) error { return nil }
+func (m *mockDBAdminHandler) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) GetFeedCategories() (map[int]string, error)        { return nil, nil }
+func (m *mockDBAdminHandler) SetFeedCategory(feedID int, category string) error { return nil }
+func (m *mockDBAdminHandler) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBAdminHandler) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBAdminHandler) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBAdminHandler) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBAdminHandler) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBAdminHandler) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBAdminHandler) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAdminHandler) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBAdminHandler) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBAdminHandler) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAdminHandler) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBAdminHandler) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBAdminHandler) DeleteWebhookDeliveriesBefore(time.Time) (int, error) { return 0, nil }
+func (m *mockDBAdminHandler) UpdateUserDigestPreferences(int, database.DigestPreferences) error {
+	return nil
+}
+func (m *mockDBAdminHandler) GetDigestUsers() ([]database.User, error) { return []database.User{}, nil }
+func (m *mockDBAdminHandler) SetUserDigestSentAt(int, time.Time) error { return nil }
+func (m *mockDBAdminHandler) GetUserStarredCountsByFeed(int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+func (m *mockDBAdminHandler) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBAdminHandler) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBAdminHandler) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBAdminHandler) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBAdminHandler) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBAdminHandler) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBAdminHandler) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBAdminHandler) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBAdminHandler) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBAdminHandler) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBAdminHandler) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBAdminHandler) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBAdminHandler) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBAdminHandler) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBAdminHandler) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBAdminHandler) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBAdminHandler) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBAdminHandler) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBAdminHandler) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBAdminHandler) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBAdminHandler) CreateAnnotation(*database.Annotation) error          { return nil }
+func (m *mockDBAdminHandler) GetAnnotation(int, int) (*database.Annotation, error) { return nil, nil }
+func (m *mockDBAdminHandler) GetArticleAnnotations(int, int) ([]database.Annotation, error) {
+	return nil, nil
+}
+func (m *mockDBAdminHandler) GetUserAnnotations(int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBAdminHandler) UpdateAnnotation(*database.Annotation) error           { return nil }
+func (m *mockDBAdminHandler) DeleteAnnotation(int, int) error                       { return nil }
+func (m *mockDBAdminHandler) CreateUser(*database.User) error                       { return nil }
+func (m *mockDBAdminHandler) GetUserByGoogleID(string) (*database.User, error)      { return nil, nil }
 func (m *mockDBAdminHandler) GetUserByID(id int) (*database.User, error) {
 	if u, ok := m.users[id]; ok {
 		return u, nil
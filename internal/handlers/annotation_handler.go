@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type AnnotationHandler struct {
+	annotationService *services.AnnotationService
+}
+
+func NewAnnotationHandler(annotationService *services.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{annotationService: annotationService}
+}
+
+type createAnnotationRequest struct {
+	QuotedText  string `json:"quoted_text"`
+	StartOffset *int   `json:"start_offset" binding:"required"`
+	EndOffset   *int   `json:"end_offset" binding:"required"`
+	Note        string `json:"note"`
+}
+
+type updateAnnotationRequest struct {
+	QuotedText  *string `json:"quoted_text"`
+	StartOffset *int    `json:"start_offset"`
+	EndOffset   *int    `json:"end_offset"`
+	Note        *string `json:"note"`
+}
+
+func (ah *AnnotationHandler) ListAnnotations(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return
+	}
+
+	annotations, err := ah.annotationService.ListArticleAnnotations(user.ID, articleID)
+	if err != nil {
+		ah.respondError(c, err, "Failed to retrieve annotations. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"annotations": annotations})
+}
+
+func (ah *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return
+	}
+
+	var req createAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include start_offset and end_offset."})
+		return
+	}
+
+	annotation, err := ah.annotationService.CreateAnnotation(user.ID, articleID, services.AnnotationInput{
+		QuotedText:  req.QuotedText,
+		StartOffset: *req.StartOffset,
+		EndOffset:   *req.EndOffset,
+		Note:        req.Note,
+	})
+	if err != nil {
+		ah.respondError(c, err, "Failed to save the annotation. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+func (ah *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, annotationID, ok := parseAnnotationParams(c)
+	if !ok {
+		return
+	}
+
+	var req updateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request body is not valid JSON."})
+		return
+	}
+
+	annotation, err := ah.annotationService.UpdateAnnotation(user.ID, articleID, annotationID, services.AnnotationUpdate{
+		QuotedText:  req.QuotedText,
+		StartOffset: req.StartOffset,
+		EndOffset:   req.EndOffset,
+		Note:        req.Note,
+	})
+	if err != nil {
+		ah.respondError(c, err, "Failed to update the annotation. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, annotation)
+}
+
+func (ah *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, annotationID, ok := parseAnnotationParams(c)
+	if !ok {
+		return
+	}
+
+	if err := ah.annotationService.DeleteAnnotation(user.ID, articleID, annotationID); err != nil {
+		ah.respondError(c, err, "Failed to delete the annotation. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted successfully"})
+}
+
+// ExportAnnotations downloads all of the user's annotations. The format query
+// parameter selects "markdown" (default) or "json".
+func (ah *AnnotationHandler) ExportAnnotations(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	format := c.DefaultQuery("format", services.AnnotationExportMarkdown)
+	contentType, filename := "text/markdown; charset=utf-8", "goread2-annotations.md"
+	switch format {
+	case services.AnnotationExportMarkdown:
+	case services.AnnotationExportJSON:
+		contentType, filename = "application/json; charset=utf-8", "goread2-annotations.json"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The export format must be 'markdown' or 'json'."})
+		return
+	}
+
+	data, err := ah.annotationService.ExportAnnotations(user.ID, format)
+	if err != nil {
+		log.Printf("Failed to export annotations for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate the annotations export. Please try again."})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// parseAnnotationParams reads the :id and :annotationId path parameters,
+// writing a 400 response and returning ok=false if either is malformed.
+func parseAnnotationParams(c *gin.Context) (articleID, annotationID int, ok bool) {
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return 0, 0, false
+	}
+	annotationID, err = strconv.Atoi(c.Param("annotationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The annotation ID is not valid."})
+		return 0, 0, false
+	}
+	return articleID, annotationID, true
+}
+
+// respondError maps annotation service errors to HTTP responses. fallback is
+// the message used for unexpected (database) failures.
+func (ah *AnnotationHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested article could not be found."})
+	case errors.Is(err, services.ErrAnnotationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested annotation could not be found."})
+	case errors.Is(err, services.ErrInvalidAnnotation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Annotation request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func newAnnotationHandler(db *mockDBFeedHandler) *AnnotationHandler {
+	return NewAnnotationHandler(services.NewAnnotationService(db))
+}
+
+func TestCreateAnnotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	newContext := func(articleID, body string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/api/articles/"+articleID+"/annotations", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{gin.Param{Key: "id", Value: articleID}}
+		return c, w
+	}
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		handler := newAnnotationHandler(newMockDBFeedHandler())
+		c, w := newContext("1", `{"start_offset":0,"end_offset":1}`)
+
+		handler.CreateAnnotation(c)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing offsets returns 400", func(t *testing.T) {
+		handler := newAnnotationHandler(newMockDBFeedHandler())
+		c, w := newContext("1", `{"note":"no range"}`)
+		c.Set("user", testUser)
+
+		handler.CreateAnnotation(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("unknown article returns 404", func(t *testing.T) {
+		handler := newAnnotationHandler(newMockDBFeedHandler())
+		c, w := newContext("42", `{"start_offset":0,"end_offset":1}`)
+		c.Set("user", testUser)
+
+		handler.CreateAnnotation(c)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("offsets outside content return 400", func(t *testing.T) {
+		db := newMockDBFeedHandler()
+		db.mockArticle = &database.Article{ID: 42, Content: "short"}
+		handler := newAnnotationHandler(db)
+		c, w := newContext("42", `{"start_offset":0,"end_offset":50}`)
+		c.Set("user", testUser)
+
+		handler.CreateAnnotation(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("valid highlight returns 201", func(t *testing.T) {
+		db := newMockDBFeedHandler()
+		db.mockArticle = &database.Article{ID: 42, Content: "short"}
+		handler := newAnnotationHandler(db)
+		c, w := newContext("42", `{"start_offset":0,"end_offset":5,"note":"n"}`)
+		c.Set("user", testUser)
+
+		handler.CreateAnnotation(c)
+
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var got database.Annotation
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got.QuotedText != "short" || got.ArticleID != 42 || got.UserID != testUser.ID {
+			t.Errorf("unexpected annotation: %+v", got)
+		}
+	})
+}
+
+func TestUpdateAnnotation_InvalidAnnotationID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := newAnnotationHandler(newMockDBFeedHandler())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("PUT", "/api/articles/1/annotations/abc", strings.NewReader(`{}`))
+	c.Params = gin.Params{{Key: "id", Value: "1"}, {Key: "annotationId", Value: "abc"}}
+	c.Set("user", &database.User{ID: 1})
+
+	handler.UpdateAnnotation(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestExportAnnotations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		query       string
+		wantStatus  int
+		wantContent string
+	}{
+		{"", http.StatusOK, "text/markdown; charset=utf-8"},
+		{"?format=json", http.StatusOK, "application/json; charset=utf-8"},
+		{"?format=pdf", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			handler := newAnnotationHandler(newMockDBFeedHandler())
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/api/annotations/export"+tt.query, nil)
+			c.Set("user", testUser)
+
+			handler.ExportAnnotations(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantContent != "" && w.Header().Get("Content-Type") != tt.wantContent {
+				t.Errorf("expected Content-Type %q, got %q", tt.wantContent, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
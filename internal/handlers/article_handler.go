@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -42,3 +45,83 @@ func (ah *ArticleHandler) GetArticle(c *gin.Context) {
 
 	c.JSON(http.StatusOK, article)
 }
+
+type articleProgressRequest struct {
+	Progress *int `json:"progress" binding:"required"`
+}
+
+// SetProgress stores the user's scroll position in an article as a
+// percentage (0-100) so it can be resumed on another device.
+func (ah *ArticleHandler) SetProgress(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return
+	}
+
+	var req articleProgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a progress value."})
+		return
+	}
+
+	err = ah.feedService.SetArticleProgress(user.ID, id, *req.Progress)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"read_progress": *req.Progress})
+	case errors.Is(err, services.ErrInvalidProgress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Progress must be a percentage between 0 and 100."})
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested article could not be found."})
+	default:
+		log.Printf("Failed to save read progress for user %d, article %d: %v", user.ID, id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save your reading position. Please try again."})
+	}
+}
+
+type articleBatchRequest struct {
+	Operations []services.ArticleStatusOp `json:"operations" binding:"required"`
+}
+
+// BatchUpdate sets read and starred flags on many articles in one request,
+// reporting the outcome of each operation.
+func (ah *ArticleHandler) BatchUpdate(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req articleBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a list of operations."})
+		return
+	}
+	if len(req.Operations) > services.MaxArticleBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Send at most %d operations per request.", services.MaxArticleBatchSize)})
+		return
+	}
+
+	result, err := ah.feedService.UpdateArticleStatuses(user.ID, req.Operations)
+	if err != nil {
+		log.Printf("Failed to batch update articles for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the articles. Please try again."})
+		return
+	}
+
+	response := gin.H{
+		"results":        result.Results,
+		"articles_count": result.Count,
+	}
+	if result.UndoID != "" {
+		response["operation_id"] = result.UndoID
+		response["undo_expires_at"] = result.UndoExpiresAt
+	}
+	c.JSON(http.StatusOK, response)
+}
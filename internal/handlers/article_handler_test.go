@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -131,3 +132,101 @@ func TestGetArticle(t *testing.T) {
 		}
 	})
 }
+
+func TestSetProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		body       string
+		article    *database.Article
+		wantStatus int
+	}{
+		{"missing progress", `{}`, &database.Article{ID: 1}, http.StatusBadRequest},
+		{"out of range", `{"progress":101}`, &database.Article{ID: 1}, http.StatusBadRequest},
+		{"unknown article", `{"progress":40}`, nil, http.StatusNotFound},
+		{"valid", `{"progress":40}`, &database.Article{ID: 1}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDBFeedHandler()
+			db.mockArticle = tt.article
+			handler := newArticleHandler(db)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("PUT", "/api/articles/1/progress", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "1"}}
+			c.Set("user", testUser)
+
+			handler.SetProgress(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBatchUpdateArticles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+	tooMany := `{"operations":[` + strings.Repeat(`{"id":1,"read":true},`, services.MaxArticleBatchSize) + `{"id":1,"read":true}]}`
+
+	tests := []struct {
+		name       string
+		body       string
+		failDB     bool
+		wantStatus int
+		wantResult []string
+	}{
+		{"missing operations", `{}`, false, http.StatusBadRequest, nil},
+		{"too many operations", tooMany, false, http.StatusBadRequest, nil},
+		{"database failure", `{"operations":[{"id":1,"read":true}]}`, true, http.StatusInternalServerError, nil},
+		{"per-item results", `{"operations":[{"id":1,"read":true},{"id":2,"starred":true},{"id":1}]}`, false, http.StatusOK,
+			[]string{services.ArticleBatchUnchanged, services.ArticleBatchNotFound, services.ArticleBatchInvalid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDBFeedHandler()
+			db.mockArticle = &database.Article{ID: 1, FeedID: 1}
+			db.shouldFailMarkRead = tt.failDB
+			handler := newArticleHandler(db)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/articles/batch", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user", testUser)
+
+			handler.BatchUpdate(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantResult == nil {
+				return
+			}
+			var resp struct {
+				Results []services.ArticleBatchItemResult `json:"results"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Results) != len(tt.wantResult) {
+				t.Fatalf("expected %d results, got %+v", len(tt.wantResult), resp.Results)
+			}
+			for i, status := range tt.wantResult {
+				if resp.Results[i].Status != status {
+					t.Errorf("result %d: expected %s, got %+v", i, status, resp.Results[i])
+				}
+			}
+		})
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"log"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
 	"github.com/jeffreyp/goread2/internal/secrets"
 )
 
@@ -29,6 +34,13 @@ func NewAuthHandler(authService *auth.AuthService, sessionManager *auth.SessionM
 }
 
 func (ah *AuthHandler) Login(c *gin.Context) {
+	// Clients that predate the provider parameter get the default (Google)
+	provider := c.DefaultQuery("provider", ah.authService.DefaultProvider())
+	if !ah.authService.HasProvider(provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "That sign-in method isn't available."})
+		return
+	}
+
 	// Generate state parameter for CSRF protection
 	state, err := generateState()
 	if err != nil {
@@ -40,19 +52,26 @@ func (ah *AuthHandler) Login(c *gin.Context) {
 	// instead of the redirect to /.
 	mobile := c.Query("client") == "ios"
 
-	// Store state in session manager for one-time use validation
-	ah.sessionManager.StoreOAuthState(state, mobile)
+	authURL, err := ah.authService.GetAuthURL(provider, state, c.Request.Host)
+	if err != nil {
+		log.Printf("Failed to build %s auth URL: %v", provider, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication setup failed. Please try signing in again."})
+		return
+	}
+
+	// Store state in session manager for one-time use validation, along with
+	// the provider the callback has to exchange the code with
+	ah.sessionManager.StoreOAuthState(state, auth.OAuthState{Provider: provider, Mobile: mobile})
 
 	// Store state in cookie for validation (backward compatibility)
 	// Use environment-specific cookie name to avoid conflicts
 	c.SetCookie(getOAuthStateCookieName(), state, 600, "/", "", false, true) // 10 minutes
 
-	authURL := ah.authService.GetAuthURL(state, c.Request.Host)
 	if mobile {
 		// The mobile flow opens this endpoint as a top-level navigation
 		// inside ASWebAuthenticationSession, so the state cookie above must
-		// land in that browser context; redirect straight to Google rather
-		// than returning JSON the web frontend would navigate to itself.
+		// land in that browser context; redirect straight to the provider
+		// rather than returning JSON the web frontend would navigate to itself.
 		c.Redirect(http.StatusFound, authURL)
 		return
 	}
@@ -71,12 +90,13 @@ func (ah *AuthHandler) Callback(c *gin.Context) {
 	}
 
 	// Validate and consume state (one-time use check)
-	valid, mobile := ah.sessionManager.ValidateAndConsumeOAuthState(queryState)
+	pending, valid := ah.sessionManager.ValidateAndConsumeOAuthState(queryState)
 	if !valid {
 		log.Printf("SECURITY: OAuth state expired or replayed from IP %s", auth.GetSecureClientIP(c))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "The OAuth state parameter has expired or has already been used. Please try signing in again."})
 		return
 	}
+	mobile := pending.Mobile
 
 	// Clear the state cookie
 	c.SetCookie(getOAuthStateCookieName(), "", -1, "/", "", false, true)
@@ -88,13 +108,50 @@ func (ah *AuthHandler) Callback(c *gin.Context) {
 	}
 
 	// Handle the OAuth callback
-	user, err := ah.authService.HandleCallback(code, c.Request.Host)
+	user, err := ah.authService.HandleCallback(pending.Provider, code, queryState, c.Request.Host)
 	if err != nil {
-		log.Printf("OAuth callback error: %v", err)
-		ah.callbackError(c, mobile, http.StatusInternalServerError, "Authentication failed. Please try signing in again.")
+		log.Printf("OAuth callback error (%s): %v", pending.Provider, err)
+		ah.callbackError(c, mobile, signInErrorStatus(err), signInErrorMessage(err))
+		return
+	}
+
+	ah.completeSignIn(c, user, mobile)
+}
+
+// mobileCallbackURL is the custom URL scheme the iOS app registers; redirecting
+// to it completes the app's ASWebAuthenticationSession.
+const mobileCallbackURL = "goread2://auth"
+
+// callbackError reports a callback failure appropriately per client: mobile
+// flows get a redirect to the app's URL scheme (so the auth sheet dismisses
+// and the app can show the error), web flows get the JSON error as before.
+func (ah *AuthHandler) callbackError(c *gin.Context, mobile bool, status int, message string) {
+	if mobile {
+		c.Redirect(http.StatusFound, mobileCallbackURL+"?error="+url.QueryEscape(message))
 		return
 	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+// signInErrorMessage turns a sign-in failure into something the user can
+// act on; only the account-linking errors are specific enough to show.
+func signInErrorMessage(err error) string {
+	if errors.Is(err, auth.ErrEmailNotVerified) || errors.Is(err, auth.ErrEmailRequired) {
+		return "Sign-in failed: " + err.Error() + "."
+	}
+	return "Authentication failed. Please try signing in again."
+}
 
+func signInErrorStatus(err error) int {
+	if errors.Is(err, auth.ErrEmailNotVerified) || errors.Is(err, auth.ErrEmailRequired) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// completeSignIn starts a session for a user who just proved who they are,
+// by any sign-in method, and sends them on to the app.
+func (ah *AuthHandler) completeSignIn(c *gin.Context, user *database.User, mobile bool) {
 	// Invalidate any pre-existing session to prevent session fixation attacks
 	if oldSession, exists := ah.sessionManager.GetSessionFromRequest(c.Request); exists {
 		ah.sessionManager.DeleteSession(oldSession.ID)
@@ -102,7 +159,7 @@ func (ah *AuthHandler) Callback(c *gin.Context) {
 	}
 
 	// Create session
-	session, err := ah.sessionManager.CreateSession(user)
+	session, err := ah.sessionManager.CreateDeviceSession(user, auth.DeviceFromRequest(c, mobile))
 	if err != nil {
 		ah.callbackError(c, mobile, http.StatusInternalServerError, "Session creation failed. Please try signing in again.")
 		return
@@ -125,28 +182,101 @@ func (ah *AuthHandler) Callback(c *gin.Context) {
 	// Set session cookie
 	ah.sessionManager.SetSessionCookie(c.Writer, session)
 
-	// Redirect to app
-	c.Redirect(http.StatusTemporaryRedirect, "/")
+	// Redirect to app. 303 so the email sign-in form's POST becomes a GET.
+	c.Redirect(http.StatusSeeOther, "/")
 }
 
-// mobileCallbackURL is the custom URL scheme the iOS app registers; redirecting
-// to it completes the app's ASWebAuthenticationSession.
-const mobileCallbackURL = "goread2://auth"
+// Providers lists the enabled sign-in methods for the login screen.
+func (ah *AuthHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": ah.authService.Providers()})
+}
 
-// callbackError reports a callback failure appropriately per client: mobile
-// flows get a redirect to the app's URL scheme (so the auth sheet dismisses
-// and the app can show the error), web flows get the JSON error as before.
-func (ah *AuthHandler) callbackError(c *gin.Context, mobile bool, status int, message string) {
-	if mobile {
-		c.Redirect(http.StatusFound, mobileCallbackURL+"?error="+url.QueryEscape(message))
+// RequestEmailLink emails a one-time sign-in link. The response is the same
+// whether or not an account exists for the address, so it can't be used to
+// find out who has one; an unknown address gets an account when the link
+// is used.
+func (ah *AuthHandler) RequestEmailLink(c *gin.Context) {
+	if !ah.authService.EmailSignInEnabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email sign-in isn't available."})
 		return
 	}
-	c.JSON(status, gin.H{"error": message})
+
+	var req struct {
+		Email  string `json:"email"`
+		Client string `json:"client"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please enter a valid email address."})
+		return
+	}
+	addr, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil || addr.Name != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Please enter a valid email address."})
+		return
+	}
+	email := strings.ToLower(addr.Address)
+
+	token, err := ah.sessionManager.CreateLoginToken(email, req.Client == "ios")
+	if err != nil {
+		log.Printf("Failed to create login token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "We couldn't send a sign-in link. Please try again."})
+		return
+	}
+	if err := ah.authService.SendSignInLink(c.Request.Context(), email, token); err != nil {
+		log.Printf("Failed to send sign-in link: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "We couldn't send a sign-in link. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Check your email for a sign-in link."})
+}
+
+// ShowEmailSignIn is where the emailed link lands. It asks the user to
+// confirm instead of signing in straight away, because mail scanners fetch
+// links to check them and would otherwise use up the token.
+func (ah *AuthHandler) ShowEmailSignIn(c *gin.Context) {
+	c.HTML(http.StatusOK, "email_sign_in.html", gin.H{
+		"title": "Sign in - GoRead2",
+		"token": c.Query("token"),
+	})
+}
+
+// VerifyEmailLink redeems a sign-in link token posted by the confirmation
+// page and signs the user in.
+func (ah *AuthHandler) VerifyEmailLink(c *gin.Context) {
+	// The form has no CSRF token (there's no session yet), so refuse posts
+	// from other sites, which could otherwise sign a visitor in to the
+	// attacker's account
+	if origin := c.GetHeader("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != c.Request.Host {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This sign-in link must be opened on GoRead2."})
+			return
+		}
+	}
+
+	email, mobile, ok := ah.sessionManager.ConsumeLoginToken(c.PostForm("token"))
+	if !ok {
+		log.Printf("SECURITY: invalid or expired sign-in link from IP %s", auth.GetSecureClientIP(c))
+		c.HTML(http.StatusBadRequest, "email_sign_in.html", gin.H{
+			"title": "Sign in - GoRead2",
+			"error": "This sign-in link is invalid, has expired, or has already been used. Please request a new one.",
+		})
+		return
+	}
+
+	user, err := ah.authService.SignInWithEmail(email)
+	if err != nil {
+		log.Printf("Email sign-in error: %v", err)
+		ah.callbackError(c, mobile, signInErrorStatus(err), signInErrorMessage(err))
+		return
+	}
+
+	ah.completeSignIn(c, user, mobile)
 }
 
-// Token exchanges a one-time code minted by the mobile OAuth callback for the
-// session token. The app stores the token as its session cookie; subsequent
-// API calls then authenticate exactly like the web frontend's.
+// Token exchanges a one-time code minted by the mobile OAuth callback for an
+// access and refresh token pair. The app sends the access token as a bearer
+// token and uses the refresh token to get a new pair before it expires.
 func (ah *AuthHandler) Token(c *gin.Context) {
 	var req struct {
 		Code string `json:"code" binding:"required"`
@@ -156,18 +286,62 @@ func (ah *AuthHandler) Token(c *gin.Context) {
 		return
 	}
 
-	sessionID, expiresAt, ok := ah.sessionManager.ExchangeAuthCode(req.Code)
+	sessionID, _, ok := ah.sessionManager.ExchangeAuthCode(req.Code)
 	if !ok {
 		log.Printf("SECURITY: invalid or expired auth code exchange attempt from IP %s", auth.GetSecureClientIP(c))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "The authorization code is invalid or has expired. Please try signing in again."})
 		return
 	}
+	session, ok := ah.sessionManager.GetSession(sessionID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "The authorization code is invalid or has expired. Please try signing in again."})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"session_token": sessionID,
-		"cookie_name":   ah.sessionManager.SessionCookieName(),
-		"expires_at":    expiresAt,
-	})
+	tokens, err := ah.sessionManager.IssueTokens(session)
+	if err != nil {
+		log.Printf("Failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete sign-in. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+// Refresh exchanges a refresh token for a new token pair. The old refresh
+// token stops working; presenting it again signs the session out.
+func (ah *AuthHandler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A refresh token is required."})
+		return
+	}
+
+	tokens, err := ah.sessionManager.RefreshTokens(req.RefreshToken, auth.GetSecureClientIP(c))
+	switch {
+	case errors.Is(err, auth.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You have been signed out for your security. Please sign in again."})
+		return
+	case errors.Is(err, auth.ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Your sign-in has expired. Please sign in again."})
+		return
+	case err != nil:
+		log.Printf("Failed to refresh tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh your sign-in. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse(tokens))
+}
+
+func tokenResponse(tokens *auth.TokenPair) gin.H {
+	return gin.H{
+		"access_token":             tokens.AccessToken,
+		"token_type":               "Bearer",
+		"expires_in":               int(time.Until(tokens.AccessExpiresAt).Seconds()),
+		"refresh_token":            tokens.RefreshToken,
+		"refresh_token_expires_at": tokens.RefreshExpiresAt,
+	}
 }
 
 func (ah *AuthHandler) Logout(c *gin.Context) {
@@ -179,6 +353,14 @@ func (ah *AuthHandler) Logout(c *gin.Context) {
 		ah.csrfManager.DeleteToken(session.ID)
 	}
 
+	// A mobile client signs out with its access token, which names the
+	// session by handle
+	if claims, ok := auth.CurrentAccessClaims(c); ok {
+		if _, err := ah.sessionManager.RevokeUserSession(claims.UserID, claims.Session); err != nil {
+			log.Printf("Failed to revoke session on logout: %v", err)
+		}
+	}
+
 	// Clear session cookie
 	ah.sessionManager.ClearSessionCookie(c.Writer)
 
@@ -279,6 +461,12 @@ func (ah *AuthHandler) CleanupExpiredSessions(c *gin.Context) {
 	ah.sessionManager.CleanupExpiredCache()
 	ah.sessionManager.CleanupExpiredOAuthStates()
 	ah.sessionManager.CleanupExpiredAuthCodes()
+	if err := ah.sessionManager.CleanupExpiredLoginTokens(); err != nil {
+		log.Printf("Login token cleanup failed: %v", err)
+	}
+	if err := ah.sessionManager.CleanupExpiredRefreshTokens(); err != nil {
+		log.Printf("Refresh token cleanup failed: %v", err)
+	}
 
 	log.Printf("Session cleanup completed successfully")
 	c.JSON(http.StatusOK, gin.H{"message": "Session cleanup completed"})
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jeffreyp/goread2/internal/auth"
 	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/mail"
 	"github.com/jeffreyp/goread2/internal/secrets"
 )
 
@@ -89,6 +92,126 @@ func (m *mockDBAuthHandler) GetAccountStats(int) (map[string]interface{}, error)
 }
 func (m *mockDBAuthHandler) UpdateFeedLastFetch(int, time.Time) error { return nil }
 func (m *mockDBAuthHandler) Close() error                             { return nil }
+func (m *mockDBAuthHandler) GetArticlesByIDs(int, []int) (map[int]database.Article, error) {
+	return nil, nil
+}
+func (m mockDBAuthHandler) CreateRefreshToken(*database.RefreshToken) error { return nil }
+func (m mockDBAuthHandler) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBAuthHandler) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBAuthHandler) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBAuthHandler) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBAuthHandler) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) GetUserIdentities(int) ([]database.UserIdentity, error)      { return nil, nil }
+func (m *mockDBAuthHandler) CreateLoginToken(*database.LoginToken) error                 { return nil }
+func (m *mockDBAuthHandler) ConsumeLoginToken(string) (*database.LoginToken, error)      { return nil, nil }
+func (m *mockDBAuthHandler) DeleteExpiredLoginTokens() error                             { return nil }
+func (m *mockDBAuthHandler) ForEachUserIdentity(func(database.UserIdentity) error) error { return nil }
+func (m *mockDBAuthHandler) DeleteUser(userID int) error                                 { return nil }
+func (m *mockDBAuthHandler) GetUserArticleHistory(userID int) ([]database.Article, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) GetUserSessions(userID int) ([]database.Session, error)       { return nil, nil }
+func (m *mockDBAuthHandler) ForEachUser(fn func(database.User) error) error               { return nil }
+func (m *mockDBAuthHandler) ForEachArticle(fn func(database.Article) error) error         { return nil }
+func (m *mockDBAuthHandler) ForEachUserArticle(fn func(database.UserArticle) error) error { return nil }
+func (m *mockDBAuthHandler) ForEachSession(fn func(database.Session) error) error         { return nil }
+func (m *mockDBAuthHandler) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) GetFeedCategories() (map[int]string, error)        { return nil, nil }
+func (m *mockDBAuthHandler) SetFeedCategory(feedID int, category string) error { return nil }
+func (m *mockDBAuthHandler) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBAuthHandler) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBAuthHandler) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBAuthHandler) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBAuthHandler) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBAuthHandler) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBAuthHandler) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAuthHandler) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBAuthHandler) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBAuthHandler) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAuthHandler) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBAuthHandler) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBAuthHandler) DeleteWebhookDeliveriesBefore(time.Time) (int, error) { return 0, nil }
+func (m *mockDBAuthHandler) UpdateUserDigestPreferences(int, database.DigestPreferences) error {
+	return nil
+}
+func (m *mockDBAuthHandler) GetDigestUsers() ([]database.User, error) { return []database.User{}, nil }
+func (m *mockDBAuthHandler) SetUserDigestSentAt(int, time.Time) error { return nil }
+func (m *mockDBAuthHandler) GetUserStarredCountsByFeed(int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+func (m *mockDBAuthHandler) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBAuthHandler) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBAuthHandler) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBAuthHandler) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBAuthHandler) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBAuthHandler) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBAuthHandler) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBAuthHandler) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBAuthHandler) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBAuthHandler) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBAuthHandler) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBAuthHandler) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBAuthHandler) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBAuthHandler) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBAuthHandler) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBAuthHandler) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBAuthHandler) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBAuthHandler) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBAuthHandler) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBAuthHandler) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBAuthHandler) CreateAnnotation(*database.Annotation) error          { return nil }
+func (m *mockDBAuthHandler) GetAnnotation(int, int) (*database.Annotation, error) { return nil, nil }
+func (m *mockDBAuthHandler) GetArticleAnnotations(int, int) ([]database.Annotation, error) {
+	return nil, nil
+}
+func (m *mockDBAuthHandler) GetUserAnnotations(int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBAuthHandler) UpdateAnnotation(*database.Annotation) error           { return nil }
+func (m *mockDBAuthHandler) DeleteAnnotation(int, int) error                       { return nil }
 func (m *mockDBAuthHandler) UpdateFeedCacheHeaders(feedID int, etag, lastModified string) error {
 	return nil
 }
@@ -132,15 +255,15 @@ func TestOAuthStateExpiration(t *testing.T) {
 
 	// Store a state
 	state := "test-state-12345"
-	sessionManager.StoreOAuthState(state, false)
+	sessionManager.StoreOAuthState(state, auth.OAuthState{Provider: auth.ProviderGoogle})
 
 	// Validate immediately - should succeed
-	if valid, _ := sessionManager.ValidateAndConsumeOAuthState(state); !valid {
+	if _, valid := sessionManager.ValidateAndConsumeOAuthState(state); !valid {
 		t.Error("Fresh state should be valid")
 	}
 
 	// Try to validate again - should fail (already consumed)
-	if valid, _ := sessionManager.ValidateAndConsumeOAuthState(state); valid {
+	if _, valid := sessionManager.ValidateAndConsumeOAuthState(state); valid {
 		t.Error("Consumed state should not be valid again")
 	}
 }
@@ -151,7 +274,7 @@ func TestOAuthStateInvalidState(t *testing.T) {
 	sessionManager := auth.NewSessionManager(db)
 
 	// Try to validate a state that was never stored
-	if valid, _ := sessionManager.ValidateAndConsumeOAuthState("unknown-state"); valid {
+	if _, valid := sessionManager.ValidateAndConsumeOAuthState("unknown-state"); valid {
 		t.Error("Unknown state should be invalid")
 	}
 }
@@ -162,14 +285,14 @@ func TestOAuthStateMobileFlag(t *testing.T) {
 	db := &mockDBAuthHandler{}
 	sessionManager := auth.NewSessionManager(db)
 
-	sessionManager.StoreOAuthState("web-state", false)
-	sessionManager.StoreOAuthState("ios-state", true)
+	sessionManager.StoreOAuthState("web-state", auth.OAuthState{Provider: auth.ProviderGoogle})
+	sessionManager.StoreOAuthState("ios-state", auth.OAuthState{Provider: auth.ProviderGoogle, Mobile: true})
 
-	if valid, mobile := sessionManager.ValidateAndConsumeOAuthState("web-state"); !valid || mobile {
-		t.Errorf("web state: expected valid=true mobile=false, got valid=%v mobile=%v", valid, mobile)
+	if pending, valid := sessionManager.ValidateAndConsumeOAuthState("web-state"); !valid || pending.Mobile {
+		t.Errorf("web state: expected valid=true mobile=false, got valid=%v mobile=%v", valid, pending.Mobile)
 	}
-	if valid, mobile := sessionManager.ValidateAndConsumeOAuthState("ios-state"); !valid || !mobile {
-		t.Errorf("ios state: expected valid=true mobile=true, got valid=%v mobile=%v", valid, mobile)
+	if pending, valid := sessionManager.ValidateAndConsumeOAuthState("ios-state"); !valid || !pending.Mobile {
+		t.Errorf("ios state: expected valid=true mobile=true, got valid=%v mobile=%v", valid, pending.Mobile)
 	}
 }
 
@@ -209,31 +332,53 @@ func TestAuthCodeExchange(t *testing.T) {
 	}
 }
 
-func TestToken(t *testing.T) {
-	gin.SetMode(gin.TestMode)
+// newTokenTestHandler returns an AuthHandler backed by an in-memory
+// database, since issuing and refreshing tokens reads and writes sessions,
+// and a session for a signed-in user.
+func newTokenTestHandler(t *testing.T) (*AuthHandler, *auth.Session) {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db := &database.DB{DB: conn}
+	if err := db.CreateTables(); err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
 
-	newHandler := func() *AuthHandler {
-		db := &mockDBAuthHandler{}
-		sessionManager := auth.NewSessionManager(db)
-		csrfManager := auth.NewCSRFManager()
-		return NewAuthHandler(nil, sessionManager, csrfManager)
+	user := &database.User{GoogleID: "ann", Email: "ann@example.com", CreatedAt: time.Now()}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
 	}
+	sessionManager := auth.NewSessionManager(db)
+	session, err := sessionManager.CreateDeviceSession(user, auth.Device{ClientType: auth.ClientTypeIOS})
+	if err != nil {
+		t.Fatalf("CreateDeviceSession: %v", err)
+	}
+	return NewAuthHandler(nil, sessionManager, auth.NewCSRFManager()), session
+}
 
-	t.Run("valid code returns session token", func(t *testing.T) {
-		handler := newHandler()
-		expiresAt := time.Now().Add(7 * 24 * time.Hour)
-		code, err := handler.sessionManager.CreateAuthCode(&auth.Session{ID: "session-xyz", ExpiresAt: expiresAt})
+func postJSON(fn gin.HandlerFunc, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	fn(c)
+	return w
+}
+
+func TestToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("valid code returns a token pair", func(t *testing.T) {
+		handler, session := newTokenTestHandler(t)
+		code, err := handler.sessionManager.CreateAuthCode(session)
 		if err != nil {
 			t.Fatalf("CreateAuthCode failed: %v", err)
 		}
 
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		body := strings.NewReader(`{"code":"` + code + `"}`)
-		c.Request = httptest.NewRequest("POST", "/auth/token", body)
-		c.Request.Header.Set("Content-Type", "application/json")
-
-		handler.Token(c)
+		w := postJSON(handler.Token, "/auth/token", `{"code":"`+code+`"}`)
 
 		if w.Code != http.StatusOK {
 			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
@@ -242,40 +387,112 @@ func TestToken(t *testing.T) {
 		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
 			t.Fatalf("failed to parse response: %v", err)
 		}
-		if resp["session_token"] != "session-xyz" {
-			t.Errorf("expected session_token session-xyz, got %v", resp["session_token"])
+		if strings.Contains(w.Body.String(), session.ID) {
+			t.Error("response must not contain the session ID")
+		}
+		if resp["token_type"] != "Bearer" {
+			t.Errorf("expected token_type Bearer, got %v", resp["token_type"])
+		}
+		accessToken, _ := resp["access_token"].(string)
+		claims, err := handler.sessionManager.VerifyAccessToken(accessToken)
+		if err != nil {
+			t.Fatalf("access token doesn't verify: %v", err)
+		}
+		if claims.UserID != session.UserID || claims.Session != auth.SessionHandle(session.ID) {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+		if expiresIn, _ := resp["expires_in"].(float64); expiresIn <= 0 || expiresIn > 900 {
+			t.Errorf("expected expires_in within 15 minutes, got %v", resp["expires_in"])
 		}
-		if resp["cookie_name"] != "session_id_local" {
-			t.Errorf("expected cookie_name session_id_local in tests, got %v", resp["cookie_name"])
+		if resp["refresh_token"] == "" || resp["refresh_token"] == nil {
+			t.Error("response missing refresh_token")
 		}
-		if resp["expires_at"] == nil {
-			t.Error("response missing expires_at")
+		if resp["refresh_token_expires_at"] == nil {
+			t.Error("response missing refresh_token_expires_at")
 		}
 	})
 
 	t.Run("invalid code returns 401", func(t *testing.T) {
-		handler := newHandler()
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest("POST", "/auth/token", strings.NewReader(`{"code":"bogus"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-
-		handler.Token(c)
-
+		handler, _ := newTokenTestHandler(t)
+		w := postJSON(handler.Token, "/auth/token", `{"code":"bogus"}`)
 		if w.Code != http.StatusUnauthorized {
 			t.Errorf("expected 401, got %d", w.Code)
 		}
 	})
 
 	t.Run("missing code returns 400", func(t *testing.T) {
-		handler := newHandler()
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest("POST", "/auth/token", strings.NewReader(`{}`))
-		c.Request.Header.Set("Content-Type", "application/json")
+		handler, _ := newTokenTestHandler(t)
+		w := postJSON(handler.Token, "/auth/token", `{}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", w.Code)
+		}
+	})
+}
 
-		handler.Token(c)
+func TestRefresh(t *testing.T) {
+	gin.SetMode(gin.TestMode)
 
+	refreshToken := func(w *httptest.ResponseRecorder) string {
+		var resp struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.RefreshToken
+	}
+
+	t.Run("rotates the refresh token", func(t *testing.T) {
+		handler, session := newTokenTestHandler(t)
+		tokens, err := handler.sessionManager.IssueTokens(session)
+		if err != nil {
+			t.Fatalf("IssueTokens: %v", err)
+		}
+
+		w := postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		next := refreshToken(w)
+		if next == "" || next == tokens.RefreshToken {
+			t.Fatalf("expected a new refresh token, got %q", next)
+		}
+
+		w = postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"`+next+`"}`)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected the rotated token to work, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("reused token signs the session out", func(t *testing.T) {
+		handler, session := newTokenTestHandler(t)
+		tokens, _ := handler.sessionManager.IssueTokens(session)
+
+		w := postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+		next := refreshToken(w)
+
+		w = postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 on reuse, got %d", w.Code)
+		}
+		w = postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"`+next+`"}`)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected the rotated token revoked with its session, got %d", w.Code)
+		}
+		if _, ok := handler.sessionManager.GetSession(session.ID); ok {
+			t.Error("expected the session signed out")
+		}
+	})
+
+	t.Run("unknown token returns 401", func(t *testing.T) {
+		handler, _ := newTokenTestHandler(t)
+		w := postJSON(handler.Refresh, "/auth/refresh", `{"refresh_token":"bogus"}`)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("missing token returns 400", func(t *testing.T) {
+		handler, _ := newTokenTestHandler(t)
+		w := postJSON(handler.Refresh, "/auth/refresh", `{}`)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("expected 400, got %d", w.Code)
 		}
@@ -312,8 +529,8 @@ func TestLoginMobileRedirect(t *testing.T) {
 		if state == "" {
 			t.Fatal("redirect URL missing state parameter")
 		}
-		if valid, mobile := handler.sessionManager.ValidateAndConsumeOAuthState(state); !valid || !mobile {
-			t.Errorf("expected stored state to be valid and mobile, got valid=%v mobile=%v", valid, mobile)
+		if pending, valid := handler.sessionManager.ValidateAndConsumeOAuthState(state); !valid || !pending.Mobile {
+			t.Errorf("expected stored state to be valid and mobile, got valid=%v mobile=%v", valid, pending.Mobile)
 		}
 	})
 
@@ -525,3 +742,132 @@ func TestLogout(t *testing.T) {
 		}
 	})
 }
+
+// recordingAuthMailer keeps sign-in emails for inspection.
+type recordingAuthMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingAuthMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestLoginProviderSelection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := &mockDBAuthHandler{}
+	handler := NewAuthHandler(auth.NewAuthService(db), auth.NewSessionManager(db), auth.NewCSRFManager())
+
+	for _, provider := range []string{"myspace", auth.ProviderEmail} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/auth/login?provider="+provider, nil)
+
+		handler.Login(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("provider %q: expected 400, got %d", provider, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/auth/providers", nil)
+	handler.Providers(c)
+
+	var resp struct {
+		Providers []auth.ProviderInfo `json:"providers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Providers) != 1 || resp.Providers[0].ID != auth.ProviderGoogle {
+		t.Errorf("expected only Google by default, got %+v", resp.Providers)
+	}
+}
+
+func TestRequestEmailLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newHandler := func(mailer mail.Mailer) *AuthHandler {
+		db := &mockDBAuthHandler{}
+		authService := auth.NewAuthService(db)
+		if mailer != nil {
+			authService.EnableEmailSignIn(mailer, "https://reader.example.com")
+		}
+		return NewAuthHandler(authService, auth.NewSessionManager(db), auth.NewCSRFManager())
+	}
+	post := func(handler *AuthHandler, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/auth/email", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.RequestEmailLink(c)
+		return w
+	}
+
+	if w := post(newHandler(nil), `{"email":"ann@example.com"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when email sign-in is off, got %d", w.Code)
+	}
+
+	mailer := &recordingAuthMailer{}
+	handler := newHandler(mailer)
+	for _, body := range []string{`{"email":"not an address"}`, `{"email":"Ann <ann@example.com>"}`, `{}`} {
+		if w := post(handler, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	if w := post(handler, `{"email":" Ann@Example.com "}`); w.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].To != "ann@example.com" {
+		t.Fatalf("expected one link sent to the normalized address, got %+v", mailer.sent)
+	}
+	if !strings.Contains(mailer.sent[0].Text, "https://reader.example.com/auth/email/verify?token=") {
+		t.Errorf("expected a verify link in the email, got %q", mailer.sent[0].Text)
+	}
+}
+
+func TestEmailSignInPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := &mockDBAuthHandler{}
+	handler := NewAuthHandler(auth.NewAuthService(db), auth.NewSessionManager(db), auth.NewCSRFManager())
+	page := `{{define "email_sign_in.html"}}token={{.token}} error={{.error}}{{end}}`
+
+	t.Run("link shows a confirmation form", func(t *testing.T) {
+		c, w := newHTMLTestEngine(t, "email_sign_in.html", page)
+		c.Request = httptest.NewRequest("GET", "/auth/email/verify?token=abc", nil)
+
+		handler.ShowEmailSignIn(c)
+
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "token=abc") {
+			t.Errorf("expected the token carried into the form, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("unknown token is rejected", func(t *testing.T) {
+		c, w := newHTMLTestEngine(t, "email_sign_in.html", page)
+		c.Request = httptest.NewRequest("POST", "/auth/email/verify", strings.NewReader("token=abc"))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		handler.VerifyEmailLink(c)
+
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "request a new one") {
+			t.Errorf("expected 400 with an explanation, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("cross-site post is refused", func(t *testing.T) {
+		c, w := newHTMLTestEngine(t, "email_sign_in.html", page)
+		c.Request = httptest.NewRequest("POST", "/auth/email/verify", strings.NewReader("token=abc"))
+		c.Request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c.Request.Header.Set("Origin", "https://evil.example.com")
+
+		handler.VerifyEmailLink(c)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", w.Code)
+		}
+	})
+}
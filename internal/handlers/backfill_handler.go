@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+// BackfillRunner runs a backfill one checkpointed batch at a time. It is
+// satisfied by *database.DatastoreDB; the SQL backends change data with
+// migrations instead and leave it nil.
+type BackfillRunner interface {
+	RunBackfillBatch(b database.Backfill, dryRun bool) (*database.BackfillProgress, error)
+}
+
+type BackfillHandler struct {
+	runner    BackfillRunner
+	taskQueue TaskQueue
+}
+
+func NewBackfillHandler(runner BackfillRunner) *BackfillHandler {
+	return &BackfillHandler{runner: runner}
+}
+
+// SetTaskQueue lets each batch enqueue the next one. See FeedHandler.SetTaskQueue.
+func (bh *BackfillHandler) SetTaskQueue(tq TaskQueue) {
+	bh.taskQueue = tq
+}
+
+// TaskRunBackfill is the Cloud Tasks worker endpoint for /tasks/backfill. Each
+// dispatch runs one batch of the backfill named by the name query parameter
+// and enqueues the next, so a long backfill becomes a chain of short requests
+// that each resume from the stored checkpoint. A failed batch returns 500 and
+// Cloud Tasks retries it from the same checkpoint. Without a task queue
+// (local dev) the remaining batches run in this request instead.
+func (bh *BackfillHandler) TaskRunBackfill(c *gin.Context) {
+	if !auth.VerifyTaskRequest(c) {
+		return
+	}
+	if bh.runner == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Backfills only run on the Datastore backend"})
+		return
+	}
+
+	name := c.Query("name")
+	backfill, ok := services.LookupBackfill(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown backfill"})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	for {
+		progress, err := bh.runner.RunBackfillBatch(backfill, dryRun)
+		if err != nil {
+			log.Printf("Backfill %s batch failed: %v", name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Backfill batch failed"})
+			return
+		}
+		if progress.Done {
+			log.Printf("Backfill %s completed: scanned %d, changed %d (dry run: %v)", name, progress.Scanned, progress.Changed, dryRun)
+			c.JSON(http.StatusOK, gin.H{"message": "Backfill completed", "progress": progress})
+			return
+		}
+		if bh.taskQueue == nil {
+			continue
+		}
+
+		if err := bh.taskQueue.Enqueue(c.Request.Context(), services.BackfillTaskURI(name, dryRun)); err != nil {
+			// The checkpoint has already moved on, so Cloud Tasks' retry of
+			// this request runs the next batch and tries the enqueue again
+			log.Printf("Failed to enqueue next batch of backfill %s: %v", name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enqueue next backfill batch"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Backfill batch completed", "progress": progress})
+		return
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/secrets"
+)
+
+// fakeBackfillRunner finishes after a fixed number of batches.
+type fakeBackfillRunner struct {
+	batches    int
+	ran        int
+	shouldFail bool
+	lastDryRun bool
+}
+
+func (r *fakeBackfillRunner) RunBackfillBatch(b database.Backfill, dryRun bool) (*database.BackfillProgress, error) {
+	if r.shouldFail {
+		return nil, errors.New("batch failed")
+	}
+	r.ran++
+	r.lastDryRun = dryRun
+	return &database.BackfillProgress{Name: b.Name, DryRun: dryRun, Batches: int64(r.ran), Done: r.ran >= r.batches}, nil
+}
+
+func serveBackfillTask(t *testing.T, handler *BackfillHandler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	t.Setenv("ADMIN_TOKEN", "test-admin-token-value")
+	secrets.ResetCacheForTesting()
+	t.Cleanup(secrets.ResetCacheForTesting)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", target, nil)
+	c.Request.Header.Set("X-Admin-Token", "test-admin-token-value")
+	c.Set("user", &database.User{ID: 1, Email: "admin@example.com", IsAdmin: true})
+	handler.TaskRunBackfill(c)
+	return w
+}
+
+func TestTaskRunBackfill(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("unauthorized without queue header or admin auth", func(t *testing.T) {
+		t.Setenv("ADMIN_TOKEN", "test-admin-token-value")
+		secrets.ResetCacheForTesting()
+		t.Cleanup(secrets.ResetCacheForTesting)
+		runner := &fakeBackfillRunner{batches: 1}
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("POST", "/tasks/backfill?name=article-reading-time", nil)
+		NewBackfillHandler(runner).TaskRunBackfill(c)
+		if w.Code != http.StatusForbidden || runner.ran != 0 {
+			t.Errorf("expected 403 without running, got %d after %d batches", w.Code, runner.ran)
+		}
+	})
+
+	t.Run("non-Datastore backend is rejected", func(t *testing.T) {
+		w := serveBackfillTask(t, NewBackfillHandler(nil), "/tasks/backfill?name=article-reading-time")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("unknown backfill returns 404", func(t *testing.T) {
+		w := serveBackfillTask(t, NewBackfillHandler(&fakeBackfillRunner{batches: 1}), "/tasks/backfill?name=nope")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("runs one batch and enqueues the next", func(t *testing.T) {
+		runner := &fakeBackfillRunner{batches: 3}
+		tq := &fakeTaskQueue{}
+		handler := NewBackfillHandler(runner)
+		handler.SetTaskQueue(tq)
+
+		w := serveBackfillTask(t, handler, "/tasks/backfill?name=article-reading-time&dry_run=true")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if runner.ran != 1 || !runner.lastDryRun {
+			t.Errorf("expected one dry-run batch, got %d (dry run %v)", runner.ran, runner.lastDryRun)
+		}
+		if len(tq.enqueued) != 1 || tq.enqueued[0] != "/tasks/backfill?dry_run=true&name=article-reading-time" {
+			t.Errorf("expected the next batch enqueued, got %v", tq.enqueued)
+		}
+	})
+
+	t.Run("last batch enqueues nothing", func(t *testing.T) {
+		runner := &fakeBackfillRunner{batches: 1}
+		tq := &fakeTaskQueue{}
+		handler := NewBackfillHandler(runner)
+		handler.SetTaskQueue(tq)
+
+		w := serveBackfillTask(t, handler, "/tasks/backfill?name=article-reading-time")
+		if w.Code != http.StatusOK || len(tq.enqueued) != 0 {
+			t.Errorf("expected 200 with nothing enqueued, got %d and %v", w.Code, tq.enqueued)
+		}
+		var resp struct {
+			Progress database.BackfillProgress `json:"progress"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if !resp.Progress.Done || resp.Progress.Name != "article-reading-time" {
+			t.Errorf("expected finished progress, got %+v", resp.Progress)
+		}
+	})
+
+	t.Run("without a task queue runs every batch", func(t *testing.T) {
+		runner := &fakeBackfillRunner{batches: 4}
+		w := serveBackfillTask(t, NewBackfillHandler(runner), "/tasks/backfill?name=article-reading-time")
+		if w.Code != http.StatusOK || runner.ran != 4 {
+			t.Errorf("expected all 4 batches in-process, got %d after %d", w.Code, runner.ran)
+		}
+	})
+
+	t.Run("failed batch returns 500 for a retry", func(t *testing.T) {
+		w := serveBackfillTask(t, NewBackfillHandler(&fakeBackfillRunner{shouldFail: true}), "/tasks/backfill?name=article-reading-time")
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d", w.Code)
+		}
+	})
+
+	t.Run("enqueue failure returns 500", func(t *testing.T) {
+		handler := NewBackfillHandler(&fakeBackfillRunner{batches: 2})
+		handler.SetTaskQueue(&fakeTaskQueue{shouldFail: true})
+		w := serveBackfillTask(t, handler, "/tasks/backfill?name=article-reading-time")
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected 500, got %d", w.Code)
+		}
+	})
+}
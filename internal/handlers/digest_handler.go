@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type DigestHandler struct {
+	digestService *services.DigestService
+}
+
+func NewDigestHandler(digestService *services.DigestService) *DigestHandler {
+	return &DigestHandler{digestService: digestService}
+}
+
+// GetPreferences returns the user's email digest settings.
+func (dh *DigestHandler) GetPreferences(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	stored, err := dh.digestService.GetPreferences(user.ID)
+	if err != nil {
+		log.Printf("Failed to load digest preferences for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load digest settings. Please try again."})
+		return
+	}
+
+	frequency := stored.DigestFrequency
+	if frequency == services.DigestOff {
+		frequency = "off"
+	}
+	timezone := stored.DigestTimezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	response := gin.H{
+		"frequency":       frequency,
+		"timezone":        timezone,
+		"hour":            stored.DigestHour,
+		"weekday":         stored.DigestWeekday,
+		"mail_configured": dh.digestService.MailConfigured(),
+	}
+	if !stored.DigestLastSentAt.IsZero() {
+		response["last_sent_at"] = stored.DigestLastSentAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type digestPreferencesRequest struct {
+	Frequency string `json:"frequency" binding:"required"`
+	Timezone  string `json:"timezone"`
+	Hour      int    `json:"hour"`
+	Weekday   int    `json:"weekday"`
+}
+
+// UpdatePreferences turns digests on or off and sets when they are sent.
+func (dh *DigestHandler) UpdatePreferences(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req digestPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a digest frequency."})
+		return
+	}
+
+	prefs, err := dh.digestService.UpdatePreferences(user.ID, database.DigestPreferences{
+		Frequency: req.Frequency,
+		Timezone:  req.Timezone,
+		Hour:      req.Hour,
+		Weekday:   req.Weekday,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidDigestPreferences) {
+			msg := strings.TrimPrefix(err.Error(), services.ErrInvalidDigestPreferences.Error()+": ")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest settings: " + msg + "."})
+			return
+		}
+		log.Printf("Failed to save digest preferences for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save digest settings. Please try again."})
+		return
+	}
+
+	frequency := prefs.Frequency
+	if frequency == services.DigestOff {
+		frequency = "off"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Digest settings updated",
+		"frequency": frequency,
+		"timezone":  prefs.Timezone,
+		"hour":      prefs.Hour,
+		"weekday":   prefs.Weekday,
+	})
+}
+
+// SendDigests is the cron endpoint that emails every digest that is due.
+func (dh *DigestHandler) SendDigests(c *gin.Context) {
+	if !auth.VerifyCronRequest(c) {
+		return
+	}
+
+	result, err := dh.digestService.SendDueDigests(c.Request.Context(), time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrMailerNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email delivery is not configured."})
+			return
+		}
+		log.Printf("Digest run failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send digests. Please try again."})
+		return
+	}
+
+	log.Printf("Digest run completed: %d due, %d sent, %d empty, %d failed", result.Due, result.Sent, result.Empty, result.Failed)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func TestDigestGetPreferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newMockDBFeedHandler()
+	db.mockUser = &database.User{ID: 1, DigestFrequency: services.DigestWeekly, DigestTimezone: "Europe/Berlin", DigestHour: 8, DigestWeekday: 1}
+	handler := NewDigestHandler(services.NewDigestService(db, nil, "https://reader.example.com"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/account/digest", nil)
+	c.Set("user", &database.User{ID: 1})
+
+	handler.GetPreferences(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["frequency"] != "weekly" || resp["timezone"] != "Europe/Berlin" || resp["hour"] != float64(8) {
+		t.Errorf("unexpected preferences: %v", resp)
+	}
+	if resp["mail_configured"] != false {
+		t.Errorf("expected mail_configured false without a mailer, got %v", resp["mail_configured"])
+	}
+}
+
+func TestDigestUpdatePreferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		body       string
+		user       *database.User
+		wantStatus int
+	}{
+		{"unauthenticated returns 401", `{"frequency":"daily"}`, nil, http.StatusUnauthorized},
+		{"missing frequency returns 400", `{}`, testUser, http.StatusBadRequest},
+		{"unknown frequency returns 400", `{"frequency":"hourly"}`, testUser, http.StatusBadRequest},
+		{"unknown timezone returns 400", `{"frequency":"daily","timezone":"Nowhere/Special"}`, testUser, http.StatusBadRequest},
+		{"daily digest", `{"frequency":"daily","timezone":"America/Chicago","hour":7}`, testUser, http.StatusOK},
+		{"turning digests off", `{"frequency":"off"}`, testUser, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewDigestHandler(services.NewDigestService(newMockDBFeedHandler(), nil, ""))
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("PUT", "/api/account/digest", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.UpdatePreferences(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type DirectoryHandler struct {
+	directoryService *services.DirectoryService
+}
+
+func NewDirectoryHandler(directoryService *services.DirectoryService) *DirectoryHandler {
+	return &DirectoryHandler{directoryService: directoryService}
+}
+
+// GetDirectory lists popular feeds, filtered by the q and category query
+// parameters and paged with limit and offset.
+func (dh *DirectoryHandler) GetDirectory(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	query := services.DirectoryQuery{
+		Search:   c.Query("q"),
+		Category: c.Query("category"),
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		query.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil {
+		query.Offset = offset
+	}
+
+	page, err := dh.directoryService.ListDirectory(user.ID, query)
+	if err != nil {
+		log.Printf("Failed to list feed directory for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the feed directory. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetRecommendations suggests feeds the user doesn't follow yet.
+func (dh *DirectoryHandler) GetRecommendations(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	recommendations, err := dh.directoryService.RecommendationsForUser(user.ID, limit)
+	if err != nil {
+		log.Printf("Failed to load recommendations for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load recommendations. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recommendations": recommendations})
+}
+
+// SetFeedCategory handles POST /admin/feeds/:id/category
+func (dh *DirectoryHandler) SetFeedCategory(c *gin.Context) {
+	feedID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || feedID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The feed ID is not valid."})
+		return
+	}
+
+	var request struct {
+		Category string `json:"category"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request body could not be parsed.", "details": err.Error()})
+		return
+	}
+
+	category, err := dh.directoryService.SetFeedCategory(feedID, request.Category)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCategory) {
+			msg := strings.TrimPrefix(err.Error(), services.ErrInvalidCategory.Error()+": ")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category: " + msg + "."})
+			return
+		}
+		log.Printf("Failed to set category for feed %d: %v", feedID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the feed's category. Please try again."})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"feed_id": feedID, "category": category})
+}
+
+// UpdateDirectory is the cron endpoint that recomputes the directory and
+// recommendations.
+func (dh *DirectoryHandler) UpdateDirectory(c *gin.Context) {
+	if !auth.VerifyCronRequest(c) {
+		return
+	}
+
+	result, err := dh.directoryService.UpdateDirectory(c.Request.Context(), time.Now())
+	if err != nil {
+		log.Printf("Directory update failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the feed directory. Please try again."})
+		return
+	}
+
+	log.Printf("Directory update completed: %d subscriptions, %d feeds listed, %d recommendations",
+		result.Subscriptions, result.Feeds, result.Recommendations)
+	c.JSON(http.StatusOK, result)
+}
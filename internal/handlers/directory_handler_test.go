@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func TestDirectoryEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+	handler := NewDirectoryHandler(services.NewDirectoryService(newMockDBFeedHandler(), 0))
+
+	tests := []struct {
+		name       string
+		handle     gin.HandlerFunc
+		method     string
+		target     string
+		body       string
+		user       *database.User
+		wantStatus int
+		wantBody   string
+	}{
+		{"directory requires sign-in", handler.GetDirectory, "GET", "/api/directory", "", nil, http.StatusUnauthorized, ""},
+		{"directory", handler.GetDirectory, "GET", "/api/directory?q=go&limit=5&offset=x", "", testUser, http.StatusOK, `"feeds":[],"total":0`},
+		{"recommendations require sign-in", handler.GetRecommendations, "GET", "/api/recommendations", "", nil, http.StatusUnauthorized, ""},
+		{"recommendations", handler.GetRecommendations, "GET", "/api/recommendations?limit=3", "", testUser, http.StatusOK, `"recommendations":[]`},
+		{"category for a bad feed ID", handler.SetFeedCategory, "POST", "/admin/feeds/abc/category", `{"category":"News"}`, testUser, http.StatusBadRequest, ""},
+		{"category that is too long", handler.SetFeedCategory, "POST", "/admin/feeds/1/category", `{"category":"` + strings.Repeat("x", 41) + `"}`, testUser, http.StatusBadRequest, ""},
+		{"category", handler.SetFeedCategory, "POST", "/admin/feeds/1/category", `{"category":"News"}`, testUser, http.StatusOK, `"category":"News","feed_id":1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if parts := strings.Split(tt.target, "/"); len(parts) > 3 && parts[2] == "feeds" {
+				c.Params = gin.Params{{Key: "id", Value: parts[3]}}
+			}
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			tt.handle(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("expected the response to contain %s, got %s", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
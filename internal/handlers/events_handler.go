@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/events"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+const (
+	// eventsHeartbeatInterval keeps idle streams from being closed by proxies.
+	eventsHeartbeatInterval = 25 * time.Second
+
+	// eventsFeedReloadInterval limits how often a stream re-reads the user's
+	// subscriptions after seeing an event for a feed it doesn't know about.
+	eventsFeedReloadInterval = time.Minute
+)
+
+type EventsHandler struct {
+	bus         events.Bus
+	feedService *services.FeedService
+}
+
+func NewEventsHandler(bus events.Bus, feedService *services.FeedService) *EventsHandler {
+	return &EventsHandler{bus: bus, feedService: feedService}
+}
+
+// Stream serves a Server-Sent Events stream of changes to the user's feeds and
+// articles. It runs until the client disconnects.
+func (eh *EventsHandler) Stream(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	feeds, err := eh.userFeedIDs(user.ID)
+	if err != nil {
+		log.Printf("Failed to load feeds for event stream (user %d): %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open the event stream. Please try again."})
+		return
+	}
+	feedsLoadedAt := time.Now()
+
+	sub, cancel := eh.bus.Subscribe(user.ID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+	c.Status(http.StatusOK)
+	if !eh.write(c, ": connected\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			if !eh.write(c, ": ping\n\n") {
+				return
+			}
+
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			if e.UserID == 0 && !feeds[e.FeedID] {
+				// Feed-wide event: only relevant if the user is subscribed. Re-read
+				// subscriptions occasionally so newly added feeds are picked up.
+				if time.Since(feedsLoadedAt) < eventsFeedReloadInterval {
+					continue
+				}
+				if reloaded, err := eh.userFeedIDs(user.ID); err == nil {
+					feeds = reloaded
+				}
+				feedsLoadedAt = time.Now()
+				if !feeds[e.FeedID] {
+					continue
+				}
+			}
+
+			data, err := json.Marshal(e.Data)
+			if err != nil {
+				log.Printf("Failed to encode %s event for user %d: %v", e.Type, user.ID, err)
+				continue
+			}
+			if !eh.write(c, fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, data)) {
+				return
+			}
+		}
+	}
+}
+
+// write sends a chunk to the client and flushes it, reporting whether the
+// connection is still usable.
+func (eh *EventsHandler) write(c *gin.Context, chunk string) bool {
+	if _, err := c.Writer.WriteString(chunk); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+func (eh *EventsHandler) userFeedIDs(userID int) (map[int]bool, error) {
+	feeds, err := eh.feedService.GetUserFeeds(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool, len(feeds))
+	for _, f := range feeds {
+		ids[f.ID] = true
+	}
+	return ids, nil
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func TestEventsStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("unauthenticated returns 401", func(t *testing.T) {
+		db := newMockDBFeedHandler()
+		handler := NewEventsHandler(events.NewMemoryBus(), services.NewFeedService(db, nil))
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/events", nil)
+
+		handler.Stream(c)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected 401, got %d", w.Code)
+		}
+	})
+
+	t.Run("streams the user's events", func(t *testing.T) {
+		db := newMockDBFeedHandler()
+		db.mockUserFeeds = []database.Feed{{ID: 5}}
+		bus := events.NewMemoryBus()
+		handler := NewEventsHandler(bus, services.NewFeedService(db, nil))
+
+		router := gin.New()
+		router.GET("/api/events", func(c *gin.Context) {
+			c.Set("user", &database.User{ID: 1})
+			handler.Stream(c)
+		})
+		server := httptest.NewServer(router)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL + "/api/events")
+		if err != nil {
+			t.Fatalf("failed to open stream: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("expected text/event-stream, got %q", got)
+		}
+
+		// Read the connect comment so the subscription is known to exist
+		reader := bufio.NewReader(resp.Body)
+		if line, err := reader.ReadString('\n'); err != nil || line != ": connected\n" {
+			t.Fatalf("expected connect comment, got %q (%v)", line, err)
+		}
+
+		bus.Publish(events.Event{Type: events.TypeNewArticles, FeedID: 6, Data: events.NewArticlesData{FeedID: 6, Count: 1}})
+		bus.Publish(events.Event{Type: events.TypeArticleState, UserID: 2, Data: events.ArticleStateData{ArticleID: 99}})
+		bus.Publish(events.Event{Type: events.TypeNewArticles, FeedID: 5, Data: events.NewArticlesData{FeedID: 5, Count: 3}})
+		bus.Publish(events.Event{Type: events.TypeArticleState, UserID: 1, Data: events.ArticleStateData{ArticleID: 7, IsRead: true}})
+
+		want := []string{
+			"",
+			"event: new_articles",
+			`data: {"feed_id":5,"count":3}`,
+			"",
+			"event: article_state",
+			`data: {"article_id":7,"is_read":true,"is_starred":false}`,
+		}
+		for _, w := range want {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read stream: %v", err)
+			}
+			if got := strings.TrimSuffix(line, "\n"); got != w {
+				t.Fatalf("expected line %q, got %q", w, got)
+			}
+		}
+	})
+}
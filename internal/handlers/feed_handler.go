@@ -112,32 +112,122 @@ func (fh *FeedHandler) AddFeed(c *gin.Context) {
 	feed, err := fh.feedService.AddFeedForUser(user.ID, req.URL)
 	if err != nil {
 		log.Printf("Failed to add feed '%s' for user %d: %v", req.URL, user.ID, err)
+		c.JSON(feedErrorStatus(err), services.GetErrorDetails(err))
+		return
+	}
+	middleware.InvalidateCachedUserFeeds(c, user.ID)
+	c.JSON(http.StatusCreated, feed)
+}
+
+// feedErrorStatus maps an error from adding or fetching a feed to its HTTP
+// status code.
+func feedErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidURL):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSSRFBlocked):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInvalidSelector):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrFeedNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrFeedTimeout):
+		return http.StatusRequestTimeout
+	case errors.Is(err, services.ErrInvalidFeedFormat):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrNoScrapedItems):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrNetworkError):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// htmlFeedRequest is the body of the HTML feed endpoints.
+type htmlFeedRequest struct {
+	URL       string                     `json:"url" binding:"required"`
+	Selectors services.HTMLFeedSelectors `json:"selectors"`
+}
+
+// PreviewHTMLFeed shows the items the selectors extract from a page so they
+// can be checked before the feed is added. Nothing is saved.
+func (fh *FeedHandler) PreviewHTMLFeed(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req htmlFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request body could not be parsed."})
+		return
+	}
+
+	feedURL, feedData, err := fh.feedService.PreviewHTMLFeed(req.URL, req.Selectors)
+	if err != nil {
+		log.Printf("Failed to preview HTML feed '%s' for user %d: %v", req.URL, user.ID, err)
+		c.JSON(feedErrorStatus(err), services.GetErrorDetails(err))
+		return
+	}
+
+	items := make([]gin.H, 0, len(feedData.Articles))
+	for _, article := range feedData.Articles {
+		items = append(items, gin.H{
+			"title":        article.Title,
+			"url":          article.Link,
+			"summary":      article.Description,
+			"published_at": article.PublishedAt,
+		})
+	}
 
-		// Get structured error details
-		errorDetails := services.GetErrorDetails(err)
+	c.JSON(http.StatusOK, gin.H{
+		"feed_url":    feedURL,
+		"title":       feedData.Title,
+		"description": feedData.Description,
+		"items":       items,
+	})
+}
+
+// AddHTMLFeed subscribes the user to a feed scraped from a web page that has
+// no RSS or Atom feed of its own.
+func (fh *FeedHandler) AddHTMLFeed(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
 
-		// Map error types to HTTP status codes
-		var statusCode int
+	if err := fh.subscriptionService.CanUserAddFeed(user.ID); err != nil {
 		switch {
-		case errors.Is(err, services.ErrInvalidURL):
-			statusCode = http.StatusBadRequest
-		case errors.Is(err, services.ErrSSRFBlocked):
-			statusCode = http.StatusBadRequest
-		case errors.Is(err, services.ErrFeedNotFound):
-			statusCode = http.StatusNotFound
-		case errors.Is(err, services.ErrFeedTimeout):
-			statusCode = http.StatusRequestTimeout
-		case errors.Is(err, services.ErrInvalidFeedFormat):
-			statusCode = http.StatusUnprocessableEntity
-		case errors.Is(err, services.ErrNetworkError):
-			statusCode = http.StatusBadGateway
-		case errors.Is(err, services.ErrDatabaseError):
-			statusCode = http.StatusInternalServerError
+		case errors.Is(err, services.ErrFeedLimitReached):
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"error":         fmt.Sprintf("You've reached the limit of %d feeds for free users. Upgrade to Pro for unlimited feeds.", services.FreeTrialFeedLimit),
+				"limit_reached": true,
+				"current_limit": services.FreeTrialFeedLimit,
+			})
+		case errors.Is(err, services.ErrTrialExpired):
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"error":         "Your 30-day free trial has expired. Subscribe to continue using GoRead2.",
+				"trial_expired": true,
+			})
 		default:
-			statusCode = http.StatusInternalServerError
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An internal error occurred. Please try again."})
 		}
+		return
+	}
 
-		c.JSON(statusCode, errorDetails)
+	var req htmlFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request body could not be parsed."})
+		return
+	}
+
+	feed, err := fh.feedService.AddHTMLFeedForUser(user.ID, req.URL, req.Selectors)
+	if err != nil {
+		log.Printf("Failed to add HTML feed '%s' for user %d: %v", req.URL, user.ID, err)
+		c.JSON(feedErrorStatus(err), services.GetErrorDetails(err))
 		return
 	}
 	middleware.InvalidateCachedUserFeeds(c, user.ID)
@@ -284,6 +374,43 @@ func (fh *FeedHandler) ToggleStar(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Article starred status toggled"})
 }
 
+// markReadRequest narrows mark-all-read to some feeds and/or older articles.
+// Every field is optional; an empty body marks everything read.
+type markReadRequest struct {
+	FeedID          int        `json:"feed_id"`
+	FeedIDs         []int      `json:"feed_ids"`
+	PublishedBefore *time.Time `json:"published_before"`
+	OlderThanDays   int        `json:"older_than_days"`
+}
+
+// scope validates the request and converts it to a database scope.
+func (r *markReadRequest) scope(now time.Time) (database.MarkReadScope, error) {
+	var scope database.MarkReadScope
+	if r.FeedID != 0 {
+		scope.FeedIDs = append(scope.FeedIDs, r.FeedID)
+	}
+	scope.FeedIDs = append(scope.FeedIDs, r.FeedIDs...)
+	for _, id := range scope.FeedIDs {
+		if id <= 0 {
+			return scope, errors.New("feed IDs must be positive integers")
+		}
+	}
+
+	switch {
+	case r.PublishedBefore != nil && r.OlderThanDays != 0:
+		return scope, errors.New("use either published_before or older_than_days, not both")
+	case r.OlderThanDays < 0:
+		return scope, errors.New("older_than_days must be a positive number of days")
+	case r.OlderThanDays > 0:
+		scope.PublishedBefore = now.AddDate(0, 0, -r.OlderThanDays)
+	case r.PublishedBefore != nil:
+		scope.PublishedBefore = *r.PublishedBefore
+	}
+	return scope, nil
+}
+
+// MarkAllRead marks the user's unread articles as read, optionally limited to
+// specific feeds and to articles published before a cutoff.
 func (fh *FeedHandler) MarkAllRead(c *gin.Context) {
 	user, exists := auth.GetUserFromContext(c)
 	if !exists {
@@ -291,15 +418,63 @@ func (fh *FeedHandler) MarkAllRead(c *gin.Context) {
 		return
 	}
 
-	count, err := fh.feedService.MarkAllArticlesRead(user.ID)
+	var req markReadRequest
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The request body is not valid JSON. Dates must use RFC 3339 format."})
+			return
+		}
+	}
+	scope, err := req.scope(time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mark-as-read request: " + err.Error() + "."})
+		return
+	}
+
+	result, err := fh.feedService.MarkArticlesRead(user.ID, scope)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark articles as read. Please try again."})
 		return
 	}
 
+	message := "All articles marked as read"
+	if len(scope.FeedIDs) > 0 || !scope.PublishedBefore.IsZero() {
+		message = "Articles marked as read"
+	}
+	response := gin.H{
+		"message":        message,
+		"articles_count": result.Count,
+	}
+	if result.UndoID != "" {
+		response["operation_id"] = result.UndoID
+		response["undo_expires_at"] = result.UndoExpiresAt
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Undo reverses a recent bulk status change such as mark-all-read, using the
+// operation_id returned by that request.
+func (fh *FeedHandler) Undo(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	restored, err := fh.feedService.UndoOperation(user.ID, c.Param("operationID"))
+	if err != nil {
+		if errors.Is(err, services.ErrUndoNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This change can no longer be undone."})
+			return
+		}
+		log.Printf("Failed to undo operation for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to undo the change. Please try again."})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":        "All articles marked as read",
-		"articles_count": count,
+		"message":        "Change undone",
+		"articles_count": restored,
 	})
 }
 
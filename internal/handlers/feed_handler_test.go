@@ -202,6 +202,135 @@ func (m *mockDBFeedHandler) GetAccountStats(userID int) (map[string]interface{},
 }
 func (m *mockDBFeedHandler) UpdateFeedLastFetch(int, time.Time) error { return nil }
 func (m *mockDBFeedHandler) Close() error                             { return nil }
+func (m *mockDBFeedHandler) GetArticlesByIDs(int, []int) (map[int]database.Article, error) {
+	return nil, nil
+}
+func (m mockDBFeedHandler) CreateRefreshToken(*database.RefreshToken) error { return nil }
+func (m mockDBFeedHandler) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBFeedHandler) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBFeedHandler) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBFeedHandler) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBFeedHandler) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) GetUserIdentities(int) ([]database.UserIdentity, error)      { return nil, nil }
+func (m *mockDBFeedHandler) CreateLoginToken(*database.LoginToken) error                 { return nil }
+func (m *mockDBFeedHandler) ConsumeLoginToken(string) (*database.LoginToken, error)      { return nil, nil }
+func (m *mockDBFeedHandler) DeleteExpiredLoginTokens() error                             { return nil }
+func (m *mockDBFeedHandler) ForEachUserIdentity(func(database.UserIdentity) error) error { return nil }
+func (m *mockDBFeedHandler) DeleteUser(userID int) error                                 { return nil }
+func (m *mockDBFeedHandler) GetUserArticleHistory(userID int) ([]database.Article, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) GetUserSessions(userID int) ([]database.Session, error)       { return nil, nil }
+func (m *mockDBFeedHandler) ForEachUser(fn func(database.User) error) error               { return nil }
+func (m *mockDBFeedHandler) ForEachArticle(fn func(database.Article) error) error         { return nil }
+func (m *mockDBFeedHandler) ForEachUserArticle(fn func(database.UserArticle) error) error { return nil }
+func (m *mockDBFeedHandler) ForEachSession(fn func(database.Session) error) error         { return nil }
+func (m *mockDBFeedHandler) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) GetFeedCategories() (map[int]string, error)        { return nil, nil }
+func (m *mockDBFeedHandler) SetFeedCategory(feedID int, category string) error { return nil }
+func (m *mockDBFeedHandler) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBFeedHandler) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBFeedHandler) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBFeedHandler) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBFeedHandler) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBFeedHandler) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBFeedHandler) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBFeedHandler) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBFeedHandler) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBFeedHandler) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBFeedHandler) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBFeedHandler) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBFeedHandler) DeleteWebhookDeliveriesBefore(time.Time) (int, error) { return 0, nil }
+func (m *mockDBFeedHandler) UpdateUserDigestPreferences(int, database.DigestPreferences) error {
+	return nil
+}
+func (m *mockDBFeedHandler) GetDigestUsers() ([]database.User, error) { return []database.User{}, nil }
+func (m *mockDBFeedHandler) SetUserDigestSentAt(int, time.Time) error { return nil }
+func (m *mockDBFeedHandler) GetUserStarredCountsByFeed(int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+func (m *mockDBFeedHandler) UpdateUserArticleStatuses(_ int, updates []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	if m.shouldFailMarkRead {
+		return nil, errors.New("database error")
+	}
+	result := &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}
+	for _, u := range updates {
+		if m.mockArticle != nil && u.ArticleID == m.mockArticle.ID {
+			result.Found[u.ArticleID] = m.mockArticle.FeedID
+		}
+	}
+	return result, nil
+}
+func (m *mockDBFeedHandler) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBFeedHandler) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBFeedHandler) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBFeedHandler) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBFeedHandler) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBFeedHandler) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBFeedHandler) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBFeedHandler) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBFeedHandler) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBFeedHandler) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBFeedHandler) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBFeedHandler) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBFeedHandler) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBFeedHandler) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBFeedHandler) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBFeedHandler) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBFeedHandler) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBFeedHandler) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBFeedHandler) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBFeedHandler) CreateAnnotation(*database.Annotation) error          { return nil }
+func (m *mockDBFeedHandler) GetAnnotation(int, int) (*database.Annotation, error) { return nil, nil }
+func (m *mockDBFeedHandler) GetArticleAnnotations(int, int) ([]database.Annotation, error) {
+	return nil, nil
+}
+func (m *mockDBFeedHandler) GetUserAnnotations(int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBFeedHandler) UpdateAnnotation(*database.Annotation) error           { return nil }
+func (m *mockDBFeedHandler) DeleteAnnotation(int, int) error                       { return nil }
 func (m *mockDBFeedHandler) UpdateFeedCacheHeaders(feedID int, etag, lastModified string) error {
 	return nil
 }
@@ -971,6 +1100,43 @@ func TestMarkAllRead(t *testing.T) {
 	})
 }
 
+func TestMarkAllRead_Scope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"single feed", `{"feed_id": 3}`, http.StatusOK},
+		{"feed set with cutoff", `{"feed_ids": [1, 2], "published_before": "2026-01-02T15:04:05Z"}`, http.StatusOK},
+		{"older than", `{"older_than_days": 7}`, http.StatusOK},
+		{"both cutoffs", `{"published_before": "2026-01-02T15:04:05Z", "older_than_days": 7}`, http.StatusBadRequest},
+		{"negative days", `{"older_than_days": -1}`, http.StatusBadRequest},
+		{"invalid feed ID", `{"feed_ids": [0]}`, http.StatusBadRequest},
+		{"malformed date", `{"published_before": "yesterday"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDBFeedHandler()
+			handler := NewFeedHandler(services.NewFeedService(db, nil), nil, nil, db)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/articles/mark-all-read", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user", &database.User{ID: 1})
+
+			handler.MarkAllRead(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestCleanupOrphanedUserArticles(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -2552,3 +2718,76 @@ func TestDebugArticleByURLFound(t *testing.T) {
 		}
 	})
 }
+
+func TestUndo_UnknownOperation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newMockDBFeedHandler()
+	feedService := services.NewFeedService(db, nil)
+	handler := NewFeedHandler(feedService, nil, nil, db)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/api/undo/abc123", nil)
+	c.Params = gin.Params{{Key: "operationID", Value: "abc123"}}
+	c.Set("user", &database.User{ID: 1})
+
+	handler.Undo(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHTMLFeedEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		user       *database.User
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unauthenticated returns 401", nil, `{"url":"https://example.com","selectors":{"item":"li"}}`, http.StatusUnauthorized, ""},
+		{"missing url returns 400", testUser, `{"selectors":{"item":"li"}}`, http.StatusBadRequest, ""},
+		{"missing item selector returns 400", testUser, `{"url":"https://example.com","selectors":{"title":"h2"}}`, http.StatusBadRequest, services.ErrorCodeInvalidSelector},
+		{"unparseable selector returns 400", testUser, `{"url":"https://example.com","selectors":{"item":"li["}}`, http.StatusBadRequest, services.ErrorCodeInvalidSelector},
+		{"SSRF-blocked page returns 400", testUser, `{"url":"http://127.0.0.1/","selectors":{"item":"li"}}`, http.StatusBadRequest, services.ErrorCodeSSRFBlocked},
+	}
+
+	endpoints := map[string]func(*FeedHandler, *gin.Context){
+		"preview": (*FeedHandler).PreviewHTMLFeed,
+		"add":     (*FeedHandler).AddHTMLFeed,
+	}
+
+	for endpoint, call := range endpoints {
+		for _, tt := range tests {
+			t.Run(endpoint+"/"+tt.name, func(t *testing.T) {
+				handler := newFeedHandlerWithSubscription(newMockDBFeedHandler())
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = httptest.NewRequest("POST", "/api/feeds/html", bytes.NewReader([]byte(tt.body)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				if tt.user != nil {
+					c.Set("user", tt.user)
+				}
+
+				call(handler, c)
+
+				if w.Code != tt.wantStatus {
+					t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+				}
+				if tt.wantCode != "" {
+					var resp services.ErrorDetails
+					_ = json.Unmarshal(w.Body.Bytes(), &resp)
+					if resp.ErrorCode != tt.wantCode {
+						t.Errorf("expected error code %q, got %q", tt.wantCode, resp.ErrorCode)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+// maxImportFileSize matches the request body limit set for /api/imports.
+const maxImportFileSize = 32 * 1024 * 1024
+
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+func NewImportHandler(importService *services.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// StartImport accepts another reader's export as the multipart "file" field,
+// with its "format", and starts importing it in the background.
+func (ih *ImportHandler) StartImport(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No export file was included in the request."})
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file exceeds the maximum allowed size of 32 MB."})
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "The export file could not be read."})
+		return
+	}
+
+	job, err := ih.importService.StartImport(user.ID, c.PostForm("format"), data)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidImport):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "formats": services.ImportFormats()})
+		case errors.Is(err, services.ErrImportInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": "An import is already running. Wait for it to finish before starting another."})
+		default:
+			log.Printf("Failed to start import for user %d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start the import. Please try again."})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImport reports an import's progress.
+func (ih *ImportHandler) GetImport(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	job, err := ih.importService.GetJob(user.ID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
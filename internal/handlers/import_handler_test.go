@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func newImportRequest(t *testing.T, format, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("format", format); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := w.CreateFormFile("file", "export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write([]byte(content))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "/api/imports", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func newImportHandlerForTest() *ImportHandler {
+	db := newMockDBFeedHandler()
+	feedService := services.NewFeedService(db, nil)
+	return NewImportHandler(services.NewImportService(db, feedService, services.NewSubscriptionService(db)))
+}
+
+func TestImportHandlerStartImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com"}
+	export := `[{"url": "https://blog.example.com/1", "status": "read", "feed": {"feed_url": "https://blog.example.com/feed"}}]`
+
+	tests := []struct {
+		name       string
+		user       *database.User
+		format     string
+		content    string
+		wantStatus int
+	}{
+		{"unauthenticated returns 401", nil, services.ImportFormatMiniflux, export, http.StatusUnauthorized},
+		{"unknown format returns 400", testUser, "netnewswire", export, http.StatusBadRequest},
+		{"unreadable export returns 400", testUser, services.ImportFormatMiniflux, "not json", http.StatusBadRequest},
+		{"valid export starts a job", testUser, services.ImportFormatMiniflux, export, http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newImportHandlerForTest()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = newImportRequest(t, tt.format, tt.content)
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.StartImport(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				return
+			}
+			var job services.ImportJob
+			if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if job.ID == "" || job.Total != 1 || job.Format != services.ImportFormatMiniflux {
+				t.Errorf("unexpected job %+v", job)
+			}
+		})
+	}
+}
+
+func TestImportHandlerGetImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := newImportHandlerForTest()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = newImportRequest(t, services.ImportFormatMiniflux,
+		`[{"url": "https://blog.example.com/1", "feed": {"feed_url": "https://blog.example.com/feed"}}]`)
+	c.Set("user", &database.User{ID: 1})
+	handler.StartImport(c)
+	var started services.ImportJob
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	for _, tc := range []struct {
+		userID     int
+		wantStatus int
+	}{{1, http.StatusOK}, {2, http.StatusNotFound}} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/api/imports/"+started.ID, nil)
+		c.Params = gin.Params{{Key: "id", Value: started.ID}}
+		c.Set("user", &database.User{ID: tc.userID})
+
+		handler.GetImport(c)
+
+		if w.Code != tc.wantStatus {
+			t.Errorf("user %d: expected %d, got %d", tc.userID, tc.wantStatus, w.Code)
+		}
+	}
+}
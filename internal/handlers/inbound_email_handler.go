@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type InboundEmailHandler struct {
+	inboundService      *services.InboundEmailService
+	subscriptionService *services.SubscriptionService
+	secret              string
+}
+
+// NewInboundEmailHandler creates the handler. Receive rejects every request
+// unless secret is set.
+func NewInboundEmailHandler(inboundService *services.InboundEmailService, subscriptionService *services.SubscriptionService, secret string) *InboundEmailHandler {
+	return &InboundEmailHandler{
+		inboundService:      inboundService,
+		subscriptionService: subscriptionService,
+		secret:              secret,
+	}
+}
+
+// ListAddresses returns the user's inbound addresses and whether new ones can
+// be created on this server.
+func (ih *InboundEmailHandler) ListAddresses(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	addresses, err := ih.inboundService.ListAddresses(user.ID)
+	if err != nil {
+		log.Printf("Failed to list inbound addresses for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve your newsletter addresses. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"addresses": addresses,
+		"enabled":   ih.inboundService.Enabled(),
+	})
+}
+
+// CreateAddress creates an inbound address and the feed its mail goes to. The
+// feed counts towards the user's feed limit like any other subscription.
+func (ih *InboundEmailHandler) CreateAddress(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request body could not be parsed."})
+		return
+	}
+
+	if err := ih.subscriptionService.CanUserAddFeed(user.ID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrFeedLimitReached):
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"error":         fmt.Sprintf("You've reached the limit of %d feeds for free users. Upgrade to Pro for unlimited feeds.", services.FreeTrialFeedLimit),
+				"limit_reached": true,
+				"current_limit": services.FreeTrialFeedLimit,
+			})
+		case errors.Is(err, services.ErrTrialExpired):
+			c.JSON(http.StatusPaymentRequired, gin.H{
+				"error":         "Your 30-day free trial has expired. Subscribe to continue using GoRead2.",
+				"trial_expired": true,
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "An internal error occurred. Please try again."})
+		}
+		return
+	}
+
+	address, err := ih.inboundService.CreateAddress(user.ID, req.Title)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInboundEmailDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Newsletter addresses aren't available on this server."})
+		case errors.Is(err, services.ErrInboundAddressLimitReached):
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You can have at most %d newsletter addresses.", services.MaxInboundAddressesPerUser)})
+		case errors.Is(err, services.ErrInvalidInboundAddress):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("Failed to create inbound address for user %d: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the newsletter address. Please try again."})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// DeleteAddress deletes an inbound address and unsubscribes the user from its
+// feed. Mail sent to the address afterwards is rejected.
+func (ih *InboundEmailHandler) DeleteAddress(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	addressID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The address ID is not valid."})
+		return
+	}
+
+	if err := ih.inboundService.DeleteAddress(user.ID, addressID); err != nil {
+		if errors.Is(err, services.ErrInboundAddressNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "The requested newsletter address could not be found."})
+			return
+		}
+		log.Printf("Failed to delete inbound address %d for user %d: %v", addressID, user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the newsletter address. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Newsletter address deleted successfully"})
+}
+
+// Receive accepts a message from the mail provider for POST /inbound/email.
+// The body is either the raw RFC 822 message or a form with the message in
+// an "email" field (SendGrid Inbound Parse with raw MIME) or "body-mime"
+// field (Mailgun routes). Requests authenticate with the inbound secret as
+// the HTTP Basic password.
+//
+// A message addressed to no known inbound address gets 406, which Mailgun
+// treats as a permanent rejection rather than retrying.
+func (ih *InboundEmailHandler) Receive(c *gin.Context) {
+	_, password, ok := c.Request.BasicAuth()
+	if ih.secret == "" || !ok || subtle.ConstantTimeCompare([]byte(password), []byte(ih.secret)) != 1 {
+		c.Header("WWW-Authenticate", `Basic realm="inbound-email"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	raw, envelopeTo, err := readInboundEmail(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Message too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	delivered, err := ih.inboundService.Receive(raw, envelopeTo)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInboundEmail):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrUnknownInboundRecipient):
+			c.JSON(http.StatusNotAcceptable, gin.H{"error": "No newsletter address matches the recipients"})
+		case errors.Is(err, services.ErrInboundEmailDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Inbound email is not configured"})
+		default:
+			log.Printf("Failed to store inbound email: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store the message"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivered": delivered})
+}
+
+// readInboundEmail returns the raw message in the request and any envelope
+// recipients the provider sent alongside it.
+func readInboundEmail(c *gin.Context) ([]byte, []string, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "multipart/form-data" && mediaType != "application/x-www-form-urlencoded" {
+		raw, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(raw) == 0 {
+			return nil, nil, errors.New("empty message")
+		}
+		return raw, nil, nil
+	}
+
+	if mediaType == "multipart/form-data" {
+		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+			return nil, nil, err
+		}
+	} else if err := c.Request.ParseForm(); err != nil {
+		return nil, nil, err
+	}
+
+	raw := c.Request.PostFormValue("email")
+	if raw == "" {
+		raw = c.Request.PostFormValue("body-mime")
+	}
+	if raw == "" {
+		return nil, nil, errors.New("the form has no email or body-mime field")
+	}
+
+	var envelopeTo []string
+	if envelope := c.Request.PostFormValue("envelope"); envelope != "" {
+		var parsed struct {
+			To []string `json:"to"`
+		}
+		if err := json.Unmarshal([]byte(envelope), &parsed); err == nil {
+			envelopeTo = append(envelopeTo, parsed.To...)
+		}
+	}
+	if recipient := c.Request.PostFormValue("recipient"); recipient != "" {
+		for _, r := range strings.Split(recipient, ",") {
+			envelopeTo = append(envelopeTo, strings.TrimSpace(r))
+		}
+	}
+	return []byte(raw), envelopeTo, nil
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+const testInboundMessage = "From: news@letters.example.com\r\nTo: abc@in.example.com\r\nSubject: Hi\r\n\r\nHello\r\n"
+
+func newTestInboundEmailHandler(domain, secret string) *InboundEmailHandler {
+	db := newMockDBFeedHandler()
+	inbound := services.NewInboundEmailService(db, services.NewFeedService(db, nil), domain)
+	return NewInboundEmailHandler(inbound, services.NewSubscriptionService(db), secret)
+}
+
+func TestInboundEmailReceive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	form := func(field, value string) (string, *bytes.Buffer) {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		_ = w.WriteField(field, value)
+		_ = w.Close()
+		return w.FormDataContentType(), body
+	}
+
+	tests := []struct {
+		name        string
+		secret      string
+		password    string
+		contentType string
+		body        *bytes.Buffer
+		wantStatus  int
+	}{
+		{"no secret configured", "", "", "message/rfc822", bytes.NewBufferString(testInboundMessage), http.StatusUnauthorized},
+		{"wrong password", "s3cret", "guess", "message/rfc822", bytes.NewBufferString(testInboundMessage), http.StatusUnauthorized},
+		{"empty body", "s3cret", "s3cret", "message/rfc822", &bytes.Buffer{}, http.StatusBadRequest},
+		{"malformed message", "s3cret", "s3cret", "message/rfc822", bytes.NewBufferString("garbage"), http.StatusBadRequest},
+		{"raw message to an unknown address", "s3cret", "s3cret", "message/rfc822", bytes.NewBufferString(testInboundMessage), http.StatusNotAcceptable},
+		{"form without a message", "s3cret", "s3cret", "", nil, http.StatusBadRequest},
+		{"sendgrid raw form", "s3cret", "s3cret", "email", bytes.NewBufferString(testInboundMessage), http.StatusNotAcceptable},
+		{"mailgun mime form", "s3cret", "s3cret", "body-mime", bytes.NewBufferString(testInboundMessage), http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestInboundEmailHandler("in.example.com", tt.secret)
+
+			contentType, body := tt.contentType, tt.body
+			switch contentType {
+			case "email", "body-mime":
+				contentType, body = form(contentType, tt.body.String())
+			case "":
+				contentType, body = form("subject", "Hi")
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/inbound/email", body)
+			c.Request.Header.Set("Content-Type", contentType)
+			if tt.password != "" {
+				c.Request.SetBasicAuth("inbound", tt.password)
+			}
+
+			handler.Receive(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestInboundEmailCreateAddress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		domain     string
+		user       *database.User
+		wantStatus int
+	}{
+		{"unauthenticated returns 401", "in.example.com", nil, http.StatusUnauthorized},
+		{"not configured returns 503", "", &database.User{ID: 1}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestInboundEmailHandler(tt.domain, "s3cret")
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/inbound-addresses", strings.NewReader(`{"title":"Letters"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.CreateAddress(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
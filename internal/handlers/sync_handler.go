@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type SyncHandler struct {
+	feedService *services.FeedService
+}
+
+func NewSyncHandler(feedService *services.FeedService) *SyncHandler {
+	return &SyncHandler{feedService: feedService}
+}
+
+// Changes returns new articles, state changes and removed feeds since the
+// sync token in ?since. Without a token it starts a new sync.
+func (sh *SyncHandler) Changes(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	limit := services.SyncDefaultLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil && parsed > 0 && parsed <= services.SyncMaxLimit {
+			limit = parsed
+		}
+	}
+
+	changes, err := sh.feedService.GetSyncChanges(user.ID, c.Query("since"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSyncToken) {
+			c.JSON(http.StatusGone, gin.H{"error": "The sync token is no longer valid. Start a new sync without a token."})
+			return
+		}
+		log.Printf("Failed to get sync changes for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+type syncActionsRequest struct {
+	Actions []services.SyncAction `json:"actions" binding:"required"`
+}
+
+// ApplyActions applies a batch of queued client actions and reports the
+// outcome of each.
+func (sh *SyncHandler) ApplyActions(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req syncActionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a list of actions."})
+		return
+	}
+	if len(req.Actions) > services.MaxSyncActions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Send at most %d actions per request.", services.MaxSyncActions)})
+		return
+	}
+
+	results, err := sh.feedService.ApplySyncActions(user.ID, req.Actions)
+	if err != nil {
+		log.Printf("Failed to apply sync actions for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply actions. Please try again."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func newSyncHandler(db *mockDBFeedHandler) *SyncHandler {
+	rateLimiter := services.NewDomainRateLimiter(services.RateLimiterConfig{
+		RequestsPerMinute: 60,
+		BurstSize:         10,
+	})
+	return NewSyncHandler(services.NewFeedService(db, rateLimiter))
+}
+
+func TestSyncChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		query      string
+		user       *database.User
+		wantStatus int
+	}{
+		{"unauthenticated returns 401", "", nil, http.StatusUnauthorized},
+		{"no token starts a sync", "", testUser, http.StatusOK},
+		{"invalid token returns 410", "?since=bogus", testUser, http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newSyncHandler(newMockDBFeedHandler())
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/api/sync"+tt.query, nil)
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.Changes(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp services.SyncChanges
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !resp.Reset || resp.Token == "" {
+				t.Errorf("expected a reset with a token, got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestSyncApplyActions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tooMany := make([]string, services.MaxSyncActions+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf(`{"id":"%d","type":"read","article_id":1,"client_time":"2026-01-01T00:00:00Z"}`, i)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"missing actions returns 400", `{}`, http.StatusBadRequest},
+		{"too many actions returns 400", `{"actions":[` + strings.Join(tooMany, ",") + `]}`, http.StatusBadRequest},
+		{"valid batch returns results", `{"actions":[` + tooMany[0] + `]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDBFeedHandler()
+			db.mockArticle = &database.Article{ID: 1, FeedID: 1}
+			handler := newSyncHandler(db)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/sync/actions", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user", testUser)
+
+			handler.ApplyActions(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Results []services.SyncActionResult `json:"results"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Results) != 1 || resp.Results[0].Status != services.SyncActionApplied {
+				t.Errorf("expected one applied result, got %+v", resp.Results)
+			}
+		})
+	}
+}
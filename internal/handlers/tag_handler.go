@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type TagHandler struct {
+	tagService *services.TagService
+}
+
+func NewTagHandler(tagService *services.TagService) *TagHandler {
+	return &TagHandler{tagService: tagService}
+}
+
+type tagNameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// tagArticleRequest identifies the tag to apply either by ID or by name. A
+// name that doesn't match an existing tag creates it.
+type tagArticleRequest struct {
+	TagID int    `json:"tag_id"`
+	Name  string `json:"name"`
+}
+
+func (th *TagHandler) ListTags(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	tags, err := th.tagService.ListTags(user.ID)
+	if err != nil {
+		th.respondError(c, err, "Failed to retrieve your tags. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+func (th *TagHandler) CreateTag(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req tagNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a tag name."})
+		return
+	}
+
+	tag, err := th.tagService.CreateTag(user.ID, req.Name)
+	if err != nil {
+		th.respondError(c, err, "Failed to create the tag. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+func (th *TagHandler) RenameTag(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tag ID is not valid."})
+		return
+	}
+
+	var req tagNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a tag name."})
+		return
+	}
+
+	tag, err := th.tagService.RenameTag(user.ID, tagID, req.Name)
+	if err != nil {
+		th.respondError(c, err, "Failed to rename the tag. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (th *TagHandler) DeleteTag(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tag ID is not valid."})
+		return
+	}
+
+	if err := th.tagService.DeleteTag(user.ID, tagID); err != nil {
+		th.respondError(c, err, "Failed to delete the tag. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
+
+// GetTagArticles lists the articles carrying a tag using the same limit and
+// cursor parameters as the feed article listings.
+func (th *TagHandler) GetTagArticles(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tag ID is not valid."})
+		return
+	}
+
+	limit, cursor, _ := parseArticlePaginationParams(c)
+	result, err := th.tagService.GetTagArticles(user.ID, tagID, limit, cursor)
+	if err != nil {
+		th.respondError(c, err, "Failed to retrieve articles for this tag. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"articles":    result.Articles,
+		"next_cursor": result.NextCursor,
+	})
+}
+
+func (th *TagHandler) TagArticle(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return
+	}
+
+	var req tagArticleRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.TagID == 0 && req.Name == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a tag_id or name."})
+		return
+	}
+
+	var tag *database.Tag
+	if req.TagID != 0 {
+		tag, err = th.tagService.TagArticle(user.ID, articleID, req.TagID)
+	} else {
+		tag, err = th.tagService.TagArticleByName(user.ID, articleID, req.Name)
+	}
+	if err != nil {
+		th.respondError(c, err, "Failed to tag the article. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+func (th *TagHandler) UntagArticle(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	articleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The article ID is not valid."})
+		return
+	}
+	tagID, err := strconv.Atoi(c.Param("tagId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tag ID is not valid."})
+		return
+	}
+
+	if err := th.tagService.UntagArticle(user.ID, articleID, tagID); err != nil {
+		th.respondError(c, err, "Failed to remove the tag. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag removed successfully"})
+}
+
+// respondError maps tag service errors to HTTP responses.
+func (th *TagHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested tag could not be found."})
+	case errors.Is(err, services.ErrArticleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested article could not be found."})
+	case errors.Is(err, services.ErrTagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "You already have a tag with that name."})
+	case errors.Is(err, services.ErrInvalidTag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Tag request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func TestTagArticle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		articleID  string
+		body       string
+		user       *database.User
+		article    *database.Article
+		wantStatus int
+	}{
+		{"unauthenticated", "1", `{"name":"x"}`, nil, nil, http.StatusUnauthorized},
+		{"invalid article ID", "abc", `{"name":"x"}`, testUser, nil, http.StatusBadRequest},
+		{"missing tag", "1", `{}`, testUser, nil, http.StatusBadRequest},
+		{"blank name", "1", `{"name":"   "}`, testUser, &database.Article{ID: 1}, http.StatusBadRequest},
+		{"unknown article", "1", `{"name":"to-share"}`, testUser, nil, http.StatusNotFound},
+		{"unknown tag", "1", `{"tag_id":7}`, testUser, &database.Article{ID: 1}, http.StatusNotFound},
+		{"tag by name", "1", `{"name":"to-share"}`, testUser, &database.Article{ID: 1}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newMockDBFeedHandler()
+			db.mockArticle = tt.article
+			handler := NewTagHandler(services.NewTagService(db))
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/articles/"+tt.articleID+"/tags", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: tt.articleID}}
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.TagArticle(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTagArticles_UnknownTag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := NewTagHandler(services.NewTagService(newMockDBFeedHandler()))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/api/tags/5/articles", nil)
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	c.Set("user", &database.User{ID: 1})
+
+	handler.GetTagArticles(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
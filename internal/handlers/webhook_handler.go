@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+type webhookRequest struct {
+	URL      string   `json:"url" binding:"required"`
+	Secret   string   `json:"secret"`
+	FeedID   int      `json:"feed_id"`
+	Keywords []string `json:"keywords"`
+	Enabled  *bool    `json:"enabled"`
+}
+
+func (r webhookRequest) input() services.WebhookInput {
+	return services.WebhookInput{URL: r.URL, Secret: r.Secret, FeedID: r.FeedID, Keywords: r.Keywords, Enabled: r.Enabled}
+}
+
+// createdWebhook is the create response: the only time the secret is returned.
+type createdWebhook struct {
+	database.Webhook
+	Secret string `json:"secret"`
+}
+
+func (wh *WebhookHandler) ListWebhooks(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	webhooks, err := wh.webhookService.ListWebhooks(user.ID)
+	if err != nil {
+		wh.respondError(c, err, "Failed to retrieve your webhooks. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks})
+}
+
+func (wh *WebhookHandler) CreateWebhook(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a webhook url."})
+		return
+	}
+
+	webhook, err := wh.webhookService.CreateWebhook(user.ID, req.input())
+	if err != nil {
+		wh.respondError(c, err, "Failed to create the webhook. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhook{Webhook: *webhook, Secret: webhook.Secret})
+}
+
+// UpdateWebhook replaces the webhook's settings. Omitting secret keeps the
+// current one and omitting enabled keeps the current state.
+func (wh *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The webhook ID is not valid."})
+		return
+	}
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The request must include a webhook url."})
+		return
+	}
+
+	webhook, err := wh.webhookService.UpdateWebhook(user.ID, webhookID, req.input())
+	if err != nil {
+		wh.respondError(c, err, "Failed to update the webhook. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (wh *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The webhook ID is not valid."})
+		return
+	}
+
+	if err := wh.webhookService.DeleteWebhook(user.ID, webhookID); err != nil {
+		wh.respondError(c, err, "Failed to delete the webhook. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// SendTestEvent posts a webhook.test event to the webhook and reports how the
+// target responded.
+func (wh *WebhookHandler) SendTestEvent(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The webhook ID is not valid."})
+		return
+	}
+
+	delivery, err := wh.webhookService.SendTestEvent(c.Request.Context(), user.ID, webhookID)
+	if err != nil {
+		wh.respondError(c, err, "Failed to send the test event. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (wh *WebhookHandler) GetDeliveries(c *gin.Context) {
+	user, exists := auth.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "You must be signed in to access this resource."})
+		return
+	}
+
+	webhookID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The webhook ID is not valid."})
+		return
+	}
+
+	deliveries, err := wh.webhookService.GetDeliveries(user.ID, webhookID)
+	if err != nil {
+		wh.respondError(c, err, "Failed to retrieve the delivery history. Please try again.")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// respondError maps webhook service errors to HTTP responses.
+func (wh *WebhookHandler) respondError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "The requested webhook could not be found."})
+	case errors.Is(err, services.ErrWebhookLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("You can have at most %d webhooks.", services.MaxWebhooksPerUser)})
+	case errors.Is(err, services.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Webhook request failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/services"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testUser := &database.User{ID: 1, Email: "test@example.com", Name: "Test User"}
+
+	tests := []struct {
+		name       string
+		body       string
+		user       *database.User
+		wantStatus int
+	}{
+		{"unauthenticated returns 401", `{"url":"https://hooks.example.com/x"}`, nil, http.StatusUnauthorized},
+		{"missing url returns 400", `{}`, testUser, http.StatusBadRequest},
+		{"private address returns 400", `{"url":"http://10.0.0.5/hook"}`, testUser, http.StatusBadRequest},
+		{"valid webhook returns 201", `{"url":"https://hooks.example.com/x","keywords":["go"]}`, testUser, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWebhookHandler(services.NewWebhookService(newMockDBFeedHandler()))
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("POST", "/api/webhooks", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			if tt.user != nil {
+				c.Set("user", tt.user)
+			}
+
+			handler.CreateWebhook(c)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+			var resp map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if secret, _ := resp["secret"].(string); !strings.HasPrefix(secret, "whsec_") {
+				t.Errorf("expected the generated secret in the create response, got %v", resp)
+			}
+		})
+	}
+}
+
+func TestWebhookNotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewWebhookHandler(services.NewWebhookService(newMockDBFeedHandler()))
+
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		handle  gin.HandlerFunc
+		wantErr int
+	}{
+		{"update", "PUT", `{"url":"https://hooks.example.com/x"}`, handler.UpdateWebhook, http.StatusNotFound},
+		{"delete", "DELETE", "", handler.DeleteWebhook, http.StatusNotFound},
+		{"test event", "POST", "", handler.SendTestEvent, http.StatusNotFound},
+		{"deliveries", "GET", "", handler.GetDeliveries, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(tt.method, "/api/webhooks/42", strings.NewReader(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: "42"}}
+			c.Set("user", &database.User{ID: 1})
+
+			tt.handle(c)
+
+			if w.Code != tt.wantErr {
+				t.Errorf("expected %d, got %d: %s", tt.wantErr, w.Code, w.Body.String())
+			}
+		})
+	}
+}
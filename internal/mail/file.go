@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// FileMailer writes each message to a .eml file in a directory instead of
+// sending it, for development and testing.
+type FileMailer struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+// NewFileMailer creates dir if needed and returns a mailer writing into it.
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	if from == "" {
+		from = "GoRead2 <noreply@localhost>"
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now()
+	data, err := build(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%03d-%s.eml", now.Format("20060102-150405"), m.seq.Add(1)%1000,
+		unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
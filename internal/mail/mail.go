@@ -0,0 +1,105 @@
+// Package mail sends email through a pluggable Mailer: SMTPMailer for real
+// delivery and FileMailer for development.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// ErrInvalidHeader is returned for addresses or subjects containing line
+// breaks, which could otherwise inject extra headers.
+var ErrInvalidHeader = errors.New("invalid mail header")
+
+// Message is an email with plain-text and HTML alternatives. Either body may
+// be empty, but not both.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// build renders msg as an RFC 5322 message from the given sender.
+func build(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, h := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(h, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+	if msg.To == "" {
+		return nil, fmt.Errorf("%w: missing recipient", ErrInvalidHeader)
+	}
+	if msg.Text == "" && msg.HTML == "" {
+		return nil, errors.New("message has no body")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	parts := [][2]string{}
+	if msg.Text != "" {
+		parts = append(parts, [2]string{"text/plain", msg.Text})
+	}
+	if msg.HTML != "" {
+		parts = append(parts, [2]string{"text/html", msg.HTML})
+	}
+
+	if len(parts) == 1 {
+		if err := writePart(&buf, parts[0][0], parts[0][1]); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, p := range parts {
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		if err := writePart(&buf, p[0], p[1]); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
+
+// writePart writes headers and a quoted-printable body for one content type.
+func writePart(buf *bytes.Buffer, contentType, body string) error {
+	fmt.Fprintf(buf, "Content-Type: %s; charset=utf-8\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(buf)
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func newBoundary() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "goread-" + hex.EncodeToString(b), nil
+}
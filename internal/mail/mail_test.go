@@ -0,0 +1,69 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	data, err := build("GoRead2 <digest@example.com>", Message{
+		To:      "reader@example.com",
+		Subject: "Your digest — 3 articles",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("ReadMessage: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your digest — 3 articles" {
+		t.Errorf("unexpected subject %q (%v)", subject, err)
+	}
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected multipart/alternative, got %q", msg.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "plain body") || !strings.Contains(string(body), "<p>html body</p>") {
+		t.Errorf("expected both alternatives in body:\n%s", body)
+	}
+}
+
+func TestBuild_RejectsHeaderInjection(t *testing.T) {
+	_, err := build("a@example.com", Message{To: "b@example.com\r\nBcc: c@example.com", Text: "x"}, time.Now())
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(filepath.Join(dir, "outbox"), "")
+	if err != nil {
+		t.Fatalf("NewFileMailer: %v", err)
+	}
+	if err := m.Send(context.Background(), Message{To: "reader@example.com", Subject: "Hi", Text: "hello"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	files, _ := os.ReadDir(filepath.Join(dir, "outbox"))
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), ".eml") {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "outbox", files[0].Name()))
+	if !strings.Contains(string(data), "To: reader@example.com") || !strings.Contains(string(data), "hello") {
+		t.Errorf("unexpected message:\n%s", data)
+	}
+}
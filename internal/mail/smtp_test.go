@@ -0,0 +1,107 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTP accepts one message and records the envelope and data.
+type fakeSMTP struct {
+	ln   net.Listener
+	done chan struct{}
+	from string
+	rcpt string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTP{ln: ln, done: make(chan struct{})}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			reply("250 fake")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.TrimPrefix(cmd, "MAIL FROM:")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = strings.TrimPrefix(cmd, "RCPT TO:")
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.data = b.String()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := newFakeSMTP(t)
+	m, err := NewSMTPMailer(server.ln.Addr().String(), "", "", "GoRead2 <digest@example.com>")
+	if err != nil {
+		t.Fatalf("NewSMTPMailer: %v", err)
+	}
+
+	err = m.Send(context.Background(), Message{To: "Reader <reader@example.com>", Subject: "Digest", Text: "hello"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-server.done
+
+	if server.from != "<digest@example.com>" || server.rcpt != "<reader@example.com>" {
+		t.Errorf("unexpected envelope from=%q rcpt=%q", server.from, server.rcpt)
+	}
+	if !strings.Contains(server.data, "Subject: Digest") || !strings.Contains(server.data, "hello") {
+		t.Errorf("unexpected data:\n%s", server.data)
+	}
+}
+
+func TestNewSMTPMailer_Validates(t *testing.T) {
+	if _, err := NewSMTPMailer("no-port", "", "", "a@example.com"); err == nil {
+		t.Error("expected an error for an address without a port")
+	}
+	if _, err := NewSMTPMailer("localhost:25", "", "", "not an address"); err == nil {
+		t.Error("expected an error for an invalid sender")
+	}
+}
@@ -75,8 +75,106 @@ func (m *mockDB) GetAuditLogs(int, int, map[string]interface{}) ([]database.Audi
 func (m *mockDB) GetAccountStats(int) (map[string]interface{}, error) {
 	return map[string]interface{}{}, nil
 }
-func (m *mockDB) UpdateFeedLastFetch(int, time.Time) error { return nil }
-func (m *mockDB) Close() error                             { return nil }
+func (m *mockDB) UpdateFeedLastFetch(int, time.Time) error                         { return nil }
+func (m *mockDB) Close() error                                                     { return nil }
+func (m *mockDB) GetArticlesByIDs(int, []int) (map[int]database.Article, error)    { return nil, nil }
+func (m mockDB) CreateRefreshToken(*database.RefreshToken) error                   { return nil }
+func (m mockDB) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) { return nil, nil }
+func (m mockDB) DeleteExpiredRefreshTokens() error                                 { return nil }
+func (m mockDB) UpdateSessionActivity(string, time.Time, string) error             { return nil }
+func (m *mockDB) CreateUserIdentity(*database.UserIdentity) error                  { return nil }
+func (m *mockDB) GetUserIdentity(string, string) (*database.UserIdentity, error)   { return nil, nil }
+func (m *mockDB) GetUserIdentities(int) ([]database.UserIdentity, error)           { return nil, nil }
+func (m *mockDB) CreateLoginToken(*database.LoginToken) error                      { return nil }
+func (m *mockDB) ConsumeLoginToken(string) (*database.LoginToken, error)           { return nil, nil }
+func (m *mockDB) DeleteExpiredLoginTokens() error                                  { return nil }
+func (m *mockDB) ForEachUserIdentity(func(database.UserIdentity) error) error      { return nil }
+func (m *mockDB) DeleteUser(userID int) error                                      { return nil }
+func (m *mockDB) GetUserArticleHistory(userID int) ([]database.Article, error)     { return nil, nil }
+func (m *mockDB) GetUserSessions(userID int) ([]database.Session, error)           { return nil, nil }
+func (m *mockDB) ForEachUser(fn func(database.User) error) error                   { return nil }
+func (m *mockDB) ForEachArticle(fn func(database.Article) error) error             { return nil }
+func (m *mockDB) ForEachUserArticle(fn func(database.UserArticle) error) error     { return nil }
+func (m *mockDB) ForEachSession(fn func(database.Session) error) error             { return nil }
+func (m *mockDB) GetAllFeedSubscriptions() ([]database.FeedSubscription, error)    { return nil, nil }
+func (m *mockDB) GetFeedCategories() (map[int]string, error)                       { return nil, nil }
+func (m *mockDB) SetFeedCategory(feedID int, category string) error                { return nil }
+func (m *mockDB) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDB) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDB) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDB) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDB) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDB) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDB) DeleteInboundAddress(userID, addressID int) error      { return nil }
+func (m *mockDB) CreateWebhook(*database.Webhook) error                 { return nil }
+func (m *mockDB) GetWebhook(int, int) (*database.Webhook, error)        { return nil, nil }
+func (m *mockDB) GetUserWebhooks(int) ([]database.Webhook, error)       { return []database.Webhook{}, nil }
+func (m *mockDB) UpdateWebhook(*database.Webhook) error                 { return nil }
+func (m *mockDB) DeleteWebhook(int, int) error                          { return nil }
+func (m *mockDB) GetFeedWebhooks(int) ([]database.Webhook, error)       { return []database.Webhook{}, nil }
+func (m *mockDB) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDB) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDB) DeleteWebhookDeliveriesBefore(time.Time) (int, error)              { return 0, nil }
+func (m *mockDB) UpdateUserDigestPreferences(int, database.DigestPreferences) error { return nil }
+func (m *mockDB) GetDigestUsers() ([]database.User, error)                          { return []database.User{}, nil }
+func (m *mockDB) SetUserDigestSentAt(int, time.Time) error                          { return nil }
+func (m *mockDB) GetUserStarredCountsByFeed(int) (map[int]int, error)               { return map[int]int{}, nil }
+func (m *mockDB) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDB) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDB) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDB) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDB) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDB) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDB) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDB) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDB) CreateUndoOperation(*database.UndoOperation) error                 { return nil }
+func (m *mockDB) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) { return nil, nil }
+func (m *mockDB) DeleteExpiredUndoOperations(time.Time) (int, error)                { return 0, nil }
+func (m *mockDB) SetUserArticleProgress(int, int, int) error                        { return nil }
+func (m *mockDB) CreateTag(*database.Tag) error                                     { return nil }
+func (m *mockDB) GetTag(int, int) (*database.Tag, error)                            { return nil, nil }
+func (m *mockDB) GetTagByName(int, string) (*database.Tag, error)                   { return nil, nil }
+func (m *mockDB) GetUserTags(int) ([]database.Tag, error)                           { return nil, nil }
+func (m *mockDB) RenameTag(int, int, string) error                                  { return nil }
+func (m *mockDB) DeleteTag(int, int) error                                          { return nil }
+func (m *mockDB) TagArticle(int, int, int) error                                    { return nil }
+func (m *mockDB) UntagArticle(int, int, int) error                                  { return nil }
+func (m *mockDB) GetArticleTags(int, []int) (map[int][]string, error)               { return nil, nil }
+func (m *mockDB) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDB) CreateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDB) GetAnnotation(int, int) (*database.Annotation, error)          { return nil, nil }
+func (m *mockDB) GetArticleAnnotations(int, int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDB) GetUserAnnotations(int) ([]database.Annotation, error)         { return nil, nil }
+func (m *mockDB) UpdateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDB) DeleteAnnotation(int, int) error                               { return nil }
 func (m *mockDB) UpdateFeedCacheHeaders(feedID int, etag, lastModified string) error {
 	return nil
 }
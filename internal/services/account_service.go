@@ -0,0 +1,290 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// ErrSubscriptionNotCancelled is returned by DeleteAccount when the user's
+// subscription couldn't be cancelled; the account is kept.
+var ErrSubscriptionNotCancelled = errors.New("subscription could not be cancelled")
+
+// SubscriptionCanceller ends a user's paid subscription. PaymentService is
+// the implementation; it is only wired up when subscriptions are enabled.
+type SubscriptionCanceller interface {
+	CancelUserSubscription(user *database.User) error
+}
+
+// AccountService serves the self-service account actions: downloading
+// everything stored about a user and deleting their account.
+type AccountService struct {
+	db           database.Database
+	feedService  *FeedService
+	auditService *AuditService
+	canceller    SubscriptionCanceller
+}
+
+func NewAccountService(db database.Database, feedService *FeedService, auditService *AuditService) *AccountService {
+	return &AccountService{
+		db:           db,
+		feedService:  feedService,
+		auditService: auditService,
+	}
+}
+
+// SetSubscriptionCanceller makes DeleteAccount cancel the user's subscription
+// before removing them. Without one, subscriptions are left for an admin to
+// cancel in Stripe.
+func (s *AccountService) SetSubscriptionCanceller(c SubscriptionCanceller) {
+	s.canceller = c
+}
+
+// AccountExport is a user's data as handed back by GET /api/account/export.
+type AccountExport struct {
+	ExportedAt  time.Time
+	User        *database.User
+	Identities  []database.UserIdentity
+	OPML        []byte
+	OtherFeeds  []ExportedFeed
+	Articles    []ExportedArticle
+	Tags        []ExportedTag
+	Annotations annotationExport
+}
+
+// ExportedFeed is a subscription subscriptions.opml can't hold, listed in
+// account.json instead: an inbound email feed or a scraped HTML page.
+type ExportedFeed struct {
+	Type  string `json:"type"` // "email" or "html"
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ExportedTag is one entry of tags.json: a tag and the articles carrying it.
+type ExportedTag struct {
+	Name      string               `json:"name"`
+	CreatedAt time.Time            `json:"created_at"`
+	Articles  []ExportedTagArticle `json:"articles"`
+}
+
+// ExportedTagArticle is an article listed under a tag in tags.json.
+type ExportedTagArticle struct {
+	Feed  string `json:"feed"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// exportTagPageSize is how many tagged articles ExportAccount reads at a time.
+const exportTagPageSize = 500
+
+// ExportedArticle is one entry of articles.json: an article the user read,
+// starred or started reading.
+type ExportedArticle struct {
+	Feed         string    `json:"feed"`
+	Title        string    `json:"title"`
+	URL          string    `json:"url"`
+	Author       string    `json:"author,omitempty"`
+	PublishedAt  time.Time `json:"published_at"`
+	Read         bool      `json:"read"`
+	Starred      bool      `json:"starred"`
+	ReadProgress int       `json:"read_progress,omitempty"`
+}
+
+// ExportAccount gathers the user's subscriptions, article state, tags,
+// annotations and account details.
+func (s *AccountService) ExportAccount(user *database.User) (*AccountExport, error) {
+	opml, err := s.feedService.ExportOPML(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	otherFeeds, err := s.exportOtherFeeds(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := s.db.GetUserArticleHistory(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	articles := make([]ExportedArticle, len(history))
+	for i, a := range history {
+		articles[i] = ExportedArticle{
+			Feed:         a.FeedTitle,
+			Title:        a.Title,
+			URL:          a.URL,
+			Author:       a.Author,
+			PublishedAt:  a.PublishedAt,
+			Read:         a.IsRead,
+			Starred:      a.IsStarred,
+			ReadProgress: a.ReadProgress,
+		}
+	}
+
+	// Reload the user so the export shows stored values, not the session's copy
+	stored, err := s.db.GetUserByID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	identities, err := s.db.GetUserIdentities(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	tags, err := s.exportTags(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	annotations, err := collectAnnotations(s.db, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccountExport{
+		ExportedAt:  time.Now().UTC(),
+		User:        stored,
+		Identities:  identities,
+		OPML:        opml,
+		OtherFeeds:  otherFeeds,
+		Articles:    articles,
+		Tags:        tags,
+		Annotations: annotations,
+	}, nil
+}
+
+// exportOtherFeeds lists the subscriptions ExportOPML leaves out because
+// another reader couldn't subscribe to them.
+func (s *AccountService) exportOtherFeeds(userID int) ([]ExportedFeed, error) {
+	feeds, err := s.db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	other := []ExportedFeed{}
+	for _, feed := range feeds {
+		switch {
+		case isInboundFeed(feed):
+			other = append(other, ExportedFeed{Type: "email", Title: feed.Title, URL: feed.URL})
+		case isHTMLFeedURL(feed.URL):
+			other = append(other, ExportedFeed{Type: "html", Title: feed.Title, URL: feed.URL})
+		}
+	}
+	return other, nil
+}
+
+// exportTags lists the user's tags with the articles carrying each one.
+func (s *AccountService) exportTags(userID int) ([]ExportedTag, error) {
+	tags, err := s.db.GetUserTags(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	exported := make([]ExportedTag, len(tags))
+	for i, tag := range tags {
+		exported[i] = ExportedTag{Name: tag.Name, CreatedAt: tag.CreatedAt, Articles: []ExportedTagArticle{}}
+		cursor := ""
+		for {
+			page, err := s.db.GetTagArticlesPaginated(userID, tag.ID, exportTagPageSize, cursor)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+			}
+			for _, a := range page.Articles {
+				exported[i].Articles = append(exported[i].Articles, ExportedTagArticle{Feed: a.FeedTitle, Title: a.Title, URL: a.URL})
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+	}
+	return exported, nil
+}
+
+// WriteZip writes the export as a ZIP archive holding account.json,
+// subscriptions.opml, articles.json, tags.json and annotations.json.
+func (e *AccountExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	account := struct {
+		ExportedAt time.Time               `json:"exported_at"`
+		Account    *database.User          `json:"account"`
+		SignIns    []database.UserIdentity `json:"sign_in_methods"`
+		OtherFeeds []ExportedFeed          `json:"other_subscriptions"`
+	}{e.ExportedAt, e.User, e.Identities, e.OtherFeeds}
+	files := []struct {
+		name string
+		data func() ([]byte, error)
+	}{
+		{"account.json", func() ([]byte, error) { return json.MarshalIndent(account, "", "  ") }},
+		{"subscriptions.opml", func() ([]byte, error) { return e.OPML, nil }},
+		{"articles.json", func() ([]byte, error) { return json.MarshalIndent(e.Articles, "", "  ") }},
+		{"tags.json", func() ([]byte, error) { return json.MarshalIndent(e.Tags, "", "  ") }},
+		{"annotations.json", func() ([]byte, error) { return json.MarshalIndent(e.Annotations, "", "  ") }},
+	}
+
+	for _, file := range files {
+		data, err := file.data()
+		if err != nil {
+			return err
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// DeleteAccount cancels the user's subscription and removes the user with
+// everything they own, recording the deletion in the audit log. If the
+// subscription can't be cancelled nothing is deleted, so the user isn't
+// left paying for an account that no longer exists. It returns the IDs of
+// the sessions that were removed, so callers can evict them from caches.
+func (s *AccountService) DeleteAccount(user *database.User, ipAddress string) ([]string, error) {
+	sessions, err := s.db.GetUserSessions(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	details := map[string]interface{}{"self_service": true, "had_subscription": user.SubscriptionID != ""}
+	if s.canceller != nil {
+		if err := s.canceller.CancelUserSubscription(user); err != nil {
+			s.logDeletion(user, details, ipAddress, err)
+			return nil, fmt.Errorf("%w: %v", ErrSubscriptionNotCancelled, err)
+		}
+	} else if user.SubscriptionID != "" {
+		log.Printf("Deleting user %d without cancelling Stripe subscription: subscriptions are disabled", user.ID)
+	}
+
+	if err := s.db.DeleteUser(user.ID); err != nil {
+		s.logDeletion(user, details, ipAddress, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	s.logDeletion(user, details, ipAddress, nil)
+
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return ids, nil
+}
+
+// logDeletion records the deletion with the user as both actor and target.
+// The entry outlives the user; the email is what identifies them afterwards.
+func (s *AccountService) logDeletion(user *database.User, details map[string]interface{}, ipAddress string, deleteErr error) {
+	var err error
+	if deleteErr == nil {
+		err = s.auditService.LogSuccess(user.ID, user.Email, "delete_account", user.ID, user.Email, details, ipAddress)
+	} else {
+		err = s.auditService.LogFailure(user.ID, user.Email, "delete_account", user.ID, user.Email, details, ipAddress, deleteErr.Error())
+	}
+	if err != nil {
+		log.Printf("Failed to write audit log for deletion of user %d: %v", user.ID, err)
+	}
+}
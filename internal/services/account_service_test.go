@@ -0,0 +1,227 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+type fakeCanceller struct {
+	err       error
+	cancelled []int
+}
+
+func (f *fakeCanceller) CancelUserSubscription(user *database.User) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.cancelled = append(f.cancelled, user.ID)
+	return nil
+}
+
+func newAccountServiceWithRealDB(t *testing.T) (*AccountService, *database.DB) {
+	t.Helper()
+	db := setupTestDB(t)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewAccountService(db, NewFeedService(db, nil), NewAuditService(db)), db
+}
+
+func readZipFiles(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("Read %s: %v", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestAccountService_ExportAccount(t *testing.T) {
+	as, db := newAccountServiceWithRealDB(t)
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	read := createTestArticle(t, db, feed.ID)
+	unread := &database.Article{FeedID: feed.ID, Title: "Unread", URL: "https://example.com/unread", PublishedAt: time.Now(), CreatedAt: time.Now()}
+	if err := db.AddArticle(unread); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	if err := db.SetUserArticleStatus(user.ID, read.ID, true, true); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+	if err := db.CreateUserIdentity(&database.UserIdentity{UserID: user.ID, Provider: "github", Subject: "42", Email: user.Email, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("CreateUserIdentity: %v", err)
+	}
+	// Feeds another reader couldn't subscribe to are listed in account.json
+	for _, url := range []string{"mailto:news-abc@in.example.com", "html:https://example.com/news#item=.post"} {
+		other := &database.Feed{Title: url, URL: url, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := db.AddFeed(other); err != nil {
+			t.Fatalf("AddFeed: %v", err)
+		}
+		if err := db.SubscribeUserToFeed(user.ID, other.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+	tag := &database.Tag{UserID: user.ID, Name: "later", CreatedAt: time.Now()}
+	if err := db.CreateTag(tag); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if err := db.TagArticle(user.ID, unread.ID, tag.ID); err != nil {
+		t.Fatalf("TagArticle: %v", err)
+	}
+	annotation := &database.Annotation{UserID: user.ID, ArticleID: read.ID, QuotedText: "quoted", EndOffset: 6, Note: "a note", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := db.CreateAnnotation(annotation); err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+
+	export, err := as.ExportAccount(user)
+	if err != nil {
+		t.Fatalf("ExportAccount: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := export.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip: %v", err)
+	}
+	files := readZipFiles(t, buf.Bytes())
+	if len(files) != 5 {
+		t.Fatalf("Expected 5 files in the export, got %d", len(files))
+	}
+
+	var account struct {
+		Account    database.User           `json:"account"`
+		SignIns    []database.UserIdentity `json:"sign_in_methods"`
+		OtherFeeds []ExportedFeed          `json:"other_subscriptions"`
+	}
+	if err := json.Unmarshal(files["account.json"], &account); err != nil {
+		t.Fatalf("account.json: %v", err)
+	}
+	if account.Account.Email != user.Email {
+		t.Errorf("Expected account email %q, got %q", user.Email, account.Account.Email)
+	}
+	if len(account.SignIns) != 1 || account.SignIns[0].Provider != "github" {
+		t.Errorf("Expected the linked GitHub sign-in, got %+v", account.SignIns)
+	}
+
+	otherTypes := make(map[string]bool)
+	for _, other := range account.OtherFeeds {
+		otherTypes[other.Type] = true
+	}
+	if len(account.OtherFeeds) != 2 || !otherTypes["email"] || !otherTypes["html"] {
+		t.Errorf("Expected the email and HTML feeds in account.json, got %+v", account.OtherFeeds)
+	}
+
+	if !strings.Contains(string(files["subscriptions.opml"]), feed.URL) {
+		t.Errorf("Expected subscriptions.opml to list %s", feed.URL)
+	}
+
+	var tags []ExportedTag
+	if err := json.Unmarshal(files["tags.json"], &tags); err != nil {
+		t.Fatalf("tags.json: %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "later" || len(tags[0].Articles) != 1 || tags[0].Articles[0].URL != unread.URL {
+		t.Errorf("Expected the later tag on the unread article, got %+v", tags)
+	}
+
+	var annotations annotationExport
+	if err := json.Unmarshal(files["annotations.json"], &annotations); err != nil {
+		t.Fatalf("annotations.json: %v", err)
+	}
+	if len(annotations.Articles) != 1 || annotations.Articles[0].URL != read.URL ||
+		len(annotations.Articles[0].Annotations) != 1 || annotations.Articles[0].Annotations[0].Note != "a note" {
+		t.Errorf("Expected the note on the read article, got %+v", annotations)
+	}
+
+	var articles []ExportedArticle
+	if err := json.Unmarshal(files["articles.json"], &articles); err != nil {
+		t.Fatalf("articles.json: %v", err)
+	}
+	if len(articles) != 1 || articles[0].URL != read.URL || !articles[0].Read || !articles[0].Starred {
+		t.Errorf("Expected only the read, starred article, got %+v", articles)
+	}
+	if articles[0].Feed != feed.Title {
+		t.Errorf("Expected feed title %q, got %q", feed.Title, articles[0].Feed)
+	}
+}
+
+func TestAccountService_DeleteAccount(t *testing.T) {
+	as, db := newAccountServiceWithRealDB(t)
+	canceller := &fakeCanceller{}
+	as.SetSubscriptionCanceller(canceller)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	now := time.Now()
+	if err := db.CreateSession(&database.Session{ID: "account-session", UserID: user.ID, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	sessionIDs, err := as.DeleteAccount(user, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("DeleteAccount: %v", err)
+	}
+	if len(sessionIDs) != 1 || sessionIDs[0] != "account-session" {
+		t.Errorf("Expected the deleted session ID returned, got %v", sessionIDs)
+	}
+	if len(canceller.cancelled) != 1 || canceller.cancelled[0] != user.ID {
+		t.Errorf("Expected the subscription cancelled, got %v", canceller.cancelled)
+	}
+	if _, err := db.GetUserByID(user.ID); err == nil {
+		t.Error("Expected the user to be deleted")
+	}
+	if feeds, err := db.GetUserFeeds(user.ID); err != nil || len(feeds) != 0 {
+		t.Errorf("Expected no subscriptions left, got %v, %v", feeds, err)
+	}
+
+	logs, err := db.GetAuditLogs(10, 0, map[string]interface{}{"operation_type": "delete_account"})
+	if err != nil {
+		t.Fatalf("GetAuditLogs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Result != "success" || logs[0].TargetUserEmail != user.Email {
+		t.Errorf("Expected one successful delete_account audit log, got %+v", logs)
+	}
+}
+
+func TestAccountService_DeleteAccountKeepsUserWhenCancelFails(t *testing.T) {
+	as, db := newAccountServiceWithRealDB(t)
+	as.SetSubscriptionCanceller(&fakeCanceller{err: errors.New("stripe unavailable")})
+	user := createTestUser(t, db)
+
+	if _, err := as.DeleteAccount(user, "127.0.0.1"); !errors.Is(err, ErrSubscriptionNotCancelled) {
+		t.Fatalf("Expected ErrSubscriptionNotCancelled, got %v", err)
+	}
+	if _, err := db.GetUserByID(user.ID); err != nil {
+		t.Errorf("Expected the user to be kept: %v", err)
+	}
+
+	logs, err := db.GetAuditLogs(10, 0, map[string]interface{}{"operation_type": "delete_account"})
+	if err != nil {
+		t.Fatalf("GetAuditLogs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Result != "failure" {
+		t.Errorf("Expected one failed delete_account audit log, got %+v", logs)
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+var (
+	// ErrArticleNotFound indicates the article does not exist or the user is not subscribed to its feed
+	ErrArticleNotFound = errors.New("article not found")
+
+	// ErrAnnotationNotFound indicates the annotation does not exist or belongs to another user
+	ErrAnnotationNotFound = errors.New("annotation not found")
+
+	// ErrInvalidAnnotation indicates the highlight range or note failed validation
+	ErrInvalidAnnotation = errors.New("invalid annotation")
+)
+
+// maxAnnotationNoteLength caps note size so a single annotation can't bloat exports.
+const maxAnnotationNoteLength = 10000
+
+// Supported annotation export formats
+const (
+	AnnotationExportMarkdown = "markdown"
+	AnnotationExportJSON     = "json"
+)
+
+// AnnotationInput describes a highlight to create. Offsets are character
+// (rune) offsets into the article's sanitised Content; EndOffset is exclusive.
+// QuotedText is optional and, when given, must match the highlighted range.
+type AnnotationInput struct {
+	QuotedText  string
+	StartOffset int
+	EndOffset   int
+	Note        string
+}
+
+// AnnotationUpdate describes a partial update. Nil fields are left unchanged;
+// StartOffset and EndOffset must be provided together.
+type AnnotationUpdate struct {
+	QuotedText  *string
+	StartOffset *int
+	EndOffset   *int
+	Note        *string
+}
+
+type AnnotationService struct {
+	db database.Database
+}
+
+func NewAnnotationService(db database.Database) *AnnotationService {
+	return &AnnotationService{db: db}
+}
+
+// ListArticleAnnotations returns the user's annotations on an article, ordered by position.
+func (s *AnnotationService) ListArticleAnnotations(userID, articleID int) ([]database.Annotation, error) {
+	if _, err := s.getArticle(userID, articleID); err != nil {
+		return nil, err
+	}
+
+	annotations, err := s.db.GetArticleAnnotations(userID, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return annotations, nil
+}
+
+// CreateAnnotation validates the highlight against the article content and stores it.
+func (s *AnnotationService) CreateAnnotation(userID, articleID int, input AnnotationInput) (*database.Annotation, error) {
+	article, err := s.getArticle(userID, articleID)
+	if err != nil {
+		return nil, err
+	}
+
+	quoted, err := resolveHighlight(article.Content, input.StartOffset, input.EndOffset, input.QuotedText)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateAnnotationNote(input.Note); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	annotation := &database.Annotation{
+		UserID:      userID,
+		ArticleID:   articleID,
+		QuotedText:  quoted,
+		StartOffset: input.StartOffset,
+		EndOffset:   input.EndOffset,
+		Note:        input.Note,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.db.CreateAnnotation(annotation); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return annotation, nil
+}
+
+// UpdateAnnotation applies a partial update to one of the user's annotations on an article.
+func (s *AnnotationService) UpdateAnnotation(userID, articleID, annotationID int, update AnnotationUpdate) (*database.Annotation, error) {
+	annotation, err := s.getAnnotation(userID, articleID, annotationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if (update.StartOffset == nil) != (update.EndOffset == nil) {
+		return nil, fmt.Errorf("%w: start_offset and end_offset must be updated together", ErrInvalidAnnotation)
+	}
+
+	if update.StartOffset != nil || update.QuotedText != nil {
+		article, err := s.getArticle(userID, articleID)
+		if err != nil {
+			return nil, err
+		}
+
+		start, end := annotation.StartOffset, annotation.EndOffset
+		if update.StartOffset != nil {
+			start, end = *update.StartOffset, *update.EndOffset
+		}
+		quoted := ""
+		if update.QuotedText != nil {
+			quoted = *update.QuotedText
+		}
+
+		resolved, err := resolveHighlight(article.Content, start, end, quoted)
+		if err != nil {
+			return nil, err
+		}
+		annotation.StartOffset = start
+		annotation.EndOffset = end
+		annotation.QuotedText = resolved
+	}
+
+	if update.Note != nil {
+		if err := validateAnnotationNote(*update.Note); err != nil {
+			return nil, err
+		}
+		annotation.Note = *update.Note
+	}
+
+	annotation.UpdatedAt = time.Now()
+	if err := s.db.UpdateAnnotation(annotation); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return annotation, nil
+}
+
+// DeleteAnnotation removes one of the user's annotations on an article.
+func (s *AnnotationService) DeleteAnnotation(userID, articleID, annotationID int) error {
+	if _, err := s.getAnnotation(userID, articleID, annotationID); err != nil {
+		return err
+	}
+
+	if err := s.db.DeleteAnnotation(userID, annotationID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// annotationExport is the JSON export shape: annotations grouped under their article.
+type annotationExport struct {
+	ExportedAt time.Time                `json:"exported_at"`
+	Articles   []annotatedArticleExport `json:"articles"`
+}
+
+type annotatedArticleExport struct {
+	ArticleID   int                   `json:"article_id"`
+	Title       string                `json:"title"`
+	URL         string                `json:"url"`
+	FeedTitle   string                `json:"feed_title"`
+	Annotations []database.Annotation `json:"annotations"`
+}
+
+// ExportAnnotations renders all of the user's annotations in the requested
+// format ("markdown" or "json"), grouped by article.
+func (s *AnnotationService) ExportAnnotations(userID int, format string) ([]byte, error) {
+	if format != AnnotationExportMarkdown && format != AnnotationExportJSON {
+		return nil, fmt.Errorf("%w: unsupported export format %q", ErrInvalidAnnotation, format)
+	}
+
+	export, err := collectAnnotations(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == AnnotationExportJSON {
+		data, err := json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal annotations: %w", err)
+		}
+		return data, nil
+	}
+	return renderAnnotationsMarkdown(export), nil
+}
+
+// collectAnnotations gathers all of the user's annotations grouped by
+// article, for ExportAnnotations and the account export.
+func collectAnnotations(db database.Database, userID int) (annotationExport, error) {
+	annotations, err := db.GetUserAnnotations(userID)
+	if err != nil {
+		return annotationExport{}, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	var articleIDs []int
+	seen := make(map[int]bool)
+	for _, annotation := range annotations {
+		if !seen[annotation.ArticleID] {
+			seen[annotation.ArticleID] = true
+			articleIDs = append(articleIDs, annotation.ArticleID)
+		}
+	}
+	articles, err := db.GetArticlesByIDs(userID, articleIDs)
+	if err != nil {
+		return annotationExport{}, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	export := annotationExport{
+		ExportedAt: time.Now().UTC(),
+		Articles:   []annotatedArticleExport{},
+	}
+	byArticle := make(map[int]int) // articleID -> index in export.Articles
+	for _, annotation := range annotations {
+		idx, seen := byArticle[annotation.ArticleID]
+		if !seen {
+			entry := annotatedArticleExport{
+				ArticleID: annotation.ArticleID,
+				Title:     fmt.Sprintf("Article %d", annotation.ArticleID),
+			}
+			// Articles from feeds the user has since unsubscribed from are no
+			// longer visible; keep their annotations under a placeholder title.
+			if article, ok := articles[annotation.ArticleID]; ok {
+				entry.Title = article.Title
+				entry.URL = article.URL
+				entry.FeedTitle = article.FeedTitle
+			}
+			idx = len(export.Articles)
+			byArticle[annotation.ArticleID] = idx
+			export.Articles = append(export.Articles, entry)
+		}
+		export.Articles[idx].Annotations = append(export.Articles[idx].Annotations, annotation)
+	}
+
+	return export, nil
+}
+
+func renderAnnotationsMarkdown(export annotationExport) []byte {
+	var b strings.Builder
+	b.WriteString("# GoRead2 Annotations\n\n")
+	fmt.Fprintf(&b, "Exported %s\n", export.ExportedAt.Format(time.RFC1123))
+
+	for _, article := range export.Articles {
+		b.WriteString("\n## ")
+		if article.URL != "" {
+			fmt.Fprintf(&b, "[%s](%s)", article.Title, article.URL)
+		} else {
+			b.WriteString(article.Title)
+		}
+		b.WriteString("\n")
+		if article.FeedTitle != "" {
+			fmt.Fprintf(&b, "\n_%s_\n", article.FeedTitle)
+		}
+
+		for _, annotation := range article.Annotations {
+			b.WriteString("\n")
+			for _, line := range strings.Split(stripHTMLTags(annotation.QuotedText), "\n") {
+				fmt.Fprintf(&b, "> %s\n", strings.TrimSpace(line))
+			}
+			if note := strings.TrimSpace(annotation.Note); note != "" {
+				fmt.Fprintf(&b, "\n%s\n", note)
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+func (s *AnnotationService) getArticle(userID, articleID int) (*database.Article, error) {
+	article, err := s.db.GetArticleByID(userID, articleID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if article == nil {
+		return nil, ErrArticleNotFound
+	}
+	return article, nil
+}
+
+func (s *AnnotationService) getAnnotation(userID, articleID, annotationID int) (*database.Annotation, error) {
+	annotation, err := s.db.GetAnnotation(userID, annotationID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if annotation == nil || annotation.ArticleID != articleID {
+		return nil, ErrAnnotationNotFound
+	}
+	return annotation, nil
+}
+
+// resolveHighlight checks that [start, end) is a non-empty rune range within
+// content and returns the text it covers. If quoted is non-empty it must equal
+// that text, which catches clients working from stale or differently
+// sanitised content.
+func resolveHighlight(content string, start, end int, quoted string) (string, error) {
+	runes := []rune(content)
+	if start < 0 || end <= start || end > len(runes) {
+		return "", fmt.Errorf("%w: offsets %d-%d are outside the article content (length %d)",
+			ErrInvalidAnnotation, start, end, len(runes))
+	}
+
+	text := string(runes[start:end])
+	if quoted != "" && quoted != text {
+		return "", fmt.Errorf("%w: quoted text does not match the article content at offsets %d-%d",
+			ErrInvalidAnnotation, start, end)
+	}
+	return text, nil
+}
+
+func validateAnnotationNote(note string) error {
+	if utf8.RuneCountInString(note) > maxAnnotationNoteLength {
+		return fmt.Errorf("%w: note exceeds %d characters", ErrInvalidAnnotation, maxAnnotationNoteLength)
+	}
+	return nil
+}
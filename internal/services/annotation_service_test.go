@@ -0,0 +1,176 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func newAnnotationServiceWithArticle(t *testing.T, content string) (*AnnotationService, *database.DB, *database.User, *database.Article) {
+	t.Helper()
+	db := setupTestDB(t)
+	t.Cleanup(func() { _ = db.Close() })
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	article := &database.Article{
+		FeedID: feed.ID, Title: "Research Notes",
+		URL:     "https://example.com/research-" + t.Name(),
+		Content: content, PublishedAt: time.Now(), CreatedAt: time.Now(),
+	}
+	if err := db.AddArticle(article); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+	return NewAnnotationService(db), db, user, article
+}
+
+func TestAnnotationService_CreateAnnotation(t *testing.T) {
+	svc, _, user, article := newAnnotationServiceWithArticle(t, "<p>Café results are promising.</p>")
+
+	t.Run("fills quoted text from offsets", func(t *testing.T) {
+		// Offsets are in runes: "Café" spans 3..7 even though é is two bytes.
+		got, err := svc.CreateAnnotation(user.ID, article.ID, AnnotationInput{StartOffset: 3, EndOffset: 7, Note: "check"})
+		if err != nil {
+			t.Fatalf("CreateAnnotation: %v", err)
+		}
+		if got.ID == 0 {
+			t.Error("expected annotation ID to be set")
+		}
+		if got.QuotedText != "Café" {
+			t.Errorf("expected quoted text %q, got %q", "Café", got.QuotedText)
+		}
+	})
+
+	t.Run("rejects mismatched quote", func(t *testing.T) {
+		_, err := svc.CreateAnnotation(user.ID, article.ID, AnnotationInput{QuotedText: "Cafe", StartOffset: 3, EndOffset: 7})
+		if !errors.Is(err, ErrInvalidAnnotation) {
+			t.Errorf("expected ErrInvalidAnnotation, got %v", err)
+		}
+	})
+
+	t.Run("rejects out of range offsets", func(t *testing.T) {
+		for _, tc := range [][2]int{{-1, 2}, {5, 5}, {6, 2}, {0, 1000}} {
+			_, err := svc.CreateAnnotation(user.ID, article.ID, AnnotationInput{StartOffset: tc[0], EndOffset: tc[1]})
+			if !errors.Is(err, ErrInvalidAnnotation) {
+				t.Errorf("offsets %v: expected ErrInvalidAnnotation, got %v", tc, err)
+			}
+		}
+	})
+
+	t.Run("rejects articles the user cannot see", func(t *testing.T) {
+		_, err := svc.CreateAnnotation(user.ID, article.ID+1000, AnnotationInput{StartOffset: 0, EndOffset: 1})
+		if !errors.Is(err, ErrArticleNotFound) {
+			t.Errorf("expected ErrArticleNotFound, got %v", err)
+		}
+	})
+}
+
+func TestAnnotationService_UpdateAndDelete(t *testing.T) {
+	svc, db, user, article := newAnnotationServiceWithArticle(t, "alpha beta gamma")
+
+	created, err := svc.CreateAnnotation(user.ID, article.ID, AnnotationInput{StartOffset: 0, EndOffset: 5})
+	if err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+
+	note := "first word"
+	start, end := 6, 10
+	updated, err := svc.UpdateAnnotation(user.ID, article.ID, created.ID, AnnotationUpdate{
+		StartOffset: &start, EndOffset: &end, Note: &note,
+	})
+	if err != nil {
+		t.Fatalf("UpdateAnnotation: %v", err)
+	}
+	if updated.QuotedText != "beta" || updated.Note != note {
+		t.Errorf("unexpected update result: %+v", updated)
+	}
+
+	if _, err := svc.UpdateAnnotation(user.ID, article.ID, created.ID, AnnotationUpdate{StartOffset: &start}); !errors.Is(err, ErrInvalidAnnotation) {
+		t.Errorf("expected ErrInvalidAnnotation for lone start_offset, got %v", err)
+	}
+
+	other := createTestUserWithEmail(t, db, "other-"+t.Name())
+	if err := svc.DeleteAnnotation(other.ID, article.ID, created.ID); !errors.Is(err, ErrAnnotationNotFound) {
+		t.Errorf("expected ErrAnnotationNotFound for another user, got %v", err)
+	}
+
+	if err := svc.DeleteAnnotation(user.ID, article.ID, created.ID); err != nil {
+		t.Fatalf("DeleteAnnotation: %v", err)
+	}
+	remaining, err := svc.ListArticleAnnotations(user.ID, article.ID)
+	if err != nil {
+		t.Fatalf("ListArticleAnnotations: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("expected no annotations after delete, got %d", len(remaining))
+	}
+}
+
+func TestAnnotationService_ExportAnnotations(t *testing.T) {
+	svc, _, user, article := newAnnotationServiceWithArticle(t, "<p>The <em>key</em> finding\nspans lines.</p>")
+
+	if _, err := svc.CreateAnnotation(user.ID, article.ID, AnnotationInput{StartOffset: 3, EndOffset: 40, Note: "Important"}); err != nil {
+		t.Fatalf("CreateAnnotation: %v", err)
+	}
+
+	t.Run("markdown", func(t *testing.T) {
+		data, err := svc.ExportAnnotations(user.ID, AnnotationExportMarkdown)
+		if err != nil {
+			t.Fatalf("ExportAnnotations: %v", err)
+		}
+		md := string(data)
+		for _, want := range []string{
+			"## [Research Notes](" + article.URL + ")",
+			"> The key finding\n> spans lines.\n",
+			"\nImportant\n",
+		} {
+			if !strings.Contains(md, want) {
+				t.Errorf("markdown export missing %q:\n%s", want, md)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		data, err := svc.ExportAnnotations(user.ID, AnnotationExportJSON)
+		if err != nil {
+			t.Fatalf("ExportAnnotations: %v", err)
+		}
+		var export annotationExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			t.Fatalf("invalid JSON export: %v", err)
+		}
+		if len(export.Articles) != 1 || len(export.Articles[0].Annotations) != 1 {
+			t.Fatalf("unexpected export shape: %+v", export)
+		}
+		if export.Articles[0].Title != "Research Notes" {
+			t.Errorf("expected article title in export, got %q", export.Articles[0].Title)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, err := svc.ExportAnnotations(user.ID, "pdf"); !errors.Is(err, ErrInvalidAnnotation) {
+			t.Errorf("expected ErrInvalidAnnotation, got %v", err)
+		}
+	})
+}
+
+func createTestUserWithEmail(t *testing.T, db *database.DB, name string) *database.User {
+	t.Helper()
+	user := &database.User{
+		GoogleID:  "google-" + name,
+		Email:     name + "@example.com",
+		Name:      "Other User",
+		CreatedAt: time.Now(),
+	}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
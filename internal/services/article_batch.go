@@ -0,0 +1,93 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// MaxArticleBatchSize caps the operations accepted by UpdateArticleStatuses.
+const MaxArticleBatchSize = 500
+
+// Per-item outcomes of a batch status update
+const (
+	ArticleBatchUpdated   = "updated"
+	ArticleBatchUnchanged = "unchanged" // already in the requested state
+	ArticleBatchNotFound  = "not_found" // not in the user's feeds
+	ArticleBatchInvalid   = "invalid"
+)
+
+// ArticleStatusOp sets an article's read and/or starred flag. Omitted flags
+// are left as they are.
+type ArticleStatusOp struct {
+	ID      int   `json:"id"`
+	Read    *bool `json:"read,omitempty"`
+	Starred *bool `json:"starred,omitempty"`
+}
+
+// ArticleBatchItemResult reports the outcome of one operation.
+type ArticleBatchItemResult struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ArticleBatchResult describes a batch status update. Count and the undo
+// fields cover the articles that actually changed.
+type ArticleBatchResult struct {
+	BulkStatusResult
+	Results []ArticleBatchItemResult
+}
+
+// UpdateArticleStatuses applies a batch of read/star operations in one
+// database call and returns a result per operation, in request order. Later
+// operations on the same article override earlier ones. Cached unread counts
+// are adjusted per feed, and the change can be reversed with UndoOperation.
+func (fs *FeedService) UpdateArticleStatuses(userID int, ops []ArticleStatusOp) (*ArticleBatchResult, error) {
+	results := make([]ArticleBatchItemResult, len(ops))
+	updates := make([]database.ArticleStatusUpdate, 0, len(ops))
+	for i, op := range ops {
+		results[i].ID = op.ID
+		switch {
+		case op.ID <= 0:
+			results[i].Status, results[i].Error = ArticleBatchInvalid, "id must be a positive integer"
+		case op.Read == nil && op.Starred == nil:
+			results[i].Status, results[i].Error = ArticleBatchInvalid, "set read or starred"
+		default:
+			updates = append(updates, database.ArticleStatusUpdate{ArticleID: op.ID, IsRead: op.Read, IsStarred: op.Starred})
+		}
+	}
+
+	updated, err := fs.db.UpdateUserArticleStatuses(userID, updates)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	changed := make(map[int]bool, len(updated.Changes))
+	for _, c := range updated.Changes {
+		changed[c.ArticleID] = true
+	}
+	for i := range results {
+		if results[i].Status != "" {
+			continue
+		}
+		switch _, found := updated.Found[results[i].ID]; {
+		case !found:
+			results[i].Status = ArticleBatchNotFound
+		case changed[results[i].ID]:
+			results[i].Status = ArticleBatchUpdated
+		default:
+			results[i].Status = ArticleBatchUnchanged
+		}
+	}
+
+	if len(updated.Changes) > 0 {
+		fs.unreadCache.AdjustCounts(userID, updated.UnreadDelta)
+		fs.publishStatusChanges(userID, updated.Changes)
+	}
+
+	result := &ArticleBatchResult{Results: results}
+	result.Count = len(updated.Changes)
+	result.UndoID, result.UndoExpiresAt = fs.recordUndo(userID, UndoKindBatchStatus, updated.Changes)
+	return result, nil
+}
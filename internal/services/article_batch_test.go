@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestUpdateArticleStatuses(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	first := addSyncTestArticle(t, db, feed.ID, "first")
+	second := addSyncTestArticle(t, db, feed.ID, "second")
+
+	fs := NewFeedService(db, nil)
+	feeds := []database.Feed{*feed}
+	if counts, err := fs.GetUserUnreadCounts(user.ID, feeds); err != nil || counts[feed.ID] != 2 {
+		t.Fatalf("expected 2 unread, got %v, %v", counts, err)
+	}
+
+	yes, no := true, false
+	result, err := fs.UpdateArticleStatuses(user.ID, []ArticleStatusOp{
+		{ID: first.ID, Read: &yes, Starred: &yes},
+		{ID: second.ID, Read: &no},
+		{ID: 999999, Read: &yes},
+		{ID: first.ID},
+	})
+	if err != nil {
+		t.Fatalf("UpdateArticleStatuses: %v", err)
+	}
+
+	want := []string{ArticleBatchUpdated, ArticleBatchUnchanged, ArticleBatchNotFound, ArticleBatchInvalid}
+	for i, status := range want {
+		if result.Results[i].Status != status {
+			t.Errorf("result %d: expected %s, got %+v", i, status, result.Results[i])
+		}
+	}
+	if result.Count != 1 || result.UndoID == "" {
+		t.Errorf("expected one undoable change, got count=%d undo=%q", result.Count, result.UndoID)
+	}
+
+	cached, hit := fs.unreadCache.Get(user.ID)
+	if !hit || cached[feed.ID] != 1 {
+		t.Errorf("expected the cached count adjusted to 1, got %v (hit=%v)", cached, hit)
+	}
+
+	if _, err := fs.UndoOperation(user.ID, result.UndoID); err != nil {
+		t.Fatalf("UndoOperation: %v", err)
+	}
+	if status, _ := db.GetUserArticleStatus(user.ID, first.ID); status.IsRead || status.IsStarred {
+		t.Errorf("expected undo to restore the article, got %+v", status)
+	}
+}
@@ -91,11 +91,119 @@ func (m *mockDBAudit) GetAuditLogs(limit, offset int, filters map[string]interfa
 }
 
 // Stub methods to satisfy interface
-func (m *mockDBAudit) Close() error                                     { return nil }
-func (m *mockDBAudit) CreateUser(*database.User) error                  { return nil }
-func (m *mockDBAudit) GetUserByGoogleID(string) (*database.User, error) { return nil, nil }
-func (m *mockDBAudit) GetUserByID(int) (*database.User, error)          { return nil, nil }
-func (m *mockDBAudit) GetUserByEmail(string) (*database.User, error)    { return nil, nil }
+func (m *mockDBAudit) Close() error                                                  { return nil }
+func (m *mockDBAudit) GetArticlesByIDs(int, []int) (map[int]database.Article, error) { return nil, nil }
+func (m mockDBAudit) CreateRefreshToken(*database.RefreshToken) error                { return nil }
+func (m mockDBAudit) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBAudit) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBAudit) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBAudit) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBAudit) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) GetUserIdentities(int) ([]database.UserIdentity, error)        { return nil, nil }
+func (m *mockDBAudit) CreateLoginToken(*database.LoginToken) error                   { return nil }
+func (m *mockDBAudit) ConsumeLoginToken(string) (*database.LoginToken, error)        { return nil, nil }
+func (m *mockDBAudit) DeleteExpiredLoginTokens() error                               { return nil }
+func (m *mockDBAudit) ForEachUserIdentity(func(database.UserIdentity) error) error   { return nil }
+func (m *mockDBAudit) DeleteUser(userID int) error                                   { return nil }
+func (m *mockDBAudit) GetUserArticleHistory(userID int) ([]database.Article, error)  { return nil, nil }
+func (m *mockDBAudit) GetUserSessions(userID int) ([]database.Session, error)        { return nil, nil }
+func (m *mockDBAudit) ForEachUser(fn func(database.User) error) error                { return nil }
+func (m *mockDBAudit) ForEachArticle(fn func(database.Article) error) error          { return nil }
+func (m *mockDBAudit) ForEachUserArticle(fn func(database.UserArticle) error) error  { return nil }
+func (m *mockDBAudit) ForEachSession(fn func(database.Session) error) error          { return nil }
+func (m *mockDBAudit) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) { return nil, nil }
+func (m *mockDBAudit) GetFeedCategories() (map[int]string, error)                    { return nil, nil }
+func (m *mockDBAudit) SetFeedCategory(feedID int, category string) error             { return nil }
+func (m *mockDBAudit) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBAudit) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBAudit) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBAudit) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBAudit) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBAudit) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBAudit) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAudit) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBAudit) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBAudit) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBAudit) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBAudit) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBAudit) DeleteWebhookDeliveriesBefore(time.Time) (int, error)              { return 0, nil }
+func (m *mockDBAudit) UpdateUserDigestPreferences(int, database.DigestPreferences) error { return nil }
+func (m *mockDBAudit) GetDigestUsers() ([]database.User, error)                          { return []database.User{}, nil }
+func (m *mockDBAudit) SetUserDigestSentAt(int, time.Time) error                          { return nil }
+func (m *mockDBAudit) GetUserStarredCountsByFeed(int) (map[int]int, error)               { return map[int]int{}, nil }
+func (m *mockDBAudit) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBAudit) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBAudit) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBAudit) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBAudit) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBAudit) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBAudit) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBAudit) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBAudit) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBAudit) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBAudit) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBAudit) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBAudit) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBAudit) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBAudit) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBAudit) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBAudit) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBAudit) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBAudit) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBAudit) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBAudit) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBAudit) CreateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDBAudit) GetAnnotation(int, int) (*database.Annotation, error)          { return nil, nil }
+func (m *mockDBAudit) GetArticleAnnotations(int, int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBAudit) GetUserAnnotations(int) ([]database.Annotation, error)         { return nil, nil }
+func (m *mockDBAudit) UpdateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDBAudit) DeleteAnnotation(int, int) error                               { return nil }
+func (m *mockDBAudit) CreateUser(*database.User) error                               { return nil }
+func (m *mockDBAudit) GetUserByGoogleID(string) (*database.User, error)              { return nil, nil }
+func (m *mockDBAudit) GetUserByID(int) (*database.User, error)                       { return nil, nil }
+func (m *mockDBAudit) GetUserByEmail(string) (*database.User, error)                 { return nil, nil }
 func (m *mockDBAudit) UpdateUserSubscription(int, string, string, time.Time, time.Time) error {
 	return nil
 }
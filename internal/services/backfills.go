@@ -0,0 +1,93 @@
+package services
+
+import (
+	"net/url"
+
+	"cloud.google.com/go/datastore"
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// backfills are the Datastore data migrations that cmd/backfill and the
+// /tasks/backfill worker can run by name. Keep finished ones listed: the
+// name is how their checkpoint is found, and rerunning one is harmless.
+var backfills = []database.Backfill{
+	{
+		Name:        "article-reading-time",
+		Description: "Estimate reading time for articles stored before it was recorded",
+		Kind:        "Article",
+		Apply:       backfillArticleReadingTime,
+	},
+	{
+		Name:        "user-email-lowercase",
+		Description: "Normalize email addresses stored before sign-in matched them case-insensitively",
+		Kind:        "User",
+		Apply:       backfillUserEmail,
+	},
+}
+
+// Backfills lists the registered backfills.
+func Backfills() []database.Backfill {
+	return backfills
+}
+
+// LookupBackfill finds a registered backfill by name.
+func LookupBackfill(name string) (database.Backfill, bool) {
+	for _, b := range backfills {
+		if b.Name == name {
+			return b, true
+		}
+	}
+	return database.Backfill{}, false
+}
+
+// backfillArticleReadingTime fills in reading_time_minutes for articles saved
+// without it. Articles that already have an estimate are left alone.
+func backfillArticleReadingTime(_ *datastore.Key, props *datastore.PropertyList) (bool, error) {
+	if v, ok := database.PropertyValue(*props, "reading_time_minutes"); ok {
+		if minutes, _ := v.(int64); minutes > 0 {
+			return false, nil
+		}
+	}
+
+	content, _ := database.PropertyValue(*props, "content")
+	description, _ := database.PropertyValue(*props, "description")
+	contentText, _ := content.(string)
+	descriptionText, _ := description.(string)
+	minutes := estimateReadingTime(contentText, descriptionText)
+	if minutes == 0 {
+		return false, nil
+	}
+
+	database.SetProperty(props, datastore.Property{
+		Name:    "reading_time_minutes",
+		Value:   int64(minutes),
+		NoIndex: true,
+	})
+	return true, nil
+}
+
+// backfillUserEmail stores a user's email address in normalized form, which
+// is the only form GetUserByEmail can find on Datastore.
+func backfillUserEmail(_ *datastore.Key, props *datastore.PropertyList) (bool, error) {
+	v, ok := database.PropertyValue(*props, "email")
+	if !ok {
+		return false, nil
+	}
+	email, _ := v.(string)
+	normalized := database.NormalizeEmail(email)
+	if normalized == email {
+		return false, nil
+	}
+
+	database.SetProperty(props, datastore.Property{Name: "email", Value: normalized})
+	return true, nil
+}
+
+// BackfillTaskURI is the /tasks/backfill URI that runs the named backfill.
+func BackfillTaskURI(name string, dryRun bool) string {
+	query := url.Values{"name": {name}}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	return "/tasks/backfill?" + query.Encode()
+}
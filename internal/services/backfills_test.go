@@ -0,0 +1,131 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestLookupBackfill(t *testing.T) {
+	for _, b := range Backfills() {
+		if b.Name == "" || b.Kind == "" || b.Apply == nil {
+			t.Errorf("backfill %q needs a name, kind and Apply", b.Name)
+		}
+		if found, ok := LookupBackfill(b.Name); !ok || found.Name != b.Name {
+			t.Errorf("expected to find %q", b.Name)
+		}
+	}
+	if _, ok := LookupBackfill("no-such-backfill"); ok {
+		t.Error("expected an unknown name to miss")
+	}
+}
+
+func TestBackfillTaskURI(t *testing.T) {
+	if got := BackfillTaskURI("article-reading-time", false); got != "/tasks/backfill?name=article-reading-time" {
+		t.Errorf("unexpected URI %s", got)
+	}
+	if got := BackfillTaskURI("a b", true); got != "/tasks/backfill?dry_run=true&name=a+b" {
+		t.Errorf("unexpected URI %s", got)
+	}
+}
+
+func TestBackfillArticleReadingTime(t *testing.T) {
+	longContent := "<p>" + strings.Repeat("word ", 500) + "</p>"
+
+	tests := []struct {
+		name        string
+		props       datastore.PropertyList
+		wantChanged bool
+		wantMinutes int64
+	}{
+		{
+			name:        "missing estimate is filled in",
+			props:       datastore.PropertyList{{Name: "content", Value: longContent}},
+			wantChanged: true,
+			wantMinutes: 3,
+		},
+		{
+			name:        "zero estimate falls back to the description",
+			props:       datastore.PropertyList{{Name: "description", Value: "a short summary"}, {Name: "reading_time_minutes", Value: int64(0)}},
+			wantChanged: true,
+			wantMinutes: 1,
+		},
+		{
+			name:        "existing estimate is kept",
+			props:       datastore.PropertyList{{Name: "content", Value: longContent}, {Name: "reading_time_minutes", Value: int64(7)}},
+			wantMinutes: 7,
+		},
+		{
+			name:  "no text leaves the article alone",
+			props: datastore.PropertyList{{Name: "title", Value: "Empty"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := tt.props
+			changed, err := backfillArticleReadingTime(nil, &props)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("expected changed=%v, got %v", tt.wantChanged, changed)
+			}
+			v, _ := database.PropertyValue(props, "reading_time_minutes")
+			minutes, _ := v.(int64)
+			if minutes != tt.wantMinutes {
+				t.Errorf("expected %d minutes, got %d", tt.wantMinutes, minutes)
+			}
+
+			// Running it again changes nothing
+			if again, _ := backfillArticleReadingTime(nil, &props); again {
+				t.Error("expected a second run to be a no-op")
+			}
+		})
+	}
+}
+
+func TestBackfillUserEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		props       datastore.PropertyList
+		wantChanged bool
+		wantEmail   string
+	}{
+		{
+			name:        "mixed case is lowercased",
+			props:       datastore.PropertyList{{Name: "email", Value: " Alice@Example.com"}},
+			wantChanged: true,
+			wantEmail:   "alice@example.com",
+		},
+		{
+			name:      "normalized address is left alone",
+			props:     datastore.PropertyList{{Name: "email", Value: "bob@example.com"}},
+			wantEmail: "bob@example.com",
+		},
+		{
+			name:  "missing address is left alone",
+			props: datastore.PropertyList{{Name: "name", Value: "Nobody"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			props := tt.props
+			changed, err := backfillUserEmail(nil, &props)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("expected changed=%v, got %v", tt.wantChanged, changed)
+			}
+			v, _ := database.PropertyValue(props, "email")
+			email, _ := v.(string)
+			if email != tt.wantEmail {
+				t.Errorf("expected email %q, got %q", tt.wantEmail, email)
+			}
+		})
+	}
+}
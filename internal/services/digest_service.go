@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/mail"
+)
+
+// ErrInvalidDigestPreferences indicates digest settings outside the allowed
+// values.
+var ErrInvalidDigestPreferences = errors.New("invalid digest preferences")
+
+// ErrMailerNotConfigured indicates digests can't be sent because no mail
+// server or development mail directory is configured.
+var ErrMailerNotConfigured = errors.New("mailer not configured")
+
+// Digest frequencies. An empty frequency means digests are off.
+const (
+	DigestOff    = ""
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+const (
+	digestArticlesPerFeed = 5
+	digestMaxFeeds        = 20
+)
+
+//go:embed templates/digest.html templates/digest.txt
+var digestTemplates embed.FS
+
+var (
+	digestHTML = htmltemplate.Must(htmltemplate.ParseFS(digestTemplates, "templates/digest.html"))
+	digestText = texttemplate.Must(texttemplate.ParseFS(digestTemplates, "templates/digest.txt"))
+)
+
+// DigestService emails users a summary of their top unread articles on the
+// schedule stored in their digest preferences.
+type DigestService struct {
+	db     database.Database
+	mailer mail.Mailer
+	appURL string
+}
+
+// NewDigestService returns a service sending through mailer, which may be nil
+// when mail isn't configured. appURL is the app's base URL, used for links
+// back to the reader.
+func NewDigestService(db database.Database, mailer mail.Mailer, appURL string) *DigestService {
+	return &DigestService{db: db, mailer: mailer, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// ValidateDigestPreferences normalizes prefs, accepting "off" for no digest
+// and defaulting the timezone to UTC.
+func ValidateDigestPreferences(prefs database.DigestPreferences) (database.DigestPreferences, error) {
+	switch prefs.Frequency {
+	case DigestOff, "off":
+		prefs.Frequency = DigestOff
+	case DigestDaily, DigestWeekly:
+	default:
+		return prefs, fmt.Errorf("%w: frequency must be daily, weekly or off", ErrInvalidDigestPreferences)
+	}
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+		return prefs, fmt.Errorf("%w: unknown timezone %q", ErrInvalidDigestPreferences, prefs.Timezone)
+	}
+	if prefs.Hour < 0 || prefs.Hour > 23 {
+		return prefs, fmt.Errorf("%w: hour must be between 0 and 23", ErrInvalidDigestPreferences)
+	}
+	if prefs.Weekday < 0 || prefs.Weekday > 6 {
+		return prefs, fmt.Errorf("%w: weekday must be between 0 (Sunday) and 6", ErrInvalidDigestPreferences)
+	}
+	return prefs, nil
+}
+
+// GetPreferences returns the user's stored digest settings.
+func (ds *DigestService) GetPreferences(userID int) (*database.User, error) {
+	user, err := ds.db.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return user, nil
+}
+
+// MailConfigured reports whether digests can be delivered.
+func (ds *DigestService) MailConfigured() bool {
+	return ds.mailer != nil
+}
+
+// UpdatePreferences validates and stores a user's digest settings.
+func (ds *DigestService) UpdatePreferences(userID int, prefs database.DigestPreferences) (database.DigestPreferences, error) {
+	prefs, err := ValidateDigestPreferences(prefs)
+	if err != nil {
+		return prefs, err
+	}
+	if err := ds.db.UpdateUserDigestPreferences(userID, prefs); err != nil {
+		return prefs, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return prefs, nil
+}
+
+// DigestRunResult summarizes one SendDueDigests run.
+type DigestRunResult struct {
+	Due    int `json:"due"`
+	Sent   int `json:"sent"`
+	Empty  int `json:"empty"` // due, but nothing unread to send
+	Failed int `json:"failed"`
+}
+
+// SendDueDigests sends every digest whose scheduled time has passed since it
+// was last sent. It is meant to run hourly; a missed run is caught up on the
+// next one. Failures for one user are logged and don't stop the others.
+func (ds *DigestService) SendDueDigests(ctx context.Context, now time.Time) (*DigestRunResult, error) {
+	if ds.mailer == nil {
+		return nil, ErrMailerNotConfigured
+	}
+	users, err := ds.db.GetDigestUsers()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	result := &DigestRunResult{}
+	for i := range users {
+		user := &users[i]
+		scheduled, due := digestDue(user, now)
+		if !due {
+			continue
+		}
+		result.Due++
+
+		sent, err := ds.sendDigest(ctx, user, scheduled)
+		if err != nil {
+			log.Printf("Failed to send digest to user %d: %v", user.ID, err)
+			result.Failed++
+			continue
+		}
+		if sent {
+			result.Sent++
+		} else {
+			result.Empty++
+		}
+		// Empty digests are recorded too, so the user isn't checked again
+		// until the next scheduled time.
+		if err := ds.db.SetUserDigestSentAt(user.ID, now); err != nil {
+			log.Printf("Failed to record digest for user %d: %v", user.ID, err)
+		}
+	}
+	return result, nil
+}
+
+// digestDue returns the user's most recent scheduled digest time at or before
+// now, and whether that digest still has to be sent.
+func digestDue(user *database.User, now time.Time) (time.Time, bool) {
+	loc, err := time.LoadLocation(user.DigestTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), user.DigestHour, 0, 0, 0, loc)
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	switch user.DigestFrequency {
+	case DigestDaily:
+	case DigestWeekly:
+		back := (int(scheduled.Weekday()) - user.DigestWeekday + 7) % 7
+		scheduled = scheduled.AddDate(0, 0, -back)
+	default:
+		return time.Time{}, false
+	}
+	return scheduled, user.DigestLastSentAt.Before(scheduled)
+}
+
+// DigestFeed is one feed's section of a digest.
+type DigestFeed struct {
+	Title     string
+	Articles  []database.Article
+	MoreCount int // unread articles in the period beyond those listed
+
+	starred int
+	newest  time.Time
+}
+
+type digestData struct {
+	Subject     string
+	Name        string
+	Frequency   string
+	PeriodText  string
+	Feeds       []DigestFeed
+	AppURL      string
+	SettingsURL string
+}
+
+// sendDigest emails the user's unread articles published since their last
+// digest (or one period before scheduled, if longer ago). It reports false
+// when there was nothing to send.
+func (ds *DigestService) sendDigest(ctx context.Context, user *database.User, scheduled time.Time) (bool, error) {
+	period, periodText := 24*time.Hour, "from the last day"
+	if user.DigestFrequency == DigestWeekly {
+		period, periodText = 7*24*time.Hour, "from the last week"
+	}
+	since := scheduled.Add(-period)
+	if user.DigestLastSentAt.After(since) {
+		since = user.DigestLastSentAt
+	}
+
+	feeds, err := ds.collectDigestFeeds(user.ID, since)
+	if err != nil {
+		return false, err
+	}
+	if len(feeds) == 0 {
+		return false, nil
+	}
+
+	total := 0
+	for _, f := range feeds {
+		total += len(f.Articles) + f.MoreCount
+	}
+	data := digestData{
+		Subject:     fmt.Sprintf("Your %s GoRead2 digest: %d unread %s", user.DigestFrequency, total, pluralize(total, "article", "articles")),
+		Name:        user.Name,
+		Frequency:   user.DigestFrequency,
+		PeriodText:  periodText,
+		Feeds:       feeds,
+		AppURL:      ds.appURL + "/",
+		SettingsURL: ds.appURL + "/account",
+	}
+
+	var html, text bytes.Buffer
+	if err := digestHTML.Execute(&html, data); err != nil {
+		return false, fmt.Errorf("failed to render digest: %w", err)
+	}
+	if err := digestText.Execute(&text, data); err != nil {
+		return false, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	msg := mail.Message{To: user.Email, Subject: data.Subject, Text: text.String(), HTML: html.String()}
+	if err := ds.mailer.Send(ctx, msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// collectDigestFeeds returns up to digestArticlesPerFeed of the newest unread
+// articles published since the cutoff in each feed. Feeds the user stars
+// articles from most come first, then feeds with the most recent articles.
+func (ds *DigestService) collectDigestFeeds(userID int, since time.Time) ([]DigestFeed, error) {
+	feeds, err := ds.db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	starred, err := ds.db.GetUserStarredCountsByFeed(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	var sections []DigestFeed
+	for _, feed := range feeds {
+		// Fetch a few extra so the "+N more" note reflects that there are more,
+		// without counting a whole backlog.
+		page, err := ds.db.GetUserFeedArticlesPaginated(userID, feed.ID, digestArticlesPerFeed*4, "", true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		var recent []database.Article
+		for _, a := range page.Articles {
+			if a.PublishedAt.After(since) {
+				recent = append(recent, a)
+			}
+		}
+		if len(recent) == 0 {
+			continue
+		}
+
+		section := DigestFeed{Title: feed.Title, starred: starred[feed.ID], newest: recent[0].PublishedAt}
+		if len(recent) > digestArticlesPerFeed {
+			section.MoreCount = len(recent) - digestArticlesPerFeed
+			recent = recent[:digestArticlesPerFeed]
+		}
+		section.Articles = recent
+		sections = append(sections, section)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		if sections[i].starred != sections[j].starred {
+			return sections[i].starred > sections[j].starred
+		}
+		return sections[i].newest.After(sections[j].newest)
+	})
+	if len(sections) > digestMaxFeeds {
+		sections = sections[:digestMaxFeeds]
+	}
+	return sections, nil
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/mail"
+)
+
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mail.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestValidateDigestPreferences(t *testing.T) {
+	prefs, err := ValidateDigestPreferences(database.DigestPreferences{Frequency: "off"})
+	if err != nil {
+		t.Fatalf("ValidateDigestPreferences: %v", err)
+	}
+	if prefs.Frequency != DigestOff || prefs.Timezone != "UTC" {
+		t.Errorf("expected off with UTC, got %+v", prefs)
+	}
+
+	invalid := []database.DigestPreferences{
+		{Frequency: "hourly"},
+		{Frequency: DigestDaily, Timezone: "Mars/Olympus_Mons"},
+		{Frequency: DigestDaily, Hour: 24},
+		{Frequency: DigestWeekly, Weekday: 7},
+	}
+	for _, p := range invalid {
+		if _, err := ValidateDigestPreferences(p); !errors.Is(err, ErrInvalidDigestPreferences) {
+			t.Errorf("%+v: expected ErrInvalidDigestPreferences, got %v", p, err)
+		}
+	}
+}
+
+func TestDigestDue(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	// Wednesday 2026-03-11 14:00 UTC is 10:00 in New York.
+	now := time.Date(2026, 3, 11, 14, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		user      database.User
+		scheduled time.Time
+		due       bool
+	}{
+		{
+			name:      "daily, hour passed today",
+			user:      database.User{DigestFrequency: DigestDaily, DigestTimezone: "UTC", DigestHour: 8},
+			scheduled: time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC),
+			due:       true,
+		},
+		{
+			name:      "daily, hour not yet reached",
+			user:      database.User{DigestFrequency: DigestDaily, DigestTimezone: "UTC", DigestHour: 20},
+			scheduled: time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC),
+			due:       true,
+		},
+		{
+			name: "daily, already sent",
+			user: database.User{DigestFrequency: DigestDaily, DigestTimezone: "UTC", DigestHour: 8,
+				DigestLastSentAt: time.Date(2026, 3, 11, 8, 5, 0, 0, time.UTC)},
+			scheduled: time.Date(2026, 3, 11, 8, 0, 0, 0, time.UTC),
+			due:       false,
+		},
+		{
+			name: "daily, in user's timezone",
+			user: database.User{DigestFrequency: DigestDaily, DigestTimezone: "America/New_York", DigestHour: 11,
+				DigestLastSentAt: time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)},
+			scheduled: time.Date(2026, 3, 10, 11, 0, 0, 0, ny),
+			due:       false,
+		},
+		{
+			name:      "weekly, earlier this week",
+			user:      database.User{DigestFrequency: DigestWeekly, DigestTimezone: "UTC", DigestHour: 6, DigestWeekday: int(time.Monday)},
+			scheduled: time.Date(2026, 3, 9, 6, 0, 0, 0, time.UTC),
+			due:       true,
+		},
+		{
+			name:      "weekly, later today",
+			user:      database.User{DigestFrequency: DigestWeekly, DigestTimezone: "UTC", DigestHour: 18, DigestWeekday: int(time.Wednesday)},
+			scheduled: time.Date(2026, 3, 4, 18, 0, 0, 0, time.UTC),
+			due:       true,
+		},
+		{
+			name: "off",
+			user: database.User{DigestFrequency: DigestOff},
+			due:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled, due := digestDue(&tt.user, now)
+			if due != tt.due {
+				t.Errorf("expected due=%v, got %v", tt.due, due)
+			}
+			if !scheduled.Equal(tt.scheduled) {
+				t.Errorf("expected scheduled %v, got %v", tt.scheduled, scheduled)
+			}
+		})
+	}
+}
+
+func TestSendDueDigests(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	unread := addSyncTestArticle(t, db, feed.ID, "unread-highlight")
+	read := addSyncTestArticle(t, db, feed.ID, "already-read")
+	if err := db.MarkUserArticleRead(user.ID, read.ID, true); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+
+	mailer := &recordingMailer{}
+	ds := NewDigestService(db, mailer, "https://reader.example.com/")
+	if _, err := ds.UpdatePreferences(user.ID, database.DigestPreferences{Frequency: DigestDaily, Hour: 0}); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+
+	now := time.Now()
+	result, err := ds.SendDueDigests(context.Background(), now)
+	if err != nil {
+		t.Fatalf("SendDueDigests: %v", err)
+	}
+	if result.Due != 1 || result.Sent != 1 || result.Failed != 0 {
+		t.Fatalf("expected one digest sent, got %+v", result)
+	}
+
+	msg := mailer.sent[0]
+	if msg.To != user.Email {
+		t.Errorf("expected digest to %s, got %s", user.Email, msg.To)
+	}
+	if !strings.Contains(msg.Text, unread.Title) || !strings.Contains(msg.HTML, unread.Title) {
+		t.Error("expected the unread article in both bodies")
+	}
+	if strings.Contains(msg.Text, read.Title) {
+		t.Error("read articles should not be in the digest")
+	}
+	if !strings.Contains(msg.Text, "https://reader.example.com/account") {
+		t.Error("expected a link to the digest settings")
+	}
+
+	result, err = ds.SendDueDigests(context.Background(), now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("SendDueDigests: %v", err)
+	}
+	if result.Due != 0 || len(mailer.sent) != 1 {
+		t.Errorf("expected nothing due on the second run, got %+v", result)
+	}
+}
+
+func TestSendDueDigests_EmptyAndFailures(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	mailer := &recordingMailer{}
+	ds := NewDigestService(db, mailer, "https://reader.example.com")
+	if _, err := ds.UpdatePreferences(user.ID, database.DigestPreferences{Frequency: DigestDaily}); err != nil {
+		t.Fatalf("UpdatePreferences: %v", err)
+	}
+
+	result, err := ds.SendDueDigests(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("SendDueDigests: %v", err)
+	}
+	if result.Empty != 1 || len(mailer.sent) != 0 {
+		t.Errorf("expected an empty digest and no mail, got %+v", result)
+	}
+	got, err := db.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.DigestLastSentAt.IsZero() {
+		t.Error("expected empty digests to be recorded")
+	}
+
+	t.Run("mail failure is retried", func(t *testing.T) {
+		feed := createTestFeed(t, db)
+		if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+		addSyncTestArticle(t, db, feed.ID, "pending")
+		if err := db.SetUserDigestSentAt(user.ID, time.Time{}); err != nil {
+			t.Fatalf("SetUserDigestSentAt: %v", err)
+		}
+
+		mailer.err = errors.New("connection refused")
+		result, err := ds.SendDueDigests(context.Background(), time.Now())
+		if err != nil {
+			t.Fatalf("SendDueDigests: %v", err)
+		}
+		if result.Failed != 1 {
+			t.Errorf("expected a failed digest, got %+v", result)
+		}
+		got, err := db.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if !got.DigestLastSentAt.IsZero() {
+			t.Error("a failed digest should be retried on the next run")
+		}
+	})
+
+	t.Run("no mailer", func(t *testing.T) {
+		if _, err := NewDigestService(db, nil, "").SendDueDigests(context.Background(), time.Now()); !errors.Is(err, ErrMailerNotConfigured) {
+			t.Errorf("expected ErrMailerNotConfigured, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// ErrInvalidCategory indicates a directory category failed validation.
+var ErrInvalidCategory = errors.New("invalid category")
+
+// DefaultDirectoryMinSubscribers is how many subscribers a feed needs before
+// it is listed or recommended, unless configured otherwise. Anything lower
+// would let a listing reveal what one or two people read.
+const DefaultDirectoryMinSubscribers = 3
+
+const (
+	maxCategoryLength         = 40
+	maxDirectoryPageSize      = 100
+	maxRecommendationsPerFeed = 10
+	maxRecommendations        = 50
+
+	// maxCoSubscriptionFeeds leaves users with more listed feeds than this
+	// out of recommendations: they pair every feed with every other and
+	// carry little signal about which feeds go together.
+	maxCoSubscriptionFeeds = 300
+)
+
+// Recommendation reasons.
+const (
+	RecommendationSimilar = "similar" // followed by people who follow Because
+	RecommendationPopular = "popular" // among the directory's most followed
+)
+
+// DirectoryService lists popular feeds and recommends feeds to users from
+// aggregate subscription counts. The counts are computed by UpdateDirectory,
+// a periodic job, and only feeds and feed pairs with at least minSubscribers
+// subscribers are ever stored, so nothing read from the directory can be
+// traced back to an individual user.
+type DirectoryService struct {
+	db             database.Database
+	minSubscribers int
+}
+
+// NewDirectoryService returns a service listing feeds with at least
+// minSubscribers subscribers; values below 1 use the default.
+func NewDirectoryService(db database.Database, minSubscribers int) *DirectoryService {
+	if minSubscribers < 1 {
+		minSubscribers = DefaultDirectoryMinSubscribers
+	}
+	return &DirectoryService{db: db, minSubscribers: minSubscribers}
+}
+
+// DirectoryQuery filters a directory listing. Every word in Search must
+// appear in a feed's title, description or URL; Category matches
+// case-insensitively.
+type DirectoryQuery struct {
+	Search   string
+	Category string
+	Limit    int
+	Offset   int
+}
+
+// DirectoryListing is a directory feed as shown to one user.
+type DirectoryListing struct {
+	database.DirectoryFeed
+	Subscribed bool `json:"subscribed"`
+}
+
+// DirectoryCategory is a category with the number of feeds listed in it.
+type DirectoryCategory struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// DirectoryPage is one page of a directory listing. Total counts every
+// match, and Categories covers the whole directory so it can be used for
+// navigation whatever the filter.
+type DirectoryPage struct {
+	Feeds      []DirectoryListing  `json:"feeds"`
+	Total      int                 `json:"total"`
+	Categories []DirectoryCategory `json:"categories"`
+	UpdatedAt  *time.Time          `json:"updated_at,omitempty"`
+}
+
+// ListDirectory returns the feeds matching q, most subscribed first, marking
+// those userID already follows.
+func (ds *DirectoryService) ListDirectory(userID int, q DirectoryQuery) (*DirectoryPage, error) {
+	if q.Limit <= 0 || q.Limit > maxDirectoryPageSize {
+		q.Limit = maxDirectoryPageSize
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	feeds, err := ds.db.GetDirectoryFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	subscribed, err := ds.subscribedFeedIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &DirectoryPage{Feeds: []DirectoryListing{}, Categories: directoryCategories(feeds)}
+	if len(feeds) > 0 {
+		updatedAt := feeds[0].UpdatedAt
+		page.UpdatedAt = &updatedAt
+	}
+
+	terms := strings.Fields(strings.ToLower(q.Search))
+	category := strings.TrimSpace(q.Category)
+	for _, f := range feeds {
+		if category != "" && !strings.EqualFold(f.Category, category) {
+			continue
+		}
+		if !matchesDirectorySearch(f, terms) {
+			continue
+		}
+		if page.Total >= q.Offset && len(page.Feeds) < q.Limit {
+			page.Feeds = append(page.Feeds, DirectoryListing{DirectoryFeed: f, Subscribed: subscribed[f.FeedID]})
+		}
+		page.Total++
+	}
+	return page, nil
+}
+
+func matchesDirectorySearch(f database.DirectoryFeed, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	text := strings.ToLower(f.Title + "\n" + f.Description + "\n" + f.URL)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// directoryCategories counts feeds per category, most populated first.
+// Categories differing only in case are merged under the first spelling seen.
+func directoryCategories(feeds []database.DirectoryFeed) []DirectoryCategory {
+	index := make(map[string]int)
+	categories := []DirectoryCategory{}
+	for _, f := range feeds {
+		if f.Category == "" {
+			continue
+		}
+		key := strings.ToLower(f.Category)
+		if i, ok := index[key]; ok {
+			categories[i].Count++
+			continue
+		}
+		index[key] = len(categories)
+		categories = append(categories, DirectoryCategory{Name: f.Category, Count: 1})
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		if categories[i].Count != categories[j].Count {
+			return categories[i].Count > categories[j].Count
+		}
+		return strings.ToLower(categories[i].Name) < strings.ToLower(categories[j].Name)
+	})
+	return categories
+}
+
+// Recommendation is a directory feed suggested to a user. Because lists the
+// user's own feeds that led to a similar recommendation.
+type Recommendation struct {
+	database.DirectoryFeed
+	Reason  string   `json:"reason"`
+	Because []string `json:"because,omitempty"`
+}
+
+// RecommendationsForUser suggests up to limit feeds userID doesn't follow:
+// first those most often followed alongside the user's feeds, then, to fill
+// the list, the directory's most popular feeds.
+func (ds *DirectoryService) RecommendationsForUser(userID, limit int) ([]Recommendation, error) {
+	if limit <= 0 || limit > maxRecommendations {
+		limit = maxRecommendations
+	}
+
+	userFeeds, err := ds.db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	directory, err := ds.db.GetDirectoryFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	subscribed := make(map[int]string, len(userFeeds))
+	feedIDs := make([]int, len(userFeeds))
+	for i, f := range userFeeds {
+		subscribed[f.ID] = f.Title
+		feedIDs[i] = f.ID
+	}
+	listed := make(map[int]database.DirectoryFeed, len(directory))
+	for _, f := range directory {
+		listed[f.FeedID] = f
+	}
+
+	pairs, err := ds.db.GetFeedRecommendations(feedIDs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	// A feed related to several of the user's feeds ranks by the sum of
+	// its scores.
+	type candidate struct {
+		feed    database.DirectoryFeed
+		score   float64
+		because []string
+	}
+	candidates := make(map[int]*candidate)
+	for _, p := range pairs {
+		if _, ok := subscribed[p.RecommendedFeedID]; ok {
+			continue
+		}
+		feed, ok := listed[p.RecommendedFeedID]
+		if !ok {
+			continue
+		}
+		c := candidates[p.RecommendedFeedID]
+		if c == nil {
+			c = &candidate{feed: feed}
+			candidates[p.RecommendedFeedID] = c
+		}
+		c.score += p.Score
+		c.because = append(c.because, subscribed[p.FeedID])
+	}
+
+	ranked := make([]*candidate, 0, len(candidates))
+	for _, c := range candidates {
+		ranked = append(ranked, c)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		if ranked[i].feed.Subscribers != ranked[j].feed.Subscribers {
+			return ranked[i].feed.Subscribers > ranked[j].feed.Subscribers
+		}
+		return ranked[i].feed.FeedID < ranked[j].feed.FeedID
+	})
+
+	recommendations := []Recommendation{}
+	for _, c := range ranked {
+		if len(recommendations) == limit {
+			return recommendations, nil
+		}
+		sort.Strings(c.because)
+		recommendations = append(recommendations, Recommendation{
+			DirectoryFeed: c.feed,
+			Reason:        RecommendationSimilar,
+			Because:       c.because,
+		})
+	}
+
+	// The directory is already sorted by popularity
+	for _, f := range directory {
+		if len(recommendations) == limit {
+			break
+		}
+		if _, ok := subscribed[f.FeedID]; ok || candidates[f.FeedID] != nil {
+			continue
+		}
+		recommendations = append(recommendations, Recommendation{DirectoryFeed: f, Reason: RecommendationPopular})
+	}
+	return recommendations, nil
+}
+
+func (ds *DirectoryService) subscribedFeedIDs(userID int) (map[int]bool, error) {
+	feeds, err := ds.db.GetUserFeeds(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	ids := make(map[int]bool, len(feeds))
+	for _, f := range feeds {
+		ids[f.ID] = true
+	}
+	return ids, nil
+}
+
+// SetFeedCategory files a feed under category in the directory, or removes
+// its category when category is empty.
+func (ds *DirectoryService) SetFeedCategory(feedID int, category string) (string, error) {
+	category = strings.Join(strings.Fields(category), " ")
+	if utf8.RuneCountInString(category) > maxCategoryLength {
+		return "", fmt.Errorf("%w: must be at most %d characters", ErrInvalidCategory, maxCategoryLength)
+	}
+	if err := ds.db.SetFeedCategory(feedID, category); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return category, nil
+}
+
+// DirectoryRunResult summarizes one UpdateDirectory run.
+type DirectoryRunResult struct {
+	Subscriptions   int `json:"subscriptions"`
+	Feeds           int `json:"feeds"`           // feeds listed in the directory
+	Recommendations int `json:"recommendations"` // feed pairs stored, counting each direction
+}
+
+// UpdateDirectory rebuilds the directory and recommendations from every
+// user's subscriptions. It is meant to run daily.
+func (ds *DirectoryService) UpdateDirectory(ctx context.Context, now time.Time) (*DirectoryRunResult, error) {
+	subscriptions, err := ds.db.GetAllFeedSubscriptions()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	feeds, err := ds.db.GetAllUserFeeds()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	categories, err := ds.db.GetFeedCategories()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	// Only public web feeds can be listed: newsletter and scraped feeds
+	// have synthetic URLs that may be private to their subscribers.
+	listable := make(map[int]database.Feed, len(feeds))
+	for _, f := range feeds {
+		if isListableFeedURL(f.URL) {
+			listable[f.ID] = f
+		}
+	}
+
+	subscribers := make(map[int]int)
+	for _, s := range subscriptions {
+		if _, ok := listable[s.FeedID]; ok {
+			subscribers[s.FeedID]++
+		}
+	}
+
+	directory := []database.DirectoryFeed{}
+	for id, count := range subscribers {
+		if count < ds.minSubscribers {
+			continue
+		}
+		f := listable[id]
+		title := f.Title
+		if title == "" {
+			title = f.URL
+		}
+		directory = append(directory, database.DirectoryFeed{
+			FeedID:      id,
+			Title:       title,
+			URL:         f.URL,
+			Description: f.Description,
+			Category:    categories[id],
+			Subscribers: count,
+			UpdatedAt:   now,
+		})
+	}
+	sort.Slice(directory, func(i, j int) bool { return directory[i].FeedID < directory[j].FeedID })
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	recommendations := ds.coSubscriptionRecommendations(subscriptions, subscribers)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ds.db.ReplaceFeedDirectory(directory, recommendations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &DirectoryRunResult{
+		Subscriptions:   len(subscriptions),
+		Feeds:           len(directory),
+		Recommendations: len(recommendations),
+	}, nil
+}
+
+// coSubscriptionRecommendations pairs listed feeds followed by the same
+// users. A pair needs minSubscribers users in common to be kept, and is
+// scored by cosine similarity so that two huge feeds aren't related just
+// because everyone follows both.
+func (ds *DirectoryService) coSubscriptionRecommendations(subscriptions []database.FeedSubscription, subscribers map[int]int) []database.FeedRecommendation {
+	byUser := make(map[int][]int)
+	for _, s := range subscriptions {
+		if subscribers[s.FeedID] >= ds.minSubscribers {
+			byUser[s.UserID] = append(byUser[s.UserID], s.FeedID)
+		}
+	}
+
+	shared := make(map[[2]int]int)
+	for _, feedIDs := range byUser {
+		if len(feedIDs) < 2 || len(feedIDs) > maxCoSubscriptionFeeds {
+			continue
+		}
+		sort.Ints(feedIDs)
+		for i := range feedIDs {
+			for j := i + 1; j < len(feedIDs); j++ {
+				shared[[2]int{feedIDs[i], feedIDs[j]}]++
+			}
+		}
+	}
+
+	byFeed := make(map[int][]database.FeedRecommendation)
+	for pair, count := range shared {
+		if count < ds.minSubscribers {
+			continue
+		}
+		score := float64(count) / math.Sqrt(float64(subscribers[pair[0]])*float64(subscribers[pair[1]]))
+		byFeed[pair[0]] = append(byFeed[pair[0]], database.FeedRecommendation{
+			FeedID: pair[0], RecommendedFeedID: pair[1], SharedSubscribers: count, Score: score,
+		})
+		byFeed[pair[1]] = append(byFeed[pair[1]], database.FeedRecommendation{
+			FeedID: pair[1], RecommendedFeedID: pair[0], SharedSubscribers: count, Score: score,
+		})
+	}
+
+	feedIDs := make([]int, 0, len(byFeed))
+	for id := range byFeed {
+		feedIDs = append(feedIDs, id)
+	}
+	sort.Ints(feedIDs)
+
+	var recommendations []database.FeedRecommendation
+	for _, id := range feedIDs {
+		recs := byFeed[id]
+		sort.Slice(recs, func(i, j int) bool {
+			if recs[i].Score != recs[j].Score {
+				return recs[i].Score > recs[j].Score
+			}
+			if recs[i].SharedSubscribers != recs[j].SharedSubscribers {
+				return recs[i].SharedSubscribers > recs[j].SharedSubscribers
+			}
+			return recs[i].RecommendedFeedID < recs[j].RecommendedFeedID
+		})
+		if len(recs) > maxRecommendationsPerFeed {
+			recs = recs[:maxRecommendationsPerFeed]
+		}
+		recommendations = append(recommendations, recs...)
+	}
+	return recommendations
+}
+
+func isListableFeedURL(feedURL string) bool {
+	lower := strings.ToLower(feedURL)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestDirectoryService(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	addFeed := func(title, url string) *database.Feed {
+		feed := &database.Feed{Title: title, URL: url, Description: title + " posts", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := db.AddFeed(feed); err != nil {
+			t.Fatalf("AddFeed: %v", err)
+		}
+		return feed
+	}
+	golang := addFeed("Go Blog", "https://go.dev/blog/feed.atom")
+	rust := addFeed("Rust Blog", "https://blog.rust-lang.org/feed.xml")
+	cooking := addFeed("Cooking", "https://example.com/cooking.xml")
+	rare := addFeed("Rarely Read", "https://example.com/rare.xml")
+	newsletter := addFeed("Newsletter", inboundFeedURLPrefix+"abc123@in.example.com")
+
+	users := make([]*database.User, 5)
+	for i := range users {
+		users[i] = createTestUserWithEmail(t, db, "directory"+string(rune('a'+i)))
+	}
+	subscribe := func(user *database.User, feeds ...*database.Feed) {
+		for _, f := range feeds {
+			if err := db.SubscribeUserToFeed(user.ID, f.ID); err != nil {
+				t.Fatalf("SubscribeUserToFeed: %v", err)
+			}
+		}
+	}
+	subscribe(users[0], golang, rust, newsletter)
+	subscribe(users[1], golang, rust, newsletter)
+	subscribe(users[2], golang, cooking)
+	subscribe(users[3], cooking, rare)
+
+	ds := NewDirectoryService(db, 2)
+	now := time.Now().Truncate(time.Second)
+	result, err := ds.UpdateDirectory(t.Context(), now)
+	if err != nil {
+		t.Fatalf("UpdateDirectory: %v", err)
+	}
+	// Rare has one subscriber, and newsletter feeds are never listed
+	if result.Subscriptions != 10 || result.Feeds != 3 || result.Recommendations != 2 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if _, err := ds.SetFeedCategory(golang.ID, "  Programming  "); err != nil {
+		t.Fatalf("SetFeedCategory: %v", err)
+	}
+	if _, err := ds.SetFeedCategory(rust.ID, "programming"); err != nil {
+		t.Fatalf("SetFeedCategory: %v", err)
+	}
+	if _, err := ds.SetFeedCategory(rust.ID, strings.Repeat("x", maxCategoryLength+1)); !errors.Is(err, ErrInvalidCategory) {
+		t.Errorf("expected ErrInvalidCategory, got %v", err)
+	}
+
+	newcomer := users[4]
+	subscribe(newcomer, golang)
+
+	t.Run("listing", func(t *testing.T) {
+		page, err := ds.ListDirectory(newcomer.ID, DirectoryQuery{})
+		if err != nil {
+			t.Fatalf("ListDirectory: %v", err)
+		}
+		if page.Total != 3 || len(page.Feeds) != 3 || page.Feeds[0].FeedID != golang.ID {
+			t.Fatalf("expected 3 feeds, most subscribed first, got %+v", page)
+		}
+		if !page.Feeds[0].Subscribed || page.Feeds[1].Subscribed || page.Feeds[0].Subscribers != 3 {
+			t.Errorf("unexpected listing: %+v", page.Feeds)
+		}
+		if page.UpdatedAt == nil || !page.UpdatedAt.Equal(now) {
+			t.Errorf("expected the job time, got %v", page.UpdatedAt)
+		}
+		if len(page.Categories) != 1 || page.Categories[0].Name != "Programming" || page.Categories[0].Count != 2 {
+			t.Errorf("expected categories to merge case-insensitively, got %+v", page.Categories)
+		}
+
+		page, _ = ds.ListDirectory(newcomer.ID, DirectoryQuery{Search: "RUST blog"})
+		if page.Total != 1 || page.Feeds[0].FeedID != rust.ID {
+			t.Errorf("expected a search for rust to find the Rust blog, got %+v", page.Feeds)
+		}
+		page, _ = ds.ListDirectory(newcomer.ID, DirectoryQuery{Category: "PROGRAMMING"})
+		if page.Total != 2 {
+			t.Errorf("expected 2 programming feeds, got %+v", page.Feeds)
+		}
+		page, _ = ds.ListDirectory(newcomer.ID, DirectoryQuery{Limit: 1, Offset: 1})
+		if page.Total != 3 || len(page.Feeds) != 1 || page.Feeds[0].FeedID == golang.ID {
+			t.Errorf("unexpected second page: %+v", page)
+		}
+	})
+
+	t.Run("recommendations", func(t *testing.T) {
+		recs, err := ds.RecommendationsForUser(newcomer.ID, 10)
+		if err != nil {
+			t.Fatalf("RecommendationsForUser: %v", err)
+		}
+		if len(recs) != 2 {
+			t.Fatalf("expected 2 recommendations, got %+v", recs)
+		}
+		if recs[0].FeedID != rust.ID || recs[0].Reason != RecommendationSimilar ||
+			len(recs[0].Because) != 1 || recs[0].Because[0] != "Go Blog" {
+			t.Errorf("expected the Rust blog because of the Go blog, got %+v", recs[0])
+		}
+		if recs[1].FeedID != cooking.ID || recs[1].Reason != RecommendationPopular {
+			t.Errorf("expected cooking to fill in as a popular feed, got %+v", recs[1])
+		}
+
+		// Cooking and rare have only one follower in common, which isn't
+		// enough to pair them, so this user only gets popular feeds.
+		recs, _ = ds.RecommendationsForUser(users[3].ID, 1)
+		if len(recs) != 1 || recs[0].FeedID != golang.ID || recs[0].Reason != RecommendationPopular {
+			t.Errorf("expected the most popular feed, got %+v", recs)
+		}
+	})
+}
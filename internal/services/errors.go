@@ -28,6 +28,15 @@ var (
 	// ErrFeedNotModified indicates the feed has not changed since the last fetch (HTTP 304)
 	ErrFeedNotModified = errors.New("feed not modified")
 
+	// ErrInvalidProgress indicates a read-progress value outside 0-100
+	ErrInvalidProgress = errors.New("invalid read progress")
+
+	// ErrInvalidSelector indicates an HTML feed's CSS selector is missing or can't be parsed
+	ErrInvalidSelector = errors.New("invalid selector")
+
+	// ErrNoScrapedItems indicates an HTML feed's item selector matched nothing usable on the page
+	ErrNoScrapedItems = errors.New("no items found on page")
+
 	// Existing subscription-related errors (already defined elsewhere, documented here for reference)
 	// ErrFeedLimitReached - user has reached their feed limit
 	// ErrTrialExpired - user's trial has expired
@@ -53,6 +62,8 @@ const (
 	ErrorCodeLimitReached      = "limit_reached"
 	ErrorCodeTrialExpired      = "trial_expired"
 	ErrorCodeAlreadySubscribed = "already_subscribed"
+	ErrorCodeInvalidSelector   = "invalid_selector"
+	ErrorCodeNoScrapedItems    = "no_items_found"
 )
 
 // GetErrorDetails extracts user-friendly error information from an error
@@ -98,6 +109,18 @@ func GetErrorDetails(err error) ErrorDetails {
 			ErrorCode: ErrorCodeSSRFBlocked,
 			Message:   "This URL cannot be accessed for security reasons. Please use a publicly accessible URL.",
 		}
+	case errors.Is(err, ErrInvalidSelector):
+		return ErrorDetails{
+			ErrorCode: ErrorCodeInvalidSelector,
+			Message:   "One of the CSS selectors isn't valid. Selectors can use tags, #ids, .classes, [attributes] and the descendant and child combinators.",
+			Details:   err.Error(),
+		}
+	case errors.Is(err, ErrNoScrapedItems):
+		return ErrorDetails{
+			ErrorCode: ErrorCodeNoScrapedItems,
+			Message:   "The item selector didn't match any entries with a link on this page. Check the selectors with a preview and try again.",
+			Details:   err.Error(),
+		}
 	case errors.Is(err, ErrDatabaseError):
 		return ErrorDetails{
 			ErrorCode: ErrorCodeDatabaseError,
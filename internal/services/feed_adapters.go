@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FeedAdapter maps the pages of a platform that doesn't link its feeds from
+// the page (or links them in a way discovery can't find) to the feeds it
+// publishes, so users can paste the page they're looking at.
+type FeedAdapter interface {
+	// Name identifies the adapter in logs.
+	Name() string
+
+	// FeedURLs returns the feeds for the page at u, best first, or nil if u
+	// isn't one of the adapter's pages. Adapters that need a lookup make it
+	// with client, which is the discovery's SSRF-safe client.
+	FeedURLs(ctx context.Context, u *url.URL, client HTTPClient) ([]string, error)
+}
+
+// SyntheticFeedAdapter is implemented by adapters whose feed URLs return
+// something other than RSS or Atom, such as a JSON API. fetchFeed fetches
+// those URLs like any other feed and hands the body to ParseFeed; titles and
+// HTML in the result are sanitised afterwards.
+type SyntheticFeedAdapter interface {
+	FeedAdapter
+	HandlesFeedURL(feedURL string) bool
+	ParseFeed(body []byte, feedURL string) (*FeedData, error)
+}
+
+var (
+	feedAdaptersMu sync.RWMutex
+	feedAdapters   = []FeedAdapter{
+		youTubeAdapter{},
+		redditAdapter{},
+		gitHubAdapter{},
+		blueskyAdapter{},
+		hackerNewsAdapter{},
+	}
+)
+
+// RegisterFeedAdapter adds an adapter ahead of the built-in ones, so it can
+// also take over pages they would handle. Call it during startup.
+func RegisterFeedAdapter(a FeedAdapter) {
+	feedAdaptersMu.Lock()
+	defer feedAdaptersMu.Unlock()
+	feedAdapters = append([]FeedAdapter{a}, feedAdapters...)
+}
+
+func registeredFeedAdapters() []FeedAdapter {
+	feedAdaptersMu.RLock()
+	defer feedAdaptersMu.RUnlock()
+	return feedAdapters
+}
+
+// syntheticFeedAdapterFor returns the adapter that parses feedURL, or nil for
+// ordinary feeds.
+func syntheticFeedAdapterFor(feedURL string) SyntheticFeedAdapter {
+	for _, a := range registeredFeedAdapters() {
+		if s, ok := a.(SyntheticFeedAdapter); ok && s.HandlesFeedURL(feedURL) {
+			return s
+		}
+	}
+	return nil
+}
+
+// tryFeedAdapters returns the feeds the first matching adapter finds for
+// pageURL. An adapter that fails is logged and skipped so ordinary discovery
+// still gets a chance.
+func (fd *FeedDiscovery) tryFeedAdapters(ctx context.Context, pageURL string) []string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+	for _, a := range registeredFeedAdapters() {
+		feeds, err := a.FeedURLs(ctx, u, fd.client)
+		if err != nil {
+			log.Printf("Feed adapter %s failed for %s: %v", a.Name(), pageURL, err)
+			continue
+		}
+		if len(feeds) > 0 {
+			return feeds
+		}
+	}
+	return nil
+}
+
+// platformHost returns u's host in lower case without the "www." style
+// prefixes platforms serve the same pages under.
+func platformHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range []string{"www.", "m.", "old.", "new."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	return host
+}
+
+// pathSegments splits u's path into its non-empty segments.
+func pathSegments(u *url.URL) []string {
+	var segments []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// adapterGet fetches a lookup URL for an adapter, reading at most limit bytes.
+func adapterGet(ctx context.Context, client HTTPClient, rawURL string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; GoRead/2.0)")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned HTTP %d", rawURL, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// youTubeAdapter maps channel, handle, user and playlist pages to YouTube's
+// Atom feeds. Handle (@name) and custom (/c/name) URLs don't contain the
+// channel ID the feed needs, so the page is fetched to find it.
+type youTubeAdapter struct{}
+
+const youTubeFeedBase = "https://www.youtube.com/feeds/videos.xml"
+
+var (
+	youTubeChannelIDPattern = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+	youTubeChannelIDInPage  = []*regexp.Regexp{
+		regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[A-Za-z0-9_-]{22})"`),
+		regexp.MustCompile(`"(?:externalId|channelId)":"(UC[A-Za-z0-9_-]{22})"`),
+	}
+)
+
+func (youTubeAdapter) Name() string { return "youtube" }
+
+func (youTubeAdapter) FeedURLs(ctx context.Context, u *url.URL, client HTTPClient) ([]string, error) {
+	if platformHost(u) != "youtube.com" {
+		return nil, nil
+	}
+	segments := pathSegments(u)
+	if len(segments) == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case segments[0] == "channel" && len(segments) > 1 && youTubeChannelIDPattern.MatchString(segments[1]):
+		return []string{youTubeFeedBase + "?channel_id=" + segments[1]}, nil
+	case segments[0] == "playlist" && u.Query().Get("list") != "":
+		return []string{youTubeFeedBase + "?playlist_id=" + url.QueryEscape(u.Query().Get("list"))}, nil
+	case segments[0] == "user" && len(segments) > 1:
+		return []string{youTubeFeedBase + "?user=" + url.QueryEscape(segments[1])}, nil
+	case strings.HasPrefix(segments[0], "@") && len(segments[0]) > 1,
+		segments[0] == "c" && len(segments) > 1:
+		pagePath := "/" + segments[0]
+		if segments[0] == "c" {
+			pagePath += "/" + segments[1]
+		}
+		page, err := adapterGet(ctx, client, "https://www.youtube.com"+pagePath, 2<<20)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range youTubeChannelIDInPage {
+			if m := pattern.FindSubmatch(page); m != nil {
+				return []string{youTubeFeedBase + "?channel_id=" + string(m[1])}, nil
+			}
+		}
+		return nil, fmt.Errorf("no channel ID on %s", pagePath)
+	}
+	return nil, nil
+}
+
+// redditAdapter maps subreddit, user, multireddit and comment pages to
+// Reddit's Atom feeds, which live at the page path plus "/.rss".
+type redditAdapter struct{}
+
+func (redditAdapter) Name() string { return "reddit" }
+
+func (redditAdapter) FeedURLs(_ context.Context, u *url.URL, _ HTTPClient) ([]string, error) {
+	if platformHost(u) != "reddit.com" {
+		return nil, nil
+	}
+	segments := pathSegments(u)
+	if len(segments) > 0 && strings.HasSuffix(segments[len(segments)-1], ".rss") {
+		return nil, nil
+	}
+	if len(segments) > 0 {
+		switch segments[0] {
+		case "r", "user":
+		case "u":
+			segments[0] = "user"
+		default:
+			return nil, nil
+		}
+		if len(segments) < 2 {
+			return nil, nil
+		}
+	}
+
+	feedURL := "https://www.reddit.com/" + strings.Join(segments, "/")
+	if len(segments) > 0 {
+		feedURL += "/"
+	}
+	feedURL += ".rss"
+	// Sort options such as ?t=week apply to the feed too
+	if u.RawQuery != "" {
+		feedURL += "?" + u.RawQuery
+	}
+	return []string{feedURL}, nil
+}
+
+// gitHubAdapter maps user, repository, release, tag and commit pages to
+// GitHub's Atom feeds. A repository offers its releases first, then its
+// commits and tags.
+type gitHubAdapter struct{}
+
+// gitHubReservedPaths are top-level GitHub pages that aren't users.
+var gitHubReservedPaths = map[string]bool{
+	"about": true, "collections": true, "contact": true, "enterprise": true, "explore": true,
+	"features": true, "login": true, "marketplace": true, "new": true, "notifications": true,
+	"orgs": true, "pricing": true, "pulls": true, "issues": true, "search": true,
+	"settings": true, "signup": true, "sponsors": true, "topics": true, "trending": true,
+}
+
+func (gitHubAdapter) Name() string { return "github" }
+
+func (gitHubAdapter) FeedURLs(_ context.Context, u *url.URL, _ HTTPClient) ([]string, error) {
+	if platformHost(u) != "github.com" {
+		return nil, nil
+	}
+	segments := pathSegments(u)
+	if len(segments) == 0 || gitHubReservedPaths[strings.ToLower(segments[0])] ||
+		strings.HasSuffix(segments[len(segments)-1], ".atom") {
+		return nil, nil
+	}
+
+	if len(segments) == 1 {
+		return []string{"https://github.com/" + segments[0] + ".atom"}, nil
+	}
+
+	repo := "https://github.com/" + segments[0] + "/" + strings.TrimSuffix(segments[1], ".git")
+	if len(segments) == 2 {
+		return []string{repo + "/releases.atom", repo + "/commits.atom", repo + "/tags.atom"}, nil
+	}
+	switch segments[2] {
+	case "releases":
+		return []string{repo + "/releases.atom"}, nil
+	case "tags":
+		return []string{repo + "/tags.atom"}, nil
+	case "commits", "tree":
+		if len(segments) > 3 {
+			return []string{repo + "/commits/" + strings.Join(segments[3:], "/") + ".atom"}, nil
+		}
+		return []string{repo + "/commits.atom"}, nil
+	}
+	return nil, nil
+}
+
+// blueskyAdapter maps profile pages to the profile's RSS feed. Handles are
+// resolved to the account's DID first so the feed survives a handle change;
+// if that fails the handle is used as is.
+type blueskyAdapter struct{}
+
+const blueskyResolveHandleURL = "https://public.api.bsky.app/xrpc/com.atproto.identity.resolveHandle?handle="
+
+func (blueskyAdapter) Name() string { return "bluesky" }
+
+func (blueskyAdapter) FeedURLs(ctx context.Context, u *url.URL, client HTTPClient) ([]string, error) {
+	if platformHost(u) != "bsky.app" {
+		return nil, nil
+	}
+	segments := pathSegments(u)
+	if len(segments) < 2 || segments[0] != "profile" || (len(segments) > 2 && segments[2] == "rss") {
+		return nil, nil
+	}
+
+	actor := segments[1]
+	if !strings.HasPrefix(actor, "did:") {
+		if body, err := adapterGet(ctx, client, blueskyResolveHandleURL+url.QueryEscape(actor), 64<<10); err == nil {
+			var resolved struct {
+				DID string `json:"did"`
+			}
+			if json.Unmarshal(body, &resolved) == nil && strings.HasPrefix(resolved.DID, "did:") {
+				actor = resolved.DID
+			}
+		} else {
+			log.Printf("Failed to resolve Bluesky handle %s: %v", actor, err)
+		}
+	}
+	return []string{"https://bsky.app/profile/" + actor + "/rss"}, nil
+}
+
+// hackerNewsAdapter maps the Hacker News front page to its RSS feed. Other
+// listings have no feed, so they map to synthetic feeds built from the
+// Algolia search API, which returns the same listings as JSON.
+type hackerNewsAdapter struct{}
+
+const (
+	hackerNewsSearchURL = "https://hn.algolia.com/api/v1/search_by_date"
+	hackerNewsItemURL   = "https://news.ycombinator.com/item?id="
+)
+
+var (
+	hackerNewsUserPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+	hackerNewsIDPattern   = regexp.MustCompile(`^[0-9]{1,12}$`)
+
+	// hackerNewsListings maps listing paths to their Algolia tags and titles.
+	hackerNewsListings = map[string]struct{ tags, title string }{
+		"newest":   {"story", "New"},
+		"show":     {"show_hn", "Show HN"},
+		"shownew":  {"show_hn", "Show HN"},
+		"ask":      {"ask_hn", "Ask HN"},
+		"jobs":     {"job", "Jobs"},
+		"polls":    {"poll", "Polls"},
+		"comments": {"comment", "Comments"},
+	}
+)
+
+func (hackerNewsAdapter) Name() string { return "hackernews" }
+
+func (hackerNewsAdapter) FeedURLs(_ context.Context, u *url.URL, _ HTTPClient) ([]string, error) {
+	if platformHost(u) != "news.ycombinator.com" {
+		return nil, nil
+	}
+	path := strings.Trim(u.Path, "/")
+	id := u.Query().Get("id")
+
+	switch {
+	case path == "" || path == "news" || path == "front":
+		return []string{"https://news.ycombinator.com/rss"}, nil
+	case (path == "user" || path == "submitted") && hackerNewsUserPattern.MatchString(id):
+		return []string{hackerNewsSearchFeedURL("story,author_" + id)}, nil
+	case path == "item" && hackerNewsIDPattern.MatchString(id):
+		return []string{hackerNewsSearchFeedURL("comment,story_" + id)}, nil
+	}
+	if listing, ok := hackerNewsListings[path]; ok {
+		return []string{hackerNewsSearchFeedURL(listing.tags)}, nil
+	}
+	return nil, nil
+}
+
+func hackerNewsSearchFeedURL(tags string) string {
+	return hackerNewsSearchURL + "?" + url.Values{"tags": {tags}, "hitsPerPage": {"30"}}.Encode()
+}
+
+func (hackerNewsAdapter) HandlesFeedURL(feedURL string) bool {
+	return strings.HasPrefix(feedURL, hackerNewsSearchURL+"?")
+}
+
+// hackerNewsHit is one result from the Algolia search API.
+type hackerNewsHit struct {
+	ObjectID    string    `json:"objectID"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Author      string    `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+	StoryText   string    `json:"story_text"`
+	CommentText string    `json:"comment_text"`
+	StoryID     int       `json:"story_id"`
+	StoryTitle  string    `json:"story_title"`
+	Points      int       `json:"points"`
+	NumComments int       `json:"num_comments"`
+}
+
+func (hackerNewsAdapter) ParseFeed(body []byte, feedURL string) (*FeedData, error) {
+	var result struct {
+		Hits []hackerNewsHit `json:"hits"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("invalid Hacker News search response: %w", err)
+	}
+
+	tags := ""
+	if u, err := url.Parse(feedURL); err == nil {
+		tags = u.Query().Get("tags")
+	}
+
+	articles := make([]ArticleData, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		discussion := hackerNewsItemURL + hit.ObjectID
+		article := ArticleData{
+			Author:      hit.Author,
+			PublishedAt: hit.CreatedAt,
+		}
+
+		if hit.CommentText != "" {
+			article.Title = fmt.Sprintf("%s on %s", hit.Author, hit.StoryTitle)
+			article.Link = discussion
+			article.Content = hit.CommentText
+		} else {
+			article.Title = hit.Title
+			article.Link = hit.URL
+			if article.Link == "" {
+				article.Link = discussion
+			}
+			article.Content = hit.StoryText + fmt.Sprintf(`<p>%d points, <a href="%s">%d comments</a></p>`,
+				hit.Points, html.EscapeString(discussion), hit.NumComments)
+		}
+		article.Description = article.Content
+		articles = append(articles, article)
+	}
+
+	return &FeedData{
+		Title:       hackerNewsFeedTitle(tags, result.Hits),
+		Description: "Hacker News",
+		Articles:    articles,
+	}, nil
+}
+
+func hackerNewsFeedTitle(tags string, hits []hackerNewsHit) string {
+	for _, tag := range strings.Split(tags, ",") {
+		switch {
+		case strings.HasPrefix(tag, "author_"):
+			return "Hacker News: " + strings.TrimPrefix(tag, "author_") + "'s submissions"
+		case strings.HasPrefix(tag, "story_"):
+			if len(hits) > 0 && hits[0].StoryTitle != "" {
+				return "Hacker News: comments on " + hits[0].StoryTitle
+			}
+			return "Hacker News: comments on item " + strings.TrimPrefix(tag, "story_")
+		}
+	}
+	for _, listing := range hackerNewsListings {
+		if listing.tags == tags {
+			return "Hacker News: " + listing.title
+		}
+	}
+	return "Hacker News"
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtureHTTPClient serves fixtures by exact URL and 404s everything else,
+// standing in for the platforms the adapters talk to.
+type fixtureHTTPClient struct {
+	t        *testing.T
+	fixtures map[string]string
+}
+
+func (c *fixtureHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	name, ok := c.fixtures[req.URL.String()]
+	if !ok {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("not found")),
+			Request:    req,
+		}, nil
+	}
+
+	contentType := "application/xml"
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		contentType = "application/json"
+	case strings.HasSuffix(name, ".html"):
+		contentType = "text/html; charset=utf-8"
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {contentType}},
+		Body:       io.NopCloser(bytes.NewReader(readFixture(c.t, name))),
+		Request:    req,
+	}, nil
+}
+
+func TestFeedAdapterDiscovery(t *testing.T) {
+	client := &fixtureHTTPClient{t: t, fixtures: map[string]string{
+		"https://www.youtube.com/@examplegophers":      "youtube_handle_page.html",
+		"https://www.youtube.com/c/ExampleGophers":     "youtube_handle_page.html",
+		blueskyResolveHandleURL + "gopher.example.com": "bluesky_resolve_handle.json",
+	}}
+	fd := NewFeedDiscoveryWithClient(client)
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		// YouTube
+		{"https://www.youtube.com/channel/UCabcdefghijklmnopqrstuv", []string{youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv"}},
+		{"https://m.youtube.com/channel/UCabcdefghijklmnopqrstuv/videos", []string{youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv"}},
+		{"https://www.youtube.com/@examplegophers", []string{youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv"}},
+		{"https://youtube.com/@examplegophers/videos", []string{youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv"}},
+		{"https://www.youtube.com/c/ExampleGophers", []string{youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv"}},
+		{"https://www.youtube.com/playlist?list=PLabc123", []string{youTubeFeedBase + "?playlist_id=PLabc123"}},
+		{"https://www.youtube.com/user/examplegophers", []string{youTubeFeedBase + "?user=examplegophers"}},
+
+		// Reddit
+		{"https://www.reddit.com/r/golang", []string{"https://www.reddit.com/r/golang/.rss"}},
+		{"https://old.reddit.com/r/golang/top/?t=week", []string{"https://www.reddit.com/r/golang/top/.rss?t=week"}},
+		{"https://reddit.com/u/spez", []string{"https://www.reddit.com/user/spez/.rss"}},
+		{"https://www.reddit.com/r/golang/comments/1l1abcd/some_post/", []string{"https://www.reddit.com/r/golang/comments/1l1abcd/some_post/.rss"}},
+		{"https://www.reddit.com/", []string{"https://www.reddit.com/.rss"}},
+
+		// GitHub
+		{"https://github.com/example", []string{"https://github.com/example.atom"}},
+		{"https://github.com/example/widget", []string{
+			"https://github.com/example/widget/releases.atom",
+			"https://github.com/example/widget/commits.atom",
+			"https://github.com/example/widget/tags.atom",
+		}},
+		{"https://github.com/example/widget/releases/tag/v2.1.0", []string{"https://github.com/example/widget/releases.atom"}},
+		{"https://github.com/example/widget/tags", []string{"https://github.com/example/widget/tags.atom"}},
+		{"https://github.com/example/widget/tree/develop", []string{"https://github.com/example/widget/commits/develop.atom"}},
+		{"https://github.com/example/widget/commits", []string{"https://github.com/example/widget/commits.atom"}},
+
+		// Bluesky
+		{"https://bsky.app/profile/gopher.example.com", []string{"https://bsky.app/profile/did:plc:abc123xyz/rss"}},
+		{"https://bsky.app/profile/did:plc:abc123xyz", []string{"https://bsky.app/profile/did:plc:abc123xyz/rss"}},
+		{"https://bsky.app/profile/unresolvable.example.com", []string{"https://bsky.app/profile/unresolvable.example.com/rss"}},
+
+		// Hacker News
+		{"https://news.ycombinator.com/", []string{"https://news.ycombinator.com/rss"}},
+		{"https://news.ycombinator.com/show", []string{hackerNewsSearchFeedURL("show_hn")}},
+		{"https://news.ycombinator.com/user?id=pg", []string{hackerNewsSearchFeedURL("story,author_pg")}},
+		{"https://news.ycombinator.com/item?id=44100001", []string{hackerNewsSearchFeedURL("comment,story_44100001")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := fd.DiscoverFeedURL(t.Context(), tt.input)
+			if err != nil {
+				t.Fatalf("DiscoverFeedURL: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// Pages the adapters don't recognise are left to ordinary discovery
+	for _, input := range []string{
+		"https://www.youtube.com/",
+		"https://www.youtube.com/@missing",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://www.reddit.com/r",
+		"https://www.reddit.com/r/golang/.rss",
+		"https://www.reddit.com/settings",
+		"https://github.com/",
+		"https://github.com/trending",
+		"https://github.com/example/widget/pulls",
+		"https://github.com/example/widget/releases.atom",
+		"https://bsky.app/",
+		"https://bsky.app/profile/did:plc:abc123xyz/rss",
+		"https://news.ycombinator.com/item?id=notanumber",
+		"https://news.ycombinator.com/rss",
+		"https://example.com/r/golang",
+	} {
+		if got := fd.tryFeedAdapters(t.Context(), input); got != nil {
+			t.Errorf("%s: expected no adapter feeds, got %v", input, got)
+		}
+	}
+}
+
+func TestFeedAdapterFixtures(t *testing.T) {
+	client := &fixtureHTTPClient{t: t, fixtures: map[string]string{
+		youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv": "youtube_channel.xml",
+		"https://www.reddit.com/r/golang/.rss":                   "reddit_subreddit.xml",
+		"https://github.com/example/widget/releases.atom":        "github_releases.xml",
+		"https://bsky.app/profile/did:plc:abc123xyz/rss":         "bluesky_profile.xml",
+		"https://news.ycombinator.com/rss":                       "hackernews_frontpage.xml",
+		hackerNewsSearchFeedURL("show_hn"):                       "hackernews_search_show.json",
+		hackerNewsSearchFeedURL("comment,story_44100001"):        "hackernews_search_comments.json",
+	}}
+	fs := NewFeedService(nil, nil)
+	fs.SetHTTPClient(client)
+
+	tests := []struct {
+		name          string
+		feedURL       string
+		expectedTitle string
+		articleCount  int
+		check         func(t *testing.T, fd *FeedData)
+	}{
+		{
+			name:          "YouTube channel",
+			feedURL:       youTubeFeedBase + "?channel_id=UCabcdefghijklmnopqrstuv",
+			expectedTitle: "Example Gophers",
+			articleCount:  2,
+			check: func(t *testing.T, fd *FeedData) {
+				a := fd.Articles[0]
+				if a.Link != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || a.Author != "Example Gophers" {
+					t.Errorf("article[0] = %+v", a)
+				}
+				if !strings.Contains(a.Description, "pprof, tracing &amp; flame graphs.") || !strings.Contains(a.Description, "<p>Slides:") {
+					t.Errorf("expected the media description as HTML, got %q", a.Description)
+				}
+				if !strings.Contains(fd.Articles[1].Content, `src="https://i3.ytimg.com/vi/a1b2c3d4e5f/hqdefault.jpg"`) {
+					t.Errorf("expected the thumbnail for a video without a description, got %q", fd.Articles[1].Content)
+				}
+			},
+		},
+		{
+			name:          "Reddit subreddit",
+			feedURL:       "https://www.reddit.com/r/golang/.rss",
+			expectedTitle: "The Go Programming Language",
+			articleCount:  2,
+			check: func(t *testing.T, fd *FeedData) {
+				a := fd.Articles[0]
+				if a.Title != "What's your favourite go vet analyzer?" || a.Author != "/u/gopher_one" {
+					t.Errorf("article[0] = %+v", a)
+				}
+				if !strings.Contains(a.Content, "<code>go vet</code>") {
+					t.Errorf("article[0] content = %q", a.Content)
+				}
+			},
+		},
+		{
+			name:          "GitHub releases",
+			feedURL:       "https://github.com/example/widget/releases.atom",
+			expectedTitle: "Release notes from widget",
+			articleCount:  2,
+			check: func(t *testing.T, fd *FeedData) {
+				a := fd.Articles[0]
+				if a.Title != "v2.1.0" || a.Link != "https://github.com/example/widget/releases/tag/v2.1.0" {
+					t.Errorf("article[0] = %+v", a)
+				}
+				if !strings.Contains(a.Content, "Add streaming API") {
+					t.Errorf("article[0] content = %q", a.Content)
+				}
+			},
+		},
+		{
+			name:          "Bluesky profile",
+			feedURL:       "https://bsky.app/profile/did:plc:abc123xyz/rss",
+			expectedTitle: "@gopher.example.com - Example Gopher",
+			articleCount:  2,
+			check: func(t *testing.T, fd *FeedData) {
+				a := fd.Articles[0]
+				if a.Link != "https://bsky.app/profile/did:plc:abc123xyz/post/3lqexample1" {
+					t.Errorf("article[0] link = %q", a.Link)
+				}
+				if a.Title != "Shipped the new scheduler today" {
+					t.Errorf("expected an untitled post to take its title from the text, got %q", a.Title)
+				}
+			},
+		},
+		{
+			name:          "Hacker News front page",
+			feedURL:       "https://news.ycombinator.com/rss",
+			expectedTitle: "Hacker News",
+			articleCount:  2,
+		},
+		{
+			name:          "Hacker News listing",
+			feedURL:       hackerNewsSearchFeedURL("show_hn"),
+			expectedTitle: "Hacker News: Show HN",
+			articleCount:  2,
+			check: func(t *testing.T, fd *FeedData) {
+				first, second := fd.Articles[0], fd.Articles[1]
+				if first.Link != "https://github.com/example/termfeed" || first.Author != "builder" || first.PublishedAt.IsZero() {
+					t.Errorf("article[0] = %+v", first)
+				}
+				if !strings.Contains(first.Content, "87 points") || !strings.Contains(first.Content, hackerNewsItemURL+"44100002") {
+					t.Errorf("article[0] content = %q", first.Content)
+				}
+				if second.Link != hackerNewsItemURL+"44100003" {
+					t.Errorf("expected a text post to link to its discussion, got %q", second.Link)
+				}
+				if strings.Contains(second.Content, "<script") || !strings.Contains(second.Content, "<i>Feedback welcome</i>") {
+					t.Errorf("expected sanitised story text, got %q", second.Content)
+				}
+			},
+		},
+		{
+			name:          "Hacker News item comments",
+			feedURL:       hackerNewsSearchFeedURL("comment,story_44100001"),
+			expectedTitle: "Hacker News: comments on A tour of the Go scheduler",
+			articleCount:  1,
+			check: func(t *testing.T, fd *FeedData) {
+				a := fd.Articles[0]
+				if a.Title != "pat on A tour of the Go scheduler" || a.Link != hackerNewsItemURL+"44100020" {
+					t.Errorf("article[0] = %+v", a)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd, err := fs.fetchFeed(t.Context(), tt.feedURL)
+			if err != nil {
+				t.Fatalf("fetchFeed: %v", err)
+			}
+			if fd.Title != tt.expectedTitle {
+				t.Errorf("feed title = %q, want %q", fd.Title, tt.expectedTitle)
+			}
+			if len(fd.Articles) != tt.articleCount {
+				t.Fatalf("article count = %d, want %d", len(fd.Articles), tt.articleCount)
+			}
+			if tt.check != nil {
+				tt.check(t, fd)
+			}
+		})
+	}
+
+	if _, err := fs.fetchFeed(t.Context(), hackerNewsSearchFeedURL("poll")); err == nil {
+		t.Error("expected a failed Hacker News search to be an error")
+	}
+}
@@ -88,6 +88,11 @@ func (fd *FeedDiscovery) DiscoverFeedURL(ctx context.Context, inputURL string) (
 		return []string{normalizedURL}, nil
 	}
 
+	// Platforms like YouTube and GitHub publish feeds their pages don't link to
+	if adapterFeeds := fd.tryFeedAdapters(ctx, normalizedURL); len(adapterFeeds) > 0 {
+		return adapterFeeds, nil
+	}
+
 	// Check for Mastodon-style feeds first (e.g., https://mastodon.social/@username.rss)
 	mastodonFeeds := fd.tryMastodonFeedPaths(ctx, normalizedURL)
 	if len(mastodonFeeds) > 0 {
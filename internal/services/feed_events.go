@@ -0,0 +1,78 @@
+package services
+
+import (
+	"log"
+	"sort"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
+)
+
+// publishNewArticles tells every subscriber of the feed that a refresh stored
+// new articles.
+func (fs *FeedService) publishNewArticles(feedID, count int) {
+	if fs.events == nil {
+		return
+	}
+	fs.events.Publish(events.Event{
+		Type:   events.TypeNewArticles,
+		FeedID: feedID,
+		Data:   events.NewArticlesData{FeedID: feedID, Count: count},
+	})
+}
+
+// publishArticleState sends the article's current read and starred flags to
+// the user's other clients. The flags are read back from the database, so the
+// lookup is skipped when nobody is listening.
+func (fs *FeedService) publishArticleState(userID, articleID, feedID int) {
+	if fs.events == nil || !fs.events.HasSubscribers(userID) {
+		return
+	}
+
+	status, err := fs.db.GetUserArticleStatus(userID, articleID)
+	if err != nil {
+		log.Printf("Failed to load article %d state for user %d event: %v", articleID, userID, err)
+		return
+	}
+	data := events.ArticleStateData{ArticleID: articleID, FeedID: feedID}
+	if status != nil {
+		data.IsRead = status.IsRead
+		data.IsStarred = status.IsStarred
+	}
+	fs.events.Publish(events.Event{Type: events.TypeArticleState, UserID: userID, FeedID: feedID, Data: data})
+}
+
+// publishUnreadCounts tells the user's clients to re-fetch unread counts for
+// the given feeds. Passing no positive feed ID means all feeds may have changed.
+func (fs *FeedService) publishUnreadCounts(userID int, feedIDs ...int) {
+	if fs.events == nil {
+		return
+	}
+	ids := []int{}
+	seen := make(map[int]bool)
+	for _, id := range feedIDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	fs.events.Publish(events.Event{
+		Type:   events.TypeUnreadCounts,
+		UserID: userID,
+		Data:   events.UnreadCountsData{FeedIDs: ids},
+	})
+}
+
+// publishStatusChanges reports a bulk status change as one unread-counts event
+// covering every feed it touched.
+func (fs *FeedService) publishStatusChanges(userID int, changes []database.ArticleStatusChange) {
+	if len(changes) == 0 {
+		return
+	}
+	feedIDs := make([]int, len(changes))
+	for i, c := range changes {
+		feedIDs[i] = c.FeedID
+	}
+	fs.publishUnreadCounts(userID, feedIDs...)
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
+)
+
+func nextEvent(t *testing.T, ch <-chan events.Event) events.Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	default:
+		t.Fatal("expected an event")
+		return events.Event{}
+	}
+}
+
+func TestFeedService_PublishesArticleEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	article := createTestArticle(t, db, feed.ID)
+
+	bus := events.NewMemoryBus()
+	fs := NewFeedService(db, nil)
+	fs.SetEventBus(bus)
+	ch, cancel := bus.Subscribe(user.ID)
+	defer cancel()
+
+	t.Run("mark read", func(t *testing.T) {
+		if err := fs.MarkUserArticleRead(user.ID, article.ID, true, feed.ID, false); err != nil {
+			t.Fatalf("MarkUserArticleRead: %v", err)
+		}
+		state := nextEvent(t, ch)
+		want := events.ArticleStateData{ArticleID: article.ID, FeedID: feed.ID, IsRead: true}
+		if state.Type != events.TypeArticleState || state.Data != want {
+			t.Errorf("expected article state %+v, got %+v", want, state)
+		}
+		counts := nextEvent(t, ch)
+		if counts.Type != events.TypeUnreadCounts || !reflect.DeepEqual(counts.Data, events.UnreadCountsData{FeedIDs: []int{feed.ID}}) {
+			t.Errorf("expected unread counts event for feed %d, got %+v", feed.ID, counts)
+		}
+	})
+
+	t.Run("toggle star", func(t *testing.T) {
+		if err := fs.ToggleUserArticleStar(user.ID, article.ID); err != nil {
+			t.Fatalf("ToggleUserArticleStar: %v", err)
+		}
+		state := nextEvent(t, ch)
+		data, ok := state.Data.(events.ArticleStateData)
+		if !ok || !data.IsStarred || !data.IsRead {
+			t.Errorf("expected starred and read article state, got %+v", state)
+		}
+		if len(ch) != 0 {
+			t.Error("starring should not publish unread count changes")
+		}
+	})
+
+	t.Run("mark all read", func(t *testing.T) {
+		second := &database.Article{FeedID: feed.ID, Title: "Second", URL: "https://example.com/events-second", PublishedAt: time.Now(), CreatedAt: time.Now()}
+		if err := db.AddArticle(second); err != nil {
+			t.Fatalf("AddArticle: %v", err)
+		}
+		if _, err := fs.MarkAllArticlesRead(user.ID); err != nil {
+			t.Fatalf("MarkAllArticlesRead: %v", err)
+		}
+		counts := nextEvent(t, ch)
+		if counts.Type != events.TypeUnreadCounts || !reflect.DeepEqual(counts.Data, events.UnreadCountsData{FeedIDs: []int{feed.ID}}) {
+			t.Errorf("expected unread counts event for feed %d, got %+v", feed.ID, counts)
+		}
+	})
+}
+
+func TestFeedService_PublishesNewArticles(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	feed := createTestFeed(t, db)
+	bus := events.NewMemoryBus()
+	fs := NewFeedService(db, nil)
+	fs.SetEventBus(bus)
+	ch, cancel := bus.Subscribe(42)
+	defer cancel()
+
+	feedData := &FeedData{Articles: []ArticleData{
+		{Title: "One", Link: "https://example.com/new-1", PublishedAt: time.Now()},
+		{Title: "Two", Link: "https://example.com/new-2", PublishedAt: time.Now()},
+	}}
+	if _, err := fs.saveArticlesFromFeedWithLimit(feed.ID, feedData, 0); err != nil {
+		t.Fatalf("saveArticlesFromFeedWithLimit: %v", err)
+	}
+
+	e := nextEvent(t, ch)
+	if e.Type != events.TypeNewArticles || e.UserID != 0 || e.FeedID != feed.ID {
+		t.Errorf("expected feed-wide new articles event for feed %d, got %+v", feed.ID, e)
+	}
+	if data := e.Data.(events.NewArticlesData); data.Count != 2 {
+		t.Errorf("expected 2 new articles, got %d", data.Count)
+	}
+}
@@ -189,9 +189,13 @@ func (fs *FeedScheduler) getAllUniqueFeeds() ([]database.Feed, error) {
 		feedMap[feed.URL] = feed
 	}
 
-	// Convert back to slice
+	// Convert back to slice, leaving out inbound email feeds, which have
+	// nothing to fetch
 	feeds := make([]database.Feed, 0, len(feedMap))
 	for _, feed := range feedMap {
+		if isInboundFeed(feed) {
+			continue
+		}
 		feeds = append(feeds, feed)
 	}
 
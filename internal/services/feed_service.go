@@ -10,12 +10,14 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/jeffreyp/goread2/internal/cache"
 	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
 	"github.com/microcosm-cc/bluemonday"
 	"golang.org/x/net/html"
 	"golang.org/x/text/cases"
@@ -51,6 +53,8 @@ type FeedService struct {
 	feedListCache *cache.FeedListCache
 	httpClient    HTTPClient // Optional: if nil, creates client using urlValidator
 	htmlPolicy    *bluemonday.Policy
+	events        events.Bus      // Optional: if nil, no change events are published
+	webhooks      *WebhookService // Optional: if nil, new articles aren't sent to webhooks
 }
 
 type RSS struct {
@@ -101,13 +105,14 @@ type Item struct {
 }
 
 type AtomEntry struct {
-	Title     string      `xml:"title"`
-	Link      AtomLink    `xml:"link"`
-	Summary   string      `xml:"summary"`
-	Content   AtomContent `xml:"content"`
-	Author    AtomAuthor  `xml:"author"`
-	Published string      `xml:"published"`
-	Updated   string      `xml:"updated"`
+	Title     string         `xml:"title"`
+	Link      AtomLink       `xml:"link"`
+	Summary   string         `xml:"summary"`
+	Content   AtomContent    `xml:"content"`
+	Author    AtomAuthor     `xml:"author"`
+	Published string         `xml:"published"`
+	Updated   string         `xml:"updated"`
+	Media     AtomMediaGroup `xml:"group"`
 }
 
 type AtomLink struct {
@@ -123,6 +128,16 @@ type AtomAuthor struct {
 	Name string `xml:"name"`
 }
 
+// AtomMediaGroup is the Media RSS <media:group> that video feeds such as
+// YouTube's use instead of a summary. The description tag is renamed by
+// preprocessXMLForMediaConflicts before parsing.
+type AtomMediaGroup struct {
+	Description string `xml:"media-description"`
+	Thumbnail   struct {
+		URL string `xml:"url,attr"`
+	} `xml:"thumbnail"`
+}
+
 // FetchOptions provides HTTP conditional request headers for bandwidth optimization
 type FetchOptions struct {
 	ETag         string
@@ -199,6 +214,25 @@ func (fs *FeedService) SetHTTPClient(client HTTPClient) {
 	fs.httpClient = client
 }
 
+// SetEventBus sets the bus that new-article and article-state changes are
+// published to for real-time clients.
+func (fs *FeedService) SetEventBus(bus events.Bus) {
+	fs.events = bus
+}
+
+// SetWebhookService sets the service that new articles are sent to for
+// delivery to users' webhooks.
+func (fs *FeedService) SetWebhookService(ws *WebhookService) {
+	fs.webhooks = ws
+}
+
+// UseCacheBackend shares the unread-count and feed-list caches with other
+// instances through b.
+func (fs *FeedService) UseCacheBackend(b *cache.Backend) {
+	fs.unreadCache.UseBackend(b)
+	fs.feedListCache.UseBackend(b)
+}
+
 func (fs *FeedService) AddFeed(url string) (*database.Feed, error) {
 	ctx := context.Background()
 	feedData, err := fs.fetchFeed(ctx, url)
@@ -276,8 +310,13 @@ func (fs *FeedService) addFeedForUserInternal(ctx context.Context, userID int, i
 	if len(feedURLs) == 0 {
 		return nil, fmt.Errorf("%w: %s", ErrFeedNotFound, inputURL)
 	}
-	feedURL := feedURLs[0]
+	return fs.subscribeUserToFeedURL(ctx, userID, feedURLs[0], nil)
+}
 
+// subscribeUserToFeedURL subscribes the user to the feed at feedURL, creating
+// the feed first if nobody has it yet. feedData is the already-fetched feed,
+// or nil to fetch it only if the feed has to be created.
+func (fs *FeedService) subscribeUserToFeedURL(ctx context.Context, userID int, feedURL string, feedData *FeedData) (*database.Feed, error) {
 	// First check if feed already exists
 	existingFeed, err := fs.db.GetFeedByURL(feedURL)
 	if err != nil {
@@ -286,10 +325,12 @@ func (fs *FeedService) addFeedForUserInternal(ctx context.Context, userID int, i
 
 	if existingFeed == nil {
 		// Feed doesn't exist, create it
-		feedData, err := fs.fetchFeed(ctx, feedURL)
-		if err != nil {
-			// Errors from fetchFeed are already wrapped with custom types
-			return nil, err
+		if feedData == nil {
+			feedData, err = fs.fetchFeed(ctx, feedURL)
+			if err != nil {
+				// Errors from fetchFeed are already wrapped with custom types
+				return nil, err
+			}
 		}
 
 		feed := &database.Feed{
@@ -363,7 +404,12 @@ func (fs *FeedService) GetUserArticles(userID int) ([]database.Article, error) {
 }
 
 func (fs *FeedService) GetUserArticlesPaginated(userID int, limit int, cursor string, unreadOnly bool) (*database.ArticlePaginationResult, error) {
-	return fs.db.GetUserArticlesPaginated(userID, limit, cursor, unreadOnly)
+	result, err := fs.db.GetUserArticlesPaginated(userID, limit, cursor, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	fs.attachArticleTags(userID, result.Articles)
+	return result, nil
 }
 
 func (fs *FeedService) GetUserFeedArticles(userID, feedID int) ([]database.Article, error) {
@@ -371,11 +417,42 @@ func (fs *FeedService) GetUserFeedArticles(userID, feedID int) ([]database.Artic
 }
 
 func (fs *FeedService) GetUserFeedArticlesPaginated(userID, feedID int, limit int, cursor string, unreadOnly bool) (*database.ArticlePaginationResult, error) {
-	return fs.db.GetUserFeedArticlesPaginated(userID, feedID, limit, cursor, unreadOnly)
+	result, err := fs.db.GetUserFeedArticlesPaginated(userID, feedID, limit, cursor, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	fs.attachArticleTags(userID, result.Articles)
+	return result, nil
 }
 
 func (fs *FeedService) GetArticleByID(userID, articleID int) (*database.Article, error) {
-	return fs.db.GetArticleByID(userID, articleID)
+	article, err := fs.db.GetArticleByID(userID, articleID)
+	if err != nil || article == nil {
+		return article, err
+	}
+	articles := []database.Article{*article}
+	fs.attachArticleTags(userID, articles)
+	return &articles[0], nil
+}
+
+// attachArticleTags fills in the user's tag names on each article. Tags are
+// decoration, so a lookup failure is logged rather than failing the listing.
+func (fs *FeedService) attachArticleTags(userID int, articles []database.Article) {
+	if len(articles) == 0 {
+		return
+	}
+	ids := make([]int, len(articles))
+	for i := range articles {
+		ids[i] = articles[i].ID
+	}
+	tags, err := fs.db.GetArticleTags(userID, ids)
+	if err != nil {
+		log.Printf("Failed to load article tags for user %d: %v", userID, err)
+		return
+	}
+	for i := range articles {
+		articles[i].Tags = tags[articles[i].ID]
+	}
 }
 
 // Legacy methods removed - use multi-user methods instead
@@ -400,24 +477,51 @@ func (fs *FeedService) MarkUserArticleRead(userID, articleID int, isRead bool, f
 		fs.unreadCache.Invalidate(userID)
 	}
 
+	fs.publishArticleState(userID, articleID, feedID)
+	if wasRead != isRead || feedID <= 0 {
+		fs.publishUnreadCounts(userID, feedID)
+	}
+	return nil
+}
+
+// SetArticleProgress records how far through an article the user has
+// scrolled, as a percentage, so reading can resume on another device.
+func (fs *FeedService) SetArticleProgress(userID, articleID, progress int) error {
+	if progress < 0 || progress > 100 {
+		return fmt.Errorf("%w: progress must be between 0 and 100", ErrInvalidProgress)
+	}
+
+	article, err := fs.db.GetArticleByID(userID, articleID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if article == nil {
+		return ErrArticleNotFound
+	}
+
+	if err := fs.db.SetUserArticleProgress(userID, articleID, progress); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
 	return nil
 }
 
 func (fs *FeedService) ToggleUserArticleStar(userID, articleID int) error {
 	// Starring doesn't affect unread counts, so no cache invalidation needed
-	return fs.db.ToggleUserArticleStar(userID, articleID)
+	if err := fs.db.ToggleUserArticleStar(userID, articleID); err != nil {
+		return err
+	}
+	fs.publishArticleState(userID, articleID, 0)
+	return nil
 }
 
+// MarkAllArticlesRead marks every unread article in the user's feeds as read
+// and returns how many changed.
 func (fs *FeedService) MarkAllArticlesRead(userID int) (int, error) {
-	count, err := fs.db.MarkAllUserArticlesRead(userID)
+	result, err := fs.MarkArticlesRead(userID, database.MarkReadScope{})
 	if err != nil {
-		return 0, fmt.Errorf("failed to mark articles as read: %w", err)
+		return 0, err
 	}
-
-	// Invalidate cache since unread counts changed
-	fs.unreadCache.Invalidate(userID)
-
-	return count, nil
+	return result.Count, nil
 }
 
 func (fs *FeedService) GetUserUnreadCounts(userID int, userFeeds []database.Feed) (map[int]int, error) {
@@ -520,9 +624,21 @@ func (fs *FeedService) UpdateUserMaxArticlesOnFeedAdd(userID, maxArticles int) e
 }
 
 func (fs *FeedService) fetchFeed(ctx context.Context, url string, opts ...*FetchOptions) (*FeedData, error) {
+	// HTML feeds fetch the page their selectors apply to
+	fetchURL := url
+	var htmlSource *htmlFeedSource
+	if isHTMLFeedURL(url) {
+		source, err := parseHTMLFeedURL(url)
+		if err != nil {
+			return nil, err
+		}
+		htmlSource = source
+		fetchURL = source.pageURL
+	}
+
 	// Validate URL for SSRF protection (skip if using mock HTTP client for testing)
 	if fs.httpClient == nil {
-		if err := fs.urlValidator.ValidateURL(ctx, url); err != nil {
+		if err := fs.urlValidator.ValidateURL(ctx, fetchURL); err != nil {
 			if errors.Is(err, ErrSSRFBlocked) {
 				return nil, fmt.Errorf("%w: %v", ErrSSRFBlocked, err)
 			}
@@ -532,13 +648,13 @@ func (fs *FeedService) fetchFeed(ctx context.Context, url string, opts ...*Fetch
 
 	// Apply rate limiting if available (skip if using mock HTTP client for testing)
 	if fs.rateLimiter != nil && fs.httpClient == nil {
-		if !fs.rateLimiter.Allow(url) {
+		if !fs.rateLimiter.Allow(fetchURL) {
 			// Rate limiting is a temporary network-related issue
 			return nil, fmt.Errorf("%w: rate limited - too many requests to domain", ErrNetworkError)
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fetchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to create request: %v", ErrNetworkError, err)
 	}
@@ -591,6 +707,32 @@ func (fs *FeedService) fetchFeed(ctx context.Context, url string, opts ...*Fetch
 		return nil, fmt.Errorf("%w: feed exceeds maximum size of %d bytes", ErrInvalidFeedFormat, maxFeedBodySize)
 	}
 
+	if htmlSource != nil {
+		feedData, err := fs.convertHTMLToFeedData(body, resp.Header.Get("Content-Type"), htmlSource)
+		if err != nil {
+			return nil, err
+		}
+		feedData.ResponseETag = resp.Header.Get("ETag")
+		feedData.ResponseLastModified = resp.Header.Get("Last-Modified")
+		return feedData, nil
+	}
+
+	if adapter := syntheticFeedAdapterFor(url); adapter != nil {
+		feedData, err := adapter.ParseFeed(body, url)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeedFormat, err)
+		}
+		for i := range feedData.Articles {
+			a := &feedData.Articles[i]
+			a.Description = fs.sanitizeHTML(a.Description)
+			a.Content = fs.sanitizeHTML(a.Content)
+			a.Title = fs.sanitizeArticleTitle(a.Title, a.Link, a.Description)
+		}
+		feedData.ResponseETag = resp.Header.Get("ETag")
+		feedData.ResponseLastModified = resp.Header.Get("Last-Modified")
+		return feedData, nil
+	}
+
 	// Handle character encoding conversion
 	body, err = fs.convertToUTF8(body)
 	if err != nil {
@@ -739,15 +881,24 @@ func (fs *FeedService) convertAtomToFeedData(atom *Atom, feedURL string) *FeedDa
 			}
 		}
 
+		summary := entry.Summary
+		if summary == "" && entry.Media.Description != "" {
+			summary = plainTextToHTML(entry.Media.Description)
+		}
+
 		content := entry.Content.Content
 		if content == "" {
-			content = entry.Summary
+			content = summary
+			if thumbnail := entry.Media.Thumbnail.URL; thumbnail != "" && entry.Summary == "" {
+				content = fmt.Sprintf(`<p><a href="%s"><img src="%s" alt=""></a></p>`,
+					html.EscapeString(entry.Link.Href), html.EscapeString(thumbnail)) + content
+			}
 		}
 
 		articles[i] = ArticleData{
-			Title:       fs.sanitizeArticleTitle(entry.Title, entry.Link.Href, entry.Summary),
+			Title:       fs.sanitizeArticleTitle(entry.Title, entry.Link.Href, summary),
 			Link:        entry.Link.Href,
-			Description: fs.sanitizeHTML(entry.Summary),
+			Description: fs.sanitizeHTML(summary),
 			Content:     fs.sanitizeHTML(content),
 			Author:      entry.Author.Name,
 			PublishedAt: publishedAt,
@@ -774,6 +925,7 @@ func (fs *FeedService) saveArticlesFromFeed(feedID int, feedData *FeedData) (int
 func (fs *FeedService) saveArticlesFromFeedWithLimit(feedID int, feedData *FeedData, maxArticles int) (int, error) {
 	var savedCount int
 	var errors []string
+	var saved []database.Article
 
 	// Sort articles by published date (most recent first) before applying limit
 	articles := make([]ArticleData, len(feedData.Articles))
@@ -816,6 +968,8 @@ func (fs *FeedService) saveArticlesFromFeedWithLimit(feedID int, feedData *FeedD
 			Author:      articleData.Author,
 			PublishedAt: articleData.PublishedAt,
 			CreatedAt:   time.Now(),
+
+			ReadingTimeMinutes: estimateReadingTime(articleData.Content, articleData.Description),
 		}
 
 		if err := fs.db.AddArticle(article); err != nil {
@@ -823,6 +977,16 @@ func (fs *FeedService) saveArticlesFromFeedWithLimit(feedID int, feedData *FeedD
 			continue // Continue processing other articles
 		}
 		savedCount++
+		if fs.webhooks != nil {
+			saved = append(saved, *article)
+		}
+	}
+
+	if savedCount > 0 {
+		fs.publishNewArticles(feedID, savedCount)
+	}
+	if fs.webhooks != nil {
+		fs.webhooks.NotifyNewArticles(feedID, feedData.Title, saved)
 	}
 
 	if maxArticles > 0 && len(articles) > maxArticles {
@@ -886,6 +1050,10 @@ func (fs *FeedService) RefreshFeeds() error {
 	notModified := 0
 
 	for _, feed := range feedMap {
+		if isInboundFeed(feed) {
+			continue
+		}
+
 		// Smart feed prioritization: only check feeds that are due
 		if !fs.shouldCheckFeed(feed, now) {
 			skipped++
@@ -1211,8 +1379,12 @@ func (fs *FeedService) ExportOPML(userID int) ([]byte, error) {
 		},
 	}
 
-	// Convert each feed to an OPML outline
+	// Convert each feed to an OPML outline. Inbound email and HTML feeds have
+	// no URL another reader could subscribe to, so they're left out.
 	for _, feed := range feeds {
+		if isInboundFeed(feed) || isHTMLFeedURL(feed.URL) {
+			continue
+		}
 		outline := OPMLOutline{
 			Type:    "rss",
 			Text:    feed.Title,
@@ -1320,6 +1492,9 @@ func (fs *FeedService) sanitizeArticleTitle(title, link, description string) str
 	return title
 }
 
+// versionTitlePattern matches release names such as "v2.1.0" or "1.4.0-rc.1".
+var versionTitlePattern = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+)+([-+][0-9a-z.-]+)?$`)
+
 func (fs *FeedService) isInvalidTitle(title string) bool {
 	title = strings.ToLower(strings.TrimSpace(title))
 
@@ -1341,6 +1516,11 @@ func (fs *FeedService) isInvalidTitle(title string) bool {
 		}
 	}
 
+	// Release feeds title entries with a bare version number
+	if versionTitlePattern.MatchString(title) {
+		return false
+	}
+
 	// Check if title is suspiciously short and contains mostly non-letter characters
 	if len(title) < 15 {
 		letterCount := 0
@@ -1435,6 +1615,11 @@ func (fs *FeedService) sanitizeHTML(s string) string {
 }
 
 func (fs *FeedService) stripHTMLTags(s string) string {
+	return stripHTMLTags(s)
+}
+
+// stripHTMLTags returns the text content of an HTML fragment.
+func stripHTMLTags(s string) string {
 	doc, err := html.Parse(strings.NewReader(s))
 	if err != nil {
 		// Fall back to returning the raw string rather than losing content entirely.
@@ -1454,6 +1639,46 @@ func (fs *FeedService) stripHTMLTags(s string) string {
 	return strings.TrimSpace(buf.String())
 }
 
+// readingWordsPerMinute is a typical adult reading speed for on-screen prose.
+const readingWordsPerMinute = 230
+
+// estimateReadingTime returns the whole minutes needed to read an article,
+// rounded up, from the word count of its sanitised content. The description
+// stands in for feeds that only publish summaries. Returns 0 when there is
+// no text at all.
+func estimateReadingTime(content, description string) int {
+	words := countHTMLWords(content)
+	if words == 0 {
+		words = countHTMLWords(description)
+	}
+	return (words + readingWordsPerMinute - 1) / readingWordsPerMinute
+}
+
+// countHTMLWords counts whitespace-separated words in the text nodes of an
+// HTML fragment. Unlike stripHTMLTags it counts each text node separately, so
+// adjacent block elements such as "<p>a</p><p>b</p>" count as two words.
+func countHTMLWords(s string) int {
+	if strings.TrimSpace(s) == "" {
+		return 0
+	}
+	doc, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return len(strings.Fields(s))
+	}
+	count := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			count += len(strings.Fields(n.Data))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return count
+}
+
 func (fs *FeedService) cleanTitle(title string) string {
 	// Remove HTML tags if any
 	title = strings.ReplaceAll(title, "<", "&lt;")
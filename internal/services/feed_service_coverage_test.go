@@ -30,9 +30,112 @@ func newMockDBFeed() *mockDBFeed {
 	}
 }
 
-func (m *mockDBFeed) Close() error                                     { return nil }
-func (m *mockDBFeed) CreateUser(*database.User) error                  { return nil }
-func (m *mockDBFeed) GetUserByGoogleID(string) (*database.User, error) { return nil, nil }
+func (m *mockDBFeed) Close() error                                                  { return nil }
+func (m *mockDBFeed) GetArticlesByIDs(int, []int) (map[int]database.Article, error) { return nil, nil }
+func (m mockDBFeed) CreateRefreshToken(*database.RefreshToken) error                { return nil }
+func (m mockDBFeed) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBFeed) DeleteExpiredRefreshTokens() error                               { return nil }
+func (m mockDBFeed) UpdateSessionActivity(string, time.Time, string) error           { return nil }
+func (m *mockDBFeed) CreateUserIdentity(*database.UserIdentity) error                { return nil }
+func (m *mockDBFeed) GetUserIdentity(string, string) (*database.UserIdentity, error) { return nil, nil }
+func (m *mockDBFeed) GetUserIdentities(int) ([]database.UserIdentity, error)         { return nil, nil }
+func (m *mockDBFeed) CreateLoginToken(*database.LoginToken) error                    { return nil }
+func (m *mockDBFeed) ConsumeLoginToken(string) (*database.LoginToken, error)         { return nil, nil }
+func (m *mockDBFeed) DeleteExpiredLoginTokens() error                                { return nil }
+func (m *mockDBFeed) ForEachUserIdentity(func(database.UserIdentity) error) error    { return nil }
+func (m *mockDBFeed) DeleteUser(userID int) error                                    { return nil }
+func (m *mockDBFeed) GetUserArticleHistory(userID int) ([]database.Article, error)   { return nil, nil }
+func (m *mockDBFeed) GetUserSessions(userID int) ([]database.Session, error)         { return nil, nil }
+func (m *mockDBFeed) ForEachUser(fn func(database.User) error) error                 { return nil }
+func (m *mockDBFeed) ForEachArticle(fn func(database.Article) error) error           { return nil }
+func (m *mockDBFeed) ForEachUserArticle(fn func(database.UserArticle) error) error   { return nil }
+func (m *mockDBFeed) ForEachSession(fn func(database.Session) error) error           { return nil }
+func (m *mockDBFeed) GetAllFeedSubscriptions() ([]database.FeedSubscription, error)  { return nil, nil }
+func (m *mockDBFeed) GetFeedCategories() (map[int]string, error)                     { return nil, nil }
+func (m *mockDBFeed) SetFeedCategory(feedID int, category string) error              { return nil }
+func (m *mockDBFeed) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBFeed) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBFeed) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBFeed) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBFeed) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBFeed) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBFeed) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBFeed) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBFeed) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBFeed) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBFeed) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBFeed) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBFeed) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBFeed) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBFeed) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBFeed) DeleteWebhookDeliveriesBefore(time.Time) (int, error)              { return 0, nil }
+func (m *mockDBFeed) UpdateUserDigestPreferences(int, database.DigestPreferences) error { return nil }
+func (m *mockDBFeed) GetDigestUsers() ([]database.User, error)                          { return []database.User{}, nil }
+func (m *mockDBFeed) SetUserDigestSentAt(int, time.Time) error                          { return nil }
+func (m *mockDBFeed) GetUserStarredCountsByFeed(int) (map[int]int, error)               { return map[int]int{}, nil }
+func (m *mockDBFeed) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBFeed) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBFeed) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBFeed) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBFeed) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBFeed) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBFeed) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBFeed) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBFeed) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBFeed) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBFeed) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBFeed) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBFeed) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBFeed) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBFeed) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBFeed) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBFeed) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBFeed) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBFeed) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBFeed) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBFeed) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBFeed) CreateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDBFeed) GetAnnotation(int, int) (*database.Annotation, error)          { return nil, nil }
+func (m *mockDBFeed) GetArticleAnnotations(int, int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBFeed) GetUserAnnotations(int) ([]database.Annotation, error)         { return nil, nil }
+func (m *mockDBFeed) UpdateAnnotation(*database.Annotation) error                   { return nil }
+func (m *mockDBFeed) DeleteAnnotation(int, int) error                               { return nil }
+func (m *mockDBFeed) CreateUser(*database.User) error                               { return nil }
+func (m *mockDBFeed) GetUserByGoogleID(string) (*database.User, error)              { return nil, nil }
 func (m *mockDBFeed) GetUserByID(int) (*database.User, error) {
 	if m.shouldFailUser {
 		return nil, errors.New("user not found")
@@ -102,6 +205,16 @@ func (m *mockDBFeed) MarkAllUserArticlesRead(int) (int, error) {
 	}
 	return len(m.articles), nil
 }
+func (m *mockDBFeed) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	if m.shouldFailArticle {
+		return nil, errors.New("db error")
+	}
+	result := &database.MarkReadResult{UnreadCleared: make(map[int]int)}
+	for _, a := range m.articles {
+		result.Changes = append(result.Changes, database.ArticleStatusChange{ArticleID: a.ID, FeedID: a.FeedID})
+	}
+	return result, nil
+}
 func (m *mockDBFeed) BatchSetUserArticleStatus(int, []database.Article, bool, bool) error {
 	if m.shouldFailBatch {
 		return errors.New("batch error")
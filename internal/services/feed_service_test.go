@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -289,6 +290,35 @@ func TestStripHTMLTags(t *testing.T) {
 	}
 }
 
+func TestEstimateReadingTime(t *testing.T) {
+	words := func(n int) string {
+		return "<p>" + strings.Repeat("word ", n) + "</p>"
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		description string
+		expected    int
+	}{
+		{"No text", "", "", 0},
+		{"Short article rounds up", "<p>A few words</p>", "", 1},
+		{"Exactly one minute", words(readingWordsPerMinute), "", 1},
+		{"Just over one minute", words(readingWordsPerMinute + 1), "", 2},
+		{"Adjacent blocks count separately", strings.Repeat("<p>word</p>", 2*readingWordsPerMinute), "", 2},
+		{"Falls back to description", "", words(3 * readingWordsPerMinute), 3},
+		{"Content wins over description", words(10), words(5 * readingWordsPerMinute), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateReadingTime(tt.content, tt.description); got != tt.expected {
+				t.Errorf("estimateReadingTime() = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGenerateFallbackTitle(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() { _ = db.Close() }()
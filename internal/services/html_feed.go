@@ -0,0 +1,405 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/jeffreyp/goread2/internal/database"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// htmlFeedURLPrefix marks feeds scraped from a web page. The rest of the
+	// feed URL is the page URL with the selectors encoded in its fragment, so
+	// the same page scraped with the same selectors is one shared feed and
+	// refreshes need nothing beyond the feed row.
+	htmlFeedURLPrefix = "html:"
+
+	// maxHTMLFeedItems caps the items taken from one page.
+	maxHTMLFeedItems = 100
+
+	// maxSelectorLength bounds a single selector so the feed URL it's stored
+	// in stays well within index limits.
+	maxSelectorLength = 200
+)
+
+// Selectors for the parts of a page read regardless of the user's selectors
+var (
+	baseSelector            = cascadia.MustCompile("base[href]")
+	titleSelector           = cascadia.MustCompile("title")
+	metaDescriptionSelector = cascadia.MustCompile(`meta[name="description"]`)
+	anchorSelector          = cascadia.MustCompile("a[href]")
+)
+
+// htmlDateLayouts extends the feed date layouts with the formats sites tend
+// to print next to posts.
+var htmlDateLayouts = append(append(append([]string{}, rdfDateLayouts...), rssDateLayouts...),
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"Jan. 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"Monday, January 2, 2006",
+	"Mon, Jan 2, 2006",
+)
+
+// HTMLFeedSelectors are the CSS selectors that turn a web page into a feed.
+// Item selects each entry on the page; the others are matched inside an
+// entry and are optional:
+//   - Title defaults to the text of the link
+//   - Link defaults to the entry itself if it has an href, else its first link
+//   - Date is read from a datetime attribute or the element's text; entries
+//     without one are dated when first seen
+//   - Summary's inner HTML becomes the article body
+type HTMLFeedSelectors struct {
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Date    string `json:"date"`
+	Summary string `json:"summary"`
+}
+
+type compiledHTMLFeedSelectors struct {
+	item, title, link, date, summary cascadia.Matcher
+}
+
+// compileSelector parses a selector group such as "article.post, div.entry > h2".
+func compileSelector(s string) (cascadia.Matcher, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty selector", ErrInvalidSelector)
+	}
+	if len(s) > maxSelectorLength {
+		return nil, fmt.Errorf("%w: selector is longer than %d characters", ErrInvalidSelector, maxSelectorLength)
+	}
+	sel, err := cascadia.ParseGroup(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidSelector, s, err)
+	}
+	return sel, nil
+}
+
+func (s HTMLFeedSelectors) compile() (*compiledHTMLFeedSelectors, error) {
+	item, err := compileSelector(s.Item)
+	if err != nil {
+		return nil, fmt.Errorf("item: %w", err)
+	}
+	compiled := &compiledHTMLFeedSelectors{item: item}
+
+	optional := []struct {
+		name  string
+		value string
+		dst   *cascadia.Matcher
+	}{
+		{"title", s.Title, &compiled.title},
+		{"link", s.Link, &compiled.link},
+		{"date", s.Date, &compiled.date},
+		{"summary", s.Summary, &compiled.summary},
+	}
+	for _, o := range optional {
+		if strings.TrimSpace(o.value) == "" {
+			continue
+		}
+		if *o.dst, err = compileSelector(o.value); err != nil {
+			return nil, fmt.Errorf("%s: %w", o.name, err)
+		}
+	}
+	return compiled, nil
+}
+
+// htmlFeedSource is a parsed HTML feed URL.
+type htmlFeedSource struct {
+	pageURL   string
+	selectors *compiledHTMLFeedSelectors
+}
+
+// isHTMLFeedURL reports whether the feed URL is scraped from a web page.
+func isHTMLFeedURL(feedURL string) bool {
+	return strings.HasPrefix(feedURL, htmlFeedURLPrefix)
+}
+
+// buildHTMLFeedURL encodes a page URL and its selectors as a feed URL. The
+// page's own fragment is dropped since it never reaches the server.
+func buildHTMLFeedURL(pageURL string, selectors HTMLFeedSelectors) string {
+	page, _, _ := strings.Cut(pageURL, "#")
+	values := url.Values{}
+	for key, value := range map[string]string{
+		"item":    selectors.Item,
+		"title":   selectors.Title,
+		"link":    selectors.Link,
+		"date":    selectors.Date,
+		"summary": selectors.Summary,
+	} {
+		if value = strings.TrimSpace(value); value != "" {
+			values.Set(key, value)
+		}
+	}
+	return htmlFeedURLPrefix + page + "#" + values.Encode()
+}
+
+func parseHTMLFeedURL(feedURL string) (*htmlFeedSource, error) {
+	page, fragment, ok := strings.Cut(strings.TrimPrefix(feedURL, htmlFeedURLPrefix), "#")
+	if !ok {
+		return nil, fmt.Errorf("%w: HTML feed URL has no selectors", ErrInvalidURL)
+	}
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%w: HTML feed selectors: %v", ErrInvalidURL, err)
+	}
+	selectors, err := HTMLFeedSelectors{
+		Item:    values.Get("item"),
+		Title:   values.Get("title"),
+		Link:    values.Get("link"),
+		Date:    values.Get("date"),
+		Summary: values.Get("summary"),
+	}.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &htmlFeedSource{pageURL: page, selectors: selectors}, nil
+}
+
+// PreviewHTMLFeed fetches the page and returns what the selectors extract
+// from it without saving anything, along with the feed URL it would be
+// saved under.
+func (fs *FeedService) PreviewHTMLFeed(pageURL string, selectors HTMLFeedSelectors) (string, *FeedData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return fs.scrapeHTMLFeed(ctx, pageURL, selectors)
+}
+
+// AddHTMLFeedForUser subscribes the user to a feed scraped from pageURL with
+// the given selectors. It fails with ErrNoScrapedItems rather than creating a
+// feed that would never have articles.
+func (fs *FeedService) AddHTMLFeedForUser(userID int, pageURL string, selectors HTMLFeedSelectors) (*database.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	feedURL, feedData, err := fs.scrapeHTMLFeed(ctx, pageURL, selectors)
+	if err != nil {
+		return nil, err
+	}
+	if len(feedData.Articles) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoScrapedItems, pageURL)
+	}
+	return fs.subscribeUserToFeedURL(ctx, userID, feedURL, feedData)
+}
+
+func (fs *FeedService) scrapeHTMLFeed(ctx context.Context, pageURL string, selectors HTMLFeedSelectors) (string, *FeedData, error) {
+	if _, err := selectors.compile(); err != nil {
+		return "", nil, err
+	}
+
+	var discovery *FeedDiscovery
+	if fs.httpClient != nil {
+		discovery = NewFeedDiscoveryWithClient(fs.httpClient)
+	} else {
+		discovery = NewFeedDiscovery()
+	}
+	normalizedURL, err := discovery.NormalizeURL(ctx, pageURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	feedURL := buildHTMLFeedURL(normalizedURL, selectors)
+	feedData, err := fs.fetchFeed(ctx, feedURL)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", nil, fmt.Errorf("%w: %s", ErrFeedTimeout, pageURL)
+		}
+		return "", nil, err
+	}
+	return feedURL, feedData, nil
+}
+
+// convertHTMLToFeedData extracts the entries the selectors match on a page.
+// Entries without a usable link are skipped, since the link is what
+// deduplicates articles across refreshes.
+func (fs *FeedService) convertHTMLToFeedData(body []byte, contentType string, source *htmlFeedSource) (*FeedData, error) {
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unsupported page encoding: %v", ErrInvalidFeedFormat, err)
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to parse HTML: %v", ErrInvalidFeedFormat, err)
+	}
+
+	base, err := url.Parse(source.pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if baseTag := cascadia.Query(doc, baseSelector); baseTag != nil {
+		if href, err := base.Parse(htmlAttr(baseTag, "href")); err == nil {
+			base = href
+		}
+	}
+
+	sel := source.selectors
+	now := time.Now()
+	seen := make(map[string]bool)
+	var articles []ArticleData
+
+	items := cascadia.QueryAll(doc, sel.item)
+	if len(items) > maxHTMLFeedItems {
+		items = items[:maxHTMLFeedItems]
+	}
+	for i, item := range items {
+		linkNode := scrapedLinkNode(item, sel.link)
+		if linkNode == nil {
+			continue
+		}
+		link, err := base.Parse(strings.TrimSpace(htmlAttr(linkNode, "href")))
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+		link.Fragment = ""
+		if seen[link.String()] {
+			continue
+		}
+		seen[link.String()] = true
+
+		title := htmlNodeText(linkNode)
+		if sel.title != nil {
+			if n := cascadia.Query(item, sel.title); n != nil {
+				title = htmlNodeText(n)
+			}
+		}
+
+		var summary string
+		if sel.summary != nil {
+			if n := cascadia.Query(item, sel.summary); n != nil {
+				resolveHTMLLinks(n, base)
+				summary = fs.sanitizeHTML(htmlNodeInnerHTML(n))
+			}
+		}
+
+		// Undated entries keep their page order: the first is newest.
+		publishedAt := now.Add(-time.Duration(i) * time.Second)
+		if sel.date != nil {
+			if n := cascadia.Query(item, sel.date); n != nil {
+				if t, ok := scrapedDate(n); ok {
+					publishedAt = t
+				}
+			}
+		}
+
+		articles = append(articles, ArticleData{
+			Title:       fs.sanitizeArticleTitle(title, link.String(), stripHTMLTags(summary)),
+			Link:        link.String(),
+			Description: summary,
+			Content:     summary,
+			PublishedAt: publishedAt,
+		})
+	}
+
+	title := base.Hostname()
+	if n := cascadia.Query(doc, titleSelector); n != nil {
+		if text := htmlNodeText(n); text != "" {
+			title = fs.cleanTitle(text)
+		}
+	}
+	var description string
+	if n := cascadia.Query(doc, metaDescriptionSelector); n != nil {
+		description = strings.TrimSpace(htmlAttr(n, "content"))
+	}
+
+	return &FeedData{
+		Title:       fs.enhanceFeedTitle(title, source.pageURL),
+		Description: description,
+		Articles:    articles,
+	}, nil
+}
+
+// scrapedLinkNode returns the element whose href is the entry's link.
+func scrapedLinkNode(item *html.Node, linkSel cascadia.Matcher) *html.Node {
+	n := item
+	if linkSel != nil {
+		if n = cascadia.Query(item, linkSel); n == nil {
+			return nil
+		}
+	}
+	if htmlAttr(n, "href") != "" {
+		return n
+	}
+	return cascadia.Query(n, anchorSelector)
+}
+
+// scrapedDate reads a date from a <time datetime>, a content attribute (as
+// used by microdata) or the element's text.
+func scrapedDate(n *html.Node) (time.Time, bool) {
+	for _, value := range []string{htmlAttr(n, "datetime"), htmlAttr(n, "content"), htmlNodeText(n)} {
+		if t, ok := parseFeedDate(value, htmlDateLayouts); ok {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// htmlNodeText returns n's text with runs of whitespace collapsed, leaving
+// out scripts and styles.
+func htmlNodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		case n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style"):
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// resolveHTMLLinks makes the links and image sources under n absolute, since
+// the summary is shown away from the page they were relative to.
+func resolveHTMLLinks(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			if attr.Namespace != "" || (attr.Key != "href" && attr.Key != "src") {
+				continue
+			}
+			if resolved, err := base.Parse(strings.TrimSpace(attr.Val)); err == nil {
+				n.Attr[i].Val = resolved.String()
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		resolveHTMLLinks(c, base)
+	}
+}
+
+// htmlAttr returns the value of n's attribute key, or "" if it has none.
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Namespace == "" && attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func htmlNodeInnerHTML(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&b, c)
+	}
+	return b.String()
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+const scrapedPage = `<!DOCTYPE html>
+<html><head>
+<title>Example Changelog</title>
+<meta name="description" content="What's new at Example">
+</head><body>
+<ul class="posts">
+  <li class="post">
+    <a href="/posts/second#comments">Second <em>release</em></a>
+    <time datetime="2025-06-02T09:30:00Z">2 June</time>
+    <div class="summary"><p>Now <b>faster</b>. <img src="/img/chart.png"> <script>alert(1)</script></p></div>
+  </li>
+  <li class="post">
+    <h3>First release</h3>
+    <a href="https://example.com/posts/first">Read more</a>
+    <span class="date">May 1, 2025</span>
+  </li>
+  <li class="post"><span>No link here</span></li>
+  <li class="post"><a href="javascript:void(0)">Script link</a></li>
+  <li class="post"><a href="/posts/second">Duplicate</a></li>
+</ul>
+</body></html>`
+
+func newScrapedPageServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+const selectorTestPage = `<html><body>
+<div id="main" class="content wide">
+  <article class="post featured" data-kind="news-item"><h2><a href="/a" lang="en-GB">A</a></h2></article>
+  <article class="post"><div><h2><a href="/b">B</a></h2></div></article>
+  <section><h2 class="md:big">C</h2></section>
+</div>
+<h2 id="footer">D</h2>
+</body></html>`
+
+func TestCompileSelector(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorTestPage))
+	if err != nil {
+		t.Fatalf("html.Parse: %v", err)
+	}
+
+	tests := []struct {
+		selector string
+		want     string // text of the matches, in document order
+	}{
+		{"h2", "ABCD"},
+		{"H2", "ABCD"},
+		{"*#footer", "D"},
+		{"article h2", "AB"},
+		{"article > h2", "A"},
+		{"#main > section h2, h2#footer", "CD"},
+		{".post.featured h2", "A"},
+		{"[data-kind] h2", "A"},
+		{"[data-kind=news-item] h2", "A"},
+		{`[data-kind^="news"] h2`, "A"},
+		{"[data-kind$=item] h2", "A"},
+		{"[data-kind*='s-i'] h2", "A"},
+		{"[class~=wide] section h2", "C"},
+		{"a[lang|=en]", "A"},
+		{"a[href='/b']", "B"},
+		{`.md\:big`, "C"},
+		{"article + article h2", "B"},
+		{"article:first-child h2", "A"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			sel, err := compileSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("compileSelector: %v", err)
+			}
+			var got strings.Builder
+			for _, n := range cascadia.QueryAll(doc, sel) {
+				got.WriteString(htmlNodeText(n))
+			}
+			if got.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got.String())
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "  ", "a[href", "a[href=]x", "div >", ".", "#", "a:no-such-class", "p!", strings.Repeat("a", maxSelectorLength+1)} {
+		if _, err := compileSelector(invalid); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("%q: expected ErrInvalidSelector, got %v", invalid, err)
+		}
+	}
+}
+
+func TestHTMLFeedURLRoundTrip(t *testing.T) {
+	selectors := HTMLFeedSelectors{Item: "li.post", Title: " h3 ", Date: "time, .date"}
+	feedURL := buildHTMLFeedURL("https://example.com/changelog?page=1#top", selectors)
+
+	if !isHTMLFeedURL(feedURL) {
+		t.Fatalf("expected %q to be an HTML feed URL", feedURL)
+	}
+	if strings.Contains(feedURL, "#top") || strings.Count(feedURL, "#") != 1 {
+		t.Errorf("expected the page fragment to be replaced by the selectors, got %q", feedURL)
+	}
+	if feedURL != buildHTMLFeedURL("https://example.com/changelog?page=1", selectors) {
+		t.Error("expected the same page and selectors to build the same feed URL")
+	}
+
+	source, err := parseHTMLFeedURL(feedURL)
+	if err != nil {
+		t.Fatalf("parseHTMLFeedURL: %v", err)
+	}
+	if source.pageURL != "https://example.com/changelog?page=1" {
+		t.Errorf("unexpected page URL %q", source.pageURL)
+	}
+	if source.selectors.item == nil || source.selectors.title == nil || source.selectors.date == nil ||
+		source.selectors.link != nil || source.selectors.summary != nil {
+		t.Errorf("unexpected selectors: %+v", source.selectors)
+	}
+
+	if _, err := parseHTMLFeedURL("html:https://example.com/#title=h2"); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("expected a missing item selector to be rejected, got %v", err)
+	}
+}
+
+func TestConvertHTMLToFeedData(t *testing.T) {
+	fs := NewFeedService(nil, nil)
+	source, err := parseHTMLFeedURL(buildHTMLFeedURL("https://example.com/changelog", HTMLFeedSelectors{
+		Item:    "li.post",
+		Title:   "h3",
+		Date:    "time, .date",
+		Summary: ".summary",
+	}))
+	if err != nil {
+		t.Fatalf("parseHTMLFeedURL: %v", err)
+	}
+
+	feedData, err := fs.convertHTMLToFeedData([]byte(scrapedPage), "text/html", source)
+	if err != nil {
+		t.Fatalf("convertHTMLToFeedData: %v", err)
+	}
+	if feedData.Title != "Example Changelog" || feedData.Description != "What's new at Example" {
+		t.Errorf("unexpected feed: %q, %q", feedData.Title, feedData.Description)
+	}
+	if len(feedData.Articles) != 2 {
+		t.Fatalf("expected 2 articles (links missing, unsafe or repeated are skipped), got %+v", feedData.Articles)
+	}
+
+	second, first := feedData.Articles[0], feedData.Articles[1]
+	if second.Link != "https://example.com/posts/second" || second.Title != "Second release" {
+		t.Errorf("unexpected first entry: %+v", second)
+	}
+	if !second.PublishedAt.Equal(time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected the datetime attribute to be used, got %v", second.PublishedAt)
+	}
+	if !strings.Contains(second.Content, "<b>faster</b>") || !strings.Contains(second.Content, `src="https://example.com/img/chart.png"`) ||
+		strings.Contains(second.Content, "<script") {
+		t.Errorf("expected a sanitised summary with absolute links, got %q", second.Content)
+	}
+	if first.Link != "https://example.com/posts/first" || first.Title != "First release" || first.Content != "" {
+		t.Errorf("unexpected second entry: %+v", first)
+	}
+	if first.PublishedAt.Year() != 2025 || first.PublishedAt.Month() != time.May {
+		t.Errorf("expected the date text to be parsed, got %v", first.PublishedAt)
+	}
+}
+
+func TestHTMLFeedForUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	server := newScrapedPageServer(t, scrapedPage)
+	fs := NewFeedService(db, nil)
+	fs.SetHTTPClient(&mockHTTPClient{Server: server})
+
+	selectors := HTMLFeedSelectors{Item: "li.post", Title: "h3"}
+
+	feedURL, preview, err := fs.PreviewHTMLFeed(server.URL+"/changelog", selectors)
+	if err != nil {
+		t.Fatalf("PreviewHTMLFeed: %v", err)
+	}
+	if !isHTMLFeedURL(feedURL) || len(preview.Articles) != 2 {
+		t.Fatalf("unexpected preview: %q, %+v", feedURL, preview)
+	}
+	if feeds, _ := db.GetFeeds(); len(feeds) != 0 {
+		t.Errorf("expected a preview to save nothing, got %d feeds", len(feeds))
+	}
+
+	if _, err := fs.AddHTMLFeedForUser(user.ID, server.URL, HTMLFeedSelectors{Item: "table.none"}); !errors.Is(err, ErrNoScrapedItems) {
+		t.Errorf("expected ErrNoScrapedItems, got %v", err)
+	}
+	if _, err := fs.AddHTMLFeedForUser(user.ID, server.URL, HTMLFeedSelectors{Item: "li["}); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("expected ErrInvalidSelector, got %v", err)
+	}
+
+	feed, err := fs.AddHTMLFeedForUser(user.ID, server.URL+"/changelog", selectors)
+	if err != nil {
+		t.Fatalf("AddHTMLFeedForUser: %v", err)
+	}
+	if feed.URL != feedURL || feed.Title != "Example Changelog" {
+		t.Errorf("unexpected feed: %+v", feed)
+	}
+	articles, err := db.GetArticles(feed.ID)
+	if err != nil || len(articles) != 2 {
+		t.Fatalf("expected 2 articles, got %d, %v", len(articles), err)
+	}
+
+	// Refreshing re-scrapes the page and only stores entries it hasn't seen.
+	feedData, err := fs.fetchFeed(t.Context(), feed.URL)
+	if err != nil {
+		t.Fatalf("fetchFeed: %v", err)
+	}
+	if saved, _ := fs.saveArticlesFromFeed(feed.ID, feedData); saved != 0 {
+		t.Errorf("expected a refresh to save nothing new, saved %d", saved)
+	}
+
+	opml, err := fs.ExportOPML(user.ID)
+	if err != nil {
+		t.Fatalf("ExportOPML: %v", err)
+	}
+	if strings.Contains(string(opml), htmlFeedURLPrefix) {
+		t.Errorf("expected HTML feeds to be left out of OPML exports:\n%s", opml)
+	}
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
+)
+
+var (
+	// ErrInvalidImport indicates an export file that can't be read or an unknown format
+	ErrInvalidImport = errors.New("invalid import")
+
+	// ErrImportNotFound indicates an import job that doesn't exist or belongs to another user
+	ErrImportNotFound = errors.New("import not found")
+
+	// ErrImportInProgress indicates the user already has an import running
+	ErrImportInProgress = errors.New("an import is already running")
+)
+
+// Import job states
+const (
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+const (
+	// importJobRetention is how long a finished job's result can be fetched.
+	importJobRetention = time.Hour
+
+	// importStatusBatchSize bounds each BatchSetUserArticleStatus call so a
+	// Datastore write stays within one 500-entity commit.
+	importStatusBatchSize = 500
+
+	// importProgressInterval is how many articles pass between progress events.
+	importProgressInterval = 100
+)
+
+// ImportJob reports the progress of one reader import.
+type ImportJob struct {
+	ID              string     `json:"id"`
+	Format          string     `json:"format"`
+	Status          string     `json:"status"`
+	Total           int        `json:"total"`
+	Processed       int        `json:"processed"`
+	FeedsCreated    int        `json:"feeds_created"`
+	FeedsSubscribed int        `json:"feeds_subscribed"`
+	NotFound        int        `json:"not_found"` // Articles GoRead2 doesn't have (yet), so their state wasn't imported
+	Read            int        `json:"read"`
+	Starred         int        `json:"starred"`
+	Skipped         int        `json:"skipped"`
+	LimitReached    bool       `json:"limit_reached,omitempty"` // Some feeds weren't subscribed because of the feed limit
+	Error           string     `json:"error,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+
+	userID int
+}
+
+// ImportService brings read and starred state over from other readers'
+// exports. Only the state is imported: feeds are shared between users, so
+// articles in an upload are never stored, and items GoRead2 hasn't fetched
+// itself are counted as not found. Imports run in the background; jobs are
+// kept in memory, so a job's progress is only visible on the instance
+// running it and an import cut short by a restart has to be started again.
+// Re-running an import is harmless: articles are matched by URL and state is
+// only ever added.
+type ImportService struct {
+	db                  database.Database
+	feedService         *FeedService
+	subscriptionService *SubscriptionService
+	events              events.Bus // Optional: if nil, progress is only available by polling
+
+	mu   sync.Mutex
+	jobs map[string]*ImportJob
+}
+
+func NewImportService(db database.Database, feedService *FeedService, subscriptionService *SubscriptionService) *ImportService {
+	return &ImportService{
+		db:                  db,
+		feedService:         feedService,
+		subscriptionService: subscriptionService,
+		jobs:                make(map[string]*ImportJob),
+	}
+}
+
+// SetEventBus sets the bus that import_progress events are published to.
+func (s *ImportService) SetEventBus(bus events.Bus) {
+	s.events = bus
+}
+
+// StartImport parses the export and starts importing it in the background.
+// The file is checked before the job starts, so a bad upload is reported
+// straight away as ErrInvalidImport.
+func (s *ImportService) StartImport(userID int, format string, data []byte) (*ImportJob, error) {
+	items, dropped, err := ParseReaderExport(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: no articles found in the export", ErrInvalidImport)
+	}
+
+	id, err := generateImportID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate import ID: %w", err)
+	}
+
+	s.mu.Lock()
+	s.pruneJobs()
+	for _, j := range s.jobs {
+		if j.userID == userID && j.Status == ImportRunning {
+			s.mu.Unlock()
+			return nil, ErrImportInProgress
+		}
+	}
+	job := &ImportJob{
+		ID:        id,
+		Format:    format,
+		Status:    ImportRunning,
+		Total:     len(items),
+		Skipped:   dropped,
+		StartedAt: time.Now(),
+		userID:    userID,
+	}
+	s.jobs[id] = job
+	snapshot := *job
+	s.mu.Unlock()
+
+	log.Printf("User %d started a %s import of %d articles (import %s)", userID, format, len(items), id)
+	go s.run(job, items)
+	return &snapshot, nil
+}
+
+// GetJob returns the current state of one of the user's imports.
+func (s *ImportService) GetJob(userID int, id string) (*ImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.userID != userID {
+		return nil, ErrImportNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// pruneJobs forgets jobs that finished more than importJobRetention ago.
+// Callers must hold s.mu.
+func (s *ImportService) pruneJobs() {
+	cutoff := time.Now().Add(-importJobRetention)
+	for id, j := range s.jobs {
+		if j.FinishedAt != nil && j.FinishedAt.Before(cutoff) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// update applies fn to the job under the lock and returns a copy of the result.
+func (s *ImportService) update(job *ImportJob, fn func(j *ImportJob)) ImportJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(job)
+	return *job
+}
+
+func (s *ImportService) publishProgress(job ImportJob) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{Type: events.TypeImportProgress, UserID: job.userID, Data: job})
+}
+
+func (s *ImportService) run(job *ImportJob, items []ImportedItem) {
+	err := s.apply(job, items)
+	snapshot := s.update(job, func(j *ImportJob) {
+		now := time.Now()
+		j.FinishedAt = &now
+		if err != nil {
+			j.Status = ImportFailed
+			j.Error = "The import stopped before finishing. Articles imported so far were kept; run it again to finish."
+			return
+		}
+		j.Status = ImportCompleted
+	})
+	s.publishProgress(snapshot)
+
+	if err != nil {
+		log.Printf("Import %s for user %d failed after %d/%d articles: %v", job.ID, job.userID, snapshot.Processed, snapshot.Total, err)
+		return
+	}
+	log.Printf("Import %s for user %d finished: %d feeds created, %d subscribed, %d read, %d starred, %d not found, %d skipped",
+		job.ID, job.userID, snapshot.FeedsCreated, snapshot.FeedsSubscribed, snapshot.Read, snapshot.Starred, snapshot.NotFound, snapshot.Skipped)
+}
+
+// importStatus is the state an import wants an article to end up in.
+type importStatus struct {
+	article database.Article
+	read    bool
+	starred bool
+}
+
+// importRun holds the lookups shared across one job's articles.
+type importRun struct {
+	job        *ImportJob
+	subscribed map[int]bool   // feed IDs the user is subscribed to
+	feeds      map[string]int // export feed URL → feed ID; 0 when unusable
+	statuses   map[string]int // article URL → index in wanted; -1 when not found
+	wanted     []importStatus
+	newFeeds   bool
+}
+
+// apply creates the missing feeds, subscribes the user to them and then
+// writes the read and starred flags.
+func (s *ImportService) apply(job *ImportJob, items []ImportedItem) error {
+	userFeeds, err := s.db.GetUserFeeds(job.userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	run := &importRun{
+		job:        job,
+		subscribed: make(map[int]bool, len(userFeeds)),
+		feeds:      make(map[string]int),
+		statuses:   make(map[string]int),
+	}
+	for _, f := range userFeeds {
+		run.subscribed[f.ID] = true
+	}
+
+	for i, item := range items {
+		if err := s.importItem(run, item); err != nil {
+			return err
+		}
+		snapshot := s.update(job, func(j *ImportJob) { j.Processed++ })
+		if (i+1)%importProgressInterval == 0 {
+			s.publishProgress(snapshot)
+		}
+	}
+
+	if err := s.writeStatuses(run); err != nil {
+		return err
+	}
+
+	s.feedService.unreadCache.Invalidate(job.userID)
+	if run.newFeeds {
+		s.feedService.feedListCache.Invalidate()
+	}
+	feedIDs := make([]int, len(run.wanted))
+	for i, w := range run.wanted {
+		feedIDs[i] = w.article.FeedID
+	}
+	s.feedService.publishUnreadCounts(job.userID, feedIDs...)
+	return nil
+}
+
+// importItem matches the item to a stored article and records the state it
+// should get. Articles are matched by URL across all feeds; an article
+// already stored under another feed keeps that feed. An item with no stored
+// article still gets its feed subscribed, so importing again once the feed
+// has been fetched brings its state over.
+func (s *ImportService) importItem(run *importRun, item ImportedItem) error {
+	if i, ok := run.statuses[item.URL]; ok {
+		if i >= 0 {
+			// The same article listed twice: keep whichever flags either copy set
+			run.wanted[i].read = run.wanted[i].read || item.Read
+			run.wanted[i].starred = run.wanted[i].starred || item.Starred
+		}
+		return nil
+	}
+
+	article, err := s.db.FindArticleByURL(item.URL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	feedID := 0
+	if article != nil {
+		feedID = article.FeedID
+	} else if feedID, err = s.importFeed(run, item); err != nil {
+		return err
+	}
+	if feedID == 0 {
+		s.update(run.job, func(j *ImportJob) { j.Skipped++ })
+		return nil
+	}
+
+	ok, err := s.ensureSubscribed(run, feedID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.update(run.job, func(j *ImportJob) { j.Skipped++ })
+		return nil
+	}
+
+	if article == nil {
+		run.statuses[item.URL] = -1
+		s.update(run.job, func(j *ImportJob) { j.NotFound++ })
+		return nil
+	}
+	run.statuses[item.URL] = len(run.wanted)
+	run.wanted = append(run.wanted, importStatus{
+		article: database.Article{ID: article.ID, FeedID: article.FeedID},
+		read:    item.Read,
+		starred: item.Starred,
+	})
+	return nil
+}
+
+// importFeed returns the ID of the item's feed, creating the feed if it isn't
+// stored yet. New feeds aren't fetched here; the scheduler fetches them like
+// any other feed, checking the URL again before it does. It returns 0 when
+// the item has no usable feed URL.
+func (s *ImportService) importFeed(run *importRun, item ImportedItem) (int, error) {
+	if id, ok := run.feeds[item.FeedURL]; ok {
+		return id, nil
+	}
+	if !s.validImportFeedURL(item.FeedURL) {
+		run.feeds[item.FeedURL] = 0
+		return 0, nil
+	}
+
+	feed, err := s.db.GetFeedByURL(item.FeedURL)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if feed == nil {
+		title := item.FeedTitle
+		if title == "" {
+			title = item.FeedURL
+		}
+		now := time.Now()
+		feed = &database.Feed{Title: title, URL: item.FeedURL, CreatedAt: now, UpdatedAt: now}
+		if err := s.db.AddFeed(feed); err != nil {
+			return 0, fmt.Errorf("%w: failed to insert feed: %v", ErrDatabaseError, err)
+		}
+		s.update(run.job, func(j *ImportJob) { j.FeedsCreated++ })
+	}
+	run.feeds[item.FeedURL] = feed.ID
+	return feed.ID, nil
+}
+
+// validImportFeedURL rejects feed URLs the scheduler could never fetch: other
+// schemes, missing hosts, credentials and literal private addresses.
+func (s *ImportService) validImportFeedURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" || parsed.User != nil {
+		return false
+	}
+	if !s.feedService.urlValidator.AllowedSchemes[parsed.Scheme] {
+		return false
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil {
+		if err := s.feedService.urlValidator.isBlockedIP(ip); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureSubscribed subscribes the user to the feed unless they already are.
+// It reports false, without an error, once the user's feed limit is reached.
+func (s *ImportService) ensureSubscribed(run *importRun, feedID int) (bool, error) {
+	if run.subscribed[feedID] {
+		return true, nil
+	}
+
+	userID := run.job.userID
+	if err := s.subscriptionService.CanUserAddFeed(userID); err != nil {
+		if errors.Is(err, ErrFeedLimitReached) || errors.Is(err, ErrTrialExpired) {
+			s.update(run.job, func(j *ImportJob) { j.LimitReached = true })
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if err := s.db.SubscribeUserToFeed(userID, feedID); err != nil {
+		return false, fmt.Errorf("%w: failed to subscribe user to feed: %v", ErrDatabaseError, err)
+	}
+	// Same as adding the feed by hand; the imported flags are written afterwards
+	if err := s.feedService.markExistingArticlesAsUnreadForUser(userID, feedID); err != nil {
+		log.Printf("Failed to mark existing articles as unread for user %d, feed %d: %v", userID, feedID, err)
+	}
+
+	run.subscribed[feedID] = true
+	run.newFeeds = true
+	s.update(run.job, func(j *ImportJob) { j.FeedsSubscribed++ })
+	return true, nil
+}
+
+// writeStatuses sets the imported flags with at most four batch writes per
+// chunk, one per (read, starred) pair. Flags the user already set here are
+// kept, so an import never unstars or marks unread.
+func (s *ImportService) writeStatuses(run *importRun) error {
+	userID := run.job.userID
+	for start := 0; start < len(run.wanted); start += importStatusBatchSize {
+		end := start + importStatusBatchSize
+		if end > len(run.wanted) {
+			end = len(run.wanted)
+		}
+		chunk := run.wanted[start:end]
+
+		ids := make([]int, len(chunk))
+		for i, w := range chunk {
+			ids[i] = w.article.ID
+		}
+		existing, err := s.db.GetUserArticleStatuses(userID, ids)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+
+		type state struct{ read, starred bool }
+		groups := make(map[state][]database.Article)
+		read, starred := 0, 0
+		for _, w := range chunk {
+			current := existing[w.article.ID]
+			st := state{w.read || current.IsRead, w.starred || current.IsStarred}
+			if st == (state{current.IsRead, current.IsStarred}) {
+				continue
+			}
+			groups[st] = append(groups[st], w.article)
+			if w.read && !current.IsRead {
+				read++
+			}
+			if w.starred && !current.IsStarred {
+				starred++
+			}
+		}
+		for st, articles := range groups {
+			if err := s.db.BatchSetUserArticleStatus(userID, articles, st.read, st.starred); err != nil {
+				return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+			}
+		}
+		s.update(run.job, func(j *ImportJob) {
+			j.Read += read
+			j.Starred += starred
+		})
+	}
+	return nil
+}
+
+func generateImportID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func newImportServiceWithRealDB(t *testing.T) (*ImportService, *database.DB) {
+	t.Helper()
+	db := setupTestDB(t)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewImportService(db, NewFeedService(db, nil), NewSubscriptionService(db)), db
+}
+
+func waitForImport(t *testing.T, s *ImportService, userID int, id string) *ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := s.GetJob(userID, id)
+		if err != nil {
+			t.Fatalf("GetJob: %v", err)
+		}
+		if job.Status != ImportRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("import %s still running after 5s: %+v", id, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestImportService_SubscribesFeedsWithoutCreatingArticles(t *testing.T) {
+	s, db := newImportServiceWithRealDB(t)
+	user := createTestUser(t, db)
+
+	export := `{"entries": [
+	  {"url": "https://imported.example.com/1", "title": "One", "status": "read", "starred": true,
+	   "feed": {"feed_url": "https://imported.example.com/feed", "title": "Imported"}},
+	  {"url": "https://imported.example.com/2", "title": "Two", "status": "unread", "starred": false,
+	   "feed": {"feed_url": "https://imported.example.com/feed", "title": "Imported"}},
+	  {"url": "https://imported.example.com/1", "title": "One again", "status": "unread", "starred": false,
+	   "feed": {"feed_url": "https://imported.example.com/feed", "title": "Imported"}},
+	  {"url": "https://private.example.com/1", "title": "Private", "status": "read",
+	   "feed": {"feed_url": "http://127.0.0.1/feed", "title": "Private"}}
+	]}`
+
+	started, err := s.StartImport(user.ID, ImportFormatMiniflux, []byte(export))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	job := waitForImport(t, s, user.ID, started.ID)
+
+	if job.Status != ImportCompleted || job.Processed != 4 || job.Total != 4 {
+		t.Fatalf("expected a completed job over 4 items, got %+v", job)
+	}
+	if job.FeedsCreated != 1 || job.FeedsSubscribed != 1 || job.NotFound != 2 {
+		t.Errorf("expected 1 feed created and 2 articles not found, got %+v", job)
+	}
+	if job.Read != 0 || job.Starred != 0 || job.Skipped != 1 {
+		t.Errorf("expected no state written and the private feed skipped, got %+v", job)
+	}
+
+	feed, err := db.GetFeedByURL("https://imported.example.com/feed")
+	if err != nil || feed == nil || feed.Title != "Imported" {
+		t.Fatalf("expected the feed created, got %+v, %v", feed, err)
+	}
+	if private, _ := db.GetFeedByURL("http://127.0.0.1/feed"); private != nil {
+		t.Error("expected no feed created for a private address")
+	}
+
+	articles, err := db.GetUserFeedArticles(user.ID, feed.ID)
+	if err != nil || len(articles) != 0 {
+		t.Fatalf("expected no articles stored from the upload, got %d, %v", len(articles), err)
+	}
+}
+
+func TestImportService_LeavesSharedFeedsAlone(t *testing.T) {
+	s, db := newImportServiceWithRealDB(t)
+	importer := createTestUser(t, db)
+	other := &database.User{GoogleID: "other-subscriber", Email: "other@example.com", Name: "Other", CreatedAt: time.Now()}
+	if err := db.CreateUser(other); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+	if err := db.SubscribeUserToFeed(other.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	before, err := db.GetUserFeedArticles(other.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("GetUserFeedArticles: %v", err)
+	}
+
+	// Someone else's upload names the shared feed, with an article of their
+	// own making and a changed copy of one that exists
+	export := `[
+	  {"url": "https://attacker.example.com/phish", "title": "Reset your password", "content": "<a href=\"https://attacker.example.com\">here</a>",
+	   "status": "read", "feed": {"feed_url": "` + feed.URL + `"}},
+	  {"url": "` + article.URL + `", "title": "Changed title", "content": "changed", "status": "read", "starred": true,
+	   "feed": {"feed_url": "` + feed.URL + `"}}
+	]`
+	started, err := s.StartImport(importer.ID, ImportFormatMiniflux, []byte(export))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	job := waitForImport(t, s, importer.ID, started.ID)
+	if job.Status != ImportCompleted || job.NotFound != 1 || job.Read != 1 || job.Starred != 1 {
+		t.Fatalf("expected the existing article's state imported and the other item not found, got %+v", job)
+	}
+
+	after, err := db.GetUserFeedArticles(other.ID, feed.ID)
+	if err != nil {
+		t.Fatalf("GetUserFeedArticles: %v", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected the other subscriber's articles unchanged, had %d, now %d", len(before), len(after))
+	}
+	for i := range after {
+		if after[i].ID != before[i].ID || after[i].Title != before[i].Title || after[i].Content != before[i].Content ||
+			after[i].IsRead != before[i].IsRead || after[i].IsStarred != before[i].IsStarred {
+			t.Errorf("expected the other subscriber's article unchanged, was %+v, now %+v", before[i], after[i])
+		}
+	}
+
+	status, err := db.GetUserArticleStatus(importer.ID, article.ID)
+	if err != nil || status == nil || !status.IsRead || !status.IsStarred {
+		t.Errorf("expected the importer's copy read and starred, got %+v, %v", status, err)
+	}
+}
+
+func TestImportService_KeepsExistingState(t *testing.T) {
+	s, db := newImportServiceWithRealDB(t)
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	if err := db.SetUserArticleStatus(user.ID, article.ID, false, true); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+
+	// The export has the article read but not starred; the star set here stays
+	export := `[{"url": "` + article.URL + `", "title": "x", "status": "read", "starred": false,
+	  "feed": {"feed_url": "` + feed.URL + `"}}]`
+	started, err := s.StartImport(user.ID, ImportFormatMiniflux, []byte(export))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	job := waitForImport(t, s, user.ID, started.ID)
+	if job.Status != ImportCompleted || job.NotFound != 0 || job.FeedsSubscribed != 0 {
+		t.Fatalf("expected the existing article matched by URL, got %+v", job)
+	}
+
+	status, err := db.GetUserArticleStatus(user.ID, article.ID)
+	if err != nil || status == nil || !status.IsRead || !status.IsStarred {
+		t.Errorf("expected the article read and still starred, got %+v, %v", status, err)
+	}
+}
+
+func TestImportService_Errors(t *testing.T) {
+	s, db := newImportServiceWithRealDB(t)
+	user := createTestUser(t, db)
+
+	if _, err := s.StartImport(user.ID, ImportFormatMiniflux, []byte(`{"entries": []}`)); !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("expected ErrInvalidImport for an empty export, got %v", err)
+	}
+	if _, err := s.StartImport(user.ID, "netnewswire", []byte(`[]`)); !errors.Is(err, ErrInvalidImport) {
+		t.Errorf("expected ErrInvalidImport for an unknown format, got %v", err)
+	}
+
+	started, err := s.StartImport(user.ID, ImportFormatMiniflux,
+		[]byte(`[{"url": "https://one.example.com/1", "feed": {"feed_url": "https://one.example.com/feed"}}]`))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	if _, err := s.GetJob(user.ID+1, started.ID); !errors.Is(err, ErrImportNotFound) {
+		t.Errorf("expected another user's import to be hidden, got %v", err)
+	}
+	waitForImport(t, s, user.ID, started.ID)
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Export formats accepted by ParseReaderExport. Inoreader and FreshRSS write
+// the Google Reader JSON format, so they share its parser.
+const (
+	ImportFormatGoogleReader = "google-reader"
+	ImportFormatInoreader    = "inoreader"
+	ImportFormatFreshRSS     = "freshrss"
+	ImportFormatFeedly       = "feedly"
+	ImportFormatMiniflux     = "miniflux"
+)
+
+// maxImportItems caps how many articles one export may hold.
+const maxImportItems = 50000
+
+// ImportFormats lists the accepted export formats.
+func ImportFormats() []string {
+	return []string{ImportFormatGoogleReader, ImportFormatFeedly, ImportFormatInoreader, ImportFormatMiniflux, ImportFormatFreshRSS}
+}
+
+// ImportedItem is one article from another reader's export, with the feed it
+// came from and the user's state for it.
+type ImportedItem struct {
+	FeedURL     string
+	FeedTitle   string
+	URL         string
+	Title       string
+	Author      string
+	Content     string
+	Summary     string
+	PublishedAt time.Time
+	Read        bool
+	Starred     bool
+}
+
+// ParseReaderExport reads an export in the given format. Items without a
+// usable article URL are dropped; the returned count says how many. Errors
+// about the file itself wrap ErrInvalidImport.
+func ParseReaderExport(format string, data []byte) ([]ImportedItem, int, error) {
+	var items []ImportedItem
+	var dropped int
+	var err error
+	switch format {
+	case ImportFormatGoogleReader, ImportFormatInoreader, ImportFormatFreshRSS:
+		items, dropped, err = parseGoogleReaderExport(data, false)
+	case ImportFormatFeedly:
+		items, dropped, err = parseGoogleReaderExport(data, true)
+	case ImportFormatMiniflux:
+		items, dropped, err = parseMinifluxExport(data)
+	default:
+		return nil, 0, fmt.Errorf("%w: unknown format %q", ErrInvalidImport, format)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(items) > maxImportItems {
+		return nil, 0, fmt.Errorf("%w: export holds %d articles; at most %d can be imported at once", ErrInvalidImport, len(items), maxImportItems)
+	}
+	return items, dropped, nil
+}
+
+type readerLink struct {
+	Href string `json:"href"`
+}
+
+type readerText struct {
+	Content string `json:"content"`
+}
+
+// readerItem covers the Google Reader item shape and Feedly's variant of it,
+// which adds unread, tags and canonicalUrl and gives times in milliseconds.
+type readerItem struct {
+	ID           string       `json:"id"`
+	OriginID     string       `json:"originId"`
+	Title        string       `json:"title"`
+	Author       string       `json:"author"`
+	Published    int64        `json:"published"`
+	Canonical    []readerLink `json:"canonical"`
+	CanonicalURL string       `json:"canonicalUrl"`
+	Alternate    []readerLink `json:"alternate"`
+	Summary      readerText   `json:"summary"`
+	Content      readerText   `json:"content"`
+	Categories   []string     `json:"categories"`
+	Unread       *bool        `json:"unread"`
+	Tags         []struct {
+		ID string `json:"id"`
+	} `json:"tags"`
+	Origin struct {
+		StreamID string `json:"streamId"`
+		Title    string `json:"title"`
+	} `json:"origin"`
+}
+
+// parseGoogleReaderExport reads {"items": [...]} or a bare array of items.
+// Google Reader records state as categories ending in /state/com.google/read
+// and /state/com.google/starred; Feedly uses the unread flag and a
+// global.saved tag for Saved for Later.
+func parseGoogleReaderExport(data []byte, feedly bool) ([]ImportedItem, int, error) {
+	var raw []readerItem
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+	} else {
+		var doc struct {
+			Items []readerItem `json:"items"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		raw = doc.Items
+	}
+
+	items := make([]ImportedItem, 0, len(raw))
+	dropped := 0
+	for _, r := range raw {
+		item := ImportedItem{
+			FeedURL:   strings.TrimPrefix(r.Origin.StreamID, "feed/"),
+			FeedTitle: r.Origin.Title,
+			URL:       firstHTTPURL(linkHref(r.Canonical), r.CanonicalURL, linkHref(r.Alternate), r.OriginID, r.ID),
+			Title:     r.Title,
+			Author:    r.Author,
+			Content:   r.Content.Content,
+			Summary:   r.Summary.Content,
+		}
+		if r.Published > 0 {
+			if feedly || r.Published > 1e11 {
+				item.PublishedAt = time.UnixMilli(r.Published).UTC()
+			} else {
+				item.PublishedAt = time.Unix(r.Published, 0).UTC()
+			}
+		}
+		for _, c := range r.Categories {
+			switch {
+			case strings.HasSuffix(c, "/state/com.google/read"):
+				item.Read = true
+			case strings.HasSuffix(c, "/state/com.google/starred"):
+				item.Starred = true
+			}
+		}
+		if r.Unread != nil {
+			item.Read = !*r.Unread
+		}
+		for _, t := range r.Tags {
+			if strings.HasSuffix(t.ID, "/tag/global.saved") {
+				item.Starred = true
+			}
+		}
+		if item.URL == "" {
+			dropped++
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, dropped, nil
+}
+
+// minifluxEntry is an entry from Miniflux's /v1/entries API response.
+type minifluxEntry struct {
+	URL         string    `json:"url"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	Content     string    `json:"content"`
+	PublishedAt time.Time `json:"published_at"`
+	Status      string    `json:"status"`
+	Starred     bool      `json:"starred"`
+	Feed        struct {
+		FeedURL string `json:"feed_url"`
+		Title   string `json:"title"`
+	} `json:"feed"`
+}
+
+// parseMinifluxExport reads the {"total": n, "entries": [...]} body returned
+// by Miniflux's entries API, or a bare array of entries.
+func parseMinifluxExport(data []byte) ([]ImportedItem, int, error) {
+	var raw []minifluxEntry
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+	} else {
+		var doc struct {
+			Entries []minifluxEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		raw = doc.Entries
+	}
+
+	items := make([]ImportedItem, 0, len(raw))
+	dropped := 0
+	for _, e := range raw {
+		item := ImportedItem{
+			FeedURL:     e.Feed.FeedURL,
+			FeedTitle:   e.Feed.Title,
+			URL:         firstHTTPURL(e.URL),
+			Title:       e.Title,
+			Author:      e.Author,
+			Content:     e.Content,
+			PublishedAt: e.PublishedAt,
+			Read:        e.Status == "read",
+			Starred:     e.Starred,
+		}
+		if item.URL == "" {
+			dropped++
+			continue
+		}
+		items = append(items, item)
+	}
+	return items, dropped, nil
+}
+
+func linkHref(links []readerLink) string {
+	if len(links) == 0 {
+		return ""
+	}
+	return links[0].Href
+}
+
+// firstHTTPURL returns the first candidate that is an absolute http(s) URL.
+// Article GUIDs are often such URLs, so they stand in for a missing link.
+func firstHTTPURL(candidates ...string) string {
+	for _, c := range candidates {
+		c = strings.TrimSpace(c)
+		if u, err := url.Parse(c); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return c
+		}
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const googleReaderStarredExport = `{
+  "id": "user/-/state/com.google/starred",
+  "items": [
+    {
+      "id": "tag:google.com,2005:reader/item/0001",
+      "title": "Read and starred",
+      "published": 1700000000,
+      "author": "Ann",
+      "canonical": [{"href": "https://blog.example.com/1"}],
+      "alternate": [{"href": "https://blog.example.com/1?utm=x", "type": "text/html"}],
+      "content": {"content": "<p>Body</p>"},
+      "categories": ["user/123/state/com.google/read", "user/123/state/com.google/starred"],
+      "origin": {"streamId": "feed/https://blog.example.com/feed", "title": "Blog"}
+    },
+    {
+      "id": "https://blog.example.com/2",
+      "title": "Only a GUID",
+      "categories": ["user/-/state/com.google/starred"],
+      "origin": {"streamId": "feed/https://blog.example.com/feed", "title": "Blog"}
+    },
+    {
+      "id": "tag:google.com,2005:reader/item/0003",
+      "title": "No URL at all",
+      "origin": {"streamId": "feed/https://blog.example.com/feed"}
+    }
+  ]
+}`
+
+func TestParseGoogleReaderExport(t *testing.T) {
+	for _, format := range []string{ImportFormatGoogleReader, ImportFormatInoreader, ImportFormatFreshRSS} {
+		items, dropped, err := ParseReaderExport(format, []byte(googleReaderStarredExport))
+		if err != nil {
+			t.Fatalf("%s: ParseReaderExport: %v", format, err)
+		}
+		if len(items) != 2 || dropped != 1 {
+			t.Fatalf("%s: expected 2 items and 1 dropped, got %d and %d", format, len(items), dropped)
+		}
+
+		first := items[0]
+		if first.URL != "https://blog.example.com/1" || first.FeedURL != "https://blog.example.com/feed" || first.FeedTitle != "Blog" {
+			t.Errorf("%s: unexpected first item %+v", format, first)
+		}
+		if !first.Read || !first.Starred {
+			t.Errorf("%s: expected the first item read and starred", format)
+		}
+		if !first.PublishedAt.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("%s: expected published time in seconds, got %v", format, first.PublishedAt)
+		}
+		if items[1].URL != "https://blog.example.com/2" || items[1].Read || !items[1].Starred {
+			t.Errorf("%s: expected the GUID used as the URL and the item starred only, got %+v", format, items[1])
+		}
+	}
+}
+
+func TestParseFeedlyExport(t *testing.T) {
+	export := `[
+	  {
+	    "id": "entry-1",
+	    "originId": "https://news.example.com/a",
+	    "title": "Saved",
+	    "published": 1700000000000,
+	    "unread": false,
+	    "tags": [{"id": "user/abc/tag/global.saved", "label": "Saved For Later"}],
+	    "summary": {"content": "Summary"},
+	    "origin": {"streamId": "feed/https://news.example.com/rss", "title": "News"}
+	  },
+	  {
+	    "id": "entry-2",
+	    "canonicalUrl": "https://news.example.com/b",
+	    "title": "Unread",
+	    "unread": true,
+	    "origin": {"streamId": "feed/https://news.example.com/rss", "title": "News"}
+	  }
+	]`
+
+	items, dropped, err := ParseReaderExport(ImportFormatFeedly, []byte(export))
+	if err != nil {
+		t.Fatalf("ParseReaderExport: %v", err)
+	}
+	if len(items) != 2 || dropped != 0 {
+		t.Fatalf("expected 2 items, got %d (%d dropped)", len(items), dropped)
+	}
+	if items[0].URL != "https://news.example.com/a" || !items[0].Read || !items[0].Starred {
+		t.Errorf("expected the saved entry read and starred, got %+v", items[0])
+	}
+	if !items[0].PublishedAt.Equal(time.UnixMilli(1700000000000)) {
+		t.Errorf("expected published time in milliseconds, got %v", items[0].PublishedAt)
+	}
+	if items[1].URL != "https://news.example.com/b" || items[1].Read || items[1].Starred {
+		t.Errorf("expected the second entry unread and unstarred, got %+v", items[1])
+	}
+}
+
+func TestParseMinifluxExport(t *testing.T) {
+	export := `{
+	  "total": 2,
+	  "entries": [
+	    {"url": "https://mf.example.com/1", "title": "One", "status": "read", "starred": true,
+	     "published_at": "2024-05-01T10:00:00Z", "feed": {"feed_url": "https://mf.example.com/feed.xml", "title": "MF"}},
+	    {"url": "https://mf.example.com/2", "title": "Two", "status": "unread", "starred": false,
+	     "published_at": "2024-05-02T10:00:00Z", "feed": {"feed_url": "https://mf.example.com/feed.xml", "title": "MF"}}
+	  ]
+	}`
+
+	items, _, err := ParseReaderExport(ImportFormatMiniflux, []byte(export))
+	if err != nil {
+		t.Fatalf("ParseReaderExport: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+	if !items[0].Read || !items[0].Starred || items[0].FeedURL != "https://mf.example.com/feed.xml" {
+		t.Errorf("unexpected first item %+v", items[0])
+	}
+	if items[1].Read || items[1].Starred {
+		t.Errorf("expected the second item unread and unstarred, got %+v", items[1])
+	}
+}
+
+func TestParseReaderExportErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"unknown format", "netnewswire", `{"items":[]}`},
+		{"not JSON", ImportFormatGoogleReader, `<opml/>`},
+		{"wrong shape", ImportFormatMiniflux, `{"entries": "none"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParseReaderExport(tt.format, []byte(tt.data)); !errors.Is(err, ErrInvalidImport) {
+				t.Errorf("expected ErrInvalidImport, got %v", err)
+			}
+		})
+	}
+}
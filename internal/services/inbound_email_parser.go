@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+const (
+	// maxInboundMIMEDepth and maxInboundMIMEParts bound the work done on a
+	// message; real newsletters nest two or three levels with a handful of parts.
+	maxInboundMIMEDepth = 10
+	maxInboundMIMEParts = 200
+)
+
+// inboundEmail holds the parts of a received message that become an article.
+type inboundEmail struct {
+	MessageID   string
+	Subject     string
+	FromName    string
+	FromAddress string
+	Date        time.Time // zero when the Date header is missing or invalid
+	HTML        string    // first text/html body, unsanitised
+	Text        string    // first text/plain body
+	Recipients  []string  // addresses from Delivered-To, X-Original-To, To and Cc
+}
+
+// inboundWordDecoder decodes RFC 2047 encoded words in any charset the HTML
+// spec knows, not just the UTF-8, US-ASCII and ISO-8859-1 that mime supports.
+var inboundWordDecoder = &mime.WordDecoder{CharsetReader: inboundCharsetReader}
+
+func inboundCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// parseInboundEmail parses a raw RFC 822 message. Attachments and non-text
+// parts are skipped; a message without a text or HTML body is an error.
+func parseInboundEmail(raw []byte) (*inboundEmail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+
+	email := &inboundEmail{
+		MessageID: strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>"),
+		Subject:   decodeInboundHeader(msg.Header.Get("Subject")),
+	}
+
+	parser := &mail.AddressParser{WordDecoder: inboundWordDecoder}
+	if from, err := parser.Parse(msg.Header.Get("From")); err == nil {
+		email.FromName = from.Name
+		email.FromAddress = from.Address
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		email.Date = date
+	}
+	for _, field := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, value := range msg.Header[field] {
+			list, err := parser.ParseList(value)
+			if err != nil {
+				continue
+			}
+			for _, addr := range list {
+				email.Recipients = append(email.Recipients, addr.Address)
+			}
+		}
+	}
+
+	walker := &inboundPartWalker{email: email}
+	if err := walker.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		return nil, err
+	}
+	if email.HTML == "" && email.Text == "" {
+		return nil, errors.New("message has no text or HTML body")
+	}
+	return email, nil
+}
+
+func decodeInboundHeader(value string) string {
+	decoded, err := inboundWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(decoded)
+}
+
+type inboundPartWalker struct {
+	email *inboundEmail
+	parts int
+}
+
+// walk descends into multipart bodies and keeps the first HTML and the first
+// plain-text part it finds. In multipart/alternative the plain-text version
+// comes first, so both are usually found.
+func (w *inboundPartWalker) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045: a missing or unreadable Content-Type means plain text.
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxInboundMIMEDepth {
+			return errors.New("MIME parts are nested too deeply")
+		}
+		boundary := params["boundary"]
+		if boundary == "" {
+			return errors.New("multipart body has no boundary")
+		}
+		reader := multipart.NewReader(body, boundary)
+		for {
+			// NextRawPart leaves Content-Transfer-Encoding to decodeInboundPart,
+			// which handles every part the same way.
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			w.parts++
+			if w.parts > maxInboundMIMEParts {
+				return errors.New("message has too many MIME parts")
+			}
+			if err := w.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if disposition, _, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && disposition == "attachment" {
+		return nil
+	}
+	switch {
+	case mediaType == "text/html" && w.email.HTML == "":
+		text, err := decodeInboundPart(header, params["charset"], body)
+		if err != nil {
+			return err
+		}
+		w.email.HTML = text
+	case mediaType == "text/plain" && w.email.Text == "":
+		text, err := decodeInboundPart(header, params["charset"], body)
+		if err != nil {
+			return err
+		}
+		w.email.Text = text
+	}
+	return nil
+}
+
+// decodeInboundPart undoes the part's transfer encoding and converts it to
+// UTF-8. Unknown charsets are read as-is with invalid bytes replaced.
+func decodeInboundPart(header textproto.MIMEHeader, charset string, body io.Reader) (string, error) {
+	reader := body
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		reader = quotedprintable.NewReader(reader)
+	case "base64":
+		reader = base64.NewDecoder(base64.StdEncoding, reader)
+	}
+
+	if charset != "" && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "us-ascii") {
+		if enc, err := htmlindex.Get(charset); err == nil {
+			reader = enc.NewDecoder().Reader(reader)
+		}
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode MIME part: %w", err)
+	}
+	return strings.ToValidUTF8(string(data), "�"), nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// crlf turns a readable test message into the CRLF line endings mail uses.
+func crlf(s string) []byte {
+	return []byte(strings.ReplaceAll(strings.TrimLeft(s, "\n"), "\n", "\r\n"))
+}
+
+func TestParseInboundEmail(t *testing.T) {
+	t.Run("multipart alternative", func(t *testing.T) {
+		raw := crlf(`
+From: "Weekly Digest" <news@letters.example.com>
+To: Reader <abc123@in.example.com>
+Delivered-To: abc123+weekly@in.example.com
+Subject: =?UTF-8?Q?Caf=C3=A9_notes?=
+Date: Mon, 02 Jun 2025 09:30:00 +0000
+Message-ID: <issue-42@letters.example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+
+Plain version
+--b1
+Content-Type: text/html; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+<p style=3D"color:red">Hello =E2=80=94 world</p>
+--b1--
+`)
+		email, err := parseInboundEmail(raw)
+		if err != nil {
+			t.Fatalf("parseInboundEmail: %v", err)
+		}
+		if email.MessageID != "issue-42@letters.example.com" || email.Subject != "Café notes" {
+			t.Errorf("unexpected headers: %+v", email)
+		}
+		if email.FromName != "Weekly Digest" || email.FromAddress != "news@letters.example.com" {
+			t.Errorf("unexpected sender: %q <%s>", email.FromName, email.FromAddress)
+		}
+		if !email.Date.Equal(time.Date(2025, 6, 2, 9, 30, 0, 0, time.UTC)) {
+			t.Errorf("unexpected date: %v", email.Date)
+		}
+		if email.Text != "Plain version" || email.HTML != `<p style="color:red">Hello — world</p>` {
+			t.Errorf("unexpected bodies: text %q, html %q", email.Text, email.HTML)
+		}
+		if strings.Join(email.Recipients, ",") != "abc123+weekly@in.example.com,abc123@in.example.com" {
+			t.Errorf("unexpected recipients: %v", email.Recipients)
+		}
+	})
+
+	t.Run("base64 body in a legacy charset", func(t *testing.T) {
+		// "<p>Été</p>" in ISO-8859-1
+		raw := crlf(`
+From: news@letters.example.com
+To: abc123@in.example.com
+Subject: =?ISO-8859-1?Q?=C9t=E9?=
+Content-Type: text/html; charset=ISO-8859-1
+Content-Transfer-Encoding: base64
+
+PHA+yXTpPC9wPg==
+`)
+		email, err := parseInboundEmail(raw)
+		if err != nil {
+			t.Fatalf("parseInboundEmail: %v", err)
+		}
+		if email.Subject != "Été" || email.HTML != "<p>Été</p>" {
+			t.Errorf("unexpected decoding: subject %q, html %q", email.Subject, email.HTML)
+		}
+	})
+
+	t.Run("attachments are skipped", func(t *testing.T) {
+		raw := crlf(`
+From: news@letters.example.com
+To: abc123@in.example.com
+Subject: With attachment
+Content-Type: multipart/mixed; boundary=outer
+
+--outer
+Content-Type: text/plain
+
+See attached.
+--outer
+Content-Type: text/html
+Content-Disposition: attachment; filename="report.html"
+
+<p>attached file</p>
+--outer--
+`)
+		email, err := parseInboundEmail(raw)
+		if err != nil {
+			t.Fatalf("parseInboundEmail: %v", err)
+		}
+		if email.Text != "See attached." || email.HTML != "" {
+			t.Errorf("expected only the inline text, got text %q, html %q", email.Text, email.HTML)
+		}
+	})
+
+	t.Run("missing content type is plain text", func(t *testing.T) {
+		email, err := parseInboundEmail(crlf("Subject: Hi\n\nJust text\n"))
+		if err != nil {
+			t.Fatalf("parseInboundEmail: %v", err)
+		}
+		if strings.TrimSpace(email.Text) != "Just text" {
+			t.Errorf("unexpected text: %q", email.Text)
+		}
+	})
+
+	invalid := map[string]string{
+		"no headers":         "not an email",
+		"no body":            "Subject: Empty\nContent-Type: image/png\n\nxxxx\n",
+		"no boundary":        "Subject: Broken\nContent-Type: multipart/mixed\n\nbody\n",
+		"only an attachment": "Subject: File\nContent-Type: text/plain\nContent-Disposition: attachment\n\ndata\n",
+	}
+	for name, raw := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseInboundEmail(crlf(raw)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	t.Run("deep nesting is rejected", func(t *testing.T) {
+		var b strings.Builder
+		b.WriteString("Subject: Deep\n")
+		for i := 0; i <= maxInboundMIMEDepth; i++ {
+			b.WriteString("Content-Type: multipart/mixed; boundary=b" + string(rune('a'+i)) + "\n\n--b" + string(rune('a'+i)) + "\n")
+		}
+		b.WriteString("Content-Type: text/plain\n\ndeep\n")
+		if _, err := parseInboundEmail(crlf(b.String())); err == nil {
+			t.Error("expected deeply nested parts to be rejected")
+		}
+	})
+}
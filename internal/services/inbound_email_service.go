@@ -0,0 +1,365 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+var (
+	// ErrInboundEmailDisabled indicates no inbound email domain is configured
+	ErrInboundEmailDisabled = errors.New("inbound email is not configured")
+
+	// ErrInboundAddressNotFound indicates the address does not exist or belongs to another user
+	ErrInboundAddressNotFound = errors.New("inbound address not found")
+
+	// ErrInboundAddressLimitReached indicates the user already has the maximum number of addresses
+	ErrInboundAddressLimitReached = errors.New("inbound address limit reached")
+
+	// ErrInvalidInboundAddress indicates the address settings failed validation
+	ErrInvalidInboundAddress = errors.New("invalid inbound address")
+
+	// ErrInvalidInboundEmail indicates a received message could not be parsed
+	ErrInvalidInboundEmail = errors.New("invalid inbound email")
+
+	// ErrUnknownInboundRecipient indicates none of a message's recipients is an inbound address
+	ErrUnknownInboundRecipient = errors.New("unknown inbound recipient")
+)
+
+const (
+	MaxInboundAddressesPerUser  = 5
+	maxInboundFeedTitleLength   = 100
+	defaultInboundFeedTitle     = "Newsletters"
+	inboundDescriptionMaxLength = 300
+
+	// inboundFeedURLPrefix marks the synthetic feeds behind inbound addresses.
+	// They have nothing to fetch, so refreshes and OPML exports skip them.
+	inboundFeedURLPrefix = "mailto:"
+
+	// inboundFutureDateSkew is how far ahead of now a Date header may be before
+	// it's treated as wrong and replaced with the time of receipt.
+	inboundFutureDateSkew = time.Hour
+)
+
+// isInboundFeed reports whether the feed receives email rather than being fetched.
+func isInboundFeed(feed database.Feed) bool {
+	return strings.HasPrefix(feed.URL, inboundFeedURLPrefix)
+}
+
+// InboundEmailService gives users email addresses that deliver newsletters
+// into synthetic feeds, and turns received messages into articles.
+type InboundEmailService struct {
+	db          database.Database
+	feedService *FeedService
+	domain      string
+}
+
+// NewInboundEmailService creates the service for addresses at domain. An empty
+// domain disables inbound email.
+func NewInboundEmailService(db database.Database, feedService *FeedService, domain string) *InboundEmailService {
+	return &InboundEmailService{
+		db:          db,
+		feedService: feedService,
+		domain:      strings.ToLower(strings.TrimSpace(domain)),
+	}
+}
+
+// Enabled reports whether an inbound email domain is configured.
+func (s *InboundEmailService) Enabled() bool {
+	return s.domain != ""
+}
+
+// Domain returns the domain inbound addresses are created under.
+func (s *InboundEmailService) Domain() string {
+	return s.domain
+}
+
+func (s *InboundEmailService) address(token string) string {
+	return token + "@" + s.domain
+}
+
+func (s *InboundEmailService) ListAddresses(userID int) ([]database.InboundAddress, error) {
+	addresses, err := s.db.GetUserInboundAddresses(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	for i := range addresses {
+		addresses[i].Address = s.address(addresses[i].Token)
+	}
+	return addresses, nil
+}
+
+// CreateAddress creates a new inbound address with its own feed, titled title
+// (or "Newsletters"), and subscribes the user to it. The three are stored
+// together, so a failure leaves no orphaned feed or subscription.
+func (s *InboundEmailService) CreateAddress(userID int, title string) (*database.InboundAddress, error) {
+	if !s.Enabled() {
+		return nil, ErrInboundEmailDisabled
+	}
+
+	title = strings.Join(strings.Fields(title), " ")
+	if title == "" {
+		title = defaultInboundFeedTitle
+	}
+	if utf8.RuneCountInString(title) > maxInboundFeedTitleLength {
+		return nil, fmt.Errorf("%w: the title must be at most %d characters", ErrInvalidInboundAddress, maxInboundFeedTitleLength)
+	}
+
+	existing, err := s.db.GetUserInboundAddresses(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if len(existing) >= MaxInboundAddressesPerUser {
+		return nil, ErrInboundAddressLimitReached
+	}
+
+	token, err := generateInboundToken()
+	if err != nil {
+		return nil, err
+	}
+	address := s.address(token)
+
+	now := time.Now()
+	feed := &database.Feed{
+		Title:       title,
+		URL:         inboundFeedURLPrefix + address,
+		Description: "Newsletters sent to " + address,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		LastFetch:   now,
+	}
+	inbound := &database.InboundAddress{
+		UserID:    userID,
+		Token:     token,
+		Address:   address,
+		CreatedAt: now,
+	}
+	if err := s.db.CreateInboundAddress(inbound, feed); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	s.feedService.unreadCache.Invalidate(userID)
+	s.feedService.feedListCache.Invalidate()
+	return inbound, nil
+}
+
+// DeleteAddress stops the address from accepting mail and unsubscribes the
+// user from its feed.
+func (s *InboundEmailService) DeleteAddress(userID, addressID int) error {
+	addresses, err := s.db.GetUserInboundAddresses(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	var found *database.InboundAddress
+	for i := range addresses {
+		if addresses[i].ID == addressID {
+			found = &addresses[i]
+			break
+		}
+	}
+	if found == nil {
+		return ErrInboundAddressNotFound
+	}
+
+	if err := s.db.DeleteInboundAddress(userID, addressID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if err := s.feedService.UnsubscribeUserFromFeed(userID, found.FeedID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// Receive stores a raw RFC 822 message as an article in the feed of each
+// inbound address it was sent to, and returns how many feeds received it.
+// Recipients are taken from envelopeTo, when the mail provider supplies it,
+// and from the message headers. Redelivering a message is harmless: a feed
+// never stores the same Message-ID twice.
+func (s *InboundEmailService) Receive(raw []byte, envelopeTo []string) (int, error) {
+	if !s.Enabled() {
+		return 0, ErrInboundEmailDisabled
+	}
+
+	email, err := parseInboundEmail(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidInboundEmail, err)
+	}
+
+	recipients := append(append([]string{}, envelopeTo...), email.Recipients...)
+	addresses, err := s.matchRecipients(recipients)
+	if err != nil {
+		return 0, err
+	}
+	if len(addresses) == 0 {
+		return 0, ErrUnknownInboundRecipient
+	}
+
+	messageID := email.MessageID
+	if messageID == "" {
+		sum := sha256.Sum256(raw)
+		messageID = hex.EncodeToString(sum[:16]) + "@" + s.domain
+	}
+
+	delivered := 0
+	for _, address := range addresses {
+		ok, err := s.deliver(address, email, messageID)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// matchRecipients returns the inbound addresses among recipients, once each.
+// A "+suffix" on the local part is ignored, so token+news@domain still matches.
+func (s *InboundEmailService) matchRecipients(recipients []string) ([]database.InboundAddress, error) {
+	var matched []database.InboundAddress
+	seen := make(map[string]bool)
+	for _, recipient := range recipients {
+		local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(recipient)), "@")
+		if !ok || domain != s.domain {
+			continue
+		}
+		token, _, _ := strings.Cut(local, "+")
+		if token == "" || seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		address, err := s.db.GetInboundAddressByToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if address != nil {
+			matched = append(matched, *address)
+		}
+	}
+	return matched, nil
+}
+
+// deliver saves the message to the address's feed. It reports false without
+// an error when the user has since unsubscribed from the feed or the message
+// is already there.
+func (s *InboundEmailService) deliver(address database.InboundAddress, email *inboundEmail, messageID string) (bool, error) {
+	feeds, err := s.db.GetUserFeeds(address.UserID)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	var feed *database.Feed
+	for i := range feeds {
+		if feeds[i].ID == address.FeedID {
+			feed = &feeds[i]
+			break
+		}
+	}
+	if feed == nil {
+		log.Printf("Inbound email %s: user %d is no longer subscribed to feed %d, dropping", messageID, address.UserID, address.FeedID)
+		return false, nil
+	}
+
+	// Article URLs are unique across feeds, so the feed ID keeps one message
+	// sent to two users' addresses from colliding.
+	articleURL := fmt.Sprintf("mid:%s#feed-%d", url.PathEscape(messageID), feed.ID)
+	existing, err := s.db.FilterExistingArticleURLs(feed.ID, []string{articleURL})
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if existing[articleURL] {
+		return false, nil
+	}
+
+	article := s.feedService.inboundEmailToArticle(email, articleURL)
+	if _, err := s.feedService.saveArticlesFromFeed(feed.ID, &FeedData{Title: feed.Title, Articles: []ArticleData{article}}); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	s.feedService.unreadCache.Invalidate(address.UserID)
+	return true, nil
+}
+
+// inboundEmailToArticle converts a message to article data, sanitising the
+// HTML body with the same policy as feed content. Plain-text messages are
+// escaped and split into paragraphs.
+func (fs *FeedService) inboundEmailToArticle(email *inboundEmail, link string) ArticleData {
+	var content string
+	if email.HTML != "" {
+		content = fs.sanitizeHTML(email.HTML)
+	} else {
+		content = plainTextToHTML(email.Text)
+	}
+
+	text := email.Text
+	if text == "" {
+		text = stripHTMLTags(content)
+	}
+	description := strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(description) > inboundDescriptionMaxLength {
+		description = string([]rune(description)[:inboundDescriptionMaxLength-3]) + "..."
+	}
+	description = html.EscapeString(description)
+
+	title := fs.cleanTitle(email.Subject)
+	if title == "" {
+		title = fs.generateFallbackTitle("", description)
+	}
+
+	author := email.FromName
+	if author == "" {
+		author = email.FromAddress
+	}
+
+	now := time.Now()
+	publishedAt := email.Date
+	if publishedAt.IsZero() || publishedAt.After(now.Add(inboundFutureDateSkew)) {
+		publishedAt = now
+	}
+
+	return ArticleData{
+		Title:       title,
+		Link:        link,
+		Description: description,
+		Content:     content,
+		Author:      author,
+		PublishedAt: publishedAt,
+	}
+}
+
+// plainTextToHTML escapes text and wraps each blank-line-separated block in a
+// paragraph, keeping single line breaks.
+func plainTextToHTML(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var b strings.Builder
+	for _, block := range strings.Split(text, "\n\n") {
+		block = strings.TrimSpace(block)
+		if block == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(block), "\n", "<br>"))
+		b.WriteString("</p>")
+	}
+	return b.String()
+}
+
+// generateInboundToken returns the random local part of a new inbound
+// address. It's lower-case hex because some mail systems fold the case of
+// local parts.
+func generateInboundToken() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate inbound address: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func newsletter(to, messageID, subject string) []byte {
+	return crlf(`
+From: "The Letter" <editor@letters.example.com>
+To: ` + to + `
+Subject: ` + subject + `
+Date: Mon, 02 Jun 2025 09:30:00 +0000
+Message-ID: <` + messageID + `>
+Content-Type: text/html; charset=utf-8
+
+<html><head><style>p { color: red }</style></head><body>
+<p onclick="steal()">Issue <b>one</b></p><script>alert(1)</script>
+<img src="cid:logo"><a href="javascript:alert(1)">bad</a><a href="https://letters.example.com/web">web</a>
+</body></html>
+`)
+}
+
+func TestInboundEmailService_Addresses(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	fs := NewFeedService(db, nil)
+
+	disabled := NewInboundEmailService(db, fs, "")
+	if _, err := disabled.CreateAddress(user.ID, ""); !errors.Is(err, ErrInboundEmailDisabled) {
+		t.Errorf("expected ErrInboundEmailDisabled, got %v", err)
+	}
+
+	s := NewInboundEmailService(db, fs, "In.Example.com")
+	if _, err := s.CreateAddress(user.ID, strings.Repeat("x", maxInboundFeedTitleLength+1)); !errors.Is(err, ErrInvalidInboundAddress) {
+		t.Errorf("expected ErrInvalidInboundAddress for a long title, got %v", err)
+	}
+
+	address, err := s.CreateAddress(user.ID, "  ")
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+	if !strings.HasSuffix(address.Address, "@in.example.com") || address.Address != address.Token+"@in.example.com" {
+		t.Errorf("unexpected address: %+v", address)
+	}
+
+	feeds, err := fs.GetUserFeeds(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserFeeds: %v", err)
+	}
+	if len(feeds) != 1 || feeds[0].ID != address.FeedID || feeds[0].Title != defaultInboundFeedTitle || !isInboundFeed(feeds[0]) {
+		t.Fatalf("expected the user to be subscribed to the new feed, got %+v", feeds)
+	}
+
+	opml, err := fs.ExportOPML(user.ID)
+	if err != nil {
+		t.Fatalf("ExportOPML: %v", err)
+	}
+	if strings.Contains(string(opml), "mailto:") {
+		t.Errorf("expected inbound feeds to be left out of OPML exports:\n%s", opml)
+	}
+
+	for i := 1; i < MaxInboundAddressesPerUser; i++ {
+		if _, err := s.CreateAddress(user.ID, "Newsletter"); err != nil {
+			t.Fatalf("CreateAddress: %v", err)
+		}
+	}
+	if _, err := s.CreateAddress(user.ID, "One too many"); !errors.Is(err, ErrInboundAddressLimitReached) {
+		t.Errorf("expected ErrInboundAddressLimitReached, got %v", err)
+	}
+
+	if err := s.DeleteAddress(user.ID+1, address.ID); !errors.Is(err, ErrInboundAddressNotFound) {
+		t.Errorf("expected ErrInboundAddressNotFound for another user, got %v", err)
+	}
+	if err := s.DeleteAddress(user.ID, address.ID); err != nil {
+		t.Fatalf("DeleteAddress: %v", err)
+	}
+	addresses, err := s.ListAddresses(user.ID)
+	if err != nil {
+		t.Fatalf("ListAddresses: %v", err)
+	}
+	if len(addresses) != MaxInboundAddressesPerUser-1 {
+		t.Errorf("expected %d addresses after the delete, got %d", MaxInboundAddressesPerUser-1, len(addresses))
+	}
+	feeds, _ = fs.GetUserFeeds(user.ID)
+	for _, f := range feeds {
+		if f.ID == address.FeedID {
+			t.Error("expected the user to be unsubscribed from the deleted address's feed")
+		}
+	}
+}
+
+func TestInboundEmailService_Receive(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	fs := NewFeedService(db, nil)
+	s := NewInboundEmailService(db, fs, "in.example.com")
+	address, err := s.CreateAddress(user.ID, "Letters")
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+
+	raw := newsletter(address.Address, "issue-1@letters.example.com", "Issue one")
+	delivered, err := s.Receive(raw, nil)
+	if err != nil || delivered != 1 {
+		t.Fatalf("Receive: delivered %d, %v", delivered, err)
+	}
+
+	articles, err := db.GetArticles(address.FeedID)
+	if err != nil {
+		t.Fatalf("GetArticles: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Fatalf("expected one article, got %d", len(articles))
+	}
+	article := articles[0]
+	if article.Title != "Issue one" || article.Author != "The Letter" || article.PublishedAt.Year() != 2025 {
+		t.Errorf("unexpected article: %+v", article)
+	}
+	if !strings.HasPrefix(article.URL, "mid:issue-1@letters.example.com") {
+		t.Errorf("expected a mid: URL, got %q", article.URL)
+	}
+	for _, unsafe := range []string{"<script", "onclick", "javascript:", "cid:", "<style", "color: red"} {
+		if strings.Contains(article.Content, unsafe) {
+			t.Errorf("expected %q to be sanitised out of %q", unsafe, article.Content)
+		}
+	}
+	if !strings.Contains(article.Content, "<b>one</b>") || !strings.Contains(article.Content, `href="https://letters.example.com/web"`) {
+		t.Errorf("expected safe markup to survive sanitising, got %q", article.Content)
+	}
+	if !strings.HasPrefix(article.Description, "Issue one") {
+		t.Errorf("expected a plain-text description, got %q", article.Description)
+	}
+
+	t.Run("redelivery is ignored", func(t *testing.T) {
+		delivered, err := s.Receive(raw, nil)
+		if err != nil || delivered != 0 {
+			t.Errorf("expected a redelivered message to be skipped, got %d, %v", delivered, err)
+		}
+		if articles, _ := db.GetArticles(address.FeedID); len(articles) != 1 {
+			t.Errorf("expected still one article, got %d", len(articles))
+		}
+	})
+
+	t.Run("envelope recipient and plus suffix", func(t *testing.T) {
+		plus := strings.Replace(address.Address, "@", "+news@", 1)
+		raw := newsletter("undisclosed-recipients:;", "issue-2@letters.example.com", "Issue two")
+		delivered, err := s.Receive(raw, []string{strings.ToUpper(plus)})
+		if err != nil || delivered != 1 {
+			t.Errorf("expected delivery through the envelope recipient, got %d, %v", delivered, err)
+		}
+	})
+
+	t.Run("unknown recipient", func(t *testing.T) {
+		for _, to := range []string{"nobody@in.example.com", address.Token + "@elsewhere.example.com"} {
+			if _, err := s.Receive(newsletter(to, "x@letters.example.com", "X"), nil); !errors.Is(err, ErrUnknownInboundRecipient) {
+				t.Errorf("%s: expected ErrUnknownInboundRecipient, got %v", to, err)
+			}
+		}
+	})
+
+	t.Run("invalid message", func(t *testing.T) {
+		if _, err := s.Receive([]byte("garbage"), []string{address.Address}); !errors.Is(err, ErrInvalidInboundEmail) {
+			t.Errorf("expected ErrInvalidInboundEmail, got %v", err)
+		}
+	})
+
+	t.Run("plain text without a message ID", func(t *testing.T) {
+		raw := crlf("From: someone@example.com\nTo: " + address.Address + "\nSubject: Plain\n\nFirst <line>\nsecond\n\nNext paragraph\n")
+		if delivered, err := s.Receive(raw, nil); err != nil || delivered != 1 {
+			t.Fatalf("Receive: delivered %d, %v", delivered, err)
+		}
+		articles, _ := db.GetArticles(address.FeedID)
+		var plain *database.Article
+		for i := range articles {
+			if articles[i].Title == "Plain" {
+				plain = &articles[i]
+			}
+		}
+		if plain == nil {
+			t.Fatal("expected the plain-text message to be stored")
+		}
+		if plain.Content != "<p>First &lt;line&gt;<br>second</p><p>Next paragraph</p>" || plain.Author != "someone@example.com" {
+			t.Errorf("unexpected plain-text article: %+v", plain)
+		}
+		if delivered, _ := s.Receive(raw, nil); delivered != 0 {
+			t.Error("expected the generated message ID to deduplicate redeliveries")
+		}
+	})
+
+	t.Run("unsubscribed feeds drop mail", func(t *testing.T) {
+		if err := fs.UnsubscribeUserFromFeed(user.ID, address.FeedID); err != nil {
+			t.Fatalf("UnsubscribeUserFromFeed: %v", err)
+		}
+		delivered, err := s.Receive(newsletter(address.Address, "issue-3@letters.example.com", "Issue three"), nil)
+		if err != nil || delivered != 0 {
+			t.Errorf("expected the message to be dropped, got %d, %v", delivered, err)
+		}
+	})
+}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"cloud.google.com/go/datastore"
 	"github.com/jeffreyp/goread2/internal/database"
 	"github.com/jeffreyp/goread2/internal/secrets"
 	"github.com/stripe/stripe-go/v78"
@@ -211,6 +213,13 @@ func (ps *PaymentService) HandleSubscriptionUpdate(subscriptionID string) error
 
 	log.Printf("HandleSubscriptionUpdate - Processing for user ID: %d", userID)
 
+	// Deleting an account cancels its subscription, and Stripe reports the
+	// cancellation after the user is gone. Nothing is left to update.
+	if _, err := ps.db.GetUserByID(userID); errors.Is(err, sql.ErrNoRows) || errors.Is(err, datastore.ErrNoSuchEntity) {
+		log.Printf("HandleSubscriptionUpdate - User %d no longer exists, ignoring update", userID)
+		return nil
+	}
+
 	// Convert Stripe status to our status
 	var status string
 	var lastPaymentDate time.Time
@@ -248,6 +257,24 @@ func (ps *PaymentService) HandleSubscriptionUpdate(subscriptionID string) error
 	return nil
 }
 
+// CancelUserSubscription ends the user's Stripe subscription immediately, if
+// they have one that is still billing. A subscription Stripe no longer knows
+// about counts as already cancelled.
+func (ps *PaymentService) CancelUserSubscription(user *database.User) error {
+	if user.SubscriptionID == "" || user.SubscriptionStatus == "cancelled" || user.SubscriptionStatus == "expired" {
+		return nil
+	}
+
+	if _, err := subscription.Cancel(user.SubscriptionID, nil); err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			return nil
+		}
+		return fmt.Errorf("failed to cancel subscription: %w", err)
+	}
+	return nil
+}
+
 // CreateProductAndPrice creates the GoRead2 Pro product and price in Stripe (one-time setup)
 func (ps *PaymentService) CreateProductAndPrice() (*stripe.Price, error) {
 	// Create product
@@ -77,7 +77,127 @@ func (m *mockDBPayment) UpdateUserSubscription(userID int, status, subscriptionI
 	m.updateCalled = true
 	return nil
 }
-func (m *mockDBPayment) Close() error                                            { return nil }
+func (m *mockDBPayment) Close() error { return nil }
+func (m *mockDBPayment) GetArticlesByIDs(int, []int) (map[int]database.Article, error) {
+	return nil, nil
+}
+func (m mockDBPayment) CreateRefreshToken(*database.RefreshToken) error { return nil }
+func (m mockDBPayment) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBPayment) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBPayment) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBPayment) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBPayment) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) GetUserIdentities(int) ([]database.UserIdentity, error)      { return nil, nil }
+func (m *mockDBPayment) CreateLoginToken(*database.LoginToken) error                 { return nil }
+func (m *mockDBPayment) ConsumeLoginToken(string) (*database.LoginToken, error)      { return nil, nil }
+func (m *mockDBPayment) DeleteExpiredLoginTokens() error                             { return nil }
+func (m *mockDBPayment) ForEachUserIdentity(func(database.UserIdentity) error) error { return nil }
+func (m *mockDBPayment) DeleteUser(userID int) error                                 { return nil }
+func (m *mockDBPayment) GetUserArticleHistory(userID int) ([]database.Article, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) GetUserSessions(userID int) ([]database.Session, error)       { return nil, nil }
+func (m *mockDBPayment) ForEachUser(fn func(database.User) error) error               { return nil }
+func (m *mockDBPayment) ForEachArticle(fn func(database.Article) error) error         { return nil }
+func (m *mockDBPayment) ForEachUserArticle(fn func(database.UserArticle) error) error { return nil }
+func (m *mockDBPayment) ForEachSession(fn func(database.Session) error) error         { return nil }
+func (m *mockDBPayment) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) GetFeedCategories() (map[int]string, error)        { return nil, nil }
+func (m *mockDBPayment) SetFeedCategory(feedID int, category string) error { return nil }
+func (m *mockDBPayment) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBPayment) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBPayment) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBPayment) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBPayment) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBPayment) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBPayment) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBPayment) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBPayment) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBPayment) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBPayment) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBPayment) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBPayment) DeleteWebhookDeliveriesBefore(time.Time) (int, error) { return 0, nil }
+func (m *mockDBPayment) UpdateUserDigestPreferences(int, database.DigestPreferences) error {
+	return nil
+}
+func (m *mockDBPayment) GetDigestUsers() ([]database.User, error) { return []database.User{}, nil }
+func (m *mockDBPayment) SetUserDigestSentAt(int, time.Time) error { return nil }
+func (m *mockDBPayment) GetUserStarredCountsByFeed(int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+func (m *mockDBPayment) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBPayment) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBPayment) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBPayment) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBPayment) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBPayment) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBPayment) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBPayment) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBPayment) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBPayment) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBPayment) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBPayment) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBPayment) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBPayment) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBPayment) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBPayment) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBPayment) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBPayment) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBPayment) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBPayment) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBPayment) CreateAnnotation(*database.Annotation) error          { return nil }
+func (m *mockDBPayment) GetAnnotation(int, int) (*database.Annotation, error) { return nil, nil }
+func (m *mockDBPayment) GetArticleAnnotations(int, int) ([]database.Annotation, error) {
+	return nil, nil
+}
+func (m *mockDBPayment) GetUserAnnotations(int) ([]database.Annotation, error)   { return nil, nil }
+func (m *mockDBPayment) UpdateAnnotation(*database.Annotation) error             { return nil }
+func (m *mockDBPayment) DeleteAnnotation(int, int) error                         { return nil }
 func (m *mockDBPayment) CreateUser(*database.User) error                         { return nil }
 func (m *mockDBPayment) GetUserByGoogleID(string) (*database.User, error)        { return nil, nil }
 func (m *mockDBPayment) IsUserSubscriptionActive(int) (bool, error)              { return false, nil }
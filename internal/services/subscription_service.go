@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -225,6 +226,13 @@ func (ss *SubscriptionService) GenerateAdminToken(description string) (string, e
 		if err != nil {
 			return "", fmt.Errorf("failed to store admin token: %w", err)
 		}
+	} else if pgDB, ok := ss.db.(*database.PostgresDB); ok {
+		query := `INSERT INTO admin_tokens (token_hash, description, created_at, last_used_at, is_active)
+				  VALUES ($1, $2, $3, $4, TRUE)`
+		_, err := pgDB.Exec(query, tokenHash, description, now, now)
+		if err != nil {
+			return "", fmt.Errorf("failed to store admin token: %w", err)
+		}
 	} else if datastoreDB, ok := ss.db.(*database.DatastoreDB); ok {
 		ctx := context.Background()
 		entity := &database.AdminTokenEntity{
@@ -285,6 +293,18 @@ func (ss *SubscriptionService) ValidateAdminToken(token string) (bool, error) {
 		}
 
 		return true, nil
+	} else if pgDB, ok := ss.db.(*database.PostgresDB); ok {
+		// Validating and recording the use is a single statement here
+		result, err := pgDB.Exec(`UPDATE admin_tokens SET last_used_at = $1 WHERE token_hash = $2 AND is_active`,
+			time.Now(), tokenHash)
+		if err != nil {
+			return false, fmt.Errorf("failed to validate admin token: %w", err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to validate admin token: %w", err)
+		}
+		return n > 0, nil
 	} else if datastoreDB, ok := ss.db.(*database.DatastoreDB); ok {
 		ctx := context.Background()
 
@@ -321,11 +341,19 @@ func (ss *SubscriptionService) ValidateAdminToken(token string) (bool, error) {
 
 // ListAdminTokens returns all admin tokens (without the actual token values)
 func (ss *SubscriptionService) ListAdminTokens() ([]AdminToken, error) {
-	if sqliteDB, ok := ss.db.(*database.DB); ok {
+	var sqlDB *sql.DB
+	switch db := ss.db.(type) {
+	case *database.DB:
+		sqlDB = db.DB
+	case *database.PostgresDB:
+		sqlDB = db.DB
+	}
+
+	if sqlDB != nil {
 		query := `SELECT id, token_hash, created_at, last_used_at, description, is_active 
 				  FROM admin_tokens ORDER BY created_at DESC`
 
-		rows, err := sqliteDB.Query(query)
+		rows, err := sqlDB.Query(query)
 		if err != nil {
 			return nil, fmt.Errorf("failed to query admin tokens: %w", err)
 		}
@@ -395,6 +423,22 @@ func (ss *SubscriptionService) RevokeAdminToken(tokenID int) error {
 			return errors.New("admin token not found")
 		}
 
+		return nil
+	} else if pgDB, ok := ss.db.(*database.PostgresDB); ok {
+		result, err := pgDB.Exec(`UPDATE admin_tokens SET is_active = FALSE WHERE id = $1 AND is_active`, tokenID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke admin token: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check revoke result: %w", err)
+		}
+
+		if rowsAffected == 0 {
+			return errors.New("admin token not found")
+		}
+
 		return nil
 	} else if datastoreDB, ok := ss.db.(*database.DatastoreDB); ok {
 		ctx := context.Background()
@@ -440,6 +484,13 @@ func (ss *SubscriptionService) HasAdminTokens() (bool, error) {
 			return false, fmt.Errorf("failed to count admin tokens: %w", err)
 		}
 		return count > 0, nil
+	} else if pgDB, ok := ss.db.(*database.PostgresDB); ok {
+		var exists bool
+		err := pgDB.QueryRow(`SELECT EXISTS(SELECT 1 FROM admin_tokens WHERE is_active)`).Scan(&exists)
+		if err != nil {
+			return false, fmt.Errorf("failed to count admin tokens: %w", err)
+		}
+		return exists, nil
 	} else if datastoreDB, ok := ss.db.(*database.DatastoreDB); ok {
 		ctx := context.Background()
 
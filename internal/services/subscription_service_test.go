@@ -41,9 +41,125 @@ func (m *mockDBForSub) addUser(user *database.User) {
 }
 
 // Mock implementations
-func (m *mockDBForSub) Close() error                                     { return nil }
-func (m *mockDBForSub) CreateUser(*database.User) error                  { return nil }
-func (m *mockDBForSub) GetUserByGoogleID(string) (*database.User, error) { return nil, nil }
+func (m *mockDBForSub) Close() error { return nil }
+func (m *mockDBForSub) GetArticlesByIDs(int, []int) (map[int]database.Article, error) {
+	return nil, nil
+}
+func (m mockDBForSub) CreateRefreshToken(*database.RefreshToken) error { return nil }
+func (m mockDBForSub) UseRefreshToken(string, time.Time) (*database.RefreshToken, error) {
+	return nil, nil
+}
+func (m mockDBForSub) DeleteExpiredRefreshTokens() error                     { return nil }
+func (m mockDBForSub) UpdateSessionActivity(string, time.Time, string) error { return nil }
+func (m *mockDBForSub) CreateUserIdentity(*database.UserIdentity) error      { return nil }
+func (m *mockDBForSub) GetUserIdentity(string, string) (*database.UserIdentity, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) GetUserIdentities(int) ([]database.UserIdentity, error)       { return nil, nil }
+func (m *mockDBForSub) CreateLoginToken(*database.LoginToken) error                  { return nil }
+func (m *mockDBForSub) ConsumeLoginToken(string) (*database.LoginToken, error)       { return nil, nil }
+func (m *mockDBForSub) DeleteExpiredLoginTokens() error                              { return nil }
+func (m *mockDBForSub) ForEachUserIdentity(func(database.UserIdentity) error) error  { return nil }
+func (m *mockDBForSub) DeleteUser(userID int) error                                  { return nil }
+func (m *mockDBForSub) GetUserArticleHistory(userID int) ([]database.Article, error) { return nil, nil }
+func (m *mockDBForSub) GetUserSessions(userID int) ([]database.Session, error)       { return nil, nil }
+func (m *mockDBForSub) ForEachUser(fn func(database.User) error) error               { return nil }
+func (m *mockDBForSub) ForEachArticle(fn func(database.Article) error) error         { return nil }
+func (m *mockDBForSub) ForEachUserArticle(fn func(database.UserArticle) error) error { return nil }
+func (m *mockDBForSub) ForEachSession(fn func(database.Session) error) error         { return nil }
+func (m *mockDBForSub) GetAllFeedSubscriptions() ([]database.FeedSubscription, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) GetFeedCategories() (map[int]string, error)        { return nil, nil }
+func (m *mockDBForSub) SetFeedCategory(feedID int, category string) error { return nil }
+func (m *mockDBForSub) ReplaceFeedDirectory(feeds []database.DirectoryFeed, recommendations []database.FeedRecommendation) error {
+	return nil
+}
+func (m *mockDBForSub) GetDirectoryFeeds() ([]database.DirectoryFeed, error) { return nil, nil }
+func (m *mockDBForSub) GetFeedRecommendations(feedIDs []int) ([]database.FeedRecommendation, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) CreateInboundAddress(address *database.InboundAddress, feed *database.Feed) error {
+	return nil
+}
+func (m *mockDBForSub) GetInboundAddressByToken(token string) (*database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) GetUserInboundAddresses(userID int) ([]database.InboundAddress, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) DeleteInboundAddress(userID, addressID int) error { return nil }
+func (m *mockDBForSub) CreateWebhook(*database.Webhook) error            { return nil }
+func (m *mockDBForSub) GetWebhook(int, int) (*database.Webhook, error)   { return nil, nil }
+func (m *mockDBForSub) GetUserWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBForSub) UpdateWebhook(*database.Webhook) error { return nil }
+func (m *mockDBForSub) DeleteWebhook(int, int) error          { return nil }
+func (m *mockDBForSub) GetFeedWebhooks(int) ([]database.Webhook, error) {
+	return []database.Webhook{}, nil
+}
+func (m *mockDBForSub) CreateWebhookDelivery(*database.WebhookDelivery) error { return nil }
+func (m *mockDBForSub) GetWebhookDeliveries(int, int, int) ([]database.WebhookDelivery, error) {
+	return []database.WebhookDelivery{}, nil
+}
+func (m *mockDBForSub) DeleteWebhookDeliveriesBefore(time.Time) (int, error)              { return 0, nil }
+func (m *mockDBForSub) UpdateUserDigestPreferences(int, database.DigestPreferences) error { return nil }
+func (m *mockDBForSub) GetDigestUsers() ([]database.User, error)                          { return []database.User{}, nil }
+func (m *mockDBForSub) SetUserDigestSentAt(int, time.Time) error                          { return nil }
+func (m *mockDBForSub) GetUserStarredCountsByFeed(int) (map[int]int, error) {
+	return map[int]int{}, nil
+}
+func (m *mockDBForSub) UpdateUserArticleStatuses(_ int, _ []database.ArticleStatusUpdate) (*database.StatusUpdateResult, error) {
+	return &database.StatusUpdateResult{Found: map[int]int{}, UnreadDelta: map[int]int{}}, nil
+}
+func (m *mockDBForSub) GetUserSyncVersion(int) (int64, error) { return 0, nil }
+func (m *mockDBForSub) GetUserArticleChanges(int, int64, int) ([]database.UserArticle, error) {
+	return []database.UserArticle{}, nil
+}
+func (m *mockDBForSub) GetUserFeedChanges(int, int64) ([]database.FeedSubscriptionChange, error) {
+	return []database.FeedSubscriptionChange{}, nil
+}
+func (m *mockDBForSub) GetNewUserArticles(int, database.ArticlePosition, int) ([]database.Article, error) {
+	return []database.Article{}, nil
+}
+func (m *mockDBForSub) ApplyUserArticleChange(int, database.UserArticleChange) (bool, error) {
+	return true, nil
+}
+func (m *mockDBForSub) MarkUserArticlesReadScoped(int, database.MarkReadScope) (*database.MarkReadResult, error) {
+	return &database.MarkReadResult{}, nil
+}
+func (m *mockDBForSub) GetUserArticleStatuses(int, []int) (map[int]database.UserArticle, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) CreateUndoOperation(*database.UndoOperation) error { return nil }
+func (m *mockDBForSub) ConsumeUndoOperation(int, string) (*database.UndoOperation, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) DeleteExpiredUndoOperations(time.Time) (int, error)  { return 0, nil }
+func (m *mockDBForSub) SetUserArticleProgress(int, int, int) error          { return nil }
+func (m *mockDBForSub) CreateTag(*database.Tag) error                       { return nil }
+func (m *mockDBForSub) GetTag(int, int) (*database.Tag, error)              { return nil, nil }
+func (m *mockDBForSub) GetTagByName(int, string) (*database.Tag, error)     { return nil, nil }
+func (m *mockDBForSub) GetUserTags(int) ([]database.Tag, error)             { return nil, nil }
+func (m *mockDBForSub) RenameTag(int, int, string) error                    { return nil }
+func (m *mockDBForSub) DeleteTag(int, int) error                            { return nil }
+func (m *mockDBForSub) TagArticle(int, int, int) error                      { return nil }
+func (m *mockDBForSub) UntagArticle(int, int, int) error                    { return nil }
+func (m *mockDBForSub) GetArticleTags(int, []int) (map[int][]string, error) { return nil, nil }
+func (m *mockDBForSub) GetTagArticlesPaginated(int, int, int, string) (*database.ArticlePaginationResult, error) {
+	return &database.ArticlePaginationResult{}, nil
+}
+func (m *mockDBForSub) CreateAnnotation(*database.Annotation) error          { return nil }
+func (m *mockDBForSub) GetAnnotation(int, int) (*database.Annotation, error) { return nil, nil }
+func (m *mockDBForSub) GetArticleAnnotations(int, int) ([]database.Annotation, error) {
+	return nil, nil
+}
+func (m *mockDBForSub) GetUserAnnotations(int) ([]database.Annotation, error) { return nil, nil }
+func (m *mockDBForSub) UpdateAnnotation(*database.Annotation) error           { return nil }
+func (m *mockDBForSub) DeleteAnnotation(int, int) error                       { return nil }
+func (m *mockDBForSub) CreateUser(*database.User) error                       { return nil }
+func (m *mockDBForSub) GetUserByGoogleID(string) (*database.User, error)      { return nil, nil }
 func (m *mockDBForSub) GetUserByID(userID int) (*database.User, error) {
 	if m.shouldFailGetUser {
 		return nil, errors.New("failed to get user")
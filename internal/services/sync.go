@@ -0,0 +1,308 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// ErrInvalidSyncToken indicates a sync token that wasn't issued by this
+// server or no longer matches its data. Clients should start a fresh sync.
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+// Sync action types. Each sets a value rather than toggling one, so replaying
+// an action is harmless.
+const (
+	SyncActionRead     = "read"
+	SyncActionUnread   = "unread"
+	SyncActionStar     = "star"
+	SyncActionUnstar   = "unstar"
+	SyncActionProgress = "progress"
+)
+
+// Outcomes of a sync action
+const (
+	SyncActionApplied  = "applied"
+	SyncActionSkipped  = "skipped"  // a newer change was already stored
+	SyncActionRejected = "rejected" // invalid, or the article isn't in the user's feeds
+)
+
+const (
+	SyncDefaultLimit = 200
+	SyncMaxLimit     = 500
+
+	// MaxSyncActions caps the actions accepted in one request.
+	MaxSyncActions = 500
+
+	// syncArticleOverlap is how far behind the present a finished sync
+	// restarts its article scan. Articles can be stored a little after their
+	// created_at (slow refreshes, clock skew between instances), so the most
+	// recent ones are sent again next time rather than risk missing one.
+	syncArticleOverlap = 2 * time.Minute
+)
+
+// syncToken is the position a client has synced up to. It is handed out
+// base64-encoded and treated as opaque by clients.
+type syncToken struct {
+	Version   int64 `json:"v"` // user's change version
+	CreatedAt int64 `json:"t"` // article creation position, Unix nanoseconds
+	ArticleID int   `json:"a"`
+}
+
+func (t syncToken) encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSyncToken(s string) (syncToken, error) {
+	var t syncToken
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(data, &t) != nil || t.Version < 0 {
+		return syncToken{}, ErrInvalidSyncToken
+	}
+	return t, nil
+}
+
+// SyncArticleState is an article's per-user state as of its last change.
+type SyncArticleState struct {
+	ArticleID    int       `json:"article_id"`
+	IsRead       bool      `json:"is_read"`
+	IsStarred    bool      `json:"is_starred"`
+	ReadProgress int       `json:"read_progress"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SyncChanges is one page of changes since a sync token. Clients apply it,
+// keep Token for the next request and call again straight away while HasMore
+// is set. Articles and states are keyed by ID, so applying a page twice is
+// harmless.
+type SyncChanges struct {
+	Token   string `json:"token"`
+	HasMore bool   `json:"has_more"`
+
+	// Reset is set when no token was given. The client should load its
+	// articles through the regular endpoints, then sync from Token.
+	Reset bool `json:"reset"`
+
+	Articles          []database.Article `json:"articles"` // new articles in the user's feeds
+	States            []SyncArticleState `json:"states"`   // read/star/progress changes
+	SubscribedFeedIDs []int              `json:"subscribed_feed_ids"`
+	DeletedFeedIDs    []int              `json:"deleted_feed_ids"` // drop these feeds and their articles
+}
+
+// GetSyncChanges returns up to limit new articles and limit state changes
+// since token. An empty token starts a new sync.
+func (fs *FeedService) GetSyncChanges(userID int, token string, limit int) (*SyncChanges, error) {
+	if limit <= 0 || limit > SyncMaxLimit {
+		limit = SyncDefaultLimit
+	}
+	now := time.Now()
+
+	current, err := fs.db.GetUserSyncVersion(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	result := &SyncChanges{
+		Articles:          []database.Article{},
+		States:            []SyncArticleState{},
+		SubscribedFeedIDs: []int{},
+		DeletedFeedIDs:    []int{},
+	}
+	if token == "" {
+		result.Reset = true
+		result.Token = syncToken{Version: current, CreatedAt: now.Add(-syncArticleOverlap).UnixNano()}.encode()
+		return result, nil
+	}
+
+	since, err := decodeSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if since.Version > current {
+		// Issued against different data, e.g. before a restore
+		return nil, ErrInvalidSyncToken
+	}
+	next := since
+
+	states, err := fs.db.GetUserArticleChanges(userID, since.Version, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if len(states) > limit {
+		states = states[:limit]
+		result.HasMore = true
+		next.Version = states[len(states)-1].Version
+	} else {
+		next.Version = current
+		if n := len(states); n > 0 && states[n-1].Version > current {
+			next.Version = states[n-1].Version // changed since current was read
+		}
+	}
+	for _, s := range states {
+		result.States = append(result.States, SyncArticleState{
+			ArticleID:    s.ArticleID,
+			IsRead:       s.IsRead,
+			IsStarred:    s.IsStarred,
+			ReadProgress: s.ReadProgress,
+			UpdatedAt:    s.UpdatedAt,
+		})
+	}
+
+	// Subscription changes share the version sequence, so only those up to the
+	// state page's end are reported; later ones come with the next page.
+	feedChanges, err := fs.db.GetUserFeedChanges(userID, since.Version)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	for _, c := range feedChanges {
+		if c.Version > next.Version {
+			break
+		}
+		if c.Subscribed {
+			result.SubscribedFeedIDs = append(result.SubscribedFeedIDs, c.FeedID)
+		} else {
+			result.DeletedFeedIDs = append(result.DeletedFeedIDs, c.FeedID)
+		}
+	}
+
+	after := database.ArticlePosition{CreatedAt: time.Unix(0, since.CreatedAt), ID: since.ArticleID}
+	articles, err := fs.db.GetNewUserArticles(userID, after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if len(articles) > limit {
+		articles = articles[:limit]
+		result.HasMore = true
+		last := articles[len(articles)-1]
+		next.CreatedAt, next.ArticleID = last.CreatedAt.UnixNano(), last.ID
+	} else if cutoff := now.Add(-syncArticleOverlap).UnixNano(); cutoff > since.CreatedAt {
+		next.CreatedAt, next.ArticleID = cutoff, 0
+	}
+	result.Articles = append(result.Articles, articles...)
+
+	result.Token = next.encode()
+	return result, nil
+}
+
+// SyncAction is a change a client made, possibly while offline, at ClientTime.
+// ID is chosen by the client and echoed in the result.
+type SyncAction struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	ArticleID  int       `json:"article_id"`
+	Progress   *int      `json:"progress,omitempty"` // for "progress" actions, 0-100
+	ClientTime time.Time `json:"client_time"`
+}
+
+// SyncActionResult reports what happened to one action.
+type SyncActionResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ApplySyncActions applies a batch of client actions in order. Each is applied
+// only if no newer change to the same article is stored, so the most recent
+// action wins regardless of which device syncs first. Action IDs repeated
+// within the batch are applied once.
+func (fs *FeedService) ApplySyncActions(userID int, actions []SyncAction) ([]SyncActionResult, error) {
+	results := make([]SyncActionResult, 0, len(actions))
+	seen := make(map[string]bool)
+	articles := make(map[int]*database.Article)
+	readFeeds := []int{}
+	now := time.Now()
+
+	for _, action := range actions {
+		if action.ID != "" && seen[action.ID] {
+			continue
+		}
+		seen[action.ID] = true
+		res := SyncActionResult{ID: action.ID}
+
+		change, err := syncActionChange(action)
+		if err != nil {
+			res.Status, res.Error = SyncActionRejected, err.Error()
+			results = append(results, res)
+			continue
+		}
+		// Future timestamps would let one device's skewed clock win every
+		// conflict; SQLite stores whole seconds, so match that everywhere.
+		if change.At.After(now) {
+			change.At = now
+		}
+		change.At = change.At.UTC().Truncate(time.Second)
+
+		article, ok := articles[action.ArticleID]
+		if !ok {
+			if article, err = fs.db.GetArticleByID(userID, action.ArticleID); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+			}
+			articles[action.ArticleID] = article
+		}
+		if article == nil {
+			res.Status, res.Error = SyncActionRejected, ErrArticleNotFound.Error()
+			results = append(results, res)
+			continue
+		}
+
+		applied, err := fs.db.ApplyUserArticleChange(userID, change)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if !applied {
+			res.Status = SyncActionSkipped
+			results = append(results, res)
+			continue
+		}
+
+		res.Status = SyncActionApplied
+		results = append(results, res)
+		if change.IsRead != nil {
+			readFeeds = append(readFeeds, article.FeedID)
+		}
+		fs.publishArticleState(userID, article.ID, article.FeedID)
+	}
+
+	if len(readFeeds) > 0 {
+		// Prior read states aren't known here, so recount rather than adjust
+		fs.unreadCache.Invalidate(userID)
+		fs.publishUnreadCounts(userID, readFeeds...)
+	}
+	return results, nil
+}
+
+// syncActionChange validates an action and converts it to a database change.
+func syncActionChange(action SyncAction) (database.UserArticleChange, error) {
+	change := database.UserArticleChange{ArticleID: action.ArticleID, At: action.ClientTime}
+	if action.ArticleID <= 0 {
+		return change, errors.New("article_id is required")
+	}
+	if action.ClientTime.IsZero() {
+		return change, errors.New("client_time is required")
+	}
+
+	yes, no := true, false
+	switch action.Type {
+	case SyncActionRead:
+		change.IsRead = &yes
+	case SyncActionUnread:
+		change.IsRead = &no
+	case SyncActionStar:
+		change.IsStarred = &yes
+	case SyncActionUnstar:
+		change.IsStarred = &no
+	case SyncActionProgress:
+		if action.Progress == nil || *action.Progress < 0 || *action.Progress > 100 {
+			return change, errors.New("progress must be between 0 and 100")
+		}
+		change.ReadProgress = action.Progress
+	default:
+		return change, fmt.Errorf("unknown action type %q", action.Type)
+	}
+	return change, nil
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func addSyncTestArticle(t *testing.T, db *database.DB, feedID int, name string) *database.Article {
+	t.Helper()
+	a := &database.Article{
+		FeedID: feedID, Title: name,
+		URL:         fmt.Sprintf("https://example.com/sync-%s-%d", name, time.Now().UnixNano()),
+		PublishedAt: time.Now(), CreatedAt: time.Now(),
+	}
+	if err := db.AddArticle(a); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+	return a
+}
+
+// addSyncTestFeed adds a feed besides the one createTestFeed gives each test.
+func addSyncTestFeed(t *testing.T, db *database.DB, name string) *database.Feed {
+	t.Helper()
+	now := time.Now()
+	feed := &database.Feed{
+		Title: name, URL: "https://example.com/" + name + "/" + t.Name(),
+		CreatedAt: now, UpdatedAt: now, LastFetch: now,
+	}
+	if err := db.AddFeed(feed); err != nil {
+		t.Fatalf("AddFeed: %v", err)
+	}
+	return feed
+}
+
+func TestGetSyncChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	dropped := addSyncTestFeed(t, db, "dropped")
+	for _, f := range []*database.Feed{feed, dropped} {
+		if err := db.SubscribeUserToFeed(user.ID, f.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+	}
+	existing := addSyncTestArticle(t, db, feed.ID, "existing")
+
+	fs := NewFeedService(db, nil)
+	start, err := fs.GetSyncChanges(user.ID, "", 0)
+	if err != nil {
+		t.Fatalf("GetSyncChanges: %v", err)
+	}
+	if !start.Reset || start.Token == "" {
+		t.Fatalf("expected a reset with a token, got %+v", start)
+	}
+
+	fresh := addSyncTestArticle(t, db, feed.ID, "fresh")
+	if err := fs.MarkUserArticleRead(user.ID, existing.ID, true, feed.ID, false); err != nil {
+		t.Fatalf("MarkUserArticleRead: %v", err)
+	}
+	if err := db.UnsubscribeUserFromFeed(user.ID, dropped.ID); err != nil {
+		t.Fatalf("UnsubscribeUserFromFeed: %v", err)
+	}
+
+	changes, err := fs.GetSyncChanges(user.ID, start.Token, 0)
+	if err != nil {
+		t.Fatalf("GetSyncChanges: %v", err)
+	}
+	if changes.Reset || changes.HasMore {
+		t.Errorf("expected a complete delta, got reset=%v has_more=%v", changes.Reset, changes.HasMore)
+	}
+	foundFresh := false
+	for _, a := range changes.Articles {
+		foundFresh = foundFresh || a.ID == fresh.ID
+	}
+	if !foundFresh {
+		t.Errorf("expected the new article in %+v", changes.Articles)
+	}
+	if len(changes.States) != 1 || changes.States[0].ArticleID != existing.ID || !changes.States[0].IsRead {
+		t.Errorf("expected the read state change, got %+v", changes.States)
+	}
+	if len(changes.DeletedFeedIDs) != 1 || changes.DeletedFeedIDs[0] != dropped.ID || len(changes.SubscribedFeedIDs) != 0 {
+		t.Errorf("expected only the unsubscribed feed, got subscribed=%v deleted=%v", changes.SubscribedFeedIDs, changes.DeletedFeedIDs)
+	}
+
+	// Nothing changed since, so no state changes are repeated
+	again, err := fs.GetSyncChanges(user.ID, changes.Token, 0)
+	if err != nil {
+		t.Fatalf("GetSyncChanges: %v", err)
+	}
+	if len(again.States) != 0 || len(again.DeletedFeedIDs) != 0 {
+		t.Errorf("expected no repeated changes, got %+v", again)
+	}
+
+	if _, err := fs.GetSyncChanges(user.ID, "not-a-token", 0); !errors.Is(err, ErrInvalidSyncToken) {
+		t.Errorf("expected ErrInvalidSyncToken, got %v", err)
+	}
+}
+
+func TestGetSyncChanges_Paging(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+
+	fs := NewFeedService(db, nil)
+	start, err := fs.GetSyncChanges(user.ID, "", 0)
+	if err != nil {
+		t.Fatalf("GetSyncChanges: %v", err)
+	}
+
+	want := map[int]bool{}
+	for i := 0; i < 3; i++ {
+		a := addSyncTestArticle(t, db, feed.ID, fmt.Sprint(i))
+		if err := db.MarkUserArticleRead(user.ID, a.ID, true); err != nil {
+			t.Fatalf("MarkUserArticleRead: %v", err)
+		}
+		want[a.ID] = true
+	}
+
+	gotArticles, gotStates := map[int]bool{}, map[int]bool{}
+	token := start.Token
+	for page := 0; ; page++ {
+		if page > 5 {
+			t.Fatal("paging did not finish")
+		}
+		changes, err := fs.GetSyncChanges(user.ID, token, 2)
+		if err != nil {
+			t.Fatalf("GetSyncChanges: %v", err)
+		}
+		if len(changes.Articles) > 2 || len(changes.States) > 2 {
+			t.Fatalf("page exceeded limit: %+v", changes)
+		}
+		for _, a := range changes.Articles {
+			gotArticles[a.ID] = true
+		}
+		for _, s := range changes.States {
+			gotStates[s.ArticleID] = true
+		}
+		token = changes.Token
+		if !changes.HasMore {
+			break
+		}
+	}
+
+	for id := range want {
+		if !gotArticles[id] || !gotStates[id] {
+			t.Errorf("article %d missing from synced pages (article=%v state=%v)", id, gotArticles[id], gotStates[id])
+		}
+	}
+}
+
+func TestApplySyncActions(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	other := addSyncTestFeed(t, db, "other")
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	article := addSyncTestArticle(t, db, feed.ID, "a")
+	starredOnline := addSyncTestArticle(t, db, feed.ID, "b")
+	foreign := addSyncTestArticle(t, db, other.ID, "c")
+
+	fs := NewFeedService(db, nil)
+
+	// Another device starred this after the offline device's unstar below
+	if err := fs.ToggleUserArticleStar(user.ID, starredOnline.ID); err != nil {
+		t.Fatalf("ToggleUserArticleStar: %v", err)
+	}
+
+	offline := time.Now().Add(-time.Hour)
+	progress := 60
+	results, err := fs.ApplySyncActions(user.ID, []SyncAction{
+		{ID: "1", Type: SyncActionRead, ArticleID: article.ID, ClientTime: offline},
+		{ID: "1", Type: SyncActionUnread, ArticleID: article.ID, ClientTime: offline}, // replayed ID
+		{ID: "2", Type: SyncActionProgress, ArticleID: article.ID, Progress: &progress, ClientTime: offline.Add(time.Minute)},
+		{ID: "3", Type: SyncActionUnstar, ArticleID: starredOnline.ID, ClientTime: offline},
+		{ID: "4", Type: SyncActionRead, ArticleID: foreign.ID, ClientTime: offline},
+		{ID: "5", Type: "archive", ArticleID: article.ID, ClientTime: offline},
+	})
+	if err != nil {
+		t.Fatalf("ApplySyncActions: %v", err)
+	}
+
+	want := []string{SyncActionApplied, SyncActionApplied, SyncActionSkipped, SyncActionRejected, SyncActionRejected}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), results)
+	}
+	for i, status := range want {
+		if results[i].Status != status {
+			t.Errorf("result %d (%s): expected %s, got %s (%s)", i, results[i].ID, status, results[i].Status, results[i].Error)
+		}
+	}
+
+	status, err := db.GetUserArticleStatus(user.ID, article.ID)
+	if err != nil {
+		t.Fatalf("GetUserArticleStatus: %v", err)
+	}
+	if !status.IsRead || status.ReadProgress != 60 {
+		t.Errorf("expected read at 60%%, got %+v", status)
+	}
+	if status, _ := db.GetUserArticleStatus(user.ID, starredOnline.ID); !status.IsStarred {
+		t.Error("expected the newer star to win over the offline unstar")
+	}
+}
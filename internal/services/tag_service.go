@@ -0,0 +1,218 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+var (
+	// ErrTagNotFound indicates the tag does not exist or belongs to another user
+	ErrTagNotFound = errors.New("tag not found")
+
+	// ErrTagExists indicates the user already has a tag with the requested name
+	ErrTagExists = errors.New("tag already exists")
+
+	// ErrInvalidTag indicates the tag name failed validation
+	ErrInvalidTag = errors.New("invalid tag")
+)
+
+// maxTagNameLength keeps tag names short enough to render as chips in the UI.
+const maxTagNameLength = 64
+
+type TagService struct {
+	db database.Database
+}
+
+func NewTagService(db database.Database) *TagService {
+	return &TagService{db: db}
+}
+
+// ListTags returns the user's tags, sorted by name, with the number of
+// articles carrying each one.
+func (s *TagService) ListTags(userID int) ([]database.Tag, error) {
+	tags, err := s.db.GetUserTags(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tags == nil {
+		tags = []database.Tag{}
+	}
+	return tags, nil
+}
+
+func (s *TagService) CreateTag(userID int, name string) (*database.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &database.Tag{UserID: userID, Name: name, CreatedAt: time.Now()}
+	if err := s.db.CreateTag(tag); err != nil {
+		return nil, mapTagError(err)
+	}
+	return tag, nil
+}
+
+func (s *TagService) RenameTag(userID, tagID int, name string) (*database.Tag, error) {
+	tag, err := s.getTag(userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err = normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == tag.Name {
+		return tag, nil
+	}
+
+	if err := s.db.RenameTag(userID, tagID, name); err != nil {
+		return nil, mapTagError(err)
+	}
+	tag.Name = name
+	return tag, nil
+}
+
+// DeleteTag removes the tag and un-tags every article that carried it.
+func (s *TagService) DeleteTag(userID, tagID int) error {
+	if _, err := s.getTag(userID, tagID); err != nil {
+		return err
+	}
+	if err := s.db.DeleteTag(userID, tagID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// TagArticle applies an existing tag to an article. Tagging an article twice
+// is a no-op.
+func (s *TagService) TagArticle(userID, articleID, tagID int) (*database.Tag, error) {
+	if err := s.checkArticle(userID, articleID); err != nil {
+		return nil, err
+	}
+	tag, err := s.getTag(userID, tagID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.TagArticle(userID, articleID, tag.ID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return tag, nil
+}
+
+// TagArticleByName applies the named tag to an article, creating the tag
+// first if the user doesn't have one by that name.
+func (s *TagService) TagArticleByName(userID, articleID int, name string) (*database.Tag, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkArticle(userID, articleID); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.db.GetTagByName(userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag == nil {
+		tag = &database.Tag{UserID: userID, Name: name, CreatedAt: time.Now()}
+		if err := s.db.CreateTag(tag); err != nil {
+			return nil, mapTagError(err)
+		}
+	}
+
+	if err := s.db.TagArticle(userID, articleID, tag.ID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return tag, nil
+}
+
+func (s *TagService) UntagArticle(userID, articleID, tagID int) error {
+	if _, err := s.getTag(userID, tagID); err != nil {
+		return err
+	}
+	if err := s.db.UntagArticle(userID, articleID, tagID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// GetTagArticles lists the articles carrying a tag, newest first. Each article
+// has its full tag list attached, matching the other article listings.
+func (s *TagService) GetTagArticles(userID, tagID, limit int, cursor string) (*database.ArticlePaginationResult, error) {
+	if _, err := s.getTag(userID, tagID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.GetTagArticlesPaginated(userID, tagID, limit, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	if len(result.Articles) > 0 {
+		ids := make([]int, len(result.Articles))
+		for i := range result.Articles {
+			ids[i] = result.Articles[i].ID
+		}
+		tags, err := s.db.GetArticleTags(userID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		for i := range result.Articles {
+			result.Articles[i].Tags = tags[result.Articles[i].ID]
+		}
+	}
+	return result, nil
+}
+
+func (s *TagService) getTag(userID, tagID int) (*database.Tag, error) {
+	tag, err := s.db.GetTag(userID, tagID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+func (s *TagService) checkArticle(userID, articleID int) error {
+	article, err := s.db.GetArticleByID(userID, articleID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if article == nil {
+		return ErrArticleNotFound
+	}
+	return nil
+}
+
+func mapTagError(err error) error {
+	if errors.Is(err, database.ErrTagExists) {
+		return ErrTagExists
+	}
+	return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+}
+
+// normalizeTagName trims and lower-cases a tag name so "To-Share" and
+// "to-share " refer to the same tag.
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: tag name is required", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", fmt.Errorf("%w: tag name exceeds %d characters", ErrInvalidTag, maxTagNameLength)
+	}
+	if strings.ContainsAny(name, "\r\n\t") {
+		return "", fmt.Errorf("%w: tag name must be a single line", ErrInvalidTag)
+	}
+	return name, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestNormalizeTagName(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"To-Share", "to-share", false},
+		{"  incident postmortem ", "incident postmortem", false},
+		{"", "", true},
+		{"   ", "", true},
+		{"two\nlines", "", true},
+		{string(make([]rune, maxTagNameLength+1)), "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeTagName(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeTagName(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("normalizeTagName(%q) expected ErrInvalidTag, got %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("normalizeTagName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTagService(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	article := &database.Article{
+		FeedID: feed.ID, Title: "Outage review", URL: "https://example.com/outage",
+		PublishedAt: time.Now(), CreatedAt: time.Now(),
+	}
+	if err := db.AddArticle(article); err != nil {
+		t.Fatalf("AddArticle: %v", err)
+	}
+
+	svc := NewTagService(db)
+
+	t.Run("tag by name creates the tag once", func(t *testing.T) {
+		first, err := svc.TagArticleByName(user.ID, article.ID, "Incident-Postmortem")
+		if err != nil {
+			t.Fatalf("TagArticleByName: %v", err)
+		}
+		second, err := svc.TagArticleByName(user.ID, article.ID, "incident-postmortem")
+		if err != nil {
+			t.Fatalf("TagArticleByName (repeat): %v", err)
+		}
+		if first.ID != second.ID {
+			t.Errorf("expected the same tag to be reused, got IDs %d and %d", first.ID, second.ID)
+		}
+
+		tags, err := svc.ListTags(user.ID)
+		if err != nil {
+			t.Fatalf("ListTags: %v", err)
+		}
+		if len(tags) != 1 || tags[0].ArticleCount != 1 {
+			t.Errorf("expected one tag on one article, got %+v", tags)
+		}
+	})
+
+	t.Run("duplicate create is reported", func(t *testing.T) {
+		if _, err := svc.CreateTag(user.ID, "INCIDENT-POSTMORTEM"); !errors.Is(err, ErrTagExists) {
+			t.Errorf("expected ErrTagExists, got %v", err)
+		}
+	})
+
+	t.Run("unknown article", func(t *testing.T) {
+		if _, err := svc.TagArticleByName(user.ID, article.ID+1000, "to-share"); !errors.Is(err, ErrArticleNotFound) {
+			t.Errorf("expected ErrArticleNotFound, got %v", err)
+		}
+	})
+
+	t.Run("article listings include tags", func(t *testing.T) {
+		got, err := NewFeedService(db, nil).GetArticleByID(user.ID, article.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetArticleByID: %v", err)
+		}
+		if len(got.Tags) != 1 || got.Tags[0] != "incident-postmortem" {
+			t.Errorf("expected article tags [incident-postmortem], got %v", got.Tags)
+		}
+	})
+
+	t.Run("tag listing", func(t *testing.T) {
+		tag, err := db.GetTagByName(user.ID, "incident-postmortem")
+		if err != nil || tag == nil {
+			t.Fatalf("GetTagByName: %v", err)
+		}
+		result, err := svc.GetTagArticles(user.ID, tag.ID, 50, "")
+		if err != nil {
+			t.Fatalf("GetTagArticles: %v", err)
+		}
+		if len(result.Articles) != 1 || result.Articles[0].ID != article.ID {
+			t.Fatalf("expected the tagged article, got %+v", result.Articles)
+		}
+		if len(result.Articles[0].Tags) != 1 {
+			t.Errorf("expected tags on listed article, got %v", result.Articles[0].Tags)
+		}
+
+		other := createTestUserWithEmail(t, db, "other-"+t.Name())
+		if _, err := svc.GetTagArticles(other.ID, tag.ID, 50, ""); !errors.Is(err, ErrTagNotFound) {
+			t.Errorf("expected ErrTagNotFound for another user, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+// ErrUndoNotFound indicates the undo operation is unknown, expired, already
+// undone, or belongs to another user
+var ErrUndoNotFound = errors.New("undo operation not found")
+
+// Kinds of bulk operation that can be undone
+const (
+	UndoKindMarkRead    = "mark_read"
+	UndoKindBatchStatus = "batch_status"
+)
+
+// undoRetention is how long a bulk change can be reversed. It only needs to
+// cover "oops" moments, so change sets are kept briefly.
+const undoRetention = 30 * time.Minute
+
+// maxUndoChanges caps the size of a recorded change set. Larger operations
+// still run but are not undoable.
+const maxUndoChanges = 25000
+
+// BulkStatusResult describes a completed bulk status change. UndoID is empty
+// when nothing changed or the change set could not be recorded.
+type BulkStatusResult struct {
+	Count         int
+	UndoID        string
+	UndoExpiresAt time.Time
+}
+
+// MarkArticlesRead marks the user's unread articles within scope as read and
+// records them so the change can be reversed with UndoOperation. Cached unread
+// counts are adjusted per feed rather than invalidated.
+func (fs *FeedService) MarkArticlesRead(userID int, scope database.MarkReadScope) (*BulkStatusResult, error) {
+	marked, err := fs.db.MarkUserArticlesReadScoped(userID, scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark articles as read: %w", err)
+	}
+
+	fs.unreadCache.DecrementCounts(userID, marked.UnreadCleared)
+	fs.publishStatusChanges(userID, marked.Changes)
+
+	result := &BulkStatusResult{Count: len(marked.Changes)}
+	result.UndoID, result.UndoExpiresAt = fs.recordUndo(userID, UndoKindMarkRead, marked.Changes)
+	return result, nil
+}
+
+// BatchSetArticleStatus sets the read and starred flags on a set of articles,
+// recording the previous states for UndoOperation.
+func (fs *FeedService) BatchSetArticleStatus(userID int, articles []database.Article, isRead, isStarred bool) (*BulkStatusResult, error) {
+	changes, err := fs.snapshotArticles(userID, articles, isRead, isStarred)
+	if err != nil {
+		log.Printf("Failed to snapshot article states for user %d before batch update: %v", userID, err)
+	}
+
+	if err := fs.db.BatchSetUserArticleStatus(userID, articles, isRead, isStarred); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	fs.unreadCache.Invalidate(userID)
+	if len(articles) > 0 {
+		feedIDs := make([]int, len(articles))
+		for i, a := range articles {
+			feedIDs[i] = a.FeedID
+		}
+		fs.publishUnreadCounts(userID, feedIDs...)
+	}
+
+	result := &BulkStatusResult{Count: len(articles)}
+	result.UndoID, result.UndoExpiresAt = fs.recordUndo(userID, UndoKindBatchStatus, changes)
+	return result, nil
+}
+
+// UndoOperation restores the article states recorded for a bulk operation and
+// returns the number of articles restored. Only the flags the operation set
+// are restored, so later changes to the others are kept. Each operation can
+// be undone once: it is claimed before anything is restored, so concurrent
+// requests to undo it can't both apply it.
+func (fs *FeedService) UndoOperation(userID int, operationID string) (int, error) {
+	op, err := fs.db.ConsumeUndoOperation(userID, operationID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if op == nil || time.Now().After(op.ExpiresAt) {
+		return 0, ErrUndoNotFound
+	}
+
+	updates := make([]database.ArticleStatusUpdate, 0, len(op.Changes))
+	for _, c := range op.Changes {
+		if !c.ReadChanged && !c.StarredChanged {
+			// Recorded before the changed flags were kept
+			c.ReadChanged, c.StarredChanged = true, true
+		}
+		update := database.ArticleStatusUpdate{ArticleID: c.ArticleID}
+		if c.ReadChanged {
+			update.IsRead = &c.WasRead
+		}
+		// Marking read never touches stars
+		if c.StarredChanged && op.Kind != UndoKindMarkRead {
+			update.IsStarred = &c.WasStarred
+		}
+		if update.IsRead != nil || update.IsStarred != nil {
+			updates = append(updates, update)
+		}
+	}
+	restored, err := fs.db.UpdateUserArticleStatuses(userID, updates)
+	if err != nil {
+		fs.unreadCache.Invalidate(userID)
+		// Put the operation back so the user can try again; restoring is
+		// idempotent, so anything already written is harmless
+		if err := fs.db.CreateUndoOperation(op); err != nil {
+			log.Printf("Failed to put back undo operation %s for user %d: %v", operationID, userID, err)
+		}
+		return 0, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	fs.unreadCache.AdjustCounts(userID, restored.UnreadDelta)
+	fs.publishStatusChanges(userID, op.Changes)
+	return len(op.Changes), nil
+}
+
+// snapshotArticles returns the prior state of each article whose status
+// differs from the target, sorted by article ID.
+func (fs *FeedService) snapshotArticles(userID int, articles []database.Article, isRead, isStarred bool) ([]database.ArticleStatusChange, error) {
+	if len(articles) == 0 {
+		return nil, nil
+	}
+	ids := make([]int, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+	statuses, err := fs.db.GetUserArticleStatuses(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []database.ArticleStatusChange
+	for _, a := range articles {
+		status := statuses[a.ID] // zero value: unread, unstarred
+		if status.IsRead == isRead && status.IsStarred == isStarred {
+			continue
+		}
+		changes = append(changes, database.ArticleStatusChange{
+			ArticleID:      a.ID,
+			FeedID:         a.FeedID,
+			WasRead:        status.IsRead,
+			WasStarred:     status.IsStarred,
+			ReadChanged:    status.IsRead != isRead,
+			StarredChanged: status.IsStarred != isStarred,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ArticleID < changes[j].ArticleID })
+	return changes, nil
+}
+
+// recordUndo stores a change set and returns its ID and expiry. Failures are
+// logged rather than returned because the bulk change has already been applied.
+func (fs *FeedService) recordUndo(userID int, kind string, changes []database.ArticleStatusChange) (string, time.Time) {
+	if len(changes) == 0 {
+		return "", time.Time{}
+	}
+	if len(changes) > maxUndoChanges {
+		log.Printf("User %d: %s changed %d articles, too many to record for undo", userID, kind, len(changes))
+		return "", time.Time{}
+	}
+
+	// Opportunistically purge expired change sets so they don't accumulate.
+	if _, err := fs.db.DeleteExpiredUndoOperations(time.Now()); err != nil {
+		log.Printf("Failed to purge expired undo operations: %v", err)
+	}
+
+	id, err := generateUndoID()
+	if err != nil {
+		log.Printf("Failed to generate undo operation ID: %v", err)
+		return "", time.Time{}
+	}
+
+	now := time.Now()
+	op := &database.UndoOperation{
+		ID:        id,
+		UserID:    userID,
+		Kind:      kind,
+		Changes:   changes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(undoRetention),
+	}
+	if err := fs.db.CreateUndoOperation(op); err != nil {
+		log.Printf("Failed to record undo operation for user %d: %v", userID, err)
+		return "", time.Time{}
+	}
+	return op.ID, op.ExpiresAt
+}
+
+func generateUndoID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
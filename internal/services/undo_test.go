@@ -0,0 +1,303 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+func TestMarkAllReadUndo(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+
+	var articles []*database.Article
+	for i := 0; i < 4; i++ {
+		a := &database.Article{
+			FeedID: feed.ID, Title: fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://example.com/undo-%d", i),
+			PublishedAt: time.Now(), CreatedAt: time.Now(),
+		}
+		if err := db.AddArticle(a); err != nil {
+			t.Fatalf("AddArticle: %v", err)
+		}
+		articles = append(articles, a)
+	}
+	// 0: unread, 1: read, 2: starred, 3: read and starred
+	if err := db.SetUserArticleStatus(user.ID, articles[1].ID, true, false); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+	if err := db.SetUserArticleStatus(user.ID, articles[2].ID, false, true); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+	if err := db.SetUserArticleStatus(user.ID, articles[3].ID, true, true); err != nil {
+		t.Fatalf("SetUserArticleStatus: %v", err)
+	}
+
+	fs := NewFeedService(db, nil)
+	feeds := []database.Feed{*feed}
+	before, err := fs.GetUserUnreadCounts(user.ID, feeds)
+	if err != nil {
+		t.Fatalf("GetUserUnreadCounts: %v", err)
+	}
+	if before[feed.ID] != 2 {
+		t.Fatalf("expected 2 unread before mark-all-read, got %d", before[feed.ID])
+	}
+
+	result, err := fs.MarkArticlesRead(user.ID, database.MarkReadScope{})
+	if err != nil {
+		t.Fatalf("MarkArticlesRead: %v", err)
+	}
+	if result.UndoID == "" {
+		t.Fatal("expected an undo operation ID")
+	}
+	if counts, _ := fs.GetUserUnreadCounts(user.ID, feeds); counts[feed.ID] != 0 {
+		t.Fatalf("expected 0 unread after mark-all-read, got %d", counts[feed.ID])
+	}
+
+	restored, err := fs.UndoOperation(user.ID, result.UndoID)
+	if err != nil {
+		t.Fatalf("UndoOperation: %v", err)
+	}
+	// Articles 1 and 3 were already read, so mark-all-read didn't change them.
+	if restored != 2 {
+		t.Errorf("expected 2 articles restored, got %d", restored)
+	}
+
+	want := []struct{ read, starred bool }{{false, false}, {true, false}, {false, true}, {true, true}}
+	for i, a := range articles {
+		got, err := db.GetArticleByID(user.ID, a.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetArticleByID: %v", err)
+		}
+		if got.IsRead != want[i].read || got.IsStarred != want[i].starred {
+			t.Errorf("article %d: expected read=%v starred=%v, got read=%v starred=%v",
+				i, want[i].read, want[i].starred, got.IsRead, got.IsStarred)
+		}
+	}
+
+	after, err := fs.GetUserUnreadCounts(user.ID, feeds)
+	if err != nil {
+		t.Fatalf("GetUserUnreadCounts: %v", err)
+	}
+	if after[feed.ID] != before[feed.ID] {
+		t.Errorf("expected cached unread count %d after undo, got %d", before[feed.ID], after[feed.ID])
+	}
+
+	if _, err := fs.UndoOperation(user.ID, result.UndoID); !errors.Is(err, ErrUndoNotFound) {
+		t.Errorf("expected a second undo to return ErrUndoNotFound, got %v", err)
+	}
+}
+
+func TestUndoOperation_ConcurrentUndosApplyOnce(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+	// Every goroutine must see the same in-memory database
+	db.SetMaxOpenConns(1)
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+	op := &database.UndoOperation{
+		ID: "concurrent-op", UserID: user.ID, Kind: UndoKindBatchStatus,
+		Changes:   []database.ArticleStatusChange{{ArticleID: article.ID, FeedID: feed.ID}},
+		CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Minute),
+	}
+	if err := db.CreateUndoOperation(op); err != nil {
+		t.Fatalf("CreateUndoOperation: %v", err)
+	}
+
+	fs := NewFeedService(db, nil)
+	const attempts = 8
+	results := make(chan error, attempts)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := fs.UndoOperation(user.ID, op.ID)
+			results <- err
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(results)
+
+	applied := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			applied++
+		case !errors.Is(err, ErrUndoNotFound):
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if applied != 1 {
+		t.Errorf("expected the operation applied exactly once, got %d", applied)
+	}
+}
+
+func TestUndoOperation_KeepsLaterStars(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+		t.Fatalf("SubscribeUserToFeed: %v", err)
+	}
+	fs := NewFeedService(db, nil)
+
+	checkStatus := func(t *testing.T, articleID int, read, starred bool) {
+		t.Helper()
+		got, err := db.GetArticleByID(user.ID, articleID)
+		if err != nil || got == nil {
+			t.Fatalf("GetArticleByID: %v", err)
+		}
+		if got.IsRead != read || got.IsStarred != starred {
+			t.Errorf("expected read=%v starred=%v, got read=%v starred=%v", read, starred, got.IsRead, got.IsStarred)
+		}
+	}
+
+	t.Run("mark all read", func(t *testing.T) {
+		article := createTestArticle(t, db, feed.ID)
+		result, err := fs.MarkArticlesRead(user.ID, database.MarkReadScope{})
+		if err != nil || result.UndoID == "" {
+			t.Fatalf("MarkArticlesRead: %+v, %v", result, err)
+		}
+		if err := db.ToggleUserArticleStar(user.ID, article.ID); err != nil {
+			t.Fatalf("ToggleUserArticleStar: %v", err)
+		}
+
+		if _, err := fs.UndoOperation(user.ID, result.UndoID); err != nil {
+			t.Fatalf("UndoOperation: %v", err)
+		}
+		checkStatus(t, article.ID, false, true)
+	})
+
+	t.Run("batch that only set read", func(t *testing.T) {
+		article := createTestArticle(t, db, feed.ID)
+		read := true
+		result, err := fs.UpdateArticleStatuses(user.ID, []ArticleStatusOp{{ID: article.ID, Read: &read}})
+		if err != nil || result.UndoID == "" {
+			t.Fatalf("UpdateArticleStatuses: %+v, %v", result, err)
+		}
+		if err := db.ToggleUserArticleStar(user.ID, article.ID); err != nil {
+			t.Fatalf("ToggleUserArticleStar: %v", err)
+		}
+
+		if _, err := fs.UndoOperation(user.ID, result.UndoID); err != nil {
+			t.Fatalf("UndoOperation: %v", err)
+		}
+		checkStatus(t, article.ID, false, true)
+	})
+}
+
+func TestUndoOperation_Expired(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	op := &database.UndoOperation{
+		ID: "expired-op", UserID: user.ID, Kind: UndoKindBatchStatus,
+		CreatedAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(-time.Minute),
+	}
+	if err := db.CreateUndoOperation(op); err != nil {
+		t.Fatalf("CreateUndoOperation: %v", err)
+	}
+
+	fs := NewFeedService(db, nil)
+	if _, err := fs.UndoOperation(user.ID, op.ID); !errors.Is(err, ErrUndoNotFound) {
+		t.Errorf("expected ErrUndoNotFound for an expired operation, got %v", err)
+	}
+}
+
+func TestBatchSetArticleStatus_NothingChanged(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	feed := createTestFeed(t, db)
+	article := createTestArticle(t, db, feed.ID)
+
+	fs := NewFeedService(db, nil)
+	result, err := fs.BatchSetArticleStatus(user.ID, []database.Article{*article}, false, false)
+	if err != nil {
+		t.Fatalf("BatchSetArticleStatus: %v", err)
+	}
+	if result.UndoID != "" {
+		t.Errorf("expected no undo operation when no article changed, got %q", result.UndoID)
+	}
+}
+
+func TestMarkArticlesRead_ScopedUpdatesCachedCounts(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	user := createTestUser(t, db)
+	var feeds []database.Feed
+	for i := 0; i < 2; i++ {
+		now := time.Now()
+		feed := &database.Feed{
+			Title: fmt.Sprintf("Feed %d", i), URL: fmt.Sprintf("https://example.com/scoped-%d", i),
+			CreatedAt: now, UpdatedAt: now, LastFetch: now,
+		}
+		if err := db.AddFeed(feed); err != nil {
+			t.Fatalf("AddFeed: %v", err)
+		}
+		if err := db.SubscribeUserToFeed(user.ID, feed.ID); err != nil {
+			t.Fatalf("SubscribeUserToFeed: %v", err)
+		}
+		for j := 0; j < 2; j++ {
+			a := &database.Article{
+				FeedID: feed.ID, Title: "Article",
+				URL:         fmt.Sprintf("https://example.com/scoped-%d-%d", i, j),
+				PublishedAt: now, CreatedAt: now,
+			}
+			if err := db.AddArticle(a); err != nil {
+				t.Fatalf("AddArticle: %v", err)
+			}
+		}
+		feeds = append(feeds, *feed)
+	}
+	feedA, feedB := feeds[0], feeds[1]
+
+	fs := NewFeedService(db, nil)
+	if _, err := fs.GetUserUnreadCounts(user.ID, feeds); err != nil {
+		t.Fatalf("GetUserUnreadCounts: %v", err)
+	}
+
+	result, err := fs.MarkArticlesRead(user.ID, database.MarkReadScope{FeedIDs: []int{feedA.ID}})
+	if err != nil {
+		t.Fatalf("MarkArticlesRead: %v", err)
+	}
+	if result.Count != 2 {
+		t.Errorf("expected 2 articles marked read, got %d", result.Count)
+	}
+
+	cached, hit := fs.unreadCache.Get(user.ID)
+	if !hit {
+		t.Fatal("expected scoped mark-as-read to keep the unread cache warm")
+	}
+	fresh, err := db.GetUserUnreadCounts(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserUnreadCounts: %v", err)
+	}
+	if cached[feedA.ID] != 0 || cached[feedB.ID] != 2 {
+		t.Errorf("expected cached counts {%d:0 %d:2}, got %v", feedA.ID, feedB.ID, cached)
+	}
+	if cached[feedA.ID] != fresh[feedA.ID] || cached[feedB.ID] != fresh[feedB.ID] {
+		t.Errorf("cached counts %v diverged from database counts %v", cached, fresh)
+	}
+}
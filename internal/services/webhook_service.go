@@ -0,0 +1,595 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jeffreyp/goread2/internal/database"
+)
+
+var (
+	// ErrWebhookNotFound indicates the webhook does not exist or belongs to another user
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrInvalidWebhook indicates the webhook settings failed validation
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrWebhookLimitReached indicates the user already has the maximum number of webhooks
+	ErrWebhookLimitReached = errors.New("webhook limit reached")
+)
+
+// Webhook event types
+const (
+	WebhookEventArticleCreated = "article.created"
+	WebhookEventTest           = "webhook.test"
+)
+
+const (
+	MaxWebhooksPerUser      = 10
+	maxWebhookKeywords      = 20
+	maxWebhookKeywordLength = 100
+	maxWebhookURLLength     = 2048
+
+	// maxWebhookArticlesPerRefresh caps the events one refresh sends to a
+	// webhook, so a feed that republishes its archive doesn't flood the target.
+	maxWebhookArticlesPerRefresh = 25
+
+	// webhookConcurrency bounds in-flight requests across all webhooks.
+	webhookConcurrency = 8
+
+	webhookTimeout           = 10 * time.Second
+	webhookHistoryLimit      = 50
+	webhookDeliveryRetention = 30 * 24 * time.Hour
+)
+
+// webhookRetryDelays are the waits before each retry of a failed delivery.
+// Retries are scheduled in-process, so pending ones are dropped when the
+// instance shuts down.
+var webhookRetryDelays = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour}
+
+// WebhookService manages users' outgoing webhooks and delivers signed events
+// to them when new articles arrive.
+type WebhookService struct {
+	db           database.Database
+	urlValidator *URLValidator
+	client       HTTPClient
+	retryDelays  []time.Duration
+	sem          chan struct{}
+	wg           sync.WaitGroup
+	ctx          context.Context
+
+	pruneMu    sync.Mutex
+	lastPruned time.Time
+}
+
+func NewWebhookService(db database.Database) *WebhookService {
+	validator := NewURLValidator()
+	client := validator.CreateSecureHTTPClient(webhookTimeout)
+	// A redirected POST would either drop the signed body or resend it to a
+	// host the user never configured, so redirects count as failures.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &WebhookService{
+		db:           db,
+		urlValidator: validator,
+		client:       client,
+		retryDelays:  webhookRetryDelays,
+		sem:          make(chan struct{}, webhookConcurrency),
+		ctx:          context.Background(),
+	}
+}
+
+// Start ties deliveries to ctx: once it is cancelled, no new attempts are made
+// and scheduled retries are abandoned.
+func (ws *WebhookService) Start(ctx context.Context) {
+	ws.ctx = ctx
+}
+
+// SetHTTPClient sets a custom HTTP client for testing purposes. Target URLs
+// are still checked by the URL validator.
+func (ws *WebhookService) SetHTTPClient(client HTTPClient) {
+	ws.client = client
+}
+
+// SetURLValidator replaces the validator webhook URLs are checked with, so
+// tests can allow the loopback address their servers listen on.
+func (ws *WebhookService) SetURLValidator(validator *URLValidator) {
+	ws.urlValidator = validator
+}
+
+// Wait blocks until every queued delivery, including retries, has finished.
+func (ws *WebhookService) Wait() {
+	ws.wg.Wait()
+}
+
+// WebhookInput holds the user-editable webhook settings. A nil Enabled keeps
+// the current value (new webhooks start enabled) and an empty Secret keeps the
+// current secret (new webhooks get a generated one).
+type WebhookInput struct {
+	URL      string
+	Secret   string
+	FeedID   int
+	Keywords []string
+	Enabled  *bool
+}
+
+func (ws *WebhookService) ListWebhooks(userID int) ([]database.Webhook, error) {
+	webhooks, err := ws.db.GetUserWebhooks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if webhooks == nil {
+		webhooks = []database.Webhook{}
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook adds a webhook for the user. The returned webhook carries its
+// secret, which is not shown again.
+func (ws *WebhookService) CreateWebhook(userID int, input WebhookInput) (*database.Webhook, error) {
+	existing, err := ws.db.GetUserWebhooks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if len(existing) >= MaxWebhooksPerUser {
+		return nil, ErrWebhookLimitReached
+	}
+
+	now := time.Now()
+	webhook := &database.Webhook{UserID: userID, Enabled: true, CreatedAt: now, UpdatedAt: now}
+	if err := ws.applyInput(webhook, input); err != nil {
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		if webhook.Secret, err = generateWebhookSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	if err := ws.db.CreateWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return webhook, nil
+}
+
+func (ws *WebhookService) UpdateWebhook(userID, webhookID int, input WebhookInput) (*database.Webhook, error) {
+	webhook, err := ws.getWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.applyInput(webhook, input); err != nil {
+		return nil, err
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := ws.db.UpdateWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes the webhook and its delivery history. Retries already
+// scheduled for it are abandoned.
+func (ws *WebhookService) DeleteWebhook(userID, webhookID int) error {
+	if _, err := ws.getWebhook(userID, webhookID); err != nil {
+		return err
+	}
+	if err := ws.db.DeleteWebhook(userID, webhookID); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	return nil
+}
+
+// GetDeliveries returns the webhook's most recent delivery attempts, newest
+// first.
+func (ws *WebhookService) GetDeliveries(userID, webhookID int) ([]database.WebhookDelivery, error) {
+	if _, err := ws.getWebhook(userID, webhookID); err != nil {
+		return nil, err
+	}
+	deliveries, err := ws.db.GetWebhookDeliveries(userID, webhookID, webhookHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if deliveries == nil {
+		deliveries = []database.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// SendTestEvent delivers a test event to the webhook once, without retries,
+// and returns the recorded attempt. It is sent even if the webhook is
+// disabled, so a target can be checked before turning it on.
+func (ws *WebhookService) SendTestEvent(ctx context.Context, userID, webhookID int) (*database.WebhookDelivery, error) {
+	webhook, err := ws.getWebhook(userID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	eventID, err := generateWebhookEventID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate event ID: %w", err)
+	}
+
+	payload := WebhookPayload{ID: eventID, Event: WebhookEventTest, CreatedAt: time.Now().UTC(), WebhookID: webhook.ID}
+	delivery, _ := ws.attempt(ctx, webhook, payload, 1)
+	return delivery, nil
+}
+
+func (ws *WebhookService) getWebhook(userID, webhookID int) (*database.Webhook, error) {
+	webhook, err := ws.db.GetWebhook(userID, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// applyInput validates input and copies it onto webhook.
+func (ws *WebhookService) applyInput(webhook *database.Webhook, input WebhookInput) error {
+	target, err := ws.validateWebhookURL(input.URL)
+	if err != nil {
+		return err
+	}
+	keywords, err := normalizeWebhookKeywords(input.Keywords)
+	if err != nil {
+		return err
+	}
+	if input.Secret != "" {
+		if len(input.Secret) < 16 || len(input.Secret) > 256 {
+			return fmt.Errorf("%w: secret must be between 16 and 256 characters", ErrInvalidWebhook)
+		}
+		webhook.Secret = input.Secret
+	}
+	if input.FeedID < 0 {
+		return fmt.Errorf("%w: unknown feed", ErrInvalidWebhook)
+	}
+	if input.FeedID > 0 {
+		feeds, err := ws.db.GetUserFeeds(webhook.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		subscribed := false
+		for _, f := range feeds {
+			if f.ID == input.FeedID {
+				subscribed = true
+				break
+			}
+		}
+		if !subscribed {
+			return fmt.Errorf("%w: you are not subscribed to feed %d", ErrInvalidWebhook, input.FeedID)
+		}
+	}
+
+	webhook.URL = target
+	webhook.FeedID = input.FeedID
+	webhook.Keywords = keywords
+	if input.Enabled != nil {
+		webhook.Enabled = *input.Enabled
+	}
+	return nil
+}
+
+// validateWebhookURL rejects targets the SSRF checks would never deliver to.
+// Host names are resolved and checked again on every delivery, since their
+// addresses can change after the webhook is saved.
+func (ws *WebhookService) validateWebhookURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", fmt.Errorf("%w: URL is required", ErrInvalidWebhook)
+	}
+	if len(rawURL) > maxWebhookURLLength {
+		return "", fmt.Errorf("%w: URL exceeds %d characters", ErrInvalidWebhook, maxWebhookURLLength)
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return "", fmt.Errorf("%w: URL must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if !ws.urlValidator.AllowedSchemes[parsed.Scheme] {
+		return "", fmt.Errorf("%w: URL must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+	if parsed.User != nil {
+		return "", fmt.Errorf("%w: URL must not contain credentials", ErrInvalidWebhook)
+	}
+	if ip := net.ParseIP(parsed.Hostname()); ip != nil {
+		if err := ws.urlValidator.isBlockedIP(ip); err != nil {
+			return "", fmt.Errorf("%w: URL points to a private or reserved address", ErrInvalidWebhook)
+		}
+	}
+	return rawURL, nil
+}
+
+// normalizeWebhookKeywords trims, lower-cases and de-duplicates keywords.
+func normalizeWebhookKeywords(keywords []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, k := range keywords {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" || seen[k] {
+			continue
+		}
+		if utf8.RuneCountInString(k) > maxWebhookKeywordLength {
+			return nil, fmt.Errorf("%w: keywords must be at most %d characters", ErrInvalidWebhook, maxWebhookKeywordLength)
+		}
+		if strings.ContainsAny(k, "\r\n") {
+			return nil, fmt.Errorf("%w: keywords must be a single line", ErrInvalidWebhook)
+		}
+		seen[k] = true
+		normalized = append(normalized, k)
+	}
+	if len(normalized) > maxWebhookKeywords {
+		return nil, fmt.Errorf("%w: at most %d keywords are allowed", ErrInvalidWebhook, maxWebhookKeywords)
+	}
+	return normalized, nil
+}
+
+// WebhookPayload is the JSON body posted to webhooks.
+type WebhookPayload struct {
+	ID        string          `json:"id"` // Same for every retry of an event
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	WebhookID int             `json:"webhook_id"`
+	Feed      *WebhookFeed    `json:"feed,omitempty"`
+	Article   *WebhookArticle `json:"article,omitempty"`
+}
+
+type WebhookFeed struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+type WebhookArticle struct {
+	ID              int       `json:"id"`
+	Title           string    `json:"title"`
+	URL             string    `json:"url"`
+	Author          string    `json:"author,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	PublishedAt     time.Time `json:"published_at"`
+	MatchedKeywords []string  `json:"matched_keywords,omitempty"`
+}
+
+// NotifyNewArticles queues an article.created event for every new article
+// that matches a subscriber's webhook. Deliveries run in the background; only
+// the webhook lookup happens before it returns.
+func (ws *WebhookService) NotifyNewArticles(feedID int, feedTitle string, articles []database.Article) {
+	if len(articles) == 0 || ws.ctx.Err() != nil {
+		return
+	}
+	webhooks, err := ws.db.GetFeedWebhooks(feedID)
+	if err != nil {
+		log.Printf("Feed %d: failed to load webhooks: %v", feedID, err)
+		return
+	}
+
+	for i := range webhooks {
+		webhook := webhooks[i]
+		queued := 0
+		for _, article := range articles {
+			matched, ok := matchWebhookKeywords(webhook.Keywords, &article)
+			if !ok {
+				continue
+			}
+			if queued == maxWebhookArticlesPerRefresh {
+				log.Printf("Webhook %d: skipping remaining new articles from feed %d after %d events", webhook.ID, feedID, queued)
+				break
+			}
+			eventID, err := generateWebhookEventID()
+			if err != nil {
+				log.Printf("Webhook %d: failed to generate event ID: %v", webhook.ID, err)
+				return
+			}
+			payload := WebhookPayload{
+				ID:        eventID,
+				Event:     WebhookEventArticleCreated,
+				CreatedAt: time.Now().UTC(),
+				WebhookID: webhook.ID,
+				Feed:      &WebhookFeed{ID: feedID, Title: feedTitle},
+				Article: &WebhookArticle{
+					ID:              article.ID,
+					Title:           article.Title,
+					URL:             article.URL,
+					Author:          article.Author,
+					Description:     article.Description,
+					PublishedAt:     article.PublishedAt,
+					MatchedKeywords: matched,
+				},
+			}
+			queued++
+			ws.wg.Add(1)
+			go ws.deliver(webhook, payload)
+		}
+	}
+}
+
+// matchWebhookKeywords reports whether the article contains any of keywords
+// (all articles match when there are none) and which ones it contains.
+func matchWebhookKeywords(keywords []string, article *database.Article) ([]string, bool) {
+	if len(keywords) == 0 {
+		return nil, true
+	}
+	text := strings.ToLower(article.Title + "\n" + article.Description + "\n" + article.Content)
+	var matched []string
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			matched = append(matched, k)
+		}
+	}
+	return matched, len(matched) > 0
+}
+
+// deliver sends one event, retrying failures that may be temporary. The
+// webhook is re-read before each retry so edits, disabling and deletion take
+// effect on pending retries.
+func (ws *WebhookService) deliver(webhook database.Webhook, payload WebhookPayload) {
+	defer ws.wg.Done()
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case ws.sem <- struct{}{}:
+		case <-ws.ctx.Done():
+			return
+		}
+		delivery, retryable := ws.attempt(ws.ctx, &webhook, payload, attempt)
+		<-ws.sem
+
+		if delivery.Success || !retryable || attempt > len(ws.retryDelays) {
+			if !delivery.Success {
+				log.Printf("Webhook %d: giving up on event %s after %d attempts: %s", webhook.ID, payload.ID, attempt, delivery.Error)
+			}
+			return
+		}
+
+		timer := time.NewTimer(ws.retryDelays[attempt-1])
+		select {
+		case <-timer.C:
+		case <-ws.ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		current, err := ws.db.GetWebhook(webhook.UserID, webhook.ID)
+		if err != nil {
+			log.Printf("Webhook %d: failed to reload before retry: %v", webhook.ID, err)
+			continue
+		}
+		if current == nil || !current.Enabled {
+			return
+		}
+		webhook = *current
+	}
+}
+
+// attempt posts the payload once and records the result. It reports whether a
+// failure is worth retrying.
+func (ws *WebhookService) attempt(ctx context.Context, webhook *database.Webhook, payload WebhookPayload, attempt int) (*database.WebhookDelivery, bool) {
+	start := time.Now()
+	delivery := &database.WebhookDelivery{
+		WebhookID: webhook.ID,
+		UserID:    webhook.UserID,
+		EventID:   payload.ID,
+		Event:     payload.Event,
+		Attempt:   attempt,
+		CreatedAt: start,
+	}
+	if payload.Article != nil {
+		delivery.ArticleID = payload.Article.ID
+	}
+
+	retryable, err := ws.post(ctx, webhook, payload, delivery)
+	delivery.DurationMS = int(time.Since(start).Milliseconds())
+	if err != nil {
+		delivery.Error = err.Error()
+	} else {
+		delivery.Success = true
+	}
+
+	if err := ws.db.CreateWebhookDelivery(delivery); err != nil {
+		log.Printf("Webhook %d: failed to record delivery: %v", webhook.ID, err)
+	}
+	ws.pruneDeliveries()
+	return delivery, retryable
+}
+
+func (ws *WebhookService) post(ctx context.Context, webhook *database.Webhook, payload WebhookPayload, delivery *database.WebhookDelivery) (bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode payload: %v", err)
+	}
+
+	if err := ws.urlValidator.ValidateURL(ctx, webhook.URL); err != nil {
+		// DNS failures may clear up; blocked addresses won't.
+		return !errors.Is(err, ErrSSRFBlocked), err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %v", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoRead-Webhook/2.0")
+	req.Header.Set("X-GoRead-Event", payload.Event)
+	req.Header.Set("X-GoRead-Delivery", payload.ID)
+	req.Header.Set("X-GoRead-Signature", "t="+timestamp+",v1="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("%w: %v", ErrNetworkError, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("target returned HTTP %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("target returned HTTP %d", resp.StatusCode)
+	}
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.body" keyed with
+// the webhook secret, as sent in the v1 part of X-GoRead-Signature.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pruneDeliveries drops delivery history past its retention, at most hourly.
+func (ws *WebhookService) pruneDeliveries() {
+	ws.pruneMu.Lock()
+	due := time.Since(ws.lastPruned) >= time.Hour
+	if due {
+		ws.lastPruned = time.Now()
+	}
+	ws.pruneMu.Unlock()
+	if !due {
+		return
+	}
+
+	if _, err := ws.db.DeleteWebhookDeliveriesBefore(time.Now().Add(-webhookDeliveryRetention)); err != nil {
+		log.Printf("Failed to purge old webhook deliveries: %v", err)
+	}
+}
+
+func generateWebhookEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -1,8 +1,8 @@
 import AuthenticationServices
 import Foundation
 
-/// Owns the app's session state: bootstrapping from the persisted cookie,
-/// the ASWebAuthenticationSession sign-in flow, and sign-out.
+/// Owns the app's session state: bootstrapping from the tokens in the
+/// Keychain, the ASWebAuthenticationSession sign-in flow, and sign-out.
 @MainActor
 final class AuthManager: ObservableObject {
     enum SessionState {
@@ -20,14 +20,15 @@ final class AuthManager: ObservableObject {
 
     init(client: NetworkClient = .shared) {
         self.client = client
-        // Session cookies arrive via Set-Cookie on API responses (the backend
-        // refreshes the sliding session); accept them unconditionally.
-        HTTPCookieStorage.shared.cookieAcceptPolicy = .always
     }
 
-    /// Validates the persisted session cookie on launch. Also fetches the
-    /// CSRF token when the session is still valid.
+    /// Validates the stored tokens on launch, refreshing them if the access
+    /// token has expired since the app last ran.
     func bootstrap() async {
+        guard await client.tokens.current != nil else {
+            state = .signedOut
+            return
+        }
         do {
             let me = try await client.fetchMe()
             state = .signedIn(me.user)
@@ -38,7 +39,7 @@ final class AuthManager: ObservableObject {
 
     /// Runs the Google OAuth flow: /auth/login?client=ios inside
     /// ASWebAuthenticationSession, then exchanges the one-time code from the
-    /// goread2://auth callback for the session token.
+    /// goread2://auth callback for an access and refresh token pair.
     func signIn() async {
         errorMessage = nil
 
@@ -60,8 +61,7 @@ final class AuthManager: ObservableObject {
                 return
             }
 
-            let token = try await client.exchangeAuthCode(code)
-            storeSessionCookie(token)
+            try await client.exchangeAuthCode(code)
             let me = try await client.fetchMe()
             state = .signedIn(me.user)
         } catch let authError as ASWebAuthenticationSessionError where authError.code == .canceledLogin {
@@ -77,32 +77,15 @@ final class AuthManager: ObservableObject {
         clearLocalSession()
     }
 
-    /// Called when an API request fails with 401: the session is gone
+    /// Called when an API request fails with 401, which the client only
+    /// reports once refreshing the tokens failed too: the session is gone
     /// server-side, so only local state needs clearing.
     func sessionExpired() {
         clearLocalSession()
     }
 
-    private func storeSessionCookie(_ token: TokenResponse) {
-        var properties: [HTTPCookiePropertyKey: Any] = [
-            .name: token.cookieName,
-            .value: token.sessionToken,
-            .domain: client.baseURL.host ?? "",
-            .path: "/",
-            .expires: token.expiresAt,
-        ]
-        if client.baseURL.scheme == "https" {
-            properties[.secure] = "TRUE"
-        }
-        if let cookie = HTTPCookie(properties: properties) {
-            HTTPCookieStorage.shared.setCookie(cookie)
-        }
-    }
-
     private func clearLocalSession() {
-        if let cookies = HTTPCookieStorage.shared.cookies(for: client.baseURL) {
-            cookies.forEach(HTTPCookieStorage.shared.deleteCookie)
-        }
+        Task { await client.tokens.clear() }
         state = .signedOut
     }
 }
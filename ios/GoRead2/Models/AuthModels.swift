@@ -10,18 +10,20 @@ struct CurrentUser: Codable, Hashable {
     let maxArticlesOnFeedAdd: Int
 }
 
-/// Response of GET /auth/me. Fetching it also refreshes the CSRF token
-/// NetworkClient injects into mutating requests.
+/// Response of GET /auth/me. The CSRF token is only needed by cookie-based
+/// web clients; the app authenticates with a bearer token instead.
 struct MeResponse: Decodable {
     let user: CurrentUser
     let csrfToken: String?
 }
 
-/// Response of POST /auth/token, the mobile auth handoff. The session token
-/// is stored as the session cookie in HTTPCookieStorage; API calls then
-/// authenticate exactly like the web frontend's.
+/// Response of POST /auth/token (the mobile auth handoff) and of POST
+/// /auth/refresh. The access token lasts `expiresIn` seconds; the refresh
+/// token works once and is replaced by every refresh.
 struct TokenResponse: Decodable {
-    let sessionToken: String
-    let cookieName: String
-    let expiresAt: Date
+    let accessToken: String
+    let tokenType: String
+    let expiresIn: Int
+    let refreshToken: String
+    let refreshTokenExpiresAt: Date
 }
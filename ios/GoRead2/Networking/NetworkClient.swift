@@ -1,26 +1,25 @@
 import Foundation
 
 /// Shared networking layer for the GoRead2 REST API. All feature screens go
-/// through this client so cookies (session), CSRF handling, JSON coding, and
-/// error mapping live in one place.
+/// through this client so authentication, JSON coding, and error mapping
+/// live in one place.
 ///
-/// Uses URLSession.shared, whose cookie store is HTTPCookieStorage.shared;
-/// the OAuth flow injects the session cookie there and every request here
-/// carries it automatically.
+/// Requests carry the access token from `tokens` as a bearer token. When
+/// one is refused, the client refreshes the pair once and retries; only a
+/// refused refresh surfaces as `NetworkError.unauthorized`. Bearer requests
+/// need no CSRF token, since a browser never attaches one on its own.
 final class NetworkClient {
     static let shared = NetworkClient()
 
     let baseURL: URL
 
-    /// CSRF token from the last GET /auth/me, injected as X-CSRF-Token into
-    /// every mutating request.
-    private(set) var csrfToken: String?
+    let tokens: TokenStore
 
     private let session: URLSession
     private let decoder: JSONDecoder
     private let encoder: JSONEncoder
 
-    init(baseURL: URL? = nil, session: URLSession = .shared) {
+    init(baseURL: URL? = nil, session: URLSession = .shared, tokens: TokenStore = TokenStore()) {
         if let baseURL {
             self.baseURL = baseURL
         } else {
@@ -31,6 +30,7 @@ final class NetworkClient {
             self.baseURL = url
         }
         self.session = session
+        self.tokens = tokens
 
         decoder = JSONDecoder()
         decoder.keyDecodingStrategy = .convertFromSnakeCase
@@ -161,27 +161,35 @@ final class NetworkClient {
 
     // MARK: - Auth
 
-    /// Fetches the signed-in user and stores the CSRF token for subsequent
-    /// mutating requests.
+    /// Fetches the signed-in user.
     @discardableResult
     func fetchMe() async throws -> MeResponse {
-        let me = try await decode(MeResponse.self, from: get("/auth/me"))
-        if let token = me.csrfToken, !token.isEmpty {
-            csrfToken = token
-        }
-        return me
+        try await decode(MeResponse.self, from: get("/auth/me"))
     }
 
-    /// Exchanges the one-time code from the goread2://auth callback for the
-    /// session token (mobile auth handoff).
-    func exchangeAuthCode(_ code: String) async throws -> TokenResponse {
+    /// Exchanges the one-time code from the goread2://auth callback for an
+    /// access and refresh token pair (mobile auth handoff) and stores it.
+    func exchangeAuthCode(_ code: String) async throws {
         struct Request: Encodable { let code: String }
-        return try await decode(TokenResponse.self, from: post("/auth/token", body: Request(code: code)))
+        let response = try await decode(TokenResponse.self,
+                                        from: post("/auth/token", body: Request(code: code)))
+        await tokens.store(AuthTokens(response))
     }
 
+    /// Signs this device's session out server-side, which also stops its
+    /// refresh token working.
     func logout() async throws {
         _ = try await post("/auth/logout")
-        csrfToken = nil
+    }
+
+    /// Trades a refresh token for a new pair. Sent without an access token,
+    /// so it never triggers a refresh itself.
+    private func refreshTokens(_ refreshToken: String) async throws -> AuthTokens {
+        struct Request: Encodable { let refreshToken: String }
+        var req = request(path: "/auth/refresh", method: "POST")
+        req.setValue("application/json", forHTTPHeaderField: "Content-Type")
+        req.httpBody = try encoder.encode(Request(refreshToken: refreshToken))
+        return AuthTokens(try decode(TokenResponse.self, from: perform(req)))
     }
 
     // MARK: - Request building
@@ -195,9 +203,6 @@ final class NetworkClient {
         var req = URLRequest(url: components.url!)
         req.httpMethod = method
         req.setValue("application/json", forHTTPHeaderField: "Accept")
-        if method != "GET", let csrfToken {
-            req.setValue(csrfToken, forHTTPHeaderField: "X-CSRF-Token")
-        }
         return req
     }
 
@@ -218,7 +223,29 @@ final class NetworkClient {
 
     // MARK: - Response handling
 
+    /// Sends an API request with the stored access token, refreshing the
+    /// tokens and retrying once if the server refuses it.
     private func send(_ request: URLRequest) async throws -> Data {
+        guard let current = await tokens.current else {
+            return try await perform(request)
+        }
+        do {
+            return try await perform(authorized(request, with: current))
+        } catch NetworkError.unauthorized {
+            let fresh = try await tokens.refreshed(after: current) { refreshToken in
+                try await self.refreshTokens(refreshToken)
+            }
+            return try await perform(authorized(request, with: fresh))
+        }
+    }
+
+    private func authorized(_ request: URLRequest, with tokens: AuthTokens) -> URLRequest {
+        var req = request
+        req.setValue("Bearer \(tokens.accessToken)", forHTTPHeaderField: "Authorization")
+        return req
+    }
+
+    private func perform(_ request: URLRequest) async throws -> Data {
         let data: Data
         let response: URLResponse
         do {
@@ -0,0 +1,118 @@
+import Foundation
+import Security
+
+/// The access and refresh token pair from POST /auth/token or /auth/refresh.
+struct AuthTokens: Codable {
+    let accessToken: String
+    let accessTokenExpiresAt: Date
+    let refreshToken: String
+
+    init(_ response: TokenResponse) {
+        accessToken = response.accessToken
+        accessTokenExpiresAt = Date().addingTimeInterval(TimeInterval(response.expiresIn))
+        refreshToken = response.refreshToken
+    }
+}
+
+/// Keeps the signed-in tokens in the Keychain and makes sure only one
+/// refresh runs at a time. Refresh tokens work once: two requests that both
+/// hit an expired access token and refreshed separately would present the
+/// same refresh token twice, which the backend treats as theft and answers
+/// by signing the device out.
+actor TokenStore {
+    private static let service = "GoRead2"
+    private static let account = "auth-tokens"
+
+    private var tokens: AuthTokens?
+    private var loaded = false
+    private var refreshTask: Task<AuthTokens, Error>?
+
+    /// The stored tokens, or nil when signed out.
+    var current: AuthTokens? {
+        if !loaded {
+            tokens = Self.readKeychain()
+            loaded = true
+        }
+        return tokens
+    }
+
+    func store(_ tokens: AuthTokens) {
+        self.tokens = tokens
+        loaded = true
+        Self.writeKeychain(tokens)
+    }
+
+    func clear() {
+        tokens = nil
+        loaded = true
+        refreshTask = nil
+        Self.deleteKeychain()
+    }
+
+    /// Returns tokens newer than `stale`, the pair a request was rejected
+    /// with. Requests that fail together share one refresh, and a request
+    /// that fails after another already refreshed just uses the result.
+    /// The stored tokens are cleared when the refresh token is refused.
+    func refreshed(after stale: AuthTokens,
+                   using refresh: @escaping (String) async throws -> AuthTokens) async throws -> AuthTokens {
+        if let task = refreshTask {
+            return try await task.value
+        }
+        guard let tokens = current else {
+            throw NetworkError.unauthorized
+        }
+        if tokens.accessToken != stale.accessToken {
+            return tokens
+        }
+
+        let task = Task { try await refresh(tokens.refreshToken) }
+        refreshTask = task
+        defer { refreshTask = nil }
+        do {
+            let fresh = try await task.value
+            store(fresh)
+            return fresh
+        } catch NetworkError.unauthorized {
+            clear()
+            throw NetworkError.unauthorized
+        }
+    }
+
+    // MARK: - Keychain
+
+    private static var query: [String: Any] {
+        [
+            kSecClass as String: kSecClassGenericPassword,
+            kSecAttrService as String: service,
+            kSecAttrAccount as String: account,
+        ]
+    }
+
+    private static func readKeychain() -> AuthTokens? {
+        var query = query
+        query[kSecReturnData as String] = true
+        query[kSecMatchLimit as String] = kSecMatchLimitOne
+        var result: AnyObject?
+        guard SecItemCopyMatching(query as CFDictionary, &result) == errSecSuccess,
+              let data = result as? Data else {
+            return nil
+        }
+        return try? JSONDecoder().decode(AuthTokens.self, from: data)
+    }
+
+    private static func writeKeychain(_ tokens: AuthTokens) {
+        guard let data = try? JSONEncoder().encode(tokens) else { return }
+        let attributes: [String: Any] = [
+            kSecValueData as String: data,
+            // Kept out of backups restored onto other devices
+            kSecAttrAccessible as String: kSecAttrAccessibleAfterFirstUnlockThisDeviceOnly,
+        ]
+        if SecItemUpdate(query as CFDictionary, attributes as CFDictionary) == errSecItemNotFound {
+            SecItemAdd(query.merging(attributes) { _, new in new } as CFDictionary, nil)
+        }
+    }
+
+    private static func deleteKeychain() {
+        SecItemDelete(query as CFDictionary)
+    }
+}
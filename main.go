@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
+	_ "time/tzdata" // digest schedules use IANA zones; don't depend on the host's zoneinfo
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
 	"github.com/jeffreyp/goread2/internal/auth"
+	"github.com/jeffreyp/goread2/internal/cache"
 	"github.com/jeffreyp/goread2/internal/config"
 	"github.com/jeffreyp/goread2/internal/database"
+	"github.com/jeffreyp/goread2/internal/events"
 	"github.com/jeffreyp/goread2/internal/handlers"
+	"github.com/jeffreyp/goread2/internal/mail"
 	"github.com/jeffreyp/goread2/internal/middleware"
 	"github.com/jeffreyp/goread2/internal/services"
 )
@@ -24,6 +30,10 @@ import (
 // Falls back to "dev" when running without either (e.g. go run main.go).
 var version = "dev"
 
+// shutdownTimeout is how long in-flight requests get to finish after SIGTERM.
+// App Engine sends SIGKILL a few seconds later regardless.
+const shutdownTimeout = 5 * time.Second
+
 func init() {
 	if v := os.Getenv("BUILD_VERSION"); v != "" {
 		version = v
@@ -59,13 +69,73 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
+	// In-process event bus for real-time client updates (GET /api/events)
+	eventBus := events.NewMemoryBus()
+
 	feedService := services.NewFeedService(db, rateLimiter)
+	feedService.SetEventBus(eventBus)
 	feedService.Start(ctx)
 	subscriptionService := services.NewSubscriptionService(db)
 	auditService := services.NewAuditService(db)
+	annotationService := services.NewAnnotationService(db)
+	tagService := services.NewTagService(db)
+	webhookService := services.NewWebhookService(db)
+	webhookService.Start(ctx)
+	feedService.SetWebhookService(webhookService)
+	inboundEmailService := services.NewInboundEmailService(db, feedService, cfg.InboundEmailDomain)
+	importService := services.NewImportService(db, feedService, subscriptionService)
+	importService.SetEventBus(eventBus)
 	authService := auth.NewAuthService(db)
 	sessionManager := auth.NewSessionManager(db)
 	csrfManager := auth.NewCSRFManager()
+	// Access tokens are signed with a key derived from CSRF_SECRET, so every
+	// instance accepts the tokens the others issue
+	sessionManager.UseAccessTokenKey(csrfManager.DeriveKey("access-token"))
+
+	// Share caches across instances when a Redis-protocol server is configured.
+	// Without one, each instance caches independently.
+	if cfg.CacheRedisAddr != "" {
+		store := cache.NewRedisStore(cfg.CacheRedisAddr, cfg.CacheRedisPassword)
+		defer func() { _ = store.Close() }()
+		cacheBackend := cache.NewBackend(store, store)
+		if err := cacheBackend.Start(ctx); err != nil {
+			log.Printf("Shared cache unavailable, using per-instance caches: %v", err)
+		} else {
+			feedService.UseCacheBackend(cacheBackend)
+			sessionManager.UseCacheBackend(cacheBackend)
+			log.Printf("Shared cache enabled at %s", cfg.CacheRedisAddr)
+		}
+	}
+
+	// Email digests are sent through SMTP when configured, or written to
+	// MAIL_DEV_DIR for local development. Without either they stay disabled.
+	var mailer mail.Mailer
+	switch {
+	case cfg.SMTPAddr != "":
+		smtpMailer, err := mail.NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+		if err != nil {
+			log.Printf("Warning: email disabled, invalid SMTP configuration: %v", err)
+		} else {
+			mailer = smtpMailer
+		}
+	case cfg.MailDevDir != "":
+		fileMailer, err := mail.NewFileMailer(cfg.MailDevDir, cfg.MailFrom)
+		if err != nil {
+			log.Printf("Warning: email disabled: %v", err)
+		} else {
+			mailer = fileMailer
+			log.Printf("Writing outgoing email to %s", cfg.MailDevDir)
+		}
+	}
+	digestService := services.NewDigestService(db, mailer, cfg.BaseURL())
+	if cfg.EmailSignInEnabled {
+		if mailer != nil {
+			authService.EnableEmailSignIn(mailer, cfg.BaseURL())
+		} else {
+			log.Printf("Warning: email sign-in disabled, no mailer is configured")
+		}
+	}
+	directoryService := services.NewDirectoryService(db, cfg.DirectoryMinSubscribers)
 
 	// Initialize rate limiters for auth and API endpoints
 	// Auth: 10 requests per second with burst of 20
@@ -74,6 +144,10 @@ func main() {
 	apiRateLimiter := auth.NewRateLimiter(30, 50)
 	// Webhook: 5 requests per second with burst of 10 (Stripe traffic is low-volume)
 	webhookRateLimiter := auth.NewRateLimiter(5, 10)
+	// Inbound email: 5 requests per second with burst of 20 (newsletters often arrive in bursts)
+	inboundEmailRateLimiter := auth.NewRateLimiter(5, 20)
+	// Sign-in links: one every 20 seconds with burst of 3, since each one sends an email
+	emailLinkRateLimiter := auth.NewRateLimiter(0.05, 3)
 
 	// Initialize feed scheduler for staggered updates
 	feedScheduler := services.NewFeedScheduler(feedService, rateLimiter, services.SchedulerConfig{
@@ -112,12 +186,23 @@ func main() {
 		log.Println("Subscription system is disabled")
 	}
 
+	accountService := services.NewAccountService(db, feedService, auditService)
+	if paymentService != nil {
+		accountService.SetSubscriptionCanceller(paymentService)
+	}
+
 	// Initialize handlers
 	feedHandler := handlers.NewFeedHandler(feedService, subscriptionService, feedScheduler, db)
+	var backfillRunner handlers.BackfillRunner
+	if datastoreDB, ok := db.(*database.DatastoreDB); ok {
+		backfillRunner = datastoreDB
+	}
+	backfillHandler := handlers.NewBackfillHandler(backfillRunner)
 
-	// Wire up Cloud Tasks for the cron endpoints, production App Engine only.
-	// Local dev and tests leave this unset, so cron handlers fall back to
-	// their in-process behavior (see FeedHandler.RefreshFeeds).
+	// Wire up Cloud Tasks for the cron endpoints and backfill batches,
+	// production App Engine only. Local dev and tests leave this unset, so
+	// cron handlers fall back to their in-process behavior (see
+	// FeedHandler.RefreshFeeds).
 	if os.Getenv("GAE_ENV") == "standard" {
 		taskQueue, err := services.NewCloudTasksQueue(ctx)
 		if err != nil {
@@ -129,12 +214,23 @@ func main() {
 				}
 			}()
 			feedHandler.SetTaskQueue(taskQueue)
+			backfillHandler.SetTaskQueue(taskQueue)
 		}
 	}
 
 	articleHandler := handlers.NewArticleHandler(feedService)
+	annotationHandler := handlers.NewAnnotationHandler(annotationService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	inboundEmailHandler := handlers.NewInboundEmailHandler(inboundEmailService, subscriptionService, cfg.InboundEmailSecret)
+	eventsHandler := handlers.NewEventsHandler(eventBus, feedService)
+	syncHandler := handlers.NewSyncHandler(feedService)
+	digestHandler := handlers.NewDigestHandler(digestService)
+	directoryHandler := handlers.NewDirectoryHandler(directoryService)
 	authHandler := handlers.NewAuthHandler(authService, sessionManager, csrfManager)
 	adminHandler := handlers.NewAdminHandler(subscriptionService, auditService)
+	accountHandler := handlers.NewAccountHandler(accountService, sessionManager)
+	importHandler := handlers.NewImportHandler(importService)
 	var paymentHandler *handlers.PaymentHandler
 	if cfg.SubscriptionEnabled && paymentService != nil {
 		paymentHandler = handlers.NewPaymentHandler(paymentService, cfg.GoogleRedirectURL)
@@ -157,8 +253,9 @@ func main() {
 		log.Printf("Warning: Failed to configure trusted proxies: %v", err)
 	}
 
-	// Add gzip compression for all responses
-	r.Use(gzip.Gzip(gzip.DefaultCompression))
+	// Add gzip compression for all responses except the event stream, which
+	// must reach the client as each event is written
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/events"})))
 
 	// Security headers (CSP, HSTS, X-Frame-Options, etc.)
 	r.Use(middleware.SecurityHeaders())
@@ -166,9 +263,13 @@ func main() {
 	// CORS: allow cross-origin requests only from ALLOWED_ORIGIN (if set)
 	r.Use(middleware.CORS())
 
-	// Limit request body size to prevent memory exhaustion; OPML uploads get 10MB.
+	// Limit request body size to prevent memory exhaustion; OPML uploads and
+	// inbound email get 10MB, other readers' exports 32MB.
 	r.Use(middleware.RequestBodyLimit(1*1024*1024, map[string]int64{
-		"/api/feeds/import": 10 * 1024 * 1024,
+		"/api/feeds/import":   10 * 1024 * 1024,
+		"/api/imports":        32 * 1024 * 1024,
+		"/inbound/email":      10 * 1024 * 1024,
+		"/inbound/email/mime": 10 * 1024 * 1024,
 	}))
 
 	// Simple caching: only cache static assets aggressively, nothing else
@@ -241,8 +342,13 @@ func main() {
 		authRoutes.GET("/login", authHandler.Login)
 		authRoutes.GET("/callback", authHandler.Callback)
 		authRoutes.POST("/token", authHandler.Token)
+		authRoutes.POST("/refresh", authHandler.Refresh)
 		authRoutes.POST("/logout", authMiddleware.CSRFMiddleware(csrfManager), authHandler.Logout)
 		authRoutes.GET("/me", authMiddleware.OptionalAuth(), authHandler.Me)
+		authRoutes.GET("/providers", authHandler.Providers)
+		authRoutes.POST("/email", auth.RateLimitMiddleware(emailLinkRateLimiter), authHandler.RequestEmailLink)
+		authRoutes.GET("/email/verify", authHandler.ShowEmailSignIn)
+		authRoutes.POST("/email/verify", authHandler.VerifyEmailLink)
 	}
 
 	// Cron endpoint - requires X-Appengine-Cron header in production or admin auth locally
@@ -258,15 +364,21 @@ func main() {
 		cronRoutes.POST("/cleanup-sessions", authHandler.CleanupExpiredSessions)
 		cronRoutes.GET("/cleanup-orphaned-articles", feedHandler.CleanupOrphanedUserArticles)
 		cronRoutes.POST("/cleanup-orphaned-articles", feedHandler.CleanupOrphanedUserArticles)
+		cronRoutes.GET("/send-digests", digestHandler.SendDigests)
+		cronRoutes.POST("/send-digests", digestHandler.SendDigests)
+		cronRoutes.GET("/update-directory", directoryHandler.UpdateDirectory)
+		cronRoutes.POST("/update-directory", directoryHandler.UpdateDirectory)
 	}
 
-	// Cloud Tasks worker endpoints - dispatched only by the cron handlers
-	// above (via Cloud Tasks), authenticated via the X-AppEngine-QueueName
-	// header App Engine attaches to genuine task dispatches.
+	// Cloud Tasks worker endpoints - dispatched by the cron handlers above
+	// and by cmd/backfill (via Cloud Tasks), authenticated via the
+	// X-AppEngine-QueueName header App Engine attaches to genuine task
+	// dispatches.
 	taskRoutes := r.Group("/tasks")
 	{
 		taskRoutes.POST("/refresh-feeds", feedHandler.TaskRefreshFeeds)
 		taskRoutes.POST("/cleanup-orphaned-articles", feedHandler.TaskCleanupOrphanedArticles)
+		taskRoutes.POST("/backfill", backfillHandler.TaskRunBackfill)
 	}
 
 	// Protected API routes
@@ -278,18 +390,58 @@ func main() {
 	{
 		api.GET("/feeds", feedHandler.GetFeeds)
 		api.POST("/feeds", feedHandler.AddFeed)
+		api.POST("/feeds/html", feedHandler.AddHTMLFeed)
+		api.POST("/feeds/html/preview", feedHandler.PreviewHTMLFeed)
 		api.POST("/feeds/import", feedHandler.ImportOPML)
 		api.GET("/feeds/export", feedHandler.ExportOPML)
+		api.POST("/imports", importHandler.StartImport)
+		api.GET("/imports/:id", importHandler.GetImport)
 		api.DELETE("/feeds/:id", feedHandler.DeleteFeed)
 		api.GET("/feeds/:id/articles", feedHandler.GetArticles)
 		api.GET("/feeds/unread-counts", feedHandler.GetUnreadCounts)
 		api.GET("/subscription", feedHandler.GetSubscriptionInfo)
 		api.GET("/account/stats", feedHandler.GetAccountStats)
 		api.PUT("/account/max-articles", feedHandler.UpdateMaxArticlesOnFeedAdd)
+		api.GET("/account/digest", digestHandler.GetPreferences)
+		api.PUT("/account/digest", digestHandler.UpdatePreferences)
+		api.GET("/account/export", accountHandler.ExportAccount)
+		api.DELETE("/account", accountHandler.DeleteAccount)
+		api.GET("/account/sessions", accountHandler.ListSessions)
+		api.DELETE("/account/sessions/:id", accountHandler.RevokeSession)
+		api.POST("/account/sessions/sign-out-others", accountHandler.SignOutOtherSessions)
 		api.GET("/articles/:id", articleHandler.GetArticle)
+		api.PUT("/articles/:id/progress", articleHandler.SetProgress)
 		api.POST("/articles/:id/read", feedHandler.MarkRead)
 		api.POST("/articles/:id/star", feedHandler.ToggleStar)
 		api.POST("/articles/mark-all-read", feedHandler.MarkAllRead)
+		api.POST("/articles/batch", articleHandler.BatchUpdate)
+		api.POST("/undo/:operationID", feedHandler.Undo)
+		api.GET("/articles/:id/annotations", annotationHandler.ListAnnotations)
+		api.POST("/articles/:id/annotations", annotationHandler.CreateAnnotation)
+		api.PUT("/articles/:id/annotations/:annotationId", annotationHandler.UpdateAnnotation)
+		api.DELETE("/articles/:id/annotations/:annotationId", annotationHandler.DeleteAnnotation)
+		api.GET("/annotations/export", annotationHandler.ExportAnnotations)
+		api.POST("/articles/:id/tags", tagHandler.TagArticle)
+		api.DELETE("/articles/:id/tags/:tagId", tagHandler.UntagArticle)
+		api.GET("/tags", tagHandler.ListTags)
+		api.POST("/tags", tagHandler.CreateTag)
+		api.PUT("/tags/:id", tagHandler.RenameTag)
+		api.DELETE("/tags/:id", tagHandler.DeleteTag)
+		api.GET("/tags/:id/articles", tagHandler.GetTagArticles)
+		api.GET("/webhooks", webhookHandler.ListWebhooks)
+		api.POST("/webhooks", webhookHandler.CreateWebhook)
+		api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+		api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		api.POST("/webhooks/:id/test", webhookHandler.SendTestEvent)
+		api.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+		api.GET("/inbound-addresses", inboundEmailHandler.ListAddresses)
+		api.POST("/inbound-addresses", inboundEmailHandler.CreateAddress)
+		api.DELETE("/inbound-addresses/:id", inboundEmailHandler.DeleteAddress)
+		api.GET("/directory", directoryHandler.GetDirectory)
+		api.GET("/recommendations", directoryHandler.GetRecommendations)
+		api.GET("/events", eventsHandler.Stream)
+		api.GET("/sync", syncHandler.Changes)
+		api.POST("/sync/actions", syncHandler.ApplyActions)
 		api.POST("/feeds/refresh", feedHandler.RefreshFeeds) // Keep for authenticated manual refresh
 
 		// Payment/subscription routes - only if subscriptions are enabled
@@ -319,6 +471,7 @@ func main() {
 		admin.POST("/users/:email/admin", adminHandler.SetAdminStatus)
 		admin.POST("/users/:email/free-months", adminHandler.GrantFreeMonths)
 		admin.GET("/audit-logs", adminHandler.GetAuditLogs)
+		admin.POST("/feeds/:id/category", directoryHandler.SetFeedCategory)
 	}
 
 	// Webhook routes (public - no auth required) - only if subscriptions are enabled
@@ -326,6 +479,13 @@ func main() {
 		r.POST("/webhooks/stripe", auth.RateLimitMiddleware(webhookRateLimiter), paymentHandler.WebhookHandler)
 	}
 
+	// Inbound email route (public - authenticated with INBOUND_EMAIL_SECRET) - only if configured
+	if cfg.InboundEmailDomain != "" && cfg.InboundEmailSecret != "" {
+		r.POST("/inbound/email", auth.RateLimitMiddleware(inboundEmailRateLimiter), inboundEmailHandler.Receive)
+		// Mailgun only posts the raw MIME message to URLs ending in "mime"
+		r.POST("/inbound/email/mime", auth.RateLimitMiddleware(inboundEmailRateLimiter), inboundEmailHandler.Receive)
+	}
+
 	// Initialize admin users from environment configuration
 	if err := authService.InitializeAdminUsers(); err != nil {
 		log.Printf("Warning: Failed to initialize admin users: %v", err)
@@ -334,5 +494,25 @@ func main() {
 	// Get port from configuration
 	port := cfg.Port
 
-	log.Fatal(r.Run(":" + port))
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	// Shutdown waits for active requests but doesn't cancel them, so end the
+	// open /api/events streams rather than let them hold it until the timeout
+	srv.RegisterOnShutdown(eventBus.Close)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// SIGTERM cancels ctx: stop taking requests, then let webhook deliveries
+	// already under way record their results before the process exits.
+	// Scheduled retries are abandoned.
+	<-ctx.Done()
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server shutdown: %v", err)
+	}
+	webhookService.Wait()
 }
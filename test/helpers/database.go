@@ -48,7 +48,12 @@ func createTestTables(db *database.DB) error {
 			next_billing_date DATETIME,
 			is_admin BOOLEAN DEFAULT 0,
 			free_months_remaining INTEGER DEFAULT 0,
-			max_articles_on_feed_add INTEGER DEFAULT 100
+			max_articles_on_feed_add INTEGER DEFAULT 100,
+			digest_frequency TEXT DEFAULT '',
+			digest_timezone TEXT DEFAULT '',
+			digest_hour INTEGER DEFAULT 0,
+			digest_weekday INTEGER DEFAULT 0,
+			digest_last_sent_at DATETIME
 		)`,
 		`CREATE TABLE feeds (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -74,6 +79,7 @@ func createTestTables(db *database.DB) error {
 			author TEXT,
 			published_at DATETIME,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			reading_time_minutes INTEGER DEFAULT 0,
 			FOREIGN KEY (feed_id) REFERENCES feeds (id) ON DELETE CASCADE
 		)`,
 		`CREATE TABLE user_feeds (
@@ -88,6 +94,7 @@ func createTestTables(db *database.DB) error {
 			article_id INTEGER NOT NULL,
 			is_read BOOLEAN DEFAULT FALSE,
 			is_starred BOOLEAN DEFAULT FALSE,
+			read_progress INTEGER DEFAULT 0,
 			PRIMARY KEY (user_id, article_id),
 			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
 			FOREIGN KEY (article_id) REFERENCES articles (id) ON DELETE CASCADE
@@ -97,8 +104,21 @@ func createTestTables(db *database.DB) error {
 			user_id INTEGER NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			expires_at DATETIME NOT NULL,
+			user_agent TEXT DEFAULT '',
+			client_type TEXT DEFAULT '',
+			ip_address TEXT DEFAULT '',
+			last_seen_at DATETIME,
 			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE refresh_tokens (
+			token_hash TEXT PRIMARY KEY,
+			session_id TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			used_at DATETIME,
+			FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+		)`,
 	}
 
 	for _, table := range tables {
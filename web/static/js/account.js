@@ -63,6 +63,21 @@ class AccountApp {
             });
         }
 
+        const startImportBtn = document.getElementById('start-import');
+        if (startImportBtn) {
+            startImportBtn.addEventListener('click', () => this.startImport());
+        }
+
+        const signOutOthersBtn = document.getElementById('sign-out-others');
+        if (signOutOthersBtn) {
+            signOutOthersBtn.addEventListener('click', () => this.signOutOtherSessions());
+        }
+
+        const deleteAccountBtn = document.getElementById('delete-account');
+        if (deleteAccountBtn) {
+            deleteAccountBtn.addEventListener('click', () => this.deleteAccount());
+        }
+
         // Click outside modal to close
         window.addEventListener('click', (e) => {
             if (e.target === modal) {
@@ -76,6 +91,7 @@ class AccountApp {
             this.loadProfile(),
             this.loadSubscriptionInfo(),
             this.loadSettings(),
+            this.loadSessions(),
             this.loadUsageStats()
         ]);
     }
@@ -313,6 +329,44 @@ class AccountApp {
                             <button id="save-max-articles" class="btn btn-primary">Save</button>
                         </div>
                     </div>
+                    <div class="setting-item" id="digest-setting">
+                        <label for="digest-frequency">
+                            <strong>Email digest of unread articles:</strong>
+                            <div class="setting-description">
+                                Get the newest unread articles from each feed by email.
+                                Times are in your browser's timezone.
+                            </div>
+                        </label>
+                        <div class="setting-control">
+                            <select id="digest-frequency">
+                                <option value="off">Off</option>
+                                <option value="daily">Daily</option>
+                                <option value="weekly">Weekly</option>
+                            </select>
+                            <select id="digest-weekday">
+                                ${['Sunday', 'Monday', 'Tuesday', 'Wednesday', 'Thursday', 'Friday', 'Saturday']
+                                    .map((day, i) => `<option value="${i}">${day}</option>`).join('')}
+                            </select>
+                            <select id="digest-hour">
+                                ${Array.from({ length: 24 }, (_, h) => `<option value="${h}">${String(h).padStart(2, '0')}:00</option>`).join('')}
+                            </select>
+                            <button id="save-digest" class="btn btn-primary">Save</button>
+                        </div>
+                    </div>
+                    <div class="setting-item" id="newsletter-setting" style="display: none;">
+                        <label for="newsletter-title">
+                            <strong>Newsletter addresses:</strong>
+                            <div class="setting-description">
+                                Subscribe to email newsletters with one of these addresses and each issue
+                                shows up as an article in its own feed.
+                            </div>
+                        </label>
+                        <ul id="newsletter-addresses" class="newsletter-addresses"></ul>
+                        <div class="setting-control">
+                            <input type="text" id="newsletter-title" placeholder="Feed name (optional)" maxlength="100">
+                            <button id="create-newsletter-address" class="btn btn-primary">Create address</button>
+                        </div>
+                    </div>
                 </div>
             `;
 
@@ -321,6 +375,9 @@ class AccountApp {
             if (saveButton) {
                 saveButton.addEventListener('click', () => this.saveMaxArticlesSetting());
             }
+
+            await this.loadDigestSetting();
+            await this.loadNewsletterAddresses();
         } else {
             settingsElement.innerHTML = '<div class="error">Failed to load settings.</div>';
         }
@@ -372,6 +429,368 @@ class AccountApp {
         }
     }
 
+    async loadDigestSetting() {
+        const frequency = document.getElementById('digest-frequency');
+        const weekday = document.getElementById('digest-weekday');
+        const hour = document.getElementById('digest-hour');
+        const saveButton = document.getElementById('save-digest');
+
+        if (!frequency || !weekday || !hour || !saveButton) return;
+
+        const updateVisibility = () => {
+            weekday.style.display = frequency.value === 'weekly' ? '' : 'none';
+            hour.style.display = frequency.value === 'off' ? 'none' : '';
+        };
+        frequency.addEventListener('change', updateVisibility);
+        saveButton.addEventListener('click', () => this.saveDigestSetting());
+
+        try {
+            const response = await fetch('/api/account/digest', { headers: this.getAuthHeaders(false) });
+            if (!response.ok) {
+                throw new Error('Failed to load digest settings');
+            }
+            const digest = await response.json();
+            if (!digest.mail_configured) {
+                document.getElementById('digest-setting').style.display = 'none';
+                return;
+            }
+            frequency.value = digest.frequency;
+            weekday.value = String(digest.weekday);
+            hour.value = String(digest.hour);
+        } catch (error) {
+            console.error('Error loading digest settings:', error);
+        }
+        updateVisibility();
+    }
+
+    async saveDigestSetting() {
+        const saveButton = document.getElementById('save-digest');
+
+        try {
+            saveButton.disabled = true;
+            saveButton.textContent = 'Saving...';
+
+            const response = await fetch('/api/account/digest', {
+                method: 'PUT',
+                headers: this.getAuthHeaders(),
+                body: JSON.stringify({
+                    frequency: document.getElementById('digest-frequency').value,
+                    timezone: Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC',
+                    hour: parseInt(document.getElementById('digest-hour').value, 10),
+                    weekday: parseInt(document.getElementById('digest-weekday').value, 10)
+                })
+            });
+
+            if (!response.ok) {
+                const error = await response.json();
+                throw new Error(error.error || 'Failed to save digest settings');
+            }
+
+            saveButton.textContent = 'Saved!';
+            setTimeout(() => {
+                saveButton.textContent = 'Save';
+                saveButton.disabled = false;
+            }, 2000);
+        } catch (error) {
+            console.error('Error saving digest settings:', error);
+            alert('Failed to save digest settings. Please try again.');
+            saveButton.textContent = 'Save';
+            saveButton.disabled = false;
+        }
+    }
+
+    async loadNewsletterAddresses() {
+        const setting = document.getElementById('newsletter-setting');
+        const createButton = document.getElementById('create-newsletter-address');
+
+        if (!setting || !createButton) return;
+
+        try {
+            const response = await fetch('/api/inbound-addresses', { headers: this.getAuthHeaders(false) });
+            if (!response.ok) {
+                throw new Error('Failed to load newsletter addresses');
+            }
+            const result = await response.json();
+            if (!result.enabled && result.addresses.length === 0) {
+                return;
+            }
+            setting.style.display = '';
+            createButton.style.display = result.enabled ? '' : 'none';
+            document.getElementById('newsletter-title').style.display = result.enabled ? '' : 'none';
+            this.renderNewsletterAddresses(result.addresses);
+        } catch (error) {
+            console.error('Error loading newsletter addresses:', error);
+            return;
+        }
+
+        if (!createButton.dataset.bound) {
+            createButton.dataset.bound = 'true';
+            createButton.addEventListener('click', () => this.createNewsletterAddress());
+        }
+    }
+
+    renderNewsletterAddresses(addresses) {
+        const list = document.getElementById('newsletter-addresses');
+        list.innerHTML = addresses.map(address => `
+            <li>
+                <code>${this.escapeHtml(address.address)}</code>
+                <button class="btn btn-secondary" data-address-id="${address.id}">Delete</button>
+            </li>
+        `).join('');
+        list.querySelectorAll('button[data-address-id]').forEach(button => {
+            button.addEventListener('click', () => this.deleteNewsletterAddress(button.dataset.addressId));
+        });
+    }
+
+    async createNewsletterAddress() {
+        const createButton = document.getElementById('create-newsletter-address');
+        const title = document.getElementById('newsletter-title');
+
+        try {
+            createButton.disabled = true;
+            const response = await fetch('/api/inbound-addresses', {
+                method: 'POST',
+                headers: this.getAuthHeaders(),
+                body: JSON.stringify({ title: title.value })
+            });
+            if (!response.ok) {
+                const error = await response.json();
+                throw new Error(error.error || 'Failed to create newsletter address');
+            }
+            title.value = '';
+            await this.loadNewsletterAddresses();
+        } catch (error) {
+            console.error('Error creating newsletter address:', error);
+            alert(error.message);
+        } finally {
+            createButton.disabled = false;
+        }
+    }
+
+    deleteNewsletterAddress(addressId) {
+        this.showModal(
+            'Delete newsletter address?',
+            'Mail sent to this address will be rejected and you will be unsubscribed from its feed.',
+            async () => {
+                try {
+                    const response = await fetch(`/api/inbound-addresses/${addressId}`, {
+                        method: 'DELETE',
+                        headers: this.getAuthHeaders(false)
+                    });
+                    if (!response.ok) {
+                        const error = await response.json();
+                        throw new Error(error.error || 'Failed to delete newsletter address');
+                    }
+                    await this.loadNewsletterAddresses();
+                } catch (error) {
+                    console.error('Error deleting newsletter address:', error);
+                    alert('Failed to delete the newsletter address. Please try again.');
+                }
+            }
+        );
+    }
+
+    async startImport() {
+        const fileInput = document.getElementById('import-file');
+        const button = document.getElementById('start-import');
+        const status = document.getElementById('import-status');
+
+        if (!fileInput.files || fileInput.files.length === 0) {
+            alert('Please choose an export file');
+            return;
+        }
+
+        button.disabled = true;
+        status.textContent = 'Uploading...';
+        try {
+            const formData = new FormData();
+            formData.append('format', document.getElementById('import-format').value);
+            formData.append('file', fileInput.files[0]);
+
+            const response = await fetch('/api/imports', {
+                method: 'POST',
+                headers: this.getAuthHeaders(false),
+                body: formData
+            });
+            const job = await response.json();
+            if (!response.ok) {
+                throw new Error(job.error || 'Failed to start the import');
+            }
+            this.showImportProgress(job);
+            await this.pollImport(job.id);
+        } catch (error) {
+            console.error('Error importing:', error);
+            status.textContent = error.message;
+        } finally {
+            button.disabled = false;
+        }
+    }
+
+    async pollImport(id) {
+        for (;;) {
+            await new Promise(resolve => setTimeout(resolve, 1000));
+            const response = await fetch(`/api/imports/${encodeURIComponent(id)}`, { headers: this.getAuthHeaders(false) });
+            if (!response.ok) {
+                throw new Error('Lost track of the import. It may still be running; check your starred articles shortly.');
+            }
+            const job = await response.json();
+            this.showImportProgress(job);
+            if (job.status !== 'running') {
+                return;
+            }
+        }
+    }
+
+    showImportProgress(job) {
+        const status = document.getElementById('import-status');
+        if (job.status === 'running') {
+            status.textContent = `Importing... ${job.processed} of ${job.total} articles`;
+            return;
+        }
+        if (job.status === 'failed') {
+            status.textContent = job.error;
+            return;
+        }
+        let message = `Import finished: ${job.starred} starred and ${job.read} read articles, ` +
+            `${job.feeds_subscribed} new subscriptions.`;
+        if (job.not_found > 0) {
+            message += ` ${job.not_found} articles aren't in GoRead2 yet; import the file again after your feeds refresh to bring them over.`;
+        }
+        if (job.skipped > 0) {
+            message += ` ${job.skipped} articles were skipped.`;
+        }
+        if (job.limit_reached) {
+            message += ' Some feeds were not added because you reached your feed limit.';
+        }
+        status.textContent = message;
+    }
+
+    async loadSessions() {
+        const list = document.getElementById('session-list');
+        if (!list) return;
+
+        try {
+            const response = await fetch('/api/account/sessions', { headers: this.getAuthHeaders(false) });
+            if (!response.ok) {
+                throw new Error('Failed to load sessions');
+            }
+            const result = await response.json();
+            this.renderSessions(result.sessions);
+        } catch (error) {
+            console.error('Error loading sessions:', error);
+            list.innerHTML = '<li class="error">Failed to load your signed-in devices.</li>';
+        }
+    }
+
+    renderSessions(sessions) {
+        const list = document.getElementById('session-list');
+        list.innerHTML = sessions.map(session => `
+            <li>
+                <div>
+                    <div class="session-device">${this.escapeHtml(this.describeDevice(session))}</div>
+                    <div class="session-meta">
+                        ${session.ip_address ? this.escapeHtml(session.ip_address) + ' &middot; ' : ''}Last active ${this.formatDate(session.last_seen_at)}
+                    </div>
+                </div>
+                ${session.current
+                    ? '<span class="session-current">This device</span>'
+                    : `<button class="btn btn-secondary" data-session-id="${this.escapeHtml(session.id)}">Sign out</button>`}
+            </li>
+        `).join('');
+        list.querySelectorAll('button[data-session-id]').forEach(button => {
+            button.addEventListener('click', () => this.revokeSession(button.dataset.sessionId));
+        });
+
+        const signOutOthersBtn = document.getElementById('sign-out-others');
+        if (signOutOthersBtn) {
+            signOutOthersBtn.disabled = !sessions.some(session => !session.current);
+        }
+    }
+
+    describeDevice(session) {
+        if (session.client_type === 'ios') {
+            return 'GoRead iOS app';
+        }
+        const ua = session.user_agent || '';
+        const browser = [['Edg/', 'Edge'], ['Firefox/', 'Firefox'], ['Chrome/', 'Chrome'], ['Safari/', 'Safari']]
+            .find(([token]) => ua.includes(token));
+        const platform = [['iPhone', 'iPhone'], ['iPad', 'iPad'], ['Android', 'Android'], ['Mac OS X', 'macOS'], ['Windows', 'Windows'], ['Linux', 'Linux']]
+            .find(([token]) => ua.includes(token));
+        if (!browser && !platform) {
+            return 'Unknown device';
+        }
+        return [browser && browser[1], platform && platform[1]].filter(Boolean).join(' on ');
+    }
+
+    revokeSession(sessionId) {
+        this.showModal(
+            'Sign out this device?',
+            'It will need to sign in again to use your account.',
+            async () => {
+                try {
+                    const response = await fetch(`/api/account/sessions/${encodeURIComponent(sessionId)}`, {
+                        method: 'DELETE',
+                        headers: this.getAuthHeaders(false)
+                    });
+                    if (!response.ok) {
+                        const error = await response.json();
+                        throw new Error(error.error || 'Failed to sign out device');
+                    }
+                    await this.loadSessions();
+                } catch (error) {
+                    console.error('Error signing out device:', error);
+                    alert('Failed to sign out the device. Please try again.');
+                }
+            }
+        );
+    }
+
+    signOutOtherSessions() {
+        this.showModal(
+            'Sign out everywhere else?',
+            'Every other browser and app signed in to your account will need to sign in again.',
+            async () => {
+                try {
+                    const response = await fetch('/api/account/sessions/sign-out-others', {
+                        method: 'POST',
+                        headers: this.getAuthHeaders(false)
+                    });
+                    if (!response.ok) {
+                        const error = await response.json();
+                        throw new Error(error.error || 'Failed to sign out other devices');
+                    }
+                    await this.loadSessions();
+                } catch (error) {
+                    console.error('Error signing out other devices:', error);
+                    alert('Failed to sign out your other devices. Please try again.');
+                }
+            }
+        );
+    }
+
+    deleteAccount() {
+        this.showModal(
+            'Delete your account?',
+            'This cancels your subscription and permanently deletes your account and reading history. Download your data first if you want to keep it. This cannot be undone.',
+            async () => {
+                try {
+                    const response = await fetch('/api/account', {
+                        method: 'DELETE',
+                        headers: this.getAuthHeaders(false)
+                    });
+                    if (!response.ok) {
+                        const error = await response.json();
+                        throw new Error(error.error || 'Failed to delete account');
+                    }
+                    window.location.href = '/';
+                } catch (error) {
+                    console.error('Error deleting account:', error);
+                    alert(error.message);
+                }
+            }
+        );
+    }
+
     async loadUsageStats() {
         const statsElement = document.getElementById('usage-stats');
         
@@ -15,6 +15,9 @@ const ToastType = {
     ERROR: 'error'
 };
 
+// Users following fewer feeds than this are shown feed suggestions
+const FEW_FEEDS_THRESHOLD = 5;
+
 // Enhanced Error Handler with retry capabilities and toast notifications
 class ErrorHandler {
     constructor() {
@@ -501,6 +504,13 @@ class GoReadApp {
             });
         }
 
+        const previewHtmlFeedBtn = document.getElementById('preview-html-feed');
+        if (previewHtmlFeedBtn) {
+            previewHtmlFeedBtn.addEventListener('click', () => {
+                this.previewHTMLFeed();
+            });
+        }
+
         const importOpmlForm = document.getElementById('import-opml-form');
         if (importOpmlForm) {
             importOpmlForm.addEventListener('submit', async (e) => {
@@ -1036,6 +1046,7 @@ class GoReadApp {
             
             if (Array.isArray(this.feeds)) {
                 this.renderFeeds();
+                this.loadRecommendations();
                 
                 // Process unread counts if available
                 if (countsResponse.ok) {
@@ -1104,6 +1115,7 @@ class GoReadApp {
 
             if (Array.isArray(this.feeds)) {
                 this.renderFeeds();
+                this.loadRecommendations();
 
                 // Process unread counts if available
                 if (countsResponse.ok) {
@@ -2429,8 +2441,71 @@ class GoReadApp {
         }
     }
 
+    // getHTMLFeedSelectors returns the selectors from the add-feed form's
+    // builder, or null when no item selector is set and the URL should be
+    // added as an ordinary feed.
+    getHTMLFeedSelectors() {
+        const value = (id) => {
+            const input = document.getElementById(id);
+            return input ? input.value.trim() : '';
+        };
+        const selectors = {
+            item: value('html-feed-item'),
+            title: value('html-feed-title'),
+            link: value('html-feed-link'),
+            date: value('html-feed-date'),
+            summary: value('html-feed-summary')
+        };
+        return selectors.item ? selectors : null;
+    }
+
+    async previewHTMLFeed() {
+        const url = document.getElementById('feed-url').value;
+        const selectors = this.getHTMLFeedSelectors();
+        const preview = document.getElementById('html-feed-preview');
+        const button = document.getElementById('preview-html-feed');
+
+        if (!url || !selectors) {
+            preview.innerHTML = '<p class="form-help">Enter the page URL and an item selector first.</p>';
+            return;
+        }
+
+        button.disabled = true;
+        preview.innerHTML = '<p class="form-help">Loading preview...</p>';
+        try {
+            const response = await fetch('/api/feeds/html/preview', {
+                method: 'POST',
+                headers: this.getAuthHeaders(),
+                body: JSON.stringify({ url, selectors })
+            });
+            const data = await response.json();
+            if (!response.ok) {
+                preview.innerHTML = `<p class="form-help html-feed-preview-error">${this.escapeHtml(data.error || `HTTP ${response.status}`)}</p>`;
+                return;
+            }
+            if (data.items.length === 0) {
+                preview.innerHTML = '<p class="form-help html-feed-preview-error">The item selector matched no entries with a link.</p>';
+                return;
+            }
+            const items = data.items.map(item => `
+                <li>
+                    <a href="${this.escapeHtml(item.url)}" target="_blank" rel="noopener noreferrer">${this.escapeHtml(item.title)}</a>
+                    <span class="form-help">${this.escapeHtml(new Date(item.published_at).toLocaleDateString())}</span>
+                </li>`).join('');
+            preview.innerHTML = `
+                <p class="form-help">${data.items.length} ${data.items.length === 1 ? 'entry' : 'entries'} from ${this.escapeHtml(data.title)}</p>
+                <ul>${items}</ul>`;
+        } catch (error) {
+            console.error('Failed to preview HTML feed:', error);
+            preview.innerHTML = '<p class="form-help html-feed-preview-error">Unable to load the preview. Please try again.</p>';
+        } finally {
+            button.disabled = false;
+        }
+    }
+
     async addFeed() {
         const url = document.getElementById('feed-url').value;
+        const selectors = this.getHTMLFeedSelectors();
         const submitButton = document.querySelector('#add-feed-form button[type="submit"]');
         const cancelButton = document.getElementById('cancel-add-feed');
         const inputField = document.getElementById('feed-url');
@@ -2494,10 +2569,10 @@ class GoReadApp {
         animateSpinner();
         
         try {
-            const response = await fetch('/api/feeds', {
+            const response = await fetch(selectors ? '/api/feeds/html' : '/api/feeds', {
                 method: 'POST',
                 headers: this.getAuthHeaders(),
-                body: JSON.stringify({ url })
+                body: JSON.stringify(selectors ? { url, selectors } : { url })
             });
             
             if (response.ok) {
@@ -2683,6 +2758,105 @@ class GoReadApp {
         return div.innerHTML;
     }
 
+    // Suggest feeds to users who follow only a few, based on what people with
+    // the same feeds also follow (or, failing that, the most popular feeds).
+    async loadRecommendations() {
+        const container = document.getElementById('feed-suggestions');
+        if (!container) return;
+        if (!Array.isArray(this.feeds) || this.feeds.length >= FEW_FEEDS_THRESHOLD) {
+            container.hidden = true;
+            return;
+        }
+
+        try {
+            const response = await fetch('/api/recommendations?limit=5');
+            if (!response.ok) {
+                container.hidden = true;
+                return;
+            }
+            const data = await response.json();
+            this.renderRecommendations(data.recommendations || []);
+        } catch (error) {
+            console.error('Failed to load recommendations:', error);
+            container.hidden = true;
+        }
+    }
+
+    renderRecommendations(recommendations) {
+        const container = document.getElementById('feed-suggestions');
+        const list = document.getElementById('feed-suggestions-list');
+        if (!container || !list) return;
+
+        list.innerHTML = '';
+        container.hidden = recommendations.length === 0;
+
+        recommendations.forEach((rec) => {
+            const item = document.createElement('div');
+            item.className = 'feed-suggestion';
+
+            const info = document.createElement('div');
+            info.className = 'feed-suggestion-info';
+            const title = document.createElement('span');
+            title.className = 'feed-suggestion-title';
+            title.textContent = rec.title;
+            title.title = rec.description || rec.url;
+            const reason = document.createElement('span');
+            reason.className = 'feed-suggestion-reason';
+            reason.textContent = rec.because && rec.because.length > 0
+                ? `Readers of ${rec.because[0]} follow this`
+                : `${rec.subscribers} readers`;
+            info.appendChild(title);
+            info.appendChild(reason);
+
+            const followButton = document.createElement('button');
+            followButton.className = 'btn btn-secondary';
+            followButton.textContent = 'Follow';
+            followButton.setAttribute('aria-label', `Follow ${rec.title}`);
+            followButton.addEventListener('click', () => this.followRecommendation(rec, followButton));
+
+            item.appendChild(info);
+            item.appendChild(followButton);
+            list.appendChild(item);
+        });
+    }
+
+    async followRecommendation(rec, button) {
+        button.disabled = true;
+        try {
+            const response = await fetch('/api/feeds', {
+                method: 'POST',
+                headers: this.getAuthHeaders(),
+                body: JSON.stringify({ url: rec.url })
+            });
+            if (response.ok) {
+                this.showSuccess(`Now following ${rec.title}`);
+                await this.loadFeeds();
+                await this.loadSubscriptionInfo();
+                await this.updateUnreadCounts();
+                this.updateSubscriptionDisplay();
+                return;
+            }
+
+            let error = {};
+            try {
+                error = await response.json();
+            } catch (e) {
+                // Use default error message
+            }
+            if (response.status === 402 && error.limit_reached) {
+                this.showSubscriptionLimitModal(error);
+            } else if (response.status === 402 && error.trial_expired) {
+                this.showTrialExpiredModal(error);
+            } else {
+                this.showError(error.error || `HTTP ${response.status}`);
+            }
+        } catch (error) {
+            console.error('Failed to follow recommended feed:', error);
+            this.showError('Failed to follow feed. Please try again.', ErrorType.NETWORK);
+        }
+        button.disabled = false;
+    }
+
     // Empty state helper
     createEmptyState(iconSrc, title, message, actionButton = null) {
         const emptyState = document.createElement('div');
@@ -2880,25 +3054,66 @@ class GoReadApp {
                     <img src="/static/goread2_logo.svg" alt="GoRead2 Logo" width="80" height="80">
                 </div>
                 <h1>GoRead2</h1>
-                <p>Sign in with Google to access your RSS feeds</p>
-                <button id="google-login-btn" class="btn btn-primary">Sign in with Google</button>
+                <p>Sign in to access your RSS feeds</p>
+                <div id="login-providers" class="login-providers"></div>
                 <div class="login-footer">
                     <a href="/privacy" target="_blank" class="privacy-link">Privacy Policy</a>
                 </div>
             </div>
         `;
         document.body.appendChild(loginScreen);
+        this.renderLoginProviders();
+    }
 
-        document.getElementById('google-login-btn').addEventListener('click', () => {
-            this.login();
-        });
+    async renderLoginProviders() {
+        // Fall back to Google alone if the server can't say what's enabled
+        let providers = [{ id: 'google', label: 'Google' }];
+        try {
+            const response = await fetch('/auth/providers');
+            if (response.ok) {
+                const data = await response.json();
+                providers = data.providers || providers;
+            }
+        } catch (error) {
+            console.error('Failed to load sign-in methods:', error);
+        }
+
+        const container = document.getElementById('login-providers');
+        if (!container) return;
+        container.innerHTML = '';
+
+        for (const provider of providers) {
+            if (provider.id === 'email') {
+                const form = document.createElement('form');
+                form.className = 'login-email-form';
+                form.innerHTML = `
+                    <input type="email" id="login-email" placeholder="you@example.com" required autocomplete="email">
+                    <button type="submit" class="btn btn-secondary">Email me a sign-in link</button>
+                `;
+                form.addEventListener('submit', (e) => {
+                    e.preventDefault();
+                    this.requestEmailLink(document.getElementById('login-email').value);
+                });
+                container.appendChild(form);
+                continue;
+            }
+
+            const button = document.createElement('button');
+            button.className = 'btn btn-primary login-provider-btn';
+            if (provider.id === 'google') {
+                button.id = 'google-login-btn';
+            }
+            button.textContent = `Sign in with ${provider.label}`;
+            button.addEventListener('click', () => this.login(provider.id));
+            container.appendChild(button);
+        }
     }
 
-    async login() {
+    async login(provider = 'google') {
         try {
             // Reset auth check flag when attempting login
             this.authCheckFailed = false;
-            const response = await fetch('/auth/login');
+            const response = await fetch('/auth/login?provider=' + encodeURIComponent(provider));
             if (response.ok) {
                 const data = await response.json();
                 window.location.href = data.auth_url;
@@ -2910,6 +3125,24 @@ class GoReadApp {
         }
     }
 
+    async requestEmailLink(email) {
+        try {
+            const response = await fetch('/auth/email', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ email })
+            });
+            const data = await response.json().catch(() => ({}));
+            if (response.ok) {
+                this.showSuccess(data.message || 'Check your email for a sign-in link.');
+            } else {
+                this.showError(data.error || 'Failed to send a sign-in link');
+            }
+        } catch (error) {
+            this.showError('Failed to send a sign-in link: ' + error.message);
+        }
+    }
+
     async logout() {
         try {
             await fetch('/auth/logout', {
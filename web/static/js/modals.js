@@ -92,6 +92,11 @@ export class ModalManager {
         this._stopFocusTrap(modal);
         modal.style.display = 'none';
         form.reset();
+        const builder = document.getElementById('html-feed-builder');
+        if (builder) {
+            builder.open = false;
+            document.getElementById('html-feed-preview').innerHTML = '';
+        }
     }
 
     showHelpModal() {